@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kr0nicas/picobot/internal/audit"
+	"github.com/kr0nicas/picobot/internal/config"
+)
+
+// auditLogPath returns the location of the hash-chained audit log.
+func auditLogPath(cfg config.Config) string {
+	return filepath.Join(workspaceDir(cfg), "audit", "chain.jsonl")
+}
+
+// toolLogDir returns the directory of the daily tool call logs.
+func toolLogDir(cfg config.Config) string {
+	return filepath.Join(workspaceDir(cfg), "audit")
+}
+
+func newAuditCmd() *cobra.Command {
+	auditCmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Inspect the tamper-evident audit log",
+	}
+	verifyCmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Verify the audit log hash chain",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, _ := cmd.Flags().GetString("file")
+			if path == "" {
+				cfg, err := config.LoadConfig()
+				if err != nil {
+					return err
+				}
+				path = auditLogPath(cfg)
+			}
+			n, err := audit.Verify(path)
+			if err != nil {
+				return fmt.Errorf("audit log %s FAILED verification after %d valid entries: %w", path, n, err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "audit log %s OK (%d entries)\n", path, n)
+			return nil
+		},
+	}
+	verifyCmd.Flags().String("file", "", "audit log to verify (default <workspace>/audit/chain.jsonl)")
+	auditCmd.AddCommand(verifyCmd)
+
+	toolsCmd := &cobra.Command{
+		Use:   "tools",
+		Short: "List the tool calls the agent made",
+		Example: `  picobot audit tools --period yesterday
+  picobot audit tools --period 7d --tool exec --outcome error`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadConfig()
+			if err != nil {
+				return err
+			}
+			period, _ := cmd.Flags().GetString("period")
+			from, to, err := audit.ParsePeriod(period, time.Now())
+			if err != nil {
+				return err
+			}
+			q := audit.ToolQuery{From: from, To: to}
+			q.Tool, _ = cmd.Flags().GetString("tool")
+			q.Chat, _ = cmd.Flags().GetString("chat")
+			q.Outcome, _ = cmd.Flags().GetString("outcome")
+			calls, err := audit.ReadToolCalls(toolLogDir(cfg), q)
+			if err != nil {
+				return err
+			}
+			asJSON, _ := cmd.Flags().GetBool("json")
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			for _, c := range calls {
+				if asJSON {
+					if err := enc.Encode(c); err != nil {
+						return err
+					}
+				} else {
+					fmt.Fprintln(cmd.OutOrStdout(), c)
+				}
+			}
+			if len(calls) == 0 && !asJSON {
+				fmt.Fprintln(cmd.OutOrStdout(), "no tool calls logged in that period")
+			}
+			return nil
+		},
+	}
+	toolsCmd.Flags().String("period", "today", "today, yesterday, a date (2026-10-16) or a duration back from now (3h, 7d)")
+	toolsCmd.Flags().String("tool", "", "only calls of this tool")
+	toolsCmd.Flags().String("chat", "", "only calls for this channel or channel:chatID")
+	toolsCmd.Flags().String("outcome", "", "only calls with this outcome: ok, error, denied or blocked")
+	toolsCmd.Flags().Bool("json", false, "print the log entries as JSON lines")
+	auditCmd.AddCommand(toolsCmd)
+	return auditCmd
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kr0nicas/picobot/internal/config"
+)
+
+func newConfigCmd() *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the configuration",
+		// the profile may be in the file given with --file
+		PersistentPreRun: func(cmd *cobra.Command, args []string) { useProfileFlag(cmd) },
+	}
+	validateCmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Report unknown keys, missing settings and out of range values in the config",
+		Example: `  picobot config validate
+  picobot config validate --file ./config.yaml`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, _ := cmd.Flags().GetString("file")
+			if path == "" {
+				path = config.ConfigPath()
+			}
+			if _, err := os.Stat(path); err != nil {
+				return fmt.Errorf("no config at %s (run \"picobot onboard\" to create one): %w", path, err)
+			}
+			// the active profile, or the base config and every profile
+			profiles := []string{config.ActiveProfile()}
+			if profiles[0] == "" {
+				cfg, _ := config.LoadProfile(path, "")
+				profiles = append(profiles, cfg.ProfileNames()...)
+			}
+			n := 0
+			seen := make(map[string]bool) // problems of the file itself come up with every profile
+			for _, name := range profiles {
+				_, err := config.LoadProfile(path, name)
+				var verr *config.ValidationError
+				if err != nil && !errors.As(err, &verr) {
+					return err
+				}
+				if verr == nil {
+					continue
+				}
+				var problems []string
+				for _, p := range verr.Problems {
+					if !seen[p.String()] {
+						seen[p.String()] = true
+						problems = append(problems, p.String())
+					}
+				}
+				if name != "" && len(problems) > 0 {
+					fmt.Fprintf(cmd.OutOrStdout(), "with profile %s:\n", name)
+				}
+				for _, p := range problems {
+					fmt.Fprintln(cmd.OutOrStdout(), p)
+				}
+				n += len(problems)
+			}
+			if n > 0 {
+				return fmt.Errorf("config %s has %d problem(s)", path, n)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "config %s OK\n", path)
+			return nil
+		},
+	}
+	validateCmd.Flags().String("file", "", "config file to check (default config.json, .yaml, .yml or .toml in ~/.picobot)")
+	profilesCmd := &cobra.Command{
+		Use:   "profiles",
+		Short: "List the config profiles; the active one is marked with *",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadProfile(config.ConfigPath(), "")
+			var verr *config.ValidationError
+			if err != nil && !errors.As(err, &verr) {
+				return err
+			}
+			names := cfg.ProfileNames()
+			if len(names) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No profiles configured.")
+				return nil
+			}
+			for _, name := range names {
+				mark := " "
+				if name == config.ActiveProfile() {
+					mark = "*"
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%s %s\n", mark, name)
+			}
+			return nil
+		},
+	}
+	configCmd.AddCommand(validateCmd, profilesCmd)
+	return configCmd
+}
@@ -0,0 +1,546 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/agent"
+	"github.com/kr0nicas/picobot/internal/agent/tools"
+	"github.com/kr0nicas/picobot/internal/audit"
+	"github.com/kr0nicas/picobot/internal/channels"
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/config"
+	"github.com/kr0nicas/picobot/internal/cron"
+	"github.com/kr0nicas/picobot/internal/debug"
+	"github.com/kr0nicas/picobot/internal/heartbeat"
+	"github.com/kr0nicas/picobot/internal/logging"
+	"github.com/kr0nicas/picobot/internal/providers"
+	"github.com/kr0nicas/picobot/internal/redact"
+	"github.com/kr0nicas/picobot/internal/telemetry"
+)
+
+var gatewayLog = logging.For("gateway")
+
+// runGateway starts the agent loop, cron scheduler, heartbeat and enabled
+// channels, and blocks until ctx is canceled. It is shared by the gateway
+// command and the Windows service wrapper.
+func runGateway(ctx context.Context, modelFlag string) {
+	hub := chat.NewHub(200)
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	// the router sends each model (main and per-task routes) to its provider
+	var provider providers.LLMProvider = providers.NewRouterFromConfig(cfg)
+
+	// scrub configured secrets from logs and everything sent to channels,
+	// and keep a tail of the process log so admins can read it via /admin logs
+	redactor, err := newRedactor(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid redaction config: %v\n", err)
+		return
+	}
+	hub.AddFilter(redactor)
+	logTail := debug.NewLogTail(500)
+	logFile, err := setupLogging(cfg, redactor, logTail)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid logging config: %v\n", err)
+		return
+	}
+	defer logFile.Close()
+	stopTelemetry, err := setupTelemetry(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid telemetry config: %v\n", err)
+		return
+	}
+	defer stopTelemetry()
+	if p := config.ActiveProfile(); p != "" {
+		gatewayLog.Info("using config profile", "profile", p, "workspace", cfg.Agents.Defaults.Workspace)
+	}
+	provider, closeTraces := recordTraces(cfg, provider)
+	defer closeTraces()
+
+	// choose model: flag > config > provider default
+	model := modelFlag
+	if model == "" {
+		model = chatModel(cfg)
+	}
+	if model == "" {
+		model = provider.GetDefaultModel()
+	}
+
+	// create scheduler with fire callback that routes back through the agent loop, so the LLM can process the reminder and respond naturally to the user.
+	scheduler := cron.NewScheduler(func(job cron.Job) {
+		gatewayLog.Info("cron fired", "job", job.Name, "message", job.Message, "channel", job.Channel, "chat_id", job.ChatID)
+		content := fmt.Sprintf("[Scheduled reminder fired] %s — Please relay this to the user in a friendly way.", job.Message)
+		if job.Recurring {
+			content = fmt.Sprintf("[Scheduled task %q fired] %s — Carry this out now and send the user the result.", job.Name, job.Message)
+		}
+		hub.In <- chat.Inbound{
+			Channel:   job.Channel,
+			SenderID:  "cron",
+			ChatID:    job.ChatID,
+			Content:   content,
+			Scheduled: &chat.ScheduledJob{ID: job.ID, Role: job.Role},
+		}
+	})
+	loc, err := scheduleLocation(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid agents.defaults.timezone: %v\n", err)
+		return
+	}
+	scheduler.SetLocation(loc)
+	if err := scheduler.Load(cron.StorePath(cfg.Agents.Defaults.Workspace)); err != nil {
+		gatewayLog.Error("could not load saved cron jobs", "err", err)
+	}
+
+	maxIter := cfg.Agents.Defaults.MaxToolIterations
+	if maxIter <= 0 {
+		maxIter = 100
+	}
+	ag, err := agent.NewAgentLoop(hub, provider, model, maxIter, cfg.Agents.Defaults.Workspace, scheduler)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	ag.SetMultiTenant(cfg.Agents.Defaults.MultiTenant)
+	ag.SetStreaming(cfg.Agents.Defaults.Stream)
+	ag.SetVision(!cfg.Agents.Defaults.DisableVision)
+	ag.SetContextWindow(cfg.Agents.Defaults.ContextWindow, cfg.Agents.Defaults.MaxTokens)
+	ag.SetModelRoutes(modelRoutes(cfg))
+	ag.SetReflection(cfg.Memory.ReflectEvery)
+	ag.SetConcurrency(cfg.Agents.Defaults.MaxConcurrentChats)
+	ag.SetPlanning(cfg.Agents.Defaults.Planning)
+	ag.SetProgressUpdates(agent.ProgressUpdates{
+		AfterSteps: cfg.Agents.Defaults.ProgressAfterSteps,
+		After:      time.Duration(cfg.Agents.Defaults.ProgressAfterS) * time.Second,
+		Every:      time.Duration(cfg.Agents.Defaults.ProgressEveryS) * time.Second,
+	})
+	ag.SetTaskBudget(agent.TaskBudget{
+		Tokens: cfg.Agents.Defaults.TaskMaxTokens,
+		Cost:   cfg.Agents.Defaults.TaskMaxCost,
+		Time:   time.Duration(cfg.Agents.Defaults.TaskTimeoutS) * time.Second,
+	})
+	if err := ag.SetMemoryRanking(memoryRanking(cfg)); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid memory config: %v\n", err)
+		return
+	}
+	if err := ag.SetPromptProfiles(promptProfiles(cfg)); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid prompt config: %v\n", err)
+		return
+	}
+	ag.SetRedactor(redactor)
+	if al, err := audit.Open(auditLogPath(cfg)); err == nil {
+		defer al.Close()
+		ag.SetAuditLog(al)
+	} else {
+		gatewayLog.Warn("audit log unavailable", "err", err)
+	}
+	if tl, err := audit.OpenToolLog(toolLogDir(cfg)); err == nil {
+		defer tl.Close()
+		ag.SetToolLog(tl)
+	} else {
+		gatewayLog.Warn("tool call log unavailable", "err", err)
+	}
+	if ul, err := openUsageLedger(cfg); err == nil {
+		defer ul.Close()
+		ag.SetUsageLedger(ul)
+	} else {
+		gatewayLog.Warn("usage ledger unavailable", "err", err)
+	}
+	updateEmbeddedSkills(cfg)
+	if s := skillSync(cfg); s != nil {
+		if err := s.Pull(); err != nil {
+			gatewayLog.Error("syncing skills failed", "remote", cfg.Skills.GitRemote, "err", err)
+		}
+		ag.SetSkillSync(s)
+	}
+	if err := applyToolConfig(ag, cfg, provider); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid tools config: %v\n", err)
+		return
+	}
+	if err := ag.SetAgents(namedAgents(cfg), cfg.Agents.Routes, cfg.Agents.Router); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid agents config: %v\n", err)
+		return
+	}
+	tracer, err := newTracer(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to start debug tracer: %v\n", err)
+	} else {
+		defer tracer.Close()
+		ag.SetTracer(tracer)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer startMCP(ctx, ag, cfg)()
+	loadPlugins(ctx, ag, cfg)
+	ag.AddTools(tools.NewHeartbeatTool(heartbeat.NewTaskStore(heartbeat.TaskPath(cfg.Agents.Defaults.Workspace)), loc))
+
+	// start agent loop
+	go ag.Run(ctx)
+
+	if days := consolidateAfterDays(cfg); days >= 0 {
+		at, err := consolidateAt(cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid memory config: %v\n", err)
+			return
+		}
+		go ag.RunMemoryConsolidation(ctx, at, loc, days)
+	}
+
+	// start cron scheduler
+	go scheduler.Start(ctx.Done())
+
+	// start heartbeat
+	hbInterval := time.Duration(cfg.Agents.Defaults.HeartbeatIntervalS) * time.Second
+	if hbInterval <= 0 {
+		hbInterval = 60 * time.Second
+	}
+	web := cfg.Tools.Web
+	feedFetch := tools.NewFeedFetcher(tools.DomainPolicy{Allow: web.AllowDomains, Deny: web.DenyDomains})
+	hb := heartbeat.StartHeartbeat(ctx, cfg.Agents.Defaults.Workspace, hbInterval, hub, feedFetch, loc)
+	if qh := cfg.Agents.Defaults.HeartbeatQuietHours; qh != "" {
+		q, err := heartbeat.ParseQuietHours(qh, loc)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid agents.defaults.heartbeatQuietHours: %v\n", err)
+			return
+		}
+		hb.SetQuietHours(q)
+	}
+	if idle := heartbeatIdle(cfg); idle > 0 {
+		hb.SetBusy(func() bool { return ag.InConversation(idle) })
+	}
+
+	ag.SetAdmins(adminIDs(cfg))
+	ag.SetInboundGuard(inboundGuard(cfg))
+	ag.SetAdminHooks(agent.AdminHooks{
+		Heartbeat: hb,
+		Logs:      logTail,
+		ReloadConfig: func() (string, error) {
+			return reloadConfig(ag, tracer, modelFlag)
+		},
+	})
+
+	// start every enabled channel; they share the hub and run side by side
+	if started := startChannels(ctx, hub, cfg); len(started) > 0 {
+		gatewayLog.Info("channels running", "channels", strings.Join(started, ", "))
+	} else {
+		gatewayLog.Warn("no channels enabled; only cron and heartbeat will run")
+	}
+
+	<-ctx.Done()
+}
+
+// startChannels starts the adapters enabled in cfg. Each one has its own
+// credentials and allowlist and subscribes to the hub under its own name,
+// so replies are routed to the channel a message came from. It returns the
+// names of the channels that started.
+func startChannels(ctx context.Context, hub *chat.Hub, cfg config.Config) []string {
+	var started []string
+	if tc := cfg.Channels.Telegram; tc.Enabled {
+		var err error
+		if wh := tc.Webhook; wh.URL != "" {
+			opts := channels.TelegramWebhookOptions{URL: wh.URL, Listen: wh.Listen, SecretToken: wh.SecretToken, CertFile: wh.CertFile, KeyFile: wh.KeyFile}
+			err = channels.StartTelegramWebhook(ctx, hub, tc.Token, opts, tc.AllowFrom)
+		} else {
+			err = channels.StartTelegram(ctx, hub, tc.Token, tc.AllowFrom)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to start telegram: %v\n", err)
+		} else {
+			started = append(started, "telegram")
+		}
+	}
+	if cfg.Channels.Discord.Enabled {
+		if err := channels.StartDiscord(ctx, hub, cfg.Channels.Discord.Token, cfg.Channels.Discord.AllowFrom); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to start discord: %v\n", err)
+		} else {
+			started = append(started, "discord")
+		}
+	}
+	if sc := cfg.Channels.Slack; sc.Enabled {
+		opts := channels.SlackOptions{BotToken: sc.BotToken, AppToken: sc.AppToken, AllowFrom: sc.AllowFrom, AllowTeams: sc.AllowTeams}
+		if err := channels.StartSlack(ctx, hub, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to start slack: %v\n", err)
+		} else {
+			started = append(started, "slack")
+		}
+	}
+	if ec := cfg.Channels.Email; ec.Enabled {
+		opts := channels.EmailOptions{IMAPAddr: ec.IMAPAddr, SMTPAddr: ec.SMTPAddr, Username: ec.Username, Password: ec.Password,
+			From: ec.From, Folder: ec.Folder, AllowFrom: ec.AllowFrom, PollInterval: time.Duration(ec.PollIntervalS) * time.Second,
+			AuthServID: ec.AuthServID, AllowUnauthenticated: ec.AllowUnauthenticated}
+		if err := channels.StartEmail(ctx, hub, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to start email: %v\n", err)
+		} else {
+			started = append(started, "email")
+		}
+	}
+	if hc := cfg.Channels.HTTP; hc.Enabled {
+		opts := channels.HTTPOptions{Listen: hc.Listen, Token: hc.Token, Timeout: time.Duration(hc.TimeoutS) * time.Second}
+		if err := channels.StartHTTP(ctx, hub, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to start http channel: %v\n", err)
+		} else {
+			started = append(started, "http")
+		}
+	}
+	if wc := cfg.Channels.WebSocket; wc.Enabled {
+		opts := channels.WebSocketOptions{Listen: wc.Listen, Path: wc.Path, Token: wc.Token}
+		if err := channels.StartWebSocket(ctx, hub, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to start websocket channel: %v\n", err)
+		} else {
+			started = append(started, "websocket")
+		}
+	}
+	if sc := cfg.Channels.Signal; sc.Enabled {
+		opts := channels.SignalOptions{Addr: sc.Addr, Account: sc.Account, AllowFrom: sc.AllowFrom}
+		if err := channels.StartSignal(ctx, hub, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to start signal: %v\n", err)
+		} else {
+			started = append(started, "signal")
+		}
+	}
+	return started
+}
+
+// adminIDs returns the admin identities from cfg in the "channel:senderID"
+// form expected by the agent loop.
+func adminIDs(cfg config.Config) []string {
+	var ids []string
+	for _, id := range cfg.Channels.Telegram.Admins {
+		ids = append(ids, "telegram:"+id)
+	}
+	for _, id := range cfg.Channels.Discord.Admins {
+		ids = append(ids, "discord:"+id)
+	}
+	for _, id := range cfg.Channels.Slack.Admins {
+		ids = append(ids, "slack:"+id)
+	}
+	for _, id := range cfg.Channels.Email.Admins {
+		ids = append(ids, "email:"+strings.ToLower(id))
+	}
+	for _, id := range cfg.Channels.Signal.Admins {
+		ids = append(ids, "signal:"+id)
+	}
+	return ids
+}
+
+// chatModel returns the configured model for replies to users: the chat
+// route if set, otherwise agents.defaults.model.
+func chatModel(cfg config.Config) string {
+	if m := cfg.Agents.Defaults.ModelRoutes.Chat; m != "" {
+		return m
+	}
+	return cfg.Agents.Defaults.Model
+}
+
+// modelRoutes converts agents.defaults.modelRoutes to the agent's task routes.
+func modelRoutes(cfg config.Config) map[string]string {
+	r := cfg.Agents.Defaults.ModelRoutes
+	return map[string]string{
+		agent.TaskRanking:   r.Ranking,
+		agent.TaskHeartbeat: r.Heartbeat,
+		agent.TaskSummarize: r.Summarize,
+		agent.TaskSubagent:  r.Subagent,
+		agent.TaskReflect:   r.Reflect,
+		agent.TaskRoute:     r.Route,
+	}
+}
+
+// namedAgents converts agents.named, in name order. Agents without a
+// workspace get <workspace>/agents/<name>.
+func namedAgents(cfg config.Config) []agent.NamedAgent {
+	var agents []agent.NamedAgent
+	for _, name := range slices.Sorted(maps.Keys(cfg.Agents.Named)) {
+		na := cfg.Agents.Named[name]
+		ws := na.Workspace
+		if ws == "" {
+			ws = filepath.Join(cfg.Agents.Defaults.Workspace, "agents", name)
+		}
+		agents = append(agents, agent.NamedAgent{
+			Name:        name,
+			Description: na.Description,
+			Workspace:   ws,
+			Model:       na.Model,
+			Tools:       tools.ToolFilter{Allow: na.Tools.Allow, Deny: na.Tools.Deny},
+		})
+	}
+	return agents
+}
+
+// inboundGuard builds the flood guard from the channels.inbound config, or
+// returns nil when it is disabled.
+func inboundGuard(cfg config.Config) *chat.Guard {
+	in := cfg.Channels.Inbound
+	if in.Disabled {
+		return nil
+	}
+	return chat.NewGuard(chat.GuardConfig{
+		MaxMessageLen:   in.MaxMessageLength,
+		MaxPerMinute:    in.MaxPerMinute,
+		DuplicateWindow: time.Duration(in.DuplicateWindowS) * time.Second,
+		MuteFor:         time.Duration(in.MuteMinutes) * time.Minute,
+	})
+}
+
+// reloadConfig re-reads the config file and applies the settings that can
+// change without a restart: model, model routes, debug tracing and admin list. Provider,
+// channel and workspace changes still require restarting the gateway.
+func reloadConfig(ag *agent.AgentLoop, tracer *debug.Tracer, modelFlag string) (string, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return "", err
+	}
+	var changes []string
+	if model := chatModel(cfg); modelFlag == "" && model != "" && model != ag.Model() {
+		ag.SetModel(model)
+		changes = append(changes, "model="+model)
+	}
+	ag.SetModelRoutes(modelRoutes(cfg))
+	if tracer != nil && tracer.Enabled() != cfg.Agents.Defaults.Debug {
+		if err := tracer.SetEnabled(cfg.Agents.Defaults.Debug); err != nil {
+			return "", err
+		}
+		changes = append(changes, fmt.Sprintf("debug=%v", cfg.Agents.Defaults.Debug))
+	}
+	ag.SetAdmins(adminIDs(cfg))
+	changes = append(changes, fmt.Sprintf("admins=%d", len(adminIDs(cfg))))
+	gatewayLog.Info("config reloaded", "changes", strings.Join(changes, ", "))
+	return strings.Join(changes, ", "), nil
+}
+
+// scheduleLocation returns the time zone of cron schedules: the configured
+// one, or the system's.
+func scheduleLocation(cfg config.Config) (*time.Location, error) {
+	if tz := cfg.Agents.Defaults.Timezone; tz != "" {
+		return time.LoadLocation(tz)
+	}
+	return time.Local, nil
+}
+
+// heartbeatIdle returns how long after a user message the heartbeat holds
+// off; 0 means it doesn't.
+func heartbeatIdle(cfg config.Config) time.Duration {
+	switch s := cfg.Agents.Defaults.HeartbeatIdleS; {
+	case s < 0:
+		return 0
+	case s == 0:
+		return 120 * time.Second
+	default:
+		return time.Duration(s) * time.Second
+	}
+}
+
+// memoryRanking converts the memory section's ranking settings.
+func memoryRanking(cfg config.Config) agent.MemoryRanking {
+	m := cfg.Memory
+	return agent.MemoryRanking{
+		Ranker:     m.Ranker,
+		TopK:       m.TopK,
+		Candidates: m.RankCandidates,
+		Timeout:    time.Duration(m.RankTimeoutS) * time.Second,
+	}
+}
+
+// setupLogging makes the process log follow cfg.Logging. Everything logged
+// passes through redactor and is copied to tail.
+func setupLogging(cfg config.Config, redactor *redact.Redactor, tail io.Writer) (io.Closer, error) {
+	lc := cfg.Logging
+	opts := logging.Options{Level: lc.Level, Levels: lc.Levels, Format: lc.Format, MaxSizeMB: lc.MaxSizeMB, MaxBackups: lc.MaxBackups}
+	if lc.File != "" {
+		opts.File = config.ExpandHome(lc.File)
+	}
+	return logging.Setup(opts, redactor.Writer, tail)
+}
+
+// setupTelemetry starts tracing if cfg.Telemetry enables it. The returned
+// function exports the spans still pending.
+func setupTelemetry(cfg config.Config) (func(), error) {
+	tc := cfg.Telemetry
+	if !tc.Enabled {
+		return func() {}, nil
+	}
+	endpoint := tc.Endpoint
+	if endpoint == "" {
+		endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+	shutdown, err := telemetry.Setup(telemetry.Options{Endpoint: endpoint, Headers: tc.Headers, ServiceName: tc.ServiceName})
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			gatewayLog.Warn("exporting the last spans failed", "err", err)
+		}
+	}, nil
+}
+
+// newRedactor returns a redactor of the configured secrets with the rules
+// of cfg.Redaction.
+func newRedactor(cfg config.Config) (*redact.Redactor, error) {
+	r := redact.New(cfg.Secrets()...)
+	for i, rc := range cfg.Redaction.Rules {
+		re, err := regexp.Compile(rc.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		r.AddRules(redact.Rule{Pattern: re, Replace: rc.Replace, Channels: rc.Channels})
+	}
+	return r, nil
+}
+
+// promptProfiles returns the default prompt profile and those of channels,
+// whose unset fields are the default's.
+func promptProfiles(cfg config.Config) (agent.PromptProfile, map[string]agent.PromptProfile) {
+	pc := cfg.Agents.Defaults.Prompt
+	def := agent.PromptProfile{Sections: pc.Sections, Omit: pc.Omit, MaxFileChars: pc.MaxFileChars}
+	perChannel := make(map[string]agent.PromptProfile, len(pc.Channels))
+	for channel, c := range pc.Channels {
+		p := def
+		if c.Sections != nil {
+			p.Sections = c.Sections
+		}
+		if c.Omit != nil {
+			p.Omit = c.Omit
+		}
+		if c.MaxFileChars != 0 {
+			p.MaxFileChars = c.MaxFileChars
+		}
+		perChannel[channel] = p
+	}
+	return def, perChannel
+}
+
+// consolidateAfterDays returns the age in days past which daily notes are
+// consolidated into MEMORY.md; negative means never.
+func consolidateAfterDays(cfg config.Config) int {
+	if d := cfg.Memory.ConsolidateAfterDays; d != 0 {
+		return d
+	}
+	return 7
+}
+
+// consolidateAt returns the time of day of memory consolidation as an offset
+// from midnight.
+func consolidateAt(cfg config.Config) (time.Duration, error) {
+	at := cfg.Memory.ConsolidateAt
+	if at == "" {
+		at = "03:30"
+	}
+	t, err := time.Parse("15:04", at)
+	if err != nil {
+		return 0, fmt.Errorf("memory.consolidateAt %q: want HH:MM", at)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
@@ -17,11 +18,10 @@ import (
 
 	"github.com/kr0nicas/picobot/internal/agent"
 	"github.com/kr0nicas/picobot/internal/agent/memory"
-	"github.com/kr0nicas/picobot/internal/channels"
+	"github.com/kr0nicas/picobot/internal/audit"
 	"github.com/kr0nicas/picobot/internal/chat"
 	"github.com/kr0nicas/picobot/internal/config"
-	"github.com/kr0nicas/picobot/internal/cron"
-	"github.com/kr0nicas/picobot/internal/heartbeat"
+	"github.com/kr0nicas/picobot/internal/debug"
 	"github.com/kr0nicas/picobot/internal/providers"
 )
 
@@ -31,7 +31,22 @@ func NewRootCmd() *cobra.Command {
 	rootCmd := &cobra.Command{
 		Use:   "picobot",
 		Short: "Gio — security-hardened agentic AI in Go",
+		// fail early on an unknown profile rather than fall back to the
+		// base config's workspace
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			useProfileFlag(cmd)
+			if config.ActiveProfile() == "" {
+				return nil
+			}
+			_, err := config.LoadConfig()
+			var verr *config.ValidationError
+			if err != nil && !errors.As(err, &verr) {
+				return err
+			}
+			return nil
+		},
 	}
+	rootCmd.PersistentFlags().String("profile", "", "Config profile to use (default $PICOBOT_PROFILE)")
 
 	rootCmd.AddCommand(&cobra.Command{
 		Use:   "version",
@@ -60,19 +75,39 @@ func NewRootCmd() *cobra.Command {
 		Run: func(cmd *cobra.Command, args []string) {
 			msg, _ := cmd.Flags().GetString("message")
 			modelFlag, _ := cmd.Flags().GetString("model")
+			replay, _ := cmd.Flags().GetString("replay")
 			if msg == "" {
 				fmt.Println("Specify a message with -m \"your message\"")
 				return
 			}
 
 			hub := chat.NewHub(100)
-			cfg, _ := config.LoadConfig()
+			cfg, err := config.LoadConfig()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return
+			}
 			var provider providers.LLMProvider
 			if cfg.Providers.OpenAI != nil && cfg.Providers.OpenAI.APIKey != "" {
-				provider = providers.NewOpenAIProvider(cfg.Providers.OpenAI.APIKey, cfg.Providers.OpenAI.APIBase, cfg.Agents.Defaults.RequestTimeoutS, cfg.Agents.Defaults.MaxTokens)
+				p := providers.NewOpenAIProvider(cfg.Providers.OpenAI.APIKey, cfg.Providers.OpenAI.APIBase, cfg.Agents.Defaults.RequestTimeoutS, cfg.Agents.Defaults.MaxTokens)
+				p.Params = providers.ParamsFromConfig(cfg)
+				p.Retry = providers.RetryPolicyFromConfig(cfg.Providers.OpenAI)
+				provider = p
 			} else {
 				provider = providers.NewStubProvider()
 			}
+			if replay != "" {
+				rp, err := providers.NewReplayProvider(replay)
+				if err != nil {
+					fmt.Fprintln(cmd.ErrOrStderr(), "error:", err)
+					return
+				}
+				provider = rp
+			} else {
+				var closeTraces func()
+				provider, closeTraces = recordTraces(cfg, provider)
+				defer closeTraces()
+			}
 
 			// choose model: flag > config default > provider default
 			model := modelFlag
@@ -87,87 +122,114 @@ func NewRootCmd() *cobra.Command {
 			if maxIter <= 0 {
 				maxIter = 100
 			}
-			ag := agent.NewAgentLoop(hub, provider, model, maxIter, cfg.Agents.Defaults.Workspace, nil)
-
-			resp, err := ag.ProcessDirect(msg, 60*time.Second)
+			ag, err := agent.NewAgentLoop(hub, provider, model, maxIter, cfg.Agents.Defaults.Workspace, nil)
 			if err != nil {
 				fmt.Fprintln(cmd.ErrOrStderr(), "error:", err)
 				return
 			}
-			fmt.Fprintln(cmd.OutOrStdout(), resp)
+			ag.SetContextWindow(cfg.Agents.Defaults.ContextWindow, cfg.Agents.Defaults.MaxTokens)
+			ag.SetModelRoutes(modelRoutes(cfg))
+			if err := ag.SetMemoryRanking(memoryRanking(cfg)); err != nil {
+				fmt.Fprintln(cmd.ErrOrStderr(), "error: invalid memory config:", err)
+				return
+			}
+			if err := ag.SetPromptProfiles(promptProfiles(cfg)); err != nil {
+				fmt.Fprintln(cmd.ErrOrStderr(), "error: invalid prompt config:", err)
+				return
+			}
+			redactor, err := newRedactor(cfg)
+			if err != nil {
+				fmt.Fprintln(cmd.ErrOrStderr(), "error: invalid redaction config:", err)
+				return
+			}
+			logFile, err := setupLogging(cfg, redactor, nil)
+			if err != nil {
+				fmt.Fprintln(cmd.ErrOrStderr(), "error: invalid logging config:", err)
+				return
+			}
+			defer logFile.Close()
+			stopTelemetry, err := setupTelemetry(cfg)
+			if err != nil {
+				fmt.Fprintln(cmd.ErrOrStderr(), "error: invalid telemetry config:", err)
+				return
+			}
+			defer stopTelemetry()
+			ag.SetRedactor(redactor)
+			if al, err := audit.Open(auditLogPath(cfg)); err == nil {
+				defer al.Close()
+				ag.SetAuditLog(al)
+			} else {
+				fmt.Fprintln(cmd.ErrOrStderr(), "warning:", err)
+			}
+			if tl, err := audit.OpenToolLog(toolLogDir(cfg)); err == nil {
+				defer tl.Close()
+				ag.SetToolLog(tl)
+			} else {
+				fmt.Fprintln(cmd.ErrOrStderr(), "warning:", err)
+			}
+			if ul, err := openUsageLedger(cfg); err == nil {
+				defer ul.Close()
+				ag.SetUsageLedger(ul)
+			} else {
+				fmt.Fprintln(cmd.ErrOrStderr(), "warning:", err)
+			}
+			if err := applyToolConfig(ag, cfg, provider); err != nil {
+				fmt.Fprintln(cmd.ErrOrStderr(), "error:", err)
+				return
+			}
+			if tracer, err := newTracer(cfg); err == nil {
+				defer tracer.Close()
+				ag.SetTracer(tracer)
+			}
+			defer startMCP(context.Background(), ag, cfg)()
+			loadPlugins(context.Background(), ag, cfg)
+
+			// with streaming on, text is printed as it arrives; the final
+			// reply is printed only if nothing was streamed
+			var onDelta providers.StreamFunc
+			streamed := false
+			if cfg.Agents.Defaults.Stream {
+				onDelta = func(delta string) {
+					streamed = true
+					fmt.Fprint(cmd.OutOrStdout(), delta)
+				}
+			}
+			resp, err := ag.ProcessDirectStream(msg, 60*time.Second, onDelta)
+			if streamed {
+				fmt.Fprintln(cmd.OutOrStdout())
+			}
+			if err != nil {
+				fmt.Fprintln(cmd.ErrOrStderr(), "error:", err)
+				return
+			}
+			if !streamed {
+				fmt.Fprintln(cmd.OutOrStdout(), resp)
+			}
 		},
 	}
 	agentCmd.Flags().StringP("message", "m", "", "Message to send to the agent")
 	agentCmd.Flags().StringP("model", "M", "", "Model to use (overrides config/provider default)")
+	agentCmd.Flags().String("replay", "", "Serve responses from a recorded trace file instead of calling the provider")
 	rootCmd.AddCommand(agentCmd)
 
 	gatewayCmd := &cobra.Command{
 		Use:   "gateway",
 		Short: "Start long-running gateway (agent, telegram, heartbeat)",
 		Run: func(cmd *cobra.Command, args []string) {
-			hub := chat.NewHub(200)
-			cfg, _ := config.LoadConfig()
-			provider := providers.NewProviderFromConfig(cfg)
-
-			// choose model: flag > config > provider default
 			modelFlag, _ := cmd.Flags().GetString("model")
-			model := modelFlag
-			if model == "" && cfg.Agents.Defaults.Model != "" {
-				model = cfg.Agents.Defaults.Model
-			}
-			if model == "" {
-				model = provider.GetDefaultModel()
-			}
-
-			// create scheduler with fire callback that routes back through the agent loop, so the LLM can process the reminder and respond naturally to the user.
-			scheduler := cron.NewScheduler(func(job cron.Job) {
-				log.Printf("cron fired: %s — %s", job.Name, job.Message)
-				hub.In <- chat.Inbound{
-					Channel:  job.Channel,
-					SenderID: "cron",
-					ChatID:   job.ChatID,
-					Content:  fmt.Sprintf("[Scheduled reminder fired] %s — Please relay this to the user in a friendly way.", job.Message),
-				}
-			})
-
-			maxIter := cfg.Agents.Defaults.MaxToolIterations
-			if maxIter <= 0 {
-				maxIter = 100
-			}
-			ag := agent.NewAgentLoop(hub, provider, model, maxIter, cfg.Agents.Defaults.Workspace, scheduler)
-			ctx, cancel := context.WithCancel(context.Background())
+			ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 			defer cancel()
-
-			// start agent loop
-			go ag.Run(ctx)
-
-			// start cron scheduler
-			go scheduler.Start(ctx.Done())
-
-			// start heartbeat
-			hbInterval := time.Duration(cfg.Agents.Defaults.HeartbeatIntervalS) * time.Second
-			if hbInterval <= 0 {
-				hbInterval = 60 * time.Second
-			}
-			heartbeat.StartHeartbeat(ctx, cfg.Agents.Defaults.Workspace, hbInterval, hub)
-
-			// start telegram if enabled
-			if cfg.Channels.Telegram.Enabled {
-				if err := channels.StartTelegram(ctx, hub, cfg.Channels.Telegram.Token, cfg.Channels.Telegram.AllowFrom); err != nil {
-					fmt.Fprintf(os.Stderr, "failed to start telegram: %v\n", err)
-				}
-			}
-
-			// wait for signal
-			sigCh := make(chan os.Signal, 1)
-			signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-			<-sigCh
+			runGateway(ctx, modelFlag)
 			fmt.Println("shutting down gateway")
-			cancel()
 		},
 	}
 	gatewayCmd.Flags().StringP("model", "M", "", "Model to use (overrides config/provider default)")
 	rootCmd.AddCommand(gatewayCmd)
+	rootCmd.AddCommand(newServiceCmd())
+	rootCmd.AddCommand(newAuditCmd())
+	rootCmd.AddCommand(newSecretsCmd())
+	rootCmd.AddCommand(newConfigCmd())
+	rootCmd.AddCommand(newSkillsCmd())
 
 	// memory subcommands: read, append, write, recent
 	memoryCmd := &cobra.Command{
@@ -182,15 +244,13 @@ func NewRootCmd() *cobra.Command {
 		Run: func(cmd *cobra.Command, args []string) {
 			target := args[0]
 			cfg, _ := config.LoadConfig()
-			ws := cfg.Agents.Defaults.Workspace
-			if ws == "" {
-				ws = "~/.picobot/workspace"
-			}
-			home, _ := os.UserHomeDir()
-			if strings.HasPrefix(ws, "~/") {
-				ws = filepath.Join(home, ws[2:])
+			ws := workspaceDir(cfg)
+			mem, err := memory.NewMemoryStoreWithWorkspace(ws, 100)
+			if err != nil {
+				fmt.Fprintln(cmd.ErrOrStderr(), "error:", err)
+				return
 			}
-			mem := memory.NewMemoryStoreWithWorkspace(ws, 100)
+			defer mem.Close()
 			switch target {
 			case "today":
 				out, _ := mem.ReadToday()
@@ -216,15 +276,13 @@ func NewRootCmd() *cobra.Command {
 				return
 			}
 			cfg, _ := config.LoadConfig()
-			ws := cfg.Agents.Defaults.Workspace
-			if ws == "" {
-				ws = "~/.picobot/workspace"
-			}
-			home, _ := os.UserHomeDir()
-			if strings.HasPrefix(ws, "~/") {
-				ws = filepath.Join(home, ws[2:])
+			ws := workspaceDir(cfg)
+			mem, err := memory.NewMemoryStoreWithWorkspace(ws, 100)
+			if err != nil {
+				fmt.Fprintln(cmd.ErrOrStderr(), "error:", err)
+				return
 			}
-			mem := memory.NewMemoryStoreWithWorkspace(ws, 100)
+			defer mem.Close()
 			switch target {
 			case "today":
 				if err := mem.AppendToday(content); err != nil {
@@ -265,15 +323,13 @@ func NewRootCmd() *cobra.Command {
 				return
 			}
 			cfg, _ := config.LoadConfig()
-			ws := cfg.Agents.Defaults.Workspace
-			if ws == "" {
-				ws = "~/.picobot/workspace"
-			}
-			home, _ := os.UserHomeDir()
-			if strings.HasPrefix(ws, "~/") {
-				ws = filepath.Join(home, ws[2:])
+			ws := workspaceDir(cfg)
+			mem, err := memory.NewMemoryStoreWithWorkspace(ws, 100)
+			if err != nil {
+				fmt.Fprintln(cmd.ErrOrStderr(), "error:", err)
+				return
 			}
-			mem := memory.NewMemoryStoreWithWorkspace(ws, 100)
+			defer mem.Close()
 			if err := mem.WriteLongTerm(content); err != nil {
 				fmt.Fprintln(cmd.ErrOrStderr(), "write failed:", err)
 				return
@@ -289,25 +345,171 @@ func NewRootCmd() *cobra.Command {
 		Run: func(cmd *cobra.Command, args []string) {
 			days, _ := cmd.Flags().GetInt("days")
 			cfg, _ := config.LoadConfig()
-			ws := cfg.Agents.Defaults.Workspace
-			if ws == "" {
-				ws = "~/.picobot/workspace"
-			}
-			home, _ := os.UserHomeDir()
-			if strings.HasPrefix(ws, "~/") {
-				ws = filepath.Join(home, ws[2:])
+			ws := workspaceDir(cfg)
+			mem, err := memory.NewMemoryStoreWithWorkspace(ws, 100)
+			if err != nil {
+				fmt.Fprintln(cmd.ErrOrStderr(), "error:", err)
+				return
 			}
-			mem := memory.NewMemoryStoreWithWorkspace(ws, 100)
+			defer mem.Close()
 			out, _ := mem.GetRecentMemories(days)
 			fmt.Fprintln(cmd.OutOrStdout(), out)
 		},
 	}
 	recentCmd.Flags().IntP("days", "d", 1, "Number of days to include")
 
+	searchCmd := &cobra.Command{
+		Use:   "search [-q words] [--tag t] [--kind short|long] [--since YYYY-MM-DD] [--until YYYY-MM-DD]",
+		Short: "Search notes and long-term memory in the memory database",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			q := memory.Query{}
+			q.Text, _ = cmd.Flags().GetString("query")
+			q.Tag, _ = cmd.Flags().GetString("tag")
+			q.Kind, _ = cmd.Flags().GetString("kind")
+			if q.Kind != "" && q.Kind != "short" && q.Kind != "long" {
+				return fmt.Errorf("--kind: want short or long")
+			}
+			q.Limit, _ = cmd.Flags().GetInt("limit")
+			for flag, dst := range map[string]*time.Time{"since": &q.Since, "until": &q.Until} {
+				s, _ := cmd.Flags().GetString(flag)
+				if s == "" {
+					continue
+				}
+				d, err := time.Parse("2006-01-02", s)
+				if err != nil {
+					return fmt.Errorf("--%s: want YYYY-MM-DD", flag)
+				}
+				if flag == "until" {
+					d = d.AddDate(0, 0, 1) // through the end of that day
+				}
+				*dst = d
+			}
+			cfg, _ := config.LoadConfig()
+			mem, err := memory.NewMemoryStoreWithWorkspace(workspaceDir(cfg), 100)
+			if err != nil {
+				return err
+			}
+			defer mem.Close()
+			items, err := mem.Query(q)
+			if err != nil {
+				return err
+			}
+			for _, it := range items {
+				line := fmt.Sprintf("%s %-5s %s", it.Timestamp.Local().Format("2006-01-02 15:04"), it.Kind, it.Text)
+				for _, tag := range it.Tags {
+					line += " #" + tag
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), line)
+			}
+			return nil
+		},
+	}
+	searchCmd.Flags().StringP("query", "q", "", "Words the memory contains, in any order")
+	searchCmd.Flags().String("tag", "", "Tag the memory has")
+	searchCmd.Flags().String("kind", "", "Only daily notes (short) or long-term memory (long)")
+	searchCmd.Flags().String("since", "", "Earliest day (YYYY-MM-DD, UTC)")
+	searchCmd.Flags().String("until", "", "Latest day (YYYY-MM-DD, UTC)")
+	searchCmd.Flags().IntP("limit", "n", 50, "Maximum results")
+
+	consolidateCmd := &cobra.Command{
+		Use:   "consolidate [--days N]",
+		Short: "Fold old daily notes into MEMORY.md and archive them",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, _ := config.LoadConfig()
+			days, _ := cmd.Flags().GetInt("days")
+			if !cmd.Flags().Changed("days") {
+				days = max(consolidateAfterDays(cfg), 0)
+			}
+			provider := providers.NewProviderFromConfig(cfg)
+			model := cfg.Agents.Defaults.ModelRoutes.Summarize
+			if model == "" {
+				model = chatModel(cfg)
+			}
+			if model == "" {
+				model = provider.GetDefaultModel()
+			}
+			mem, err := memory.NewMemoryStoreWithWorkspace(workspaceDir(cfg), 100)
+			if err != nil {
+				return err
+			}
+			defer mem.Close()
+			res, err := mem.Consolidate(cmd.Context(), provider, model, days)
+			if len(res.Archived) > 0 {
+				fmt.Fprintf(cmd.OutOrStdout(), "consolidated %s into MEMORY.md (%d -> %d lines)\n", strings.Join(res.Archived, ", "), res.Before, res.After)
+			} else if err == nil {
+				fmt.Fprintf(cmd.OutOrStdout(), "no daily notes older than %d days\n", days)
+			}
+			return err
+		},
+	}
+	consolidateCmd.Flags().Int("days", 7, "Consolidate notes older than this many days (default memory.consolidateAfterDays)")
+
+	exportCmd := &cobra.Command{
+		Use:     "export [-o file]",
+		Aliases: []string{"backup"},
+		Short:   "Back up long-term memory, daily notes and the memory database as a .tar.gz",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, _ := config.LoadConfig()
+			out, _ := cmd.Flags().GetString("output")
+			if out == "" {
+				out = "picobot-memory-" + time.Now().Format("2006-01-02") + ".tar.gz"
+			}
+			mem, err := memory.NewMemoryStoreWithWorkspace(workspaceDir(cfg), 100)
+			if err != nil {
+				return err
+			}
+			defer mem.Close()
+			f, err := os.Create(out)
+			if err != nil {
+				return err
+			}
+			if err := mem.Export(f); err != nil {
+				f.Close()
+				os.Remove(out)
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "exported memory to %s\n", out)
+			return nil
+		},
+	}
+	exportCmd.Flags().StringP("output", "o", "", "Archive to write (default picobot-memory-<date>.tar.gz)")
+
+	importCmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Merge a backup made by export into the memory",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, _ := config.LoadConfig()
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			mem, err := memory.NewMemoryStoreWithWorkspace(workspaceDir(cfg), 100)
+			if err != nil {
+				return err
+			}
+			defer mem.Close()
+			res, err := mem.Import(f)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "imported %d memories; %d memory files created or extended\n", res.Memories, res.Files)
+			return nil
+		},
+	}
+
 	memoryCmd.AddCommand(readCmd)
 	memoryCmd.AddCommand(appendCmd)
 	memoryCmd.AddCommand(writeCmd)
 	memoryCmd.AddCommand(recentCmd)
+	memoryCmd.AddCommand(searchCmd)
+	memoryCmd.AddCommand(consolidateCmd)
+	memoryCmd.AddCommand(exportCmd)
+	memoryCmd.AddCommand(importCmd)
 
 	// rank subcommand: rank recent memories by relevance to a query
 	rankCmd := &cobra.Command{
@@ -322,15 +524,13 @@ func NewRootCmd() *cobra.Command {
 			top, _ := cmd.Flags().GetInt("top")
 			verbose, _ := cmd.Flags().GetBool("verbose")
 			cfg, _ := config.LoadConfig()
-			ws := cfg.Agents.Defaults.Workspace
-			if ws == "" {
-				ws = "~/.picobot/workspace"
-			}
-			home, _ := os.UserHomeDir()
-			if strings.HasPrefix(ws, "~/") {
-				ws = filepath.Join(home, ws[2:])
+			ws := workspaceDir(cfg)
+			mem, err := memory.NewMemoryStoreWithWorkspace(ws, 100)
+			if err != nil {
+				fmt.Fprintln(cmd.ErrOrStderr(), "error:", err)
+				return
 			}
-			mem := memory.NewMemoryStoreWithWorkspace(ws, 100)
+			defer mem.Close()
 			// Build memory items from today's file (split into lines) and long-term memory
 			items := make([]memory.MemoryItem, 0)
 			if td, err := mem.ReadToday(); err == nil && td != "" {
@@ -376,6 +576,37 @@ func NewRootCmd() *cobra.Command {
 	return rootCmd
 }
 
+// workspaceDir returns the configured workspace with "~" expanded, falling
+// back to the default location when unset.
+func workspaceDir(cfg config.Config) string {
+	ws := cfg.Agents.Defaults.Workspace
+	if ws == "" {
+		ws = "~/.picobot/workspace"
+	}
+	return config.ExpandHome(ws)
+}
+
+// newTracer creates the debug tracer configured by agents.defaults.debug/debugLogFile.
+// Tracing starts enabled if debug is true and can be toggled at runtime with /debug.
+func newTracer(cfg config.Config) (*debug.Tracer, error) {
+	path := cfg.Agents.Defaults.DebugLogFile
+	if path == "" {
+		ws := cfg.Agents.Defaults.Workspace
+		if ws == "" {
+			ws = "."
+		}
+		path = filepath.Join(ws, "logs", "debug.log")
+	}
+	return debug.NewTracer(path, cfg.Agents.Defaults.Debug)
+}
+
+// useProfileFlag selects the profile given with --profile; without it,
+// PICOBOT_PROFILE applies.
+func useProfileFlag(cmd *cobra.Command) {
+	p, _ := cmd.Flags().GetString("profile")
+	config.SetProfile(p)
+}
+
 func main() {
 	rootCmd := NewRootCmd()
 	if err := rootCmd.Execute(); err != nil {
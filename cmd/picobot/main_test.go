@@ -101,7 +101,10 @@ func TestMemoryCLI_Rank(t *testing.T) {
 		home, _ := os.UserHomeDir()
 		ws = filepath.Join(home, ws[2:])
 	}
-	mem := memory.NewMemoryStoreWithWorkspace(ws, 100)
+	mem, err := memory.NewMemoryStoreWithWorkspace(ws, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
 	_ = mem.AppendToday("buy milk and eggs")
 	_ = mem.AppendToday("call mom tomorrow")
 	_ = mem.AppendToday("milkshake recipe")
@@ -148,3 +151,69 @@ func TestAgentCLI_ModelFlag(t *testing.T) {
 		t.Fatalf("expected stub echo output, got: %q", out)
 	}
 }
+
+func TestConfigValidateCLI(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("PICOBOT_HOME", tmp)
+	if _, _, err := config.Onboard(); err != nil {
+		t.Fatalf("onboard failed: %v", err)
+	}
+	cmd := NewRootCmd()
+	buf := &bytes.Buffer{}
+	cmd.SetOut(buf)
+	cmd.SetArgs([]string{"config", "validate"})
+	if err := cmd.Execute(); err != nil || !strings.Contains(buf.String(), "OK") {
+		t.Fatalf("onboarded config should be valid: %v %q", err, buf.String())
+	}
+
+	bad := filepath.Join(tmp, "config.toml")
+	os.WriteFile(bad, []byte("[agents.defaults]\nmaxTokens = -1\n\n[channels.slack]\nenabled = true\n"), 0o600)
+	cmd = NewRootCmd()
+	buf.Reset()
+	cmd.SetOut(buf)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"config", "validate", "--file", bad})
+	if err := cmd.Execute(); err == nil {
+		t.Fatal("expected an error for an invalid config")
+	}
+	for _, want := range []string{"agents.defaults.maxTokens: must not be negative", "channels.slack.botToken: required"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected %q in output, got %q", want, buf.String())
+		}
+	}
+}
+
+func TestProfileFlag(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("PICOBOT_HOME", tmp)
+	t.Setenv("PICOBOT_PROFILE", "")
+	t.Cleanup(func() { config.SetProfile("") })
+	data := `{"agents": {"defaults": {"workspace": "` + filepath.ToSlash(filepath.Join(tmp, "workspace")) + `"}},
+  "profiles": {"dev": {"agents": {"defaults": {"maxTokens": -1}}}, "home": {}}}`
+	os.WriteFile(filepath.Join(tmp, "config.json"), []byte(data), 0o600)
+
+	cmd := NewRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"--profile", "work", "memory", "read", "today"})
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), `no profile "work"`) {
+		t.Fatalf("expected an error for an unknown profile, got %v", err)
+	}
+
+	cmd = NewRootCmd()
+	buf := &bytes.Buffer{}
+	cmd.SetOut(buf)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"config", "validate"})
+	if err := cmd.Execute(); err == nil || !strings.Contains(buf.String(), "with profile dev:\nagents.defaults.maxTokens") {
+		t.Fatalf("expected the problem of the dev profile, got %v %q", err, buf.String())
+	}
+
+	cmd = NewRootCmd()
+	buf.Reset()
+	cmd.SetOut(buf)
+	cmd.SetArgs([]string{"--profile", "home", "config", "profiles"})
+	if err := cmd.Execute(); err != nil || buf.String() != "  dev\n* home\n" {
+		t.Fatalf("profiles listed as %q, %v", buf.String(), err)
+	}
+}
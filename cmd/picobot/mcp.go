@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/agent"
+	"github.com/kr0nicas/picobot/internal/agent/tools"
+	"github.com/kr0nicas/picobot/internal/config"
+	"github.com/kr0nicas/picobot/internal/logging"
+	"github.com/kr0nicas/picobot/internal/mcp"
+)
+
+const mcpConnectTimeout = 30 * time.Second
+
+// startMCP connects to the MCP servers of tools.mcp and adds their tools to
+// the agent. Servers that fail to start are logged and skipped. The
+// returned function closes the connections.
+func startMCP(ctx context.Context, ag *agent.AgentLoop, cfg config.Config) func() {
+	names := make([]string, 0, len(cfg.Tools.MCP))
+	for name := range cfg.Tools.MCP {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var clients []*mcp.Client
+	for _, name := range names {
+		sc := cfg.Tools.MCP[name]
+		if sc.Dir == "" && sc.Command != "" {
+			sc.Dir = workspaceDir(cfg)
+		}
+		c, remote, err := connectMCP(ctx, name, sc)
+		if err != nil {
+			logging.For("mcp").Error("connecting to server failed", "server", name, "err", err)
+			continue
+		}
+		var ts []tools.Tool
+		for _, t := range tools.NewMCPTools(name, c, remote, time.Duration(sc.TimeoutS)*time.Second) {
+			ts = append(ts, t)
+		}
+		ag.AddTools(ts...)
+		clients = append(clients, c)
+		logging.For("mcp").Info("connected to server", "server", name, "tools", len(ts))
+	}
+	return func() {
+		for _, c := range clients {
+			c.Close()
+		}
+	}
+}
+
+// connectMCP connects to one server and lists its tools.
+func connectMCP(ctx context.Context, name string, sc config.MCPServerConfig) (*mcp.Client, []mcp.Tool, error) {
+	ctx, cancel := context.WithTimeout(ctx, mcpConnectTimeout)
+	defer cancel()
+	c, err := mcp.Connect(ctx, mcp.Server{Name: name, Command: sc.Command, Args: sc.Args, Env: sc.Env, Dir: sc.Dir, URL: sc.URL, Headers: sc.Headers})
+	if err != nil {
+		return nil, nil, err
+	}
+	remote, err := c.Tools(ctx)
+	if err != nil {
+		c.Close()
+		return nil, nil, err
+	}
+	return c, remote, nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/kr0nicas/picobot/internal/agent"
+	"github.com/kr0nicas/picobot/internal/agent/tools"
+	"github.com/kr0nicas/picobot/internal/config"
+	"github.com/kr0nicas/picobot/internal/logging"
+)
+
+// loadPlugins registers the executables in the plugins directory next to the
+// config file as tools. Plugins are not loaded from the workspace, where the
+// agent could place its own.
+func loadPlugins(ctx context.Context, ag *agent.AgentLoop, cfg config.Config) {
+	pluginsLog := logging.For("plugins")
+	dir := filepath.Join(filepath.Dir(config.ConfigPath()), tools.PluginDir)
+	ws := workspaceDir(cfg)
+	plugins, errs := tools.LoadPlugins(ctx, dir, ws, cfg.Tools.Exec.PassEnv)
+	for _, err := range errs {
+		pluginsLog.Error("loading plugin failed", "err", err)
+	}
+	if len(plugins) == 0 {
+		return
+	}
+	ag.ConfigureTools(func(reg *tools.Registry) {
+		for _, p := range plugins {
+			reg.Register(p)
+		}
+	})
+	pluginsLog.Info("plugins loaded", "count", len(plugins))
+}
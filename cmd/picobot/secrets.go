@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kr0nicas/picobot/internal/config"
+)
+
+func newSecretsCmd() *cobra.Command {
+	secretsCmd := &cobra.Command{
+		Use:   "secrets",
+		Short: "Keep credentials out of plaintext config.json",
+		Long:  "Encrypt API keys and tokens with a master passphrase taken from PICOBOT_MASTER_KEY or PICOBOT_MASTER_KEY_FILE (the same variable must be set when picobot starts), or move them to the OS keyring.",
+	}
+	encryptCmd := &cobra.Command{
+		Use:   "encrypt [value]",
+		Short: "Encrypt a single value (read from stdin if omitted)",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, err := config.MasterKey()
+			if err != nil {
+				return err
+			}
+			value, err := argOrStdin(cmd, args)
+			if err != nil {
+				return err
+			}
+			enc, err := config.EncryptSecret(value, key)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), enc)
+			return nil
+		},
+	}
+	encryptConfigCmd := &cobra.Command{
+		Use:   "encrypt-config",
+		Short: "Encrypt every plaintext credential in config.json in place",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, err := config.MasterKey()
+			if err != nil {
+				return err
+			}
+			cfg, path, err := readJSONConfig("encrypt values with \"picobot secrets encrypt\" and paste them in")
+			if err != nil {
+				return err
+			}
+			n, err := config.EncryptSecrets(&cfg, key)
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No plaintext credentials found.")
+				return nil
+			}
+			if err := config.SaveConfig(cfg, path); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Encrypted %d credential(s) in %s\n", n, path)
+			return nil
+		},
+	}
+	keyringSetCmd := &cobra.Command{
+		Use:   "keyring-set <name> [value]",
+		Short: "Store a value in the OS keyring (read from stdin if omitted)",
+		Long:  "Store a value in the OS keyring under name; use it in the config as \"keyring:<name>\".",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			value, err := argOrStdin(cmd, args[1:])
+			if err != nil {
+				return err
+			}
+			if err := config.KeyringSet(args[0], value); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Stored %s in the OS keyring; use %q in the config\n", args[0], config.KeyringRef(args[0]))
+			return nil
+		},
+	}
+	keyringConfigCmd := &cobra.Command{
+		Use:   "keyring-config",
+		Short: "Move every plaintext credential in config.json to the OS keyring",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, path, err := readJSONConfig("store values with \"picobot secrets keyring-set\" and refer to them as \"keyring:<name>\"")
+			if err != nil {
+				return err
+			}
+			n, err := config.StoreSecretsInKeyring(&cfg)
+			if n > 0 {
+				// keep the references to what was stored even if a later field failed
+				if err := config.SaveConfig(cfg, path); err != nil {
+					return err
+				}
+			}
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No plaintext credentials found.")
+				return nil
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Moved %d credential(s) from %s to the OS keyring\n", n, path)
+			return nil
+		},
+	}
+	secretsCmd.AddCommand(encryptCmd, encryptConfigCmd, keyringSetCmd, keyringConfigCmd)
+	return secretsCmd
+}
+
+// readJSONConfig reads the config file as it is on disk, without
+// environment overrides, for commands that rewrite it. Only JSON configs
+// are rewritten; for the others the error says what to do instead.
+func readJSONConfig(instead string) (config.Config, string, error) {
+	var cfg config.Config
+	path := config.ConfigPath()
+	if filepath.Ext(path) != ".json" {
+		return cfg, path, fmt.Errorf("%s: only JSON configs are rewritten; %s", path, instead)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, path, err
+	}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return cfg, path, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return cfg, path, nil
+}
+
+// argOrStdin returns the first of args, or else the first line of stdin.
+func argOrStdin(cmd *cobra.Command, args []string) (string, error) {
+	if len(args) > 0 {
+		return args[0], nil
+	}
+	line, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("read value: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
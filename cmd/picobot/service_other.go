@@ -0,0 +1,44 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kr0nicas/picobot/internal/config"
+)
+
+// newServiceCmd returns the "service" command. Service installation is only
+// built in on Windows; elsewhere it prints a systemd unit to adapt.
+func newServiceCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "service",
+		Short: "Print a systemd unit for running the gateway as a service",
+		Run: func(cmd *cobra.Command, args []string) {
+			exe, err := os.Executable()
+			if err != nil {
+				exe = "/usr/local/bin/picobot"
+			}
+			if p := config.ActiveProfile(); p != "" {
+				exe += " --profile " + p
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), `# Save as /etc/systemd/system/picobot.service, then:
+#   systemctl daemon-reload && systemctl enable --now picobot
+[Unit]
+Description=Picobot gateway
+After=network-online.target
+
+[Service]
+ExecStart=%s gateway
+Restart=on-failure
+User=%s
+
+[Install]
+WantedBy=multi-user.target
+`, exe, os.Getenv("USER"))
+		},
+	}
+}
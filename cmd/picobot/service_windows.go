@@ -0,0 +1,180 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+
+	"github.com/kr0nicas/picobot/internal/config"
+	"github.com/kr0nicas/picobot/internal/logging"
+)
+
+const serviceName = "picobot"
+
+// newServiceCmd returns the "service" command that installs and runs the
+// gateway as a Windows service.
+func newServiceCmd() *cobra.Command {
+	serviceCmd := &cobra.Command{
+		Use:   "service",
+		Short: "Install, remove, start or stop the gateway Windows service",
+	}
+
+	installCmd := &cobra.Command{
+		Use:   "install",
+		Short: "Install the gateway as an auto-start Windows service",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			home, _ := cmd.Flags().GetString("home")
+			if home == "" {
+				cfgPath, _, err := config.ResolveDefaultPaths()
+				if err != nil {
+					return err
+				}
+				home = filepath.Dir(cfgPath)
+			}
+			exe, err := os.Executable()
+			if err != nil {
+				return err
+			}
+			m, err := mgr.Connect()
+			if err != nil {
+				return fmt.Errorf("connect to service manager: %w", err)
+			}
+			defer m.Disconnect()
+			runArgs := []string{"service", "run", "--home", home}
+			if p := config.ActiveProfile(); p != "" {
+				runArgs = append(runArgs, "--profile", p)
+			}
+			s, err := m.CreateService(serviceName, exe, mgr.Config{
+				DisplayName: "Picobot gateway",
+				Description: "Picobot agent gateway (agent loop, channels, heartbeat)",
+				StartType:   mgr.StartAutomatic,
+			}, runArgs...)
+			if err != nil {
+				return fmt.Errorf("create service: %w", err)
+			}
+			defer s.Close()
+			fmt.Fprintf(cmd.OutOrStdout(), "Installed service %q (home: %s)\n", serviceName, home)
+			return nil
+		},
+	}
+	installCmd.Flags().String("home", "", "Picobot home directory (config.json + workspace) for the service; defaults to the current user's")
+
+	uninstallCmd := &cobra.Command{
+		Use:   "uninstall",
+		Short: "Remove the gateway Windows service",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withService(func(s *mgr.Service) error {
+				if err := s.Delete(); err != nil {
+					return fmt.Errorf("delete service: %w", err)
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Removed service %q\n", serviceName)
+				return nil
+			})
+		},
+	}
+
+	startCmd := &cobra.Command{
+		Use:   "start",
+		Short: "Start the gateway Windows service",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withService(func(s *mgr.Service) error {
+				return s.Start()
+			})
+		},
+	}
+
+	stopCmd := &cobra.Command{
+		Use:   "stop",
+		Short: "Stop the gateway Windows service",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withService(func(s *mgr.Service) error {
+				_, err := s.Control(svc.Stop)
+				return err
+			})
+		},
+	}
+
+	runCmd := &cobra.Command{
+		Use:    "run",
+		Short:  "Run the gateway under the Windows service manager",
+		Hidden: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			home, _ := cmd.Flags().GetString("home")
+			if home != "" {
+				os.Setenv("PICOBOT_HOME", home)
+				// services have no console; keep a log next to the config
+				if f, err := os.OpenFile(filepath.Join(home, "picobot.log"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644); err == nil {
+					log.SetOutput(f)
+				}
+			}
+			isService, err := svc.IsWindowsService()
+			if err != nil {
+				return err
+			}
+			if !isService {
+				return fmt.Errorf("not running under the service manager; use 'picobot gateway' instead")
+			}
+			return svc.Run(serviceName, &gatewayService{})
+		},
+	}
+	runCmd.Flags().String("home", "", "Picobot home directory")
+
+	serviceCmd.AddCommand(installCmd, uninstallCmd, startCmd, stopCmd, runCmd)
+	return serviceCmd
+}
+
+// withService opens the installed picobot service and calls fn with it.
+func withService(fn func(s *mgr.Service) error) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("connect to service manager: %w", err)
+	}
+	defer m.Disconnect()
+	s, err := m.OpenService(serviceName)
+	if err != nil {
+		return fmt.Errorf("open service %q: %w", serviceName, err)
+	}
+	defer s.Close()
+	return fn(s)
+}
+
+// gatewayService adapts runGateway to the Windows service control protocol.
+type gatewayService struct{}
+
+func (g *gatewayService) Execute(args []string, r <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		runGateway(ctx, "")
+		close(done)
+	}()
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	for c := range r {
+		switch c.Cmd {
+		case svc.Interrogate:
+			status <- c.CurrentStatus
+		case svc.Stop, svc.Shutdown:
+			status <- svc.Status{State: svc.StopPending}
+			cancel()
+			select {
+			case <-done:
+			case <-time.After(20 * time.Second):
+				logging.For("service").Warn("gateway did not stop in time")
+			}
+			return false, 0
+		}
+	}
+	cancel()
+	return false, 0
+}
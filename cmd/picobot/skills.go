@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kr0nicas/picobot/internal/agent/skills"
+	"github.com/kr0nicas/picobot/internal/config"
+	"github.com/kr0nicas/picobot/internal/logging"
+)
+
+// skillSync returns the git sync of the workspace's skills configured in
+// skills.gitRemote, or nil.
+func skillSync(cfg config.Config) *skills.GitSync {
+	if cfg.Skills.GitRemote == "" {
+		return nil
+	}
+	return skills.NewGitSync(filepath.Join(workspaceDir(cfg), "skills"), cfg.Skills.GitRemote, cfg.Skills.GitBranch)
+}
+
+// updateEmbeddedSkills upgrades the workspace's copies of the skills that ship
+// with picobot and logs what changed.
+func updateEmbeddedSkills(cfg config.Config) {
+	skillsLog := logging.For("skills")
+	dir := filepath.Join(workspaceDir(cfg), "skills")
+	if _, err := os.Stat(dir); err != nil {
+		return // not onboarded
+	}
+	u, err := config.UpdateEmbeddedSkills(dir)
+	if err != nil {
+		skillsLog.Error("updating built-in skills failed", "err", err)
+		return
+	}
+	for _, f := range u.Added {
+		skillsLog.Info("added built-in skill", "file", f)
+	}
+	for _, f := range u.Updated {
+		skillsLog.Info("updated built-in skill", "file", f)
+	}
+	for _, f := range u.Kept {
+		skillsLog.Warn("kept your edited skill; a newer version ships with picobot", "file", f)
+	}
+}
+
+func newSkillsCmd() *cobra.Command {
+	skillsCmd := &cobra.Command{
+		Use:   "skills",
+		Short: "Share skills between picobot instances",
+	}
+
+	exportCmd := &cobra.Command{
+		Use:   "export <name>",
+		Short: "Write a skill and its scripts as a .tar.gz",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadConfig()
+			if err != nil {
+				return err
+			}
+			out, _ := cmd.Flags().GetString("output")
+			if out == "" {
+				out = args[0] + ".tar.gz"
+			}
+			f, err := os.Create(out)
+			if err != nil {
+				return err
+			}
+			if err := skills.Export(filepath.Join(workspaceDir(cfg), "skills"), args[0], f); err != nil {
+				f.Close()
+				os.Remove(out)
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "exported skill %s to %s\n", args[0], out)
+			return nil
+		},
+	}
+	exportCmd.Flags().StringP("output", "o", "", "archive to write (default <name>.tar.gz)")
+
+	importCmd := &cobra.Command{
+		Use:   "import <file|url>",
+		Short: "Install a skill from a .tar.gz made by export",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadConfig()
+			if err != nil {
+				return err
+			}
+			r, err := openSkillArchive(args[0])
+			if err != nil {
+				return err
+			}
+			defer r.Close()
+			replace, _ := cmd.Flags().GetBool("replace")
+			skill, err := skills.Import(filepath.Join(workspaceDir(cfg), "skills"), r, replace)
+			if err != nil {
+				return err
+			}
+			if s := skillSync(cfg); s != nil {
+				if err := s.Commit(fmt.Sprintf("picobot: import skill %q", skill.Name)); err != nil {
+					return err
+				}
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "imported skill %s: %s\n", skill.Name, skill.Description)
+			return nil
+		},
+	}
+	importCmd.Flags().Bool("replace", false, "replace an installed skill of the same name")
+
+	syncCmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Merge the skills of skills.gitRemote and push local ones",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadConfig()
+			if err != nil {
+				return err
+			}
+			s := skillSync(cfg)
+			if s == nil {
+				return fmt.Errorf("skills.gitRemote is not set")
+			}
+			if err := s.Pull(); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "skills in %s synced with %s\n", s.Dir(), cfg.Skills.GitRemote)
+			return nil
+		},
+	}
+
+	skillsCmd.AddCommand(exportCmd, importCmd, syncCmd)
+	return skillsCmd
+}
+
+// openSkillArchive opens a local archive or downloads one over http(s).
+func openSkillArchive(source string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return os.Open(source)
+	}
+	client := &http.Client{Timeout: time.Minute}
+	resp, err := client.Get(source)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("download %s: HTTP %s", source, resp.Status)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(resp.Body, skills.MaxArchiveBytes), resp.Body}, nil
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/agent"
+	"github.com/kr0nicas/picobot/internal/agent/tools"
+	"github.com/kr0nicas/picobot/internal/calendar"
+	"github.com/kr0nicas/picobot/internal/config"
+	"github.com/kr0nicas/picobot/internal/moderation"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+// applyToolConfig applies the tools, access and moderation sections of the
+// config (and the tool related agent defaults) to the agent.
+func applyToolConfig(ag *agent.AgentLoop, cfg config.Config, provider providers.LLMProvider) error {
+	if err := applyExecProfiles(ag, cfg); err != nil {
+		return err
+	}
+	if cfg.Agents.Defaults.StrictSymlinks {
+		ag.SetStrictPaths(true)
+	}
+	ag.SetStaticToolDocs(cfg.Agents.Defaults.StaticToolDocs)
+	ag.SetApprovals(!cfg.Agents.Defaults.DisableApprovals, time.Duration(cfg.Agents.Defaults.ApprovalTimeoutS)*time.Second)
+	if err := applyApprovalRules(ag, cfg.Tools.Approvals); err != nil {
+		return err
+	}
+	ag.SetToolConcurrency(cfg.Agents.Defaults.MaxParallelTools, time.Duration(cfg.Agents.Defaults.ToolTimeoutS)*time.Second)
+	ag.SetToolResultLimit(cfg.Agents.Defaults.ToolResultMaxChars, cfg.Agents.Defaults.SummarizeToolResults)
+	if cfg.Agents.Defaults.InjectionClassifier {
+		ag.SetInjectionClassifier(agent.NewLLMInjectionClassifier(provider, ag.Model()))
+	}
+	if err := applyAccess(ag, cfg.Access); err != nil {
+		return err
+	}
+	if err := applyModeration(ag, cfg); err != nil {
+		return err
+	}
+	if len(cfg.Tools.Channels) > 0 {
+		scopes := make(tools.ScopePolicy, len(cfg.Tools.Channels))
+		for scope, f := range cfg.Tools.Channels {
+			scopes[scope] = tools.ToolFilter{Allow: f.Allow, Deny: f.Deny}
+		}
+		ag.SetToolScopes(scopes)
+	}
+	for i, mc := range cfg.Tools.Middleware {
+		m, err := toolMiddleware(ag, mc)
+		if err != nil {
+			return fmt.Errorf("tools.middleware[%d]: %w", i, err)
+		}
+		ag.UseToolMiddleware(tools.ForTools(mc.Tools, m))
+	}
+	web := cfg.Tools.Web
+	if len(web.AllowDomains) > 0 || len(web.DenyDomains) > 0 {
+		ag.SetDomainPolicy(tools.DomainPolicy{Allow: web.AllowDomains, Deny: web.DenyDomains})
+	}
+	if hc := cfg.Tools.HTTPRequest; hc.TimeoutS > 0 || hc.MaxResponseBytes > 0 {
+		ag.SetHTTPLimits(time.Duration(hc.TimeoutS)*time.Second, hc.MaxResponseBytes)
+	}
+	if sc := cfg.Tools.Search; sc.Backend != "" {
+		b, err := tools.NewSearchBackend(sc.Backend, sc.URL, sc.APIKey)
+		if err != nil {
+			return fmt.Errorf("tools.search: %w", err)
+		}
+		ag.SetSearchBackend(b)
+	}
+	if cc := cfg.Tools.Calendar; cc.Provider != "" {
+		if err := applyCalendar(ag, cc); err != nil {
+			return fmt.Errorf("tools.calendar: %w", err)
+		}
+	}
+	return nil
+}
+
+// applyCalendar enables the calendar tool.
+func applyCalendar(ag *agent.AgentLoop, cc config.CalendarConfig) error {
+	loc := time.Local
+	if cc.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(cc.Timezone); err != nil {
+			return err
+		}
+	}
+	var b calendar.Backend
+	var err error
+	switch cc.Provider {
+	case "caldav":
+		b, err = calendar.NewCalDAV(cc.URL, cc.Username, cc.Password, loc)
+	case "google":
+		b, err = calendar.NewGoogle(cc.CalendarID, cc.ClientID, cc.ClientSecret, cc.RefreshToken, loc)
+	default:
+		return fmt.Errorf("unknown provider %q (want caldav or google)", cc.Provider)
+	}
+	if err != nil {
+		return err
+	}
+	ag.SetCalendar(b, loc)
+	return nil
+}
+
+// applyApprovalRules compiles tools.approvals.
+func applyApprovalRules(ag *agent.AgentLoop, rules []config.ApprovalRuleConfig) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	out := make([]agent.ApprovalRule, 0, len(rules))
+	for i, rc := range rules {
+		if rc.Tool == "" {
+			return fmt.Errorf("tools.approvals[%d]: tool is required", i)
+		}
+		r := agent.ApprovalRule{Tool: rc.Tool, Args: make(map[string]*regexp.Regexp, len(rc.Args))}
+		for arg, pattern := range rc.Args {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("tools.approvals[%d].args.%s: %w", i, arg, err)
+			}
+			r.Args[arg] = re
+		}
+		out = append(out, r)
+	}
+	ag.SetApprovalRules(out)
+	return nil
+}
+
+// toolMiddleware builds the middleware described by mc.
+func toolMiddleware(ag *agent.AgentLoop, mc config.ToolMiddlewareConfig) (tools.ToolMiddleware, error) {
+	switch mc.Type {
+	case "log":
+		return tools.NewLogMiddleware(mc.RedactArgs), nil
+	case "rateLimit":
+		if mc.PerMinute <= 0 {
+			return nil, fmt.Errorf("rateLimit needs perMinute")
+		}
+		return tools.NewRateLimitMiddleware(mc.PerMinute), nil
+	case "confirm":
+		return tools.NewConfirmMiddleware(ag.AskUser), nil
+	}
+	return nil, fmt.Errorf("unknown middleware type %q", mc.Type)
+}
+
+// applyExecProfiles configures the agent's exec tool from agents.defaults.execProfile
+// and tools.exec. Without any exec settings the built-in standard profile is kept.
+func applyExecProfiles(ag *agent.AgentLoop, cfg config.Config) error {
+	ec := cfg.Tools.Exec
+	ag.SetExecLimits(ec.MaxConcurrent, ec.PassEnv)
+	if cfg.Agents.Defaults.ExecProfile == "" && len(ec.Channels) == 0 && len(ec.Profiles) == 0 {
+		return nil
+	}
+	name := cfg.Agents.Defaults.ExecProfile
+	if name == "" {
+		name = "standard"
+	}
+	def, err := resolveExecProfile(name, ec.Profiles)
+	if err != nil {
+		return err
+	}
+	perChannel := make(map[string]tools.ExecProfile, len(ec.Channels))
+	for channel, pname := range ec.Channels {
+		p, err := resolveExecProfile(pname, ec.Profiles)
+		if err != nil {
+			return fmt.Errorf("tools.exec.channels.%s: %w", channel, err)
+		}
+		perChannel[channel] = p
+	}
+	ag.SetExecProfiles(def, perChannel)
+	return nil
+}
+
+// resolveExecProfile returns the named profile, applying a custom definition
+// from config on top of its base profile when one exists.
+func resolveExecProfile(name string, custom map[string]config.ExecProfileConfig) (tools.ExecProfile, error) {
+	pc, ok := custom[name]
+	if !ok {
+		return tools.ExecProfileByName(name)
+	}
+	base := pc.Base
+	if base == "" {
+		base = name
+		if _, err := tools.ExecProfileByName(name); err != nil {
+			base = "standard"
+		}
+	}
+	p, err := tools.ExecProfileByName(base)
+	if err != nil {
+		return tools.ExecProfile{}, err
+	}
+	p.Name = name
+	if len(pc.Allow) > 0 {
+		p.Allow = pc.Allow
+	}
+	if len(pc.Deny) > 0 {
+		p.Deny = pc.Deny
+	}
+	switch pc.Policy {
+	case "":
+	case "allowlist":
+		p.Allowlist = true
+	case "denylist":
+		p.Allowlist = false
+		if len(pc.Allow) == 0 {
+			p.Allow = nil
+		}
+	default:
+		return tools.ExecProfile{}, fmt.Errorf("exec profile %q: unknown policy %q (want allowlist or denylist)", name, pc.Policy)
+	}
+	if len(pc.Args) > 0 {
+		p.Args = make(map[string]tools.ArgValidator, len(pc.Args))
+		for prog, ac := range pc.Args {
+			v := tools.ArgValidator{Subcommands: ac.Subcommands}
+			var err error
+			if v.Allow, err = compilePatterns(ac.Allow); err != nil {
+				return tools.ExecProfile{}, fmt.Errorf("exec profile %q: args.%s.allow: %w", name, prog, err)
+			}
+			if v.Deny, err = compilePatterns(ac.Deny); err != nil {
+				return tools.ExecProfile{}, fmt.Errorf("exec profile %q: args.%s.deny: %w", name, prog, err)
+			}
+			p.Args[prog] = v
+		}
+	}
+	if pc.AllowShellMeta != nil {
+		p.AllowShellMeta = *pc.AllowShellMeta
+	}
+	if pc.AllowInterpreters != nil {
+		p.AllowInterpreters = *pc.AllowInterpreters
+	}
+	if pc.Network != nil {
+		p.DenyNetwork = !*pc.Network
+	}
+	if len(pc.NetworkAllow) > 0 {
+		p.NetworkAllow = pc.NetworkAllow
+	}
+	if pc.TimeoutS > 0 {
+		p.Timeout = time.Duration(pc.TimeoutS) * time.Second
+	}
+	if pc.MaxOutputBytes > 0 {
+		p.MaxOutputBytes = pc.MaxOutputBytes
+	}
+	if pc.CPUSeconds > 0 {
+		p.CPUSeconds = pc.CPUSeconds
+	}
+	if pc.MemoryMB > 0 {
+		p.MemoryMB = pc.MemoryMB
+	}
+	switch pc.Sandbox {
+	case "":
+	case tools.SandboxWorkspace, tools.SandboxNone:
+		p.Sandbox = pc.Sandbox
+	case tools.SandboxContainer:
+		p.Sandbox = pc.Sandbox
+		// containers are offline unless the profile says otherwise
+		if pc.Network == nil {
+			p.DenyNetwork = true
+		}
+	default:
+		return tools.ExecProfile{}, fmt.Errorf("exec profile %q: unknown sandbox %q", name, pc.Sandbox)
+	}
+	if c := pc.Container; c != nil {
+		p.Container = tools.ContainerOptions{Runtime: c.Runtime, Image: c.Image, CPUs: c.CPUs, MemoryMB: c.MemoryMB}
+	}
+	if p.Sandbox == tools.SandboxContainer && p.Container.Image == "" {
+		return tools.ExecProfile{}, fmt.Errorf("exec profile %q: the container sandbox needs container.image", name)
+	}
+	return p, nil
+}
+
+// compilePatterns compiles a list of regular expressions.
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	out := make([]*regexp.Regexp, 0, len(patterns))
+	for _, s := range patterns {
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, re)
+	}
+	return out, nil
+}
+
+// applyModeration enables the outbound content filter from the moderation
+// section. It stays off when neither rules nor a provider are configured.
+func applyModeration(ag *agent.AgentLoop, cfg config.Config) error {
+	mc := cfg.Moderation
+	switch mc.Mode {
+	case "", agent.ModerationBlock, agent.ModerationFlag:
+	default:
+		return fmt.Errorf("moderation: unknown mode %q (want block or flag)", mc.Mode)
+	}
+	var chain moderation.Chain
+	if len(mc.Rules) > 0 {
+		rules, err := moderation.NewRules(mc.Rules)
+		if err != nil {
+			return err
+		}
+		chain = append(chain, rules)
+	}
+	switch mc.Provider {
+	case "":
+	case "openai":
+		if cfg.Providers.OpenAI == nil || cfg.Providers.OpenAI.APIKey == "" {
+			return fmt.Errorf("moderation: provider openai requires providers.openai.apiKey")
+		}
+		chain = append(chain, moderation.NewOpenAI(cfg.Providers.OpenAI.APIKey, cfg.Providers.OpenAI.APIBase, mc.Model))
+	default:
+		return fmt.Errorf("moderation: unknown provider %q", mc.Provider)
+	}
+	if len(chain) > 0 {
+		ag.SetModerator(chain, mc.Mode)
+	}
+	return nil
+}
+
+// applyAccess configures role-based access control from the access section.
+func applyAccess(ag *agent.AgentLoop, ac config.AccessConfig) error {
+	policy := tools.DefaultRolePolicy()
+	for role, grants := range ac.Roles {
+		policy[tools.Role(role)] = grants
+	}
+	known := func(role string) error {
+		if _, ok := policy[tools.Role(role)]; !ok {
+			return fmt.Errorf("access: unknown role %q", role)
+		}
+		return nil
+	}
+	if ac.DefaultRole != "" {
+		if err := known(ac.DefaultRole); err != nil {
+			return err
+		}
+	}
+	users := make(map[string]tools.Role, len(ac.Users))
+	for id, role := range ac.Users {
+		if err := known(role); err != nil {
+			return fmt.Errorf("%w (user %s)", err, id)
+		}
+		users[id] = tools.Role(role)
+	}
+	ag.SetAccess(tools.Role(ac.DefaultRole), users, policy)
+	return nil
+}
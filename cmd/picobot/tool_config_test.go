@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/agent/tools"
+	"github.com/kr0nicas/picobot/internal/config"
+)
+
+func TestResolveExecProfileCustom(t *testing.T) {
+	custom := map[string]config.ExecProfileConfig{
+		"ops":    {Base: "strict", Allow: []string{"ls", "git"}, TimeoutS: 10},
+		"strict": {TimeoutS: 5},
+	}
+	p, err := resolveExecProfile("ops", custom)
+	if err != nil {
+		t.Fatalf("resolve ops: %v", err)
+	}
+	if p.Name != "ops" || len(p.Allow) != 2 || p.Timeout != 10*time.Second || p.Sandbox != "workspace" {
+		t.Fatalf("unexpected ops profile: %+v", p)
+	}
+	p, err = resolveExecProfile("strict", custom)
+	if err != nil || p.Timeout != 5*time.Second || len(p.Allow) == 0 {
+		t.Fatalf("expected strict override to keep its allowlist, got %+v (%v)", p, err)
+	}
+	if _, err := resolveExecProfile("nope", custom); err == nil {
+		t.Fatalf("expected error for unknown profile")
+	}
+}
+
+func TestResolveExecProfileNetwork(t *testing.T) {
+	off := false
+	custom := map[string]config.ExecProfileConfig{
+		"offline": {Base: "standard", Network: &off, NetworkAllow: []string{"pip"}},
+	}
+	p, err := resolveExecProfile("offline", custom)
+	if err != nil {
+		t.Fatalf("resolve offline: %v", err)
+	}
+	if !p.DenyNetwork || len(p.NetworkAllow) != 1 {
+		t.Fatalf("expected network to be denied except for pip, got %+v", p)
+	}
+}
+
+func TestResolveExecProfileContainer(t *testing.T) {
+	custom := map[string]config.ExecProfileConfig{
+		"boxed":   {Sandbox: "container", Container: &config.ExecContainerConfig{Image: "alpine:3", MemoryMB: 256}},
+		"noimage": {Sandbox: "container"},
+	}
+	p, err := resolveExecProfile("boxed", custom)
+	if err != nil {
+		t.Fatalf("resolve boxed: %v", err)
+	}
+	if p.Sandbox != tools.SandboxContainer || !p.DenyNetwork || p.Container.Image != "alpine:3" {
+		t.Fatalf("expected an offline container profile, got %+v", p)
+	}
+	if _, err := resolveExecProfile("noimage", custom); err == nil {
+		t.Fatalf("expected error for a container profile without an image")
+	}
+}
+
+func TestResolveExecProfilePolicy(t *testing.T) {
+	custom := map[string]config.ExecProfileConfig{
+		"ci": {Policy: "allowlist", Allow: []string{"go", "git"}, Args: map[string]config.ExecArgsConfig{
+			"git": {Subcommands: []string{"status"}, Deny: []string{`^--upload-pack`}},
+		}},
+		"empty": {Policy: "allowlist"},
+		"bad":   {Args: map[string]config.ExecArgsConfig{"git": {Allow: []string{"("}}}},
+	}
+	p, err := resolveExecProfile("ci", custom)
+	if err != nil {
+		t.Fatalf("resolve ci: %v", err)
+	}
+	if !p.Allowlist || len(p.Args["git"].Deny) != 1 {
+		t.Fatalf("expected an allowlist with a git validator, got %+v", p)
+	}
+	if p, _ := resolveExecProfile("empty", custom); !p.Allowlist || len(p.Allow) != 0 {
+		t.Fatalf("expected an empty allowlist, got %+v", p)
+	}
+	if _, err := resolveExecProfile("bad", custom); err == nil {
+		t.Fatalf("expected error for an invalid pattern")
+	}
+}
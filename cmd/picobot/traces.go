@@ -0,0 +1,20 @@
+package main
+
+import (
+	"path/filepath"
+
+	"github.com/kr0nicas/picobot/internal/config"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+// recordTraces wraps p so its requests and responses are saved under
+// <workspace>/traces/ when agents.defaults.recordTraces is set. The returned
+// function closes the trace file.
+func recordTraces(cfg config.Config, p providers.LLMProvider) (providers.LLMProvider, func()) {
+	if !cfg.Agents.Defaults.RecordTraces {
+		return p, func() {}
+	}
+	rp := providers.NewRecordingProvider(p, filepath.Join(workspaceDir(cfg), "traces"))
+	gatewayLog.Info("recording provider traffic", "path", rp.Path())
+	return rp, func() { rp.Close() }
+}
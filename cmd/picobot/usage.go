@@ -0,0 +1,17 @@
+package main
+
+import (
+	"path/filepath"
+
+	"github.com/kr0nicas/picobot/internal/config"
+	"github.com/kr0nicas/picobot/internal/usage"
+)
+
+// openUsageLedger opens <workspace>/usage.jsonl with the configured prices.
+func openUsageLedger(cfg config.Config) (*usage.Ledger, error) {
+	prices := make(map[string]usage.Price, len(cfg.Usage.Prices))
+	for model, p := range cfg.Usage.Prices {
+		prices[model] = usage.Price{Input: p.Input, Output: p.Output}
+	}
+	return usage.Open(filepath.Join(workspaceDir(cfg), "usage.jsonl"), prices)
+}
@@ -0,0 +1,6 @@
+#!/usr/bin/env python3
+"""Greets the given name: a minimal helper script bundled with a skill."""
+import sys
+
+name = " ".join(sys.argv[1:]) or "world"
+print(f"Hello, {name}!")
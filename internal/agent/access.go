@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"github.com/kr0nicas/picobot/internal/agent/tools"
+	"github.com/kr0nicas/picobot/internal/chat"
+)
+
+// SetAccess configures role-based access control. users maps identities
+// ("channel:senderID", e.g. "telegram:8881234567") to roles; everyone else
+// gets defaultRole (owner if empty). policy, if non-nil, replaces the
+// built-in tool grants per role.
+func (a *AgentLoop) SetAccess(defaultRole tools.Role, users map[string]tools.Role, policy tools.RolePolicy) {
+	a.settingsMu.Lock()
+	a.defaultRole = defaultRole
+	a.userRoles = users
+	a.settingsMu.Unlock()
+	if policy != nil {
+		a.ConfigureTools(func(reg *tools.Registry) { reg.SetRolePolicy(policy) })
+	}
+}
+
+// SetToolScopes restricts tools per channel, group chats or single chats
+// (see tools.ScopePolicy). It applies to every tenant.
+func (a *AgentLoop) SetToolScopes(p tools.ScopePolicy) {
+	a.settingsMu.Lock()
+	a.scopePolicy = p
+	a.settingsMu.Unlock()
+	a.applyScopePolicy()
+}
+
+// toolScopes returns the scopes whose tool filters apply to msg: its
+// channel, "<channel>:group" for group chats, "<channel>:<chatID>" and
+// "agent:<name>" for the named agent serving the chat.
+func (a *AgentLoop) toolScopes(msg *chat.Inbound) []string {
+	if msg == nil {
+		return nil
+	}
+	scopes := []string{msg.Channel}
+	if g, _ := msg.Metadata["group"].(bool); g {
+		scopes = append(scopes, msg.Channel+":group")
+	}
+	return append(scopes, msg.Channel+":"+msg.ChatID, "agent:"+a.agentName(msg))
+}
+
+// roleFor returns the role of msg's sender. Internal sources (direct calls,
+// the local cli and heartbeat) act as owner. Scheduled jobs act with the role
+// of whoever scheduled them; jobs from before roles were recorded get the
+// role of the chat they belong to (in private chats, the user's ID).
+func (a *AgentLoop) roleFor(msg *chat.Inbound) tools.Role {
+	if msg == nil || msg.Channel == "cli" || msg.Channel == "heartbeat" {
+		return tools.RoleOwner
+	}
+	if msg.Scheduled != nil {
+		if msg.Scheduled.Role != "" {
+			return tools.Role(msg.Scheduled.Role)
+		}
+		return tools.RoleReadOnly
+	}
+	a.settingsMu.RLock()
+	defer a.settingsMu.RUnlock()
+	if r, ok := a.userRoles[msg.Channel+":"+msg.SenderID]; ok {
+		return r
+	}
+	if a.defaultRole != "" {
+		return a.defaultRole
+	}
+	return tools.RoleOwner
+}
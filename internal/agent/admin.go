@@ -0,0 +1,214 @@
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kr0nicas/picobot/internal/agent/tools"
+	"github.com/kr0nicas/picobot/internal/audit"
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/debug"
+)
+
+// HeartbeatControl is the subset of the heartbeat service used by /admin.
+type HeartbeatControl interface {
+	Pause()
+	Resume()
+	Paused() bool
+}
+
+// AdminHooks connects /admin commands to subsystems owned by the caller.
+// Any hook may be nil, in which case the corresponding command reports that
+// it is unavailable.
+type AdminHooks struct {
+	// ReloadConfig re-reads the config file and applies reloadable settings,
+	// returning a short summary of what changed.
+	ReloadConfig func() (string, error)
+	Heartbeat    HeartbeatControl
+	Logs         *debug.LogTail
+}
+
+const adminUsage = `Admin commands:
+/admin model [name] — show or switch the active model
+/admin tools — list tools and whether they are enabled
+/admin tool <name> on|off — enable or disable a tool
+/admin logs [n] — show the last n log lines (default 20)
+/admin reload — reload config from disk
+/admin heartbeat pause|resume|status — control the heartbeat
+/debug on|off|status — toggle verbose tracing`
+
+// SetAdmins replaces the admin identities ("channel:senderID", e.g.
+// "telegram:8881234567"). The local cli channel is always treated as admin.
+func (a *AgentLoop) SetAdmins(ids []string) {
+	a.settingsMu.Lock()
+	defer a.settingsMu.Unlock()
+	a.admins = make(map[string]bool, len(ids))
+	for _, id := range ids {
+		a.admins[id] = true
+	}
+}
+
+// SetAdminHooks connects /admin commands to the caller's subsystems.
+func (a *AgentLoop) SetAdminHooks(hooks AdminHooks) {
+	a.settingsMu.Lock()
+	defer a.settingsMu.Unlock()
+	a.adminHooks = hooks
+}
+
+// isAdmin reports whether the sender of msg may run admin commands: listed
+// admins and identities explicitly assigned the owner role.
+func (a *AgentLoop) isAdmin(msg chat.Inbound) bool {
+	if msg.Channel == "cli" {
+		return true
+	}
+	a.settingsMu.RLock()
+	defer a.settingsMu.RUnlock()
+	id := msg.Channel + ":" + msg.SenderID
+	return a.admins[id] || a.userRoles[id] == tools.RoleOwner
+}
+
+// adminCommand implements the /admin command family.
+func (a *AgentLoop) adminCommand(msg *chat.Inbound, args []string) string {
+	if len(args) == 0 || args[0] == "help" {
+		return adminUsage
+	}
+	a.settingsMu.RLock()
+	hooks := a.adminHooks
+	a.settingsMu.RUnlock()
+
+	switch strings.ToLower(args[0]) {
+	case "model":
+		if len(args) < 2 {
+			return fmt.Sprintf("Active model: %s", a.Model())
+		}
+		a.SetModel(args[1])
+		a.audit(audit.KindConfig, msg, "model "+args[1], nil)
+		return fmt.Sprintf("Switched model to %s.", args[1])
+
+	case "tools":
+		names := a.tenant.tools.Names()
+		var sb strings.Builder
+		sb.WriteString("Tools:\n")
+		for _, name := range names {
+			state := "on"
+			if !a.tenant.tools.Enabled(name) {
+				state = "off"
+			}
+			sb.WriteString(fmt.Sprintf("- %s: %s\n", name, state))
+		}
+		return sb.String()
+
+	case "tool":
+		if len(args) < 3 || (args[2] != "on" && args[2] != "off") {
+			return "Usage: /admin tool <name> on|off"
+		}
+		if err := a.SetToolEnabled(args[1], args[2] == "on"); err != nil {
+			return fmt.Sprintf("Failed: %v", err)
+		}
+		a.audit(audit.KindConfig, msg, "tool "+args[1]+" "+args[2], nil)
+		return fmt.Sprintf("Tool %s is now %s.", args[1], args[2])
+
+	case "logs":
+		if hooks.Logs == nil {
+			return "Log capture is not configured."
+		}
+		n := 20
+		if len(args) > 1 {
+			if v, err := strconv.Atoi(args[1]); err == nil && v > 0 {
+				n = v
+			}
+		}
+		lines := hooks.Logs.Tail(n)
+		if len(lines) == 0 {
+			return "No log lines captured yet."
+		}
+		return strings.Join(lines, "\n")
+
+	case "reload":
+		if hooks.ReloadConfig == nil {
+			return "Config reload is not available."
+		}
+		summary, err := hooks.ReloadConfig()
+		if err != nil {
+			return fmt.Sprintf("Reload failed: %v", err)
+		}
+		a.audit(audit.KindConfig, msg, "reload: "+summary, nil)
+		return "Config reloaded. " + summary
+
+	case "heartbeat":
+		if hooks.Heartbeat == nil {
+			return "Heartbeat is not running."
+		}
+		sub := "status"
+		if len(args) > 1 {
+			sub = strings.ToLower(args[1])
+		}
+		switch sub {
+		case "pause":
+			hooks.Heartbeat.Pause()
+			a.audit(audit.KindConfig, msg, "heartbeat pause", nil)
+			return "Heartbeat paused."
+		case "resume":
+			hooks.Heartbeat.Resume()
+			a.audit(audit.KindConfig, msg, "heartbeat resume", nil)
+			return "Heartbeat resumed."
+		case "status":
+			if hooks.Heartbeat.Paused() {
+				return "Heartbeat is paused."
+			}
+			return "Heartbeat is running."
+		}
+		return "Usage: /admin heartbeat pause|resume|status"
+	}
+	return adminUsage
+}
+
+// Model returns the model currently used for chat requests.
+func (a *AgentLoop) Model() string {
+	a.settingsMu.RLock()
+	defer a.settingsMu.RUnlock()
+	return a.model
+}
+
+// SetModel switches the model used for subsequent chat requests.
+func (a *AgentLoop) SetModel(model string) {
+	a.settingsMu.Lock()
+	defer a.settingsMu.Unlock()
+	a.model = model
+}
+
+// SetToolEnabled enables or disables a tool in every tenant's registry.
+// The setting also applies to tenants created later.
+func (a *AgentLoop) SetToolEnabled(name string, enabled bool) error {
+	if err := a.tenant.tools.SetEnabled(name, enabled); err != nil {
+		return err
+	}
+	a.settingsMu.Lock()
+	if enabled {
+		delete(a.disabledTools, name)
+	} else {
+		a.disabledTools[name] = true
+	}
+	a.settingsMu.Unlock()
+
+	a.tenantsMu.Lock()
+	defer a.tenantsMu.Unlock()
+	for _, t := range a.tenants {
+		_ = t.tools.SetEnabled(name, enabled)
+	}
+	return nil
+}
+
+// DisabledTools returns the names of tools disabled at runtime, sorted.
+func (a *AgentLoop) DisabledTools() []string {
+	a.settingsMu.RLock()
+	defer a.settingsMu.RUnlock()
+	out := make([]string, 0, len(a.disabledTools))
+	for name := range a.disabledTools {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
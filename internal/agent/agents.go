@@ -0,0 +1,265 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/kr0nicas/picobot/internal/agent/tools"
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+// defaultAgent is the name of the agent of the main workspace in routes.
+const defaultAgent = "default"
+
+// NamedAgent is an agent with its own workspace (and so persona, memory
+// and skills), model and tools, that chats can be routed to.
+type NamedAgent struct {
+	Name        string
+	Description string // what the agent is for, shown to the router
+	Workspace   string // created from the main workspace's bootstrap files if missing
+	Model       string // "" uses the chat model
+	Tools       tools.ToolFilter
+}
+
+// namedAgent is a NamedAgent with the tenant serving its workspace.
+type namedAgent struct {
+	NamedAgent
+	tenant *tenant
+}
+
+// agentModelKey is the ctx value holding the model of the named agent a
+// request is for.
+type agentModelKey struct{}
+
+// SetAgents adds named agents next to the default one. routes maps a
+// channel ("discord") or one chat ("telegram:-1001234") to an agent name or
+// "default"; a chat's route wins over its channel's. With router, the model
+// of TaskRoute picks the agent of every message from a chat without a
+// route, and the choice sticks until it picks another.
+func (a *AgentLoop) SetAgents(agents []NamedAgent, routes map[string]string, router bool) error {
+	byName := make(map[string]*namedAgent, len(agents))
+	for _, na := range agents {
+		if na.Name == "" || na.Name == defaultAgent || unsafeTenantChars.MatchString(na.Name) {
+			return fmt.Errorf("agent: invalid agent name %q: use letters, digits, - and _", na.Name)
+		}
+		if err := initTenantWorkspace(a.tenant.workspace, na.Workspace); err != nil {
+			return fmt.Errorf("agent %s: %w", na.Name, err)
+		}
+		t, err := a.newTenant(na.Workspace)
+		if err != nil {
+			return fmt.Errorf("agent %s: %w", na.Name, err)
+		}
+		byName[na.Name] = &namedAgent{NamedAgent: na, tenant: t}
+	}
+	for scope, name := range routes {
+		if _, ok := byName[name]; !ok && name != defaultAgent {
+			return fmt.Errorf("agent: route %q: unknown agent %q", scope, name)
+		}
+	}
+	chats, err := loadChatAgents(chatAgentsPath(a.tenant.workspace))
+	if err != nil {
+		agentLog.Warn("loading routed chats failed", "err", err)
+	}
+
+	a.tenantsMu.Lock()
+	for name, ag := range byName {
+		a.tenants["agent:"+name] = ag.tenant
+	}
+	a.agents, a.agentRoutes, a.agentRouter, a.chatAgents = byName, routes, router, chats
+	a.tenantsMu.Unlock()
+	a.applyScopePolicy()
+	return nil
+}
+
+// agentForLocked returns the named agent serving a chat, or nil for the
+// default agent. The heartbeat always runs on the default agent. The caller
+// holds tenantsMu.
+func (a *AgentLoop) agentForLocked(channel, chatID string) *namedAgent {
+	if channel == "heartbeat" || len(a.agents) == 0 {
+		return nil
+	}
+	name, ok := a.routeLocked(channel, chatID)
+	if !ok {
+		name = a.chatAgents[channel+":"+chatID]
+	}
+	return a.agents[name]
+}
+
+// routeLocked returns the agent a route sends a chat to, if one does.
+func (a *AgentLoop) routeLocked(channel, chatID string) (string, bool) {
+	if name, ok := a.agentRoutes[channel+":"+chatID]; ok {
+		return name, true
+	}
+	name, ok := a.agentRoutes[channel]
+	return name, ok
+}
+
+// agentName returns the name of the agent serving msg's chat.
+func (a *AgentLoop) agentName(msg *chat.Inbound) string {
+	a.tenantsMu.Lock()
+	defer a.tenantsMu.Unlock()
+	if ag := a.agentForLocked(msg.Channel, msg.ChatID); ag != nil {
+		return ag.Name
+	}
+	return defaultAgent
+}
+
+// routeAgent picks the agent of msg's chat, asking the router about user
+// messages from chats without a route, and returns ctx with the agent's
+// model.
+func (a *AgentLoop) routeAgent(ctx context.Context, msg *chat.Inbound) context.Context {
+	a.tenantsMu.Lock()
+	_, routed := a.routeLocked(msg.Channel, msg.ChatID)
+	ask := a.agentRouter && !routed && len(a.agents) > 0
+	a.tenantsMu.Unlock()
+	if ask && msg.Channel != "heartbeat" && msg.Scheduled == nil && msg.Button == nil && commandName(msg.Content) == "" {
+		a.pickAgent(ctx, msg)
+	}
+
+	a.tenantsMu.Lock()
+	defer a.tenantsMu.Unlock()
+	if ag := a.agentForLocked(msg.Channel, msg.ChatID); ag != nil && ag.Model != "" {
+		ctx = context.WithValue(ctx, agentModelKey{}, ag.Model)
+	}
+	return ctx
+}
+
+const routerPrompt = `You route a user's message to one of these assistants:
+%s
+The chat is currently with %q. Keep it for follow-ups, answers and anything that fits it; switch only when another assistant is clearly better suited. Reply with the assistant's name only.`
+
+// pickAgent has the model of TaskRoute choose the agent for msg and records
+// the choice for its chat.
+func (a *AgentLoop) pickAgent(ctx context.Context, msg *chat.Inbound) {
+	a.tenantsMu.Lock()
+	current := a.chatAgents[msg.Channel+":"+msg.ChatID]
+	if _, ok := a.agents[current]; !ok {
+		current = defaultAgent
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "- %s: general assistant\n", defaultAgent)
+	for _, name := range slices.Sorted(maps.Keys(a.agents)) {
+		fmt.Fprintf(&sb, "- %s: %s\n", name, a.agents[name].Description)
+	}
+	a.tenantsMu.Unlock()
+
+	messages := []providers.Message{
+		{Role: "system", Content: fmt.Sprintf(routerPrompt, sb.String(), current)},
+		{Role: "user", Content: clipRunes(msg.Content, 2000)},
+	}
+	resp, err := taskProvider{a, TaskRoute}.Chat(ctx, messages, nil, "")
+	if err != nil {
+		agentLog.Warn("routing failed", "channel", msg.Channel, "chat_id", msg.ChatID, "err", err)
+		return
+	}
+	reply := strings.Trim(strings.TrimSpace(resp.Content), "\"'`.*")
+
+	a.tenantsMu.Lock()
+	defer a.tenantsMu.Unlock()
+	name := ""
+	for n := range a.agents {
+		if strings.EqualFold(n, reply) {
+			name = n
+		}
+	}
+	if strings.EqualFold(reply, defaultAgent) {
+		name = defaultAgent
+	}
+	if name == "" {
+		agentLog.Warn("router picked unknown agent", "agent", clipRunes(reply, 40))
+		return
+	}
+	if name == current {
+		return
+	}
+	agentLog.Info("routing chat to agent", "channel", msg.Channel, "chat_id", msg.ChatID, "agent", name)
+	a.chatAgents[msg.Channel+":"+msg.ChatID] = name
+	if err := saveChatAgents(chatAgentsPath(a.tenant.workspace), a.chatAgents); err != nil {
+		agentLog.Error("saving routed chats failed", "err", err)
+	}
+}
+
+// chatAgentsPath returns the file remembering the router's choices.
+func chatAgentsPath(workspace string) string {
+	return filepath.Join(workspace, "state", "agents.json")
+}
+
+// loadChatAgents reads the router's choices per chat; a missing file is an
+// empty map.
+func loadChatAgents(path string) (map[string]string, error) {
+	chats := make(map[string]string)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return chats, nil
+	}
+	if err != nil {
+		return chats, err
+	}
+	if err := json.Unmarshal(data, &chats); err != nil {
+		return make(map[string]string), fmt.Errorf("%s: %w", path, err)
+	}
+	return chats, nil
+}
+
+// saveChatAgents writes the router's choices atomically.
+func saveChatAgents(path string, chats map[string]string) error {
+	data, err := json.MarshalIndent(chats, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// workspaces returns the main workspace and those of the named agents.
+func (a *AgentLoop) workspaces() []string {
+	a.tenantsMu.Lock()
+	defer a.tenantsMu.Unlock()
+	ws := []string{a.tenant.workspace}
+	for _, name := range slices.Sorted(maps.Keys(a.agents)) {
+		ws = append(ws, a.agents[name].tenant.workspace)
+	}
+	return ws
+}
+
+// modelFor returns the model that handles task for the request of ctx: the
+// model of its named agent for replies, otherwise ModelFor.
+func (a *AgentLoop) modelFor(ctx context.Context, task string) string {
+	if m, _ := ctx.Value(agentModelKey{}).(string); m != "" && task == TaskChat {
+		return m
+	}
+	return a.ModelFor(task)
+}
+
+// applyScopePolicy gives every registry the tool scopes of SetToolScopes
+// together with the tool filters of the named agents, as "agent:<name>"
+// scopes.
+func (a *AgentLoop) applyScopePolicy() {
+	a.settingsMu.RLock()
+	p := maps.Clone(a.scopePolicy)
+	a.settingsMu.RUnlock()
+	if p == nil {
+		p = make(tools.ScopePolicy)
+	}
+	a.tenantsMu.Lock()
+	for name, ag := range a.agents {
+		if len(ag.Tools.Allow) > 0 || len(ag.Tools.Deny) > 0 {
+			p["agent:"+name] = ag.Tools
+		}
+	}
+	a.tenantsMu.Unlock()
+	a.ConfigureTools(func(reg *tools.Registry) { reg.SetScopePolicy(p) })
+}
@@ -0,0 +1,291 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/agent/tools"
+	"github.com/kr0nicas/picobot/internal/audit"
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+const defaultApprovalTimeout = 5 * time.Minute
+
+// pendingQuestion is a question waiting for the user's answer.
+type pendingQuestion struct {
+	id       string
+	senderID string
+	options  []string          // answers offered as buttons
+	aliases  map[string]string // lowercased typed replies -> answer
+	anyText  bool              // other typed replies are answers too, except commands
+	reply    chan string
+}
+
+// approvalBroker tracks at most one pending question (approval or confirm
+// prompt) per chat. The Run loop offers every inbound message to resolve
+// before queueing it, so a reply can unblock a tool call that is waiting
+// inside processMessage.
+type approvalBroker struct {
+	mu      sync.Mutex
+	pending map[string]*pendingQuestion // keyed by channel:chatID
+	seq     int
+}
+
+func newApprovalBroker() *approvalBroker {
+	return &approvalBroker{pending: make(map[string]*pendingQuestion)}
+}
+
+var (
+	approveWords = []string{"yes", "y", "approve", "approved", "ok"}
+	denyWords    = []string{"no", "n", "deny", "denied", "cancel"}
+)
+
+// errQuestionPending is returned when a chat already has an open question.
+var errQuestionPending = errors.New("another question is already pending in this chat")
+
+// answerData is the button payload for option i of question id.
+func answerData(id string, i int) string {
+	return fmt.Sprintf("answer:%s:%d", id, i)
+}
+
+// resolve delivers msg to a pending question in its chat, if it is an
+// answer from the user who was asked: a button press for the question or a
+// typed reply matching an option. It reports whether msg was consumed.
+func (b *approvalBroker) resolve(msg chat.Inbound) bool {
+	key := msg.Channel + ":" + msg.ChatID
+	b.mu.Lock()
+	p, ok := b.pending[key]
+	b.mu.Unlock()
+	if !ok || (p.senderID != "" && msg.SenderID != p.senderID) {
+		return false
+	}
+	data := msg.Content
+	if msg.Button != nil {
+		data = msg.Button.Data
+	}
+	answer := ""
+	if rest, ok := strings.CutPrefix(data, "answer:"+p.id+":"); ok {
+		if i, err := strconv.Atoi(rest); err == nil && i >= 0 && i < len(p.options) {
+			answer = p.options[i]
+		}
+	} else {
+		answer = p.aliases[strings.ToLower(strings.TrimSpace(data))]
+		if answer == "" && p.anyText && msg.Button == nil && commandName(data) == "" {
+			answer = strings.TrimSpace(data)
+		}
+	}
+	if answer == "" {
+		return false
+	}
+	b.mu.Lock()
+	delete(b.pending, key)
+	b.mu.Unlock()
+	p.reply <- answer
+	return true
+}
+
+// ask publishes prompt with one button per option in msg's chat and blocks
+// until the asking user answers, timeout passes or ctx is canceled. Typed
+// replies matching an option (case-insensitively) or one of aliases count
+// as answers too, and with anyText so does any other typed reply that is
+// not a command.
+func (a *AgentLoop) ask(ctx context.Context, msg *chat.Inbound, prompt string, options []string, aliases map[string]string, anyText bool, timeout time.Duration) (string, error) {
+	b := a.approvals
+	key := msg.Channel + ":" + msg.ChatID
+	b.mu.Lock()
+	if _, busy := b.pending[key]; busy {
+		b.mu.Unlock()
+		return "", errQuestionPending
+	}
+	b.seq++
+	p := &pendingQuestion{id: strconv.Itoa(b.seq), senderID: msg.SenderID, options: options,
+		aliases: make(map[string]string), anyText: anyText, reply: make(chan string, 1)}
+	for _, o := range options {
+		p.aliases[strings.ToLower(o)] = o
+	}
+	for k, v := range aliases {
+		p.aliases[k] = v
+	}
+	b.pending[key] = p
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		if b.pending[key] == p {
+			delete(b.pending, key)
+		}
+		b.mu.Unlock()
+	}()
+
+	var row []chat.Button
+	for i, o := range options {
+		row = append(row, chat.Button{Text: o, Data: answerData(p.id, i)})
+	}
+	a.publish(chat.Outbound{Channel: msg.Channel, ChatID: msg.ChatID, Content: prompt, Buttons: [][]chat.Button{row}})
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case answer := <-p.reply:
+		return answer, nil
+	case <-timer.C:
+		return "", context.DeadlineExceeded
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// SetApprovals enables or disables approval prompts for destructive tool
+// calls and sets how long to wait for an answer (0 = default 5m). Unanswered
+// requests are denied.
+func (a *AgentLoop) SetApprovals(enabled bool, timeout time.Duration) {
+	a.settingsMu.Lock()
+	defer a.settingsMu.Unlock()
+	a.approvalsOff = !enabled
+	a.approvalTimeout = timeout
+}
+
+// ApprovalRule makes calls of Tool need the user's approval, like the
+// destructive calls tools report themselves. With Args, only calls whose
+// arguments all match their patterns do; non-string arguments are matched
+// in their JSON form.
+type ApprovalRule struct {
+	Tool string
+	Args map[string]*regexp.Regexp
+}
+
+func (r ApprovalRule) matches(tc providers.ToolCall) bool {
+	if r.Tool != tc.Name {
+		return false
+	}
+	for name, re := range r.Args {
+		v, ok := tc.Arguments[name]
+		if !ok {
+			return false
+		}
+		s, ok := v.(string)
+		if !ok {
+			b, _ := json.Marshal(v)
+			s = string(b)
+		}
+		if !re.MatchString(s) {
+			return false
+		}
+	}
+	return true
+}
+
+// SetApprovalRules adds approval prompts for the tool calls rules match.
+func (a *AgentLoop) SetApprovalRules(rules []ApprovalRule) {
+	a.settingsMu.Lock()
+	defer a.settingsMu.Unlock()
+	a.approvalRules = rules
+}
+
+// approvalAction describes what tc, made for msg's chat, would do if it
+// needs the user's approval, or returns "" if it doesn't.
+func (a *AgentLoop) approvalAction(t *tenant, msg *chat.Inbound, tc providers.ToolCall) string {
+	if !t.tools.Enabled(tc.Name) {
+		return "" // refused anyway
+	}
+	tool := t.tools.Get(tc.Name)
+	if ar, ok := tool.(tools.ApprovalRequirer); ok {
+		if action := ar.RequiresApproval(tc.Arguments); action != "" {
+			return action
+		}
+	}
+	if ar, ok := tool.(tools.ChatApprovalRequirer); ok && msg != nil {
+		if action := ar.RequiresApprovalIn(msg.Channel, msg.ChatID, tc.Arguments); action != "" {
+			return action
+		}
+	}
+	a.settingsMu.RLock()
+	rules := a.approvalRules
+	a.settingsMu.RUnlock()
+	for _, r := range rules {
+		if r.matches(tc) {
+			args, _ := json.Marshal(tools.ShownArgs(t.tools.Get(tc.Name), tc.Arguments))
+			detail := string(args)
+			if len(detail) > 300 {
+				detail = detail[:300] + "..."
+			}
+			return "run with " + detail
+		}
+	}
+	return ""
+}
+
+// requestApproval asks the user in msg's chat to approve action and blocks
+// until they answer, the request times out, or ctx is canceled. It returns
+// whether the action may proceed and, if not, why.
+func (a *AgentLoop) requestApproval(ctx context.Context, msg *chat.Inbound, toolName, action string) (bool, string) {
+	a.settingsMu.RLock()
+	off, timeout := a.approvalsOff, a.approvalTimeout
+	a.settingsMu.RUnlock()
+	if off {
+		return true, ""
+	}
+	if msg == nil || msg.Channel == "heartbeat" || msg.Scheduled != nil {
+		return false, fmt.Sprintf("%s requires user approval (%s), which is not available in this context", toolName, action)
+	}
+	if timeout <= 0 {
+		timeout = defaultApprovalTimeout
+	}
+
+	aliases := make(map[string]string)
+	for _, w := range approveWords {
+		aliases[w] = "Approve"
+	}
+	for _, w := range denyWords {
+		aliases[w] = "Deny"
+	}
+	prompt := fmt.Sprintf("Approval needed: %s wants to %s.\nReply \"yes\" to approve or \"no\" to deny (expires in %s).", toolName, action, timeout)
+	answer, err := a.ask(ctx, msg, prompt, []string{"Approve", "Deny"}, aliases, false, timeout)
+	switch {
+	case errors.Is(err, errQuestionPending):
+		return false, "another approval is already pending in this chat"
+	case errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil:
+		a.publish(chat.Outbound{Channel: msg.Channel, ChatID: msg.ChatID, Content: "Approval request expired; the action was not performed."})
+		return false, "approval timed out; the action was not performed"
+	case err != nil:
+		return false, "canceled while waiting for approval"
+	}
+	ok := answer == "Approve"
+	a.audit(audit.KindApproval, msg, fmt.Sprintf("%s: %s", toolName, action), map[string]interface{}{"approved": ok})
+	if !ok {
+		return false, "the user denied this action"
+	}
+	return true, ""
+}
+
+// inboundKey carries the message being processed to tools that talk back to
+// its sender, such as confirm.
+type inboundKey struct{}
+
+// AskUser implements tools.AskFunc for the message in ctx: it asks the
+// sender to pick one of options, for tools and tool middleware.
+func (a *AgentLoop) AskUser(ctx context.Context, question string, options []string) (string, error) {
+	msg, _ := ctx.Value(inboundKey{}).(*chat.Inbound)
+	if msg == nil || msg.Channel == "heartbeat" || msg.Scheduled != nil {
+		return "", errors.New("there is no user to ask in this context")
+	}
+	a.settingsMu.RLock()
+	timeout := a.approvalTimeout
+	a.settingsMu.RUnlock()
+	if timeout <= 0 {
+		timeout = defaultApprovalTimeout
+	}
+	prompt := fmt.Sprintf("%s\nReply with one of: %s.", question, strings.Join(options, ", "))
+	answer, err := a.ask(ctx, msg, prompt, options, nil, false, timeout)
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		return "", errors.New("the user did not answer in time")
+	}
+	return answer, err
+}
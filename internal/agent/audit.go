@@ -0,0 +1,132 @@
+package agent
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/agent/tools"
+	"github.com/kr0nicas/picobot/internal/audit"
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+// SetAuditLog attaches the tamper-evident log of privileged operations.
+func (a *AgentLoop) SetAuditLog(l *audit.Log) {
+	a.auditLog = l
+}
+
+// SetToolLog attaches the log of every tool call.
+func (a *AgentLoop) SetToolLog(l *audit.ToolLog) {
+	a.toolLog = l
+	if l != nil {
+		a.ConfigureTools(func(reg *tools.Registry) { reg.Register(tools.NewToolLogTool(l.Dir())) })
+	}
+}
+
+// maxLoggedArg bounds the string arguments kept in the tool call log.
+const maxLoggedArg = 1000
+
+// logToolCall adds tc, made for msg (nil for direct calls), to the tool call
+// log with its arguments redacted.
+func (a *AgentLoop) logToolCall(t *tenant, msg *chat.Inbound, tc providers.ToolCall, started time.Time, resultBytes int, outcome string, err error) {
+	if a.toolLog == nil {
+		return
+	}
+	c := audit.ToolCall{Time: started, Tool: tc.Name, ResultBytes: resultBytes, DurationMS: time.Since(started).Milliseconds(), Outcome: outcome}
+	if msg != nil {
+		c.Channel, c.ChatID, c.Sender = msg.Channel, msg.ChatID, msg.SenderID
+	}
+	if args, err := json.Marshal(clipArgs(tools.ShownArgs(t.tools.Get(tc.Name), tc.Arguments))); err == nil {
+		if clean := a.redactor.Redact(string(args)); json.Valid([]byte(clean)) {
+			c.Args = json.RawMessage(clean)
+		}
+	}
+	if err != nil {
+		c.Error = clipRunes(a.redactor.Redact(err.Error()), maxLoggedArg)
+	}
+	if err := a.toolLog.Record(c); err != nil {
+		agentLog.Error("writing audit log failed", "err", err)
+	}
+}
+
+// clipArgs returns a copy of args with long strings shortened, such as the
+// content of written files.
+func clipArgs(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return clipRunes(v, maxLoggedArg)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			out[k] = clipArgs(e)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			out[i] = clipArgs(e)
+		}
+		return out
+	}
+	return v
+}
+
+// audit records a privileged operation performed on behalf of msg's sender
+// (msg is nil for direct calls).
+func (a *AgentLoop) audit(kind string, msg *chat.Inbound, action string, detail map[string]interface{}) {
+	actor := "system"
+	if msg != nil {
+		actor = msg.Channel + ":" + msg.SenderID
+	}
+	if err := a.auditLog.Record(kind, actor, a.redactor.Redact(action), detail); err != nil {
+		agentLog.Error("writing audit log failed", "err", err)
+	}
+}
+
+// privilegedAction describes a tool call that must be audited, or returns
+// "" for read-only calls.
+func privilegedAction(name string, args map[string]interface{}) (kind, action string) {
+	switch name {
+	case "exec":
+		return audit.KindExec, "run " + cmdString(args)
+	case "job_kill":
+		id, _ := args["id"].(string)
+		return audit.KindExec, "kill " + id
+	case "filesystem":
+		act, _ := args["action"].(string)
+		switch act {
+		case "read", "list", "glob", "grep", "":
+			return "", ""
+		case "move":
+			from, _ := args["path"].(string)
+			to, _ := args["to"].(string)
+			return audit.KindFile, "move " + from + " to " + to
+		}
+		path, _ := args["path"].(string)
+		return audit.KindFile, act + " " + path
+	case "write_memory":
+		target, _ := args["target"].(string)
+		return audit.KindFile, "write memory " + target
+	case "create_skill", "delete_skill":
+		skill, _ := args["name"].(string)
+		return audit.KindFile, strings.Replace(name, "_", " ", 1) + " " + skill
+	}
+	return "", ""
+}
+
+// cmdString renders the exec tool's "cmd" argument as a single string.
+func cmdString(args map[string]interface{}) string {
+	switch v := args["cmd"].(type) {
+	case string:
+		return v
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, p := range v {
+			s, _ := p.(string)
+			parts = append(parts, s)
+		}
+		return strings.Join(parts, " ")
+	}
+	return "(invalid cmd)"
+}
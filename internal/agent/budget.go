@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+// TaskBudget limits what the agent may spend on one request, besides the
+// iteration cap. Zero fields are unlimited.
+type TaskBudget struct {
+	Tokens int           // prompt and completion tokens reported by the provider
+	Cost   float64       // USD, from the prices of the usage ledger
+	Time   time.Duration // since the request arrived
+}
+
+// SetTaskBudget sets the budget of each request. A request that uses it up
+// is paused: its sender gets a summary of the progress and is asked whether
+// to continue with a fresh budget of the same size. Heartbeat and cron
+// requests are stopped.
+func (a *AgentLoop) SetTaskBudget(b TaskBudget) {
+	a.settingsMu.Lock()
+	defer a.settingsMu.Unlock()
+	a.taskBudget = b
+}
+
+// taskSpend is what a request has used of its budget.
+type taskSpend struct {
+	tokens  int
+	cost    float64
+	started time.Time
+}
+
+func newTaskSpend() taskSpend { return taskSpend{started: time.Now()} }
+
+// add counts the usage of a request to model.
+func (s *taskSpend) add(a *AgentLoop, model string, u providers.Usage) {
+	s.tokens += u.PromptTokens + u.CompletionTokens
+	s.cost += a.usage.Cost(model, u.PromptTokens, u.CompletionTokens)
+}
+
+// exceeded describes the limit of b that s has reached, or returns "".
+func (b TaskBudget) exceeded(s taskSpend) string {
+	switch {
+	case b.Tokens > 0 && s.tokens >= b.Tokens:
+		return fmt.Sprintf("%d tokens", b.Tokens)
+	case b.Cost > 0 && s.cost >= b.Cost:
+		return fmt.Sprintf("$%.2f", b.Cost)
+	case b.Time > 0 && time.Since(s.started) >= b.Time:
+		return b.Time.String()
+	}
+	return ""
+}
+
+// budgetUsedUp tells msg's sender that their request used up its budget,
+// limit, with a summary of steps, and asks whether to go on. It returns
+// whether to, or else the reply that ends the request.
+func (a *AgentLoop) budgetUsedUp(ctx context.Context, msg *chat.Inbound, steps []providers.Message, limit string) (bool, string) {
+	agentLog.Warn("task reached its budget", "channel", msg.Channel, "chat_id", msg.ChatID, "limit", limit)
+	summary, err := a.summarizeTask(ctx, &taskState{Request: msg.Content, Steps: steps})
+	if err != nil {
+		agentLog.Error("summarizing task failed", "err", err)
+		summary = stoppedReply(steps)
+	}
+	stopped := fmt.Sprintf("%s\n\n(Stopped: the task reached its budget of %s.)", summary, limit)
+	if msg.Channel == "heartbeat" || msg.Scheduled != nil {
+		return false, stopped
+	}
+
+	a.settingsMu.RLock()
+	timeout := a.approvalTimeout
+	a.settingsMu.RUnlock()
+	if timeout <= 0 {
+		timeout = defaultApprovalTimeout
+	}
+	aliases := make(map[string]string)
+	for _, w := range approveWords {
+		aliases[w] = "Continue"
+	}
+	for _, w := range denyWords {
+		aliases[w] = "Stop"
+	}
+	aliases["stop"] = "Stop"
+	prompt := fmt.Sprintf("This task has used its budget of %s. So far:\n%s\n\nContinue with another %s? Reply \"yes\" or \"no\".", limit, summary, limit)
+	answer, err := a.ask(ctx, msg, prompt, []string{"Continue", "Stop"}, aliases, false, timeout)
+	if errors.Is(err, errQuestionPending) {
+		return false, stopped
+	}
+	return err == nil && answer == "Continue", stopped
+}
@@ -0,0 +1,134 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/agent/tools"
+	"github.com/kr0nicas/picobot/internal/audit"
+	"github.com/kr0nicas/picobot/internal/chat"
+)
+
+// handleCommand intercepts slash commands that are handled locally without
+// calling the LLM. It returns the reply text and true if msg was a command.
+func (a *AgentLoop) handleCommand(msg chat.Inbound) (string, bool) {
+	fields := strings.Fields(strings.TrimSpace(msg.Content))
+	switch commandName(msg.Content) {
+	case "/reset":
+		return a.resetCommand(msg), true
+	case "/status":
+		return a.statusCommand(msg), true
+	case "/memory":
+		return a.memoryCommand(msg), true
+	case "/usage":
+		return a.usageCommand(), true
+	case "/stop":
+		// a running task was stopped before msg was queued
+		return "There is no running task to stop.", true
+	case "/model":
+		if len(fields) < 2 {
+			return fmt.Sprintf("Active model: %s", a.Model()), true
+		}
+		if !a.isAdmin(msg) {
+			return notAdminReply, true
+		}
+		return a.adminCommand(&msg, []string{"model", fields[1]}), true
+	case "/debug":
+		if !a.isAdmin(msg) {
+			return notAdminReply, true
+		}
+		return a.debugCommand(&msg, fields[1:]), true
+	case "/admin":
+		if !a.isAdmin(msg) {
+			return notAdminReply, true
+		}
+		return a.adminCommand(&msg, fields[1:]), true
+	}
+	return "", false
+}
+
+// commandName returns the slash command content starts with, lower-cased,
+// or "".
+func commandName(content string) string {
+	fields := strings.Fields(strings.TrimSpace(content))
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return ""
+	}
+	// Telegram appends the bot's name in groups: /status@picobot_bot
+	cmd, _, _ := strings.Cut(strings.ToLower(fields[0]), "@")
+	return cmd
+}
+
+const notAdminReply = "This command is restricted to admins."
+
+// debugCommand implements "/debug on|off|status".
+func (a *AgentLoop) debugCommand(msg *chat.Inbound, args []string) string {
+	if a.tracer == nil {
+		return "Debug tracing is not configured."
+	}
+	sub := "status"
+	if len(args) > 0 {
+		sub = strings.ToLower(args[0])
+	}
+	switch sub {
+	case "on", "off":
+		if err := a.tracer.SetEnabled(sub == "on"); err != nil {
+			return fmt.Sprintf("Failed to switch debug tracing %s: %v", sub, err)
+		}
+		a.audit(audit.KindConfig, msg, "debug "+sub, nil)
+		return fmt.Sprintf("Debug tracing %s (log: %s).", sub, a.tracer.Path())
+	case "status":
+		state := "off"
+		if a.tracer.Enabled() {
+			state = "on"
+		}
+		return fmt.Sprintf("Debug tracing is %s (log: %s).", state, a.tracer.Path())
+	default:
+		return "Usage: /debug on|off|status"
+	}
+}
+
+// resetCommand clears the conversation history of msg's chat. Long-term
+// memory and notes are kept.
+func (a *AgentLoop) resetCommand(msg chat.Inbound) string {
+	t, err := a.tenantFor(msg.Channel, msg.ChatID)
+	if err != nil {
+		return "Sorry, I couldn't open your workspace."
+	}
+	session := t.sessions.GetOrCreate(msg.Channel, msg.ChatID)
+	session.Clear()
+	if sp, ok := t.tools.Get("scratchpad").(*tools.ScratchpadTool); ok {
+		sp.Clear(msg.Channel, msg.ChatID)
+	}
+	if err := t.sessions.Save(session); err != nil {
+		agentLog.Error("reset: saving session failed", "err", err)
+	}
+	return "Conversation history cleared. Memory and notes are kept."
+}
+
+// statusCommand reports uptime, the active model and the chat's history size.
+func (a *AgentLoop) statusCommand(msg chat.Inbound) string {
+	history := 0
+	if t, err := a.tenantFor(msg.Channel, msg.ChatID); err == nil {
+		history = len(t.sessions.GetOrCreate(msg.Channel, msg.ChatID).GetHistory())
+	}
+	return fmt.Sprintf("Up %s\nModel: %s\nHistory: %d messages in this chat",
+		time.Since(a.startedAt).Round(time.Second), a.Model(), history)
+}
+
+// memoryCommand shows today's notes.
+func (a *AgentLoop) memoryCommand(msg chat.Inbound) string {
+	t, err := a.tenantFor(msg.Channel, msg.ChatID)
+	if err != nil {
+		return "Sorry, I couldn't open your workspace."
+	}
+	notes, err := t.memory.ReadToday()
+	if err != nil {
+		return fmt.Sprintf("Failed to read today's notes: %v", err)
+	}
+	if strings.TrimSpace(notes) == "" {
+		return "No notes for today yet."
+	}
+	return notes
+}
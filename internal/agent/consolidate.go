@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"context"
+	"time"
+)
+
+// consolidateTimeout bounds one consolidation run of a workspace.
+const consolidateTimeout = 10 * time.Minute
+
+// ConsolidateMemory folds the daily notes older than keepDays into MEMORY.md,
+// with the model of TaskSummarize, in the default workspace and every open
+// tenant's, and archives them.
+func (a *AgentLoop) ConsolidateMemory(ctx context.Context, keepDays int) error {
+	var firstErr error
+	for _, t := range a.allTenants() {
+		tctx, cancel := context.WithTimeout(ctx, consolidateTimeout)
+		res, err := t.memory.Consolidate(tctx, taskProvider{a, TaskSummarize}, "", keepDays)
+		cancel()
+		if len(res.Archived) > 0 {
+			agentLog.Info("consolidated daily notes into MEMORY.md", "notes", len(res.Archived), "workspace", t.workspace, "lines_before", res.Before, "lines_after", res.After)
+		}
+		if err != nil {
+			agentLog.Error("consolidating memory failed", "workspace", t.workspace, "err", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// RunMemoryConsolidation calls ConsolidateMemory every day at the time of
+// day at (an offset from midnight) in loc, until ctx is done.
+func (a *AgentLoop) RunMemoryConsolidation(ctx context.Context, at time.Duration, loc *time.Location, keepDays int) {
+	for {
+		timer := time.NewTimer(time.Until(nextDaily(time.Now().In(loc), at)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			a.ConsolidateMemory(ctx, keepDays)
+		}
+	}
+}
+
+// nextDaily returns the first time after now that is at past midnight.
+func nextDaily(now time.Time, at time.Duration) time.Time {
+	y, m, d := now.Date()
+	next := time.Date(y, m, d, 0, 0, 0, 0, now.Location()).Add(at)
+	if !next.After(now) {
+		next = time.Date(y, m, d+1, 0, 0, 0, 0, now.Location()).Add(at)
+	}
+	return next
+}
+
+// memorySweepInterval is how often expired memories are deleted. Queries
+// hide them as soon as they expire.
+const memorySweepInterval = time.Hour
+
+// sweepMemories deletes expired memories of every open tenant every
+// memorySweepInterval, until ctx is done.
+func (a *AgentLoop) sweepMemories(ctx context.Context) {
+	ticker := time.NewTicker(memorySweepInterval)
+	defer ticker.Stop()
+	for {
+		for _, t := range a.allTenants() {
+			expired, err := t.memory.Sweep(time.Now())
+			if err != nil {
+				agentLog.Error("sweeping expired memories failed", "workspace", t.workspace, "err", err)
+			} else if len(expired) > 0 {
+				agentLog.Info("forgot expired memories", "count", len(expired), "workspace", t.workspace)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// allTenants returns the default tenant and the open per-chat ones.
+func (a *AgentLoop) allTenants() []*tenant {
+	tenants := []*tenant{a.tenant}
+	a.tenantsMu.Lock()
+	defer a.tenantsMu.Unlock()
+	for _, t := range a.tenants {
+		tenants = append(tenants, t)
+	}
+	return tenants
+}
@@ -0,0 +1,19 @@
+package agent
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextDaily(t *testing.T) {
+	loc := time.FixedZone("X", 3600)
+	at := 3*time.Hour + 30*time.Minute
+	now := time.Date(2026, 5, 1, 2, 0, 0, 0, loc)
+	if got := nextDaily(now, at); !got.Equal(time.Date(2026, 5, 1, 3, 30, 0, 0, loc)) {
+		t.Errorf("before the time: %v", got)
+	}
+	now = time.Date(2026, 5, 31, 3, 30, 0, 0, loc)
+	if got := nextDaily(now, at); !got.Equal(time.Date(2026, 6, 1, 3, 30, 0, 0, loc)) {
+		t.Errorf("at the time: %v", got)
+	}
+}
@@ -1,15 +1,20 @@
 package agent
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/kr0nicas/picobot/internal/agent/memory"
+	"github.com/kr0nicas/picobot/internal/providers"
+	"github.com/kr0nicas/picobot/internal/session"
 )
 
 func TestBuildMessagesIncludesMemories(t *testing.T) {
 	cb := NewContextBuilder(".", memory.NewSimpleRanker(), 5)
-	history := []string{"user: hi"}
+	history := []session.Message{{Role: "user", Content: "hi"}, {Role: "assistant", Content: "hello there"}}
 	mems := []memory.MemoryItem{{Kind: "short", Text: "remember this"}, {Kind: "long", Text: "big fact"}}
 	memCtx := "Long-term memory: important fact"
 	msgs := cb.BuildMessages(history, "hello", "telegram", "123", memCtx, mems)
@@ -38,4 +43,172 @@ func TestBuildMessagesIncludesMemories(t *testing.T) {
 	if !foundSummary {
 		t.Fatalf("expected memory summary to be present in messages: %v", msgs)
 	}
+	// history keeps its roles and precedes the current message
+	n := len(msgs)
+	if msgs[n-3].Role != "user" || msgs[n-3].Content != "hi" || msgs[n-2].Role != "assistant" || msgs[n-1].Content != "hello" {
+		t.Fatalf("unexpected history replay: %v", msgs[n-3:])
+	}
+}
+
+func TestBuildMessagesWithinTrimsOldestFirst(t *testing.T) {
+	cb := NewContextBuilder(t.TempDir(), nil, 5)
+	var history []session.Message
+	for i := 0; i < 20; i++ {
+		history = append(history,
+			session.Message{Role: "user", Content: fmt.Sprintf("question %d %s", i, strings.Repeat("x", 400))},
+			session.Message{Role: "assistant", Content: fmt.Sprintf("answer %d %s", i, strings.Repeat("y", 400))})
+	}
+	mems := []memory.MemoryItem{{Kind: "short", Text: "first"}, {Kind: "short", Text: "second"}}
+	full := cb.BuildMessages(history, "now", "cli", "direct", "notes", mems)
+	size := providers.EstimateMessageTokens(full)
+
+	// a little less room: only the oldest turns go
+	msgs := cb.BuildMessagesWithin(size-300, "", history, "now", "cli", "direct", "notes", mems)
+	if got := providers.EstimateMessageTokens(msgs); got > size-300 {
+		t.Fatalf("prompt of %d tokens exceeds budget %d", got, size-300)
+	}
+	joined := fmt.Sprint(msgs)
+	if strings.Contains(joined, "question 0 ") || !strings.Contains(joined, "question 19 ") {
+		t.Fatalf("expected the oldest turns to be dropped first: %v", msgs)
+	}
+	if !strings.Contains(joined, "Memory:\nnotes") || !strings.Contains(joined, "second") {
+		t.Fatalf("memories should be kept while history can be trimmed: %v", msgs)
+	}
+	n := len(msgs)
+	if msgs[n-1].Content != "now" {
+		t.Fatalf("current message must be last, got %q", msgs[n-1].Content)
+	}
+
+	// no room: only the fixed system prompt and the current message remain
+	msgs = cb.BuildMessagesWithin(1, "", history, "now", "cli", "direct", "notes", mems)
+	for _, m := range msgs[:len(msgs)-1] {
+		if m.Role != "system" || strings.HasPrefix(m.Content, "Memory:") || strings.HasPrefix(m.Content, "Relevant memories:") {
+			t.Fatalf("expected everything optional to be trimmed, got %v", msgs)
+		}
+	}
+}
+
+func TestBuildMessagesToolDocsReplaceToolsMD(t *testing.T) {
+	ws := t.TempDir()
+	os.WriteFile(filepath.Join(ws, "TOOLS.md"), []byte("### removed_tool"), 0o644)
+	cb := NewContextBuilder(ws, nil, 5)
+
+	has := func(msgs []providers.Message, s string) bool {
+		for _, m := range msgs {
+			if strings.Contains(m.Content, s) {
+				return true
+			}
+		}
+		return false
+	}
+	msgs := cb.BuildMessagesWithin(0, "## Tools\n\n### scratchpad\n", nil, "now", "cli", "direct", "", nil)
+	if has(msgs, "removed_tool") || !has(msgs, "### scratchpad") {
+		t.Fatalf("generated docs should replace TOOLS.md: %v", msgs)
+	}
+	if msgs = cb.BuildMessages(nil, "now", "cli", "direct", "", nil); !has(msgs, "removed_tool") {
+		t.Fatal("TOOLS.md should be used without generated docs")
+	}
+}
+
+func TestBuildMessagesSelectsSkills(t *testing.T) {
+	ws := t.TempDir()
+	for name, fm := range map[string]string{
+		"weather": "triggers: [forecast]",
+		"rules":   "always_load: true",
+		"poems":   "",
+	} {
+		os.MkdirAll(filepath.Join(ws, "skills", name), 0o755)
+		os.WriteFile(filepath.Join(ws, "skills", name, "SKILL.md"), []byte("---\nname: "+name+"\ndescription: about "+name+"\n"+fm+"\n---\n\n"+name+" instructions"), 0o644)
+	}
+	cb := NewContextBuilder(ws, nil, 5)
+	skillsPrompt := func(history []session.Message, msg string) string {
+		for _, m := range cb.BuildMessages(history, msg, "cli", "direct", "", nil) {
+			if strings.HasPrefix(m.Content, "Available Skills:") {
+				return m.Content
+			}
+		}
+		t.Fatal("no skills in prompt")
+		return ""
+	}
+
+	got := skillsPrompt(nil, "write me a haiku")
+	if !strings.Contains(got, "rules instructions") || strings.Contains(got, "weather instructions") || !strings.Contains(got, "- weather: about weather") {
+		t.Fatalf("unexpected skills: %s", got)
+	}
+	// a follow-up keeps the skill the previous message triggered
+	history := []session.Message{{Role: "user", Content: "forecast for Rome?"}, {Role: "assistant", Content: "Sunny."}}
+	if got = skillsPrompt(history, "and tomorrow?"); !strings.Contains(got, "weather instructions") || strings.Contains(got, "poems instructions") {
+		t.Fatalf("unexpected skills: %s", got)
+	}
+}
+
+func TestBuildMessagesReplaysToolCalls(t *testing.T) {
+	cb := NewContextBuilder(t.TempDir(), nil, 5)
+	call := session.ToolCall{ID: "c1", Name: "web", Arguments: map[string]interface{}{"url": "https://example.com"}}
+	history := []session.Message{
+		{Role: "tool", Content: "orphaned result", ToolCallID: "c0"},
+		{Role: "user", Content: "what's on example.com?"},
+		{Role: "assistant", ToolCalls: []session.ToolCall{call}},
+		{Role: "tool", Content: "Example Domain", ToolCallID: "c1"},
+		{Role: "assistant", Content: "It says Example Domain."},
+	}
+	msgs := cb.BuildMessages(history, "and the title?", "telegram", "1", "", nil)
+	replay := msgs[len(msgs)-5:]
+	want := []string{"user", "assistant", "tool", "assistant", "user"}
+	for i, m := range replay {
+		if m.Role != want[i] {
+			t.Fatalf("message %d is %q, want %q: %+v", i, m.Role, want[i], replay)
+		}
+	}
+	if tc := replay[1].ToolCalls; len(tc) != 1 || tc[0].ID != "c1" || tc[0].Arguments["url"] != "https://example.com" {
+		t.Fatalf("tool call not replayed: %+v", replay[1])
+	}
+	if replay[2].ToolCallID != "c1" || replay[2].Content != "Example Domain" {
+		t.Fatalf("tool result not replayed: %+v", replay[2])
+	}
+}
+
+func TestBuildMessagesFollowsPromptProfile(t *testing.T) {
+	ws := t.TempDir()
+	for name, content := range map[string]string{
+		"SOUL.md":   "You are Gio.",
+		"AGENTS.md": "Long agent instructions.",
+		"USER.md":   "The user is Ana.",
+		"STYLE.md":  "Answer in one sentence.",
+		"PROMPT_OVERRIDES.md": "Notes for myself.\n" +
+			"## USER.md\nThe user is Ana, a nurse.\n" +
+			"## memoryTool @sms\n" +
+			"## SOUL.md @sms\nYou are Gio. Reply in plain text.\n",
+	} {
+		if err := os.WriteFile(filepath.Join(ws, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cb := NewContextBuilder(ws, nil, 5)
+	cb.SetProfiles(PromptProfile{Omit: []string{"AGENTS.md"}}, map[string]PromptProfile{
+		"sms": {Sections: []string{"STYLE.md", "SOUL.md", "USER.md", SectionMemoryTool, SectionMemory}},
+	})
+	system := func(channel string) []string {
+		var out []string
+		for _, m := range cb.BuildMessages(nil, "hi", channel, "1", "Likes tea.", nil)[1:] {
+			if m.Role == "system" {
+				out = append(out, strings.SplitN(m.Content, "\n", 2)[0])
+			}
+		}
+		return out
+	}
+
+	got := strings.Join(system("telegram"), " | ")
+	want := `## SOUL.md | The user is Ana, a nurse. | You are operating on channel="telegram" chatID="1". The tools provided with this conversation are the ones this user may use here; if a request needs a tool you don't have, say it isn't available in this chat. Always use your tools when the user asks you to perform actions (file operations, shell commands, web fetches, etc.). | If you decide something should be remembered, call the tool 'write_memory' with JSON arguments: {"target": "today"|"long", "content": "...", "append": true|false}. Use a tool call rather than plain chat text when writing memory. | Memory:`
+	if got != want {
+		t.Fatalf("unexpected default prompt:\n%s", got)
+	}
+	got = strings.Join(system("sms"), " | ")
+	if got != "## STYLE.md | You are Gio. Reply in plain text. | The user is Ana, a nurse. | Memory:" {
+		t.Fatalf("unexpected sms prompt:\n%s", got)
+	}
+
+	if err := (PromptProfile{Sections: []string{"persona"}}).validate(); err == nil {
+		t.Fatal("an unknown section was accepted")
+	}
 }
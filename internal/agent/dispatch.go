@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+)
+
+// defaultConcurrentChats is how many chats are handled at once by default.
+const defaultConcurrentChats = 4
+
+// maxQueuedPerChat bounds the messages of one chat waiting for its worker.
+const maxQueuedPerChat = 100
+
+// SetConcurrency sets how many chats the agent works on at once (0 = default
+// 4). Messages of one chat are always handled one after another, in order.
+// It takes effect when Run starts.
+func (a *AgentLoop) SetConcurrency(chats int) {
+	a.settingsMu.Lock()
+	defer a.settingsMu.Unlock()
+	a.concurrentChats = chats
+}
+
+// dispatch queues msg for the worker of its chat, starting one if the chat
+// has none. It never blocks, so the hub keeps being read while workers wait
+// for approvals or run long tasks.
+func (a *AgentLoop) dispatch(ctx context.Context, slots chan struct{}, msg chat.Inbound) {
+	key := msg.Channel + ":" + msg.ChatID
+	a.dispatchMu.Lock()
+	queue, busy := a.queued[key]
+	if busy && len(queue) >= maxQueuedPerChat {
+		a.dispatchMu.Unlock()
+		agentLog.Warn("dropping message, queue is full", "chat", key, "waiting", len(queue))
+		a.publish(chat.Outbound{Channel: msg.Channel, ChatID: msg.ChatID, Content: "I'm still working through your earlier messages; please send this one again later."})
+		return
+	}
+	if busy {
+		a.queued[key] = append(queue, msg)
+		a.dispatchMu.Unlock()
+		return
+	}
+	a.queued[key] = nil
+	a.dispatchMu.Unlock()
+	go a.chatWorker(ctx, slots, key, msg)
+}
+
+// chatWorker handles msg and then the messages queued for its chat behind
+// it, each while holding one of slots. It exits once the queue is empty.
+func (a *AgentLoop) chatWorker(ctx context.Context, slots chan struct{}, key string, msg chat.Inbound) {
+	for {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		a.processMessage(ctx, msg)
+		<-slots
+
+		a.dispatchMu.Lock()
+		queue := a.queued[key]
+		if len(queue) == 0 {
+			delete(a.queued, key)
+			a.dispatchMu.Unlock()
+			return
+		}
+		msg, a.queued[key] = queue[0], queue[1:]
+		a.dispatchMu.Unlock()
+	}
+}
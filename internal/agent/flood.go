@@ -0,0 +1,60 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+)
+
+// SetInboundGuard enables flood protection for messages arriving from
+// channels. A nil guard disables it.
+func (a *AgentLoop) SetInboundGuard(g *chat.Guard) {
+	a.settingsMu.Lock()
+	defer a.settingsMu.Unlock()
+	a.guard = g
+}
+
+// screenInbound applies the inbound guard to msg and reports whether it
+// should be processed. Newly muted senders are told once and the admins are
+// notified.
+func (a *AgentLoop) screenInbound(msg *chat.Inbound) bool {
+	a.settingsMu.RLock()
+	g := a.guard
+	a.settingsMu.RUnlock()
+	if g == nil {
+		return true
+	}
+	switch g.Check(msg) {
+	case chat.DropDuplicate:
+		agentLog.Info("inbound guard: dropped duplicate message", "channel", msg.Channel, "sender", msg.SenderID)
+		return false
+	case chat.DropMuted:
+		return false
+	case chat.MuteStarted:
+		agentLog.Warn("inbound guard: muted flooding sender", "channel", msg.Channel, "sender", msg.SenderID, "for", g.MuteFor())
+		a.publish(chat.Outbound{Channel: msg.Channel, ChatID: msg.ChatID,
+			Content: fmt.Sprintf("You're sending messages too quickly. I'll ignore new messages for %s.", g.MuteFor())})
+		a.notifyAdmins(fmt.Sprintf("Inbound guard: muted %s:%s (chat %s) for %s after a message flood.", msg.Channel, msg.SenderID, msg.ChatID, g.MuteFor()))
+		return false
+	}
+	return true
+}
+
+// notifyAdmins sends text to every configured admin identity. Admin IDs are
+// user IDs, which double as private chat IDs on the supported channels.
+func (a *AgentLoop) notifyAdmins(text string) {
+	a.settingsMu.RLock()
+	ids := make([]string, 0, len(a.admins))
+	for id := range a.admins {
+		ids = append(ids, id)
+	}
+	a.settingsMu.RUnlock()
+	for _, id := range ids {
+		channel, chatID, ok := strings.Cut(id, ":")
+		if !ok {
+			continue
+		}
+		a.publish(chat.Outbound{Channel: channel, ChatID: chatID, Content: text})
+	}
+}
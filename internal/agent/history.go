@@ -0,0 +1,67 @@
+package agent
+
+import (
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/providers"
+	"github.com/kr0nicas/picobot/internal/session"
+)
+
+// maxRecordedResult bounds the tool results kept in session history; the
+// model gets the whole result while it works on the request.
+const maxRecordedResult = 2000
+
+// historyMessages replays session history to the model as it happened:
+// user messages, replies, and the tool calls of earlier requests with their
+// results. Results of calls that are not in history are left out, as
+// providers reject them.
+func historyMessages(history []session.Message) []providers.Message {
+	var out []providers.Message
+	called := make(map[string]bool)
+	for _, h := range history {
+		switch {
+		case h.Role == "tool":
+			if !called[h.ToolCallID] {
+				continue
+			}
+			delete(called, h.ToolCallID)
+			out = append(out, providers.Message{Role: "tool", Content: h.Content, ToolCallID: h.ToolCallID})
+		case len(h.ToolCalls) > 0:
+			m := providers.Message{Role: h.Role, Content: h.Content}
+			for _, tc := range h.ToolCalls {
+				called[tc.ID] = true
+				m.ToolCalls = append(m.ToolCalls, providers.ToolCall{ID: tc.ID, Name: tc.Name, Arguments: tc.Arguments, ThoughtSignature: tc.ThoughtSignature})
+			}
+			out = append(out, m)
+		case h.Content != "":
+			out = append(out, providers.Message{Role: h.Role, Content: h.Content})
+		}
+	}
+	return out
+}
+
+// recordSteps adds the tool calls and results of a request to its session,
+// with long results shortened.
+func recordSteps(s *session.Session, steps []providers.Message) {
+	for _, m := range steps {
+		switch {
+		case m.Role == "tool":
+			s.Add(session.Message{Role: "tool", Content: clipRunes(m.Content, maxRecordedResult), ToolCallID: m.ToolCallID})
+		case m.Role == "assistant" && len(m.ToolCalls) > 0:
+			sm := session.Message{Role: "assistant", Content: m.Content}
+			for _, tc := range m.ToolCalls {
+				sm.ToolCalls = append(sm.ToolCalls, session.ToolCall{ID: tc.ID, Name: tc.Name, Arguments: tc.Arguments, ThoughtSignature: tc.ThoughtSignature})
+			}
+			s.Add(sm)
+		}
+	}
+}
+
+// internalResult replaces the current message at the end of messages with a
+// call of msg.Internal's tool and msg's content as its result, so the model
+// gets the content as a tool result rather than as the user's words.
+func internalResult(messages []providers.Message, msg chat.Inbound) []providers.Message {
+	in := msg.Internal
+	call := providers.ToolCall{ID: in.CallID, Name: in.Tool, Arguments: in.Args}
+	messages[len(messages)-1] = providers.Message{Role: "assistant", ToolCalls: []providers.ToolCall{call}}
+	return append(messages, providers.Message{Role: "tool", Content: msg.Content, ToolCallID: in.CallID})
+}
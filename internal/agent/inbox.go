@@ -0,0 +1,118 @@
+package agent
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+// inboxDir is the workspace folder that receives files users attach.
+const inboxDir = "inbox"
+
+// receiveMedia moves the files attached to msg into t's inbox folder and
+// appends a note with their workspace-relative paths to msg.Content, so the
+// model can open them with the filesystem tool. msg.Media is rewritten to
+// the new locations.
+func (a *AgentLoop) receiveMedia(t *tenant, msg *chat.Inbound) {
+	if len(msg.Media) == 0 {
+		return
+	}
+	dir := filepath.Join(t.workspace, inboxDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		agentLog.Error("creating inbox failed", "err", err)
+		return
+	}
+	var saved, notes []string
+	for _, src := range msg.Media {
+		name, err := moveIntoDir(src, dir)
+		if err != nil {
+			agentLog.Error("storing attachment failed", "file", src, "err", err)
+			notes = append(notes, fmt.Sprintf("[The user attached %s, but it could not be saved.]", filepath.Base(src)))
+			continue
+		}
+		saved = append(saved, filepath.Join(dir, name))
+		notes = append(notes, fmt.Sprintf("[The user attached a file, saved in the workspace as %s/%s. Use the filesystem tool to work with it.]", inboxDir, name))
+	}
+	msg.Media = saved
+	msg.Content = strings.TrimSpace(msg.Content + "\n" + strings.Join(notes, "\n"))
+}
+
+// maxImageBytes is the largest image sent to the model; providers reject
+// bigger ones (Anthropic's limit is 5 MB).
+const maxImageBytes = 5 << 20
+
+// loadImages reads the images among paths (JPEG, PNG, GIF and WebP) so they
+// can be sent to the model. Other files and oversized or unreadable images
+// are skipped; the model still sees their inbox paths.
+func loadImages(paths []string) []providers.Image {
+	var images []providers.Image
+	for _, p := range paths {
+		typ := mime.TypeByExtension(strings.ToLower(filepath.Ext(p)))
+		switch typ {
+		case "image/jpeg", "image/png", "image/gif", "image/webp":
+		default:
+			continue
+		}
+		if fi, err := os.Stat(p); err != nil || fi.Size() > maxImageBytes {
+			agentLog.Warn("not sending image to the model: missing or too large", "file", filepath.Base(p), "max_bytes", maxImageBytes)
+			continue
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			agentLog.Warn("reading image failed", "file", p, "err", err)
+			continue
+		}
+		images = append(images, providers.Image{MediaType: typ, Data: data})
+	}
+	return images
+}
+
+// moveIntoDir moves src into dir under a name that does not clash with an
+// existing file and returns that name. The temporary directory src was
+// downloaded into is removed once empty.
+func moveIntoDir(src, dir string) (string, error) {
+	base := filepath.Base(src)
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	name := base
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(filepath.Join(dir, name)); os.IsNotExist(err) {
+			break
+		}
+		name = fmt.Sprintf("%s_%d%s", stem, i, ext)
+	}
+	dst := filepath.Join(dir, name)
+	if err := os.Rename(src, dst); err != nil {
+		// different filesystems: copy, then remove the original
+		if err := copyFile(src, dst); err != nil {
+			return "", err
+		}
+		os.Remove(src)
+	}
+	os.Remove(filepath.Dir(src))
+	return name, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
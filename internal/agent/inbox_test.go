@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+func TestReceiveMedia(t *testing.T) {
+	ws := t.TempDir()
+	ag := newTestLoop(t, chat.NewHub(1), &overwriteProvider{}, "test", 1, ws, nil)
+	os.MkdirAll(filepath.Join(ws, "inbox"), 0o755)
+	os.WriteFile(filepath.Join(ws, "inbox", "report.pdf"), []byte("old"), 0o644)
+
+	src := filepath.Join(t.TempDir(), "report.pdf")
+	os.WriteFile(src, []byte("new"), 0o644)
+	msg := chat.Inbound{Channel: "telegram", ChatID: "1", Content: "summarize", Media: []string{src}}
+	ag.receiveMedia(ag.tenant, &msg)
+
+	want := filepath.Join(ws, "inbox", "report_1.pdf")
+	if len(msg.Media) != 1 || msg.Media[0] != want {
+		t.Fatalf("expected media at %s, got %v", want, msg.Media)
+	}
+	if data, _ := os.ReadFile(want); string(data) != "new" {
+		t.Fatalf("unexpected content %q", data)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Fatalf("expected source to be moved")
+	}
+	if !strings.HasPrefix(msg.Content, "summarize\n") || !strings.Contains(msg.Content, "inbox/report_1.pdf") {
+		t.Fatalf("expected note with inbox path, got %q", msg.Content)
+	}
+}
+
+// imageRecorder records the images of the last user message it receives.
+type imageRecorder struct{ images []providers.Image }
+
+func (p *imageRecorder) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string) (providers.LLMResponse, error) {
+	p.images = messages[len(messages)-1].Images
+	return providers.LLMResponse{Content: "a cat"}, nil
+}
+
+func (p *imageRecorder) GetDefaultModel() string { return "test" }
+
+func TestAttachedImagesAreSentToTheModel(t *testing.T) {
+	p := &imageRecorder{}
+	ag := newTestLoop(t, chat.NewHub(10), p, "test", 1, t.TempDir(), nil)
+	dir := t.TempDir()
+	photo := filepath.Join(dir, "photo_1.jpg")
+	os.WriteFile(photo, []byte("\xff\xd8jpeg"), 0o644)
+	doc := filepath.Join(dir, "notes.txt")
+	os.WriteFile(doc, []byte("text"), 0o644)
+
+	msg := chat.Inbound{Channel: "telegram", SenderID: "1", ChatID: "1", Content: "what is this?", Media: []string{photo, doc}}
+	ag.processMessage(context.Background(), msg)
+	if len(p.images) != 1 || p.images[0].MediaType != "image/jpeg" || string(p.images[0].Data) != "\xff\xd8jpeg" {
+		t.Fatalf("expected the photo to be sent, got %+v", p.images)
+	}
+
+	ag.SetVision(false)
+	photo2 := filepath.Join(dir, "photo_2.jpg")
+	os.WriteFile(photo2, []byte("jpeg"), 0o644)
+	msg.Media = []string{photo2}
+	ag.processMessage(context.Background(), msg)
+	if len(p.images) != 0 {
+		t.Fatalf("expected no images with vision off, got %d", len(p.images))
+	}
+}
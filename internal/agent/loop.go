@@ -2,243 +2,680 @@ package agent
 
 import (
 	"context"
-	"log"
-	"os"
+	"encoding/json"
+	"fmt"
 	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/kr0nicas/picobot/internal/agent/memory"
+	"github.com/kr0nicas/picobot/internal/agent/skills"
 	"github.com/kr0nicas/picobot/internal/agent/tools"
+	"github.com/kr0nicas/picobot/internal/audit"
+	"github.com/kr0nicas/picobot/internal/calendar"
 	"github.com/kr0nicas/picobot/internal/chat"
 	"github.com/kr0nicas/picobot/internal/cron"
+	"github.com/kr0nicas/picobot/internal/debug"
+	"github.com/kr0nicas/picobot/internal/heartbeat"
+	"github.com/kr0nicas/picobot/internal/logging"
+	"github.com/kr0nicas/picobot/internal/moderation"
 	"github.com/kr0nicas/picobot/internal/providers"
-	"github.com/kr0nicas/picobot/internal/session"
+	"github.com/kr0nicas/picobot/internal/redact"
+	"github.com/kr0nicas/picobot/internal/telemetry"
+	"github.com/kr0nicas/picobot/internal/usage"
 )
 
+var agentLog = logging.For("agent")
+
 var rememberRE = regexp.MustCompile(`(?i)^remember(?:\s+to)?\s+(.+)$`)
 
 // AgentLoop is the core processing loop; it holds an LLM provider, tools, sessions and context builder.
+// The embedded tenant is the default (shared) workspace; in multi-tenant mode
+// additional tenants are created on demand per chat.
 type AgentLoop struct {
+	*tenant
 	hub           *chat.Hub
 	provider      providers.LLMProvider
-	tools         *tools.Registry
-	sessions      *session.SessionManager
-	context       *ContextBuilder
-	memory        *memory.MemoryStore
+	scheduler     *cron.Scheduler
 	model         string
 	maxIterations int
 	running       bool
+	startedAt     time.Time
+	tracer        *debug.Tracer // optional verbose tracer, toggled via /debug
+
+	multiTenant bool
+	tenantsMu   sync.Mutex // guards tenants and the named agents
+	tenants     map[string]*tenant
+
+	agents      map[string]*namedAgent // see SetAgents
+	agentRoutes map[string]string      // channel or chat -> agent
+	agentRouter bool
+	chatAgents  map[string]string // chat -> agent picked by the router
+
+	// settingsMu guards settings that /admin can change at runtime.
+	settingsMu    sync.RWMutex
+	modelRoutes   map[string]string // task -> model, see SetModelRoutes
+	ranking       MemoryRanking     // see SetMemoryRanking
+	admins        map[string]bool
+	adminHooks    AdminHooks
+	disabledTools map[string]bool
+	scopePolicy   tools.ScopePolicy       // see SetToolScopes
+	toolSetup     []func(*tools.Registry) // applied to every tenant's registry
+	classifier    InjectionClassifier     // optional pass over untrusted tool output
+	redactor      *redact.Redactor        // scrubs secrets from prompts, tool results and replies
+
+	defaultRole tools.Role
+	userRoles   map[string]tools.Role
+
+	auditLog *audit.Log     // hash-chained record of privileged operations
+	toolLog  *audit.ToolLog // every tool call, see SetToolLog
+
+	approvals       *approvalBroker
+	approvalsOff    bool
+	approvalTimeout time.Duration
+	approvalRules   []ApprovalRule
+
+	guard *chat.Guard // inbound flood protection
+
+	moderator      moderation.Moderator // optional pass over outbound content
+	moderationMode string
+
+	streaming bool // stream reply text to channels as chat.EventToken events
+	visionOff bool // don't send attached images to the model
+	// staticToolDocs puts the workspace's TOOLS.md in the prompt instead of
+	// the reference generated from the registered tools
+	staticToolDocs bool
+
+	contextWindow int // model context size in tokens; 0 looks it up by model name
+	maxTokens     int // reserved for the reply
+
+	parallelTools int           // tool calls run at once, see SetToolConcurrency
+	toolTimeout   time.Duration // per tool call
+
+	usage *usage.Ledger // token and cost accounting
+
+	subagents *subagentManager // background agents started with spawn
+
+	skillSync *skills.GitSync // publishes skill changes, see SetSkillSync
+
+	reflectEvery int            // exchanges per reflection, see SetReflection
+	reflectMu    sync.Mutex     // guards exchanges
+	exchanges    map[string]int // per chat, since its last reflection
+
+	tasksMu  sync.Mutex
+	tasks    map[string]*runningTask // per chat, see stopTask
+	progress *ProgressUpdates        // nil sends none, see SetProgressUpdates
+	planning bool                    // see SetPlanning
+
+	taskBudget TaskBudget // see SetTaskBudget
+
+	toolResultChars  int  // see SetToolResultLimit
+	summarizeResults bool // see SetToolResultLimit
+
+	promptProfile  PromptProfile            // see SetPromptProfiles
+	channelPrompts map[string]PromptProfile // see SetPromptProfiles
+
+	concurrentChats int                       // see SetConcurrency
+	dispatchMu      sync.Mutex                // guards queued
+	queued          map[string][]chat.Inbound // per chat with a worker, see dispatch
+
+	// user messages being handled and when the last one was answered, see
+	// InConversation
+	conversing       atomic.Int32
+	lastConversation atomic.Int64
 }
 
-// NewAgentLoop creates a new AgentLoop with the given provider.
-func NewAgentLoop(b *chat.Hub, provider providers.LLMProvider, model string, maxIterations int, workspace string, scheduler *cron.Scheduler) *AgentLoop {
+// NewAgentLoop creates a new AgentLoop with the given provider. It fails if
+// the workspace's tools or memory store can't be set up.
+func NewAgentLoop(b *chat.Hub, provider providers.LLMProvider, model string, maxIterations int, workspace string, scheduler *cron.Scheduler) (*AgentLoop, error) {
 	if model == "" {
 		model = provider.GetDefaultModel()
 	}
 	if workspace == "" {
 		workspace = "."
 	}
-	reg := tools.NewRegistry()
-	// register default tools
-	reg.Register(tools.NewMessageTool(b))
-
-	// Open an os.Root anchored at the workspace for kernel-enforced sandboxing.
-	root, err := os.OpenRoot(workspace)
+	a := &AgentLoop{hub: b, provider: provider, scheduler: scheduler, model: model, maxIterations: maxIterations, startedAt: time.Now(), tenants: make(map[string]*tenant), exchanges: make(map[string]int), tasks: make(map[string]*runningTask), queued: make(map[string][]chat.Inbound), disabledTools: make(map[string]bool), approvals: newApprovalBroker()}
+	a.subagents = newSubagentManager(a)
+	t, err := a.newTenant(workspace)
 	if err != nil {
-		log.Fatalf("failed to open workspace root %q: %v", workspace, err)
+		return nil, fmt.Errorf("initialize workspace %s: %w", workspace, err)
 	}
+	a.tenant = t
+	return a, nil
+}
 
-	fsTool, err := tools.NewFilesystemTool(workspace)
-	if err != nil {
-		log.Fatalf("failed to create filesystem tool: %v", err)
+// SetSkillSync makes the agent commit and push the default workspace's
+// skills through s whenever a skill is created, imported or deleted.
+func (a *AgentLoop) SetSkillSync(s *skills.GitSync) {
+	a.settingsMu.Lock()
+	a.skillSync = s
+	a.settingsMu.Unlock()
+}
+
+// SkillSync returns the skill sync set with SetSkillSync, or nil.
+func (a *AgentLoop) SkillSync() *skills.GitSync {
+	a.settingsMu.RLock()
+	defer a.settingsMu.RUnlock()
+	return a.skillSync
+}
+
+// SetRedactor installs the secret redactor applied to tool results, provider
+// prompts and responses, outbound messages and debug traces.
+func (a *AgentLoop) SetRedactor(r *redact.Redactor) {
+	a.redactor = r
+	a.tracer.SetFilter(r.Redact)
+}
+
+// SetStreaming makes the agent stream reply text, for providers that support
+// it, as chat.EventToken events that channels can show while it is generated.
+// Replies are not streamed while content moderation is on, since they must
+// be checked before anyone sees them.
+func (a *AgentLoop) SetStreaming(on bool) {
+	a.streaming = on
+}
+
+// SetVision controls whether images users attach are sent to the model
+// along with their message (on by default). Turn it off for models that
+// don't accept images; the files are still saved to the inbox.
+func (a *AgentLoop) SetVision(on bool) {
+	a.visionOff = !on
+}
+
+// SetStaticToolDocs makes the prompt use the workspace's TOOLS.md as the
+// tool reference instead of one generated from the tools offered to the
+// model (the default).
+func (a *AgentLoop) SetStaticToolDocs(on bool) {
+	a.staticToolDocs = on
+}
+
+// toolDocs returns the generated tool reference for defs, or "" when
+// TOOLS.md is used.
+func (a *AgentLoop) toolDocs(defs []providers.ToolDefinition) string {
+	if a.staticToolDocs {
+		return ""
 	}
-	reg.Register(fsTool)
+	return tools.Documentation(defs)
+}
+
+// SetContextWindow sets the context size of the model in tokens (0 looks
+// it up by model name) and the tokens reserved for its reply. Prompts are
+// trimmed to fit what is left after tool definitions.
+func (a *AgentLoop) SetContextWindow(window, maxTokens int) {
+	a.contextWindow, a.maxTokens = window, maxTokens
+}
 
-	reg.Register(tools.NewExecToolWithWorkspace(60, workspace))
-	reg.Register(tools.NewWebTool())
-	reg.Register(tools.NewSpawnTool())
-	if scheduler != nil {
-		reg.Register(tools.NewCronTool(scheduler))
+// promptBudget returns the tokens available to the messages of a prompt
+// for model with toolDefs.
+func (a *AgentLoop) promptBudget(model string, toolDefs []providers.ToolDefinition) int {
+	window := a.contextWindow
+	if window <= 0 {
+		window = providers.ContextWindow(model)
+	}
+	reserve := a.maxTokens
+	if reserve <= 0 {
+		reserve = 8192
 	}
+	return max(window-reserve-providers.EstimateToolTokens(toolDefs), window/4)
+}
 
-	sm := session.NewSessionManager(workspace)
-	ctx := NewContextBuilder(workspace, memory.NewLLMRanker(provider, model), 5)
-	mem := memory.NewMemoryStoreWithWorkspace(workspace, 100)
-	// register memory tool (needs store instance)
-	reg.Register(tools.NewWriteMemoryTool(mem))
+// SetTracer attaches a debug tracer used for verbose prompt/tool/provider traces.
+func (a *AgentLoop) SetTracer(t *debug.Tracer) {
+	a.tracer = t
+	if a.redactor != nil {
+		t.SetFilter(a.redactor.Redact)
+	}
+}
 
-	// register skill management tools (share the same os.Root)
-	skillMgr := tools.NewSkillManager(root)
-	reg.Register(tools.NewCreateSkillTool(skillMgr))
-	reg.Register(tools.NewListSkillsTool(skillMgr))
-	reg.Register(tools.NewReadSkillTool(skillMgr))
-	reg.Register(tools.NewDeleteSkillTool(skillMgr))
+// SetExecProfiles sets the exec security profile used by default and the
+// per-channel overrides, for all current and future tenants.
+func (a *AgentLoop) SetExecProfiles(def tools.ExecProfile, perChannel map[string]tools.ExecProfile) {
+	a.ConfigureTools(func(reg *tools.Registry) {
+		if et, ok := reg.Get("exec").(*tools.ExecTool); ok {
+			et.SetProfiles(def, perChannel)
+		}
+	})
+}
 
-	return &AgentLoop{hub: b, provider: provider, tools: reg, sessions: sm, context: ctx, memory: mem, model: model, maxIterations: maxIterations}
+// SetExecLimits caps the exec commands running at once across all tenants
+// (0 means no limit) and names the credential-like environment variables
+// commands may still see.
+func (a *AgentLoop) SetExecLimits(maxConcurrent int, passEnv []string) {
+	limiter := tools.NewExecLimiter(maxConcurrent)
+	a.ConfigureTools(func(reg *tools.Registry) {
+		if et, ok := reg.Get("exec").(*tools.ExecTool); ok {
+			et.SetLimits(limiter, passEnv)
+		}
+	})
 }
 
+// killJobs stops the background exec jobs of every tenant.
+func (a *AgentLoop) killJobs() {
+	a.tenantsMu.Lock()
+	defer a.tenantsMu.Unlock()
+	for _, t := range a.tenants {
+		if et, ok := t.tools.Get("exec").(*tools.ExecTool); ok {
+			et.Jobs().KillAll()
+		}
+	}
+}
+
+// SetStrictPaths makes the file, exec, skill and memory tools of all current
+// and future tenants refuse workspace paths that go through a symlink.
+func (a *AgentLoop) SetStrictPaths(strict bool) {
+	a.ConfigureTools(func(reg *tools.Registry) {
+		for _, name := range reg.Names() {
+			if st, ok := reg.Get(name).(interface{ SetStrictPaths(bool) }); ok {
+				st.SetStrictPaths(strict)
+			}
+		}
+	})
+}
+
+// SetDomainPolicy restricts the hosts network tools may contact, for all
+// current and future tenants.
+func (a *AgentLoop) SetDomainPolicy(p tools.DomainPolicy) {
+	a.ConfigureTools(func(reg *tools.Registry) {
+		for _, name := range reg.Names() {
+			if nt, ok := reg.Get(name).(interface{ SetDomainPolicy(tools.DomainPolicy) }); ok {
+				nt.SetDomainPolicy(p)
+			}
+		}
+	})
+}
+
+// SetHTTPLimits sets the default timeout and response size cap of the
+// http_request tool; zero values keep the defaults.
+func (a *AgentLoop) SetHTTPLimits(timeout time.Duration, maxBytes int) {
+	a.ConfigureTools(func(reg *tools.Registry) {
+		if ht, ok := reg.Get("http_request").(*tools.HTTPRequestTool); ok {
+			ht.SetLimits(timeout, maxBytes)
+		}
+	})
+}
+
+// SetSearchBackend selects the backend of the search tool.
+func (a *AgentLoop) SetSearchBackend(b tools.SearchBackend) {
+	a.ConfigureTools(func(reg *tools.Registry) {
+		if st, ok := reg.Get("search").(*tools.SearchTool); ok {
+			st.SetBackend(b)
+		}
+	})
+}
+
+// SetCalendar adds the calendar tool, backed by b, to every tenant.
+func (a *AgentLoop) SetCalendar(b calendar.Backend, loc *time.Location) {
+	a.ConfigureTools(func(reg *tools.Registry) {
+		reg.Register(tools.NewCalendarTool(b, loc))
+	})
+}
+
+// AddTools registers ts with every tenant.
+func (a *AgentLoop) AddTools(ts ...tools.Tool) {
+	a.ConfigureTools(func(reg *tools.Registry) {
+		for _, t := range ts {
+			reg.Register(t)
+		}
+	})
+}
+
+// UseToolMiddleware wraps the execution of every tool of every tenant
+// with m.
+func (a *AgentLoop) UseToolMiddleware(m tools.ToolMiddleware) {
+	a.ConfigureTools(func(reg *tools.Registry) { reg.Use(m) })
+}
+
+// recallCandidates is how many earlier notes the memory ranker chooses the
+// prompt's relevant memories from.
+const recallCandidates = 20
+
+// skillsPollInterval is how often the workspace's skills are checked for
+// edits made outside the agent.
+const skillsPollInterval = 2 * time.Second
+
 // Run starts processing inbound messages. This is a blocking call until context is canceled.
 func (a *AgentLoop) Run(ctx context.Context) {
 	a.running = true
-	log.Println("Agent loop started")
+	agentLog.Info("agent loop started")
+	go a.tenant.context.skillsLoader.Watch(ctx, skillsPollInterval)
+	go a.sweepMemories(ctx)
+	a.announceInterruptedTasks()
+
+	// Messages are processed by a worker per chat, a few chats at a time, so
+	// this goroutine can keep reading the hub: replies to a pending approval
+	// must reach the tool call that is blocked waiting for them, and a long
+	// task in one chat doesn't hold up the others.
+	a.settingsMu.RLock()
+	chats := a.concurrentChats
+	a.settingsMu.RUnlock()
+	if chats <= 0 {
+		chats = defaultConcurrentChats
+	}
+	slots := make(chan struct{}, chats)
 
 	for a.running {
 		select {
 		case <-ctx.Done():
-			log.Println("Agent loop received shutdown signal")
+			agentLog.Info("agent loop received shutdown signal")
 			a.running = false
+			a.killJobs()
+			a.subagents.stopAll()
 			return
 		case msg, ok := <-a.hub.In:
 			if !ok {
-				log.Println("Inbound channel closed, stopping agent loop")
+				agentLog.Info("inbound channel closed, stopping agent loop")
 				a.running = false
 				return
 			}
+			if !a.screenInbound(&msg) {
+				continue
+			}
+			if msg.Internal == nil && (a.approvals.resolve(msg) || a.stopTask(msg)) {
+				continue
+			}
+			a.dispatch(ctx, slots, msg)
+		}
+	}
+}
 
-			log.Printf("Processing message from %s:%s\n", msg.Channel, msg.SenderID)
+// processMessage handles a single inbound message end-to-end and publishes the reply.
+func (a *AgentLoop) processMessage(ctx context.Context, msg chat.Inbound) {
+	agentLog.Info("processing message", "channel", msg.Channel, "chat_id", msg.ChatID, "sender", msg.SenderID)
+	// the trace of a message starts when the channel received it, so time
+	// spent waiting for earlier messages of the chat shows up
+	ctx, span := telemetry.Start(ctx, "message", "channel", msg.Channel, "chat_id", msg.ChatID, "sender", msg.SenderID)
+	span.SetStart(msg.Timestamp)
+	defer span.End()
+	a.tracer.Tracef("inbound %s:%s from %s: %q", msg.Channel, msg.ChatID, msg.SenderID, msg.Content)
+	if msg.Channel != "heartbeat" && msg.Scheduled == nil {
+		a.conversing.Add(1)
+		defer func() {
+			a.lastConversation.Store(time.Now().UnixNano())
+			a.conversing.Add(-1)
+		}()
+	}
 
-			// Quick heuristic: if user asks the agent to remember something explicitly,
-			// store it in today's note and reply immediately without calling the LLM.
-			trimmed := strings.TrimSpace(msg.Content)
-			rememberRe := rememberRE
-			if matches := rememberRe.FindStringSubmatch(trimmed); len(matches) == 2 {
-				note := matches[1]
-				if err := a.memory.AppendToday(note); err != nil {
-					log.Printf("error appending to memory: %v", err)
-				}
-				out := chat.Outbound{Channel: msg.Channel, ChatID: msg.ChatID, Content: "OK, I've remembered that."}
-				select {
-				case a.hub.Out <- out:
-				default:
-					log.Println("Outbound channel full, dropping message")
-				}
-				// save to session as well
-				session := a.sessions.GetOrCreate(msg.Channel + ":" + msg.ChatID)
-				session.AddMessage("user", msg.Content)
-				session.AddMessage("assistant", "OK, I've remembered that.")
-				a.sessions.Save(session)
-				continue
+	ctx = a.routeAgent(ctx, &msg)
+
+	// Slash commands (e.g. /debug on) are handled locally without calling the LLM.
+	if reply, ok := a.handleCommand(msg); ok {
+		a.publish(chat.Outbound{Channel: msg.Channel, ChatID: msg.ChatID, Content: reply})
+		return
+	}
+
+	t, err := a.tenantFor(msg.Channel, msg.ChatID)
+	if err != nil {
+		agentLog.Error("preparing workspace failed", "channel", msg.Channel, "chat_id", msg.ChatID, "err", err)
+		a.publish(chat.Outbound{Channel: msg.Channel, ChatID: msg.ChatID, Content: "Sorry, I couldn't prepare your workspace."})
+		return
+	}
+
+	a.receiveMedia(t, &msg)
+
+	// /resume continues a task a restart interrupted, as if its message had
+	// just arrived; /summarize reports on it instead
+	var resumed *taskState
+	if cmd := commandName(msg.Content); cmd == "/resume" || cmd == "/summarize" {
+		if resumed = a.resumeCommand(ctx, t, &msg, cmd); resumed == nil {
+			return
+		}
+		msg.Content = resumed.Request
+	}
+	// /plan has the request planned and approved before any work starts
+	forcePlan := false
+	if commandName(msg.Content) == "/plan" {
+		content := strings.TrimSpace(msg.Content)
+		request := strings.TrimSpace(content[len(strings.Fields(content)[0]):])
+		if request == "" {
+			a.publish(chat.Outbound{Channel: msg.Channel, ChatID: msg.ChatID, Content: a.planCommand(t, &msg)})
+			return
+		}
+		msg.Content, forcePlan = request, true
+	}
+
+	// Quick heuristic: if user asks the agent to remember something explicitly,
+	// store it in today's note and reply immediately without calling the LLM.
+	trimmed := strings.TrimSpace(msg.Content)
+	if matches := rememberRE.FindStringSubmatch(trimmed); len(matches) == 2 {
+		note := matches[1]
+		if err := t.memory.AppendToday(note); err != nil {
+			agentLog.Error("appending to memory failed", "err", err)
+		}
+		a.publish(chat.Outbound{Channel: msg.Channel, ChatID: msg.ChatID, Content: "OK, I've remembered that."})
+		// save to session as well
+		session := t.sessions.GetOrCreate(msg.Channel, msg.ChatID)
+		session.AddMessage("user", msg.Content)
+		session.AddMessage("assistant", "OK, I've remembered that.")
+		t.sessions.Save(session)
+		return
+	}
+
+	// Build messages from session, long-term memory, and recent memory
+	_, buildSpan := telemetry.Start(ctx, "context build")
+	session := t.sessions.GetOrCreate(msg.Channel, msg.ChatID)
+	// get file-backed memory context (long-term + today)
+	memCtx, _ := t.memory.GetMemoryContext()
+	memories := t.memory.Recall(recallCandidates)
+	role := a.roleFor(&msg)
+	// update_plan is only offered while a plan is being worked through
+	toolDefs, planDef := withoutTool(t.tools.DefinitionsFor(role, a.toolScopes(&msg)...), "update_plan")
+	task := TaskChat
+	if msg.Channel == "heartbeat" {
+		task = TaskHeartbeat
+	}
+	model := a.modelFor(ctx, task)
+	budget := a.promptBudget(model, toolDefs)
+	messages := t.context.BuildMessagesWithin(budget, a.toolDocs(toolDefs), session.GetHistory(), msg.Content, msg.Channel, msg.ChatID, memCtx, memories)
+	histEnd := len(messages) - 1 // history ends at the current message
+	if msg.Internal != nil {
+		messages = internalResult(messages, msg)
+	}
+	buildSpan.SetAttributes("messages", len(messages), "tools", len(toolDefs))
+	buildSpan.End()
+	if !a.visionOff {
+		messages[len(messages)-1].Images = loadImages(msg.Media)
+	}
+
+	// user tasks are checkpointed so a restart can resume them, and can be
+	// stopped from the chat
+	taskCtx := ctx
+	var state *taskState
+	if msg.Channel != "heartbeat" && msg.Scheduled == nil && msg.Internal == nil {
+		var endTask func()
+		taskCtx, endTask = a.startTask(ctx, &msg)
+		defer endTask()
+		state = resumed
+		if state == nil {
+			state = &taskState{Channel: msg.Channel, ChatID: msg.ChatID, SenderID: msg.SenderID, Request: msg.Content, Started: time.Now().UTC()}
+		}
+	}
+	finalContent := ""
+	var plan *taskPlan
+	if resumed != nil {
+		var err error
+		if plan, err = loadPlan(planPath(t.workspace, msg.Channel, msg.ChatID)); err != nil {
+			agentLog.Warn("loading plan failed", "channel", msg.Channel, "chat_id", msg.ChatID, "err", err)
+		}
+	} else if state != nil && (forcePlan || a.planningOn() && len(strings.Fields(msg.Content)) >= planMinWords) {
+		plan, finalContent = a.planRequest(taskCtx, t, &msg, messages, forcePlan)
+	}
+	if plan != nil {
+		messages[histEnd].Content += plan.instructions()
+		if planDef != nil {
+			toolDefs = append(toolDefs, *planDef)
+		}
+	}
+	iteration := 0
+	if resumed != nil {
+		messages = append(messages, resumed.Steps...)
+		iteration = resumed.Iteration
+	}
+	lastToolResult := ""
+	var runErr error
+	a.settingsMu.RLock()
+	limits := a.taskBudget
+	a.settingsMu.RUnlock()
+	spend := newTaskSpend()
+	for iteration < a.maxIterations && taskCtx.Err() == nil && finalContent == "" {
+		if limit := limits.exceeded(spend); limit != "" {
+			goOn, reply := a.budgetUsedUp(taskCtx, &msg, messages[histEnd+1:], limit)
+			if !goOn {
+				finalContent = reply
+				break
 			}
+			spend = newTaskSpend()
+		}
+		iteration++
+		a.traceRequest(iteration, model, messages, toolDefs)
+		resp, err := a.chatFitting(taskCtx, task, &messages, &histEnd, toolDefs, a.streamTo(&msg))
+		a.traceResponse(iteration, resp, err)
+		spend.add(a, model, resp.Usage)
+		if err != nil {
+			agentLog.Error("provider failed", "channel", msg.Channel, "chat_id", msg.ChatID, "err", err)
+			finalContent = providerErrorReply(err, model)
+			runErr = err
+			break
+		}
 
-			// Set tool context (so message tool knows channel+chat)
-			if mt := a.tools.Get("message"); mt != nil {
-				if mtool, ok := mt.(interface{ SetContext(string, string) }); ok {
-					mtool.SetContext(msg.Channel, msg.ChatID)
-				}
+		if resp.HasToolCalls {
+			// append assistant message with tool_calls attached
+			messages = append(messages, providers.Message{Role: "assistant", Content: resp.Content, ToolCalls: resp.ToolCalls, Thinking: resp.Thinking})
+			// Execute the tool calls and return results with "tool" role, in order
+			for i, res := range a.runTools(taskCtx, t, &msg, resp.ToolCalls) {
+				lastToolResult = res
+				messages = append(messages, providers.Message{Role: "tool", Content: res, ToolCallID: resp.ToolCalls[i].ID})
 			}
-			if ct := a.tools.Get("cron"); ct != nil {
-				if ctool, ok := ct.(interface{ SetContext(string, string) }); ok {
-					ctool.SetContext(msg.Channel, msg.ChatID)
+			if state != nil {
+				state.Steps, state.Iteration = messages[histEnd+1:], iteration
+				if err := state.save(t.workspace); err != nil {
+					agentLog.Error("saving task state failed", "err", err)
 				}
+				a.progressUpdate(&msg, iteration, resp.ToolCalls)
 			}
+			// loop again
+			continue
+		} else {
+			finalContent = resp.Content
+			break
+		}
+	}
 
-			// Build messages from session, long-term memory, and recent memory
-			session := a.sessions.GetOrCreate(msg.Channel + ":" + msg.ChatID)
-			// get file-backed memory context (long-term + today)
-			memCtx, _ := a.memory.GetMemoryContext()
-			memories := a.memory.Recent(5)
-			messages := a.context.BuildMessages(session.GetHistory(), msg.Content, msg.Channel, msg.ChatID, memCtx, memories)
-
-			iteration := 0
-			finalContent := ""
-			lastToolResult := ""
-			toolDefs := a.tools.Definitions()
-			for iteration < a.maxIterations {
-				iteration++
-				resp, err := a.provider.Chat(ctx, messages, toolDefs, a.model)
-				if err != nil {
-					log.Printf("provider error: %v", err)
-					if strings.Contains(err.Error(), "429") {
-						finalContent = "I'm being rate-limited by the AI provider. Please try again in a minute."
-					} else {
-						finalContent = "Sorry, I encountered an error while processing your request."
-					}
-					break
-				}
+	span.SetAttributes("iterations", iteration)
+	span.RecordError(runErr)
+	if context.Cause(taskCtx) == errStopped {
+		agentLog.Info("task stopped by the user", "channel", msg.Channel, "chat_id", msg.ChatID, "iterations", iteration)
+		finalContent, runErr = stoppedReply(messages[histEnd+1:]), errStopped
+	}
 
-				if resp.HasToolCalls {
-					// append assistant message with tool_calls attached
-					messages = append(messages, providers.Message{Role: "assistant", Content: resp.Content, ToolCalls: resp.ToolCalls})
-					// Execute each tool call and return results with "tool" role
-					for _, tc := range resp.ToolCalls {
-						res, err := a.tools.Execute(ctx, tc.Name, tc.Arguments)
-						if err != nil {
-							if res != "" {
-								res = "(tool error) " + err.Error() + "\n" + res
-							} else {
-								res = "(tool error) " + err.Error()
-							}
-						}
-						lastToolResult = res
-						messages = append(messages, providers.Message{Role: "tool", Content: res, ToolCallID: tc.ID})
-					}
-					// loop again
-					continue
-				} else {
-					finalContent = resp.Content
-					break
-				}
+	// a shutdown leaves the checkpoint for the next start, which records the
+	// exchange once the task is resumed or summarized
+	if state != nil {
+		if ctx.Err() == nil {
+			state.clear(t.workspace)
+			if plan != nil {
+				plan.clear(t.workspace)
 			}
+		} else if len(state.Steps) > 0 {
+			return
+		}
+	}
 
-			if finalContent == "" && lastToolResult != "" {
-				finalContent = lastToolResult
-			} else if finalContent == "" {
-				finalContent = "I've completed processing but have no response to give."
-			}
+	if finalContent == "" && lastToolResult != "" {
+		finalContent = lastToolResult
+	} else if finalContent == "" {
+		finalContent = "I've completed processing but have no response to give."
+	}
 
-			// For heartbeat messages, don't send error replies back to avoid noise
-			if msg.Channel == "heartbeat" && strings.Contains(finalContent, "rate-limited") {
-				log.Println("heartbeat: suppressing rate-limit error reply")
-				continue
-			}
+	if msg.Channel == "heartbeat" {
+		a.recordHeartbeatTask(&msg, runErr)
+	}
+	// For heartbeat messages, don't send error replies back to avoid noise
+	if msg.Channel == "heartbeat" && strings.Contains(finalContent, "rate-limited") {
+		agentLog.Info("suppressing rate-limit error reply", "channel", "heartbeat")
+		return
+	}
 
-			// Save session
-			session.AddMessage("user", msg.Content)
-			session.AddMessage("assistant", finalContent)
-			a.sessions.Save(session)
+	if !a.moderate(ctx, &msg, "reply", finalContent) {
+		finalContent = moderatedReply
+	}
 
-			out := chat.Outbound{Channel: msg.Channel, ChatID: msg.ChatID, Content: finalContent}
-			select {
-			case a.hub.Out <- out:
-			default:
-				log.Println("Outbound channel full, dropping message")
-			}
-		default:
-			// idle tick
-			time.Sleep(100 * time.Millisecond)
-		}
+	// Save session, with the tool calls that led to the reply
+	if msg.Internal != nil {
+		recordSteps(session, messages[histEnd:])
+	} else {
+		session.AddMessage("user", msg.Content)
+		recordSteps(session, messages[histEnd+1:])
+	}
+	session.AddMessage("assistant", finalContent)
+	t.sessions.Save(session)
+
+	a.publish(chat.Outbound{Channel: msg.Channel, ChatID: msg.ChatID, Content: finalContent, Metadata: telemetry.Inject(ctx, nil)})
+	if runErr == nil && msg.Channel != "heartbeat" && msg.Scheduled == nil {
+		a.afterExchange(ctx, t, session, msg.Channel, msg.ChatID)
+	}
+}
+
+// InConversation reports whether a user message is being handled or the
+// last one was answered less than idle ago, for background work that
+// should not interleave with a conversation.
+func (a *AgentLoop) InConversation(idle time.Duration) bool {
+	if a.conversing.Load() > 0 {
+		return true
+	}
+	last := a.lastConversation.Load()
+	return last != 0 && time.Since(time.Unix(0, last)) < idle
+}
+
+// recordHeartbeatTask stores the outcome of the heartbeat task msg ran, if
+// any, so failing tasks are counted and eventually disabled.
+func (a *AgentLoop) recordHeartbeatTask(msg *chat.Inbound, runErr error) {
+	name, _ := msg.Metadata[heartbeat.TaskKey].(string)
+	if name == "" {
+		return
+	}
+	if err := heartbeat.NewTaskStore(heartbeat.TaskPath(a.tenant.workspace)).Record(name, runErr); err != nil {
+		agentLog.Error("recording heartbeat task failed", "task", name, "err", err)
+	}
+}
+
+// publish sends an outbound message without blocking, dropping it if the hub is full.
+func (a *AgentLoop) publish(out chat.Outbound) {
+	out.Content = a.redactor.FilterOutbound(out.Channel, out.ChatID, out.Content)
+	select {
+	case a.hub.Out <- out:
+	default:
+		agentLog.Warn("outbound channel full, dropping message", "channel", out.Channel, "chat_id", out.ChatID)
 	}
 }
 
 // ProcessDirect sends a message directly to the provider and returns the response.
 // It supports tool calling - if the model requests tools, they will be executed.
 func (a *AgentLoop) ProcessDirect(content string, timeout time.Duration) (string, error) {
+	return a.ProcessDirectStream(content, timeout, nil)
+}
+
+// ProcessDirectStream is ProcessDirect with the reply text passed to onDelta
+// as it is generated, when the provider can stream.
+func (a *AgentLoop) ProcessDirectStream(content string, timeout time.Duration, onDelta providers.StreamFunc) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	// Set tool context so message/cron tools know the originating channel,
-	// matching what Run() does for hub-based messages.
-	if mt := a.tools.Get("message"); mt != nil {
-		if mtool, ok := mt.(interface{ SetContext(string, string) }); ok {
-			mtool.SetContext("cli", "direct")
-		}
-	}
-	if ct := a.tools.Get("cron"); ct != nil {
-		if ctool, ok := ct.(interface{ SetContext(string, string) }); ok {
-			ctool.SetContext("cli", "direct")
-		}
-	}
+	// Tell message/cron tools the originating channel, as runTool does for
+	// hub-based messages.
+	ctx = tools.WithChat(ctx, "cli", "direct")
 
 	// Build full context (bootstrap files, skills, memory) just like the main loop
 	memCtx, _ := a.memory.GetMemoryContext()
-	memories := a.memory.Recent(5)
-	messages := a.context.BuildMessages(nil, content, "cli", "direct", memCtx, memories)
+	memories := a.memory.Recall(recallCandidates)
+	toolDefs, _ := withoutTool(a.tools.DefinitionsFor(a.roleFor(nil)), "update_plan")
+	messages := a.context.BuildMessagesWithin(a.promptBudget(a.Model(), toolDefs), a.toolDocs(toolDefs), nil, content, "cli", "direct", memCtx, memories)
 
 	// Support tool calling iterations (similar to main loop)
 	var lastToolResult string
 	for iteration := 0; iteration < a.maxIterations; iteration++ {
-		resp, err := a.provider.Chat(ctx, messages, a.tools.Definitions(), a.model)
+		a.traceRequest(iteration+1, a.Model(), messages, toolDefs)
+		resp, err := a.chat(ctx, TaskChat, messages, toolDefs, onDelta)
+		a.traceResponse(iteration+1, resp, err)
 		if err != nil {
 			return "", err
 		}
@@ -255,20 +692,148 @@ func (a *AgentLoop) ProcessDirect(content string, timeout time.Duration) (string
 		}
 
 		// Execute tool calls
-		messages = append(messages, providers.Message{Role: "assistant", Content: resp.Content, ToolCalls: resp.ToolCalls})
-		for _, tc := range resp.ToolCalls {
-			result, err := a.tools.Execute(ctx, tc.Name, tc.Arguments)
-			if err != nil {
-				if result != "" {
-					result = "(tool error) " + err.Error() + "\n" + result
-				} else {
-					result = "(tool error) " + err.Error()
-				}
-			}
+		messages = append(messages, providers.Message{Role: "assistant", Content: resp.Content, ToolCalls: resp.ToolCalls, Thinking: resp.Thinking})
+		for i, result := range a.runTools(ctx, a.tenant, nil, resp.ToolCalls) {
 			lastToolResult = result
-			messages = append(messages, providers.Message{Role: "tool", Content: result, ToolCallID: tc.ID})
+			messages = append(messages, providers.Message{Role: "tool", Content: result, ToolCallID: resp.ToolCalls[i].ID})
 		}
 	}
 
 	return "Max iterations reached without final response", nil
 }
+
+// runTool executes one tool call against the tenant's registry and returns
+// the text handed back to the model. Destructive calls first need the user's
+// approval in msg's chat (msg is nil for direct, non-interactive calls).
+// Errors are reported inline, and output from tools that fetch outside
+// content is guarded against prompt injection.
+func (a *AgentLoop) runTool(ctx context.Context, t *tenant, msg *chat.Inbound, tc providers.ToolCall) string {
+	a.tracer.TraceJSON("tool call "+tc.Name+" ("+tc.ID+") args", tools.ShownArgs(t.tools.Get(tc.Name), tc.Arguments))
+	ctx, span := telemetry.Start(ctx, "tool "+tc.Name, "tool", tc.Name)
+	defer span.End()
+	if msg != nil {
+		ctx = context.WithValue(ctx, inboundKey{}, msg)
+		ctx = tools.WithChat(ctx, msg.Channel, msg.ChatID)
+	}
+	started := time.Now()
+	if what, text := generatedContent(tc); text != "" && !a.moderate(ctx, msg, what, text) {
+		a.logToolCall(t, msg, tc, started, 0, audit.OutcomeBlocked, nil)
+		span.SetAttributes("outcome", audit.OutcomeBlocked)
+		return "(tool error) blocked by the content filter; do not retry with the same content"
+	}
+	if action := a.approvalAction(t, msg, tc); action != "" {
+		if ok, why := a.requestApproval(ctx, msg, tc.Name, action); !ok {
+			a.tracer.Tracef("tool call %s (%s) not approved: %s", tc.Name, tc.ID, why)
+			a.logToolCall(t, msg, tc, started, 0, audit.OutcomeDenied, nil)
+			span.SetAttributes("outcome", audit.OutcomeDenied)
+			return "(tool error) " + why
+		}
+	}
+	if msg != nil {
+		args, _ := json.Marshal(tools.ShownArgs(t.tools.Get(tc.Name), tc.Arguments))
+		a.hub.Emit(chat.Event{Channel: msg.Channel, ChatID: msg.ChatID, Type: chat.EventToolCall, Tool: tc.Name, Content: a.redactor.Redact(string(args))})
+	}
+	res, err := a.execute(ctx, t, msg, tc)
+	outcome := audit.OutcomeOK
+	if err != nil {
+		outcome = audit.OutcomeError
+	}
+	a.logToolCall(t, msg, tc, started, len(res), outcome, err)
+	span.SetAttributes("outcome", outcome, "result_bytes", len(res))
+	span.RecordError(err)
+	if kind, action := privilegedAction(tc.Name, tc.Arguments); kind != "" {
+		detail := map[string]interface{}{"tool": tc.Name, "ok": err == nil}
+		if err != nil {
+			detail["error"] = a.redactor.Redact(err.Error())
+		}
+		a.audit(kind, msg, action, detail)
+	}
+	res = a.compactResult(ctx, t, tc, res)
+	if err != nil {
+		if res != "" {
+			res = "(tool error) " + err.Error() + "\n" + res
+		} else {
+			res = "(tool error) " + err.Error()
+		}
+	} else if u, ok := t.tools.Get(tc.Name).(tools.UntrustedOutput); ok && u.UntrustedOutput() {
+		res = a.guardUntrusted(ctx, t, tc.Name, res)
+	}
+	res = a.redactor.Redact(res)
+	a.tracer.Tracef("tool result %s (%s):\n%s", tc.Name, tc.ID, res)
+	if msg != nil {
+		a.hub.Emit(chat.Event{Channel: msg.Channel, ChatID: msg.ChatID, Type: chat.EventToolResult, Tool: tc.Name, Content: res})
+	}
+	return res
+}
+
+// chat sends messages to the model of task with secrets redacted from the
+// prompt and from the response text, and records the tokens used. If
+// onDelta is set and the provider can stream, it receives the reply text as
+// it is generated.
+func (a *AgentLoop) chat(ctx context.Context, task string, messages []providers.Message, toolDefs []providers.ToolDefinition, onDelta providers.StreamFunc) (providers.LLMResponse, error) {
+	model := a.modelFor(ctx, task)
+	ctx, span := telemetry.Start(ctx, "chat "+model, "task", task, "model", model, "messages", len(messages))
+	defer span.End()
+	ctx = providers.WithParams(ctx, taskParams[task])
+	if a.redactor != nil {
+		clean := make([]providers.Message, len(messages))
+		for i, m := range messages {
+			m.Content = a.redactor.Redact(m.Content)
+			clean[i] = m
+		}
+		messages = clean
+	}
+	if onDelta != nil && a.redactor != nil {
+		// redact across deltas, so a secret split between two is caught
+		stream := a.redactor.Stream(onDelta)
+		onDelta = stream.Write
+		defer stream.Flush()
+	}
+	resp, err := providers.ChatStream(ctx, a.provider, messages, toolDefs, model, onDelta)
+	a.recordUsage(model, task, resp.Usage)
+	span.SetAttributes("prompt_tokens", resp.Usage.PromptTokens, "completion_tokens", resp.Usage.CompletionTokens, "tool_calls", len(resp.ToolCalls))
+	span.RecordError(err)
+	if resp.Reasoning != "" {
+		// reasoning is logged and traced, never sent to the user
+		agentLog.Debug("model reasoned before replying", "model", model, "chars", len(resp.Reasoning))
+		a.tracer.Tracef("reasoning:\n%s", resp.Reasoning)
+	}
+	resp.Content = a.redactor.Redact(resp.Content)
+	return resp, err
+}
+
+// streamTo returns the callback that streams reply text to msg's chat, or
+// nil when streaming is off or replies are moderated.
+func (a *AgentLoop) streamTo(msg *chat.Inbound) providers.StreamFunc {
+	a.settingsMu.RLock()
+	moderated := a.moderator != nil
+	a.settingsMu.RUnlock()
+	if !a.streaming || moderated || msg.Channel == "heartbeat" {
+		return nil
+	}
+	return func(delta string) {
+		a.hub.Emit(chat.Event{Channel: msg.Channel, ChatID: msg.ChatID, Type: chat.EventToken, Content: delta})
+	}
+}
+
+// traceRequest records the full prompt and tool definitions sent to the provider.
+func (a *AgentLoop) traceRequest(iteration int, model string, messages []providers.Message, toolDefs []providers.ToolDefinition) {
+	if !a.tracer.Enabled() {
+		return
+	}
+	a.tracer.Tracef("provider request #%d model=%s messages=%d tools=%d", iteration, model, len(messages), len(toolDefs))
+	a.tracer.TraceJSON("messages", messages)
+	a.tracer.TraceJSON("tools", toolDefs)
+}
+
+// traceResponse records the provider's normalized response (or error).
+func (a *AgentLoop) traceResponse(iteration int, resp providers.LLMResponse, err error) {
+	if !a.tracer.Enabled() {
+		return
+	}
+	if err != nil {
+		a.tracer.Tracef("provider response #%d error: %v", iteration, err)
+		return
+	}
+	a.tracer.TraceJSON(fmt.Sprintf("provider response #%d", iteration), resp)
+}
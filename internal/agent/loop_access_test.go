@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kr0nicas/picobot/internal/agent/tools"
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/cron"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+func TestRoleForIdentities(t *testing.T) {
+	p := providers.NewStubProvider()
+	ag := newTestLoop(t, chat.NewHub(1), p, p.GetDefaultModel(), 3, t.TempDir(), nil)
+	ag.SetAccess(tools.RoleReadOnly, map[string]tools.Role{"telegram:1": tools.RoleOwner}, nil)
+
+	if r := ag.roleFor(&chat.Inbound{Channel: "telegram", SenderID: "1"}); r != tools.RoleOwner {
+		t.Fatalf("expected owner, got %s", r)
+	}
+	guest := &chat.Inbound{Channel: "telegram", SenderID: "2", ChatID: "2"}
+	if r := ag.roleFor(guest); r != tools.RoleReadOnly {
+		t.Fatalf("expected default role readonly, got %s", r)
+	}
+	if !ag.isAdmin(chat.Inbound{Channel: "telegram", SenderID: "1"}) {
+		t.Fatalf("expected explicit owner to be admin")
+	}
+
+	res := ag.runTool(context.Background(), ag.tenant, guest, providers.ToolCall{ID: "1", Name: "exec", Arguments: map[string]interface{}{"cmd": "echo hi"}})
+	if res == "hi" {
+		t.Fatalf("readonly user must not be able to exec")
+	}
+}
+
+func TestScheduledJobsKeepTheSchedulersRole(t *testing.T) {
+	p := providers.NewStubProvider()
+	sched := cron.NewScheduler(func(cron.Job) {})
+	ag := newTestLoop(t, chat.NewHub(1), p, p.GetDefaultModel(), 3, t.TempDir(), sched)
+	ag.SetAccess(tools.RoleUser, map[string]tools.Role{"telegram:1": tools.RoleOwner}, nil)
+	execCall := providers.ToolCall{ID: "2", Name: "exec", Arguments: map[string]interface{}{"cmd": "echo hi"}}
+
+	forged := &chat.Inbound{Channel: "http", SenderID: "cron", ChatID: "2"}
+	if r := ag.roleFor(forged); r != tools.RoleUser {
+		t.Fatalf("a client calling itself cron must get its own role, got %s", r)
+	}
+	if res := ag.runTool(context.Background(), ag.tenant, forged, execCall); !strings.Contains(res, "not permitted") {
+		t.Fatalf("a forged sender_id must not reach exec")
+	}
+
+	user := &chat.Inbound{Channel: "http", SenderID: "2", ChatID: "2"}
+	ag.runTool(context.Background(), ag.tenant, user, providers.ToolCall{ID: "1", Name: "cron",
+		Arguments: map[string]interface{}{"action": "add", "message": "run exec", "delay": "1h"}})
+	jobs := sched.List()
+	if len(jobs) != 1 || jobs[0].Role != string(tools.RoleUser) {
+		t.Fatalf("expected the job to record the user role, got %+v", jobs)
+	}
+	fired := &chat.Inbound{Channel: "http", SenderID: "cron", ChatID: "2", Scheduled: &chat.ScheduledJob{ID: jobs[0].ID, Role: jobs[0].Role}}
+	if r := ag.roleFor(fired); r != tools.RoleUser {
+		t.Fatalf("a job scheduled by a user must run as user, got %s", r)
+	}
+	if res := ag.runTool(context.Background(), ag.tenant, fired, execCall); !strings.Contains(res, "not permitted") {
+		t.Fatalf("a job scheduled by a user must not reach exec")
+	}
+
+	// a job without a recorded role gets the least privileges, even in the owner's chat
+	if r := ag.roleFor(&chat.Inbound{Channel: "telegram", ChatID: "1", Scheduled: &chat.ScheduledJob{ID: "job-9"}}); r != tools.RoleReadOnly {
+		t.Fatalf("expected a job without a role to run as readonly, got %s", r)
+	}
+}
@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/debug"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+type fakeHeartbeat struct{ paused bool }
+
+func (h *fakeHeartbeat) Pause()       { h.paused = true }
+func (h *fakeHeartbeat) Resume()      { h.paused = false }
+func (h *fakeHeartbeat) Paused() bool { return h.paused }
+
+func TestAdminCommands(t *testing.T) {
+	b := chat.NewHub(10)
+	p := providers.NewStubProvider()
+	ag := newTestLoop(t, b, p, p.GetDefaultModel(), 3, t.TempDir(), nil)
+	hb := &fakeHeartbeat{}
+	logs := debug.NewLogTail(10)
+	logs.Write([]byte("first line\nsecond line\n"))
+	ag.SetAdmins([]string{"telegram:42"})
+	ag.SetAdminHooks(AdminHooks{Heartbeat: hb, Logs: logs})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	go ag.Run(ctx)
+
+	send := func(sender, content string) string {
+		b.In <- chat.Inbound{Channel: "telegram", SenderID: sender, ChatID: sender, Content: content}
+		select {
+		case out := <-b.Out:
+			return out.Content
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout waiting for reply to %q", content)
+		}
+		return ""
+	}
+
+	if reply := send("7", "/admin model other"); reply != notAdminReply {
+		t.Fatalf("expected non-admin to be rejected, got %q", reply)
+	}
+	if reply := send("42", "/admin model other-model"); !strings.Contains(reply, "other-model") {
+		t.Fatalf("unexpected reply: %q", reply)
+	}
+	if ag.Model() != "other-model" {
+		t.Fatalf("expected model to be switched, got %q", ag.Model())
+	}
+	if reply := send("42", "/admin tool exec off"); !strings.Contains(reply, "now off") {
+		t.Fatalf("unexpected reply: %q", reply)
+	}
+	if ag.tools.Enabled("exec") {
+		t.Fatalf("expected exec to be disabled")
+	}
+	if reply := send("42", "/admin tools"); !strings.Contains(reply, "exec: off") {
+		t.Fatalf("unexpected tools listing: %q", reply)
+	}
+	if reply := send("42", "/admin heartbeat pause"); !hb.paused {
+		t.Fatalf("expected heartbeat to be paused, reply %q", reply)
+	}
+	if reply := send("42", "/admin logs 1"); reply != "second line" {
+		t.Fatalf("unexpected logs reply: %q", reply)
+	}
+}
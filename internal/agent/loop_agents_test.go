@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/kr0nicas/picobot/internal/agent/tools"
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+// agentsProvider answers the router with route and records the model,
+// system prompt and tools of replies.
+type agentsProvider struct {
+	route  string
+	routed int
+	model  string
+	system string
+	tools  []string
+}
+
+func (p *agentsProvider) Chat(ctx context.Context, messages []providers.Message, defs []providers.ToolDefinition, model string) (providers.LLMResponse, error) {
+	if strings.HasPrefix(messages[0].Content, "You route") {
+		p.routed++
+		return providers.LLMResponse{Content: p.route}, nil
+	}
+	p.model, p.system, p.tools = model, "", nil
+	for _, m := range messages {
+		if m.Role == "system" {
+			p.system += m.Content + "\n"
+		}
+	}
+	for _, d := range defs {
+		p.tools = append(p.tools, d.Name)
+	}
+	slices.Sort(p.tools)
+	return providers.LLMResponse{Content: "ok"}, nil
+}
+
+func (p *agentsProvider) GetDefaultModel() string { return "main-model" }
+
+func TestNamedAgents(t *testing.T) {
+	ws := t.TempDir()
+	coderWS := filepath.Join(ws, "agents", "coder")
+	os.MkdirAll(coderWS, 0o755)
+	os.WriteFile(filepath.Join(coderWS, "SOUL.md"), []byte("You are Coder, a terse programmer."), 0o644)
+	hub := chat.NewHub(10)
+	p := &agentsProvider{}
+	ag := newTestLoop(t, hub, p, "main-model", 5, ws, nil)
+	err := ag.SetAgents([]NamedAgent{{
+		Name: "coder", Description: "writes code", Workspace: coderWS, Model: "coder-model",
+		Tools: tools.ToolFilter{Allow: []string{"filesystem", "exec"}},
+	}}, map[string]string{"discord": "coder"}, true)
+	if err != nil {
+		t.Fatalf("SetAgents: %v", err)
+	}
+	send := func(channel, content string) {
+		t.Helper()
+		ag.processMessage(context.Background(), chat.Inbound{Channel: channel, SenderID: "1", ChatID: "7", Content: content})
+		<-hub.Out
+	}
+
+	send("discord", "fix the build")
+	if p.routed != 0 || p.model != "coder-model" || !strings.Contains(p.system, "You are Coder") || strings.Join(p.tools, ",") != "exec,filesystem" {
+		t.Fatalf("routed chat not served by coder (router asked %d times): model %q, tools %v", p.routed, p.model, p.tools)
+	}
+
+	p.route = "default"
+	send("telegram", "what's the weather?")
+	if p.routed != 1 || p.model != "main-model" || strings.Contains(p.system, "You are Coder") || len(p.tools) < 5 {
+		t.Fatalf("unexpected default agent request: model %q, tools %v", p.model, p.tools)
+	}
+	p.route = "Coder"
+	send("telegram", "now write me a script")
+	if p.model != "coder-model" || !strings.Contains(p.system, "You are Coder") {
+		t.Fatalf("router's choice not used: model %q", p.model)
+	}
+	send("telegram", "/help")
+	if p.routed != 2 {
+		t.Fatalf("commands must not be routed, router asked %d times", p.routed)
+	}
+
+	// the router's choice survives a restart
+	again := newTestLoop(t, chat.NewHub(10), p, "main-model", 5, ws, nil)
+	if err := again.SetAgents([]NamedAgent{{Name: "coder", Workspace: coderWS}}, nil, true); err != nil {
+		t.Fatalf("SetAgents: %v", err)
+	}
+	if name := again.agentName(&chat.Inbound{Channel: "telegram", ChatID: "7"}); name != "coder" {
+		t.Fatalf("chat is with %q after a restart", name)
+	}
+	if err := again.SetAgents(nil, map[string]string{"slack": "writer"}, false); err == nil {
+		t.Fatal("a route to an unknown agent was accepted")
+	}
+}
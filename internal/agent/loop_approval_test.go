@@ -0,0 +1,187 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+// overwriteProvider asks to overwrite notes.txt, then finishes.
+type overwriteProvider struct{ calls int }
+
+func (p *overwriteProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string) (providers.LLMResponse, error) {
+	p.calls++
+	if p.calls%2 == 1 {
+		args := map[string]interface{}{"action": "write", "path": "notes.txt", "content": "new"}
+		return providers.LLMResponse{HasToolCalls: true, ToolCalls: []providers.ToolCall{{ID: "1", Name: "filesystem", Arguments: args}}}, nil
+	}
+	return providers.LLMResponse{Content: "Done: " + messages[len(messages)-1].Content}, nil
+}
+func (p *overwriteProvider) GetDefaultModel() string { return "test" }
+
+func TestApprovalGatesOverwrite(t *testing.T) {
+	ws := t.TempDir()
+	target := filepath.Join(ws, "notes.txt")
+	os.WriteFile(target, []byte("old"), 0o644)
+
+	b := chat.NewHub(10)
+	ag := newTestLoop(t, b, &overwriteProvider{}, "test", 5, ws, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go ag.Run(ctx)
+
+	next := func() chat.Outbound {
+		select {
+		case out := <-b.Out:
+			return out
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout waiting for outbound")
+		}
+		return chat.Outbound{}
+	}
+
+	// denied via typed reply
+	b.In <- chat.Inbound{Channel: "telegram", SenderID: "1", ChatID: "1", Content: "replace notes"}
+	prompt := next()
+	if !strings.Contains(prompt.Content, "overwrite notes.txt") || len(prompt.Buttons) == 0 {
+		t.Fatalf("expected approval prompt with buttons, got %+v", prompt)
+	}
+	b.In <- chat.Inbound{Channel: "telegram", SenderID: "1", ChatID: "1", Content: "no"}
+	if out := next(); !strings.Contains(out.Content, "denied") {
+		t.Fatalf("expected denial to reach the model, got %q", out.Content)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "old" {
+		t.Fatalf("file changed without approval: %q", data)
+	}
+
+	// approved via button data
+	b.In <- chat.Inbound{Channel: "telegram", SenderID: "1", ChatID: "1", Content: "replace notes"}
+	prompt = next()
+	b.In <- chat.Inbound{Channel: "telegram", SenderID: "1", ChatID: "1", Content: prompt.Buttons[0][0].Data}
+	next()
+	if data, _ := os.ReadFile(target); string(data) != "new" {
+		t.Fatalf("expected approved overwrite, got %q", data)
+	}
+}
+
+// confirmProvider asks the user to pick a color, then echoes the answer.
+type confirmProvider struct{ calls int }
+
+func (p *confirmProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string) (providers.LLMResponse, error) {
+	p.calls++
+	if p.calls == 1 {
+		args := map[string]interface{}{"question": "Which color?", "options": []interface{}{"Red", "Blue"}}
+		return providers.LLMResponse{HasToolCalls: true, ToolCalls: []providers.ToolCall{{ID: "1", Name: "confirm", Arguments: args}}}, nil
+	}
+	return providers.LLMResponse{Content: messages[len(messages)-1].Content}, nil
+}
+func (p *confirmProvider) GetDefaultModel() string { return "test" }
+
+func TestConfirmToolButtons(t *testing.T) {
+	b := chat.NewHub(10)
+	ag := newTestLoop(t, b, &confirmProvider{}, "test", 5, t.TempDir(), nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go ag.Run(ctx)
+
+	b.In <- chat.Inbound{Channel: "telegram", SenderID: "1", ChatID: "1", Content: "pick a color"}
+	var prompt chat.Outbound
+	select {
+	case prompt = <-b.Out:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for question")
+	}
+	if !strings.Contains(prompt.Content, "Which color?") || len(prompt.Buttons) != 1 || len(prompt.Buttons[0]) != 2 {
+		t.Fatalf("expected question with two buttons, got %+v", prompt)
+	}
+
+	// a press from someone else in the chat is not an answer
+	data := prompt.Buttons[0][1].Data
+	b.In <- chat.Inbound{Channel: "telegram", SenderID: "2", ChatID: "1", Content: data, Button: &chat.ButtonPress{Data: data}}
+	b.In <- chat.Inbound{Channel: "telegram", SenderID: "1", ChatID: "1", Content: data, Button: &chat.ButtonPress{Data: data}}
+	for {
+		select {
+		case out := <-b.Out:
+			if strings.Contains(out.Content, "The user answered: Blue") {
+				return
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout waiting for answer to reach the model")
+		}
+	}
+}
+
+func TestInternalResultIsNoAnswer(t *testing.T) {
+	b := chat.NewHub(10)
+	ag := newTestLoop(t, b, &confirmProvider{}, "test", 5, t.TempDir(), nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go ag.Run(ctx)
+
+	b.In <- chat.Inbound{Channel: "telegram", SenderID: "1", ChatID: "1", Content: "pick a color"}
+	var prompt chat.Outbound
+	select {
+	case prompt = <-b.Out:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for question")
+	}
+	// a subagent's report carries the chat's sender, but is not the user's answer
+	b.In <- chat.Inbound{Channel: "telegram", SenderID: "1", ChatID: "1", Content: "Red",
+		Internal: &chat.InternalResult{CallID: "report_sa1", Tool: "subagent_status"}}
+	data := prompt.Buttons[0][1].Data
+	b.In <- chat.Inbound{Channel: "telegram", SenderID: "1", ChatID: "1", Content: data, Button: &chat.ButtonPress{Data: data}}
+	for {
+		select {
+		case out := <-b.Out:
+			if strings.Contains(out.Content, "The user answered:") {
+				if !strings.Contains(out.Content, "Blue") {
+					t.Fatalf("an internal result answered the question: %q", out.Content)
+				}
+				return
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout waiting for answer to reach the model")
+		}
+	}
+}
+
+func TestApprovalRules(t *testing.T) {
+	ag := newTestLoop(t, chat.NewHub(10), &confirmProvider{}, "test", 5, t.TempDir(), nil)
+	ag.SetApprovalRules([]ApprovalRule{
+		{Tool: "message"},
+		{Tool: "exec", Args: map[string]*regexp.Regexp{"cmd": regexp.MustCompile(`"?docker\b`)}},
+	})
+	cases := []struct {
+		tc   providers.ToolCall
+		want bool
+	}{
+		{providers.ToolCall{Name: "message", Arguments: map[string]interface{}{"content": "hi"}}, true},
+		{providers.ToolCall{Name: "exec", Arguments: map[string]interface{}{"cmd": []interface{}{"docker", "ps"}}}, true},
+		{providers.ToolCall{Name: "exec", Arguments: map[string]interface{}{"cmd": "docker ps"}}, true},
+		{providers.ToolCall{Name: "exec", Arguments: map[string]interface{}{"cmd": []interface{}{"ls"}}}, false},
+		{providers.ToolCall{Name: "web", Arguments: map[string]interface{}{"url": "https://example.com"}}, false},
+	}
+	for _, c := range cases {
+		if got := ag.approvalAction(ag.tenant, nil, c.tc) != ""; got != c.want {
+			t.Errorf("%s %v: needs approval = %v, want %v", c.tc.Name, c.tc.Arguments, got, c.want)
+		}
+	}
+}
+
+func TestEmailSendNeedsApproval(t *testing.T) {
+	ag := newTestLoop(t, chat.NewHub(10), &confirmProvider{}, "test", 5, t.TempDir(), nil)
+	tc := providers.ToolCall{Name: "message", Arguments: map[string]interface{}{"content": "hi"}}
+	if got := ag.approvalAction(ag.tenant, &chat.Inbound{Channel: "email", ChatID: "bob@example.com"}, tc); got != "send an email to bob@example.com" {
+		t.Errorf("email: action = %q", got)
+	}
+	if got := ag.approvalAction(ag.tenant, &chat.Inbound{Channel: "telegram", ChatID: "42"}, tc); got != "" {
+		t.Errorf("telegram: action = %q, want none", got)
+	}
+}
@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+// spendingProvider calls a tool with every reply, reporting 600 tokens each
+// time, and summarizes when asked to.
+type spendingProvider struct{ calls int }
+
+func (p *spendingProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string) (providers.LLMResponse, error) {
+	if strings.Contains(messages[0].Content, "interrupted") {
+		return providers.LLMResponse{Content: "I set the scratchpad twice."}, nil
+	}
+	p.calls++
+	tc := providers.ToolCall{ID: "1", Name: "scratchpad", Arguments: map[string]interface{}{"action": "set", "key": "k", "value": "v"}}
+	return providers.LLMResponse{HasToolCalls: true, ToolCalls: []providers.ToolCall{tc}, Usage: providers.Usage{PromptTokens: 500, CompletionTokens: 100}}, nil
+}
+
+func (p *spendingProvider) GetDefaultModel() string { return "fake" }
+
+func TestTaskBudget(t *testing.T) {
+	hub := chat.NewHub(10)
+	p := &spendingProvider{}
+	ag := newTestLoop(t, hub, p, "fake", 100, t.TempDir(), nil)
+	ag.SetTaskBudget(TaskBudget{Tokens: 1000})
+	from := func(content string) chat.Inbound {
+		return chat.Inbound{Channel: "telegram", SenderID: "1", ChatID: "1", Content: content}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ag.processMessage(context.Background(), from("keep setting the scratchpad"))
+		close(done)
+	}()
+	if out := <-hub.Out; !strings.Contains(out.Content, "budget of 1000 tokens") || !strings.Contains(out.Content, "I set the scratchpad twice.") {
+		t.Fatalf("unexpected question: %+v", out)
+	}
+	if !ag.approvals.resolve(from("yes")) {
+		t.Fatal("continuing not taken")
+	}
+	if out := <-hub.Out; !strings.Contains(out.Content, "budget of 1000 tokens") {
+		t.Fatalf("unexpected second question: %+v", out)
+	}
+	if p.calls != 4 {
+		t.Fatalf("expected a fresh budget of 2 more calls, got %d calls", p.calls)
+	}
+	if !ag.approvals.resolve(from("no")) {
+		t.Fatal("stopping not taken")
+	}
+	<-done
+	if out := <-hub.Out; !strings.Contains(out.Content, "I set the scratchpad twice.") || !strings.Contains(out.Content, "(Stopped: the task reached its budget of 1000 tokens.)") {
+		t.Fatalf("unexpected reply: %q", out.Content)
+	}
+
+	// cron tasks stop without asking
+	ag.processMessage(context.Background(), chat.Inbound{Channel: "telegram", SenderID: "cron", ChatID: "1", Content: "scheduled work", Scheduled: &chat.ScheduledJob{ID: "job-1"}})
+	if out := <-hub.Out; !strings.Contains(out.Content, "(Stopped:") {
+		t.Fatalf("unexpected cron reply: %q", out.Content)
+	}
+}
@@ -0,0 +1,62 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+func TestChatCommands(t *testing.T) {
+	b := chat.NewHub(10)
+	p := providers.NewStubProvider()
+	ag := newTestLoop(t, b, p, p.GetDefaultModel(), 3, t.TempDir(), nil)
+	ag.SetAdmins([]string{"telegram:42"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	go ag.Run(ctx)
+
+	send := func(sender, content string) string {
+		b.In <- chat.Inbound{Channel: "telegram", SenderID: sender, ChatID: "1", Content: content}
+		select {
+		case out := <-b.Out:
+			return out.Content
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout waiting for reply to %q", content)
+		}
+		return ""
+	}
+
+	send("7", "hello there")
+	if reply := send("7", "/status@picobot_bot"); !strings.Contains(reply, "Model: "+p.GetDefaultModel()) || !strings.Contains(reply, "History: 2 messages") {
+		t.Fatalf("unexpected status: %q", reply)
+	}
+	if reply := send("7", "/reset"); !strings.Contains(reply, "cleared") {
+		t.Fatalf("unexpected reset reply: %q", reply)
+	}
+	if n := len(ag.tenant.sessions.GetOrCreate("telegram", "1").GetHistory()); n != 0 {
+		t.Fatalf("expected empty history after /reset, got %d messages", n)
+	}
+
+	if reply := send("7", "/memory"); reply != "No notes for today yet." {
+		t.Fatalf("unexpected memory reply: %q", reply)
+	}
+	ag.tenant.memory.AppendToday("buy milk")
+	if reply := send("7", "/memory"); !strings.Contains(reply, "buy milk") {
+		t.Fatalf("expected today's notes, got %q", reply)
+	}
+
+	if reply := send("7", "/model other"); reply != notAdminReply {
+		t.Fatalf("expected non-admin to be refused, got %q", reply)
+	}
+	if reply := send("42", "/model other"); !strings.Contains(reply, "Switched model to other") {
+		t.Fatalf("unexpected model reply: %q", reply)
+	}
+	if reply := send("7", "/model"); reply != "Active model: other" {
+		t.Fatalf("unexpected model reply: %q", reply)
+	}
+}
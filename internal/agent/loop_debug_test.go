@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/debug"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+func TestDebugCommandTogglesTracing(t *testing.T) {
+	b := chat.NewHub(10)
+	p := providers.NewStubProvider()
+	ag := newTestLoop(t, b, p, p.GetDefaultModel(), 3, t.TempDir(), nil)
+	logPath := filepath.Join(t.TempDir(), "debug.log")
+	tr, err := debug.NewTracer(logPath, false)
+	if err != nil {
+		t.Fatalf("NewTracer: %v", err)
+	}
+	defer tr.Close()
+	ag.SetTracer(tr)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	go ag.Run(ctx)
+
+	send := func(content string) string {
+		b.In <- chat.Inbound{Channel: "cli", SenderID: "user", ChatID: "one", Content: content}
+		select {
+		case out := <-b.Out:
+			return out.Content
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout waiting for reply to %q", content)
+		}
+		return ""
+	}
+
+	if reply := send("/debug on"); !strings.Contains(reply, "Debug tracing on") {
+		t.Fatalf("unexpected reply: %q", reply)
+	}
+	if !tr.Enabled() {
+		t.Fatalf("expected tracer to be enabled")
+	}
+	send("hello tracer")
+	if reply := send("/debug off"); !strings.Contains(reply, "Debug tracing off") {
+		t.Fatalf("unexpected reply: %q", reply)
+	}
+
+	b2, _ := os.ReadFile(logPath)
+	if !strings.Contains(string(b2), "hello tracer") {
+		t.Fatalf("expected prompt to be traced, got %q", string(b2))
+	}
+}
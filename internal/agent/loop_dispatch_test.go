@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+// gateProvider echoes the user's message; messages starting with "slow"
+// wait until release is closed.
+type gateProvider struct {
+	release chan struct{}
+}
+
+func (p *gateProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string) (providers.LLMResponse, error) {
+	content := messages[len(messages)-1].Content
+	if strings.HasPrefix(content, "slow") {
+		select {
+		case <-p.release:
+		case <-ctx.Done():
+			return providers.LLMResponse{}, ctx.Err()
+		}
+	}
+	return providers.LLMResponse{Content: "re: " + content}, nil
+}
+
+func (p *gateProvider) GetDefaultModel() string { return "fake" }
+
+func TestChatsRunConcurrentlyInOrder(t *testing.T) {
+	hub := chat.NewHub(10)
+	p := &gateProvider{release: make(chan struct{})}
+	ag := newTestLoop(t, hub, p, "fake", 5, t.TempDir(), nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go ag.Run(ctx)
+
+	next := func() chat.Outbound {
+		t.Helper()
+		select {
+		case out := <-hub.Out:
+			return out
+		case <-time.After(5 * time.Second):
+			t.Fatal("no reply")
+		}
+		return chat.Outbound{}
+	}
+	hub.In <- chat.Inbound{Channel: "heartbeat", SenderID: "heartbeat", ChatID: "heartbeat", Content: "slow check"}
+	hub.In <- chat.Inbound{Channel: "telegram", SenderID: "1", ChatID: "1", Content: "slow one"}
+	hub.In <- chat.Inbound{Channel: "telegram", SenderID: "1", ChatID: "1", Content: "then two"}
+	hub.In <- chat.Inbound{Channel: "telegram", SenderID: "2", ChatID: "2", Content: "quick question"}
+	if out := next(); out.Content != "re: quick question" {
+		t.Fatalf("a slow chat held up another: %+v", out)
+	}
+
+	close(p.release)
+	var replies []string
+	for range 3 {
+		if out := next(); out.ChatID == "1" {
+			replies = append(replies, out.Content)
+		}
+	}
+	if strings.Join(replies, "|") != "re: slow one|re: then two" {
+		t.Fatalf("messages of a chat answered out of order: %q", replies)
+	}
+}
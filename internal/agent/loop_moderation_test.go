@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/moderation"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+func TestModerationBlocksMessageTool(t *testing.T) {
+	hub := chat.NewHub(10)
+	p := providers.NewStubProvider()
+	ag := newTestLoop(t, hub, p, p.GetDefaultModel(), 3, t.TempDir(), nil)
+	rules, err := moderation.NewRules(map[string][]string{"profanity": {"darn"}})
+	if err != nil {
+		t.Fatalf("NewRules: %v", err)
+	}
+	ag.SetModerator(rules, "")
+	ag.SetAdmins([]string{"telegram:99"})
+
+	msg := &chat.Inbound{Channel: "telegram", SenderID: "1", ChatID: "1"}
+	res := ag.runTool(context.Background(), ag.tenant, msg, providers.ToolCall{ID: "1", Name: "message", Arguments: map[string]interface{}{"content": "darn it"}})
+	if !strings.Contains(res, "content filter") {
+		t.Fatalf("expected message to be blocked, got %q", res)
+	}
+	out := <-hub.Out
+	if out.ChatID != "99" || !strings.Contains(out.Content, "profanity") {
+		t.Fatalf("expected admin notification, got %+v", out)
+	}
+	select {
+	case out := <-hub.Out:
+		t.Fatalf("blocked message was delivered: %+v", out)
+	default:
+	}
+
+	ag.SetModerator(rules, ModerationFlag)
+	if res := ag.runTool(context.Background(), ag.tenant, msg, providers.ToolCall{ID: "2", Name: "message", Arguments: map[string]interface{}{"content": "darn it"}}); res != "sent" {
+		t.Fatalf("flag mode should deliver the message, got %q", res)
+	}
+}
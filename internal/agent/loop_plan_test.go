@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+// plannerProvider drafts a plan when asked for one, revising it once, then
+// marks the first step done and finishes.
+type plannerProvider struct {
+	t        *testing.T
+	ws       string
+	revised  bool
+	executed bool
+}
+
+func (p *plannerProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string) (providers.LLMResponse, error) {
+	last := messages[len(messages)-1]
+	if strings.Contains(last.Content, "Don't start working yet") {
+		if strings.HasPrefix(last.Content, "Change the plan: shorter") {
+			p.revised = true
+			return providers.LLMResponse{Content: "1. Fetch the page\n2. Summarize it"}, nil
+		}
+		return providers.LLMResponse{Content: "Sure:\n1. Fetch the page\n2. Read it twice\n3. Summarize it"}, nil
+	}
+	if last.Role == "tool" {
+		if !strings.Contains(last.Content, "1. [x] Fetch the page (fetched)") {
+			p.t.Errorf("unexpected update_plan result: %q", last.Content)
+		}
+		return providers.LLMResponse{Content: "Here is the summary."}, nil
+	}
+	p.executed = true
+	offered := false
+	for _, d := range tools {
+		offered = offered || d.Name == "update_plan"
+	}
+	if !offered || !strings.Contains(last.Content, "[The user approved this plan]\n1. [ ] Fetch the page\n2. [ ] Summarize it") {
+		p.t.Errorf("plan not handed to the model (update_plan offered: %v): %q", offered, last.Content)
+	}
+	tc := providers.ToolCall{ID: "1", Name: "update_plan", Arguments: map[string]interface{}{"step": 1.0, "status": "done", "note": "fetched"}}
+	return providers.LLMResponse{HasToolCalls: true, ToolCalls: []providers.ToolCall{tc}}, nil
+}
+
+func (p *plannerProvider) GetDefaultModel() string { return "fake" }
+
+func TestPlanCommand(t *testing.T) {
+	ws := t.TempDir()
+	hub := chat.NewHub(10)
+	p := &plannerProvider{t: t, ws: ws}
+	ag := newTestLoop(t, hub, p, "fake", 5, ws, nil)
+	from := func(content string) chat.Inbound {
+		return chat.Inbound{Channel: "telegram", SenderID: "1", ChatID: "1", Content: content}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ag.processMessage(context.Background(), from("/plan summarize example.com"))
+		close(done)
+	}()
+	if out := <-hub.Out; !strings.Contains(out.Content, "3. Summarize it") || len(out.Buttons) != 1 {
+		t.Fatalf("unexpected plan question: %+v", out)
+	}
+	if !ag.approvals.resolve(from("shorter please")) {
+		t.Fatal("a change to the plan was not taken as an answer")
+	}
+	if out := <-hub.Out; !strings.Contains(out.Content, "2. Summarize it") {
+		t.Fatalf("unexpected revised plan: %+v", out)
+	}
+	if !ag.approvals.resolve(from("yes")) {
+		t.Fatal("approval not taken")
+	}
+	<-done
+	if out := <-hub.Out; out.Content != "Here is the summary." {
+		t.Fatalf("unexpected reply: %+v", out)
+	}
+	if !p.revised || !p.executed {
+		t.Fatalf("revised %v, executed %v", p.revised, p.executed)
+	}
+	if _, err := os.Stat(planPath(ws, "telegram", "1")); !os.IsNotExist(err) {
+		t.Fatal("the plan file must be removed once the task is done")
+	}
+	h := ag.sessions.GetOrCreate("telegram", "1").GetHistory()
+	if len(h) < 4 || h[0].Content != "summarize example.com" || h[1].ToolCalls[0].Name != "update_plan" || h[len(h)-1].Role != "assistant" {
+		t.Fatalf("unexpected history: %+v", h)
+	}
+
+	// a cancelled plan is not worked on
+	p.executed = false
+	go ag.processMessage(context.Background(), from("/plan summarize example.com"))
+	<-hub.Out
+	ag.approvals.resolve(from("no"))
+	if out := <-hub.Out; !strings.Contains(out.Content, "won't go ahead") || p.executed {
+		t.Fatalf("cancelled plan: %+v, executed %v", out, p.executed)
+	}
+
+	ag.processMessage(context.Background(), from("/plan"))
+	if out := <-hub.Out; !strings.Contains(out.Content, "Send /plan followed by a request") {
+		t.Fatalf("unexpected /plan reply: %+v", out)
+	}
+}
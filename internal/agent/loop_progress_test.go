@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+// stepsProvider reports its progress, then calls the scratchpad until it
+// has made steps rounds of tool calls.
+type stepsProvider struct {
+	steps, calls int
+}
+
+func (p *stepsProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string) (providers.LLMResponse, error) {
+	p.calls++
+	if p.calls > p.steps {
+		return providers.LLMResponse{Content: "all done"}, nil
+	}
+	tc := providers.ToolCall{ID: "1", Name: "scratchpad", Arguments: map[string]interface{}{"action": "list"}}
+	if p.calls == 1 {
+		tc = providers.ToolCall{ID: "1", Name: "report_progress", Arguments: map[string]interface{}{"status": "warming up", "step": 1.0, "total": 4.0}}
+	}
+	return providers.LLMResponse{HasToolCalls: true, ToolCalls: []providers.ToolCall{tc}}, nil
+}
+
+func (p *stepsProvider) GetDefaultModel() string { return "fake" }
+
+func TestProgressUpdates(t *testing.T) {
+	hub := chat.NewHub(10)
+	ag := newTestLoop(t, hub, &stepsProvider{steps: 4}, "fake", 10, t.TempDir(), nil)
+	msg := chat.Inbound{Channel: "telegram", SenderID: "1", ChatID: "1", Content: "long job"}
+
+	// off unless configured
+	ag.processMessage(context.Background(), msg)
+	if out := <-hub.Out; out.Content != "all done" {
+		t.Fatalf("unexpected update without progress updates: %q", out.Content)
+	}
+
+	ag = newTestLoop(t, hub, &stepsProvider{steps: 4}, "fake", 10, t.TempDir(), nil)
+	ag.SetProgressUpdates(ProgressUpdates{AfterSteps: 2, After: -1, Every: time.Nanosecond})
+	ag.processMessage(context.Background(), msg)
+	var got []string
+	for len(hub.Out) > 0 {
+		got = append(got, (<-hub.Out).Content)
+	}
+	want := []string{"Step 1/4: warming up", "Still working on it (step 3): running scratchpad…", "Still working on it (step 4): running scratchpad…", "all done"}
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/agent/memory"
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+// reflectingProvider answers chats with "ok" and reflections with a fact.
+type reflectingProvider struct {
+	mu          sync.Mutex
+	reflections []string // conversations reviewed
+}
+
+func (p *reflectingProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string) (providers.LLMResponse, error) {
+	if strings.Contains(messages[0].Content, "You review a conversation") {
+		p.mu.Lock()
+		p.reflections = append(p.reflections, model+"|"+messages[len(messages)-1].Content)
+		p.mu.Unlock()
+		return providers.LLMResponse{Content: `[{"text": "The user plays the cello", "target": "long"}]`}, nil
+	}
+	return providers.LLMResponse{Content: "ok"}, nil
+}
+
+func (p *reflectingProvider) GetDefaultModel() string { return "big" }
+
+func TestReflectionEveryNthExchange(t *testing.T) {
+	p := &reflectingProvider{}
+	ag := newTestLoop(t, chat.NewHub(10), p, "big", 3, t.TempDir(), nil)
+	ag.SetModelRoutes(map[string]string{TaskReflect: "small"})
+	ag.SetReflection(2)
+
+	for _, text := range []string{"hi", "I play the cello", "bye"} {
+		ag.processMessage(context.Background(), chat.Inbound{Channel: "telegram", SenderID: "1", ChatID: "1", Content: text})
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if got, _ := ag.memory.Query(memory.Query{Tag: memory.ReflectTag}); len(got) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("reflection stored nothing")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.reflections) != 1 {
+		t.Fatalf("expected one reflection after two exchanges, got %d", len(p.reflections))
+	}
+	r := p.reflections[0]
+	if !strings.HasPrefix(r, "small|") || !strings.Contains(r, "user: hi") || !strings.Contains(r, "user: I play the cello") || strings.Contains(r, "bye") {
+		t.Fatalf("unexpected reflection request: %s", r)
+	}
+}
@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/kr0nicas/picobot/internal/agent/memory"
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+// modelRecorder records the model of every request.
+type modelRecorder struct {
+	mu     sync.Mutex
+	models []string
+}
+
+func (p *modelRecorder) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string) (providers.LLMResponse, error) {
+	p.mu.Lock()
+	p.models = append(p.models, model)
+	p.mu.Unlock()
+	return providers.LLMResponse{Content: "ok"}, nil
+}
+
+func (p *modelRecorder) GetDefaultModel() string { return "default" }
+
+func TestModelRoutes(t *testing.T) {
+	p := &modelRecorder{}
+	ag := newTestLoop(t, chat.NewHub(10), p, "big", 3, t.TempDir(), nil)
+	ag.SetModelRoutes(map[string]string{TaskRanking: "small", TaskHeartbeat: "cheap", TaskChat: "ignored"})
+
+	if ag.ModelFor(TaskChat) != "big" || ag.ModelFor(TaskSummarize) != "big" || ag.ModelFor(TaskRanking) != "small" {
+		t.Fatalf("unexpected routes: chat=%s summarize=%s ranking=%s", ag.ModelFor(TaskChat), ag.ModelFor(TaskSummarize), ag.ModelFor(TaskRanking))
+	}
+
+	ag.processMessage(context.Background(), chat.Inbound{Channel: "telegram", SenderID: "1", ChatID: "1", Content: "hi"})
+	ag.processMessage(context.Background(), chat.Inbound{Channel: "heartbeat", SenderID: "heartbeat", ChatID: "hb", Content: "check"})
+	// the ranker is built once but follows the current route
+	ag.context.ranker.Rank("q", []memory.MemoryItem{{Kind: "short", Text: "a"}, {Kind: "short", Text: "b"}}, 1)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	want := []string{"big", "cheap", "small"}
+	if len(p.models) != len(want) {
+		t.Fatalf("expected models %v, got %v", want, p.models)
+	}
+	for i := range want {
+		if p.models[i] != want[i] {
+			t.Fatalf("expected models %v, got %v", want, p.models)
+		}
+	}
+}
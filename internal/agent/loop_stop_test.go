@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+// endlessProvider keeps calling tools; from the second call on it waits
+// for the request to be cancelled.
+type endlessProvider struct {
+	calls   int
+	waiting chan struct{}
+}
+
+func (p *endlessProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string) (providers.LLMResponse, error) {
+	p.calls++
+	if p.calls > 1 {
+		close(p.waiting)
+		<-ctx.Done()
+		return providers.LLMResponse{}, ctx.Err()
+	}
+	tc := providers.ToolCall{ID: "1", Name: "scratchpad", Arguments: map[string]interface{}{"action": "set", "key": "k", "value": "step one"}}
+	return providers.LLMResponse{HasToolCalls: true, ToolCalls: []providers.ToolCall{tc}}, nil
+}
+
+func (p *endlessProvider) GetDefaultModel() string { return "fake" }
+
+func TestStopRunningTask(t *testing.T) {
+	ws := t.TempDir()
+	hub := chat.NewHub(10)
+	p := &endlessProvider{waiting: make(chan struct{})}
+	ag := newTestLoop(t, hub, p, "fake", 100, ws, nil)
+
+	if ag.stopTask(chat.Inbound{Channel: "telegram", SenderID: "1", ChatID: "9", Content: "/stop"}) {
+		t.Fatal("stopped a task that isn't running")
+	}
+	done := make(chan struct{})
+	go func() {
+		ag.processMessage(context.Background(), chat.Inbound{Channel: "telegram", SenderID: "1", ChatID: "9", Content: "work forever"})
+		close(done)
+	}()
+	<-p.waiting
+
+	if ag.stopTask(chat.Inbound{Channel: "telegram", SenderID: "2", ChatID: "9", Content: "stop"}) {
+		t.Fatal("another user stopped the task")
+	}
+	if ag.stopTask(chat.Inbound{Channel: "telegram", SenderID: "1", ChatID: "9", Content: "stop the music"}) {
+		t.Fatal("a message mentioning stop stopped the task")
+	}
+	if !ag.stopTask(chat.Inbound{Channel: "telegram", SenderID: "1", ChatID: "9", Content: "Cancel!"}) {
+		t.Fatal("cancel did not stop the task")
+	}
+	<-done
+	out := <-hub.Out
+	if !strings.Contains(out.Content, "Stopped after 1 step.") || !strings.Contains(out.Content, "scratchpad") {
+		t.Fatalf("unexpected reply: %q", out.Content)
+	}
+	if _, err := os.Stat(taskStatePath(ws, "telegram", "9")); !os.IsNotExist(err) {
+		t.Fatal("a stopped task must not be offered to resume")
+	}
+	if ag.stopTask(chat.Inbound{Channel: "telegram", SenderID: "1", ChatID: "9", Content: "/stop"}) {
+		t.Fatal("stopped a finished task")
+	}
+}
@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/providers"
+	"github.com/kr0nicas/picobot/internal/redact"
+)
+
+// streamingProvider streams a fixed reply in two fragments.
+type streamingProvider struct{}
+
+func (streamingProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string) (providers.LLMResponse, error) {
+	return providers.LLMResponse{Content: "Hello world"}, nil
+}
+
+func (p streamingProvider) ChatStream(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, onDelta providers.StreamFunc) (providers.LLMResponse, error) {
+	onDelta("Hello ")
+	onDelta("world")
+	return p.Chat(ctx, messages, tools, model)
+}
+
+func (streamingProvider) GetDefaultModel() string { return "stream" }
+
+func TestStreamingEmitsTokenEvents(t *testing.T) {
+	hub := chat.NewHub(10)
+	events := hub.SubscribeEvents("websocket")
+	ag := newTestLoop(t, hub, streamingProvider{}, "stream", 3, t.TempDir(), nil)
+	ag.SetStreaming(true)
+
+	ag.processMessage(context.Background(), chat.Inbound{Channel: "websocket", SenderID: "s", ChatID: "s", Content: "hi"})
+
+	var got []string
+	for len(events) > 0 {
+		ev := <-events
+		if ev.Type == chat.EventToken {
+			got = append(got, ev.Content)
+		}
+	}
+	if strings.Join(got, "|") != "Hello |world" {
+		t.Fatalf("unexpected token events: %q", got)
+	}
+	if out := <-hub.Out; out.Content != "Hello world" {
+		t.Fatalf("unexpected final reply: %+v", out)
+	}
+}
+
+// leakyProvider streams a reply with a secret split across two fragments.
+type leakyProvider struct{ streamingProvider }
+
+func (leakyProvider) ChatStream(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, onDelta providers.StreamFunc) (providers.LLMResponse, error) {
+	onDelta("the token is my-very-sec")
+	onDelta("ret-token, keep it safe")
+	return providers.LLMResponse{Content: "the token is my-very-secret-token, keep it safe"}, nil
+}
+
+func TestStreamingRedactsSecretsSplitAcrossDeltas(t *testing.T) {
+	hub := chat.NewHub(10)
+	events := hub.SubscribeEvents("websocket")
+	ag := newTestLoop(t, hub, leakyProvider{}, "stream", 3, t.TempDir(), nil)
+	ag.SetStreaming(true)
+	ag.SetRedactor(redact.New("my-very-secret-token"))
+
+	ag.processMessage(context.Background(), chat.Inbound{Channel: "websocket", SenderID: "s", ChatID: "s", Content: "hi"})
+
+	var got []string
+	for len(events) > 0 {
+		ev := <-events
+		if ev.Type == chat.EventToken {
+			got = append(got, ev.Content)
+		}
+	}
+	streamed := strings.Join(got, "")
+	if streamed != "the token is "+redact.Placeholder+", keep it safe" {
+		t.Fatalf("secret leaked into the stream: %q", got)
+	}
+}
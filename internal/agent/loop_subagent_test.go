@@ -0,0 +1,114 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+// subagentProvider spawns a subagent limited to the filesystem tool, which
+// lists the workspace and reports; the main agent relays the report.
+type subagentProvider struct {
+	mu            sync.Mutex
+	subagentTools []string
+}
+
+func (p *subagentProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string) (providers.LLMResponse, error) {
+	last := messages[len(messages)-1]
+	first := ""
+	for _, m := range messages {
+		if m.Role == "user" {
+			first = m.Content
+			break
+		}
+	}
+	switch {
+	case strings.Contains(first, subagentInstruction):
+		if last.Role == "tool" {
+			return providers.LLMResponse{Content: "the workspace is ready"}, nil
+		}
+		p.mu.Lock()
+		for _, d := range tools {
+			p.subagentTools = append(p.subagentTools, d.Name)
+		}
+		p.mu.Unlock()
+		args := map[string]interface{}{"action": "list", "path": "."}
+		return providers.LLMResponse{HasToolCalls: true, ToolCalls: []providers.ToolCall{{ID: "s1", Name: "filesystem", Arguments: args}}}, nil
+	case strings.HasPrefix(last.Content, "[Subagent"):
+		return providers.LLMResponse{Content: "Relayed: " + last.Content}, nil
+	case last.Role == "tool":
+		return providers.LLMResponse{Content: last.Content}, nil
+	}
+	args := map[string]interface{}{"task": "check the workspace", "tools": []interface{}{"filesystem"}}
+	return providers.LLMResponse{HasToolCalls: true, ToolCalls: []providers.ToolCall{{ID: "1", Name: "spawn", Arguments: args}}}, nil
+}
+func (p *subagentProvider) GetDefaultModel() string { return "test" }
+
+func TestSpawnedSubagentReportsToChat(t *testing.T) {
+	b := chat.NewHub(10)
+	p := &subagentProvider{}
+	ag := newTestLoop(t, b, p, "test", 5, t.TempDir(), nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go ag.Run(ctx)
+
+	next := func() chat.Outbound {
+		select {
+		case out := <-b.Out:
+			return out
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout waiting for outbound")
+		}
+		return chat.Outbound{}
+	}
+
+	b.In <- chat.Inbound{Channel: "telegram", SenderID: "1", ChatID: "1", Content: "look around"}
+	if out := next(); !strings.Contains(out.Content, "Started subagent sa1") {
+		t.Fatalf("expected the spawn result, got %q", out.Content)
+	}
+	out := next()
+	if !strings.Contains(out.Content, "[Subagent sa1 finished]") || !strings.Contains(out.Content, "the workspace is ready") {
+		t.Fatalf("expected the subagent's report, got %q", out.Content)
+	}
+
+	// the report is recorded as a tool result, not as words of the user
+	history := ag.tenant.sessions.GetOrCreate("telegram", "1").GetHistory()
+	reported := false
+	for _, m := range history {
+		if m.Role == "user" && strings.Contains(m.Content, "[Subagent") {
+			t.Fatalf("the report was recorded as a user message: %+v", history)
+		}
+		reported = reported || m.Role == "tool" && m.ToolCallID == "report_sa1"
+	}
+	if !reported {
+		t.Fatalf("expected the report in the history as a tool result: %+v", history)
+	}
+
+	p.mu.Lock()
+	got := strings.Join(p.subagentTools, ",")
+	p.mu.Unlock()
+	if got != "filesystem" {
+		t.Fatalf("subagent was offered %s, want only filesystem", got)
+	}
+	list := ag.subagents.List("telegram", "1")
+	if len(list) != 1 || list[0].Status != "done" || list[0].Iterations != 2 || list[0].LastTool != "filesystem" {
+		t.Fatalf("unexpected status: %+v", list)
+	}
+}
+
+func TestSpawnNeedsChat(t *testing.T) {
+	b := chat.NewHub(10)
+	ag := newTestLoop(t, b, &subagentProvider{}, "test", 5, t.TempDir(), nil)
+	out, err := ag.ProcessDirect("look around", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "only be started from a chat") {
+		t.Fatalf("expected spawn to be refused outside chats, got %q", out)
+	}
+}
@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+// crashingProvider calls a tool, then "crashes" by cancelling the task's
+// context; once revived it answers with the last tool result it was sent.
+type crashingProvider struct {
+	crash func()
+	calls int
+}
+
+func (p *crashingProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string) (providers.LLMResponse, error) {
+	p.calls++
+	if p.crash == nil {
+		last := messages[len(messages)-1]
+		return providers.LLMResponse{Content: "done after " + last.Role + ": " + last.Content}, nil
+	}
+	if p.calls == 1 {
+		tc := providers.ToolCall{ID: "1", Name: "scratchpad", Arguments: map[string]interface{}{"action": "set", "key": "k", "value": "step one"}}
+		return providers.LLMResponse{HasToolCalls: true, ToolCalls: []providers.ToolCall{tc}}, nil
+	}
+	p.crash()
+	return providers.LLMResponse{}, ctx.Err()
+}
+
+func (p *crashingProvider) GetDefaultModel() string { return "fake" }
+
+func TestInterruptedTaskResumes(t *testing.T) {
+	ws := t.TempDir()
+	hub := chat.NewHub(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &crashingProvider{crash: cancel}
+	ag := newTestLoop(t, hub, p, "fake", 5, ws, nil)
+	msg := chat.Inbound{Channel: "telegram", SenderID: "1", ChatID: "9", Content: "do the long thing"}
+	ag.processMessage(ctx, msg)
+	if len(hub.Out) != 0 {
+		t.Fatal("an interrupted task must not be answered")
+	}
+
+	path := taskStatePath(ws, "telegram", "9")
+	st, err := loadTaskState(path)
+	if err != nil || st == nil {
+		t.Fatalf("no task state after the crash: %v", err)
+	}
+	if st.Request != "do the long thing" || st.Iteration != 1 || len(st.Steps) != 2 {
+		t.Fatalf("unexpected task state: %+v", st)
+	}
+
+	// restart
+	p2 := &crashingProvider{}
+	ag2 := newTestLoop(t, hub, p2, "fake", 5, ws, nil)
+	ag2.announceInterruptedTasks()
+	if out := <-hub.Out; out.ChatID != "9" || !strings.Contains(out.Content, "/resume") {
+		t.Fatalf("unexpected announcement: %+v", out)
+	}
+	ag2.processMessage(context.Background(), chat.Inbound{Channel: "telegram", SenderID: "1", ChatID: "9", Content: "/resume"})
+	if out := <-hub.Out; !strings.Contains(out.Content, "done after tool") {
+		t.Fatalf("task not resumed from its steps: %+v", out)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("task state must be removed once the task is done")
+	}
+	h := ag2.sessions.GetOrCreate("telegram", "9").GetHistory()
+	if len(h) != 4 || h[0].Content != "do the long thing" || h[1].ToolCalls[0].Name != "scratchpad" || h[2].ToolCallID != "1" {
+		t.Fatalf("unexpected history: %+v", h)
+	}
+
+	ag2.processMessage(context.Background(), chat.Inbound{Channel: "telegram", SenderID: "1", ChatID: "9", Content: "/summarize"})
+	if out := <-hub.Out; !strings.Contains(out.Content, "no interrupted task") {
+		t.Fatalf("unexpected reply: %+v", out)
+	}
+}
@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/telemetry"
+)
+
+func TestMessageIsTraced(t *testing.T) {
+	type span struct {
+		TraceID, SpanID, ParentSpanID, Name string
+	}
+	var mu sync.Mutex
+	var spans []span
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct{ Spans []span }
+			}
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		defer mu.Unlock()
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				spans = append(spans, ss.Spans...)
+			}
+		}
+	}))
+	defer srv.Close()
+	shutdown, err := telemetry.Setup(telemetry.Options{Endpoint: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hub := chat.NewHub(10)
+	ag := newTestLoop(t, hub, &twoToolsProvider{}, "fake", 5, t.TempDir(), nil)
+	ag.processMessage(context.Background(), chat.Inbound{Channel: "telegram", SenderID: "7", ChatID: "1", Content: "go"})
+	var reply chat.Outbound
+	for reply = range hub.Out {
+		if reply.Content == "done" {
+			break
+		}
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	var root span
+	for _, s := range spans {
+		names = append(names, s.Name)
+		if s.Name == "message" {
+			root = s
+		}
+	}
+	for _, want := range []string{"message", "context build", "chat fake", "tool message", "tool filesystem"} {
+		if !slices.Contains(names, want) {
+			t.Fatalf("no %q span in %v", want, names)
+		}
+	}
+	for _, s := range spans {
+		if s.TraceID != root.TraceID || s.Name != "message" && s.ParentSpanID != root.SpanID {
+			t.Fatalf("span %q is not a child of the message span", s.Name)
+		}
+	}
+	tp, _ := reply.Metadata[telemetry.MetadataKey].(string)
+	if !strings.Contains(tp, root.TraceID+"-"+root.SpanID) {
+		t.Fatalf("reply does not carry the trace: %q", tp)
+	}
+}
@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+)
+
+func TestMultiTenantIsolatesMemory(t *testing.T) {
+	ws := filepath.Join(t.TempDir(), "workspace")
+	os.MkdirAll(ws, 0o755)
+	os.WriteFile(filepath.Join(ws, "SOUL.md"), []byte("custom soul"), 0o644)
+
+	b := chat.NewHub(10)
+	p := &FailingProvider{}
+	ag := newTestLoop(t, b, p, p.GetDefaultModel(), 5, ws, nil)
+	ag.SetMultiTenant(true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	go ag.Run(ctx)
+
+	for _, in := range []chat.Inbound{
+		{Channel: "telegram", SenderID: "111", ChatID: "111", Content: "remember alice's secret"},
+		{Channel: "telegram", SenderID: "222", ChatID: "222", Content: "remember bob's secret"},
+	} {
+		b.In <- in
+		select {
+		case <-b.Out:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout waiting for reply")
+		}
+	}
+
+	alice, err := ag.tenantFor("telegram", "111")
+	if err != nil {
+		t.Fatalf("tenantFor: %v", err)
+	}
+	bob, _ := ag.tenantFor("telegram", "222")
+	aliceNotes, _ := alice.memory.ReadToday()
+	bobNotes, _ := bob.memory.ReadToday()
+	if !strings.Contains(aliceNotes, "alice") || strings.Contains(aliceNotes, "bob") {
+		t.Fatalf("alice's notes not isolated: %q", aliceNotes)
+	}
+	if !strings.Contains(bobNotes, "bob") || strings.Contains(bobNotes, "alice") {
+		t.Fatalf("bob's notes not isolated: %q", bobNotes)
+	}
+	if shared, _ := ag.memory.ReadToday(); shared != "" {
+		t.Fatalf("expected shared workspace memory to stay empty, got %q", shared)
+	}
+
+	if want := filepath.Join(ws+"-tenants", "telegram_111"); alice.workspace != want {
+		t.Fatalf("tenant workspace = %s, want %s outside the shared one", alice.workspace, want)
+	}
+	soul, err := os.ReadFile(filepath.Join(alice.workspace, "SOUL.md"))
+	if err != nil || string(soul) != "custom soul" {
+		t.Fatalf("expected shared SOUL.md to be copied into tenant, got %q err=%v", soul, err)
+	}
+}
+
+func TestSingleTenantUsesSharedWorkspace(t *testing.T) {
+	b := chat.NewHub(10)
+	p := &FailingProvider{}
+	ag := newTestLoop(t, b, p, p.GetDefaultModel(), 5, t.TempDir(), nil)
+	tn, err := ag.tenantFor("telegram", "111")
+	if err != nil {
+		t.Fatalf("tenantFor: %v", err)
+	}
+	if tn != ag.tenant {
+		t.Fatalf("expected shared tenant when multi-tenant mode is off")
+	}
+}
+
+func TestTenantKey(t *testing.T) {
+	if got := tenantKey("telegram", "8881234567"); got != "telegram_8881234567" {
+		t.Fatalf("tenantKey of a plain ID = %q", got)
+	}
+	a, b := tenantKey("email", "a.b@x.com"), tenantKey("email", "a_b@x.com")
+	if a == b || !strings.HasPrefix(a, "email_a_b_x_com.") {
+		t.Fatalf("keys of different chats must differ: %q, %q", a, b)
+	}
+	if c := tenantKey("http", "foo_bar"); c == tenantKey("http", "foo:bar") || strings.Contains(c, ".") {
+		t.Fatalf("unexpected keys %q, %q", c, tenantKey("http", "foo:bar"))
+	}
+}
@@ -1,18 +1,30 @@
 package agent
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/cron"
 	"github.com/kr0nicas/picobot/internal/providers"
 )
 
+// newTestLoop is NewAgentLoop for tests, failing t if the loop can't be set up.
+func newTestLoop(t *testing.T, b *chat.Hub, p providers.LLMProvider, model string, maxIterations int, workspace string, scheduler *cron.Scheduler) *AgentLoop {
+	t.Helper()
+	ag, err := NewAgentLoop(b, p, model, maxIterations, workspace, scheduler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ag
+}
+
 func TestProcessDirectWithStub(t *testing.T) {
 	b := chat.NewHub(10)
 	p := providers.NewStubProvider()
 
-	ag := NewAgentLoop(b, p, p.GetDefaultModel(), 5, "", nil)
+	ag := newTestLoop(t, b, p, p.GetDefaultModel(), 5, "", nil)
 
 	resp, err := ag.ProcessDirect("hello", 1*time.Second)
 	if err != nil {
@@ -22,3 +34,21 @@ func TestProcessDirectWithStub(t *testing.T) {
 		t.Fatalf("expected response, got empty string")
 	}
 }
+
+func TestInConversation(t *testing.T) {
+	b := chat.NewHub(10)
+	p := providers.NewStubProvider()
+	ag := newTestLoop(t, b, p, p.GetDefaultModel(), 3, t.TempDir(), nil)
+
+	ag.processMessage(context.Background(), chat.Inbound{Channel: "heartbeat", SenderID: "heartbeat", ChatID: "hb", Content: "check"})
+	if ag.InConversation(time.Minute) {
+		t.Fatal("heartbeat message counted as a conversation")
+	}
+	ag.processMessage(context.Background(), chat.Inbound{Channel: "telegram", SenderID: "1", ChatID: "1", Content: "hi"})
+	if !ag.InConversation(time.Minute) {
+		t.Fatal("user message not counted as a conversation")
+	}
+	if ag.InConversation(0) {
+		t.Fatal("conversation still in progress after the idle time")
+	}
+}
@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kr0nicas/picobot/internal/audit"
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/providers"
+	"github.com/kr0nicas/picobot/internal/redact"
+)
+
+// twoToolsProvider sends a message with a secret and reads a missing file in
+// one reply, then finishes.
+type twoToolsProvider struct{ calls int }
+
+func (p *twoToolsProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string) (providers.LLMResponse, error) {
+	p.calls++
+	if p.calls > 1 {
+		return providers.LLMResponse{Content: "done"}, nil
+	}
+	return providers.LLMResponse{HasToolCalls: true, ToolCalls: []providers.ToolCall{
+		{ID: "1", Name: "message", Arguments: map[string]interface{}{"content": "your key is sk-abcdefghijklmnopqrstuvwxyz0123"}},
+		{ID: "2", Name: "filesystem", Arguments: map[string]interface{}{"action": "read", "path": "missing.txt"}},
+	}}, nil
+}
+
+func (p *twoToolsProvider) GetDefaultModel() string { return "fake" }
+
+func TestToolCallsAreLogged(t *testing.T) {
+	ws := t.TempDir()
+	hub := chat.NewHub(10)
+	ag := newTestLoop(t, hub, &twoToolsProvider{}, "fake", 5, ws, nil)
+	tl, err := audit.OpenToolLog(filepath.Join(ws, "audit"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Close()
+	ag.SetToolLog(tl)
+	ag.SetRedactor(redact.New())
+
+	ag.processMessage(context.Background(), chat.Inbound{Channel: "telegram", SenderID: "7", ChatID: "1", Content: "go"})
+	for out := range hub.Out {
+		if out.Content == "done" {
+			break
+		}
+	}
+
+	calls, err := audit.ReadToolCalls(tl.Dir(), audit.ToolQuery{})
+	if err != nil || len(calls) != 2 {
+		t.Fatalf("expected two logged calls, got %d: %v", len(calls), err)
+	}
+	byTool := map[string]audit.ToolCall{calls[0].Tool: calls[0], calls[1].Tool: calls[1]}
+	msg, read := byTool["message"], byTool["filesystem"]
+	if msg.Outcome != audit.OutcomeOK || msg.Channel != "telegram" || msg.ChatID != "1" || msg.Sender != "7" {
+		t.Fatalf("unexpected message entry: %+v", msg)
+	}
+	if strings.Contains(string(msg.Args), "sk-abc") || !strings.Contains(string(msg.Args), "[REDACTED]") {
+		t.Fatalf("arguments not redacted: %s", msg.Args)
+	}
+	if read.Outcome != audit.OutcomeError || read.Error == "" || !strings.Contains(string(read.Args), "missing.txt") {
+		t.Fatalf("unexpected filesystem entry: %+v", read)
+	}
+
+	res, err := ag.tenant.tools.Get("tool_log").Execute(context.Background(), map[string]interface{}{"tool": "filesystem"})
+	if err != nil || !strings.Contains(res, "1 tool calls") || !strings.Contains(res, "telegram:1 filesystem") {
+		t.Fatalf("unexpected tool_log answer: %q, %v", res, err)
+	}
+}
@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+// bigReadProvider reads a large file, then a part of its saved copy, and
+// records the results it got.
+type bigReadProvider struct{ results []string }
+
+var savedResultRE = regexp.MustCompile(`artifacts/tool-results/\S+\.txt`)
+
+func (p *bigReadProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string) (providers.LLMResponse, error) {
+	last := messages[len(messages)-1]
+	var args map[string]interface{}
+	switch len(p.results) {
+	case 0:
+		if last.Role == "tool" {
+			p.results = append(p.results, last.Content)
+			args = map[string]interface{}{"action": "read", "path": savedResultRE.FindString(last.Content), "start_line": 1000.0, "end_line": 1001.0}
+		} else {
+			args = map[string]interface{}{"action": "read", "path": "build.log"}
+		}
+	case 1:
+		p.results = append(p.results, last.Content)
+		return providers.LLMResponse{Content: "The build failed."}, nil
+	}
+	tc := providers.ToolCall{ID: "call/1", Name: "filesystem", Arguments: args}
+	return providers.LLMResponse{HasToolCalls: true, ToolCalls: []providers.ToolCall{tc}}, nil
+}
+
+func (p *bigReadProvider) GetDefaultModel() string { return "fake" }
+
+func TestLongToolResultIsShortened(t *testing.T) {
+	ws := t.TempDir()
+	var log strings.Builder
+	for i := 1; i <= 2000; i++ {
+		log.WriteString("compiling package number " + strings.Repeat("x", 20) + "\n")
+	}
+	log.WriteString("error: undefined: foo\n")
+	if err := os.WriteFile(filepath.Join(ws, "build.log"), []byte(log.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	hub := chat.NewHub(10)
+	p := &bigReadProvider{}
+	ag := newTestLoop(t, hub, p, "fake", 5, ws, nil)
+	ag.SetToolResultLimit(3000, false)
+
+	ag.processMessage(context.Background(), chat.Inbound{Channel: "telegram", SenderID: "1", ChatID: "1", Content: "why did the build fail?"})
+	if out := <-hub.Out; out.Content != "The build failed." {
+		t.Fatalf("unexpected reply: %q", out.Content)
+	}
+	if len(p.results) != 2 {
+		t.Fatalf("expected two tool results, got %d", len(p.results))
+	}
+	short := p.results[0]
+	if len([]rune(short)) > 3500 || !strings.Contains(short, "error: undefined: foo") || !strings.Contains(short, "characters left out") {
+		t.Fatalf("result not cut to its beginning and end: %d chars", len([]rune(short)))
+	}
+	path := savedResultRE.FindString(short)
+	saved, err := os.ReadFile(filepath.Join(ws, path))
+	if path == "" || err != nil || string(saved) != log.String() {
+		t.Fatalf("full output not saved (%q): %v", path, err)
+	}
+	if got := p.results[1]; !strings.HasPrefix(got, "lines 1000-1001 of 2001:\ncompiling") {
+		t.Fatalf("unexpected part of the saved output: %q", got)
+	}
+}
@@ -32,11 +32,14 @@ func (p *toolCallingProvider) GetDefaultModel() string { return "fake-model" }
 func TestAgentExecutesWriteMemoryToolCall(t *testing.T) {
 	b := chat.NewHub(10)
 	p := &toolCallingProvider{}
-	ag := NewAgentLoop(b, p, p.GetDefaultModel(), 5, "", nil)
+	ag := newTestLoop(t, b, p, p.GetDefaultModel(), 5, "", nil)
 
 	// replace memory with temp workspace and re-register write_memory tool
 	tmp := t.TempDir()
-	m := memory.NewMemoryStoreWithWorkspace(tmp, 100)
+	m, err := memory.NewMemoryStoreWithWorkspace(tmp, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
 	ag.memory = m
 	ag.tools.Register(tools.NewWriteMemoryTool(m))
 
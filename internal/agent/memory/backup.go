@@ -0,0 +1,306 @@
+package memory
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// backupVersion is the format version of memory.json in a backup.
+const backupVersion = 1
+
+// maxBackupBytes bounds the unpacked size of an imported backup.
+const maxBackupBytes = 256 << 20
+
+// backupFile matches the markdown files a backup carries, relative to the
+// memory directory.
+var backupFile = regexp.MustCompile(`^(MEMORY\.md|(archive/)?\d{4}-\d{2}-\d{2}\.md)$`)
+
+// backup is the content of memory.json.
+type backup struct {
+	Version  int            `json:"version"`
+	Exported time.Time      `json:"exported"`
+	Memories []backupMemory `json:"memories"`
+}
+
+type backupMemory struct {
+	Kind      string     `json:"kind"`
+	Text      string     `json:"text"`
+	Timestamp time.Time  `json:"timestamp"`
+	Channel   string     `json:"channel,omitempty"`
+	Tags      []string   `json:"tags,omitempty"`
+	Expires   *time.Time `json:"expires,omitempty"`
+}
+
+// Imported reports what Import added.
+type Imported struct {
+	Memories int // database rows
+	Files    int // markdown files created or extended
+}
+
+// Export writes a backup of all memories to w as a gzipped tarball:
+// memory.json holds every row of the database with its time, tags, channel
+// and expiry, next to MEMORY.md and the daily notes, archived ones under
+// archive/.
+func (s *MemoryStore) Export(w io.Writer) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	items, err := s.Query(Query{Limit: math.MaxInt32})
+	if err != nil {
+		return err
+	}
+	b := backup{Version: backupVersion, Exported: time.Now().UTC(), Memories: make([]backupMemory, 0, len(items))}
+	for _, it := range items {
+		m := backupMemory{Kind: it.Kind, Text: it.Text, Timestamp: it.Timestamp, Channel: it.Channel, Tags: it.Tags}
+		if !it.Expires.IsZero() {
+			m.Expires = &it.Expires
+		}
+		b.Memories = append(b.Memories, m)
+	}
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	if err := addTarFile(tw, "memory.json", data, b.Exported); err != nil {
+		return err
+	}
+	notes, _ := filepath.Glob(filepath.Join(s.memoryDir, "????-??-??.md"))
+	archived, _ := filepath.Glob(filepath.Join(s.memoryDir, "archive", "????-??-??.md"))
+	for _, f := range append(append([]string{filepath.Join(s.memoryDir, "MEMORY.md")}, notes...), archived...) {
+		fi, err := os.Lstat(f)
+		if err != nil || !fi.Mode().IsRegular() {
+			continue // no MEMORY.md yet, or not a plain file
+		}
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(s.memoryDir, f)
+		if err := addTarFile(tw, filepath.ToSlash(rel), data, fi.ModTime()); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func addTarFile(tw *tar.Writer, name string, data []byte, mod time.Time) error {
+	hdr := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(data)), ModTime: mod, Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// Import merges a backup made by Export into the store. Memories and note
+// lines already present are skipped, so importing the same backup twice
+// changes nothing; expired memories are left out.
+func (s *MemoryStore) Import(r io.Reader) (Imported, error) {
+	var res Imported
+	files, err := readBackup(r)
+	if err != nil {
+		return res, err
+	}
+	var b backup
+	if err := json.Unmarshal(files["memory.json"], &b); err != nil {
+		return res, fmt.Errorf("memory: import: memory.json: %w", err)
+	}
+	if b.Version > backupVersion {
+		return res, fmt.Errorf("memory: import: backup format %d is newer than this picobot supports", b.Version)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, data := range files {
+		if name == "memory.json" || name == "MEMORY.md" {
+			continue
+		}
+		changed, err := s.mergeNotes(name, string(data))
+		if err != nil {
+			return res, err
+		}
+		if changed {
+			res.Files++
+		}
+	}
+	lt, err := s.ReadLongTerm()
+	if err != nil {
+		return res, err
+	}
+	merged, changed := mergeLines(lt, string(files["MEMORY.md"]))
+	if changed {
+		res.Files++
+	}
+	if res.Memories, err = s.insertBackup(b.Memories); err != nil {
+		return res, err
+	}
+	// the long-term rows just inserted keep their metadata; lines of
+	// MEMORY.md without one are added by writeLong
+	if changed {
+		err = s.writeLong(merged, MemoryItem{})
+	}
+	return res, err
+}
+
+// readBackup unpacks the files of a backup that Import uses.
+func readBackup(r io.Reader) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("memory: import: not a memory backup: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	files := map[string][]byte{}
+	var total int64
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("memory: import: %w", err)
+		}
+		name := path.Clean(hdr.Name)
+		if hdr.Typeflag != tar.TypeReg || (name != "memory.json" && !backupFile.MatchString(name)) {
+			continue
+		}
+		if total += hdr.Size; total > maxBackupBytes {
+			return nil, fmt.Errorf("memory: import: backup larger than %d MB", maxBackupBytes>>20)
+		}
+		data, err := io.ReadAll(io.LimitReader(tr, hdr.Size))
+		if err != nil {
+			return nil, fmt.Errorf("memory: import: %w", err)
+		}
+		files[name] = data
+	}
+	if _, ok := files["memory.json"]; !ok {
+		return nil, errors.New("memory: import: not a memory backup: memory.json is missing")
+	}
+	return files, nil
+}
+
+// mergeNotes adds the lines of a backed-up daily note file missing from the
+// local copy of that day, wherever consolidation has put it.
+func (s *MemoryStore) mergeNotes(name, data string) (bool, error) {
+	base := path.Base(name)
+	for _, rel := range []string{base, "archive/" + base} {
+		if _, err := os.Stat(filepath.Join(s.memoryDir, filepath.FromSlash(rel))); err == nil {
+			name = rel
+			break
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(s.memoryDir, filepath.FromSlash(path.Dir(name))), 0o755); err != nil {
+		return false, err
+	}
+	p, err := s.writablePath(filepath.FromSlash(name))
+	if err != nil {
+		return false, err
+	}
+	local, err := os.ReadFile(p)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	merged, changed := mergeLines(string(local), data)
+	if !changed {
+		return false, nil
+	}
+	return true, os.WriteFile(p, []byte(merged), 0o644)
+}
+
+// mergeLines appends the non-blank lines of add missing from content.
+func mergeLines(content, add string) (string, bool) {
+	have := map[string]bool{}
+	for _, line := range strings.Split(content, "\n") {
+		have[strings.TrimSpace(line)] = true
+	}
+	var sb strings.Builder
+	sb.WriteString(content)
+	changed := false
+	for _, line := range strings.Split(add, "\n") {
+		key := strings.TrimSpace(line)
+		if key == "" || have[key] {
+			continue
+		}
+		have[key] = true
+		if !changed && content != "" && !strings.HasSuffix(content, "\n") {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(line + "\n")
+		changed = true
+	}
+	return sb.String(), changed
+}
+
+// insertBackup adds the backed-up rows the database doesn't have: notes by
+// their text and time, long-term memories by their text.
+func (s *MemoryStore) insertBackup(items []backupMemory) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	rows, err := tx.Query(`SELECT kind, text, created_at FROM memories`)
+	if err != nil {
+		return 0, err
+	}
+	have := map[string]bool{}
+	for rows.Next() {
+		var kind, text string
+		var ts int64
+		if err := rows.Scan(&kind, &text, &ts); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		have[backupKey(kind, text, ts)] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	n := 0
+	for _, m := range items {
+		if (m.Kind != "short" && m.Kind != "long") || m.Text == "" || (m.Expires != nil && !m.Expires.After(now)) {
+			continue
+		}
+		key := backupKey(m.Kind, m.Text, m.Timestamp.UnixNano())
+		if have[key] {
+			continue
+		}
+		have[key] = true
+		it := MemoryItem{Kind: m.Kind, Text: m.Text, Timestamp: m.Timestamp.UTC(), Channel: m.Channel, Tags: m.Tags}
+		if m.Expires != nil {
+			it.Expires = m.Expires.UTC()
+		}
+		if err := insert(tx, it); err != nil {
+			return 0, err
+		}
+		n++
+	}
+	return n, tx.Commit()
+}
+
+// backupKey identifies a row when merging a backup; long-term memories are
+// lines of MEMORY.md, so their time doesn't matter.
+func backupKey(kind, text string, ts int64) string {
+	if kind == "long" {
+		ts = 0
+	}
+	return fmt.Sprintf("%s\x00%d\x00%s", kind, ts, text)
+}
@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportImportMergesMemories(t *testing.T) {
+	src, err := NewMemoryStoreWithWorkspace(t.TempDir(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	if err := src.AppendNote(MemoryItem{Text: "passport renewed", Tags: []string{"travel"}, Channel: "telegram"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.AppendLong(MemoryItem{Text: "- likes green tea", Tags: []string{"food"}}); err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(filepath.Join(src.memoryDir, "archive"), 0o755)
+	os.WriteFile(filepath.Join(src.memoryDir, "archive", "2026-01-02.md"), []byte("[2026-01-02T10:00:00Z] old note\n"), 0o644)
+	var buf bytes.Buffer
+	if err := src.Export(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	dstWS := t.TempDir()
+	dst, err := NewMemoryStoreWithWorkspace(dstWS, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+	if err := dst.AppendLong(MemoryItem{Text: "- lives in Porto"}); err != nil {
+		t.Fatal(err)
+	}
+	res, err := dst.Import(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the note, the long-term line; the archived note file has no row
+	if res.Memories != 2 || res.Files != 3 {
+		t.Fatalf("unexpected import result: %+v", res)
+	}
+	lt, _ := dst.ReadLongTerm()
+	if !strings.Contains(lt, "lives in Porto") || !strings.Contains(lt, "likes green tea") {
+		t.Fatalf("MEMORY.md not merged: %q", lt)
+	}
+	if got, _ := dst.Query(Query{Tag: "food"}); len(got) != 1 || got[0].Kind != "long" {
+		t.Fatalf("long-term memory lost its tags: %+v", got)
+	}
+	if got, _ := dst.Query(Query{Tag: "travel"}); len(got) != 1 || got[0].Channel != "telegram" {
+		t.Fatalf("note lost its metadata: %+v", got)
+	}
+	if got, _ := dst.Query(Query{Kind: "long"}); len(got) != 2 {
+		t.Fatalf("long-term rows duplicated: %+v", got)
+	}
+	if _, err := os.Stat(filepath.Join(dstWS, "memory", "archive", "2026-01-02.md")); err != nil {
+		t.Fatalf("archived notes not restored: %v", err)
+	}
+
+	res, err = dst.Import(bytes.NewReader(buf.Bytes()))
+	if err != nil || res.Memories != 0 || res.Files != 0 {
+		t.Fatalf("second import should change nothing: %+v %v", res, err)
+	}
+	if _, err := dst.Import(strings.NewReader("not a backup")); err == nil {
+		t.Fatal("expected an error for a file that is not a backup")
+	}
+}
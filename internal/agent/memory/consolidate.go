@@ -0,0 +1,154 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+// consolidateBatchChars bounds the daily notes summarized per LLM request.
+const consolidateBatchChars = 24000
+
+// consolidatePrompt asks the model for the new MEMORY.md.
+const consolidatePrompt = `You maintain the long-term memory of a personal assistant. You are given the current MEMORY.md and daily notes that are about to be archived.
+Return the complete new MEMORY.md: keep every lasting fact from the current file, add the lasting facts, preferences, decisions and open commitments from the notes, merge duplicates and facts that say the same thing, and update facts that the notes show have changed. Leave out chit-chat and things that only mattered on that day.
+Keep it short: one fact per line, grouped under markdown headings. Reply with the file's content only.`
+
+// Consolidation reports what Consolidate did.
+type Consolidation struct {
+	Archived []string // daily note files moved to memory/archive/
+	Before   int      // lines of MEMORY.md before
+	After    int      // and after
+}
+
+// Consolidate folds the daily notes older than keepDays into MEMORY.md with
+// the model, then moves them to memory/archive/. Notes are sent in batches;
+// each batch is archived once its summary is written, so a failure leaves
+// the remaining notes for the next run. The notes stay searchable in the
+// database.
+func (s *MemoryStore) Consolidate(ctx context.Context, provider providers.LLMProvider, model string, keepDays int) (Consolidation, error) {
+	var res Consolidation
+	cutoff := time.Now().UTC().AddDate(0, 0, -keepDays).Format("2006-01-02")
+	files, err := filepath.Glob(filepath.Join(s.memoryDir, "????-??-??.md"))
+	if err != nil {
+		return res, err
+	}
+	sort.Strings(files)
+	var old []string
+	for _, f := range files {
+		if strings.TrimSuffix(filepath.Base(f), ".md") < cutoff {
+			old = append(old, f)
+		}
+	}
+	lt, err := s.ReadLongTerm()
+	if err != nil {
+		return res, err
+	}
+	res.Before = countLines(lt)
+	res.After = res.Before
+
+	for len(old) > 0 {
+		var batch []string
+		var notes strings.Builder
+		for len(old) > 0 && (len(batch) == 0 || notes.Len() < consolidateBatchChars) {
+			data, err := os.ReadFile(old[0])
+			if err != nil {
+				return res, err
+			}
+			fmt.Fprintf(&notes, "## %s\n%s\n", strings.TrimSuffix(filepath.Base(old[0]), ".md"), data)
+			batch, old = append(batch, old[0]), old[1:]
+		}
+		messages := []providers.Message{
+			{Role: "system", Content: consolidatePrompt},
+			{Role: "user", Content: "Current MEMORY.md:\n" + lt + "\n\nDaily notes:\n" + notes.String()},
+		}
+		resp, err := provider.Chat(ctx, messages, nil, model)
+		if err != nil {
+			return res, fmt.Errorf("memory: consolidating: %w", err)
+		}
+		merged := dedupeLines(stripFence(resp.Content))
+		if strings.TrimSpace(merged) == "" {
+			return res, errors.New("memory: consolidating: the model returned an empty memory")
+		}
+		if err := s.WriteLongTerm(merged); err != nil {
+			return res, err
+		}
+		lt, res.After = merged, countLines(merged)
+		archived, err := s.archive(batch)
+		res.Archived = append(res.Archived, archived...)
+		if err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// archive moves daily note files to memory/archive/.
+func (s *MemoryStore) archive(files []string) ([]string, error) {
+	dir, err := s.writablePath("archive")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	var moved []string
+	for _, f := range files {
+		if err := os.Rename(f, filepath.Join(dir, filepath.Base(f))); err != nil {
+			return moved, err
+		}
+		moved = append(moved, filepath.Base(f))
+	}
+	return moved, nil
+}
+
+// stripFence removes the code fence models sometimes wrap a file in.
+func stripFence(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") || !strings.HasSuffix(s, "```") {
+		return s + "\n"
+	}
+	s = strings.TrimSuffix(s, "```")
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[i+1:]
+	} else {
+		s = ""
+	}
+	return strings.TrimSpace(s) + "\n"
+}
+
+// dedupeLines drops repeated fact lines, keeping the first; headings and
+// blank lines are left alone.
+func dedupeLines(s string) string {
+	seen := map[string]bool{}
+	var out []string
+	for _, line := range strings.Split(s, "\n") {
+		key := strings.ToLower(strings.TrimSpace(line))
+		if key != "" && !strings.HasPrefix(key, "#") {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
+
+// countLines counts the non-blank lines of s.
+func countLines(s string) int {
+	n := 0
+	for _, line := range strings.Split(s, "\n") {
+		if strings.TrimSpace(line) != "" {
+			n++
+		}
+	}
+	return n
+}
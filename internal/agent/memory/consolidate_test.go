@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConsolidateArchivesOldNotes(t *testing.T) {
+	tmp := t.TempDir()
+	s, err := NewMemoryStoreWithWorkspace(tmp, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	dir := filepath.Join(tmp, "memory")
+	day := func(ago int) string { return time.Now().UTC().AddDate(0, 0, -ago).Format("2006-01-02") + ".md" }
+	os.WriteFile(filepath.Join(dir, day(30)), []byte("[t] moved to Porto\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, day(10)), []byte("[t] likes green tea\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, day(1)), []byte("[t] dentist tomorrow\n"), 0o644)
+	s.WriteLongTerm("# Facts\nlikes tea\n")
+
+	p := &fakeProvider{resp: "```markdown\n# Facts\nlikes green tea\nlives in Porto\nlives in Porto\n```"}
+	res, err := s.Consolidate(context.Background(), p, "m", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Archived) != 2 || res.Before != 2 || res.After != 3 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	lt, _ := s.ReadLongTerm()
+	if lt != "# Facts\nlikes green tea\nlives in Porto\n" {
+		t.Fatalf("unexpected MEMORY.md: %q", lt)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "archive", day(30))); err != nil {
+		t.Error("old note not archived")
+	}
+	if _, err := os.Stat(filepath.Join(dir, day(1))); err != nil {
+		t.Error("recent note must stay")
+	}
+	if out, _ := s.GetRecentMemories(11); !strings.Contains(out, "green tea") {
+		t.Error("archived notes should still show in recent memories")
+	}
+
+	p.resp = "  "
+	os.WriteFile(filepath.Join(dir, day(20)), []byte("[t] x\n"), 0o644)
+	if _, err := s.Consolidate(context.Background(), p, "m", 7); err == nil {
+		t.Fatal("an empty summary must not replace MEMORY.md")
+	}
+	if _, err := os.Stat(filepath.Join(dir, day(20))); err != nil {
+		t.Error("notes of a failed run must not be archived")
+	}
+}
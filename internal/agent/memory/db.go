@@ -0,0 +1,136 @@
+package memory
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS memories (
+	id         INTEGER PRIMARY KEY,
+	kind       TEXT NOT NULL,              -- "short" or "long"
+	text       TEXT NOT NULL,
+	created_at INTEGER NOT NULL,           -- Unix nanoseconds, UTC
+	channel    TEXT NOT NULL DEFAULT '',
+	expires_at INTEGER                     -- Unix nanoseconds; NULL never expires
+);
+CREATE INDEX IF NOT EXISTS memories_kind_created ON memories (kind, created_at);
+CREATE TABLE IF NOT EXISTS memory_tags (
+	memory_id INTEGER NOT NULL REFERENCES memories (id),
+	tag       TEXT NOT NULL,
+	PRIMARY KEY (memory_id, tag)
+);
+CREATE INDEX IF NOT EXISTS memory_tags_tag ON memory_tags (tag);
+CREATE VIRTUAL TABLE IF NOT EXISTS memories_fts USING fts5 (
+	text, content = 'memories', content_rowid = 'id', tokenize = 'unicode61 remove_diacritics 2'
+);
+CREATE TRIGGER IF NOT EXISTS memories_fts_insert AFTER INSERT ON memories BEGIN
+	INSERT INTO memories_fts (rowid, text) VALUES (new.id, new.text);
+END;
+CREATE TRIGGER IF NOT EXISTS memories_fts_delete AFTER DELETE ON memories BEGIN
+	INSERT INTO memories_fts (memories_fts, rowid, text) VALUES ('delete', old.id, old.text);
+END;
+`
+
+// schemaVersion is the user_version of a database with the current schema.
+const schemaVersion = 3
+
+// migrations[v] upgrades a database from version v to v+1.
+var migrations = map[int]string{
+	1: `ALTER TABLE memories ADD COLUMN expires_at INTEGER`,
+	// the schema has just created the full-text index empty
+	2: `INSERT INTO memories_fts (memories_fts) VALUES ('rebuild')`,
+}
+
+// openDB opens (creating if needed) the SQLite database at path, or an
+// in-memory one for ":memory:".
+func openDB(path string) (*sql.DB, error) {
+	dsn := path
+	if path != ":memory:" {
+		dsn = "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	}
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	// one connection: writes are serialized, and an in-memory database
+	// lives as long as its connection
+	db.SetMaxOpenConns(1)
+	db.SetConnMaxLifetime(0)
+	db.SetConnMaxIdleTime(0)
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	return db, nil
+}
+
+// migrate upgrades the schema of a database written by an older version.
+// New databases (version 0) are created with the current schema.
+func migrate(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil || version == 0 {
+		return err
+	}
+	for ; version < schemaVersion; version++ {
+		if _, err := db.Exec(migrations[version]); err != nil {
+			return fmt.Errorf("migrating from version %d: %w", version, err)
+		}
+		if _, err := db.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, version+1)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insert adds item and its tags in tx; a zero Timestamp means now.
+func insert(tx *sql.Tx, item MemoryItem) error {
+	ts := item.Timestamp
+	if ts.IsZero() {
+		ts = time.Now().UTC()
+	}
+	var expires sql.NullInt64
+	if !item.Expires.IsZero() {
+		expires = sql.NullInt64{Int64: item.Expires.UnixNano(), Valid: true}
+	}
+	res, err := tx.Exec(`INSERT INTO memories (kind, text, created_at, channel, expires_at) VALUES (?, ?, ?, ?, ?)`,
+		item.Kind, item.Text, ts.UnixNano(), item.Channel, expires)
+	if err != nil {
+		return fmt.Errorf("memory: insert: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	for _, tag := range normalizeTags(item.Tags) {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO memory_tags (memory_id, tag) VALUES (?, ?)`, id, tag); err != nil {
+			return fmt.Errorf("memory: insert tag: %w", err)
+		}
+	}
+	return nil
+}
+
+// normalizeTag lower-cases a tag, drops a leading '#' and joins words with
+// '-', so "#Work Trip" and "work-trip" are the same tag.
+func normalizeTag(tag string) string {
+	return strings.Join(strings.Fields(strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))), "-")
+}
+
+// normalizeTags normalizes tags and drops empty and duplicate ones.
+func normalizeTags(tags []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, t := range tags {
+		if t = normalizeTag(t); t != "" && !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
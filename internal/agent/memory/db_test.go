@@ -0,0 +1,121 @@
+package memory
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueryByTagAndTime(t *testing.T) {
+	s, err := NewMemoryStore(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 12, 0, 0, 0, time.UTC) }
+	s.Add(MemoryItem{Kind: "short", Text: "flight to Lisbon", Timestamp: day(1), Tags: []string{"#Travel"}, Channel: "telegram"})
+	s.Add(MemoryItem{Kind: "short", Text: "hotel booked", Timestamp: day(2), Tags: []string{"travel", "money"}})
+	s.Add(MemoryItem{Kind: "short", Text: "dentist 50% off", Timestamp: day(3)})
+
+	got, err := s.Query(Query{Tag: "travel"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Text != "hotel booked" || strings.Join(got[0].Tags, ",") != "money,travel" {
+		t.Fatalf("unexpected tag query: %+v", got)
+	}
+	if got[1].Channel != "telegram" || !got[1].Timestamp.Equal(day(1)) {
+		t.Fatalf("unexpected item: %+v", got[1])
+	}
+	got, _ = s.Query(Query{Since: day(2), Until: day(3)})
+	if len(got) != 1 || got[0].Text != "hotel booked" {
+		t.Fatalf("unexpected range query: %+v", got)
+	}
+	if got, _ := s.Query(Query{Keyword: "50%"}); len(got) != 1 {
+		t.Fatalf("LIKE wildcards must be matched literally: %+v", got)
+	}
+	if got, _ := s.Query(Query{Keyword: "0%"}); len(got) != 1 {
+		t.Fatalf("unexpected keyword query: %+v", got)
+	}
+	if got, _ := s.Query(Query{Keyword: "_"}); len(got) != 0 {
+		t.Fatalf("unexpected keyword query: %+v", got)
+	}
+}
+
+func TestQueryByWords(t *testing.T) {
+	s, err := NewMemoryStore(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Add(MemoryItem{Kind: "short", Text: "Meeting with Zoë about the garden"})
+	s.Add(MemoryItem{Kind: "long", Text: "garden: tomatoes in the back"})
+	s.Add(MemoryItem{Kind: "short", Text: "50% off at the dentist"})
+
+	for query, want := range map[string]int{"garden": 2, "zoe meet": 1, "GARDEN tomato": 1, "garden, zoë!": 1, "tomatoes dentist": 0, "%": 1} {
+		if got, err := s.Query(Query{Text: query}); err != nil || len(got) != want {
+			t.Errorf("Text %q: got %d results (%v), want %d", query, len(got), err, want)
+		}
+	}
+	if got, _ := s.Query(Query{Text: "garden", Kind: "long"}); len(got) != 1 || got[0].Kind != "long" {
+		t.Fatalf("unexpected kind filter: %+v", got)
+	}
+	got, _ := s.Query(Query{Text: "dentist"})
+	if err := s.Remove(got); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.Query(Query{Text: "dentist"}); len(got) != 0 {
+		t.Fatalf("deleted memory still in the index: %+v", got)
+	}
+}
+
+func TestDatabasePersistsAndTracksFiles(t *testing.T) {
+	tmp := t.TempDir()
+	dir := filepath.Join(tmp, "memory")
+	os.MkdirAll(dir, 0o755)
+	// notes written before the database existed are imported
+	os.WriteFile(filepath.Join(dir, "2026-01-05.md"), []byte("[2026-01-05T09:00:00Z] old note #work\n"), 0o644)
+
+	s, err := NewMemoryStoreWithWorkspace(tmp, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AppendNote(MemoryItem{Text: "new note", Tags: []string{"Home Office"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteLongTerm("# Long-term Memory\n\nlikes tea\nlives in Porto\n"); err != nil {
+		t.Fatal(err)
+	}
+	td, _ := s.ReadToday()
+	if !strings.Contains(td, "new note #home-office") {
+		t.Fatalf("tags missing from the note file: %q", td)
+	}
+	before, _ := s.Query(Query{Kind: "long", Keyword: "tea"})
+	s.Close()
+
+	// edits to MEMORY.md while stopped are picked up
+	os.WriteFile(filepath.Join(dir, "MEMORY.md"), []byte("likes tea\nlives in Braga\n"), 0o644)
+	s, err = NewMemoryStoreWithWorkspace(tmp, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	old, _ := s.Query(Query{Tag: "work"})
+	if len(old) != 1 || old[0].Text != "old note" || old[0].Timestamp.Format(time.RFC3339) != "2026-01-05T09:00:00Z" {
+		t.Fatalf("old note not imported: %+v", old)
+	}
+	if got, _ := s.Query(Query{Tag: "home-office"}); len(got) != 1 {
+		t.Fatalf("note not persisted: %+v", got)
+	}
+	long, _ := s.Query(Query{Kind: "long"})
+	if len(long) != 2 || long[0].Text != "lives in Braga" || long[1].Text != "likes tea" {
+		t.Fatalf("long-term rows out of step with MEMORY.md: %+v", long)
+	}
+	if !long[1].Timestamp.Equal(before[0].Timestamp) {
+		t.Error("unchanged long-term line lost its timestamp")
+	}
+	if recall := s.Recall(5); len(recall) != 1 || recall[0].Text != "old note" {
+		t.Fatalf("Recall should return notes from before today: %+v", recall)
+	}
+}
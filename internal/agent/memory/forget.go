@@ -0,0 +1,141 @@
+package memory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Sweep deletes the memories that expired by now, from the database and
+// from the markdown files, and returns them.
+func (s *MemoryStore) Sweep(now time.Time) ([]MemoryItem, error) {
+	rows, err := s.db.Query(`SELECT id, kind, text, created_at FROM memories WHERE expires_at IS NOT NULL AND expires_at <= ?`, now.UnixNano())
+	if err != nil {
+		return nil, fmt.Errorf("memory: sweep: %w", err)
+	}
+	var expired []MemoryItem
+	for rows.Next() {
+		var it MemoryItem
+		var ts int64
+		if err := rows.Scan(&it.ID, &it.Kind, &it.Text, &ts); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("memory: sweep: %w", err)
+		}
+		it.Timestamp = time.Unix(0, ts).UTC()
+		expired = append(expired, it)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return expired, s.Remove(expired)
+}
+
+// Forget deletes every memory containing keyword (see Query), from the
+// database and from the markdown files, and returns them.
+func (s *MemoryStore) Forget(keyword string) ([]MemoryItem, error) {
+	if strings.TrimSpace(keyword) == "" {
+		return nil, fmt.Errorf("memory: forget: empty keyword")
+	}
+	items, err := s.Query(Query{Keyword: keyword, Limit: 1000})
+	if err != nil {
+		return nil, err
+	}
+	return items, s.Remove(items)
+}
+
+// Remove deletes items, as returned by Query, from the database, their
+// lines from MEMORY.md and their notes from the daily files.
+func (s *MemoryStore) Remove(items []MemoryItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, it := range items {
+		if _, err := tx.Exec(`DELETE FROM memory_tags WHERE memory_id = ?`, it.ID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM memories WHERE id = ?`, it.ID); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	var long []string
+	notes := map[string][]string{} // daily file -> line prefixes to drop
+	for _, it := range items {
+		if it.Kind == "long" {
+			long = append(long, it.Text)
+			continue
+		}
+		day := it.Timestamp.Format("2006-01-02") + ".md"
+		notes[day] = append(notes[day], "["+it.Timestamp.Format(time.RFC3339)+"] "+it.Text)
+	}
+	if len(long) > 0 {
+		lt, err := s.ReadLongTerm()
+		if err != nil {
+			return err
+		}
+		if err := s.writeLong(dropLines(lt, long, true), MemoryItem{}); err != nil {
+			return err
+		}
+	}
+	for day, prefixes := range notes {
+		if err := s.dropNotes(day, prefixes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropNotes removes the lines starting with one of prefixes from the daily
+// file day, or from its archived copy.
+func (s *MemoryStore) dropNotes(day string, prefixes []string) error {
+	for _, rel := range []string{day, filepath.Join("archive", day)} {
+		data, err := os.ReadFile(filepath.Join(s.memoryDir, rel))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		path, err := s.writablePath(rel)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, []byte(dropLines(string(data), prefixes, false)), 0o644)
+	}
+	return nil
+}
+
+// dropLines removes from content one line per entry of match: the line equal
+// to it (exact) or starting with it, ignoring surrounding space.
+func dropLines(content string, match []string, exact bool) string {
+	left := append([]string(nil), match...)
+	var out []string
+	for _, line := range strings.Split(content, "\n") {
+		t := strings.TrimSpace(line)
+		dropped := false
+		for i, m := range left {
+			if t == m || (!exact && strings.HasPrefix(t, m)) {
+				left = append(left[:i], left[i+1:]...)
+				dropped = true
+				break
+			}
+		}
+		if !dropped {
+			out = append(out, line)
+		}
+	}
+	return strings.Join(out, "\n")
+}
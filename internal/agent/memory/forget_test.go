@@ -0,0 +1,106 @@
+package memory
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSweepAndForget(t *testing.T) {
+	tmp := t.TempDir()
+	s, err := NewMemoryStoreWithWorkspace(tmp, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	soon := time.Now().Add(time.Hour)
+	s.AppendNote(MemoryItem{Text: "door code is 1234", Expires: soon})
+	s.AppendNote(MemoryItem{Text: "old address: Rua A 1"})
+	s.AppendNote(MemoryItem{Text: "call mum"})
+	s.WriteLongTerm("# Facts\nlikes tea\n")
+	s.AppendLong(MemoryItem{Text: "parking spot 12", Expires: soon})
+	s.AppendLong(MemoryItem{Text: "lives at old address Rua A 1"})
+
+	if got, _ := s.Query(Query{Keyword: "door"}); len(got) != 1 || !got[0].Expires.Equal(soon.UTC()) {
+		t.Fatalf("expiry not stored: %+v", got)
+	}
+	td, _ := s.ReadToday()
+	if !strings.Contains(td, "door code is 1234 (until ") {
+		t.Errorf("expiry missing from the note file: %q", td)
+	}
+
+	expired, err := s.Sweep(soon.Add(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expired) != 2 {
+		t.Fatalf("expected 2 expired memories, got %+v", expired)
+	}
+	td, _ = s.ReadToday()
+	lt, _ := s.ReadLongTerm()
+	if strings.Contains(td, "door code") || strings.Contains(lt, "parking") {
+		t.Fatalf("expired memories left in files:\n%s\n%s", td, lt)
+	}
+
+	forgotten, err := s.Forget("old address")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(forgotten) != 2 {
+		t.Fatalf("expected 2 forgotten memories, got %+v", forgotten)
+	}
+	td, _ = s.ReadToday()
+	lt, _ = s.ReadLongTerm()
+	if strings.Contains(td, "Rua A") || strings.Contains(lt, "Rua A") || !strings.Contains(td, "call mum") || !strings.Contains(lt, "likes tea") {
+		t.Fatalf("unexpected files after forget:\n%s\n%s", td, lt)
+	}
+	if got, _ := s.Query(Query{}); len(got) != 2 {
+		t.Fatalf("expected 2 memories left, got %+v", got)
+	}
+}
+
+func TestExpiredMemoriesAreHidden(t *testing.T) {
+	s, err := NewMemoryStore(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Add(MemoryItem{Kind: "short", Text: "gone", Expires: time.Now().Add(-time.Minute)})
+	if got := s.Recent(5); len(got) != 0 {
+		t.Fatalf("expired memory returned: %+v", got)
+	}
+}
+
+func TestMigratesVersion1Database(t *testing.T) {
+	tmp := t.TempDir()
+	path := filepath.Join(tmp, "memory", "memory.db")
+	os.MkdirAll(filepath.Dir(path), 0o755)
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`CREATE TABLE memories (id INTEGER PRIMARY KEY, kind TEXT NOT NULL, text TEXT NOT NULL, created_at INTEGER NOT NULL, channel TEXT NOT NULL DEFAULT '');
+		INSERT INTO memories (kind, text, created_at) VALUES ('short', 'kept', 1);
+		PRAGMA user_version = 1;`)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewMemoryStoreWithWorkspace(tmp, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if got, err := s.Query(Query{Keyword: "kept"}); err != nil || len(got) != 1 {
+		t.Fatalf("migrated database lost its rows: %+v %v", got, err)
+	}
+	if got, err := s.Query(Query{Text: "kept"}); err != nil || len(got) != 1 {
+		t.Fatalf("full-text index not built for old rows: %+v %v", got, err)
+	}
+	if err := s.AppendNote(MemoryItem{Text: "new", Expires: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+}
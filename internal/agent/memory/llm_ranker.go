@@ -1,24 +1,45 @@
 package memory
 
 import (
+	"container/list"
 	"context"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/kr0nicas/picobot/internal/providers"
 )
 
 // LLMMemoryRanker uses an LLM provider to rank memories relative to a query.
 // It falls back to a SimpleRanker if the provider fails or returns an unparsable response.
+// To keep requests small, only the SimpleRanker's best candidates are sent,
+// each cut to a maximum length, and rankings are cached per query and
+// candidate set.
 type LLMMemoryRanker struct {
-	provider providers.LLMProvider
-	model    string
-	fallback *SimpleRanker
-	logger   *log.Logger // optional per-instance logger for diagnostics
+	provider   providers.LLMProvider
+	model      string
+	fallback   *SimpleRanker
+	logger     *log.Logger   // optional per-instance logger for diagnostics
+	candidates int           // memories sent to the model; 0 sends all
+	maxChars   int           // of each memory's text sent
+	timeout    time.Duration // of a ranking request; 0 means none
+	cache      *rankCache
 }
 
+// Defaults of the LLMMemoryRanker's requests and cache.
+const (
+	DefaultRankCandidates = 12
+	DefaultRankTimeout    = 15 * time.Second
+	defaultRankChars      = 300
+	rankCacheSize         = 256
+)
+
 // NewLLMRanker constructs an LLMMemoryRanker using the given provider and model.
 func NewLLMRanker(provider providers.LLMProvider, model string) *LLMMemoryRanker {
 	return NewLLMRankerWithLogger(provider, model, nil)
@@ -29,20 +50,29 @@ func NewLLMRankerWithLogger(provider providers.LLMProvider, model string, logger
 	if model == "" && provider != nil {
 		model = provider.GetDefaultModel()
 	}
-	return &LLMMemoryRanker{provider: provider, model: model, fallback: NewSimpleRanker(), logger: logger}
+	return &LLMMemoryRanker{provider: provider, model: model, fallback: NewSimpleRanker(), logger: logger,
+		candidates: DefaultRankCandidates, maxChars: defaultRankChars, timeout: DefaultRankTimeout, cache: newRankCache(rankCacheSize)}
 }
 
-// logf logs using the instance logger if present, else falls back to package log.
+// SetCandidates sets how many of the keyword ranker's best memories are
+// sent to the model; 0 sends all of them.
+func (r *LLMMemoryRanker) SetCandidates(n int) { r.candidates = max(n, 0) }
+
+// SetTimeout bounds each ranking request; the keyword ranking is used when
+// it runs out. 0 means no limit.
+func (r *LLMMemoryRanker) SetTimeout(d time.Duration) { r.timeout = d }
+
+// logf logs using the instance logger if present, else at debug level.
 func (r *LLMMemoryRanker) logf(format string, args ...interface{}) {
 	if r.logger != nil {
 		r.logger.Printf(format, args...)
 	} else {
-		log.Printf(format, args...)
+		memoryLog.Debug(fmt.Sprintf(format, args...))
 	}
 }
 
-// Rank implements the Ranker interface. It uses a background context for provider calls
-// (this is acceptable for short operations; timeouts are applied by the provider implementation).
+// Rank implements the Ranker interface. Provider calls are bounded by the
+// ranker's timeout (see SetTimeout).
 func (r *LLMMemoryRanker) Rank(query string, memories []MemoryItem, top int) []MemoryItem {
 	if len(memories) == 0 || top <= 0 {
 		return nil
@@ -52,113 +82,26 @@ func (r *LLMMemoryRanker) Rank(query string, memories []MemoryItem, top int) []M
 		return r.fallback.Rank(query, memories, top)
 	}
 
-	// Build a simple prompt listing memories with indices and expose a 'rank_memories' tool.
-	var sb strings.Builder
-	sb.WriteString("You are a ranking assistant. Given the query and a list of memories numbered 0..N-1, return only an ordered list of indices (most relevant first). Respond either by calling the tool 'rank_memories' with argument {\"indices\": [i, j, ...]} or by returning a JSON array like [i,j,...] in the assistant content. Do not return other text around the array; if you must, ensure the array appears in full (e.g. 'Result: [1,0]')." + "\n\n")
-	sb.WriteString("Query: " + query + "\n\n")
-	sb.WriteString("Memories (index: text):\n")
-	for i, m := range memories {
-		sb.WriteString(fmt.Sprintf("%d: %s\n", i, m.Text))
-	}
-
-	messages := []providers.Message{{Role: "system", Content: sb.String()}, {Role: "user", Content: "Return an ordered list of indices ranked by relevance, or call the 'rank_memories' tool."}}
-
-	// expose a tool definition to allow function-call style responses from providers
-	rankTool := providers.ToolDefinition{
-		Name:        "rank_memories",
-		Description: "Return ranking indices for memories",
-		Parameters: map[string]interface{}{
-			"type":     "object",
-			"required": []string{"indices"},
-			"properties": map[string]interface{}{
-				"indices": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "number"}},
-			},
-		},
-	}
-	// diagnostic log
-	r.logf("LLMMemoryRanker: sending ranking request for query=%q with %d memories", query, len(memories))
-	resp, err := r.provider.Chat(context.Background(), messages, []providers.ToolDefinition{rankTool}, r.model)
-	if err != nil {
-		r.logf("LLMMemoryRanker provider error: %v", err)
-		return r.fallback.Rank(query, memories, top)
-	}
-	// log response summary
-	if resp.HasToolCalls {
-		r.logf("LLMMemoryRanker: provider returned %d tool calls", len(resp.ToolCalls))
-	} else {
-		r.logf("LLMMemoryRanker: provider returned content=%q", strings.TrimSpace(resp.Content))
-	}
-
-	// Prefer function call results (ToolCalls) if present
-	if resp.HasToolCalls && len(resp.ToolCalls) > 0 {
-		for _, tc := range resp.ToolCalls {
-			if tc.Name != "rank_memories" {
-				continue
-			}
-			// expected argument: indices: [int]
-			if raw, ok := tc.Arguments["indices"]; ok {
-				if idxs, err := parseIndicesFromArgs(raw); err == nil {
-					out := make([]MemoryItem, 0, top)
-					seen := make(map[int]struct{})
-					for _, idx := range idxs {
-						if idx < 0 || idx >= len(memories) {
-							continue
-						}
-						if _, ok := seen[idx]; ok {
-							continue
-						}
-						out = append(out, memories[idx])
-						seen[idx] = struct{}{}
-						if len(out) >= top {
-							break
-						}
-					}
-					// pad if needed
-					if len(out) < top {
-						fallback := r.fallback.Rank(query, memories, len(memories))
-						for _, m := range fallback {
-							if len(out) >= top {
-								break
-							}
-							skip := false
-							for _, s := range out {
-								if s.Text == m.Text && s.Kind == m.Kind {
-									skip = true
-									break
-								}
-							}
-							if !skip {
-								out = append(out, m)
-							}
-						}
-					}
-					return out
-				}
-			}
-		}
-	}
-
-	// Attempt to parse JSON array of ints from resp.Content as a fallback
-	var idxs []int
-	body := strings.TrimSpace(resp.Content)
-	if err := json.Unmarshal([]byte(body), &idxs); err != nil {
-		// try to be forgiving: extract digits from content
-		if err2 := parseIndicesFromText(body, &idxs); err2 != nil {
-			r.logf("LLMMemoryRanker parse error: %v (content=%q)", err2, body)
+	candidates := r.prefilter(query, memories)
+	key := rankKey(query, candidates)
+	idxs, ok := r.cache.get(key)
+	if !ok {
+		if idxs, ok = r.ask(query, candidates); !ok {
 			return r.fallback.Rank(query, memories, top)
 		}
+		r.cache.add(key, idxs)
 	}
 
 	out := make([]MemoryItem, 0, top)
 	seen := make(map[int]struct{})
 	for _, idx := range idxs {
-		if idx < 0 || idx >= len(memories) {
+		if idx < 0 || idx >= len(candidates) {
 			continue
 		}
 		if _, ok := seen[idx]; ok {
 			continue
 		}
-		out = append(out, memories[idx])
+		out = append(out, candidates[idx])
 		seen[idx] = struct{}{}
 		if len(out) >= top {
 			break
@@ -187,6 +130,157 @@ func (r *LLMMemoryRanker) Rank(query string, memories []MemoryItem, top int) []M
 	return out
 }
 
+// prefilter returns the memories the keyword ranker scores best, at most
+// r.candidates, in their original order.
+func (r *LLMMemoryRanker) prefilter(query string, memories []MemoryItem) []MemoryItem {
+	if r.candidates <= 0 || len(memories) <= r.candidates {
+		return memories
+	}
+	keep := make(map[int]bool, r.candidates)
+	for _, i := range r.fallback.rankIndices(query, memories)[:r.candidates] {
+		keep[i] = true
+	}
+	out := make([]MemoryItem, 0, r.candidates)
+	for i, m := range memories {
+		if keep[i] {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// ask has the model rank memories and returns their indices, most relevant
+// first; false means the fallback should be used.
+func (r *LLMMemoryRanker) ask(query string, memories []MemoryItem) ([]int, bool) {
+	// Build a simple prompt listing memories with indices; the reply is a
+	// JSON object {"indices": [i, j, ...]}.
+	var sb strings.Builder
+	sb.WriteString("You are a ranking assistant. Given the query and a list of memories numbered 0..N-1, return the indices of the memories ordered from most to least relevant, as a JSON object {\"indices\": [i, j, ...]}." + "\n\n")
+	sb.WriteString("Query: " + query + "\n\n")
+	sb.WriteString("Memories (index: text):\n")
+	for i, m := range memories {
+		sb.WriteString(fmt.Sprintf("%d: %s\n", i, clipText(m.Text, r.maxChars)))
+	}
+
+	messages := []providers.Message{{Role: "system", Content: sb.String()}, {Role: "user", Content: "Return the indices ranked by relevance."}}
+
+	// diagnostic log
+	r.logf("LLMMemoryRanker: sending ranking request for query=%q with %d memories", query, len(memories))
+	var reply struct {
+		Indices []float64 `json:"indices"`
+	}
+	ctx := context.Background()
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+	// no retries: ranking runs before every reply and has a fallback
+	resp, err := providers.StructuredAsk(ctx, r.provider, messages, r.model, rankFormat, 0, &reply)
+	if err != nil && !errors.Is(err, providers.ErrInvalidStructuredReply) {
+		r.logf("LLMMemoryRanker provider error: %v", err)
+		return nil, false
+	}
+	// log response summary
+	if resp.HasToolCalls {
+		r.logf("LLMMemoryRanker: provider returned %d tool calls", len(resp.ToolCalls))
+	} else {
+		r.logf("LLMMemoryRanker: provider returned content=%q", strings.TrimSpace(resp.Content))
+	}
+
+	var idxs []int
+	if err == nil {
+		idxs, _ = parseIndicesFromArgs(reply.Indices)
+	} else if err2 := parseIndicesFromText(strings.TrimSpace(resp.Content), &idxs); err2 != nil {
+		// models without JSON mode may still answer with a bare array
+		r.logf("LLMMemoryRanker parse error: %v (content=%q)", err, strings.TrimSpace(resp.Content))
+		return nil, false
+	}
+	return idxs, true
+}
+
+// clipText cuts s to at most n bytes on a rune boundary; n <= 0 keeps all.
+func clipText(s string, n int) string {
+	if n <= 0 || len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "…"
+}
+
+// rankKey identifies a ranking request: the query and the memories sent.
+func rankKey(query string, memories []MemoryItem) [sha256.Size]byte {
+	h := sha256.New()
+	h.Write([]byte(query))
+	for _, m := range memories {
+		h.Write([]byte{0})
+		h.Write([]byte(m.Kind))
+		h.Write([]byte{0})
+		h.Write([]byte(m.Text))
+	}
+	var key [sha256.Size]byte
+	h.Sum(key[:0])
+	return key
+}
+
+// rankCache is an LRU cache of rankings.
+type rankCache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List // of rankEntry, most recently used first
+	items map[[sha256.Size]byte]*list.Element
+}
+
+type rankEntry struct {
+	key  [sha256.Size]byte
+	idxs []int
+}
+
+func newRankCache(size int) *rankCache {
+	return &rankCache{size: size, order: list.New(), items: make(map[[sha256.Size]byte]*list.Element)}
+}
+
+func (c *rankCache) get(key [sha256.Size]byte) ([]int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(rankEntry).idxs, true
+}
+
+func (c *rankCache) add(key [sha256.Size]byte, idxs []int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[key]; ok {
+		e.Value = rankEntry{key, idxs}
+		c.order.MoveToFront(e)
+		return
+	}
+	c.items[key] = c.order.PushFront(rankEntry{key, idxs})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(rankEntry).key)
+	}
+}
+
+// rankFormat is the reply the ranker asks for.
+var rankFormat = providers.ResponseFormat{
+	Name: "rank_memories",
+	Schema: map[string]interface{}{
+		"type":     "object",
+		"required": []string{"indices"},
+		"properties": map[string]interface{}{
+			"indices": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "integer"}},
+		},
+	},
+}
+
 // parseIndicesFromText attempts to extract a JSON-like array of ints from arbitrary text.
 func parseIndicesFromText(s string, out *[]int) error {
 	// find the first [ ... ] substring
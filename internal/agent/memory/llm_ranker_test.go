@@ -2,7 +2,10 @@ package memory
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/kr0nicas/picobot/internal/providers"
 )
@@ -71,3 +74,69 @@ func TestLLMRankerParsesArrayFromContentText(t *testing.T) {
 		t.Fatalf("expected first result to be 'call mom', got %q", res[0].Text)
 	}
 }
+
+// promptRecorder answers [0] and records the ranking prompts it gets.
+type promptRecorder struct {
+	prompts []string
+}
+
+func (p *promptRecorder) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string) (providers.LLMResponse, error) {
+	p.prompts = append(p.prompts, messages[0].Content)
+	return providers.LLMResponse{Content: "[0]"}, nil
+}
+func (p *promptRecorder) GetDefaultModel() string { return "test-model" }
+
+func TestLLMRankerPrefiltersAndCaches(t *testing.T) {
+	var mems []MemoryItem
+	for i := 0; i < 30; i++ {
+		mems = append(mems, MemoryItem{Kind: "short", Text: fmt.Sprintf("note %d about nothing", i)})
+	}
+	mems[5].Text = "the cello lesson is on Monday " + strings.Repeat("la ", 200)
+	p := &promptRecorder{}
+	r := NewLLMRanker(p, "test-model")
+
+	res := r.Rank("cello lesson", mems, 3)
+	if len(res) != 3 || !strings.HasPrefix(res[0].Text, "the cello lesson") {
+		t.Fatalf("unexpected ranking: %+v", res)
+	}
+	if len(p.prompts) != 1 {
+		t.Fatalf("expected one request, got %d", len(p.prompts))
+	}
+	prompt := p.prompts[0]
+	if n := strings.Count(prompt, "about nothing"); n != DefaultRankCandidates-1 {
+		t.Fatalf("expected %d candidates besides the match, got %d", DefaultRankCandidates-1, n)
+	}
+	if strings.Count(prompt, "la ") > defaultRankChars/3 {
+		t.Fatal("long memory text was not cut")
+	}
+
+	r.Rank("cello lesson", mems, 3)
+	if len(p.prompts) != 1 {
+		t.Fatal("the same query and memories must be answered from the cache")
+	}
+	r.Rank("cello teacher", mems, 3)
+	mems[29].Text = "changed"
+	r.Rank("cello lesson", mems, 3)
+	if len(p.prompts) != 3 {
+		t.Fatalf("a new query or memory set needs a new request, got %d requests", len(p.prompts))
+	}
+}
+
+// slowProvider answers only when the request is cancelled.
+type slowProvider struct{}
+
+func (slowProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string) (providers.LLMResponse, error) {
+	<-ctx.Done()
+	return providers.LLMResponse{}, ctx.Err()
+}
+func (slowProvider) GetDefaultModel() string { return "test-model" }
+
+func TestLLMRankerTimesOut(t *testing.T) {
+	mems := []MemoryItem{{Kind: "short", Text: "buy milk"}, {Kind: "short", Text: "call mom"}}
+	r := NewLLMRanker(slowProvider{}, "test-model")
+	r.SetTimeout(10 * time.Millisecond)
+	res := r.Rank("milk", mems, 1)
+	if len(res) != 1 || res[0].Text != "buy milk" {
+		t.Fatalf("expected the keyword ranking after the timeout, got %+v", res)
+	}
+}
@@ -55,8 +55,18 @@ func (s *SimpleRanker) Rank(query string, memories []MemoryItem, top int) []Memo
 		return rev
 	}
 
+	out := make([]MemoryItem, 0, top)
+	for _, i := range s.rankIndices(query, memories)[:top] {
+		out = append(out, memories[i])
+	}
+	return out
+}
+
+// rankIndices returns the indices of memories ordered by keyword overlap
+// with query, ties going to the later (newer) item.
+func (s *SimpleRanker) rankIndices(query string, memories []MemoryItem) []int {
+	qTokens := tokenize(query)
 	type scored struct {
-		m     MemoryItem
 		score int
 		idx   int
 	}
@@ -74,7 +84,7 @@ func (s *SimpleRanker) Rank(query string, memories []MemoryItem, top int) []Memo
 				score++
 			}
 		}
-		scores = append(scores, scored{m: m, score: score, idx: i})
+		scores = append(scores, scored{score: score, idx: i})
 	}
 
 	sort.Slice(scores, func(i, j int) bool {
@@ -85,9 +95,9 @@ func (s *SimpleRanker) Rank(query string, memories []MemoryItem, top int) []Memo
 		return scores[i].idx > scores[j].idx
 	})
 
-	out := make([]MemoryItem, 0, top)
-	for i := 0; i < top && i < len(scores); i++ {
-		out = append(out, scores[i].m)
+	out := make([]int, len(scores))
+	for i, sc := range scores {
+		out[i] = sc.idx
 	}
 	return out
 }
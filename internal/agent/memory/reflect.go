@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+// reflectPrompt asks the model for the facts of a conversation worth keeping.
+const reflectPrompt = `You review a conversation between a user and their personal assistant and pick out what the assistant should remember for future conversations: lasting facts about the user and the people and things in their life, preferences, decisions, plans and commitments. Leave out small talk, questions that were fully answered, and anything already in the assistant's memory below.
+Reply with a JSON array only, [] if there is nothing new. Each element is {"text": "<one short fact, in the third person>", "target": "long" for lasting facts or "today" for things that matter for the next days, "tags": ["<optional label>"]}.`
+
+// maxReflectFacts bounds the facts stored from one reflection.
+const maxReflectFacts = 10
+
+// ReflectTag marks memories stored by Reflect.
+const ReflectTag = "reflection"
+
+// Reflect has the model review conversation, a transcript of recent
+// exchanges, and stores the new facts it proposes: lasting ones in
+// MEMORY.md, others in today's notes. Facts whose words all appear in an
+// existing memory are skipped as duplicates. It returns the stored memories.
+func (s *MemoryStore) Reflect(ctx context.Context, provider providers.LLMProvider, model, conversation, channel string) ([]MemoryItem, error) {
+	known, err := s.GetMemoryContext()
+	if err != nil {
+		return nil, err
+	}
+	messages := []providers.Message{
+		{Role: "system", Content: reflectPrompt},
+		{Role: "user", Content: "Current memory:\n" + known + "\n\nConversation:\n" + conversation},
+	}
+	resp, err := provider.Chat(ctx, messages, nil, model)
+	if err != nil {
+		return nil, fmt.Errorf("memory: reflecting: %w", err)
+	}
+	facts, err := parseFacts(resp.Content)
+	if err != nil {
+		return nil, fmt.Errorf("memory: reflecting: %w", err)
+	}
+	var stored []MemoryItem
+	for _, f := range facts {
+		if len(stored) == maxReflectFacts {
+			break
+		}
+		text := strings.Join(strings.Fields(f.Text), " ")
+		if text == "" {
+			continue
+		}
+		if dup, err := s.Query(Query{Text: text, Limit: 1}); err != nil || len(dup) > 0 {
+			continue
+		}
+		item := MemoryItem{Text: text, Channel: channel, Tags: append(f.Tags, ReflectTag)}
+		if f.Target == "long" {
+			item.Kind = "long"
+			err = s.AppendLong(item)
+		} else {
+			item.Kind = "short"
+			err = s.AppendNote(item)
+		}
+		if err != nil {
+			return stored, err
+		}
+		stored = append(stored, item)
+	}
+	return stored, nil
+}
+
+type reflectFact struct {
+	Text   string   `json:"text"`
+	Target string   `json:"target"`
+	Tags   []string `json:"tags"`
+}
+
+// parseFacts reads the JSON array of a reflection reply, ignoring any text
+// or code fence around it.
+func parseFacts(reply string) ([]reflectFact, error) {
+	start, end := strings.IndexByte(reply, '['), strings.LastIndexByte(reply, ']')
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no JSON array in the reply %q", reply)
+	}
+	var facts []reflectFact
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &facts); err != nil {
+		return nil, err
+	}
+	return facts, nil
+}
@@ -0,0 +1,50 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestReflectStoresNewFacts(t *testing.T) {
+	s, err := NewMemoryStoreWithWorkspace(t.TempDir(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.WriteLongTerm("# Facts\nThe user lives in Porto\n")
+
+	p := &fakeProvider{resp: "Here you go:\n```json\n" + `[
+		{"text": "The user's sister is called Ana", "target": "long", "tags": ["family"]},
+		{"text": "The user lives in  Porto", "target": "long"},
+		{"text": "Dentist appointment on Friday", "target": "today"},
+		{"text": "  "}
+	]` + "\n```"}
+	stored, err := s.Reflect(context.Background(), p, "m", "user: my sister Ana visits on Friday, after the dentist\nassistant: nice", "telegram")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 2 {
+		t.Fatalf("expected 2 new facts, got %+v", stored)
+	}
+	lt, _ := s.ReadLongTerm()
+	if strings.Count(lt, "Porto") != 1 || !strings.Contains(lt, "sister is called Ana") {
+		t.Fatalf("unexpected MEMORY.md: %q", lt)
+	}
+	got, _ := s.Query(Query{Tag: ReflectTag})
+	if len(got) != 2 {
+		t.Fatalf("reflected memories not tagged: %+v", got)
+	}
+	if got, _ := s.Query(Query{Tag: "family"}); len(got) != 1 || got[0].Channel != "telegram" {
+		t.Fatalf("unexpected metadata: %+v", got)
+	}
+
+	p.resp = "[]"
+	if stored, err := s.Reflect(context.Background(), p, "m", "user: thanks", ""); err != nil || len(stored) != 0 {
+		t.Fatalf("nothing new: %+v %v", stored, err)
+	}
+	p.resp = "I can't tell."
+	if _, err := s.Reflect(context.Background(), p, "m", "user: hm", ""); err == nil {
+		t.Fatal("expected an error for a reply without JSON")
+	}
+}
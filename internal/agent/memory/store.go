@@ -1,94 +1,168 @@
 package memory
 
 import (
+	"database/sql"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
+
+	_ "modernc.org/sqlite" // registers the "sqlite" database/sql driver
+
+	"github.com/kr0nicas/picobot/internal/logging"
+	"github.com/kr0nicas/picobot/internal/workspace"
 )
 
+var memoryLog = logging.For("memory")
+
 // MemoryItem is a stored memory entry.
 // Kind is "short" or "long". Timestamp is in UTC.
 type MemoryItem struct {
 	Kind      string
 	Text      string
 	Timestamp time.Time
+	Channel   string    // channel the memory was recorded from, if known
+	Tags      []string  // lower-case labels for Query
+	Expires   time.Time // when the memory is forgotten; zero means never
+	ID        int64     // database row, set by Query
 }
 
-// MemoryStore is a minimal in-memory memory system with simple query capabilities.
-// - Long-term: append-only list (persisted in a real implementation)
-// - Short-term: append-only list with a configurable limit (recent items kept)
-// This is intentionally simple for v0 and unit-testable.
+// MemoryStore keeps memories in a SQLite database under workspace/memory/.
+// - Short-term: daily notes, one row per note
+// - Long-term: one row per line of MEMORY.md
+// The markdown files remain as a view of the database that the prompt and
+// the user read; queries by keyword, time range and tag hit the database.
 type MemoryStore struct {
 	workspace string // workspace root (used for disk-backed memory)
 	memoryDir string // workspace/memory/
-	limit     int    // max short-term items to keep
-	long      []MemoryItem
-	short     []MemoryItem
-	mu        sync.RWMutex
+	strict    bool   // refuse memory paths through symlinks
+	limit     int    // short-term items considered by Recent
+	db        *sql.DB
+	mu        sync.RWMutex // guards strict and serializes file writes
+}
+
+// NewMemoryStore creates a store whose database lives in memory, with
+// short-term limit (e.g., 100). Kept for tests and simple use-cases.
+func NewMemoryStore(limit int) (*MemoryStore, error) {
+	return newStore(".", ":memory:", limit)
 }
 
-// NewMemoryStore creates an in-memory store with short-term limit (e.g., 100).
-// Kept for tests and simple use-cases.
-func NewMemoryStore(limit int) *MemoryStore {
-	return NewMemoryStoreWithWorkspace(".", limit)
+// NewMemoryStoreWithWorkspace creates a MemoryStore backed by
+// workspace/memory/memory.db and the markdown files next to it. If that
+// database can't be opened, memories are kept in RAM until restart; the
+// error is only returned when not even that works.
+func NewMemoryStoreWithWorkspace(workspace string, limit int) (*MemoryStore, error) {
+	return newStore(workspace, filepath.Join(workspace, "memory", "memory.db"), limit)
 }
 
-// NewMemoryStoreWithWorkspace creates a MemoryStore backed by files under workspace/memory/.
-func NewMemoryStoreWithWorkspace(workspace string, limit int) *MemoryStore {
+func newStore(ws, dbPath string, limit int) (*MemoryStore, error) {
 	if limit <= 0 {
 		limit = 100
 	}
 	ms := &MemoryStore{
-		workspace: workspace,
-		memoryDir: workspace + "/memory",
-		short:     make([]MemoryItem, 0, limit),
-		long:      make([]MemoryItem, 0),
+		workspace: ws,
+		memoryDir: filepath.Join(ws, "memory"),
 		limit:     limit,
 	}
 	// ensure memory directory exists
 	_ = os.MkdirAll(ms.memoryDir, 0o755)
-	return ms
+	var db *sql.DB
+	resolved, err := dbPath, error(nil)
+	if dbPath != ":memory:" {
+		resolved, err = workspace.Jail{Root: ws}.Resolve(dbPath)
+	}
+	if err == nil {
+		db, err = openDB(resolved)
+	}
+	if err != nil {
+		memoryLog.Error("opening database failed; keeping memories in RAM until restart", "err", err)
+		if db, err = openDB(":memory:"); err != nil {
+			return nil, fmt.Errorf("open in-memory database: %w", err)
+		}
+	}
+	ms.db = db
+	if dbPath == ":memory:" {
+		return ms, nil
+	}
+	if err := ms.importNotes(); err != nil {
+		memoryLog.Error("importing notes failed", "err", err)
+	}
+	if lt, err := ms.ReadLongTerm(); err == nil {
+		// pick up edits made to MEMORY.md while picobot was stopped
+		if err := ms.syncLong(lt, MemoryItem{}); err != nil {
+			memoryLog.Error("indexing MEMORY.md failed", "err", err)
+		}
+	}
+	return ms, nil
+}
+
+// Close closes the database.
+func (s *MemoryStore) Close() error {
+	return s.db.Close()
 }
 
 // AddShort adds a short-term memory entry.
 func (s *MemoryStore) AddShort(text string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	it := MemoryItem{Timestamp: time.Now().UTC(), Text: text, Kind: "short"}
-	s.short = append(s.short, it)
-	// drop oldest if over limit
-	if len(s.short) > s.limit {
-		s.short = s.short[len(s.short)-s.limit:]
+	if err := s.Add(MemoryItem{Kind: "short", Text: text}); err != nil {
+		memoryLog.Error("storing memory failed", "err", err)
 	}
 }
 
 // AddLong adds a long-term memory entry.
 func (s *MemoryStore) AddLong(text string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	it := MemoryItem{Timestamp: time.Now().UTC(), Text: text, Kind: "long"}
-	s.long = append(s.long, it)
+	if err := s.Add(MemoryItem{Kind: "long", Text: text}); err != nil {
+		memoryLog.Error("storing memory failed", "err", err)
+	}
+}
+
+// Add records item in the database only; a zero Timestamp means now.
+func (s *MemoryStore) Add(item MemoryItem) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := insert(tx, item); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // Recent returns up to n most recent memory items, combining short and long (short first).
+// Only the newest limit short-term items are considered.
 // Items are returned in most-recent-first order.
 func (s *MemoryStore) Recent(n int) []MemoryItem {
 	if n <= 0 {
 		return nil
 	}
-	out := make([]MemoryItem, 0, n)
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	// take from short (newest first)
-	for i := len(s.short) - 1; i >= 0 && len(out) < n; i-- {
-		out = append(out, s.short[i])
+	out, err := s.Query(Query{Kind: "short", Limit: min(n, s.limit)})
+	if err != nil {
+		memoryLog.Error("querying memories failed", "err", err)
 	}
-	// then from long (newest first)
-	for i := len(s.long) - 1; i >= 0 && len(out) < n; i-- {
-		out = append(out, s.long[i])
+	if len(out) < n {
+		long, err := s.Query(Query{Kind: "long", Limit: n - len(out)})
+		if err != nil {
+			memoryLog.Error("querying memories failed", "err", err)
+		}
+		out = append(out, long...)
+	}
+	return out
+}
+
+// Recall returns up to n short-term notes from before today, most recent
+// first: the memories GetMemoryContext leaves out of the prompt.
+func (s *MemoryStore) Recall(n int) []MemoryItem {
+	if n <= 0 {
+		return nil
+	}
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	out, err := s.Query(Query{Kind: "short", Until: today, Limit: n})
+	if err != nil {
+		memoryLog.Error("querying memories failed", "err", err)
 	}
 	return out
 }
@@ -99,25 +173,102 @@ func (s *MemoryStore) QueryByKeyword(keyword string, n int) []MemoryItem {
 	if n <= 0 || keyword == "" {
 		return nil
 	}
-	k := strings.ToLower(keyword)
-	out := make([]MemoryItem, 0, n)
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	// scan short (newest first)
-	for i := len(s.short) - 1; i >= 0 && len(out) < n; i-- {
-		if strings.Contains(strings.ToLower(s.short[i].Text), k) {
-			out = append(out, s.short[i])
+	out, err := s.Query(Query{Keyword: keyword, Limit: n})
+	if err != nil {
+		memoryLog.Error("querying memories failed", "err", err)
+	}
+	return out
+}
+
+// Query selects memories from the database. Zero fields don't filter.
+type Query struct {
+	Kind    string    // "short" or "long"
+	Keyword string    // case-insensitive (ASCII) substring of the text
+	Text    string    // words the text contains, in any order, found with the full-text index
+	Tag     string    // one of the item's tags
+	Channel string    // channel the item was recorded from
+	Since   time.Time // inclusive
+	Until   time.Time // exclusive
+	Limit   int       // default 100
+}
+
+// Query returns the memories matching q, short-term before long-term and
+// newest first within each.
+func (s *MemoryStore) Query(q Query) ([]MemoryItem, error) {
+	// expired memories are hidden until the sweep deletes them
+	where := []string{"(m.expires_at IS NULL OR m.expires_at > ?)"}
+	args := []any{time.Now().UnixNano()}
+	if q.Kind != "" {
+		where, args = append(where, "m.kind = ?"), append(args, q.Kind)
+	}
+	if q.Keyword != "" {
+		where, args = append(where, `m.text LIKE ? ESCAPE '\'`), append(args, "%"+likeEscaper.Replace(q.Keyword)+"%")
+	}
+	if q.Text != "" {
+		if match := ftsQuery(q.Text); match != "" {
+			where, args = append(where, "m.id IN (SELECT rowid FROM memories_fts WHERE memories_fts MATCH ?)"), append(args, match)
+		} else {
+			// no words, only punctuation: match it literally
+			where, args = append(where, `m.text LIKE ? ESCAPE '\'`), append(args, "%"+likeEscaper.Replace(q.Text)+"%")
 		}
 	}
-	// then long (newest first)
-	for i := len(s.long) - 1; i >= 0 && len(out) < n; i-- {
-		if strings.Contains(strings.ToLower(s.long[i].Text), k) {
-			out = append(out, s.long[i])
+	if q.Tag != "" {
+		where, args = append(where, "EXISTS (SELECT 1 FROM memory_tags t WHERE t.memory_id = m.id AND t.tag = ?)"), append(args, normalizeTag(q.Tag))
+	}
+	if q.Channel != "" {
+		where, args = append(where, "m.channel = ?"), append(args, q.Channel)
+	}
+	if !q.Since.IsZero() {
+		where, args = append(where, "m.created_at >= ?"), append(args, q.Since.UnixNano())
+	}
+	if !q.Until.IsZero() {
+		where, args = append(where, "m.created_at < ?"), append(args, q.Until.UnixNano())
+	}
+	if q.Limit <= 0 {
+		q.Limit = 100
+	}
+	stmt := `SELECT m.id, m.kind, m.text, m.created_at, m.channel, m.expires_at,
+		(SELECT group_concat(t.tag, ' ') FROM memory_tags t WHERE t.memory_id = m.id)
+		FROM memories m WHERE ` + strings.Join(where, " AND ") + " ORDER BY m.kind = 'short' DESC, m.created_at DESC, m.id DESC LIMIT ?"
+	rows, err := s.db.Query(stmt, append(args, q.Limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("memory: query: %w", err)
+	}
+	defer rows.Close()
+	var out []MemoryItem
+	for rows.Next() {
+		var it MemoryItem
+		var ts int64
+		var expires sql.NullInt64
+		var tags sql.NullString
+		if err := rows.Scan(&it.ID, &it.Kind, &it.Text, &ts, &it.Channel, &expires, &tags); err != nil {
+			return nil, fmt.Errorf("memory: query: %w", err)
+		}
+		it.Timestamp = time.Unix(0, ts).UTC()
+		if expires.Valid {
+			it.Expires = time.Unix(0, expires.Int64).UTC()
+		}
+		if tags.String != "" {
+			it.Tags = strings.Fields(tags.String)
 		}
+		out = append(out, it)
 	}
-	return out
+	return out, rows.Err()
 }
 
+// ftsQuery turns text into a full-text query matching every word of it,
+// each also as the prefix of a longer word ("meet" finds "meeting").
+func ftsQuery(text string) string {
+	words := strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsNumber(r) })
+	for i, w := range words {
+		words[i] = `"` + w + `"*`
+	}
+	return strings.Join(words, " ")
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
 // ReadLongTerm reads the long-term MEMORY.md file under workspace/memory/MEMORY.md
 func (s *MemoryStore) ReadLongTerm() (string, error) {
 	path := filepath.Join(s.memoryDir, "MEMORY.md")
@@ -131,13 +282,107 @@ func (s *MemoryStore) ReadLongTerm() (string, error) {
 	return string(b), nil
 }
 
-// WriteLongTerm writes content to MEMORY.md (overwrites).
+// WriteLongTerm writes content to MEMORY.md (overwrites) and updates the
+// long-term rows to match its lines.
 func (s *MemoryStore) WriteLongTerm(content string) error {
-	if err := os.MkdirAll(s.memoryDir, 0o755); err != nil {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writeLong(content, MemoryItem{})
+}
+
+// AppendLong appends item's text as a line of MEMORY.md and records it, with
+// its channel and tags, as a long-term memory.
+func (s *MemoryStore) AppendLong(item MemoryItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, err := s.ReadLongTerm()
+	if err != nil {
 		return err
 	}
-	path := filepath.Join(s.memoryDir, "MEMORY.md")
-	return os.WriteFile(path, []byte(content), 0o644)
+	return s.writeLong(prev+"\n"+item.Text, item)
+}
+
+func (s *MemoryStore) writeLong(content string, meta MemoryItem) error {
+	path, err := s.writablePath("MEMORY.md")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return err
+	}
+	return s.syncLong(content, meta)
+}
+
+// syncLong makes the long-term rows match the lines of content: rows whose
+// line is still there keep their timestamp and tags, new lines are added
+// with meta's channel and tags, and rows of removed lines are deleted.
+// Headings and blank lines are not memories.
+func (s *MemoryStore) syncLong(content string, meta MemoryItem) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	rows, err := tx.Query(`SELECT id, text FROM memories WHERE kind = 'long'`)
+	if err != nil {
+		return err
+	}
+	existing := map[string][]int64{}
+	for rows.Next() {
+		var id int64
+		var text string
+		if err := rows.Scan(&id, &text); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[text] = append(existing[text], id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if ids := existing[line]; len(ids) > 0 {
+			existing[line] = ids[1:]
+			continue
+		}
+		if err := insert(tx, MemoryItem{Kind: "long", Text: line, Channel: meta.Channel, Tags: meta.Tags, Expires: meta.Expires}); err != nil {
+			return err
+		}
+	}
+	for _, ids := range existing {
+		for _, id := range ids {
+			if _, err := tx.Exec(`DELETE FROM memory_tags WHERE memory_id = ?`, id); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(`DELETE FROM memories WHERE id = ?`, id); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// writablePath creates the memory directory and returns the resolved path of
+// name inside it, refusing paths that a symlink would redirect outside the
+// workspace.
+func (s *MemoryStore) writablePath(name string) (string, error) {
+	if err := os.MkdirAll(s.memoryDir, 0o755); err != nil {
+		return "", err
+	}
+	return workspace.Jail{Root: s.workspace, Strict: s.strict}.Resolve(filepath.Join(s.memoryDir, name))
+}
+
+// SetStrictPaths makes memory writes refuse paths through symlinks (see
+// workspace.Jail).
+func (s *MemoryStore) SetStrictPaths(strict bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.strict = strict
 }
 
 // ReadToday reads today's memory note file (YYYY-MM-DD.md)
@@ -154,20 +399,40 @@ func (s *MemoryStore) ReadToday() (string, error) {
 	return string(b), nil
 }
 
-// AppendToday appends a line (with timestamp) to today's memory note file.
+// AppendToday appends a line (with timestamp) to today's memory note file
+// and records it as a short-term memory.
 func (s *MemoryStore) AppendToday(text string) error {
-	if err := os.MkdirAll(s.memoryDir, 0o755); err != nil {
+	return s.AppendNote(MemoryItem{Text: text})
+}
+
+// AppendNote records item as a short-term memory and appends it, with its
+// timestamp and tags, to today's note file.
+func (s *MemoryStore) AppendNote(item MemoryItem) error {
+	item.Kind = "short"
+	item.Timestamp = time.Now().UTC()
+	item.Tags = normalizeTags(item.Tags)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path, err := s.writablePath(item.Timestamp.Format("2006-01-02") + ".md")
+	if err != nil {
 		return err
 	}
-	name := time.Now().UTC().Format("2006-01-02") + ".md"
-	path := filepath.Join(s.memoryDir, name)
 	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
-	_, err = fmt.Fprintf(f, "[%s] %s\n", time.Now().UTC().Format(time.RFC3339), text)
-	return err
+	line := item.Text
+	for _, t := range item.Tags {
+		line += " #" + t
+	}
+	if !item.Expires.IsZero() {
+		line += " (until " + item.Expires.Format("2006-01-02 15:04") + " UTC)"
+	}
+	if _, err := fmt.Fprintf(f, "[%s] %s\n", item.Timestamp.Format(time.RFC3339), line); err != nil {
+		return err
+	}
+	return s.Add(item)
 }
 
 // GetRecentMemories reads last N days' files and joins them with separators.
@@ -179,8 +444,11 @@ func (s *MemoryStore) GetRecentMemories(days int) (string, error) {
 	for i := 0; i < days; i++ {
 		d := time.Now().UTC().AddDate(0, 0, -i)
 		name := d.Format("2006-01-02") + ".md"
-		path := filepath.Join(s.memoryDir, name)
-		b, err := os.ReadFile(path)
+		b, err := os.ReadFile(filepath.Join(s.memoryDir, name))
+		if os.IsNotExist(err) {
+			// consolidated notes are kept in the archive
+			b, err = os.ReadFile(filepath.Join(s.memoryDir, "archive", name))
+		}
 		if err != nil {
 			if os.IsNotExist(err) {
 				continue
@@ -213,3 +481,74 @@ func (s *MemoryStore) GetMemoryContext() (string, error) {
 	}
 	return lt + "\n\n---\n\n" + td, nil
 }
+
+// noteLine matches a line of a daily note file: "[timestamp] text #tag".
+var noteLine = regexp.MustCompile(`^\[([^\]]+)\] (.*)$`)
+
+// importNotes fills a new database with the daily note files written before
+// it existed.
+func (s *MemoryStore) importNotes() error {
+	var version int
+	if err := s.db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil || version > 0 {
+		return err
+	}
+	files, err := filepath.Glob(filepath.Join(s.memoryDir, "????-??-??.md"))
+	if err != nil {
+		return err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, f := range files {
+		day, err := time.Parse("2006-01-02", strings.TrimSuffix(filepath.Base(f), ".md"))
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			it := MemoryItem{Kind: "short", Text: line, Timestamp: day}
+			if m := noteLine.FindStringSubmatch(line); m != nil {
+				if ts, err := time.Parse(time.RFC3339, m[1]); err == nil {
+					it.Timestamp, it.Text = ts, m[2]
+				}
+			}
+			it.Text, it.Tags = splitTags(it.Text)
+			if err := insert(tx, it); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, schemaVersion)); err != nil {
+		return err
+	}
+	if len(files) > 0 {
+		memoryLog.Info("imported daily note files into the database", "files", len(files))
+	}
+	return tx.Commit()
+}
+
+// splitTags separates the trailing #tags of a note from its text.
+func splitTags(line string) (string, []string) {
+	fields := strings.Fields(line)
+	i := len(fields)
+	for i > 1 && len(fields[i-1]) > 1 && strings.HasPrefix(fields[i-1], "#") {
+		i--
+	}
+	if i == len(fields) {
+		return line, nil
+	}
+	tags := make([]string, 0, len(fields)-i)
+	for _, f := range fields[i:] {
+		tags = append(tags, f[1:])
+	}
+	return strings.Join(fields[:i], " "), normalizeTags(tags)
+}
@@ -4,11 +4,15 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestMemoryPersistence_ReadWriteLongAndToday(t *testing.T) {
 	tmp := t.TempDir()
-	s := NewMemoryStoreWithWorkspace(tmp, 10)
+	s, err := NewMemoryStoreWithWorkspace(tmp, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	// Write long-term
 	if err := s.WriteLongTerm("Long-term fact\n"); err != nil {
@@ -58,3 +62,43 @@ func TestMemoryPersistence_ReadWriteLongAndToday(t *testing.T) {
 		t.Fatalf("expected memory context, got empty")
 	}
 }
+
+func TestMemoryRefusesSymlinkedMemoryDir(t *testing.T) {
+	ws := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(ws, "memory")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	s, err := NewMemoryStoreWithWorkspace(ws, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteLongTerm("secret"); err == nil {
+		t.Fatalf("expected write through symlinked memory dir to fail")
+	}
+	if err := s.AppendToday("note"); err == nil {
+		t.Fatalf("expected append through symlinked memory dir to fail")
+	}
+}
+
+func TestMemoryRefusesDanglingSymlink(t *testing.T) {
+	ws := t.TempDir()
+	outside := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(ws, "memory"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	today := time.Now().UTC().Format("2006-01-02") + ".md"
+	if err := os.Symlink(filepath.Join(outside, "planted"), filepath.Join(ws, "memory", today)); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	s, err := NewMemoryStoreWithWorkspace(ws, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AppendToday("note"); err == nil {
+		t.Fatalf("expected append through a dangling symlink to fail")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "planted")); !os.IsNotExist(err) {
+		t.Fatalf("expected nothing written outside the workspace, got %v", err)
+	}
+}
@@ -6,7 +6,10 @@ import (
 )
 
 func TestMemoryAddAndRecent(t *testing.T) {
-	s := NewMemoryStore(3)
+	s, err := NewMemoryStore(3)
+	if err != nil {
+		t.Fatal(err)
+	}
 	s.AddLong("L1")
 	s.AddShort("two")
 	s.AddShort("one")
@@ -21,7 +24,10 @@ func TestMemoryAddAndRecent(t *testing.T) {
 }
 
 func TestShortLimit(t *testing.T) {
-	s := NewMemoryStore(2)
+	s, err := NewMemoryStore(2)
+	if err != nil {
+		t.Fatal(err)
+	}
 	s.AddShort("c")
 	time.Sleep(5 * time.Millisecond)
 	s.AddShort("b")
@@ -38,7 +44,10 @@ func TestShortLimit(t *testing.T) {
 }
 
 func TestQueryByKeyword(t *testing.T) {
-	s := NewMemoryStore(10)
+	s, err := NewMemoryStore(10)
+	if err != nil {
+		t.Fatal(err)
+	}
 	s.AddLong("apple pie recipe")
 	s.AddShort("Remember the apple")
 
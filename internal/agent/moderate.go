@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kr0nicas/picobot/internal/audit"
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/moderation"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+// Moderation modes.
+const (
+	// ModerationBlock withholds flagged content from users.
+	ModerationBlock = "block"
+	// ModerationFlag delivers flagged content but records and reports it.
+	ModerationFlag = "flag"
+)
+
+const moderatedReply = "Sorry, I can't send that reply: it was withheld by the content filter."
+
+// SetModerator enables a moderation pass over replies, messages sent by the
+// message tool and files written by the agent. mode is ModerationBlock
+// (default) or ModerationFlag. A nil moderator disables moderation.
+func (a *AgentLoop) SetModerator(m moderation.Moderator, mode string) {
+	if mode == "" {
+		mode = ModerationBlock
+	}
+	a.settingsMu.Lock()
+	defer a.settingsMu.Unlock()
+	a.moderator = m
+	a.moderationMode = mode
+}
+
+// moderate checks generated text before it is delivered and reports whether
+// it may go out. Flagged content is logged, audited and reported to the
+// admins. Moderator errors let the content through.
+func (a *AgentLoop) moderate(ctx context.Context, msg *chat.Inbound, what, text string) bool {
+	a.settingsMu.RLock()
+	m, mode := a.moderator, a.moderationMode
+	a.settingsMu.RUnlock()
+	if m == nil || strings.TrimSpace(text) == "" {
+		return true
+	}
+	res, err := m.Moderate(ctx, text)
+	if err != nil {
+		agentLog.Warn("moderation failed, content passed through", "err", err)
+	}
+	if !res.Flagged {
+		return true
+	}
+	blocked := mode == ModerationBlock
+	cats := strings.Join(res.Categories, ", ")
+	if cats == "" {
+		cats = "unspecified"
+	}
+	agentLog.Warn("moderation flagged content", "what", what, "categories", cats, "blocked", blocked, "channel", msg.Channel, "chat_id", msg.ChatID)
+	a.audit(audit.KindModeration, msg, what+" flagged", map[string]interface{}{"categories": res.Categories, "blocked": blocked})
+	where := "a direct request"
+	if msg != nil {
+		where = msg.Channel + ":" + msg.ChatID
+	}
+	verb := "delivered"
+	if blocked {
+		verb = "blocked"
+	}
+	a.notifyAdmins(fmt.Sprintf("Content filter: %s for %s was flagged (%s) and %s.", what, where, cats, verb))
+	return !blocked
+}
+
+// generatedContent returns the user-facing content produced by a tool call
+// (messages sent, files written) so it can be moderated before it runs.
+func generatedContent(tc providers.ToolCall) (what, text string) {
+	switch tc.Name {
+	case "message":
+		text, _ = tc.Arguments["content"].(string)
+		return "message", text
+	case "filesystem":
+		switch act, _ := tc.Arguments["action"].(string); act {
+		case "write", "append", "edit":
+			if enc, _ := tc.Arguments["encoding"].(string); enc == "base64" {
+				return "", "" // binary data, nothing to read
+			}
+			path, _ := tc.Arguments["path"].(string)
+			text, _ = tc.Arguments["content"].(string)
+			if s, _ := tc.Arguments["new_string"].(string); s != "" {
+				text = s
+			}
+			return "file " + path, text
+		}
+	}
+	return "", ""
+}
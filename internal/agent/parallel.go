@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+const (
+	defaultParallelTools = 4
+	defaultToolTimeout   = 5 * time.Minute
+)
+
+// SetToolConcurrency sets how many tool calls of one reply run at once
+// (0 = default 4, 1 = one after another) and how long a call may run before
+// the agent gives up on it (0 = default 5m).
+func (a *AgentLoop) SetToolConcurrency(parallel int, timeout time.Duration) {
+	a.settingsMu.Lock()
+	defer a.settingsMu.Unlock()
+	a.parallelTools = parallel
+	a.toolTimeout = timeout
+}
+
+func (a *AgentLoop) toolLimits() (int, time.Duration) {
+	a.settingsMu.RLock()
+	defer a.settingsMu.RUnlock()
+	parallel, timeout := a.parallelTools, a.toolTimeout
+	if parallel <= 0 {
+		parallel = defaultParallelTools
+	}
+	if timeout <= 0 {
+		timeout = defaultToolTimeout
+	}
+	return parallel, timeout
+}
+
+// runTools executes the tool calls of one reply and returns their results
+// in the same order. Calls run concurrently on a bounded number of workers,
+// except those that wait for the user (approvals, confirm), which take
+// turns since a chat holds one question at a time.
+func (a *AgentLoop) runTools(ctx context.Context, t *tenant, msg *chat.Inbound, calls []providers.ToolCall) []string {
+	results := make([]string, len(calls))
+	parallel, _ := a.toolLimits()
+	if parallel == 1 || len(calls) == 1 {
+		for i, tc := range calls {
+			results[i] = a.runTool(ctx, t, msg, tc)
+		}
+		return results
+	}
+	var wg sync.WaitGroup
+	var userMu sync.Mutex
+	sem := make(chan struct{}, parallel)
+	for i, tc := range calls {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			if a.asksUser(t, msg, tc) {
+				userMu.Lock()
+				defer userMu.Unlock()
+			}
+			results[i] = a.runTool(ctx, t, msg, tc)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// asksUser reports whether tc may wait for an answer from the user.
+func (a *AgentLoop) asksUser(t *tenant, msg *chat.Inbound, tc providers.ToolCall) bool {
+	return tc.Name == "confirm" || t.tools.AsksUser(tc.Name) || a.approvalAction(t, msg, tc) != ""
+}
+
+// execute runs a tool call with the tool timeout. A tool that ignores its
+// context keeps running in the background, but the agent moves on.
+func (a *AgentLoop) execute(ctx context.Context, t *tenant, msg *chat.Inbound, tc providers.ToolCall) (string, error) {
+	_, timeout := a.toolLimits()
+	tctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	type result struct {
+		res string
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		res, err := t.tools.ExecuteAs(tctx, a.roleFor(msg), tc.Name, tc.Arguments, a.toolScopes(msg)...)
+		done <- result{res, err}
+	}()
+	select {
+	case r := <-done:
+		return r.res, r.err
+	case <-tctx.Done():
+		if ctx.Err() == nil && errors.Is(tctx.Err(), context.DeadlineExceeded) {
+			return "", fmt.Errorf("%s timed out after %s", tc.Name, timeout)
+		}
+		return "", tctx.Err()
+	}
+}
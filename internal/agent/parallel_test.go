@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+// sleepTool sleeps for args["ms"] and reports its argument.
+type sleepTool struct{ running, peak atomic.Int32 }
+
+func (s *sleepTool) Name() string                       { return "sleep" }
+func (s *sleepTool) Description() string                { return "sleep" }
+func (s *sleepTool) Parameters() map[string]interface{} { return nil }
+func (s *sleepTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	n := s.running.Add(1)
+	defer s.running.Add(-1)
+	for {
+		p := s.peak.Load()
+		if n <= p || s.peak.CompareAndSwap(p, n) {
+			break
+		}
+	}
+	ms, _ := args["ms"].(float64)
+	select {
+	case <-time.After(time.Duration(ms) * time.Millisecond):
+		return fmt.Sprintf("slept %v", ms), nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func TestToolCallsRunConcurrentlyInOrder(t *testing.T) {
+	ag := newTestLoop(t, chat.NewHub(10), &contextLimitProvider{}, "test", 3, t.TempDir(), nil)
+	st := &sleepTool{}
+	ag.tools.Register(st)
+	ag.SetToolConcurrency(2, 0)
+
+	var calls []providers.ToolCall
+	for i, ms := range []float64{60, 10, 30, 20} {
+		calls = append(calls, providers.ToolCall{ID: fmt.Sprint(i), Name: "sleep", Arguments: map[string]interface{}{"ms": ms}})
+	}
+	start := time.Now()
+	res := ag.runTools(context.Background(), ag.tenant, nil, calls)
+	if got := strings.Join(res, ","); got != "slept 60,slept 10,slept 30,slept 20" {
+		t.Fatalf("results out of order: %s", got)
+	}
+	if st.peak.Load() != 2 {
+		t.Fatalf("expected 2 calls at once, peak was %d", st.peak.Load())
+	}
+	if d := time.Since(start); d >= 110*time.Millisecond {
+		t.Fatalf("calls did not overlap: took %s", d)
+	}
+
+	ag.SetToolConcurrency(0, 20*time.Millisecond)
+	res = ag.runTools(context.Background(), ag.tenant, nil, calls[:2])
+	if !strings.Contains(res[0], "timed out after 20ms") || res[1] != "slept 10" {
+		t.Fatalf("expected only the slow call to time out, got %q", res)
+	}
+}
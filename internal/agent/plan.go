@@ -0,0 +1,291 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+// planMinWords is the length below which messages are not considered for a
+// plan unless /plan asks for one.
+const planMinWords = 8
+
+// maxPlanSteps bounds the steps taken from a plan.
+const maxPlanSteps = 12
+
+// maxPlanRevisions is how often the user can have a plan changed before
+// they are asked to send the request again.
+const maxPlanRevisions = 3
+
+const (
+	planPrompt       = `Before doing anything, decide whether this request needs several distinct steps with tools, such as building or setting something up, research across several sources or changes to several files. If it doesn't, reply with the single word SIMPLE. If it does, reply with a numbered plan of 2 to 10 short steps, one per line, and nothing else. Don't start working yet.`
+	forcedPlanPrompt = `Before doing anything, reply with a numbered plan of 2 to 10 short steps for this request, one per line, and nothing else. Don't start working yet.`
+)
+
+// SetPlanning turns planning mode on or off. In planning mode the agent
+// first drafts a numbered plan for requests that need several steps and
+// works through it only once the user approves it. /plan asks for a plan
+// either way.
+func (a *AgentLoop) SetPlanning(on bool) {
+	a.settingsMu.Lock()
+	defer a.settingsMu.Unlock()
+	a.planning = on
+}
+
+func (a *AgentLoop) planningOn() bool {
+	a.settingsMu.RLock()
+	defer a.settingsMu.RUnlock()
+	return a.planning
+}
+
+// planStep is one step of a plan; Status is "", "done" or "skipped".
+type planStep struct {
+	Text   string `json:"text"`
+	Status string `json:"status,omitempty"`
+	Note   string `json:"note,omitempty"`
+}
+
+// taskPlan is a plan the user approved, saved to <workspace>/state/plans/
+// while the agent works through it.
+type taskPlan struct {
+	Channel  string     `json:"channel"`
+	ChatID   string     `json:"chatID"`
+	Request  string     `json:"request"`
+	Steps    []planStep `json:"steps"`
+	Approved time.Time  `json:"approved"`
+	Updated  time.Time  `json:"updated"`
+}
+
+// planPath returns the plan file of a chat.
+func planPath(workspace, channel, chatID string) string {
+	return filepath.Join(workspace, "state", "plans", tenantKey(channel, chatID)+".json")
+}
+
+// save writes the plan atomically.
+func (p *taskPlan) save(workspace string) error {
+	p.Updated = time.Now().UTC()
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := planPath(workspace, p.Channel, p.ChatID)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// clear removes the plan file once the task is over.
+func (p *taskPlan) clear(workspace string) {
+	if err := os.Remove(planPath(workspace, p.Channel, p.ChatID)); err != nil && !os.IsNotExist(err) {
+		agentLog.Warn("removing plan failed", "err", err)
+	}
+}
+
+// loadPlan reads a plan file; a missing file is (nil, nil).
+func loadPlan(path string) (*taskPlan, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var p taskPlan
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &p, nil
+}
+
+// render lists the steps with their status.
+func (p *taskPlan) render() string {
+	var sb strings.Builder
+	for i, s := range p.Steps {
+		mark := " "
+		switch s.Status {
+		case "done":
+			mark = "x"
+		case "skipped":
+			mark = "-"
+		}
+		fmt.Fprintf(&sb, "%d. [%s] %s", i+1, mark, s.Text)
+		if s.Note != "" {
+			fmt.Fprintf(&sb, " (%s)", s.Note)
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// instructions is added to the request the plan was approved for.
+func (p *taskPlan) instructions() string {
+	return "\n\n[The user approved this plan]\n" + p.render() +
+		"Work through the steps in order and call update_plan after each one, then give the user your final reply."
+}
+
+var planLineRE = regexp.MustCompile(`^\s*\d+[.)]\s+(.+)$`)
+
+// parsePlan returns the numbered steps of a reply; a SIMPLE reply has none.
+func parsePlan(reply string) []string {
+	var steps []string
+	for _, line := range strings.Split(reply, "\n") {
+		if m := planLineRE.FindStringSubmatch(line); m != nil && len(steps) < maxPlanSteps {
+			steps = append(steps, strings.TrimSpace(m[1]))
+		}
+	}
+	return steps
+}
+
+// planRequest drafts a plan for msg, the last of messages, and asks its
+// sender to approve it. It returns the approved plan, or nil if the
+// request needs none; a non-empty reply means the request is not to be
+// worked on and reply answers it instead.
+func (a *AgentLoop) planRequest(ctx context.Context, t *tenant, msg *chat.Inbound, messages []providers.Message, forced bool) (*taskPlan, string) {
+	instr := planPrompt
+	if forced {
+		instr = forcedPlanPrompt
+	}
+	last := len(messages) - 1
+	convo := slices.Clone(messages)
+	convo[last].Content += "\n\n" + instr
+	a.settingsMu.RLock()
+	timeout := a.approvalTimeout
+	a.settingsMu.RUnlock()
+	if timeout <= 0 {
+		timeout = defaultApprovalTimeout
+	}
+	aliases := make(map[string]string)
+	for _, w := range approveWords {
+		aliases[w] = "Approve"
+	}
+	for _, w := range denyWords {
+		aliases[w] = "Cancel"
+	}
+	aliases["stop"] = "Cancel"
+
+	for revision := 0; ; revision++ {
+		resp, err := a.chat(ctx, TaskChat, convo, nil, nil)
+		if err != nil {
+			agentLog.Warn("planning failed", "err", err)
+			return nil, ""
+		}
+		steps := parsePlan(resp.Content)
+		if len(steps) < 2 {
+			return nil, ""
+		}
+		prompt := "Here is my plan:\n" + strings.Join(numbered(steps), "\n") +
+			"\n\nReply \"yes\" to go ahead, \"no\" to cancel, or tell me what to change."
+		answer, err := a.ask(ctx, msg, prompt, []string{"Approve", "Cancel"}, aliases, true, timeout)
+		switch {
+		case errors.Is(err, errQuestionPending):
+			return nil, ""
+		case errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil:
+			return nil, "The plan wasn't approved in time, so I didn't start. Send the request again when you're ready."
+		case err != nil:
+			return nil, "Cancelled."
+		case answer == "Cancel":
+			return nil, "OK, I won't go ahead with that plan."
+		case answer == "Approve":
+			p := &taskPlan{Channel: msg.Channel, ChatID: msg.ChatID, Request: msg.Content, Approved: time.Now().UTC()}
+			for _, s := range steps {
+				p.Steps = append(p.Steps, planStep{Text: s})
+			}
+			if err := p.save(t.workspace); err != nil {
+				agentLog.Error("saving plan failed", "err", err)
+			}
+			return p, ""
+		}
+		if revision == maxPlanRevisions {
+			return nil, "Let's start over: send the request again with the changes you want."
+		}
+		convo = append(convo,
+			providers.Message{Role: "assistant", Content: resp.Content},
+			providers.Message{Role: "user", Content: "Change the plan: " + answer + "\n\n" + forcedPlanPrompt})
+	}
+}
+
+// withoutTool returns defs without the definition of the named tool, and
+// that definition if it was there.
+func withoutTool(defs []providers.ToolDefinition, name string) ([]providers.ToolDefinition, *providers.ToolDefinition) {
+	for i, d := range defs {
+		if d.Name == name {
+			return slices.Delete(defs, i, i+1), &d
+		}
+	}
+	return defs, nil
+}
+
+// numbered numbers lines from 1.
+func numbered(lines []string) []string {
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		out[i] = fmt.Sprintf("%d. %s", i+1, l)
+	}
+	return out
+}
+
+// planCommand answers "/plan" without a request: it shows the chat's plan,
+// if one is being worked on or was interrupted.
+func (a *AgentLoop) planCommand(t *tenant, msg *chat.Inbound) string {
+	p, err := loadPlan(planPath(t.workspace, msg.Channel, msg.ChatID))
+	if err != nil {
+		agentLog.Warn("loading plan failed", "err", err)
+	}
+	if p == nil {
+		return "Send /plan followed by a request to get a plan to approve before I start on it."
+	}
+	return fmt.Sprintf("Plan for %q:\n%s", clipRunes(p.Request, 200), p.render())
+}
+
+// updatePlan implements tools.PlanFunc for the chat in ctx.
+func (a *AgentLoop) updatePlan(ctx context.Context, step int, status, note string) (string, error) {
+	msg, _ := ctx.Value(inboundKey{}).(*chat.Inbound)
+	if msg == nil {
+		return "", errors.New("update_plan: there is no plan in this context")
+	}
+	t, err := a.tenantFor(msg.Channel, msg.ChatID)
+	if err != nil {
+		return "", fmt.Errorf("update_plan: %w", err)
+	}
+	p, err := loadPlan(planPath(t.workspace, msg.Channel, msg.ChatID))
+	if err != nil {
+		return "", fmt.Errorf("update_plan: %w", err)
+	}
+	if p == nil {
+		return "", errors.New("update_plan: there is no approved plan in this chat")
+	}
+	if step < 1 || step > len(p.Steps) {
+		return "", fmt.Errorf("update_plan: the plan has steps 1 to %d", len(p.Steps))
+	}
+	s := &p.Steps[step-1]
+	s.Status, s.Note = status, strings.TrimSpace(note)
+	if err := p.save(t.workspace); err != nil {
+		return "", fmt.Errorf("update_plan: %w", err)
+	}
+	a.reportProgress(ctx, fmt.Sprintf("Step %d/%d %s: %s", step, len(p.Steps), status, s.Text))
+	left := 0
+	for _, s := range p.Steps {
+		if s.Status == "" {
+			left++
+		}
+	}
+	if left == 0 {
+		return p.render() + "All steps are finished; give the user your final reply.", nil
+	}
+	return p.render(), nil
+}
@@ -0,0 +1,114 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+// Progress update defaults.
+const (
+	defaultProgressSteps = 5
+	defaultProgressAfter = time.Minute
+	defaultProgressEvery = time.Minute
+)
+
+// ProgressUpdates configures the progress messages of long tasks. Zero
+// fields use the defaults; a negative threshold is turned off.
+type ProgressUpdates struct {
+	AfterSteps int           // rounds of tool calls before the first update; default 5
+	After      time.Duration // running time before the first update; default 1m
+	Every      time.Duration // between updates; default 1m
+}
+
+// SetProgressUpdates makes the agent tell a chat how a task is going once
+// it has taken more than p.AfterSteps rounds of tool calls or longer than
+// p.After, and then at most every p.Every. An update is the latest status
+// the model gave with report_progress, or else the tools it is calling.
+func (a *AgentLoop) SetProgressUpdates(p ProgressUpdates) {
+	if p.AfterSteps == 0 {
+		p.AfterSteps = defaultProgressSteps
+	}
+	if p.After == 0 {
+		p.After = defaultProgressAfter
+	}
+	if p.Every <= 0 {
+		p.Every = defaultProgressEvery
+	}
+	a.settingsMu.Lock()
+	defer a.settingsMu.Unlock()
+	a.progress = &p
+}
+
+// taskProgress is what the progress updates of a running task go by.
+type taskProgress struct {
+	mu       sync.Mutex
+	started  time.Time
+	lastSent time.Time
+	status   string // from report_progress, not shown yet
+}
+
+// reportProgress implements tools.ProgressFunc for the task of the chat
+// in ctx.
+func (a *AgentLoop) reportProgress(ctx context.Context, status string) {
+	msg, _ := ctx.Value(inboundKey{}).(*chat.Inbound)
+	if msg == nil {
+		return
+	}
+	a.tasksMu.Lock()
+	task := a.tasks[msg.Channel+":"+msg.ChatID]
+	a.tasksMu.Unlock()
+	if task == nil {
+		return
+	}
+	task.progress.mu.Lock()
+	task.progress.status = status
+	task.progress.mu.Unlock()
+}
+
+// progressUpdate sends a progress message to msg's chat after round step
+// of tool calls, if its task runs long enough and no update went out
+// recently.
+func (a *AgentLoop) progressUpdate(msg *chat.Inbound, step int, calls []providers.ToolCall) {
+	a.settingsMu.RLock()
+	cfg := a.progress
+	a.settingsMu.RUnlock()
+	a.tasksMu.Lock()
+	task := a.tasks[msg.Channel+":"+msg.ChatID]
+	a.tasksMu.Unlock()
+	if cfg == nil || task == nil {
+		return
+	}
+	p := &task.progress
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	long := (cfg.AfterSteps > 0 && step >= cfg.AfterSteps) || (cfg.After > 0 && now.Sub(p.started) >= cfg.After)
+	if !long || now.Sub(p.lastSent) < cfg.Every {
+		return
+	}
+	text := p.status
+	if text == "" {
+		text = fmt.Sprintf("Still working on it (step %d): %s…", step, toolNames(calls))
+	}
+	p.status, p.lastSent = "", now
+	a.publish(chat.Outbound{Channel: msg.Channel, ChatID: msg.ChatID, Content: text})
+}
+
+// toolNames lists the tools of calls once each, in order.
+func toolNames(calls []providers.ToolCall) string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, tc := range calls {
+		if !seen[tc.Name] {
+			seen[tc.Name] = true
+			names = append(names, tc.Name)
+		}
+	}
+	return "running " + strings.Join(names, ", ")
+}
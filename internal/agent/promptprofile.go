@@ -0,0 +1,155 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Sections of the system prompt besides the bootstrap files, which are
+// sections named after their file ("SOUL.md").
+const (
+	SectionChannel    = "channel"    // the channel and chat the request comes from
+	SectionMemoryTool = "memoryTool" // how to write memory
+	SectionSkills     = "skills"     // the skills and instructions of the relevant ones
+	SectionMemory     = "memory"     // long-term memory and today's notes
+	SectionMemories   = "memories"   // ranked memories
+)
+
+// DefaultPromptSections is the order of the system prompt sections unless a
+// PromptProfile names its own. The master instruction always comes first and
+// history last.
+var DefaultPromptSections = []string{"SOUL.md", "AGENTS.md", "USER.md", "TOOLS.md", SectionChannel, SectionMemoryTool, SectionSkills, SectionMemory, SectionMemories}
+
+// promptOverridesFile is the workspace file whose "## <section>" parts
+// replace sections of the system prompt.
+const promptOverridesFile = "PROMPT_OVERRIDES.md"
+
+// PromptProfile controls what goes into the system prompt.
+type PromptProfile struct {
+	Sections     []string // included sections in order; nil = DefaultPromptSections
+	Omit         []string // sections left out
+	MaxFileChars int      // bootstrap files longer than this are cut; 0 = no limit
+}
+
+// sections returns the sections of p in order.
+func (p PromptProfile) sections() []string {
+	order := p.Sections
+	if order == nil {
+		order = DefaultPromptSections
+	}
+	out := make([]string, 0, len(order))
+	for _, s := range order {
+		if !slices.Contains(p.Omit, s) && !slices.Contains(out, s) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// validate reports sections that are neither built in nor a workspace
+// Markdown file.
+func (p PromptProfile) validate() error {
+	for _, s := range append(slices.Clone(p.Sections), p.Omit...) {
+		if !isPromptSection(s) {
+			return fmt.Errorf("unknown prompt section %q; use a workspace .md file or %s", s, strings.Join([]string{SectionChannel, SectionMemoryTool, SectionSkills, SectionMemory, SectionMemories}, ", "))
+		}
+	}
+	if p.MaxFileChars < 0 {
+		return fmt.Errorf("maxFileChars must not be negative")
+	}
+	return nil
+}
+
+// isPromptSection reports whether name is a built-in section or the name of
+// a Markdown file at the top of the workspace.
+func isPromptSection(name string) bool {
+	switch name {
+	case SectionChannel, SectionMemoryTool, SectionSkills, SectionMemory, SectionMemories:
+		return true
+	}
+	return strings.HasSuffix(name, ".md") && filepath.Base(name) == name && name != promptOverridesFile
+}
+
+// SetPromptProfiles sets the prompt profile used by default and the
+// per-channel ones, for all current and future tenants.
+func (a *AgentLoop) SetPromptProfiles(def PromptProfile, perChannel map[string]PromptProfile) error {
+	if err := def.validate(); err != nil {
+		return err
+	}
+	for channel, p := range perChannel {
+		if err := p.validate(); err != nil {
+			return fmt.Errorf("channel %s: %w", channel, err)
+		}
+	}
+	a.settingsMu.Lock()
+	a.promptProfile, a.channelPrompts = def, perChannel
+	a.settingsMu.Unlock()
+	for _, t := range a.allTenants() {
+		t.context.SetProfiles(def, perChannel)
+	}
+	return nil
+}
+
+// SetProfiles sets the prompt profile used by default and those of
+// channels.
+func (cb *ContextBuilder) SetProfiles(def PromptProfile, perChannel map[string]PromptProfile) {
+	cb.profile, cb.channelProfiles = def, perChannel
+}
+
+// profileFor returns the prompt profile of channel.
+func (cb *ContextBuilder) profileFor(channel string) PromptProfile {
+	if p, ok := cb.channelProfiles[channel]; ok {
+		return p
+	}
+	return cb.profile
+}
+
+// promptOverrides reads the workspace's PROMPT_OVERRIDES.md and returns the
+// sections it replaces on channel. A "## <section>" part applies to every
+// channel, a "## <section> @<channel>" part only to that channel and wins
+// over the former; an empty part leaves the section out. Text before the
+// first heading is ignored.
+func (cb *ContextBuilder) promptOverrides(channel string) map[string]string {
+	data, err := os.ReadFile(filepath.Join(cb.workspace, promptOverridesFile))
+	if err != nil {
+		return nil
+	}
+	general := make(map[string]string)
+	specific := make(map[string]string)
+	var target map[string]string
+	var name string
+	var body strings.Builder
+	flush := func() {
+		if target != nil {
+			target[name] = strings.TrimSpace(body.String())
+		}
+		body.Reset()
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		heading, ok := strings.CutPrefix(strings.TrimRight(line, "\r"), "## ")
+		if !ok {
+			body.WriteString(line + "\n")
+			continue
+		}
+		flush()
+		name, target = strings.TrimSpace(heading), general
+		if n, ch, ok := strings.Cut(name, "@"); ok {
+			name, target = strings.TrimSpace(n), specific
+			if strings.TrimSpace(ch) != channel {
+				target = nil
+			}
+		}
+		if target != nil && !isPromptSection(name) {
+			agentLog.Warn("unknown prompt section", "file", promptOverridesFile, "section", name)
+			target = nil
+		}
+	}
+	flush()
+	for name, content := range specific {
+		general[name] = content
+	}
+	return general
+}
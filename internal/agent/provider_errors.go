@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+// chatFitting is chat that recovers from prompts too long for the model by
+// dropping the oldest half of the replayed history, messages[s:*histEnd]
+// where s is the number of leading system messages, and trying again.
+// *histEnd is moved back by the number of dropped messages.
+func (a *AgentLoop) chatFitting(ctx context.Context, task string, messages *[]providers.Message, histEnd *int, toolDefs []providers.ToolDefinition, onDelta providers.StreamFunc) (providers.LLMResponse, error) {
+	for {
+		resp, err := a.chat(ctx, task, *messages, toolDefs, onDelta)
+		if !errors.Is(err, providers.ErrContextTooLong) {
+			return resp, err
+		}
+		shorter, dropped := dropOldHistory(*messages, *histEnd)
+		if dropped == 0 {
+			return resp, err
+		}
+		agentLog.Warn("prompt too long, dropped the oldest history messages", "model", a.modelFor(ctx, task), "dropped", dropped)
+		*messages, *histEnd = shorter, *histEnd-dropped
+	}
+}
+
+// dropOldHistory removes the oldest half (at least one) of the history that
+// ends at histEnd, keeping it starting with a user message. It returns the
+// new messages and how many were removed.
+func dropOldHistory(messages []providers.Message, histEnd int) ([]providers.Message, int) {
+	start := 0
+	for start < len(messages) && messages[start].Role == "system" {
+		start++
+	}
+	n := histEnd - start
+	if n <= 0 {
+		return messages, 0
+	}
+	drop := max(n/2, 1)
+	for drop < n && messages[start+drop].Role != "user" {
+		drop++
+	}
+	out := make([]providers.Message, 0, len(messages)-drop)
+	out = append(out, messages[:start]...)
+	out = append(out, messages[start+drop:]...)
+	return out, drop
+}
+
+// providerErrorReply tells the user why a request to the provider failed.
+func providerErrorReply(err error, model string) string {
+	var rl *providers.ErrRateLimited
+	switch {
+	case errors.As(err, &rl):
+		if rl.RetryAfter > 0 {
+			return fmt.Sprintf("I'm being rate-limited by the AI provider. Please try again in %s.", rl.RetryAfter.Round(time.Second))
+		}
+		return "I'm being rate-limited by the AI provider. Please try again in a minute."
+	case errors.Is(err, providers.ErrAuth):
+		return "I can't reach the AI provider: it rejected the API key. Please check the provider configuration."
+	case errors.Is(err, providers.ErrContextTooLong):
+		return "This conversation is too long for the model. Send /reset to start over."
+	case errors.Is(err, providers.ErrContentFiltered):
+		return "The AI provider's content filter blocked this request."
+	case errors.Is(err, providers.ErrModelNotFound):
+		return fmt.Sprintf("The model %q is not available at the provider. An admin can switch models with /model.", model)
+	}
+	return "Sorry, I encountered an error while processing your request."
+}
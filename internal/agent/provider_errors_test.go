@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+// contextLimitProvider fails while a prompt has more than limit messages.
+type contextLimitProvider struct {
+	limit int
+	sizes []int
+	err   error
+}
+
+func (p *contextLimitProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string) (providers.LLMResponse, error) {
+	p.sizes = append(p.sizes, len(messages))
+	if p.err != nil {
+		return providers.LLMResponse{}, p.err
+	}
+	if len(messages) > p.limit {
+		return providers.LLMResponse{}, fmt.Errorf("API error: 400: %w", providers.ErrContextTooLong)
+	}
+	if messages[len(messages)-1].Content != "latest" {
+		return providers.LLMResponse{}, fmt.Errorf("current message lost: %q", messages[len(messages)-1].Content)
+	}
+	return providers.LLMResponse{Content: "fits"}, nil
+}
+
+func (p *contextLimitProvider) GetDefaultModel() string { return "test" }
+
+func TestHistoryIsTrimmedWhenPromptIsTooLong(t *testing.T) {
+	p := &contextLimitProvider{limit: 6}
+	hub := chat.NewHub(10)
+	ag := newTestLoop(t, hub, p, "test", 3, t.TempDir(), nil)
+	s := ag.sessions.GetOrCreate("telegram", "1")
+	for i := 0; i < 10; i++ {
+		s.AddMessage("user", fmt.Sprintf("q%d", i))
+		s.AddMessage("assistant", fmt.Sprintf("a%d", i))
+	}
+
+	ag.processMessage(context.Background(), chat.Inbound{Channel: "telegram", SenderID: "1", ChatID: "1", Content: "latest"})
+	if out := <-hub.Out; out.Content != "fits" {
+		t.Fatalf("expected a reply after trimming, got %q (prompt sizes %v)", out.Content, p.sizes)
+	}
+	if len(p.sizes) < 2 || p.sizes[len(p.sizes)-1] > 6 {
+		t.Fatalf("expected shrinking prompts, got %v", p.sizes)
+	}
+}
+
+func TestProviderErrorReplies(t *testing.T) {
+	p := &contextLimitProvider{err: fmt.Errorf("API error: 401: %w", providers.ErrAuth)}
+	hub := chat.NewHub(10)
+	ag := newTestLoop(t, hub, p, "test", 3, t.TempDir(), nil)
+	ag.processMessage(context.Background(), chat.Inbound{Channel: "telegram", SenderID: "1", ChatID: "1", Content: "hi"})
+	if out := <-hub.Out; !strings.Contains(out.Content, "rejected the API key") {
+		t.Fatalf("unexpected reply %q", out.Content)
+	}
+	if got := providerErrorReply(&providers.ErrRateLimited{RetryAfter: 20e9}, "m"); !strings.Contains(got, "20s") {
+		t.Fatalf("expected the retry hint in %q", got)
+	}
+}
@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/agent/memory"
+)
+
+// Memory rankers selectable with SetMemoryRanking.
+const (
+	RankerHybrid = "hybrid" // the model re-ranks the best keyword matches
+	RankerLLM    = "llm"    // the model ranks every candidate
+	RankerSimple = "simple" // keyword overlap only, no requests
+)
+
+// defaultTopK is how many ranked memories go into the prompt by default.
+const defaultTopK = 5
+
+// MemoryRanking configures how earlier notes are picked for the prompt.
+// Zero fields use the defaults.
+type MemoryRanking struct {
+	Ranker     string        // RankerHybrid (default), RankerLLM or RankerSimple
+	TopK       int           // memories put in the prompt; default 5
+	Candidates int           // keyword matches the hybrid ranker sends; default 12
+	Timeout    time.Duration // of a ranking request; default 15s, negative means none
+}
+
+// SetMemoryRanking sets the memory ranker of all current and future
+// tenants. Ranking requests use the model of TaskRanking.
+func (a *AgentLoop) SetMemoryRanking(r MemoryRanking) error {
+	switch r.Ranker {
+	case "":
+		r.Ranker = RankerHybrid
+	case RankerHybrid, RankerLLM, RankerSimple:
+	case "vector":
+		return fmt.Errorf("memory ranker %q: no vector index is available; use %s, %s or %s", r.Ranker, RankerHybrid, RankerLLM, RankerSimple)
+	default:
+		return fmt.Errorf("unknown memory ranker %q; use %s, %s or %s", r.Ranker, RankerHybrid, RankerLLM, RankerSimple)
+	}
+	a.settingsMu.Lock()
+	a.ranking = r
+	a.settingsMu.Unlock()
+	for _, t := range a.allTenants() {
+		t.context.ranker, t.context.topK = a.newRanker()
+	}
+	return nil
+}
+
+// newRanker builds the memory ranker of a tenant and returns it with the
+// number of memories to pick.
+func (a *AgentLoop) newRanker() (memory.Ranker, int) {
+	a.settingsMu.RLock()
+	r := a.ranking
+	a.settingsMu.RUnlock()
+	topK := r.TopK
+	if topK <= 0 {
+		topK = defaultTopK
+	}
+	if r.Ranker == RankerSimple {
+		return memory.NewSimpleRanker(), topK
+	}
+	llm := memory.NewLLMRanker(taskProvider{a, TaskRanking}, "")
+	switch {
+	case r.Ranker == RankerLLM:
+		llm.SetCandidates(0)
+	case r.Candidates > 0:
+		llm.SetCandidates(r.Candidates)
+	}
+	switch {
+	case r.Timeout < 0:
+		llm.SetTimeout(0)
+	case r.Timeout > 0:
+		llm.SetTimeout(r.Timeout)
+	}
+	return llm, topK
+}
@@ -0,0 +1,38 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kr0nicas/picobot/internal/agent/memory"
+	"github.com/kr0nicas/picobot/internal/chat"
+)
+
+func TestSetMemoryRanking(t *testing.T) {
+	ws := filepath.Join(t.TempDir(), "workspace") // tenants go next to it
+	os.MkdirAll(ws, 0o755)
+	ag := newTestLoop(t, chat.NewHub(10), &modelRecorder{}, "big", 3, ws, nil)
+	if _, ok := ag.context.ranker.(*memory.LLMMemoryRanker); !ok || ag.context.topK != defaultTopK {
+		t.Fatalf("unexpected default ranker %T, topK %d", ag.context.ranker, ag.context.topK)
+	}
+	if err := ag.SetMemoryRanking(MemoryRanking{Ranker: RankerSimple, TopK: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ag.context.ranker.(*memory.SimpleRanker); !ok || ag.context.topK != 2 {
+		t.Fatalf("ranking not applied: %T, topK %d", ag.context.ranker, ag.context.topK)
+	}
+	ag.SetMultiTenant(true)
+	tn, err := ag.tenantFor("telegram", "7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tn.context.ranker.(*memory.SimpleRanker); !ok {
+		t.Fatalf("new tenants must use the configured ranker, got %T", tn.context.ranker)
+	}
+	for _, bad := range []string{"vector", "bm25"} {
+		if err := ag.SetMemoryRanking(MemoryRanking{Ranker: bad}); err == nil {
+			t.Errorf("expected an error for ranker %q", bad)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kr0nicas/picobot/internal/session"
+)
+
+// reflectTimeout bounds one reflection.
+const reflectTimeout = 2 * time.Minute
+
+// reflectMessageChars is how much of each message a reflection sees.
+const reflectMessageChars = 4000
+
+// SetReflection makes the agent review every nth exchange of each chat with
+// the model of TaskReflect, together with the exchanges before it since the
+// last review, and store the facts worth remembering. 0 turns it off.
+func (a *AgentLoop) SetReflection(every int) {
+	a.settingsMu.Lock()
+	defer a.settingsMu.Unlock()
+	a.reflectEvery = max(every, 0)
+}
+
+// afterExchange counts a completed exchange of a chat and, when a
+// reflection is due, starts one in the background.
+func (a *AgentLoop) afterExchange(ctx context.Context, t *tenant, s *session.Session, channel, chatID string) {
+	a.settingsMu.RLock()
+	every := a.reflectEvery
+	a.settingsMu.RUnlock()
+	if every == 0 {
+		return
+	}
+	key := channel + ":" + chatID
+	a.reflectMu.Lock()
+	a.exchanges[key]++
+	due := a.exchanges[key] >= every
+	if due {
+		delete(a.exchanges, key)
+	}
+	a.reflectMu.Unlock()
+	if !due {
+		return
+	}
+	history := s.GetHistory()
+	start, users := len(history), 0
+	for start > 0 && users < every {
+		start--
+		if history[start].Role == "user" {
+			users++
+		}
+	}
+	history = history[start:]
+	transcript := formatTranscript(history)
+	go func() {
+		rctx, cancel := context.WithTimeout(ctx, reflectTimeout)
+		defer cancel()
+		stored, err := t.memory.Reflect(rctx, taskProvider{a, TaskReflect}, "", transcript, channel)
+		if err != nil {
+			agentLog.Error("reflecting on conversation failed", "chat", key, "err", err)
+		}
+		if len(stored) > 0 {
+			agentLog.Info("reflection stored memories", "chat", key, "count", len(stored))
+		}
+	}()
+}
+
+// formatTranscript renders the user messages and replies of history for a
+// reflection; tool calls and results are left out.
+func formatTranscript(history []session.Message) string {
+	var sb strings.Builder
+	for _, m := range history {
+		if m.Role == "tool" || len(m.ToolCalls) > 0 {
+			continue
+		}
+		content := m.Content
+		if len(content) > reflectMessageChars {
+			cut := reflectMessageChars
+			for cut > 0 && !utf8.RuneStart(content[cut]) {
+				cut--
+			}
+			content = content[:cut] + " [...]"
+		}
+		fmt.Fprintf(&sb, "%s: %s\n", m.Role, content)
+	}
+	return sb.String()
+}
@@ -0,0 +1,67 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+// Tasks that can be routed to their own model with SetModelRoutes.
+const (
+	TaskChat      = "chat"      // replies to users; always the active model
+	TaskRanking   = "ranking"   // picking relevant memories for the prompt
+	TaskHeartbeat = "heartbeat" // heartbeat tasks
+	TaskSummarize = "summarize" // condensing history and notes
+	TaskSubagent  = "subagent"  // spawned background agents
+	TaskReflect   = "reflect"   // picking facts to remember from conversations
+	TaskRoute     = "route"     // picking the named agent of a message
+)
+
+// taskParams override the configured generation params per task. Ranking,
+// reflection and routing are asked for quick, repeatable answers.
+var taskParams = map[string]providers.GenerationParams{
+	TaskRanking: {Temperature: providers.Float(0), ThinkingBudget: providers.Int(0)},
+	TaskReflect: {Temperature: providers.Float(0), ThinkingBudget: providers.Int(0)},
+	TaskRoute:   {Temperature: providers.Float(0), ThinkingBudget: providers.Int(0)},
+}
+
+// SetModelRoutes sets the model used per task, so cheap models can do
+// background work while the active model answers users. Tasks without a
+// route, and TaskChat, use the active model (see SetModel).
+func (a *AgentLoop) SetModelRoutes(routes map[string]string) {
+	a.settingsMu.Lock()
+	defer a.settingsMu.Unlock()
+	a.modelRoutes = make(map[string]string, len(routes))
+	for task, model := range routes {
+		if model != "" && task != TaskChat {
+			a.modelRoutes[task] = model
+		}
+	}
+}
+
+// ModelFor returns the model that handles task.
+func (a *AgentLoop) ModelFor(task string) string {
+	a.settingsMu.RLock()
+	defer a.settingsMu.RUnlock()
+	if m, ok := a.modelRoutes[task]; ok {
+		return m
+	}
+	return a.model
+}
+
+// taskProvider hands a task's requests to the agent's provider with the
+// task's current model, for components such as the memory ranker that are
+// created once but must follow route and model changes.
+type taskProvider struct {
+	a    *AgentLoop
+	task string
+}
+
+func (p taskProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, _ string) (providers.LLMResponse, error) {
+	model := p.a.ModelFor(p.task)
+	resp, err := p.a.provider.Chat(providers.WithParams(ctx, taskParams[p.task]), messages, tools, model)
+	p.a.recordUsage(model, p.task, resp.Usage)
+	return resp, err
+}
+
+func (p taskProvider) GetDefaultModel() string { return p.a.ModelFor(p.task) }
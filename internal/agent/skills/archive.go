@@ -0,0 +1,209 @@
+package skills
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Limits of imported skill archives.
+const (
+	MaxArchiveBytes = 10 << 20 // unpacked size
+	maxArchiveFiles = 500
+)
+
+// Export writes the named skill in skillsDir to w as a gzipped tarball whose
+// entries sit in a folder named after the skill. Hidden files and folders,
+// such as the skill's .venv, are left out.
+func Export(skillsDir, name string, w io.Writer) error {
+	dir, err := skillFolder(skillsDir, name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(dir, "SKILL.md")); err != nil {
+		return fmt.Errorf("skills: no skill %q", name)
+	}
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, p)
+		if rel != "." && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() && !d.Type().IsRegular() {
+			return nil // symlinks and the like are not exported
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(fi, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = path.Join(name, filepath.ToSlash(rel))
+		if d.IsDir() {
+			hdr.Name += "/"
+		}
+		hdr.Uid, hdr.Gid, hdr.Uname, hdr.Gname = 0, 0, "", ""
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("skills: exporting %s: %w", name, err)
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Import unpacks a skill exported with Export from r into skillsDir and
+// returns it. The archive must hold a single folder with a valid SKILL.md;
+// the folder's name is the skill's. An existing skill of that name is only
+// replaced if replace is set.
+func Import(skillsDir string, r io.Reader, replace bool) (Skill, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return Skill{}, fmt.Errorf("skills: not a gzipped tarball: %w", err)
+	}
+	if err := os.MkdirAll(skillsDir, 0o755); err != nil {
+		return Skill{}, err
+	}
+	stage, err := os.MkdirTemp(skillsDir, ".import-")
+	if err != nil {
+		return Skill{}, err
+	}
+	defer os.RemoveAll(stage)
+
+	name, err := unpack(tar.NewReader(gz), stage)
+	if err != nil {
+		return Skill{}, err
+	}
+	data, err := os.ReadFile(filepath.Join(stage, name, "SKILL.md"))
+	if err != nil {
+		return Skill{}, fmt.Errorf("skills: the archive has no %s/SKILL.md", name)
+	}
+	skill, err := Parse(string(data))
+	if err != nil {
+		return Skill{}, fmt.Errorf("skills: %s/SKILL.md: %w", name, err)
+	}
+
+	dest, err := skillFolder(skillsDir, name)
+	if err != nil {
+		return Skill{}, err
+	}
+	if _, err := os.Lstat(dest); err == nil {
+		if !replace {
+			return Skill{}, fmt.Errorf("skills: there is already a skill %q", name)
+		}
+		old := filepath.Join(stage, ".old")
+		if err := os.Rename(dest, old); err != nil {
+			return Skill{}, err
+		}
+	}
+	if err := os.Rename(filepath.Join(stage, name), dest); err != nil {
+		return Skill{}, err
+	}
+	return skill, nil
+}
+
+// unpack extracts the regular files and folders of tr into dir and returns
+// the single top-level folder they are in.
+func unpack(tr *tar.Reader, dir string) (string, error) {
+	top := ""
+	var size int64
+	for files := 0; ; files++ {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("skills: reading archive: %w", err)
+		}
+		if files >= maxArchiveFiles {
+			return "", fmt.Errorf("skills: archive has more than %d entries", maxArchiveFiles)
+		}
+		clean := path.Clean(strings.TrimPrefix(hdr.Name, "./"))
+		if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") || clean == "." {
+			return "", fmt.Errorf("skills: unsafe path %q in archive", hdr.Name)
+		}
+		first, _, nested := strings.Cut(clean, "/")
+		if !nested && hdr.Typeflag != tar.TypeDir {
+			return "", errors.New("skills: the archive must hold a single skill folder")
+		}
+		if top == "" {
+			top = first
+		} else if first != top {
+			return "", errors.New("skills: the archive must hold a single skill folder")
+		}
+		dest := filepath.Join(dir, filepath.FromSlash(clean))
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(dest, 0o755); err != nil {
+				return "", err
+			}
+		case tar.TypeReg:
+			if size += hdr.Size; size > MaxArchiveBytes {
+				return "", fmt.Errorf("skills: archive unpacks to more than %d MB", MaxArchiveBytes>>20)
+			}
+			if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+				return "", err
+			}
+			mode := os.FileMode(0o644)
+			if hdr.Mode&0o111 != 0 {
+				mode = 0o755
+			}
+			f, err := os.OpenFile(dest, os.O_CREATE|os.O_EXCL|os.O_WRONLY, mode)
+			if err != nil {
+				return "", err
+			}
+			_, err = io.Copy(f, io.LimitReader(tr, hdr.Size))
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return "", err
+			}
+		default:
+			return "", fmt.Errorf("skills: %q in archive is not a regular file or folder", hdr.Name)
+		}
+	}
+	if top == "" {
+		return "", errors.New("skills: empty archive")
+	}
+	return top, nil
+}
+
+// skillFolder returns the folder of the named skill in skillsDir, refusing
+// names that are not a single path component.
+func skillFolder(skillsDir, name string) (string, error) {
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("skills: invalid skill name %q", name)
+	}
+	return filepath.Join(skillsDir, name), nil
+}
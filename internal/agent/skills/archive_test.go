@@ -0,0 +1,107 @@
+package skills
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func writeSkill(t *testing.T, skillsDir, name, description string) {
+	t.Helper()
+	dir := filepath.Join(skillsDir, name)
+	if err := os.MkdirAll(filepath.Join(dir, "scripts"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	md := "---\nname: " + name + "\ndescription: " + description + "\n---\n\nBody"
+	if err := os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte(md), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "scripts", "run.py"), []byte("print(1)\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	src := t.TempDir()
+	writeSkill(t, src, "feeds", "Read feeds")
+	if err := os.MkdirAll(filepath.Join(src, "feeds", ".venv"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := Export(src, "feeds", &buf); err != nil {
+		t.Fatal(err)
+	}
+	archive := buf.Bytes()
+
+	dst := t.TempDir()
+	skill, err := Import(dst, bytes.NewReader(archive), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if skill.Name != "feeds" || skill.Description != "Read feeds" {
+		t.Fatalf("unexpected skill: %+v", skill)
+	}
+	fi, err := os.Stat(filepath.Join(dst, "feeds", "scripts", "run.py"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && fi.Mode().Perm()&0o100 == 0 {
+		t.Errorf("script lost its executable bit: %v", fi.Mode())
+	}
+	if _, err := os.Stat(filepath.Join(dst, "feeds", ".venv")); err == nil {
+		t.Error(".venv was exported")
+	}
+
+	if _, err := Import(dst, bytes.NewReader(archive), false); err == nil {
+		t.Fatal("importing over an installed skill needs replace")
+	}
+	os.WriteFile(filepath.Join(dst, "feeds", "stale.txt"), []byte("x"), 0o644)
+	if _, err := Import(dst, bytes.NewReader(archive), true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "feeds", "stale.txt")); err == nil {
+		t.Error("replace kept files of the old skill")
+	}
+	entries, _ := os.ReadDir(dst)
+	if len(entries) != 1 {
+		t.Errorf("staging folders left behind: %v", entries)
+	}
+}
+
+func TestImportRejectsUnsafeArchives(t *testing.T) {
+	tarball := func(entries map[string]string) []byte {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gz)
+		for name, body := range entries {
+			tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(body)), Typeflag: tar.TypeReg})
+			tw.Write([]byte(body))
+		}
+		tw.Close()
+		gz.Close()
+		return buf.Bytes()
+	}
+	md := "---\nname: x\ndescription: d\n---\n"
+	cases := map[string][]byte{
+		"parent path":   tarball(map[string]string{"../x/SKILL.md": md}),
+		"absolute path": tarball(map[string]string{"/tmp/x/SKILL.md": md}),
+		"two folders":   tarball(map[string]string{"a/SKILL.md": md, "b/SKILL.md": md}),
+		"top-level":     tarball(map[string]string{"SKILL.md": md}),
+		"no SKILL.md":   tarball(map[string]string{"x/README.md": "hi"}),
+		"not gzip":      []byte("plain text"),
+	}
+	for name, data := range cases {
+		dir := t.TempDir()
+		if _, err := Import(dir, bytes.NewReader(data), false); err == nil {
+			t.Errorf("%s: import succeeded", name)
+		}
+		if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "x")); err == nil {
+			t.Errorf("%s: wrote outside the skills folder", name)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package skills
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// GitSync keeps a skills folder in sync with a git remote, so instances
+// can share their skills: Pull merges the remote's skills on startup and
+// Commit publishes local changes.
+type GitSync struct {
+	dir    string
+	remote string
+	branch string
+	mu     sync.Mutex
+}
+
+// NewGitSync returns a sync of dir with branch (default "main") of remote.
+func NewGitSync(dir, remote, branch string) *GitSync {
+	if branch == "" {
+		branch = "main"
+	}
+	return &GitSync{dir: dir, remote: remote, branch: branch}
+}
+
+// Dir returns the synced skills folder.
+func (g *GitSync) Dir() string { return g.dir }
+
+// Pull makes the folder a clone of the remote if it isn't one, commits
+// local changes, merges the remote branch and pushes the result. A merge
+// that conflicts is aborted, leaving the local skills as they were.
+func (g *GitSync) Pull() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.init(); err != nil {
+		return err
+	}
+	if _, err := g.commitAll("picobot: sync local skills"); err != nil {
+		return err
+	}
+	heads, err := g.git("ls-remote", "--heads", "origin", g.branch)
+	if err != nil {
+		return err
+	}
+	if heads != "" {
+		if _, err := g.git("fetch", "origin", g.branch); err != nil {
+			return err
+		}
+		if _, err := g.git("merge", "--no-edit", "--allow-unrelated-histories", "FETCH_HEAD"); err != nil {
+			g.git("merge", "--abort")
+			return fmt.Errorf("skills: merging %s of %s failed, local skills kept: %w", g.branch, g.remote, err)
+		}
+	}
+	_, err = g.git("push", "origin", "HEAD:"+g.branch)
+	return err
+}
+
+// Commit commits every change in the folder with message and pushes it.
+// Nothing is pushed when there is nothing to commit.
+func (g *GitSync) Commit(message string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.init(); err != nil {
+		return err
+	}
+	committed, err := g.commitAll(message)
+	if err != nil || !committed {
+		return err
+	}
+	_, err = g.git("push", "origin", "HEAD:"+g.branch)
+	return err
+}
+
+// init creates the repository and points origin at the remote.
+func (g *GitSync) init() error {
+	if err := os.MkdirAll(g.dir, 0o755); err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(g.dir, ".git")); errors.Is(err, os.ErrNotExist) {
+		if _, err := g.git("init", "-q"); err != nil {
+			return err
+		}
+		if _, err := g.git("symbolic-ref", "HEAD", "refs/heads/"+g.branch); err != nil {
+			return err
+		}
+		skillsLog.Info("created a git repository", "dir", g.dir, "remote", g.remote)
+	}
+	if email, _ := g.git("config", "user.email"); email == "" {
+		// commits and merges need an author; don't borrow one that isn't set
+		if _, err := g.git("config", "user.name", "picobot"); err != nil {
+			return err
+		}
+		if _, err := g.git("config", "user.email", "picobot@localhost"); err != nil {
+			return err
+		}
+	}
+	ignore := filepath.Join(g.dir, ".gitignore")
+	if _, err := os.Stat(ignore); errors.Is(err, os.ErrNotExist) {
+		// skill environments, import staging and the built-in skills
+		// manifest stay local
+		if err := os.WriteFile(ignore, []byte(".venv/\n.import-*/\n.embedded.json\n"), 0o644); err != nil {
+			return err
+		}
+	}
+	if url, err := g.git("remote", "get-url", "origin"); err != nil {
+		_, err = g.git("remote", "add", "origin", g.remote)
+		return err
+	} else if url != g.remote {
+		_, err = g.git("remote", "set-url", "origin", g.remote)
+		return err
+	}
+	return nil
+}
+
+// commitAll stages and commits everything and reports whether there was
+// anything to commit.
+func (g *GitSync) commitAll(message string) (bool, error) {
+	if _, err := g.git("add", "-A"); err != nil {
+		return false, err
+	}
+	if status, err := g.git("status", "--porcelain"); err != nil || status == "" {
+		return false, err
+	}
+	_, err := g.git("commit", "-q", "-m", message)
+	return err == nil, err
+}
+
+// git runs a git command in the folder and returns its trimmed output.
+func (g *GitSync) git(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = g.dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("skills: git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
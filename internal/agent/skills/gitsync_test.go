@@ -0,0 +1,49 @@
+package skills
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestGitSync(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	remote := filepath.Join(t.TempDir(), "skills.git")
+	if out, err := exec.Command("git", "init", "-q", "--bare", remote).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v %s", err, out)
+	}
+
+	a := NewGitSync(filepath.Join(t.TempDir(), "skills"), remote, "")
+	writeSkill(t, a.Dir(), "weather", "Get weather")
+	if err := a.Pull(); err != nil {
+		t.Fatal(err)
+	}
+
+	b := NewGitSync(filepath.Join(t.TempDir(), "skills"), remote, "")
+	writeSkill(t, b.Dir(), "feeds", "Read feeds")
+	if err := b.Pull(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(b.Dir(), "weather", "SKILL.md")); err != nil {
+		t.Fatal("pull did not bring the remote's skill")
+	}
+
+	if err := os.RemoveAll(filepath.Join(b.Dir(), "weather")); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Commit("delete weather"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Pull(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(a.Dir(), "feeds", "SKILL.md")); err != nil {
+		t.Error("pull did not bring the other instance's skill")
+	}
+	if _, err := os.Stat(filepath.Join(a.Dir(), "weather")); err == nil {
+		t.Error("pull did not apply the deletion")
+	}
+}
@@ -1,22 +1,56 @@
 package skills
 
 import (
+	"context"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/logging"
 )
 
+var skillsLog = logging.For("skills")
+
 // Skill represents a loaded skill with its metadata and content.
 type Skill struct {
 	Name        string
 	Description string
 	Content     string
+	// Triggers are words and phrases that make the skill relevant to a
+	// message (see Select); AlwaysLoad skills are relevant to every one.
+	Triggers   []string
+	AlwaysLoad bool
+	// Scripts are helper scripts in the skill's folder (e.g.
+	// "scripts/fetch.py"), run with exec and its "skill" argument.
+	// Dependencies are the Python packages they need, installed with uv on
+	// first use; Examples show how to invoke them.
+	Scripts      []string
+	Dependencies []string
+	Examples     []string
 }
 
-// Loader handles loading skills from the skills directory.
+// Loader handles loading skills from the skills directory. Loaded skills
+// are cached until a SKILL.md is added, removed or modified on disk, so
+// edits apply to the next prompt without a restart.
 type Loader struct {
 	workspacePath string
+
+	mu     sync.Mutex
+	loaded bool
+	skills []Skill
+	files  map[string]fileStamp // skill folder -> its SKILL.md when loaded
+}
+
+// fileStamp identifies a version of a SKILL.md.
+type fileStamp struct {
+	mod  time.Time
+	size int64
 }
 
 // NewLoader creates a new skill loader.
@@ -24,33 +58,104 @@ func NewLoader(workspacePath string) *Loader {
 	return &Loader{workspacePath: workspacePath}
 }
 
-// LoadAll loads all skills from the skills directory.
+// LoadAll loads all skills from the skills directory. Skills that fail to
+// parse are skipped.
 func (l *Loader) LoadAll() ([]Skill, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.refresh(); err != nil {
+		return nil, err
+	}
+	return slices.Clone(l.skills), nil
+}
+
+// Invalidate drops the cached skills, so the next LoadAll reads them all
+// again. The skill tools call it after changing a skill.
+func (l *Loader) Invalidate() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.loaded = false
+}
+
+// Watch checks the skills directory for changes every interval until ctx
+// is done, reloading and logging changed skills as they are picked up.
+func (l *Loader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		l.mu.Lock()
+		if err := l.refresh(); err != nil {
+			skillsLog.Error("refreshing skills failed", "err", err)
+		}
+		l.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// scan returns the SKILL.md of each skill folder.
+func (l *Loader) scan() (map[string]fileStamp, error) {
 	skillsPath := filepath.Join(l.workspacePath, "skills")
 	entries, err := os.ReadDir(skillsPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return []Skill{}, nil
+			return map[string]fileStamp{}, nil
 		}
 		return nil, err
 	}
-
-	var skills []Skill
+	files := make(map[string]fileStamp, len(entries))
 	for _, entry := range entries {
 		if !entry.IsDir() {
 			continue
 		}
-		skillPath := filepath.Join(skillsPath, entry.Name(), "SKILL.md")
-		if _, err := os.Stat(skillPath); err == nil {
-			skill, err := l.loadSkill(skillPath)
-			if err != nil {
-				// skip invalid skills but log error
-				continue
+		if fi, err := os.Stat(filepath.Join(skillsPath, entry.Name(), "SKILL.md")); err == nil {
+			files[entry.Name()] = fileStamp{mod: fi.ModTime(), size: fi.Size()}
+		}
+	}
+	return files, nil
+}
+
+// refresh reloads the skills if any SKILL.md changed since they were
+// loaded. Changes after the first load are logged. l.mu must be held.
+func (l *Loader) refresh() error {
+	files, err := l.scan()
+	if err != nil {
+		return err
+	}
+	if l.loaded && maps.Equal(files, l.files) {
+		return nil
+	}
+
+	names := slices.Sorted(maps.Keys(files))
+	skills := make([]Skill, 0, len(names))
+	for _, name := range names {
+		skill, err := l.loadSkill(filepath.Join(l.workspacePath, "skills", name, "SKILL.md"))
+		changed := l.files != nil && l.files[name] != files[name]
+		if err != nil {
+			if changed {
+				skillsLog.Warn("skipping skill", "skill", name, "err", err)
 			}
-			skills = append(skills, skill)
+			continue
+		}
+		if changed {
+			if _, ok := l.files[name]; ok {
+				skillsLog.Info("reloaded skill", "skill", name)
+			} else {
+				skillsLog.Info("added skill", "skill", name)
+			}
+		}
+		skills = append(skills, skill)
+	}
+	for name := range l.files {
+		if _, ok := files[name]; !ok {
+			skillsLog.Info("removed skill", "skill", name)
 		}
 	}
-	return skills, nil
+	l.skills, l.files, l.loaded = skills, files, true
+	return nil
 }
 
 // LoadByName loads a specific skill by name.
@@ -65,27 +170,35 @@ func (l *Loader) loadSkill(skillPath string) (Skill, error) {
 	if err != nil {
 		return Skill{}, err
 	}
+	return Parse(string(content))
+}
 
-	// Parse frontmatter
-	lines := strings.Split(string(content), "\n")
+// Parse parses the contents of a SKILL.md: frontmatter between "---" lines
+// followed by the instructions. Lists in the frontmatter may be written
+// inline ("triggers: [weather, forecast]" or "triggers: weather, forecast")
+// or as "- item" lines below the key; examples, which may contain commas,
+// take one inline value or "- item" lines.
+func Parse(content string) (Skill, error) {
+	lines := strings.Split(content, "\n")
 	if len(lines) < 3 || lines[0] != "---" {
 		return Skill{}, fmt.Errorf("invalid SKILL.md format: missing frontmatter")
 	}
 
 	skill := Skill{}
-	inFrontmatter := true
 	contentStartIdx := 0
+	listKey := "" // key whose "- item" lines follow
 
 	for i := 1; i < len(lines); i++ {
 		line := lines[i]
 		if line == "---" {
-			inFrontmatter = false
 			contentStartIdx = i + 1
 			break
 		}
-		if !inFrontmatter {
-			break
+		if item, ok := strings.CutPrefix(strings.TrimSpace(line), "- "); ok && listKey != "" {
+			skill.addList(listKey, unquote(strings.TrimSpace(item)))
+			continue
 		}
+		listKey = ""
 		parts := strings.SplitN(line, ":", 2)
 		if len(parts) != 2 {
 			continue
@@ -96,7 +209,20 @@ func (l *Loader) loadSkill(skillPath string) (Skill, error) {
 		case "name":
 			skill.Name = value
 		case "description":
-			skill.Description = value
+			skill.Description = unquote(value)
+		case "triggers", "keywords", "scripts", "dependencies":
+			if value == "" {
+				listKey = key
+			}
+			skill.addList(key, splitList(value)...)
+		case "examples":
+			if value == "" {
+				listKey = key
+			} else {
+				skill.addList(key, unquote(value))
+			}
+		case "always_load":
+			skill.AlwaysLoad, _ = strconv.ParseBool(value)
 		}
 	}
 
@@ -111,3 +237,56 @@ func (l *Loader) loadSkill(skillPath string) (Skill, error) {
 
 	return skill, nil
 }
+
+// addList appends items to the frontmatter list named key.
+func (s *Skill) addList(key string, items ...string) {
+	switch key {
+	case "triggers", "keywords":
+		s.Triggers = append(s.Triggers, items...)
+	case "scripts":
+		s.Scripts = append(s.Scripts, items...)
+	case "dependencies":
+		s.Dependencies = append(s.Dependencies, items...)
+	case "examples":
+		s.Examples = append(s.Examples, items...)
+	}
+}
+
+// Manifest describes the skill's scripts, dependencies and example
+// invocations for the prompt, or returns "" if it has none.
+func (s Skill) Manifest() string {
+	var sb strings.Builder
+	if len(s.Scripts) > 0 {
+		fmt.Fprintf(&sb, "Scripts (run with exec and \"skill\": %q; paths are relative to the skill): %s\n", s.Name, strings.Join(s.Scripts, ", "))
+	}
+	if len(s.Dependencies) > 0 {
+		fmt.Fprintf(&sb, "Python packages (installed on first use): %s\n", strings.Join(s.Dependencies, ", "))
+	}
+	if len(s.Examples) > 0 {
+		sb.WriteString("Examples:\n")
+		for _, e := range s.Examples {
+			fmt.Fprintf(&sb, "- %s\n", e)
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// splitList splits an inline frontmatter list such as "[a, 'b c']".
+func splitList(s string) []string {
+	s = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(s), "["), "]")
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = unquote(strings.TrimSpace(item)); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// unquote strips the quotes around a YAML scalar.
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
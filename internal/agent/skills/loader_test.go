@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoader_LoadAll(t *testing.T) {
@@ -91,3 +92,61 @@ func TestLoader_LoadByName(t *testing.T) {
 		t.Errorf("expected content to contain 'Test content', got '%s'", skill.Content)
 	}
 }
+
+func TestLoader_PicksUpChanges(t *testing.T) {
+	tmpDir := t.TempDir()
+	write := func(name, desc string, mod time.Time) {
+		dir := filepath.Join(tmpDir, "skills", name)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		p := filepath.Join(dir, "SKILL.md")
+		if err := os.WriteFile(p, []byte("---\nname: "+name+"\ndescription: "+desc+"\n---\n\nbody"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	descriptions := func(l *Loader) map[string]string {
+		skills, err := l.LoadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := map[string]string{}
+		for _, s := range skills {
+			out[s.Name] = s.Description
+		}
+		return out
+	}
+
+	base := time.Now().Add(-time.Hour)
+	write("weather", "old", base)
+	loader := NewLoader(tmpDir)
+	if d := descriptions(loader); d["weather"] != "old" {
+		t.Fatalf("first load: %v", d)
+	}
+
+	write("weather", "new", base.Add(time.Minute))
+	write("notes", "take notes", base)
+	if d := descriptions(loader); d["weather"] != "new" || d["notes"] != "take notes" {
+		t.Fatalf("edits not picked up: %v", d)
+	}
+
+	if err := os.RemoveAll(filepath.Join(tmpDir, "skills", "notes")); err != nil {
+		t.Fatal(err)
+	}
+	if d := descriptions(loader); len(d) != 1 {
+		t.Fatalf("removed skill still loaded: %v", d)
+	}
+
+	// same size and time: only an invalidation shows the edit
+	write("weather", "NEW", base.Add(time.Minute))
+	if d := descriptions(loader); d["weather"] != "new" {
+		t.Fatalf("expected cached skill, got %v", d)
+	}
+	loader.Invalidate()
+	if d := descriptions(loader); d["weather"] != "NEW" {
+		t.Fatalf("invalidated skill not reloaded: %v", d)
+	}
+}
@@ -0,0 +1,48 @@
+package skills
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Select splits skills into those relevant to text, whose instructions go
+// into the prompt, and the rest, which are only listed. A skill is relevant
+// when it is marked always_load or when one of its triggers (its name, if it
+// has none) starts a word of text; "remind" matches "reminders" but not
+// "unreminded".
+func Select(all []Skill, text string) (relevant, rest []Skill) {
+	for _, s := range all {
+		if s.Matches(text) {
+			relevant = append(relevant, s)
+		} else {
+			rest = append(rest, s)
+		}
+	}
+	return relevant, rest
+}
+
+// Matches reports whether the skill is relevant to text.
+func (s Skill) Matches(text string) bool {
+	if s.AlwaysLoad {
+		return true
+	}
+	triggers := s.Triggers
+	if len(triggers) == 0 {
+		triggers = []string{s.Name}
+	}
+	text = " " + normalize(text)
+	for _, t := range triggers {
+		if t = normalize(t); t != "" && strings.Contains(text, " "+t) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalize lowercases s and turns runs of anything but letters and digits
+// into single spaces.
+func normalize(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
@@ -0,0 +1,72 @@
+package skills
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseTriggers(t *testing.T) {
+	inline, err := Parse("---\nname: weather\ndescription: \"Forecasts\"\ntriggers: [weather, 'rain or shine']\nalways_load: false\n---\n\nUse wttr.in")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inline.Description != "Forecasts" || len(inline.Triggers) != 2 || inline.Triggers[1] != "rain or shine" || inline.AlwaysLoad {
+		t.Fatalf("unexpected skill: %+v", inline)
+	}
+
+	block, err := Parse("---\nname: cron\nkeywords:\n  - remind\n  - every day\nalways_load: true\n---\nbody")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(block.Triggers) != 2 || block.Triggers[1] != "every day" || !block.AlwaysLoad || block.Content != "body" {
+		t.Fatalf("unexpected skill: %+v", block)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	all := []Skill{
+		{Name: "weather", Triggers: []string{"forecast", "rain or shine"}},
+		{Name: "git-helper"},
+		{Name: "rules", AlwaysLoad: true},
+	}
+	names := func(skills []Skill) string {
+		s := ""
+		for _, sk := range skills {
+			s += sk.Name + " "
+		}
+		return s
+	}
+	for _, c := range []struct{ text, want string }{
+		{"What's the FORECAST for Berlin?", "weather rules "},
+		{"forecasts please", "weather rules "},
+		{"rain-or-shine, let's go", "weather rules "},
+		{"ask the git helper", "git-helper rules "},
+		{"hello", "rules "},
+		{"weather?", "rules "}, // weather has its own triggers
+	} {
+		relevant, rest := Select(all, c.text)
+		if got := names(relevant); got != c.want {
+			t.Errorf("Select(%q) = %q, want %q", c.text, got, c.want)
+		}
+		if len(relevant)+len(rest) != len(all) {
+			t.Errorf("Select(%q) lost skills", c.text)
+		}
+	}
+}
+
+func TestParseManifest(t *testing.T) {
+	s, err := Parse("---\nname: feeds\ndescription: d\nscripts: [scripts/read.py]\ndependencies:\n  - feedparser\n  - \"requests>=2\"\nexamples: exec {\"cmd\": [\"python3\", \"scripts/read.py\", \"a,b\"]}\n---\nbody")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Scripts) != 1 || len(s.Dependencies) != 2 || s.Dependencies[1] != "requests>=2" || len(s.Examples) != 1 {
+		t.Fatalf("unexpected manifest: %+v", s)
+	}
+	m := s.Manifest()
+	if !strings.Contains(m, `"skill": "feeds"`) || !strings.Contains(m, "feedparser, requests>=2") || !strings.Contains(m, `"a,b"]}`) {
+		t.Fatalf("unexpected manifest text: %s", m)
+	}
+	if (Skill{Name: "plain"}).Manifest() != "" {
+		t.Fatal("skills without scripts have no manifest")
+	}
+}
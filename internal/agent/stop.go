@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+// errStopped is the cause of a task's context when its chat asks to stop it.
+var errStopped = errors.New("stopped by the user")
+
+// stopWords stop a chat's running task when sent on their own, like /stop.
+var stopWords = map[string]bool{"stop": true, "cancel": true}
+
+// runningTask is a user message the agent is working on.
+type runningTask struct {
+	senderID string
+	cancel   context.CancelCauseFunc
+	progress taskProgress
+}
+
+// startTask registers the task of msg's chat so stopTask can cancel it. It
+// returns the task's context and the function to call when it is over.
+func (a *AgentLoop) startTask(ctx context.Context, msg *chat.Inbound) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	key := msg.Channel + ":" + msg.ChatID
+	task := &runningTask{senderID: msg.SenderID, cancel: cancel, progress: taskProgress{started: time.Now()}}
+	a.tasksMu.Lock()
+	a.tasks[key] = task
+	a.tasksMu.Unlock()
+	return ctx, func() {
+		a.tasksMu.Lock()
+		if a.tasks[key] == task {
+			delete(a.tasks, key)
+		}
+		a.tasksMu.Unlock()
+		cancel(nil)
+	}
+}
+
+// stopTask cancels the running task of msg's chat if msg is /stop or a stop
+// word from the user who started it or an admin, and reports whether it did.
+// It runs before msg is queued, since the worker is busy with the task.
+func (a *AgentLoop) stopTask(msg chat.Inbound) bool {
+	word := strings.ToLower(strings.Trim(strings.TrimSpace(msg.Content), ".!"))
+	if commandName(msg.Content) != "/stop" && !stopWords[word] {
+		return false
+	}
+	a.tasksMu.Lock()
+	task, ok := a.tasks[msg.Channel+":"+msg.ChatID]
+	a.tasksMu.Unlock()
+	if !ok || (task.senderID != msg.SenderID && !a.isAdmin(msg)) {
+		return false
+	}
+	task.cancel(errStopped)
+	return true
+}
+
+// maxStoppedSteps bounds the tool calls listed when a task is stopped.
+const maxStoppedSteps = 10
+
+// stoppedReply tells what a stopped task had done: its tool calls, with the
+// first line of each result.
+func stoppedReply(steps []providers.Message) string {
+	results := make(map[string]string)
+	var calls []providers.ToolCall
+	for _, m := range steps {
+		calls = append(calls, m.ToolCalls...)
+		if m.Role == "tool" {
+			results[m.ToolCallID] = m.Content
+		}
+	}
+	if len(calls) == 0 {
+		return "Stopped before any step was done."
+	}
+	var sb strings.Builder
+	if len(calls) == 1 {
+		sb.WriteString("Stopped after 1 step.")
+	} else {
+		fmt.Fprintf(&sb, "Stopped after %d steps.", len(calls))
+	}
+	if n := len(calls) - maxStoppedSteps; n > 0 {
+		fmt.Fprintf(&sb, " The last %d:", maxStoppedSteps)
+		calls = calls[n:]
+	}
+	for _, tc := range calls {
+		res, ok := results[tc.ID]
+		if !ok {
+			res = "(not finished)"
+		}
+		first, _, _ := strings.Cut(strings.TrimSpace(res), "\n")
+		fmt.Fprintf(&sb, "\n- %s: %s", tc.Name, clipRunes(first, 120))
+	}
+	return sb.String()
+}
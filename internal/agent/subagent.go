@@ -0,0 +1,272 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/agent/tools"
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+// Limits of subagents.
+const (
+	defaultSubagentIterations = 20
+	subagentTimeout           = 30 * time.Minute
+	maxSubagentsPerChat       = 3  // running at once
+	keepFinishedSubagents     = 10 // per chat, for subagent_status
+)
+
+// subagentDeniedTools are never given to subagents: they cannot start
+// subagents of their own, stop to ask the user questions or report progress
+// to the chat.
+var subagentDeniedTools = []string{"spawn", "subagent_status", "confirm", "report_progress", "update_plan"}
+
+const subagentInstruction = `You are a subagent working in the background on one task for the user of this chat. Nobody reads your messages while you work: use your tools to do the task, then reply with your report. The report is all the user gets, so make it complete and self-contained, but concise.`
+
+// subagentManager runs the background agents started with the spawn tool.
+// Each subagent gets a fresh tool registry for the chat's workspace, limited
+// to the tools it was given and the role of the user who spawned it, and
+// reports back by sending its result into the chat through the hub, where
+// the agent relays it like a fired cron reminder.
+type subagentManager struct {
+	a      *AgentLoop
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu   sync.Mutex
+	next int
+	runs []*subagentRun
+}
+
+type subagentRun struct {
+	channel, chatID string
+	info            tools.SubagentInfo // guarded by the manager's mu
+	stopped         bool
+	cancel          context.CancelFunc
+}
+
+func newSubagentManager(a *AgentLoop) *subagentManager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &subagentManager{a: a, ctx: ctx, cancel: cancel}
+}
+
+// Spawn implements tools.Subagents for the message being processed in ctx.
+func (m *subagentManager) Spawn(ctx context.Context, req tools.SpawnRequest) (tools.SubagentInfo, error) {
+	parent, _ := ctx.Value(inboundKey{}).(*chat.Inbound)
+	if parent == nil {
+		return tools.SubagentInfo{}, errors.New("spawn: subagents can only be started from a chat")
+	}
+	if m.ctx.Err() != nil {
+		return tools.SubagentInfo{}, errors.New("spawn: the agent is shutting down")
+	}
+	t, err := m.a.tenantFor(parent.Channel, parent.ChatID)
+	if err != nil {
+		return tools.SubagentInfo{}, fmt.Errorf("spawn: %w", err)
+	}
+	child, err := m.a.newTenant(t.workspace)
+	if err != nil {
+		return tools.SubagentInfo{}, fmt.Errorf("spawn: %w", err)
+	}
+	if err := restrictSubagentTools(child.tools, req.Tools); err != nil {
+		child.close()
+		return tools.SubagentInfo{}, err
+	}
+	budget := req.MaxIterations
+	if budget <= 0 {
+		budget = defaultSubagentIterations
+	}
+	if m.a.maxIterations > 0 {
+		budget = min(budget, m.a.maxIterations)
+	}
+
+	m.mu.Lock()
+	running := 0
+	for _, r := range m.runs {
+		if r.channel == parent.Channel && r.chatID == parent.ChatID && r.info.Status == "running" {
+			running++
+		}
+	}
+	if running >= maxSubagentsPerChat {
+		m.mu.Unlock()
+		child.close()
+		return tools.SubagentInfo{}, fmt.Errorf("spawn: %d subagents are already running in this chat; wait for one to finish or stop one", running)
+	}
+	m.next++
+	runCtx, cancel := context.WithTimeout(m.ctx, subagentTimeout)
+	r := &subagentRun{
+		channel: parent.Channel,
+		chatID:  parent.ChatID,
+		cancel:  cancel,
+		info: tools.SubagentInfo{
+			ID:      fmt.Sprintf("sa%d", m.next),
+			Name:    req.Name,
+			Task:    req.Task,
+			Status:  "running",
+			Started: time.Now(),
+		},
+	}
+	m.runs = append(m.runs, r)
+	info := r.info
+	m.mu.Unlock()
+
+	agentLog.Info("subagent started", "subagent", info.ID, "channel", parent.Channel, "chat_id", parent.ChatID, "steps", budget)
+	msg := *parent
+	msg.Media, msg.Button = nil, nil
+	go m.run(runCtx, r, child, msg, budget)
+	return info, nil
+}
+
+// restrictSubagentTools disables the tools of reg a subagent may not use:
+// subagentDeniedTools and, when allowed is not empty, every tool not in it.
+func restrictSubagentTools(reg *tools.Registry, allowed []string) error {
+	for _, name := range allowed {
+		if reg.Get(name) == nil || slices.Contains(subagentDeniedTools, name) {
+			return fmt.Errorf("spawn: tool %q is not available to subagents", name)
+		}
+	}
+	for _, name := range reg.Names() {
+		if slices.Contains(subagentDeniedTools, name) || (len(allowed) > 0 && !slices.Contains(allowed, name)) {
+			_ = reg.SetEnabled(name, false)
+		}
+	}
+	return nil
+}
+
+// run works on the subagent's task, then records and reports the outcome.
+func (m *subagentManager) run(ctx context.Context, r *subagentRun, child *tenant, msg chat.Inbound, budget int) {
+	defer r.cancel()
+	defer child.close()
+	result, err := m.work(ctx, r, child, &msg, budget)
+
+	m.mu.Lock()
+	r.info.Finished = time.Now()
+	switch {
+	case r.stopped:
+		r.info.Status = "stopped"
+	case err != nil:
+		r.info.Status = "failed"
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s", subagentTimeout)
+		}
+		r.info.Result = "Error: " + err.Error()
+	default:
+		r.info.Status = "done"
+		r.info.Result = result
+	}
+	info := r.info
+	m.prune(r.channel, r.chatID)
+	m.mu.Unlock()
+
+	agentLog.Info("subagent finished", "subagent", info.ID, "status", info.Status, "steps", info.Iterations)
+	if info.Status == "stopped" || m.ctx.Err() != nil {
+		return
+	}
+	// the report goes back as the result of a subagent_status call, not as
+	// a message of the user
+	report := msg
+	report.Timestamp, report.Media, report.Button = time.Now(), nil, nil
+	report.Internal = &chat.InternalResult{CallID: "report_" + info.ID, Tool: "subagent_status", Args: map[string]interface{}{"id": info.ID}}
+	if info.Status == "done" {
+		report.Content = fmt.Sprintf("[Subagent %s finished] Task: %s\n\nReport:\n%s\n\nPlease relay this to the user.", info.ID, info.Task, info.Result)
+	} else {
+		report.Content = fmt.Sprintf("[Subagent %s failed] Task: %s\n\n%s\n\nPlease let the user know.", info.ID, info.Task, info.Result)
+	}
+	select {
+	case m.a.hub.In <- report:
+	case <-m.ctx.Done():
+	}
+}
+
+// work runs the subagent's tool loop and returns its final reply.
+func (m *subagentManager) work(ctx context.Context, r *subagentRun, child *tenant, msg *chat.Inbound, budget int) (string, error) {
+	a := m.a
+	toolDefs := child.tools.DefinitionsFor(a.roleFor(msg), a.toolScopes(msg)...)
+	memCtx, _ := child.memory.GetMemoryContext()
+	prompt := subagentInstruction + "\n\nTask: " + r.info.Task
+	messages := child.context.BuildMessagesWithin(a.promptBudget(a.ModelFor(TaskSubagent), toolDefs), a.toolDocs(toolDefs), nil, prompt, msg.Channel, msg.ChatID, memCtx, nil)
+
+	for i := 1; i <= budget; i++ {
+		resp, err := a.chat(ctx, TaskSubagent, messages, toolDefs, nil)
+		m.mu.Lock()
+		r.info.Iterations = i
+		m.mu.Unlock()
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			return "", err
+		}
+		if !resp.HasToolCalls {
+			if resp.Content == "" {
+				return "(the subagent finished without a report)", nil
+			}
+			return resp.Content, nil
+		}
+		messages = append(messages, providers.Message{Role: "assistant", Content: resp.Content, ToolCalls: resp.ToolCalls, Thinking: resp.Thinking})
+		for j, res := range a.runTools(ctx, child, msg, resp.ToolCalls) {
+			messages = append(messages, providers.Message{Role: "tool", Content: res, ToolCallID: resp.ToolCalls[j].ID})
+		}
+		m.mu.Lock()
+		r.info.LastTool = resp.ToolCalls[len(resp.ToolCalls)-1].Name
+		m.mu.Unlock()
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+	}
+	return "", fmt.Errorf("used all %d steps without finishing", budget)
+}
+
+// prune forgets the oldest finished subagents of a chat beyond
+// keepFinishedSubagents. m.mu must be held.
+func (m *subagentManager) prune(channel, chatID string) {
+	finished := 0
+	for i := len(m.runs) - 1; i >= 0; i-- {
+		r := m.runs[i]
+		if r.channel != channel || r.chatID != chatID || r.info.Status == "running" {
+			continue
+		}
+		if finished++; finished > keepFinishedSubagents {
+			m.runs = slices.Delete(m.runs, i, i+1)
+		}
+	}
+}
+
+// List implements tools.Subagents.
+func (m *subagentManager) List(channel, chatID string) []tools.SubagentInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []tools.SubagentInfo
+	for i := len(m.runs) - 1; i >= 0; i-- {
+		if r := m.runs[i]; r.channel == channel && r.chatID == chatID {
+			out = append(out, r.info)
+		}
+	}
+	return out
+}
+
+// Stop implements tools.Subagents.
+func (m *subagentManager) Stop(channel, chatID, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range m.runs {
+		if r.channel == channel && r.chatID == chatID && r.info.ID == id {
+			if r.info.Status != "running" {
+				return fmt.Errorf("subagent_status: subagent %s is not running", id)
+			}
+			r.stopped = true
+			r.cancel()
+			return nil
+		}
+	}
+	return fmt.Errorf("subagent_status: no subagent %q in this chat", id)
+}
+
+// stopAll cancels every subagent, on shutdown.
+func (m *subagentManager) stopAll() {
+	m.cancel()
+}
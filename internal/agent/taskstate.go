@@ -0,0 +1,165 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/config"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+// taskState is a checkpoint of a task the agent is working on, saved to
+// <workspace>/state/tasks/ after every round of tool calls. A restart
+// leaves it behind, and the chat is offered to resume the task or get a
+// summary of what was done.
+type taskState struct {
+	Channel   string              `json:"channel"`
+	ChatID    string              `json:"chatID"`
+	SenderID  string              `json:"senderID"`
+	Request   string              `json:"request"` // the message that started the task
+	Steps     []providers.Message `json:"steps"`   // tool calls and their results so far
+	Iteration int                 `json:"iteration"`
+	Started   time.Time           `json:"started"`
+	Updated   time.Time           `json:"updated"`
+}
+
+// taskStatePath returns the checkpoint file of a chat's task.
+func taskStatePath(workspace, channel, chatID string) string {
+	return filepath.Join(workspace, "state", "tasks", tenantKey(channel, chatID)+".json")
+}
+
+// save writes the checkpoint atomically.
+func (st *taskState) save(workspace string) error {
+	st.Updated = time.Now().UTC()
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	path := taskStatePath(workspace, st.Channel, st.ChatID)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// clear removes the checkpoint once the task is over.
+func (st *taskState) clear(workspace string) {
+	if err := os.Remove(taskStatePath(workspace, st.Channel, st.ChatID)); err != nil && !os.IsNotExist(err) {
+		agentLog.Warn("removing task state failed", "err", err)
+	}
+}
+
+// loadTaskState reads a checkpoint file; a missing file is (nil, nil).
+func loadTaskState(path string) (*taskState, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var st taskState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &st, nil
+}
+
+// announceInterruptedTasks tells each chat whose task a restart interrupted
+// how to resume it, in the workspace of every agent and their tenants.
+func (a *AgentLoop) announceInterruptedTasks() {
+	var files []string
+	for _, ws := range a.workspaces() {
+		own, _ := filepath.Glob(filepath.Join(ws, "state", "tasks", "*.json"))
+		files = append(files, own...)
+	}
+	tenants := config.TenantsDir(a.tenant.workspace)
+	for _, pattern := range []string{"*", filepath.Join("agents", "*", "*")} {
+		tenantFiles, _ := filepath.Glob(filepath.Join(tenants, pattern, "state", "tasks", "*.json"))
+		files = append(files, tenantFiles...)
+	}
+	for _, f := range files {
+		st, err := loadTaskState(f)
+		if err != nil || st == nil {
+			agentLog.Warn("loading interrupted task failed", "err", err)
+			continue
+		}
+		agentLog.Info("task was interrupted", "channel", st.Channel, "chat_id", st.ChatID, "iterations", st.Iteration)
+		a.publish(chat.Outbound{Channel: st.Channel, ChatID: st.ChatID, Content: fmt.Sprintf(
+			"I was restarted while working on %q (%d steps done). Send /resume to continue, or /summarize to see what was done so far.",
+			clipRunes(st.Request, 200), st.Iteration)})
+	}
+}
+
+// resumeCommand handles /resume and /summarize for msg's chat. For /resume
+// it returns the interrupted task for processMessage to continue; otherwise
+// it has answered the message itself.
+func (a *AgentLoop) resumeCommand(ctx context.Context, t *tenant, msg *chat.Inbound, cmd string) *taskState {
+	st, err := loadTaskState(taskStatePath(t.workspace, msg.Channel, msg.ChatID))
+	if err != nil {
+		agentLog.Warn("loading task state failed", "channel", msg.Channel, "chat_id", msg.ChatID, "err", err)
+	}
+	if st == nil {
+		a.publish(chat.Outbound{Channel: msg.Channel, ChatID: msg.ChatID, Content: "There is no interrupted task to resume."})
+		return nil
+	}
+	if cmd == "/resume" {
+		return st
+	}
+	summary, err := a.summarizeTask(ctx, st)
+	if err != nil {
+		agentLog.Error("summarizing interrupted task failed", "channel", msg.Channel, "chat_id", msg.ChatID, "err", err)
+		a.publish(chat.Outbound{Channel: msg.Channel, ChatID: msg.ChatID, Content: "Sorry, I couldn't summarize the interrupted task; /resume still works."})
+		return nil
+	}
+	st.clear(t.workspace)
+	session := t.sessions.GetOrCreate(msg.Channel, msg.ChatID)
+	session.AddMessage("user", st.Request)
+	session.AddMessage("assistant", summary)
+	t.sessions.Save(session)
+	a.publish(chat.Outbound{Channel: msg.Channel, ChatID: msg.ChatID, Content: summary})
+	return nil
+}
+
+// summarizeTask has the model of TaskSummarize tell what an interrupted
+// task did and what is left.
+func (a *AgentLoop) summarizeTask(ctx context.Context, st *taskState) (string, error) {
+	var sb strings.Builder
+	for _, m := range st.Steps {
+		for _, tc := range m.ToolCalls {
+			args, _ := json.Marshal(tc.Arguments)
+			fmt.Fprintf(&sb, "call %s %s\n", tc.Name, args)
+		}
+		if m.Role == "tool" {
+			fmt.Fprintf(&sb, "result: %s\n", clipRunes(m.Content, 1000))
+		}
+	}
+	messages := []providers.Message{
+		{Role: "system", Content: "You were working on the user's request below when you were interrupted. From the tool calls and results, tell the user briefly what was done and what is left. Don't continue the task."},
+		{Role: "user", Content: "Request: " + st.Request + "\n\nSteps:\n" + sb.String()},
+	}
+	resp, err := taskProvider{a, TaskSummarize}.Chat(ctx, messages, nil, "")
+	if err != nil {
+		return "", err
+	}
+	return resp.Content, nil
+}
+
+// clipRunes shortens s to at most n runes.
+func clipRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
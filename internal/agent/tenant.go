@@ -0,0 +1,228 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/kr0nicas/picobot/internal/agent/memory"
+	"github.com/kr0nicas/picobot/internal/agent/tools"
+	"github.com/kr0nicas/picobot/internal/config"
+	"github.com/kr0nicas/picobot/internal/session"
+)
+
+// tenant bundles the state the loop works against for one workspace: tools
+// (sandboxed to the workspace), sessions, memory and the context builder.
+type tenant struct {
+	workspace string
+	root      *os.Root
+	tools     *tools.Registry
+	sessions  *session.SessionManager
+	context   *ContextBuilder
+	memory    *memory.MemoryStore
+}
+
+// sharedBootstrapFiles are copied from the shared workspace into each new
+// tenant workspace so operator customizations apply to every user.
+// USER.md is deliberately excluded: it describes a specific person.
+var sharedBootstrapFiles = []string{"SOUL.md", "AGENTS.md", "TOOLS.md"}
+
+var unsafeTenantChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// newTenant builds the tool registry, session manager, memory store and
+// context builder for the given workspace.
+func (a *AgentLoop) newTenant(workspace string) (*tenant, error) {
+	reg := tools.NewRegistry()
+
+	// Open an os.Root anchored at the workspace for kernel-enforced sandboxing.
+	root, err := os.OpenRoot(workspace)
+	if err != nil {
+		return nil, fmt.Errorf("open workspace root: %w", err)
+	}
+
+	// register default tools
+	reg.Register(tools.NewMessageToolWithWorkspace(a.hub, root))
+
+	fsTool, err := tools.NewFilesystemTool(workspace)
+	if err != nil {
+		return nil, fmt.Errorf("create filesystem tool: %w", err)
+	}
+	reg.Register(fsTool)
+
+	execTool := tools.NewExecToolWithWorkspace(60, workspace)
+	reg.Register(execTool)
+	reg.Register(tools.NewJobStatusTool(execTool.Jobs()))
+	reg.Register(tools.NewJobLogsTool(execTool.Jobs()))
+	reg.Register(tools.NewJobKillTool(execTool.Jobs()))
+	reg.Register(tools.NewWebTool())
+	reg.Register(tools.NewSearchTool())
+	reg.Register(tools.NewFeedsTool(workspace))
+	reg.Register(tools.NewHTTPRequestTool())
+	reg.Register(tools.NewSpawnTool(a.subagents))
+	reg.Register(tools.NewSubagentStatusTool(a.subagents))
+	reg.Register(tools.NewConfirmTool(a.AskUser))
+	reg.Register(tools.NewReportProgressTool(a.reportProgress))
+	reg.Register(tools.NewUpdatePlanTool(a.updatePlan))
+	reg.Register(tools.NewScratchpadTool())
+	if a.scheduler != nil {
+		reg.Register(tools.NewCronTool(a.scheduler))
+		reg.Register(tools.NewRemindTool(a.scheduler))
+	}
+
+	sm := session.NewSessionManager(workspace)
+	ranker, topK := a.newRanker()
+	ctx := NewContextBuilder(workspace, ranker, topK)
+	a.settingsMu.RLock()
+	ctx.SetProfiles(a.promptProfile, a.channelPrompts)
+	a.settingsMu.RUnlock()
+	mem, err := memory.NewMemoryStoreWithWorkspace(workspace, 100)
+	if err != nil {
+		return nil, fmt.Errorf("open memory store: %w", err)
+	}
+	// register memory tool (needs store instance)
+	reg.Register(tools.NewWriteMemoryTool(mem))
+	reg.Register(tools.NewSearchMemoryTool(mem))
+	reg.Register(tools.NewForgetTool(mem))
+	reg.Register(tools.NewExportMemoryTool(mem, root))
+	reg.Register(tools.NewImportMemoryTool(mem, root))
+
+	// register skill management tools (share the same os.Root)
+	skillMgr := tools.NewSkillManager(root)
+	skillMgr.SetOnChange(func(change string) {
+		ctx.skillsLoader.Invalidate()
+		if s := a.SkillSync(); s != nil && s.Dir() == filepath.Join(workspace, "skills") {
+			go func() {
+				if err := s.Commit("picobot: " + change); err != nil {
+					agentLog.Error("syncing skills failed", "change", change, "err", err)
+				}
+			}()
+		}
+	})
+	reg.Register(tools.NewCreateSkillTool(skillMgr))
+	reg.Register(tools.NewListSkillsTool(skillMgr))
+	reg.Register(tools.NewReadSkillTool(skillMgr))
+	reg.Register(tools.NewDeleteSkillTool(skillMgr))
+	reg.Register(tools.NewExportSkillTool(skillMgr))
+	reg.Register(tools.NewImportSkillTool(skillMgr))
+
+	for _, name := range a.DisabledTools() {
+		_ = reg.SetEnabled(name, false)
+	}
+	a.settingsMu.RLock()
+	for _, fn := range a.toolSetup {
+		fn(reg)
+	}
+	a.settingsMu.RUnlock()
+
+	return &tenant{workspace: workspace, root: root, tools: reg, sessions: sm, context: ctx, memory: mem}, nil
+}
+
+// close stops the tenant's background jobs and releases its workspace
+// handles, for tenants that are thrown away such as a subagent's.
+func (t *tenant) close() {
+	if et, ok := t.tools.Get("exec").(*tools.ExecTool); ok {
+		et.Jobs().KillAll()
+	}
+	if ft, ok := t.tools.Get("filesystem").(*tools.FilesystemTool); ok {
+		ft.Close()
+	}
+	t.memory.Close()
+	t.root.Close()
+}
+
+// ConfigureTools registers fn to adjust tool settings. It runs immediately on
+// every existing tenant's registry and on each tenant created later.
+func (a *AgentLoop) ConfigureTools(fn func(*tools.Registry)) {
+	a.settingsMu.Lock()
+	a.toolSetup = append(a.toolSetup, fn)
+	a.settingsMu.Unlock()
+
+	fn(a.tenant.tools)
+	a.tenantsMu.Lock()
+	defer a.tenantsMu.Unlock()
+	for _, t := range a.tenants {
+		fn(t.tools)
+	}
+}
+
+// SetMultiTenant enables or disables multi-tenant mode. When enabled, every
+// user-facing chat gets its own workspace under <workspace>-tenants/ with
+// separate files, memory, skills and session history. In private chats the
+// chat ID is the user's ID, so each allowlisted user is isolated; a group chat
+// shares one tenant among its members. System channels (heartbeat, cli) keep
+// using the shared workspace.
+func (a *AgentLoop) SetMultiTenant(enabled bool) {
+	a.tenantsMu.Lock()
+	defer a.tenantsMu.Unlock()
+	a.multiTenant = enabled
+}
+
+// tenantFor returns the tenant that serves the given channel and chat,
+// creating and initializing its workspace on first use. Chats of a named
+// agent are served from its workspace.
+func (a *AgentLoop) tenantFor(channel, chatID string) (*tenant, error) {
+	a.tenantsMu.Lock()
+	defer a.tenantsMu.Unlock()
+	base, key := a.tenant, tenantKey(channel, chatID)
+	mapKey := key
+	dir := filepath.Join(config.TenantsDir(a.tenant.workspace), key)
+	if ag := a.agentForLocked(channel, chatID); ag != nil {
+		base, mapKey = ag.tenant, "agent:"+ag.Name+":"+key
+		dir = filepath.Join(config.TenantsDir(a.tenant.workspace), "agents", ag.Name, key)
+	}
+	if !a.multiTenant || channel == "heartbeat" || channel == "cli" || chatID == "" {
+		return base, nil
+	}
+	if t, ok := a.tenants[mapKey]; ok {
+		return t, nil
+	}
+	if err := initTenantWorkspace(base.workspace, dir); err != nil {
+		return nil, err
+	}
+	t, err := a.newTenant(dir)
+	if err != nil {
+		return nil, err
+	}
+	agentLog.Info("initialized tenant workspace", "channel", channel, "chat_id", chatID, "dir", dir)
+	a.tenants[mapKey] = t
+	return t, nil
+}
+
+// tenantKey derives a filesystem-safe directory name from a channel identity.
+// Identities that need characters replaced get a hash of the original
+// appended after a ".", which no unchanged identity contains, so
+// "a.b@x.com" and "a_b@x.com" don't share a workspace.
+func tenantKey(channel, chatID string) string {
+	raw := channel + "_" + chatID
+	key := unsafeTenantChars.ReplaceAllString(channel, "_") + "_" + unsafeTenantChars.ReplaceAllString(chatID, "_")
+	if key != raw {
+		sum := sha256.Sum256([]byte(channel + "\x00" + chatID))
+		key += "." + hex.EncodeToString(sum[:8])
+	}
+	return key
+}
+
+// initTenantWorkspace creates a fresh workspace at dir (bootstrap files, memory,
+// embedded skills) and overlays the shared workspace's bootstrap files.
+// Existing tenant workspaces are left untouched.
+func initTenantWorkspace(shared, dir string) error {
+	if _, err := os.Stat(dir); err == nil {
+		return nil
+	}
+	if err := config.InitializeWorkspace(dir); err != nil {
+		return fmt.Errorf("initialize tenant workspace: %w", err)
+	}
+	for _, name := range sharedBootstrapFiles {
+		data, err := os.ReadFile(filepath.Join(shared, name))
+		if err != nil {
+			continue // not customized in the shared workspace, keep the default
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			return fmt.Errorf("copy %s to tenant workspace: %w", name, err)
+		}
+	}
+	return nil
+}
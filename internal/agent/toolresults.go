@@ -0,0 +1,133 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/agent/tools"
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+// defaultToolResultChars is the size above which tool results are cut down
+// by default.
+const defaultToolResultChars = 16000
+
+// toolResultsDir holds the full output of cut-down tool results, relative to
+// the workspace; files older than toolResultsKeep are removed.
+const (
+	toolResultsDir  = "artifacts/tool-results"
+	toolResultsKeep = 7 * 24 * time.Hour
+)
+
+// maxSummarizedChars bounds the output handed to the summarizer.
+const maxSummarizedChars = 200000
+
+// SetToolResultLimit sets the size in characters above which a tool result
+// is cut down before it goes into the conversation (0 = default 16000,
+// negative = never). The full output is saved under artifacts/tool-results/
+// in the workspace, where the model can read it in parts. With summarize,
+// the model of TaskSummarize condenses the result instead of it being cut
+// to its beginning and end; output of untrusted tools is always cut.
+func (a *AgentLoop) SetToolResultLimit(maxChars int, summarize bool) {
+	a.settingsMu.Lock()
+	defer a.settingsMu.Unlock()
+	a.toolResultChars = maxChars
+	a.summarizeResults = summarize
+}
+
+var unsafeNameRE = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// compactResult returns res, the output of tc, cut down or summarized if it
+// is over the limit, with the path of the saved full output.
+func (a *AgentLoop) compactResult(ctx context.Context, t *tenant, tc providers.ToolCall, res string) string {
+	a.settingsMu.RLock()
+	limit, summarize := a.toolResultChars, a.summarizeResults
+	a.settingsMu.RUnlock()
+	if limit == 0 {
+		limit = defaultToolResultChars
+	}
+	size := len([]rune(res))
+	if limit < 0 || size <= limit {
+		return res
+	}
+
+	// a whole saved result read back is cut again, not saved again
+	path, _ := tc.Arguments["path"].(string)
+	if tc.Name != "filesystem" || !strings.HasPrefix(filepath.ToSlash(filepath.Clean(path)), toolResultsDir+"/") {
+		path = saveToolResult(t.workspace, tc, a.redactor.Redact(res))
+	}
+	if u, ok := t.tools.Get(tc.Name).(tools.UntrustedOutput); ok && u.UntrustedOutput() {
+		summarize = false
+	}
+
+	short := ""
+	if summarize {
+		s, err := a.summarizeResult(ctx, tc.Name, res)
+		if err != nil {
+			agentLog.Warn("summarizing tool output failed", "tool", tc.Name, "err", err)
+		}
+		short = s
+	}
+	if short == "" {
+		short = cutMiddle(res, limit)
+	}
+	if path == "" {
+		return short + fmt.Sprintf("\n[The output was %d characters long and was shortened.]", size)
+	}
+	return short + fmt.Sprintf("\n[The output was %d characters long and was shortened. The full output is in %s; read the parts you need with the filesystem tool's read action and start_line/end_line.]", size, path)
+}
+
+// saveToolResult writes the full output of tc under toolResultsDir and
+// returns its workspace-relative path, or "" if it could not be saved. Old
+// results are removed first.
+func saveToolResult(workspace string, tc providers.ToolCall, res string) string {
+	dir := filepath.Join(workspace, filepath.FromSlash(toolResultsDir))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		agentLog.Error("creating tool results dir failed", "err", err)
+		return ""
+	}
+	if entries, err := os.ReadDir(dir); err == nil {
+		for _, e := range entries {
+			if info, err := e.Info(); err == nil && time.Since(info.ModTime()) > toolResultsKeep {
+				os.Remove(filepath.Join(dir, e.Name()))
+			}
+		}
+	}
+	name := fmt.Sprintf("%s-%s-%s.txt", time.Now().UTC().Format("20060102T150405.000"), tc.Name, unsafeNameRE.ReplaceAllString(tc.ID, "_"))
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(res), 0o600); err != nil {
+		agentLog.Error("saving tool result failed", "err", err)
+		return ""
+	}
+	return toolResultsDir + "/" + name
+}
+
+// summarizeResult has the model of TaskSummarize condense the output of a
+// tool.
+func (a *AgentLoop) summarizeResult(ctx context.Context, toolName, res string) (string, error) {
+	messages := []providers.Message{
+		{Role: "system", Content: "You condense tool output for an assistant that is working on a task. Keep the facts, figures, names, paths, URLs and error messages it is likely to need, in the order they appear; drop repetition and boilerplate. Reply with the condensed output only."},
+		{Role: "user", Content: fmt.Sprintf("Output of %s:\n%s", toolName, clipRunes(res, maxSummarizedChars))},
+	}
+	resp, err := taskProvider{a, TaskSummarize}.Chat(ctx, messages, nil, "")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(resp.Content), nil
+}
+
+// cutMiddle shortens s to about n runes, keeping its beginning and, as logs
+// and build output end with what matters most, a third of it from its end.
+func cutMiddle(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	tail := n / 3
+	head := n - tail
+	return string(r[:head]) + fmt.Sprintf("\n[... %d characters left out ...]\n", len(r)-n) + string(r[len(r)-tail:])
+}
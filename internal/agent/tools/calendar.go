@@ -0,0 +1,245 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/calendar"
+)
+
+const defaultCalendarDays = 7
+
+// CalendarTool lists, creates and deletes events of the user's calendar.
+// Args: {"action": "create_event", "title": "Dentist", "start": "2026-03-02T09:30", "end": "2026-03-02T10:00"}
+type CalendarTool struct {
+	backend calendar.Backend
+	loc     *time.Location
+	now     func() time.Time
+}
+
+// NewCalendarTool returns a calendar tool for b. Times given without a zone
+// are in loc (time.Local if nil).
+func NewCalendarTool(b calendar.Backend, loc *time.Location) *CalendarTool {
+	if loc == nil {
+		loc = time.Local
+	}
+	return &CalendarTool{backend: b, loc: loc, now: time.Now}
+}
+
+// UntrustedOutput marks events, which may come from invitations, as
+// untrusted data.
+func (t *CalendarTool) UntrustedOutput() bool { return true }
+
+func (t *CalendarTool) Name() string { return "calendar" }
+func (t *CalendarTool) Description() string {
+	return "Read and change the user's calendar. Actions: list_events (from/to, default the next 7 days), create_event, delete_event (by the id shown by list_events)."
+}
+
+func (t *CalendarTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"description": "list_events, create_event or delete_event",
+				"enum":        []string{"list_events", "create_event", "delete_event"},
+			},
+			"from": map[string]interface{}{
+				"type":        "string",
+				"description": "list_events: start of the range (default now)",
+			},
+			"to": map[string]interface{}{
+				"type":        "string",
+				"description": fmt.Sprintf("list_events: end of the range (default %d days after from)", defaultCalendarDays),
+			},
+			"title": map[string]interface{}{
+				"type":        "string",
+				"description": "create_event: the event title",
+			},
+			"start": map[string]interface{}{
+				"type":        "string",
+				"description": "create_event: start, e.g. 2026-03-02T09:30 (local time), an RFC 3339 time, or a date for all-day events",
+			},
+			"end": map[string]interface{}{
+				"type":        "string",
+				"description": "create_event: end (default one hour after start, or the same day for all-day events); for all-day events the last day",
+			},
+			"all_day": map[string]interface{}{
+				"type":        "boolean",
+				"description": "create_event: an all-day event (implied when start is a date)",
+			},
+			"location": map[string]interface{}{
+				"type":        "string",
+				"description": "create_event: where the event takes place",
+			},
+			"description": map[string]interface{}{
+				"type":        "string",
+				"description": "create_event: notes",
+			},
+			"id": map[string]interface{}{
+				"type":        "string",
+				"description": "delete_event: the event id",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+// RequiresApproval asks before deleting an event.
+func (t *CalendarTool) RequiresApproval(args map[string]interface{}) string {
+	if action, _ := args["action"].(string); action == "delete_event" {
+		id, _ := args["id"].(string)
+		return fmt.Sprintf("delete calendar event %s", id)
+	}
+	return ""
+}
+
+func (t *CalendarTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	action, _ := args["action"].(string)
+	str := func(k string) string {
+		s, _ := args[k].(string)
+		return strings.TrimSpace(s)
+	}
+	switch action {
+	case "list_events":
+		from := t.now().In(t.loc)
+		if s := str("from"); s != "" {
+			var err error
+			if from, _, err = t.parseTime(s); err != nil {
+				return "", err
+			}
+		}
+		to := from.AddDate(0, 0, defaultCalendarDays)
+		if s := str("to"); s != "" {
+			var dateOnly bool
+			var err error
+			if to, dateOnly, err = t.parseTime(s); err != nil {
+				return "", err
+			}
+			if dateOnly {
+				to = to.AddDate(0, 0, 1) // include the whole day
+			}
+		}
+		if !to.After(from) {
+			return "", fmt.Errorf("calendar: 'to' must be after 'from'")
+		}
+		events, err := t.backend.List(ctx, from, to)
+		if err != nil {
+			return "", err
+		}
+		if len(events) == 0 {
+			return fmt.Sprintf("No events between %s and %s.", from.Format("2006-01-02 15:04"), to.Format("2006-01-02 15:04")), nil
+		}
+		var sb strings.Builder
+		for _, ev := range events {
+			sb.WriteString(t.formatEvent(ev) + "\n")
+		}
+		return strings.TrimRight(sb.String(), "\n"), nil
+
+	case "create_event":
+		title := str("title")
+		if title == "" {
+			return "", fmt.Errorf("calendar: create_event needs 'title'")
+		}
+		if str("start") == "" {
+			return "", fmt.Errorf("calendar: create_event needs 'start'")
+		}
+		start, allDay, err := t.parseTime(str("start"))
+		if err != nil {
+			return "", err
+		}
+		if v, ok := args["all_day"].(bool); ok && v {
+			allDay = true
+			start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, t.loc)
+		}
+		var end time.Time
+		if s := str("end"); s != "" {
+			if end, _, err = t.parseTime(s); err != nil {
+				return "", err
+			}
+			if allDay {
+				// the end of all-day events is exclusive
+				end = time.Date(end.Year(), end.Month(), end.Day()+1, 0, 0, 0, 0, t.loc)
+			}
+		} else if allDay {
+			end = start.AddDate(0, 0, 1)
+		} else {
+			end = start.Add(time.Hour)
+		}
+		if !end.After(start) {
+			return "", fmt.Errorf("calendar: the event must end after it starts")
+		}
+		ev, err := t.backend.Create(ctx, calendar.Event{
+			Title:       title,
+			Start:       start,
+			End:         end,
+			AllDay:      allDay,
+			Location:    str("location"),
+			Description: str("description"),
+		})
+		if err != nil {
+			return "", err
+		}
+		return "Created " + t.formatEvent(ev), nil
+
+	case "delete_event":
+		id := str("id")
+		if id == "" {
+			return "", fmt.Errorf("calendar: delete_event needs 'id'")
+		}
+		if err := t.backend.Delete(ctx, id); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Deleted event %s.", id), nil
+	}
+	return "", fmt.Errorf("calendar: unknown action %q", action)
+}
+
+// calendarLayouts are the accepted forms of local times.
+var calendarLayouts = []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04"}
+
+// parseTime parses an RFC 3339 time, a local time or a date; dateOnly
+// reports a date.
+func (t *CalendarTool) parseTime(s string) (tm time.Time, dateOnly bool, err error) {
+	if tm, err := time.Parse(time.RFC3339, s); err == nil {
+		return tm, false, nil
+	}
+	for _, layout := range calendarLayouts {
+		if tm, err := time.ParseInLocation(layout, s, t.loc); err == nil {
+			return tm, false, nil
+		}
+	}
+	if tm, err := time.ParseInLocation("2006-01-02", s, t.loc); err == nil {
+		return tm, true, nil
+	}
+	return time.Time{}, false, fmt.Errorf("calendar: invalid time %q (use 2006-01-02T15:04, RFC 3339 or a date)", s)
+}
+
+// formatEvent renders ev on one line, in the tool's time zone.
+func (t *CalendarTool) formatEvent(ev calendar.Event) string {
+	var when string
+	start, end := ev.Start.In(t.loc), ev.End.In(t.loc)
+	switch {
+	case ev.AllDay:
+		when = ev.Start.Format("2006-01-02")
+		if last := ev.End.AddDate(0, 0, -1); last.After(ev.Start) {
+			when += " to " + last.Format("2006-01-02")
+		}
+		when += " (all day)"
+	case start.YearDay() == end.YearDay() && start.Year() == end.Year():
+		when = start.Format("2006-01-02 15:04") + "-" + end.Format("15:04")
+	default:
+		when = start.Format("2006-01-02 15:04") + " to " + end.Format("2006-01-02 15:04")
+	}
+	s := fmt.Sprintf("- %s: %s", when, ev.Title)
+	if ev.Location != "" {
+		s += " @ " + ev.Location
+	}
+	s += fmt.Sprintf(" [id: %s]", ev.ID)
+	if ev.Description != "" {
+		s += "\n  " + strings.ReplaceAll(ev.Description, "\n", "\n  ")
+	}
+	return s
+}
@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/calendar"
+)
+
+type fakeCalendar struct {
+	events []calendar.Event
+	from   time.Time
+	to     time.Time
+}
+
+func (f *fakeCalendar) List(_ context.Context, from, to time.Time) ([]calendar.Event, error) {
+	f.from, f.to = from, to
+	return f.events, nil
+}
+
+func (f *fakeCalendar) Create(_ context.Context, ev calendar.Event) (calendar.Event, error) {
+	ev.ID = "new"
+	f.events = append(f.events, ev)
+	return ev, nil
+}
+
+func (f *fakeCalendar) Delete(context.Context, string) error { return nil }
+
+func TestCalendarTool(t *testing.T) {
+	fc := &fakeCalendar{}
+	tool := NewCalendarTool(fc, time.UTC)
+	tool.now = func() time.Time { return time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC) }
+	ctx := context.Background()
+
+	out, err := tool.Execute(ctx, map[string]interface{}{"action": "create_event", "title": "Dentist", "start": "2026-03-02T09:30"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := fc.events[0]
+	if !ev.Start.Equal(time.Date(2026, 3, 2, 9, 30, 0, 0, time.UTC)) || ev.End.Sub(ev.Start) != time.Hour || ev.AllDay {
+		t.Fatalf("created %+v", ev)
+	}
+	if !strings.Contains(out, "2026-03-02 09:30-10:30: Dentist [id: new]") {
+		t.Errorf("output %q", out)
+	}
+
+	if _, err := tool.Execute(ctx, map[string]interface{}{"action": "create_event", "title": "Trip", "start": "2026-03-05", "end": "2026-03-06"}); err != nil {
+		t.Fatal(err)
+	}
+	if ev := fc.events[1]; !ev.AllDay || !ev.End.Equal(time.Date(2026, 3, 7, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("all-day event %+v", ev)
+	}
+
+	out, err = tool.Execute(ctx, map[string]interface{}{"action": "list_events", "to": "2026-03-05"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fc.from.Equal(tool.now()) || !fc.to.Equal(time.Date(2026, 3, 6, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("listed %v to %v", fc.from, fc.to)
+	}
+	if !strings.Contains(out, "2026-03-05 to 2026-03-06 (all day): Trip") {
+		t.Errorf("list output %q", out)
+	}
+
+	if _, err := tool.Execute(ctx, map[string]interface{}{"action": "create_event", "title": "x", "start": "tomorrow"}); err == nil {
+		t.Error("expected an error for an invalid time")
+	}
+	if tool.RequiresApproval(map[string]interface{}{"action": "delete_event", "id": "new"}) == "" {
+		t.Error("delete_event should require approval")
+	}
+}
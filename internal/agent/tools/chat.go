@@ -0,0 +1,20 @@
+package tools
+
+import "context"
+
+type chatKey struct{}
+
+type chatRef struct{ channel, chatID string }
+
+// WithChat returns a copy of ctx naming the channel and chat a tool call is
+// made for, so one registry can serve several chats at once.
+func WithChat(ctx context.Context, channel, chatID string) context.Context {
+	return context.WithValue(ctx, chatKey{}, chatRef{channel, chatID})
+}
+
+// chatOf returns the channel and chat of ctx, or empty strings if ctx names
+// none.
+func chatOf(ctx context.Context) (string, string) {
+	c, _ := ctx.Value(chatKey{}).(chatRef)
+	return c.channel, c.chatID
+}
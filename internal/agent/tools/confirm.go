@@ -0,0 +1,70 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// AskFunc shows question to the user of the current chat with one button per
+// option and returns the chosen option.
+type AskFunc func(ctx context.Context, question string, options []string) (string, error)
+
+// ConfirmTool lets the model ask the user to pick an answer before it
+// continues, e.g. to confirm a plan. On channels with buttons (Telegram) the
+// options are shown as an inline keyboard; elsewhere the user types one.
+// Args: {"question": "...", "options": ["Yes", "No"]}
+type ConfirmTool struct {
+	ask AskFunc
+}
+
+func NewConfirmTool(ask AskFunc) *ConfirmTool { return &ConfirmTool{ask: ask} }
+
+func (t *ConfirmTool) Name() string { return "confirm" }
+func (t *ConfirmTool) Description() string {
+	return "Ask the user a question with a few fixed answers (buttons where supported) and wait for their choice"
+}
+
+func (t *ConfirmTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"question": map[string]interface{}{
+				"type":        "string",
+				"description": "The question to ask",
+			},
+			"options": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Up to 6 short answers to offer (default: Yes, No)",
+			},
+		},
+		"required": []string{"question"},
+	}
+}
+
+func (t *ConfirmTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	question, _ := args["question"].(string)
+	if strings.TrimSpace(question) == "" {
+		return "", fmt.Errorf("confirm: 'question' argument required")
+	}
+	var options []string
+	if raw, ok := args["options"].([]interface{}); ok {
+		for _, o := range raw {
+			if s, ok := o.(string); ok && strings.TrimSpace(s) != "" {
+				options = append(options, strings.TrimSpace(s))
+			}
+		}
+	}
+	if len(options) == 0 {
+		options = []string{"Yes", "No"}
+	}
+	if len(options) > 6 {
+		return "", fmt.Errorf("confirm: at most 6 options are supported")
+	}
+	answer, err := t.ask(ctx, question, options)
+	if err != nil {
+		return "", fmt.Errorf("confirm: %w", err)
+	}
+	return "The user answered: " + answer, nil
+}
@@ -9,13 +9,10 @@ import (
 	"github.com/kr0nicas/picobot/internal/cron"
 )
 
-// CronTool schedules delayed/recurring tasks via the cron scheduler.
-// It holds a channel/chatID context (set per-incoming-message) so fired jobs
-// know where to send their notification.
+// CronTool schedules delayed/recurring tasks via the cron scheduler. Jobs
+// are sent to the chat of the call that scheduled them when they fire.
 type CronTool struct {
 	scheduler *cron.Scheduler
-	channel   string
-	chatID    string
 }
 
 func NewCronTool(scheduler *cron.Scheduler) *CronTool {
@@ -24,7 +21,7 @@ func NewCronTool(scheduler *cron.Scheduler) *CronTool {
 
 func (t *CronTool) Name() string { return "cron" }
 func (t *CronTool) Description() string {
-	return "Schedule one-time or recurring reminders/tasks. Actions: add (schedule), list (show pending), cancel (remove by name)."
+	return "Schedule one-time or recurring reminders/tasks for this chat; jobs survive restarts. Recurring jobs use a cron schedule (e.g. '0 8 * * mon-fri' = weekdays at 8:00) or a fixed interval. Actions: add, list, remove, pause, resume."
 }
 
 func (t *CronTool) Parameters() map[string]interface{} {
@@ -33,47 +30,53 @@ func (t *CronTool) Parameters() map[string]interface{} {
 		"properties": map[string]interface{}{
 			"action": map[string]interface{}{
 				"type":        "string",
-				"description": "The action: add (schedule a new job), list (show pending jobs), cancel (remove a job by name)",
-				"enum":        []string{"add", "list", "cancel"},
+				"description": "The action: add (schedule a new job), list (show this chat's jobs), remove (delete a job), pause or resume a job",
+				"enum":        []string{"add", "list", "remove", "pause", "resume", "cancel"},
 			},
 			"name": map[string]interface{}{
 				"type":        "string",
-				"description": "A short name for the job (used to identify it for cancellation)",
+				"description": "A short name for the job; remove, pause and resume take the name or the job ID",
 			},
 			"message": map[string]interface{}{
 				"type":        "string",
-				"description": "The reminder message or task description to deliver when the job fires",
+				"description": "The reminder, or the task to carry out, when the job fires",
+			},
+			"schedule": map[string]interface{}{
+				"type":        "string",
+				"description": "Cron expression for recurring jobs: minute hour day-of-month month day-of-week, e.g. '30 7 * * *' (daily 7:30), '0 9 1 * *' (monthly), or @hourly/@daily/@weekly",
+			},
+			"timezone": map[string]interface{}{
+				"type":        "string",
+				"description": "IANA time zone of the schedule, e.g. 'Europe/Madrid' (default: the configured one)",
 			},
 			"delay": map[string]interface{}{
 				"type":        "string",
-				"description": "How long to wait before first firing, e.g. '2m', '1h30m', '30s', '1h'. Uses Go duration format.",
+				"description": "Without a schedule: how long to wait before first firing, e.g. '2m', '1h30m', '30s', '1h'. Uses Go duration format.",
 			},
 			"recurring": map[string]interface{}{
 				"type":        "boolean",
-				"description": "If true, the job will repeat at the specified interval. If false or omitted, fires only once.",
+				"description": "Without a schedule: if true, the job will repeat at the specified interval. If false or omitted, fires only once.",
 			},
 			"interval": map[string]interface{}{
 				"type":        "string",
-				"description": "For recurring jobs: how often to repeat (minimum 2m). Uses Go duration format.",
+				"description": "For recurring jobs without a schedule: how often to repeat (minimum 2m). Uses Go duration format.",
 			},
 		},
 		"required": []string{"action"},
 	}
 }
 
-// SetContext sets the originating channel and chat for scheduled jobs.
-func (t *CronTool) SetContext(channel, chatID string) {
-	t.channel = channel
-	t.chatID = chatID
-}
-
 func (t *CronTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
 	action, _ := args["action"].(string)
+	channel, chatID := chatOf(ctx)
+	role := string(roleOf(ctx))
 
 	switch action {
 	case "add":
 		name, _ := args["name"].(string)
 		message, _ := args["message"].(string)
+		schedule, _ := args["schedule"].(string)
+		tz, _ := args["timezone"].(string)
 		delayStr, _ := args["delay"].(string)
 		recurring, _ := args["recurring"].(bool)
 		intervalStr, _ := args["interval"].(string)
@@ -84,8 +87,15 @@ func (t *CronTool) Execute(ctx context.Context, args map[string]interface{}) (st
 		if message == "" {
 			return "", fmt.Errorf("cron add: 'message' is required")
 		}
+		if schedule != "" {
+			job, err := t.scheduler.AddCron(name, message, schedule, tz, channel, chatID, role)
+			if err != nil {
+				return "", fmt.Errorf("cron add: %v", err)
+			}
+			return fmt.Sprintf("Scheduled job %q (id: %s) on %q. Next run: %s.", name, job.ID, schedule, formatFireAt(job)), nil
+		}
 		if delayStr == "" {
-			return "", fmt.Errorf("cron add: 'delay' is required (e.g. '2m', '1h')")
+			return "", fmt.Errorf("cron add: 'schedule' or 'delay' is required (e.g. '0 9 * * *', '2m', '1h')")
 		}
 
 		delay, err := time.ParseDuration(delayStr)
@@ -109,38 +119,86 @@ func (t *CronTool) Execute(ctx context.Context, args map[string]interface{}) (st
 			if interval < 2*time.Minute {
 				return "", fmt.Errorf("cron add: recurring interval must be at least 2m (got %v)", interval)
 			}
-			id := t.scheduler.AddRecurring(name, message, interval, t.channel, t.chatID)
+			id := t.scheduler.AddRecurring(name, message, interval, channel, chatID, role)
 			return fmt.Sprintf("Scheduled recurring job %q (id: %s). Will fire in %v, then repeat every %v.", name, id, delay, interval), nil
 		}
 
 		// One-time job
-		id := t.scheduler.Add(name, message, delay, t.channel, t.chatID)
+		id := t.scheduler.Add(name, message, delay, channel, chatID, role)
 		return fmt.Sprintf("Scheduled job %q (id: %s). Will fire in %v.", name, id, delay), nil
 
 	case "list":
-		jobs := t.scheduler.List()
+		jobs := t.chatJobs(channel, chatID)
 		if len(jobs) == 0 {
 			return "No pending jobs.", nil
 		}
 		var sb strings.Builder
 		sb.WriteString(fmt.Sprintf("%d pending job(s):\n", len(jobs)))
 		for _, j := range jobs {
-			remaining := time.Until(j.FireAt).Round(time.Second)
-			sb.WriteString(fmt.Sprintf("- %s (%s): %q — fires in %v\n", j.Name, j.ID, j.Message, remaining))
+			when := "fires in " + time.Until(j.FireAt).Round(time.Second).String()
+			switch {
+			case j.Paused:
+				when = "paused"
+			case j.Schedule != "":
+				when = fmt.Sprintf("%q, next %s", j.Schedule, formatFireAt(j))
+			case j.Recurring:
+				when += fmt.Sprintf(", every %v", j.Interval)
+			}
+			sb.WriteString(fmt.Sprintf("- %s (%s): %q — %s\n", j.Name, j.ID, j.Message, when))
 		}
 		return sb.String(), nil
 
-	case "cancel":
+	case "remove", "cancel", "pause", "resume":
 		name, _ := args["name"].(string)
 		if name == "" {
-			return "", fmt.Errorf("cron cancel: 'name' is required")
+			return "", fmt.Errorf("cron %s: 'name' is required", action)
+		}
+		job, ok := t.findJob(channel, chatID, name)
+		if !ok {
+			return fmt.Sprintf("No job found with name %q.", name), nil
 		}
-		if t.scheduler.CancelByName(name) {
-			return fmt.Sprintf("Cancelled job %q.", name), nil
+		switch action {
+		case "pause", "resume":
+			t.scheduler.SetPaused(job.ID, action == "pause")
+			return fmt.Sprintf("Job %q is %sd.", job.Name, action), nil
 		}
-		return fmt.Sprintf("No job found with name %q.", name), nil
+		t.scheduler.Cancel(job.ID)
+		return fmt.Sprintf("Removed job %q.", job.Name), nil
 
 	default:
-		return "", fmt.Errorf("cron: unknown action %q (use add, list, or cancel)", action)
+		return "", fmt.Errorf("cron: unknown action %q (use add, list, remove, pause or resume)", action)
+	}
+}
+
+// chatJobs returns the jobs of a chat, without reminders set with the
+// remind tool.
+func (t *CronTool) chatJobs(channel, chatID string) []cron.Job {
+	var jobs []cron.Job
+	for _, j := range t.scheduler.List() {
+		if !j.Reminder && j.Channel == channel && j.ChatID == chatID {
+			jobs = append(jobs, j)
+		}
+	}
+	return jobs
+}
+
+// findJob looks up a job of a chat by ID or name.
+func (t *CronTool) findJob(channel, chatID, key string) (cron.Job, bool) {
+	for _, j := range t.chatJobs(channel, chatID) {
+		if j.ID == key || j.Name == key {
+			return j, true
+		}
+	}
+	return cron.Job{}, false
+}
+
+// formatFireAt shows when a cron job fires next, in its time zone.
+func formatFireAt(j cron.Job) string {
+	at := j.FireAt
+	if j.Timezone != "" {
+		if loc, err := time.LoadLocation(j.Timezone); err == nil {
+			at = at.In(loc)
+		}
 	}
+	return at.Format("Mon Jan 2 15:04 MST")
 }
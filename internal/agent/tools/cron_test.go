@@ -0,0 +1,44 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kr0nicas/picobot/internal/cron"
+)
+
+func TestCronToolScheduleAndChatScope(t *testing.T) {
+	s := cron.NewScheduler(nil)
+	ct := NewCronTool(s)
+	ctx := WithChat(context.Background(), "telegram", "1")
+
+	args := map[string]interface{}{"action": "add", "name": "digest", "message": "summarize my feeds", "schedule": "0 8 * * *", "timezone": "UTC"}
+	out, err := ct.Execute(ctx, args)
+	if err != nil || !strings.Contains(out, "08:00 UTC") {
+		t.Fatalf("unexpected result %q, %v", out, err)
+	}
+	if _, err := ct.Execute(ctx, map[string]interface{}{"action": "add", "message": "x", "schedule": "every day"}); err == nil {
+		t.Fatal("expected an invalid schedule to be rejected")
+	}
+	if out, _ := ct.Execute(ctx, map[string]interface{}{"action": "pause", "name": "digest"}); out != `Job "digest" is paused.` {
+		t.Fatalf("unexpected pause result %q", out)
+	}
+	if out, _ := ct.Execute(ctx, map[string]interface{}{"action": "list"}); !strings.Contains(out, "digest (job-1)") || !strings.Contains(out, "paused") {
+		t.Fatalf("unexpected list:\n%s", out)
+	}
+
+	// other chats neither see nor change the job
+	ctx = WithChat(context.Background(), "telegram", "2")
+	if out, _ := ct.Execute(ctx, map[string]interface{}{"action": "list"}); out != "No pending jobs." {
+		t.Fatalf("job leaked into another chat:\n%s", out)
+	}
+	if out, _ := ct.Execute(ctx, map[string]interface{}{"action": "remove", "name": "job-1"}); !strings.Contains(out, "No job found") {
+		t.Fatalf("another chat removed the job: %q", out)
+	}
+
+	ctx = WithChat(context.Background(), "telegram", "1")
+	if _, err := ct.Execute(ctx, map[string]interface{}{"action": "remove", "name": "job-1"}); err != nil || len(s.List()) != 0 {
+		t.Fatalf("expected the job to be removed, got %v, %+v", err, s.List())
+	}
+}
@@ -0,0 +1,111 @@
+package tools
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kr0nicas/picobot/internal/providers"
+)
+
+// Documentation renders the tool reference of the system prompt from the
+// definitions offered to the model, so it always matches the registered
+// tools, their descriptions and their parameters.
+func Documentation(defs []providers.ToolDefinition) string {
+	if len(defs) == 0 {
+		return ""
+	}
+	defs = append([]providers.ToolDefinition(nil), defs...)
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+	var sb strings.Builder
+	sb.WriteString("## Tools\n")
+	for _, d := range defs {
+		fmt.Fprintf(&sb, "\n### %s\n", d.Name)
+		if d.Description != "" {
+			sb.WriteString(d.Description + "\n")
+		}
+		for _, line := range paramLines(d.Parameters) {
+			sb.WriteString(line + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// DocumentationFor is Documentation for the tools role may use in scopes.
+func (r *Registry) DocumentationFor(role Role, scopes ...string) string {
+	return Documentation(r.DefinitionsFor(role, scopes...))
+}
+
+// paramLines describes the properties of an object schema, required ones
+// first.
+func paramLines(schema map[string]interface{}) []string {
+	props, _ := schema["properties"].(map[string]interface{})
+	required := map[string]bool{}
+	switch req := schema["required"].(type) {
+	case []string:
+		for _, r := range req {
+			required[r] = true
+		}
+	case []interface{}:
+		for _, r := range req {
+			if s, ok := r.(string); ok {
+				required[s] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if required[names[i]] != required[names[j]] {
+			return required[names[i]]
+		}
+		return names[i] < names[j]
+	})
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		p, _ := props[name].(map[string]interface{})
+		kind := schemaType(p)
+		if required[name] {
+			kind += ", required"
+		}
+		line := fmt.Sprintf("- %s (%s)", name, kind)
+		if desc, _ := p["description"].(string); desc != "" {
+			line += ": " + desc
+		}
+		if enum := enumValues(p["enum"]); len(enum) > 0 {
+			line += fmt.Sprintf(" [one of: %s]", strings.Join(enum, ", "))
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// schemaType returns a short name for the type of a property.
+func schemaType(p map[string]interface{}) string {
+	t, _ := p["type"].(string)
+	if t == "array" {
+		if items, ok := p["items"].(map[string]interface{}); ok {
+			return "array of " + schemaType(items)
+		}
+	}
+	if t == "" {
+		return "any"
+	}
+	return t
+}
+
+func enumValues(v interface{}) []string {
+	switch e := v.(type) {
+	case []string:
+		return e
+	case []interface{}:
+		out := make([]string, 0, len(e))
+		for _, x := range e {
+			out = append(out, fmt.Sprint(x))
+		}
+		return out
+	}
+	return nil
+}
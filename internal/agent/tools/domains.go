@@ -0,0 +1,52 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DomainPolicy restricts which hosts network tools may contact.
+// Patterns are exact hostnames ("example.com"), wildcard subdomains
+// ("*.example.com", which does not match example.com itself) or "*".
+// Deny takes precedence over Allow; an empty Allow permits every host
+// not denied.
+type DomainPolicy struct {
+	Allow []string
+	Deny  []string
+}
+
+// Check returns an error if host is not permitted by the policy.
+func (p DomainPolicy) Check(host string) error {
+	host = normalizeHost(host)
+	for _, pat := range p.Deny {
+		if matchDomain(pat, host) {
+			return fmt.Errorf("domain %s is denied by policy", host)
+		}
+	}
+	if len(p.Allow) == 0 {
+		return nil
+	}
+	for _, pat := range p.Allow {
+		if matchDomain(pat, host) {
+			return nil
+		}
+	}
+	return fmt.Errorf("domain %s is not in the allowlist", host)
+}
+
+func normalizeHost(h string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(h)), ".")
+}
+
+// matchDomain reports whether host matches pattern.
+func matchDomain(pattern, host string) bool {
+	pattern = normalizeHost(pattern)
+	switch {
+	case pattern == "*":
+		return true
+	case strings.HasPrefix(pattern, "*."):
+		return strings.HasSuffix(host, pattern[1:])
+	default:
+		return host == pattern
+	}
+}
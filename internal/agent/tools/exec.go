@@ -3,32 +3,88 @@ package tools
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/kr0nicas/picobot/internal/agent/skills"
+	"github.com/kr0nicas/picobot/internal/workspace"
 )
 
 // ExecTool runs shell commands with a timeout.
 // For safety:
 // - prefer array form: {"cmd": ["ls", "-la"]}
-// - string form (shell) is disallowed to avoid shell injection
-// - blacklist dangerous program names (rm, sudo, dd, mkfs, shutdown, reboot)
-// - arguments containing absolute paths, ~ or .. are rejected
+// - string form is split on whitespace and never passed to a shell
+// - programs are checked against the active ExecProfile's allow/deny lists
+// - arguments containing ~ or .. (and shell metacharacters) are rejected
+//   unless the profile relaxes those rules
 // - optional allowedDir enforces a working directory
+// - profiles with DenyNetwork run commands without network access
+// - profiles with the container sandbox run commands in an ephemeral container
+// - {"background": true} starts the command as a job managed with the
+//   job_status, job_logs and job_kill tools
+// - {"skill": "name"} resolves script paths in that skill's folder and runs
+//   Python in the skill's own environment with its declared packages
+// - output is capped, CPU and memory are limited via rlimits (Linux), and
+//   credential-like environment variables are not passed on
+//
+// The active profile can differ per channel; it is selected by the channel
+// the call is made for (see WithChat).
 
 type ExecTool struct {
-	timeout    time.Duration
-	allowedDir string
+	profile         ExecProfile
+	channelProfiles map[string]ExecProfile
+	allowedDir      string
+	strictPaths     bool
+	limiter         *ExecLimiter
+	passEnv         []string
+	jobs            *JobManager
 }
 
+// NewExecTool creates an ExecTool using the standard profile with the given timeout.
 func NewExecTool(timeoutSecs int) *ExecTool {
-	return &ExecTool{timeout: time.Duration(timeoutSecs) * time.Second}
+	return NewExecToolWithWorkspace(timeoutSecs, "")
 }
 
 // NewExecToolWithWorkspace creates an ExecTool restricted to the provided workspace directory.
 func NewExecToolWithWorkspace(timeoutSecs int, allowedDir string) *ExecTool {
-	return &ExecTool{timeout: time.Duration(timeoutSecs) * time.Second, allowedDir: allowedDir}
+	p, _ := ExecProfileByName("standard")
+	p.Timeout = time.Duration(timeoutSecs) * time.Second
+	return NewExecToolWithProfile(p, allowedDir)
+}
+
+// NewExecToolWithProfile creates an ExecTool enforcing profile p in allowedDir.
+func NewExecToolWithProfile(p ExecProfile, allowedDir string) *ExecTool {
+	return &ExecTool{profile: p, allowedDir: allowedDir, jobs: NewJobManager()}
+}
+
+// Jobs returns the manager of the tool's background jobs.
+func (t *ExecTool) Jobs() *JobManager { return t.jobs }
+
+// SetProfiles replaces the default profile and the per-channel overrides.
+func (t *ExecTool) SetProfiles(def ExecProfile, perChannel map[string]ExecProfile) {
+	t.profile = def
+	t.channelProfiles = perChannel
+}
+
+// SetLimits shares limiter, which caps concurrent commands, with the tool
+// and names the environment variables passed to commands even though they
+// look like credentials.
+func (t *ExecTool) SetLimits(limiter *ExecLimiter, passEnv []string) {
+	t.limiter = limiter
+	t.passEnv = passEnv
+}
+
+// activeProfile returns the profile for the channel of ctx.
+func (t *ExecTool) activeProfile(ctx context.Context) ExecProfile {
+	channel, _ := chatOf(ctx)
+	if p, ok := t.channelProfiles[channel]; ok {
+		return p
+	}
+	return t.profile
 }
 
 func (t *ExecTool) Name() string { return "exec" }
@@ -54,6 +110,14 @@ func (t *ExecTool) Parameters() map[string]interface{} {
 					},
 				},
 			},
+			"background": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Run as a background job for long-running commands (builds, servers, scrapers). Returns a job ID at once; use job_status, job_logs and job_kill to follow it.",
+			},
+			"skill": map[string]interface{}{
+				"type":        "string",
+				"description": "Run a script of this skill: paths like scripts/fetch.py are relative to the skill's folder, and python runs with the skill's packages installed",
+			},
 		},
 		"required": []string{"cmd"},
 	}
@@ -72,13 +136,30 @@ var dangerous = map[string]struct{}{
 	"nc":       {},
 	"netcat":   {},
 	"nmap":     {},
+	// Windows shells and destructive built-ins
+	"cmd":        {},
+	"powershell": {},
+	"pwsh":       {},
+	"wsl":        {},
+	"del":        {},
+	"rmdir":      {},
+	"format":     {},
+	"diskpart":   {},
+	"runas":      {},
+	"reg":        {},
 }
 
-func isDangerousProg(prog string) bool {
-	base := filepath.Base(prog)
-	base = strings.ToLower(base)
-	_, ok := dangerous[base]
-	return ok
+// progName returns the lowercased base name of prog with any Windows
+// executable extension removed, so C:\Windows\System32\CMD.EXE and "cmd"
+// are treated alike.
+func progName(prog string) string {
+	base := strings.ToLower(filepath.Base(prog))
+	for _, ext := range []string{".exe", ".cmd", ".bat", ".com"} {
+		if strings.HasSuffix(base, ext) {
+			return strings.TrimSuffix(base, ext)
+		}
+	}
+	return base
 }
 
 // isInterpreter returns true for programs that accept -c with inline source code.
@@ -91,9 +172,7 @@ var interpreters = map[string]struct{}{
 }
 
 func isInterpreter(prog string) bool {
-	base := filepath.Base(prog)
-	base = strings.ToLower(base)
-	_, ok := interpreters[base]
+	_, ok := interpreters[progName(prog)]
 	return ok
 }
 
@@ -106,21 +185,19 @@ var packageManagers = map[string]struct{}{
 }
 
 func isPackageManager(prog string) bool {
-	base := filepath.Base(prog)
-	base = strings.ToLower(base)
-	_, ok := packageManagers[base]
+	_, ok := packageManagers[progName(prog)]
 	return ok
 }
 
-func hasUnsafeArg(s string) bool {
-	// A more aggressive check: reject any arg containing path separators,
-	// home expansion or parent directory references anywhere.
-	// We also reject shell characters that could be used for chaining.
-	if strings.Contains(s, "..") || strings.Contains(s, "~") {
-		return true
-	}
-	// Reject common shell meta-characters that might bypass the array-only restriction
-	// if the binary itself invokes a shell (e.g. some scripts).
+// hasPathEscape reports whether s references the home directory or a parent
+// directory.
+func hasPathEscape(s string) bool {
+	return strings.Contains(s, "..") || strings.Contains(s, "~")
+}
+
+// hasShellMeta reports whether s contains shell metacharacters that could be
+// used for chaining if the binary itself invokes a shell (e.g. some scripts).
+func hasShellMeta(s string) bool {
 	meta := []string{";", "&", "|", ">", "<", "$", "`"}
 	for _, m := range meta {
 		if strings.Contains(s, m) {
@@ -130,10 +207,38 @@ func hasUnsafeArg(s string) bool {
 	return false
 }
 
-func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+// SetStrictPaths makes path arguments that go through a symlink count as
+// leaving the workspace (see workspace.Jail).
+func (t *ExecTool) SetStrictPaths(strict bool) { t.strictPaths = strict }
+
+// resolvePath checks that the path argument a stays inside the workspace.
+// Without a workspace directory only ".." escapes can be detected.
+func (t *ExecTool) resolvePath(a string) (string, error) {
+	if t.allowedDir == "" {
+		return workspace.SafeJoin(".", a)
+	}
+	return workspace.Jail{Root: t.allowedDir, Strict: t.strictPaths}.Resolve(a)
+}
+
+// escapesViaSymlink reports whether a relative argument names an existing
+// path in the workspace that symlinks out of it.
+func (t *ExecTool) escapesViaSymlink(a string) bool {
+	if t.allowedDir == "" || a == "" || strings.HasPrefix(a, "-") || filepath.IsAbs(a) {
+		return false
+	}
+	if _, err := os.Lstat(filepath.Join(t.allowedDir, a)); err != nil {
+		return false
+	}
+	_, err := t.resolvePath(a)
+	return err != nil
+}
+
+// parseArgv extracts the command line from the "cmd" argument, accepting
+// either an array of strings or a whitespace-separated string.
+func parseArgv(args map[string]interface{}) ([]string, error) {
 	cmdRaw, ok := args["cmd"]
 	if !ok {
-		return "", fmt.Errorf("exec: 'cmd' argument required")
+		return nil, fmt.Errorf("exec: 'cmd' argument required")
 	}
 
 	var argv []string
@@ -142,32 +247,100 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) (st
 		// Allow string form: split by whitespace into argv.
 		parts := strings.Fields(v)
 		if len(parts) == 0 {
-			return "", fmt.Errorf("exec: empty cmd string")
+			return nil, fmt.Errorf("exec: empty cmd string")
 		}
 		argv = parts
 	case []interface{}:
 		if len(v) == 0 {
-			return "", fmt.Errorf("exec: empty cmd array")
+			return nil, fmt.Errorf("exec: empty cmd array")
 		}
 		for _, a := range v {
 			s, ok := a.(string)
 			if !ok {
-				return "", fmt.Errorf("exec: cmd array must contain strings only")
+				return nil, fmt.Errorf("exec: cmd array must contain strings only")
 			}
 			argv = append(argv, s)
 		}
 	default:
-		return "", fmt.Errorf("exec: unsupported cmd type")
+		return nil, fmt.Errorf("exec: unsupported cmd type")
 	}
+	return argv, nil
+}
+
+// riskyPrograms change or destroy state outside the command's own output and
+// need the user's approval. A non-nil list restricts it to those subcommands.
+var riskyPrograms = map[string][]string{
+	"rm":        nil,
+	"mv":        nil,
+	"chmod":     nil,
+	"chown":     nil,
+	"kill":      nil,
+	"pkill":     nil,
+	"killall":   nil,
+	"truncate":  nil,
+	"shred":     nil,
+	"crontab":   nil,
+	"systemctl": nil,
+	"docker":    {"rm", "rmi", "stop", "kill", "prune", "system"},
+	"git":       {"push", "reset", "clean", "rebase", "checkout", "restore"},
+	"pip":       {"uninstall"},
+	"pip3":      {"uninstall"},
+	"uv":        {"remove"},
+}
 
+// RequiresApproval implements ApprovalRequirer for risky commands.
+func (t *ExecTool) RequiresApproval(args map[string]interface{}) string {
+	argv, err := parseArgv(args)
+	if err != nil {
+		return ""
+	}
+	subs, ok := riskyPrograms[progName(argv[0])]
+	if !ok {
+		return ""
+	}
+	if subs != nil {
+		risky := false
+		for _, a := range argv[1:] {
+			for _, sub := range subs {
+				if a == sub {
+					risky = true
+				}
+			}
+		}
+		if !risky {
+			return ""
+		}
+	}
+	return "run " + strings.Join(argv, " ")
+}
+
+func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	argv, err := parseArgv(args)
+	if err != nil {
+		return "", err
+	}
+	skillName, _ := args["skill"].(string)
+	var skillDir string
+	var skill skills.Skill
+	if skillName != "" {
+		if skillDir, skill, err = t.skillArgs(skillName, argv); err != nil {
+			return "", err
+		}
+	}
+
+	p := t.activeProfile(ctx)
 	prog := argv[0]
-	if isDangerousProg(prog) {
-		return "", fmt.Errorf("exec: program '%s' is disallowed", prog)
+	if !p.allows(prog) {
+		return "", fmt.Errorf("exec: program '%s' is disallowed by the %s profile", prog, p.Name)
+	}
+	if err := p.checkArgs(argv); err != nil {
+		return "", err
 	}
+	jailed := p.Sandbox != SandboxNone
 
 	// Catch common LLM hallucination: "uv run pip install ..."
 	// The correct syntax is "uv pip install ...", not "uv run pip install ...".
-	if strings.ToLower(filepath.Base(prog)) == "uv" && len(argv) >= 3 &&
+	if progName(prog) == "uv" && len(argv) >= 3 &&
 		argv[1] == "run" && argv[2] == "pip" {
 		return "", fmt.Errorf("exec: wrong syntax 'uv run pip install'. Use [\"uv\", \"pip\", \"install\", ...] instead")
 	}
@@ -176,17 +349,17 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) (st
 	// - With -c: the code argument can contain any characters (it's source code).
 	// - Without -c: the first argument is a script path — allow relative paths
 	//   (containing /) as long as they don't escape with "..".
-	interpreterMode := isInterpreter(prog)
+	interpreterMode := p.AllowInterpreters && isInterpreter(prog)
 
 	// Package managers (pip/pip3): allow all arguments through.
 	// They need flags like --user, --break-system-packages and package names.
-	pkgMgrMode := isPackageManager(prog)
+	pkgMgrMode := p.AllowInterpreters && isPackageManager(prog)
 
 	for i, a := range argv[1:] {
 		idx := i + 1 // index in argv
 		if pkgMgrMode {
 			// Only reject directory traversal for safety
-			if strings.Contains(a, "..") {
+			if jailed && strings.Contains(a, "..") {
 				return "", fmt.Errorf("exec: argument '%s' looks unsafe", a)
 			}
 			continue
@@ -194,43 +367,95 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) (st
 		if interpreterMode {
 			// All interpreter arguments are allowed (script args may contain
 			// free-form text like log messages with special characters).
-			// Only reject directory traversal in the script path itself.
-			if idx == 1 && strings.Contains(a, "..") {
-				return "", fmt.Errorf("exec: argument '%s' looks unsafe", a)
-			}
-			// Auto-resolve absolute script paths inside workspace
-			if idx == 1 && strings.HasPrefix(a, "/") && t.allowedDir != "" {
-				rel, err := filepath.Rel(t.allowedDir, a)
-				if err == nil && !strings.HasPrefix(rel, "..") {
-					argv[idx] = rel
-				} else {
+			// Only check the script path itself, resolving symlinks so a
+			// link inside the workspace cannot point the interpreter at a
+			// script outside it.
+			if jailed && idx == 1 && !strings.HasPrefix(a, "-") {
+				if _, err := t.resolvePath(a); err != nil {
 					return "", fmt.Errorf("exec: script path '%s' is outside workspace", a)
 				}
+				// Auto-resolve absolute script paths inside workspace
+				if t.allowedDir != "" && filepath.IsAbs(a) {
+					if rel, err := filepath.Rel(t.allowedDir, a); err == nil {
+						argv[idx] = rel
+					}
+				}
 			}
 			continue
 		}
-		if hasUnsafeArg(a) {
+		if (jailed && hasPathEscape(a)) || (!p.AllowShellMeta && hasShellMeta(a)) {
 			return "", fmt.Errorf("exec: argument '%s' looks unsafe", a)
 		}
+		if jailed && t.escapesViaSymlink(a) {
+			return "", fmt.Errorf("exec: argument '%s' resolves outside workspace", a)
+		}
+	}
+
+	// The skill's virtualenv lives on the host, so container runs use the
+	// image's interpreter.
+	if len(skill.Dependencies) > 0 && p.Sandbox != SandboxContainer && strings.HasPrefix(progName(prog), "python") {
+		python, err := t.skillPython(ctx, skillDir, skill)
+		if err != nil {
+			return "", err
+		}
+		argv[0] = python
+	}
+
+	if background, _ := args["background"].(bool); background {
+		return t.startJob(p, argv)
 	}
 
+	release, err := t.limiter.acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
 	cctx := ctx
-	if t.timeout > 0 {
+	if p.Timeout > 0 {
 		var cancel context.CancelFunc
-		cctx, cancel = context.WithTimeout(ctx, t.timeout)
+		cctx, cancel = context.WithTimeout(ctx, p.Timeout)
 		defer cancel()
 	}
 
-	cmd := exec.CommandContext(cctx, prog, argv[1:]...)
+	out := &cappedBuffer{max: p.MaxOutputBytes}
+	cmd, cleanup, err := t.start(cctx, p, argv, out)
+	if err != nil {
+		return "", err
+	}
+	err = cmd.Wait()
+	cleanup()
+	// out.String trims the trailing newline for nicer test assertions
+	if err != nil {
+		return out.String(), fmt.Errorf("exec error: %w", err)
+	}
+	return out.String(), nil
+}
+
+// start starts argv under profile p with its output going to out. The
+// returned cleanup must be called once the command has exited.
+func (t *ExecTool) start(ctx context.Context, p ExecProfile, argv []string, out io.Writer) (*exec.Cmd, func(), error) {
+	if p.Sandbox == SandboxContainer {
+		return t.startContainer(ctx, p, argv, out)
+	}
+	prog := argv[0]
+	cmd := exec.CommandContext(ctx, prog, argv[1:]...)
 	if t.allowedDir != "" {
 		cmd.Dir = t.allowedDir
 	}
-	b, err := cmd.CombinedOutput()
-	if err != nil {
-		return string(b), fmt.Errorf("exec error: %w", err)
+	if !p.networkAllowed(prog) {
+		if err := isolateNetwork(cmd); err != nil {
+			return nil, nil, err
+		}
+	}
+	cmd.Env = scrubEnv(os.Environ(), t.passEnv)
+	limitCommand(cmd, p)
+	cmd.Stdout, cmd.Stderr = out, out
+	if err := cmd.Start(); err != nil {
+		if !p.networkAllowed(prog) {
+			return nil, nil, fmt.Errorf("exec: cannot start %s without network access (are unprivileged user namespaces enabled?): %w", prog, err)
+		}
+		return nil, nil, fmt.Errorf("exec error: %w", err)
 	}
-	// Trim trailing newline for nicer test assertions
-	out := string(b)
-	out = strings.TrimRight(out, "\n")
-	return out, nil
+	return cmd, func() {}, nil
 }
@@ -0,0 +1,87 @@
+package tools
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// ContainerOptions configures the SandboxContainer backend.
+type ContainerOptions struct {
+	Runtime  string  // container CLI, "docker" (default) or "podman"
+	Image    string  // image the commands run in
+	CPUs     float64 // CPU limit; 0 means unlimited
+	MemoryMB int     // memory limit in MiB; 0 means unlimited
+}
+
+// containerWorkdir is where the workspace is mounted inside the container.
+const containerWorkdir = "/workspace"
+
+func (o ContainerOptions) runtime() string {
+	if o.Runtime == "" {
+		return "docker"
+	}
+	return o.Runtime
+}
+
+// containerArgs returns the arguments to the container runtime that run argv
+// in a fresh container called name, with dir mounted as the working
+// directory. The container gets no network unless network is set, no extra
+// capabilities and the CPU and memory limits of the options.
+func (o ContainerOptions) containerArgs(name, dir string, network bool, argv []string) []string {
+	args := []string{"run", "--rm", "--name", name,
+		"--cap-drop", "ALL", "--security-opt", "no-new-privileges"}
+	if !network {
+		args = append(args, "--network", "none")
+	}
+	if o.CPUs > 0 {
+		args = append(args, "--cpus", strconv.FormatFloat(o.CPUs, 'f', -1, 64))
+	}
+	if o.MemoryMB > 0 {
+		args = append(args, "--memory", strconv.Itoa(o.MemoryMB)+"m")
+	}
+	// run as the host user so files written to the workspace stay theirs
+	if uid, gid := os.Getuid(), os.Getgid(); uid >= 0 && gid >= 0 {
+		args = append(args, "--user", fmt.Sprintf("%d:%d", uid, gid))
+	}
+	if dir != "" {
+		args = append(args, "-v", dir+":"+containerWorkdir, "-w", containerWorkdir)
+	}
+	args = append(args, o.Image)
+	return append(args, argv...)
+}
+
+// startContainer starts argv in an ephemeral container, writing its combined
+// output to out. The returned cleanup removes the container when ctx ended
+// first, since killing the runtime's client does not stop it.
+func (t *ExecTool) startContainer(ctx context.Context, p ExecProfile, argv []string, out io.Writer) (*exec.Cmd, func(), error) {
+	o := p.Container
+	if o.Image == "" {
+		return nil, nil, fmt.Errorf("exec: profile %s uses the container sandbox but sets no image", p.Name)
+	}
+	b := make([]byte, 6)
+	rand.Read(b)
+	name := "picobot-exec-" + hex.EncodeToString(b)
+
+	cmd := exec.CommandContext(ctx, o.runtime(), o.containerArgs(name, t.allowedDir, p.networkAllowed(argv[0]), argv)...)
+	cmd.Env = scrubEnv(os.Environ(), t.passEnv)
+	cmd.Stdout, cmd.Stderr = out, out
+	if err := cmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("exec: cannot start container runtime %s: %w", o.runtime(), err)
+	}
+	cleanup := func() {
+		if ctx.Err() == nil {
+			return
+		}
+		rctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		exec.CommandContext(rctx, o.runtime(), "rm", "-f", name).Run()
+	}
+	return cmd, cleanup, nil
+}
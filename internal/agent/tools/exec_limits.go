@@ -0,0 +1,112 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// DefaultExecMaxOutput is the output cap of the built-in exec profiles.
+const DefaultExecMaxOutput = 64 << 10
+
+// cappedBuffer keeps the first max bytes written to it (all of them when max
+// is 0) and counts the rest.
+type cappedBuffer struct {
+	mu      sync.Mutex // stdout and stderr are copied concurrently
+	buf     bytes.Buffer
+	max     int
+	dropped int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	keep := len(p)
+	if b.max > 0 {
+		keep = min(keep, max(b.max-b.buf.Len(), 0))
+	}
+	b.buf.Write(p[:keep])
+	b.dropped += len(p) - keep
+	return len(p), nil
+}
+
+// String returns the kept output, followed by a notice when some was cut.
+func (b *cappedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := strings.TrimRight(b.buf.String(), "\n")
+	if b.dropped > 0 {
+		out += fmt.Sprintf("\n[output truncated: %d more bytes]", b.dropped)
+	}
+	return out
+}
+
+// ExecLimiter caps how many exec commands run at once. It is shared by the
+// exec tools of all tenants; a nil *ExecLimiter imposes no limit.
+type ExecLimiter struct {
+	slots chan struct{}
+}
+
+// NewExecLimiter returns a limiter allowing n concurrent commands, or nil
+// (no limit) when n <= 0.
+func NewExecLimiter(n int) *ExecLimiter {
+	if n <= 0 {
+		return nil
+	}
+	return &ExecLimiter{slots: make(chan struct{}, n)}
+}
+
+// acquire waits for a free slot and returns the function releasing it.
+func (l *ExecLimiter) acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return func() { <-l.slots }, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("exec: waiting for a free slot (%d commands already running): %w", cap(l.slots), ctx.Err())
+	}
+}
+
+// secretEnvWords mark environment variables that likely hold credentials.
+var secretEnvWords = []string{"KEY", "TOKEN", "SECRET", "PASSWORD", "PASSWD", "CREDENTIAL", "AUTH", "COOKIE", "SESSION"}
+
+// scrubEnv returns env without the variables that look like credentials and
+// picobot's own PICOBOT_* settings, so commands cannot read the API keys
+// of the picobot process. Variables named in keep are always passed on.
+func scrubEnv(env []string, keep []string) []string {
+	out := make([]string, 0, len(env))
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		if !containsFold(keep, name) && sensitiveEnv(name) {
+			continue
+		}
+		out = append(out, kv)
+	}
+	return out
+}
+
+func sensitiveEnv(name string) bool {
+	upper := strings.ToUpper(name)
+	if strings.HasPrefix(upper, "PICOBOT_") {
+		return true
+	}
+	for _, w := range secretEnvWords {
+		if strings.Contains(upper, w) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
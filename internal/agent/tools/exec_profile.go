@@ -0,0 +1,248 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Sandbox modes for exec profiles.
+const (
+	// SandboxWorkspace runs commands in the workspace and rejects arguments
+	// that reference the home directory or escape it with "..".
+	SandboxWorkspace = "workspace"
+	// SandboxNone runs commands in the workspace without argument path checks.
+	SandboxNone = "none"
+	// SandboxContainer applies the workspace checks and runs each command in
+	// an ephemeral container with the workspace mounted, see ContainerOptions.
+	SandboxContainer = "container"
+)
+
+// ExecProfile bundles the rules the exec tool enforces.
+type ExecProfile struct {
+	Name string
+	// Allow, if non-empty, is the complete list of programs that may run.
+	// Entries may be globs such as "python3*".
+	Allow []string
+	// Allowlist makes Allow the complete list even when it is empty, so a
+	// profile without entries runs nothing.
+	Allowlist bool
+	// Deny lists programs that may never run. Ignored for programs in Allow.
+	Deny []string
+	// Args validates the arguments of the programs (or globs) it names.
+	Args map[string]ArgValidator
+	// AllowShellMeta permits shell metacharacters (; & | > < $ `) in arguments.
+	AllowShellMeta bool
+	// AllowInterpreters permits python/node/... and package managers with
+	// relaxed argument checks (inline code, package names, flags).
+	AllowInterpreters bool
+	Timeout           time.Duration
+	Sandbox           string
+	// DenyNetwork runs commands in an isolated network namespace (Linux) so
+	// they cannot reach the network, except programs listed in NetworkAllow.
+	DenyNetwork  bool
+	NetworkAllow []string
+	// MaxOutputBytes caps the output returned to the model; 0 means no cap.
+	MaxOutputBytes int
+	// CPUSeconds and MemoryMB limit each command's CPU time and address
+	// space via rlimits (Linux only); 0 means no limit.
+	CPUSeconds int
+	MemoryMB   int
+	// Container configures the SandboxContainer backend.
+	Container ContainerOptions
+}
+
+// ArgValidator restricts the arguments a program may be run with.
+type ArgValidator struct {
+	// Subcommands, if non-empty, lists the allowed first arguments,
+	// e.g. "status" and "log" for git.
+	Subcommands []string
+	// Allow, if non-empty, must match every argument in full.
+	Allow []*regexp.Regexp
+	// Deny must not match any argument.
+	Deny []*regexp.Regexp
+}
+
+// check returns an error naming the first argument of prog the validator
+// rejects.
+func (v ArgValidator) check(prog string, args []string) error {
+	if len(v.Subcommands) > 0 && (len(args) == 0 || !slices.Contains(v.Subcommands, args[0])) {
+		return fmt.Errorf("exec: %s may only run the subcommands %s", prog, strings.Join(v.Subcommands, ", "))
+	}
+	for i, a := range args {
+		for _, re := range v.Deny {
+			if re.MatchString(a) {
+				return fmt.Errorf("exec: argument '%s' is not allowed for %s", a, prog)
+			}
+		}
+		if i == 0 && len(v.Subcommands) > 0 {
+			continue
+		}
+		if len(v.Allow) > 0 && !slices.ContainsFunc(v.Allow, func(re *regexp.Regexp) bool { return fullMatch(re, a) }) {
+			return fmt.Errorf("exec: argument '%s' is not allowed for %s", a, prog)
+		}
+	}
+	return nil
+}
+
+func fullMatch(re *regexp.Regexp, s string) bool {
+	loc := re.FindStringIndex(s)
+	return loc != nil && loc[0] == 0 && loc[1] == len(s)
+}
+
+// defaultDeny is the deny list used by the standard profile.
+func defaultDeny() []string {
+	out := make([]string, 0, len(dangerous))
+	for name := range dangerous {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// builtinExecProfiles returns the named profiles shipped with picobot:
+//   - strict: read-only inspection utilities only, short timeout
+//   - standard: the default deny list and argument checks
+//   - trusted: only catastrophic programs denied, no argument checks
+func builtinExecProfiles() map[string]ExecProfile {
+	return map[string]ExecProfile{
+		"strict": {
+			Name:           "strict",
+			Allowlist:      true,
+			Allow:          []string{"ls", "cat", "head", "tail", "wc", "grep", "echo", "date", "pwd", "sort", "uniq", "diff", "stat", "file"},
+			Timeout:        30 * time.Second,
+			Sandbox:        SandboxWorkspace,
+			MaxOutputBytes: DefaultExecMaxOutput,
+		},
+		"standard": {
+			Name:              "standard",
+			Deny:              defaultDeny(),
+			AllowInterpreters: true,
+			Timeout:           60 * time.Second,
+			Sandbox:           SandboxWorkspace,
+			MaxOutputBytes:    DefaultExecMaxOutput,
+		},
+		"trusted": {
+			Name:              "trusted",
+			Deny:              []string{"sudo", "mkfs", "dd", "shutdown", "reboot", "format", "diskpart"},
+			AllowShellMeta:    true,
+			AllowInterpreters: true,
+			Timeout:           5 * time.Minute,
+			Sandbox:           SandboxNone,
+			MaxOutputBytes:    DefaultExecMaxOutput,
+		},
+	}
+}
+
+// ExecProfileByName returns the built-in profile with the given name.
+func ExecProfileByName(name string) (ExecProfile, error) {
+	p, ok := builtinExecProfiles()[name]
+	if !ok {
+		return ExecProfile{}, fmt.Errorf("exec: unknown profile %q (want strict, standard or trusted)", name)
+	}
+	return p, nil
+}
+
+// allows reports whether the profile lets prog run.
+func (p ExecProfile) allows(prog string) bool {
+	if p.Allowlist || len(p.Allow) > 0 {
+		return p.allowlisted(prog)
+	}
+	return !matchesProgram(p.Deny, prog)
+}
+
+// allowlisted reports whether prog is in the allowlist. Only bare names
+// count, and they must resolve through PATH to the same file as the entry
+// they match, so "./cat" or a "cat.exe" dropped into the workspace don't pass
+// for cat. An entry that is an absolute path allows only that file. In a
+// container, names are resolved inside it instead.
+func (p ExecProfile) allowlisted(prog string) bool {
+	if prog == "" || strings.ContainsAny(prog, `/\`) || filepath.VolumeName(prog) != "" {
+		return false
+	}
+	if !matchesProgram(p.Allow, prog) {
+		return false
+	}
+	if p.Sandbox == SandboxContainer {
+		return true
+	}
+	resolved, err := exec.LookPath(prog)
+	if err != nil {
+		return false
+	}
+	name := progName(prog)
+	for _, entry := range p.Allow {
+		pattern := progName(entry)
+		if strings.ContainsAny(pattern, "*?[") {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+			continue
+		}
+		if pattern != name {
+			continue
+		}
+		if want, err := exec.LookPath(entry); err == nil && sameFile(want, resolved) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameFile reports whether paths a and b name the same file.
+func sameFile(a, b string) bool {
+	fa, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	fb, err := os.Stat(b)
+	return err == nil && os.SameFile(fa, fb)
+}
+
+// checkArgs applies the argument validator of argv's program, if any.
+func (p ExecProfile) checkArgs(argv []string) error {
+	for pattern, v := range p.Args {
+		if matchesProgram([]string{pattern}, argv[0]) {
+			if err := v.check(progName(argv[0]), argv[1:]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// matchesProgram reports whether prog is one of the names or globs in list.
+func matchesProgram(list []string, prog string) bool {
+	name := progName(prog)
+	for _, entry := range list {
+		pattern := progName(entry)
+		if pattern == name {
+			return true
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// networkAllowed reports whether prog may use the network under the profile.
+func (p ExecProfile) networkAllowed(prog string) bool {
+	if !p.DenyNetwork {
+		return true
+	}
+	name := progName(prog)
+	for _, a := range p.NetworkAllow {
+		if progName(a) == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/kr0nicas/picobot/internal/agent/skills"
+	"github.com/kr0nicas/picobot/internal/workspace"
+)
+
+// skillEnvMu serializes the setup of skill environments, so parallel calls
+// don't install the same packages twice.
+var skillEnvMu sync.Mutex
+
+// skillDepsStamp records, inside a skill's virtualenv, which packages were
+// installed into it.
+const skillDepsStamp = ".picobot-deps"
+
+// skillArgs rewrites the arguments of a command run for the named skill:
+// those naming a file in the skill's folder, such as "scripts/fetch.py",
+// become workspace paths. It returns the skill's folder (relative to the
+// workspace) and its parsed SKILL.md.
+func (t *ExecTool) skillArgs(name string, argv []string) (string, skills.Skill, error) {
+	dir, err := workspace.SafeJoin("skills", strings.TrimSpace(name))
+	if err != nil || filepath.Dir(dir) != "skills" {
+		return "", skills.Skill{}, fmt.Errorf("exec: invalid skill name %q", name)
+	}
+	if _, err := t.resolvePath(dir); err != nil {
+		return "", skills.Skill{}, err
+	}
+	data, err := os.ReadFile(filepath.Join(t.allowedDir, dir, "SKILL.md"))
+	if err != nil {
+		return "", skills.Skill{}, fmt.Errorf("exec: no skill %q", name)
+	}
+	skill, err := skills.Parse(string(data))
+	if err != nil {
+		return "", skills.Skill{}, fmt.Errorf("exec: skill %q: %w", name, err)
+	}
+	for i, a := range argv[1:] {
+		if a == "" || strings.HasPrefix(a, "-") || filepath.IsAbs(a) || strings.Contains(a, "..") {
+			continue
+		}
+		rel := filepath.Join(dir, a)
+		if fi, err := os.Stat(filepath.Join(t.allowedDir, rel)); err == nil && fi.Mode().IsRegular() {
+			argv[i+1] = filepath.ToSlash(rel)
+		}
+	}
+	return dir, skill, nil
+}
+
+// skillPython returns the interpreter of the skill's virtualenv in dir,
+// creating it and installing the skill's dependencies with uv when they
+// changed since the last run.
+func (t *ExecTool) skillPython(ctx context.Context, dir string, skill skills.Skill) (string, error) {
+	venv := filepath.Join(t.allowedDir, dir, ".venv")
+	python := filepath.Join(venv, "bin", "python")
+	if runtime.GOOS == "windows" {
+		python = filepath.Join(venv, "Scripts", "python.exe")
+	}
+	deps := slices.Sorted(slices.Values(skill.Dependencies))
+	want := strings.Join(deps, "\n")
+
+	skillEnvMu.Lock()
+	defer skillEnvMu.Unlock()
+	if got, err := os.ReadFile(filepath.Join(venv, skillDepsStamp)); err == nil && string(got) == want {
+		if _, err := os.Stat(python); err == nil {
+			return python, nil
+		}
+	}
+	uv, err := exec.LookPath("uv")
+	if err != nil {
+		return "", fmt.Errorf("exec: skill %q needs uv to install its Python packages", skill.Name)
+	}
+	run := func(args ...string) error {
+		cmd := exec.CommandContext(ctx, uv, args...)
+		cmd.Dir = t.allowedDir
+		cmd.Env = scrubEnv(os.Environ(), t.passEnv)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("exec: installing the Python packages of skill %q: %w\n%s", skill.Name, err, out)
+		}
+		return nil
+	}
+	if _, err := os.Stat(python); err != nil {
+		if err := run("venv", venv); err != nil {
+			return "", err
+		}
+	}
+	if err := run(append([]string{"pip", "install", "--python", python}, deps...)...); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(venv, skillDepsStamp), []byte(want), 0o644); err != nil {
+		return "", err
+	}
+	toolsLog.Info("installed Python packages of skill", "tool", "exec", "skill", skill.Name, "packages", strings.Join(deps, ", "))
+	return python, nil
+}
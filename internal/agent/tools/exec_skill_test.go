@@ -0,0 +1,78 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// writeSkillPackage creates skills/demo with a hello.py helper script.
+func writeSkillPackage(t *testing.T, ws, frontmatter string) {
+	t.Helper()
+	dir := filepath.Join(ws, "skills", "demo", "scripts")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(ws, "skills", "demo", "SKILL.md"), []byte("---\nname: demo\ndescription: d\n"+frontmatter+"---\n\nbody"), 0o644)
+	os.WriteFile(filepath.Join(dir, "hello.py"), []byte("import sys\nprint('hello', sys.argv[1])\n"), 0o755)
+}
+
+func TestExecSkillScriptPaths(t *testing.T) {
+	ws := t.TempDir()
+	writeSkillPackage(t, ws, "scripts: [scripts/hello.py]\n")
+	e := NewExecToolWithWorkspace(5, ws)
+
+	argv := []string{"python3", "scripts/hello.py", "scripts/missing.py", "-u"}
+	if _, _, err := e.skillArgs("demo", argv); err != nil {
+		t.Fatal(err)
+	}
+	if argv[1] != "skills/demo/scripts/hello.py" || argv[2] != "scripts/missing.py" || argv[3] != "-u" {
+		t.Fatalf("unexpected argv: %q", argv)
+	}
+	for _, name := range []string{"../demo", "nope", ""} {
+		if _, _, err := e.skillArgs(name, []string{"python3"}); err == nil {
+			t.Errorf("skillArgs(%q) should fail", name)
+		}
+	}
+
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not installed")
+	}
+	out, err := e.Execute(context.Background(), map[string]interface{}{"skill": "demo", "cmd": []interface{}{"python3", "scripts/hello.py", "Ada"}})
+	if err != nil || out != "hello Ada" {
+		t.Fatalf("got %q, %v", out, err)
+	}
+}
+
+func TestSkillPythonReusesInstalledEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("virtualenv layout differs on Windows")
+	}
+	ws := t.TempDir()
+	writeSkillPackage(t, ws, "dependencies: [requests, feedparser]\n")
+	e := NewExecToolWithWorkspace(5, ws)
+	_, skill, err := e.skillArgs("demo", []string{"python3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	venv := filepath.Join(ws, "skills", "demo", ".venv")
+	os.MkdirAll(filepath.Join(venv, "bin"), 0o755)
+	os.WriteFile(filepath.Join(venv, "bin", "python"), nil, 0o755)
+	os.WriteFile(filepath.Join(venv, skillDepsStamp), []byte("feedparser\nrequests"), 0o644)
+
+	// the stamp matches, so uv is not needed
+	t.Setenv("PATH", "")
+	python, err := e.skillPython(context.Background(), filepath.Join("skills", "demo"), skill)
+	if err != nil || python != filepath.Join(venv, "bin", "python") {
+		t.Fatalf("got %q, %v", python, err)
+	}
+
+	skill.Dependencies = append(skill.Dependencies, "numpy")
+	if _, err := e.skillPython(context.Background(), filepath.Join("skills", "demo"), skill); err == nil {
+		t.Fatal("changed dependencies need an install, which fails without uv")
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
 	"strings"
 	"testing"
 )
@@ -124,3 +126,222 @@ func TestExecTimeout(t *testing.T) {
 		t.Fatalf("expected timeout error")
 	}
 }
+
+func TestExecRejectsWindowsShells(t *testing.T) {
+	e := NewExecTool(2)
+	for _, prog := range []string{"cmd.exe", "CMD", "powershell.exe", "pwsh"} {
+		_, err := e.Execute(context.Background(), map[string]interface{}{"cmd": []interface{}{prog, "/c", "dir"}})
+		if err == nil || !strings.Contains(err.Error(), "disallowed") {
+			t.Fatalf("expected %s to be disallowed, got %v", prog, err)
+		}
+	}
+}
+
+func TestExecStrictProfile(t *testing.T) {
+	p, err := ExecProfileByName("strict")
+	if err != nil {
+		t.Fatalf("ExecProfileByName: %v", err)
+	}
+	e := NewExecToolWithProfile(p, t.TempDir())
+	if _, err := e.Execute(context.Background(), map[string]interface{}{"cmd": []interface{}{"echo", "hi"}}); err != nil {
+		t.Fatalf("expected echo to be allowed, got %v", err)
+	}
+	_, err = e.Execute(context.Background(), map[string]interface{}{"cmd": []interface{}{"python3", "-c", "print(1)"}})
+	if err == nil || !strings.Contains(err.Error(), "strict profile") {
+		t.Fatalf("expected python3 to be rejected by strict profile, got %v", err)
+	}
+}
+
+func TestExecStrictRejectsProgramPaths(t *testing.T) {
+	p, _ := ExecProfileByName("strict")
+	ws := t.TempDir()
+	for _, name := range []string{"cat", "cat.exe"} {
+		if err := os.WriteFile(filepath.Join(ws, name), []byte("#!/bin/sh\necho pwned\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	e := NewExecToolWithProfile(p, ws)
+	for _, prog := range []string{"./cat", filepath.Join(ws, "cat"), "cat.exe"} {
+		_, err := e.Execute(context.Background(), map[string]interface{}{"cmd": []interface{}{prog, "x"}})
+		if err == nil || !strings.Contains(err.Error(), "strict profile") {
+			t.Errorf("expected %q to be rejected by the strict profile, got %v", prog, err)
+		}
+	}
+	if runtime.GOOS != "windows" {
+		if _, err := e.Execute(context.Background(), map[string]interface{}{"cmd": []interface{}{"cat", "cat"}}); err != nil {
+			t.Fatalf("expected cat to be allowed, got %v", err)
+		}
+	}
+	pinned := ExecProfile{Name: "pinned", Allow: []string{filepath.Join(ws, "cat")}}
+	if pinned.allows("cat") {
+		t.Fatalf("an entry with a path must allow only that file")
+	}
+}
+
+func TestExecPerChannelProfile(t *testing.T) {
+	strict, _ := ExecProfileByName("strict")
+	trusted, _ := ExecProfileByName("trusted")
+	e := NewExecTool(2)
+	e.SetProfiles(strict, map[string]ExecProfile{"cli": trusted})
+
+	args := map[string]interface{}{"cmd": []interface{}{"echo", "a;b"}}
+	ctx := WithChat(context.Background(), "telegram", "1")
+	if _, err := e.Execute(ctx, args); err == nil {
+		t.Fatalf("expected metacharacters to be rejected on telegram")
+	}
+	ctx = WithChat(context.Background(), "cli", "direct")
+	out, err := e.Execute(ctx, args)
+	if err != nil {
+		t.Fatalf("expected trusted profile on cli, got %v", err)
+	}
+	if out != "a;b" {
+		t.Fatalf("unexpected out: %s", out)
+	}
+}
+
+func TestExecRejectsSymlinkEscape(t *testing.T) {
+	d := t.TempDir()
+	outside := t.TempDir()
+	os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644)
+	os.WriteFile(filepath.Join(outside, "evil.py"), []byte("print('x')"), 0644)
+	if err := os.Symlink(outside, filepath.Join(d, "link")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	e := NewExecToolWithWorkspace(2, d)
+	if _, err := e.Execute(context.Background(), map[string]interface{}{"cmd": []interface{}{"cat", "link/secret.txt"}}); err == nil {
+		t.Fatalf("expected symlinked argument to be rejected")
+	}
+	if _, err := e.Execute(context.Background(), map[string]interface{}{"cmd": []interface{}{"python3", "link/evil.py"}}); err == nil || !strings.Contains(err.Error(), "outside workspace") {
+		t.Fatalf("expected symlinked script to be rejected, got %v", err)
+	}
+}
+
+func TestExecRequiresApproval(t *testing.T) {
+	e := NewExecTool(2)
+	cases := map[string]bool{
+		"git status":          false,
+		"git push origin":     true,
+		"mv a b":              true,
+		"ls -la":              false,
+		"pip install httpx":   false,
+		"pip uninstall httpx": true,
+	}
+	for cmd, want := range cases {
+		got := e.RequiresApproval(map[string]interface{}{"cmd": cmd}) != ""
+		if got != want {
+			t.Errorf("RequiresApproval(%q) = %v, want %v", cmd, got, want)
+		}
+	}
+}
+
+func TestExecDenyNetwork(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("network isolation is Linux-only")
+	}
+	p, _ := ExecProfileByName("strict")
+	p.DenyNetwork = true
+	e := NewExecToolWithProfile(p, t.TempDir())
+	out, err := e.Execute(context.Background(), map[string]interface{}{"cmd": []interface{}{"cat", "/proc/net/dev"}})
+	if err != nil && strings.Contains(err.Error(), "user namespaces") {
+		t.Skipf("unprivileged user namespaces unavailable: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, line := range strings.Split(out, "\n")[2:] {
+		if iface, _, _ := strings.Cut(strings.TrimSpace(line), ":"); iface != "lo" {
+			t.Fatalf("expected only loopback inside the sandbox, saw %q", iface)
+		}
+	}
+	if !p.DenyNetwork || p.networkAllowed("cat") {
+		t.Fatalf("cat should not be allowed network access")
+	}
+	p.NetworkAllow = []string{"cat"}
+	if !p.networkAllowed("/bin/cat") {
+		t.Fatalf("expected NetworkAllow to exempt cat")
+	}
+}
+
+func TestExecRlimits(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("rlimits are Linux-only")
+	}
+	p, _ := ExecProfileByName("strict")
+	p.CPUSeconds, p.MemoryMB = 7, 256
+	e := NewExecToolWithProfile(p, t.TempDir())
+	out, err := e.Execute(context.Background(), map[string]interface{}{"cmd": []interface{}{"cat", "/proc/self/limits"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{`Max cpu time\s+7\s+7`, `Max address space\s+268435456\s+268435456`} {
+		if !regexp.MustCompile(want).MatchString(out) {
+			t.Fatalf("expected %q in the limits of the command:\n%s", want, out)
+		}
+	}
+}
+
+func TestContainerArgs(t *testing.T) {
+	o := ContainerOptions{Image: "python:3-slim", CPUs: 1.5, MemoryMB: 512}
+	got := strings.Join(o.containerArgs("box", "/home/me/ws", false, []string{"python3", "x.py"}), " ")
+	for _, want := range []string{"run --rm --name box", "--network none", "--cpus 1.5", "--memory 512m", "-v /home/me/ws:/workspace -w /workspace", "python:3-slim python3 x.py"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %q in %q", want, got)
+		}
+	}
+	if got := strings.Join(o.containerArgs("box", "", true, []string{"ls"}), " "); strings.Contains(got, "--network") || strings.Contains(got, "-v ") {
+		t.Fatalf("expected no network or mount flags, got %q", got)
+	}
+}
+
+func TestExecOutputCap(t *testing.T) {
+	p, _ := ExecProfileByName("standard")
+	p.MaxOutputBytes = 5
+	e := NewExecToolWithProfile(p, t.TempDir())
+	out, err := e.Execute(context.Background(), map[string]interface{}{"cmd": []interface{}{"echo", "hello world"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "hello\n[output truncated: 7 more bytes]" {
+		t.Fatalf("unexpected out: %q", out)
+	}
+}
+
+func TestScrubEnv(t *testing.T) {
+	env := []string{"PATH=/bin", "OPENAI_API_KEY=sk-1", "GITHUB_TOKEN=x", "PICOBOT_HOME=/p", "LANG=C", "NPM_TOKEN=y"}
+	got := strings.Join(scrubEnv(env, []string{"npm_token"}), " ")
+	if got != "PATH=/bin LANG=C NPM_TOKEN=y" {
+		t.Fatalf("unexpected env: %q", got)
+	}
+}
+
+func TestExecAllowlistPolicy(t *testing.T) {
+	p := ExecProfile{
+		Name:      "ci",
+		Allowlist: true,
+		Allow:     []string{"ec*", "git"},
+		Args: map[string]ArgValidator{
+			"git":  {Subcommands: []string{"status", "log"}, Allow: []*regexp.Regexp{regexp.MustCompile(`--[a-z]+|-n|\d+`)}},
+			"echo": {Deny: []*regexp.Regexp{regexp.MustCompile(`secret`)}},
+		},
+		Sandbox: SandboxWorkspace,
+	}
+	for cmd, want := range map[string]bool{
+		"echo hi":           true,
+		"echo the secret":   false,
+		"cat file":          false,
+		"git status":        true,
+		"git log -n 5":      true,
+		"git log -n HEAD~":  false,
+		"git push":          false,
+		"git":               false,
+		"git log --oneline": true,
+	} {
+		argv := strings.Fields(cmd)
+		if got := p.allows(argv[0]) && p.checkArgs(argv) == nil; got != want {
+			t.Errorf("%q allowed = %v, want %v", cmd, got, want)
+		}
+	}
+	if (ExecProfile{Allowlist: true}).allows("ls") {
+		t.Fatalf("an empty allowlist should allow nothing")
+	}
+}
@@ -0,0 +1,217 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/feeds"
+	"github.com/kr0nicas/picobot/internal/netguard"
+)
+
+const (
+	feedTimeout  = 20 * time.Second
+	maxFeedBytes = 5 << 20
+)
+
+// FeedsTool manages RSS/Atom subscriptions. New posts of subscribed feeds
+// are announced in the chat that subscribed by the heartbeat, or fetched on
+// demand.
+// Args: {"action": "subscribe", "url": "https://blog.example/feed.xml", "name": "blog"}
+type FeedsTool struct {
+	store     *feeds.Store
+	lookup    netguard.Lookup
+	domains   DomainPolicy
+	transport http.RoundTripper // nil means netguard.Transport
+}
+
+// NewFeedsTool returns a feeds tool keeping its subscriptions in the
+// workspace.
+func NewFeedsTool(workspace string) *FeedsTool {
+	return &FeedsTool{store: feeds.NewStore(feeds.StorePath(workspace)), lookup: netguard.DefaultLookup}
+}
+
+// UntrustedOutput marks feed items as untrusted data.
+func (t *FeedsTool) UntrustedOutput() bool { return true }
+
+// SetDomainPolicy restricts the hosts feeds may be fetched from.
+func (t *FeedsTool) SetDomainPolicy(p DomainPolicy) { t.domains = p }
+
+func (t *FeedsTool) Name() string { return "feeds" }
+func (t *FeedsTool) Description() string {
+	return "Watch RSS/Atom feeds. Actions: subscribe (new posts are then announced in this chat automatically), unsubscribe, list, fetch_new (return posts not seen yet)."
+}
+
+func (t *FeedsTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"description": "subscribe, unsubscribe, list or fetch_new",
+				"enum":        []string{"subscribe", "unsubscribe", "list", "fetch_new"},
+			},
+			"url": map[string]interface{}{
+				"type":        "string",
+				"description": "subscribe: the feed URL",
+			},
+			"name": map[string]interface{}{
+				"type":        "string",
+				"description": "subscribe: a short name (default: the feed title); unsubscribe/fetch_new: the subscription's name or URL",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *FeedsTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	action, _ := args["action"].(string)
+	name, _ := args["name"].(string)
+	name = strings.TrimSpace(name)
+	switch action {
+	case "subscribe":
+		uStr, _ := args["url"].(string)
+		if uStr == "" {
+			return "", fmt.Errorf("feeds: subscribe needs 'url'")
+		}
+		data, err := t.fetch(ctx, uStr)
+		if err != nil {
+			return "", err
+		}
+		f, err := feeds.Parse(data)
+		if err != nil {
+			return "", err
+		}
+		if name == "" {
+			name = f.Title
+		}
+		if name == "" {
+			name = uStr
+		}
+		channel, chatID := chatOf(ctx)
+		sub := feeds.Subscription{Name: name, URL: uStr, Channel: channel, ChatID: chatID}
+		if err := t.store.Subscribe(sub, f.Items); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Subscribed to %q (%d posts so far). New posts will be announced in this chat.", name, len(f.Items)), nil
+
+	case "unsubscribe":
+		if name == "" {
+			return "", fmt.Errorf("feeds: unsubscribe needs 'name'")
+		}
+		ok, err := t.store.Unsubscribe(name)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return "", fmt.Errorf("feeds: no subscription %q", name)
+		}
+		return fmt.Sprintf("Unsubscribed from %q.", name), nil
+
+	case "list":
+		subs, err := t.store.List()
+		if err != nil {
+			return "", err
+		}
+		if len(subs) == 0 {
+			return "No feed subscriptions.", nil
+		}
+		var sb strings.Builder
+		for _, s := range subs {
+			fmt.Fprintf(&sb, "- %s: %s", s.Name, s.URL)
+			if !s.LastChecked.IsZero() {
+				fmt.Fprintf(&sb, " (checked %s)", s.LastChecked.Format(time.RFC3339))
+			}
+			if s.LastError != "" {
+				fmt.Fprintf(&sb, " [error: %s]", s.LastError)
+			}
+			sb.WriteString("\n")
+		}
+		return strings.TrimRight(sb.String(), "\n"), nil
+
+	case "fetch_new":
+		var want func(feeds.Subscription) bool
+		if name != "" {
+			want = func(s feeds.Subscription) bool { return strings.EqualFold(s.Name, name) || s.URL == name }
+		}
+		updates, err := t.store.FetchNew(ctx, t.fetch, want)
+		if err != nil {
+			return "", err
+		}
+		if name != "" && len(updates) == 0 {
+			return "", fmt.Errorf("feeds: no subscription %q", name)
+		}
+		var parts []string
+		for _, u := range updates {
+			switch {
+			case u.Err != nil:
+				parts = append(parts, fmt.Sprintf("%s: %v", u.Subscription.Name, u.Err))
+			case len(u.Items) > 0:
+				parts = append(parts, feeds.Format(u))
+			}
+		}
+		if len(parts) == 0 {
+			return "No new posts.", nil
+		}
+		return strings.Join(parts, "\n\n"), nil
+	}
+	return "", fmt.Errorf("feeds: unknown action %q", action)
+}
+
+func (t *FeedsTool) fetch(ctx context.Context, u string) ([]byte, error) {
+	return fetchFeed(ctx, u, t.lookup, t.domains, t.transport)
+}
+
+// NewFeedFetcher returns a feeds.Fetcher with the domain policy and SSRF
+// checks of the network tools, for polling feeds outside a tool call.
+func NewFeedFetcher(p DomainPolicy) feeds.Fetcher {
+	return func(ctx context.Context, u string) ([]byte, error) {
+		return fetchFeed(ctx, u, netguard.DefaultLookup, p, nil)
+	}
+}
+
+func fetchFeed(ctx context.Context, uStr string, lookup netguard.Lookup, domains DomainPolicy, transport http.RoundTripper) ([]byte, error) {
+	u, err := url.Parse(uStr)
+	if err != nil {
+		return nil, fmt.Errorf("feeds: invalid url: %w", err)
+	}
+	check := func(ctx context.Context, u *url.URL) error {
+		if err := domains.Check(u.Hostname()); err != nil {
+			return err
+		}
+		return netguard.CheckURL(ctx, u, lookup)
+	}
+	if err := check(ctx, u); err != nil {
+		return nil, fmt.Errorf("feeds: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, feedTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml;q=0.9, */*;q=0.8")
+	client := &http.Client{
+		Transport: transportOr(transport),
+		CheckRedirect: func(r *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errors.New("too many redirects")
+			}
+			return check(r.Context(), r.URL)
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("feeds: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("feeds: %s: HTTP %s", u.Redacted(), resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxFeedBytes))
+}
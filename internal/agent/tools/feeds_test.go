@@ -0,0 +1,47 @@
+package tools
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kr0nicas/picobot/internal/feeds"
+)
+
+func TestFeedsTool(t *testing.T) {
+	items := `<item><title>First</title><link>https://blog.example/1</link><guid>1</guid></item>`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `<rss><channel><title>Blog</title>`+items+`</channel></rss>`)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	ft := NewFeedsTool(dir)
+	ft.lookup = func(ctx context.Context, host string) ([]net.IP, error) {
+		return []net.IP{net.ParseIP("93.184.216.34")}, nil
+	}
+	ft.transport = toServer{srv}
+	ctx := WithChat(context.Background(), "telegram", "42")
+
+	if _, err := ft.Execute(ctx, map[string]interface{}{"action": "subscribe", "url": "https://blog.example/feed.xml"}); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	subs, _ := feeds.NewStore(feeds.StorePath(dir)).List()
+	if len(subs) != 1 || subs[0].Name != "Blog" || subs[0].Channel != "telegram" || subs[0].ChatID != "42" {
+		t.Fatalf("unexpected subscriptions: %+v", subs)
+	}
+
+	items = `<item><title>Second</title><link>https://blog.example/2</link><guid>2</guid></item>` + items
+	out, err := ft.Execute(ctx, map[string]interface{}{"action": "fetch_new"})
+	if err != nil || !strings.Contains(out, "Second https://blog.example/2") || strings.Contains(out, "First") {
+		t.Fatalf("unexpected fetch_new result %q (%v)", out, err)
+	}
+
+	if _, err := ft.Execute(ctx, map[string]interface{}{"action": "subscribe", "url": "http://127.0.0.1/feed"}); err == nil {
+		t.Fatalf("expected a private feed URL to be refused")
+	}
+}
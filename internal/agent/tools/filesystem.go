@@ -5,14 +5,23 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+
+	"github.com/kr0nicas/picobot/internal/workspace"
 )
 
-// FilesystemTool provides read/write/list operations within the filesystem.
+// FilesystemTool provides file operations within the workspace: read, write,
+// list, and targeted changes (edit, append, delete, move, mkdir) and searches
+// (glob, grep) so the model doesn't rewrite whole files to change a line.
 // All operations are sandboxed to the workspace directory using os.Root (Go 1.24+),
 // which provides kernel-enforced path containment via openat() syscalls.
 // This prevents symlink escapes, TOCTOU races, and path traversal attacks.
+//
+// Paths are also checked with workspace.Jail first, for clear errors and so
+// the strict symlink mode applies.
 type FilesystemTool struct {
 	root *os.Root
+	jail workspace.Jail
 }
 
 // NewFilesystemTool opens an os.Root anchored at workspaceDir.
@@ -26,16 +35,21 @@ func NewFilesystemTool(workspaceDir string) (*FilesystemTool, error) {
 	if err != nil {
 		return nil, fmt.Errorf("filesystem: open workspace root: %w", err)
 	}
-	return &FilesystemTool{root: root}, nil
+	return &FilesystemTool{root: root, jail: workspace.Jail{Root: absDir}}, nil
 }
 
+// SetStrictPaths makes the tool refuse paths through symlinks.
+func (t *FilesystemTool) SetStrictPaths(strict bool) { t.jail.Strict = strict }
+
 // Close releases the underlying os.Root file descriptor.
 func (t *FilesystemTool) Close() error {
 	return t.root.Close()
 }
 
-func (t *FilesystemTool) Name() string        { return "filesystem" }
-func (t *FilesystemTool) Description() string { return "Read, write, and list files in the workspace" }
+func (t *FilesystemTool) Name() string { return "filesystem" }
+func (t *FilesystemTool) Description() string {
+	return "Read, write, list, edit, append to, delete, move and search files in the workspace. Prefer edit over write to change part of a file."
+}
 
 func (t *FilesystemTool) Parameters() map[string]interface{} {
 	return map[string]interface{}{
@@ -44,15 +58,56 @@ func (t *FilesystemTool) Parameters() map[string]interface{} {
 			"action": map[string]interface{}{
 				"type":        "string",
 				"description": "The filesystem operation to perform",
-				"enum":        []string{"read", "write", "list"},
+				"enum":        []string{"read", "write", "list", "edit", "append", "delete", "move", "mkdir", "glob", "grep"},
 			},
 			"path": map[string]interface{}{
 				"type":        "string",
-				"description": "The file or directory path (relative to workspace)",
+				"description": "The file or directory path (relative to workspace); for glob and grep the directory to search (default: workspace root)",
 			},
 			"content": map[string]interface{}{
 				"type":        "string",
-				"description": "Content to write (required when action is 'write')",
+				"description": "write/append: the content; edit with start_line: the replacement lines",
+			},
+			"old_string": map[string]interface{}{
+				"type":        "string",
+				"description": "edit: exact text to replace; must occur once unless replace_all is set",
+			},
+			"new_string": map[string]interface{}{
+				"type":        "string",
+				"description": "edit: the replacement text",
+			},
+			"replace_all": map[string]interface{}{
+				"type":        "boolean",
+				"description": "edit: replace every occurrence of old_string",
+			},
+			"start_line": map[string]interface{}{
+				"type":        "integer",
+				"description": "edit: first line (1-based) of the range replaced by content; read: first line to return",
+			},
+			"end_line": map[string]interface{}{
+				"type":        "integer",
+				"description": "edit: last line of the range (default start_line); start_line-1 inserts content before start_line; read: last line to return (default end of file)",
+			},
+			"to": map[string]interface{}{
+				"type":        "string",
+				"description": "move: the destination path",
+			},
+			"recursive": map[string]interface{}{
+				"type":        "boolean",
+				"description": "delete: remove a directory and everything in it",
+			},
+			"pattern": map[string]interface{}{
+				"type":        "string",
+				"description": "glob: a pattern such as \"**/*.py\"; grep: a regular expression",
+			},
+			"encoding": map[string]interface{}{
+				"type":        "string",
+				"description": "read/write/append: \"base64\" for binary files such as images, zips or databases (default text)",
+				"enum":        []string{"text", "base64"},
+			},
+			"include": map[string]interface{}{
+				"type":        "string",
+				"description": "grep: only search files whose path matches this glob, e.g. \"*.go\"",
 			},
 		},
 		"required": []string{"action", "path"},
@@ -81,14 +136,22 @@ func (t *FilesystemTool) Execute(ctx context.Context, args map[string]interface{
 	if pathStr == "" {
 		pathStr = "."
 	}
+	if _, err := t.jail.Resolve(pathStr); err != nil {
+		return "", fmt.Errorf("filesystem: %w", err)
+	}
+	encoding, _ := args["encoding"].(string)
+	if encoding != "" && encoding != "text" && encoding != "base64" {
+		return "", fmt.Errorf("filesystem: unknown encoding %q (want text or base64)", encoding)
+	}
 
 	switch action {
 	case "read":
-		b, err := t.root.ReadFile(pathStr)
-		if err != nil {
-			return "", err
+		out, err := t.read(pathStr, encoding)
+		if sl, ok := args["start_line"].(float64); ok && err == nil && encoding != "base64" {
+			el, _ := args["end_line"].(float64)
+			return readLines(out, int(sl), int(el))
 		}
-		return string(b), nil
+		return out, err
 	case "write":
 		contentRaw, _ := args["content"]
 		content := ""
@@ -98,6 +161,10 @@ func (t *FilesystemTool) Execute(ctx context.Context, args map[string]interface{
 		default:
 			return "", fmt.Errorf("filesystem: 'content' must be a string")
 		}
+		data, err := decodeContent(content, encoding)
+		if err != nil {
+			return "", err
+		}
 		// Create parent directories if needed
 		dir := filepath.Dir(pathStr)
 		if dir != "." {
@@ -105,7 +172,7 @@ func (t *FilesystemTool) Execute(ctx context.Context, args map[string]interface{
 				return "", err
 			}
 		}
-		if err := t.root.WriteFile(pathStr, []byte(content), 0o644); err != nil {
+		if err := t.root.WriteFile(pathStr, data, 0o644); err != nil {
 			return "", err
 		}
 		return "written", nil
@@ -128,7 +195,83 @@ func (t *FilesystemTool) Execute(ctx context.Context, args map[string]interface{
 			out += name + "\n"
 		}
 		return out, nil
+	case "edit":
+		return t.edit(pathStr, args)
+	case "append":
+		content, _ := args["content"].(string)
+		data, err := decodeContent(content, encoding)
+		if err != nil {
+			return "", err
+		}
+		return t.appendFile(pathStr, data)
+	case "delete":
+		recursive, _ := args["recursive"].(bool)
+		return t.delete(pathStr, recursive)
+	case "move":
+		to, _ := args["to"].(string)
+		if _, err := t.jail.Resolve(to); err != nil {
+			return "", fmt.Errorf("filesystem: %w", err)
+		}
+		return t.move(pathStr, to)
+	case "mkdir":
+		if err := t.root.MkdirAll(pathStr, 0o755); err != nil {
+			return "", err
+		}
+		return "created", nil
+	case "glob":
+		pattern, _ := args["pattern"].(string)
+		return t.glob(pathStr, pattern)
+	case "grep":
+		pattern, _ := args["pattern"].(string)
+		include, _ := args["include"].(string)
+		return t.grep(ctx, pathStr, pattern, include)
 	default:
 		return "", fmt.Errorf("filesystem: unknown action %s", action)
 	}
 }
+
+// RequiresApproval implements ApprovalRequirer: overwriting or deleting an
+// existing file outside a project folder (project-*/, where the agent keeps
+// its own deliverables) needs the user's approval.
+func (t *FilesystemTool) RequiresApproval(args map[string]interface{}) string {
+	action, _ := args["action"].(string)
+	p, _ := args["path"].(string)
+	switch action {
+	case "write":
+		if !inProjectFolder(p) && t.exists(p) {
+			return fmt.Sprintf("overwrite %s", p)
+		}
+	case "edit", "append":
+		if !inProjectFolder(p) && t.exists(p) {
+			return fmt.Sprintf("%s %s", action, p)
+		}
+	case "delete":
+		if inProjectFolder(p) || !t.exists(p) {
+			return ""
+		}
+		if recursive, _ := args["recursive"].(bool); recursive {
+			return fmt.Sprintf("delete %s and everything in it", p)
+		}
+		return fmt.Sprintf("delete %s", p)
+	case "move":
+		to, _ := args["to"].(string)
+		// moving removes the file from its old place
+		if (!inProjectFolder(p) && t.exists(p)) || (!inProjectFolder(to) && t.exists(to)) {
+			return fmt.Sprintf("move %s to %s", p, to)
+		}
+	}
+	return ""
+}
+
+func (t *FilesystemTool) exists(p string) bool {
+	_, err := t.root.Lstat(p)
+	return err == nil
+}
+
+// inProjectFolder reports whether p lies inside a project-* folder at the
+// workspace root.
+func inProjectFolder(p string) bool {
+	clean := filepath.ToSlash(filepath.Clean(p))
+	first, _, found := strings.Cut(clean, "/")
+	return found && strings.HasPrefix(first, "project-")
+}
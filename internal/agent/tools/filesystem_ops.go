@@ -0,0 +1,394 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits of the filesystem tool's binary reads and searches.
+const (
+	maxBase64Read  = 1 << 20
+	maxGlobResults = 500
+	maxGrepMatches = 200
+	maxGrepFile    = 2 << 20
+	maxGrepLine    = 300
+)
+
+// errSearchFull stops a walk once a search has enough results.
+var errSearchFull = errors.New("search limit reached")
+
+// read returns a file's content. Binary files, and any file read with the
+// base64 encoding, are reported with their MIME type and size; the content
+// of binary files is only returned in base64.
+func (t *FilesystemTool) read(p, encoding string) (string, error) {
+	info, err := t.root.Stat(p)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("filesystem: %s is a directory; use list", p)
+	}
+	if encoding != "base64" {
+		b, err := t.root.ReadFile(p)
+		if err != nil {
+			return "", err
+		}
+		if isText(b) {
+			return string(b), nil
+		}
+		return fmt.Sprintf("%s is a binary file (%s, %d bytes). Read it with encoding \"base64\" or process it with a script.", p, fileMIME(p, b), len(b)), nil
+	}
+	if info.Size() > maxBase64Read {
+		return "", fmt.Errorf("filesystem: %s is %d bytes; base64 reads are limited to %d bytes, process it with a script instead", p, info.Size(), maxBase64Read)
+	}
+	b, err := t.root.ReadFile(p)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("mime: %s\nsize: %d bytes\n\n%s", fileMIME(p, b), len(b), base64.StdEncoding.EncodeToString(b)), nil
+}
+
+// isText reports whether b looks like text: valid UTF-8 without NUL bytes.
+func isText(b []byte) bool {
+	return utf8.Valid(b) && bytes.IndexByte(b, 0) < 0
+}
+
+// fileMIME returns the MIME type of a file from its extension, or sniffed
+// from its content.
+func fileMIME(name string, data []byte) string {
+	if t := mime.TypeByExtension(filepath.Ext(name)); t != "" {
+		return t
+	}
+	return http.DetectContentType(data)
+}
+
+// decodeContent returns the bytes to write for content given in encoding.
+// Base64 content may be wrapped in lines or given as a data: URL.
+func decodeContent(content, encoding string) ([]byte, error) {
+	if encoding != "base64" {
+		return []byte(content), nil
+	}
+	if strings.HasPrefix(content, "data:") {
+		if _, rest, ok := strings.Cut(content, ";base64,"); ok {
+			content = rest
+		}
+	}
+	content = strings.Join(strings.Fields(content), "")
+	b, err := base64.StdEncoding.DecodeString(content)
+	if err != nil {
+		if b, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(content, "=")); err != nil {
+			return nil, fmt.Errorf("filesystem: invalid base64 content: %w", err)
+		}
+	}
+	return b, nil
+}
+
+// edit changes part of a file: a find/replace with old_string and
+// new_string, or a line range replaced by content.
+func (t *FilesystemTool) edit(p string, args map[string]interface{}) (string, error) {
+	b, err := t.root.ReadFile(p)
+	if err != nil {
+		return "", err
+	}
+	if !isText(b) {
+		return "", fmt.Errorf("filesystem: %s is a binary file and cannot be edited; write it with encoding base64", p)
+	}
+	text := string(b)
+	info, err := t.root.Stat(p)
+	if err != nil {
+		return "", err
+	}
+
+	var out, result string
+	if old, ok := args["old_string"].(string); ok {
+		if old == "" {
+			return "", fmt.Errorf("filesystem: edit: 'old_string' must not be empty")
+		}
+		newStr, _ := args["new_string"].(string)
+		n := strings.Count(text, old)
+		all, _ := args["replace_all"].(bool)
+		switch {
+		case n == 0:
+			return "", fmt.Errorf("filesystem: edit: old_string not found in %s", p)
+		case n > 1 && !all:
+			return "", fmt.Errorf("filesystem: edit: old_string occurs %d times in %s; include more context or set replace_all", n, p)
+		}
+		if !all {
+			n = 1
+		}
+		out = strings.Replace(text, old, newStr, n)
+		result = fmt.Sprintf("edited %s: %d replacement(s)", p, n)
+	} else if sl, ok := args["start_line"].(float64); ok {
+		content, _ := args["content"].(string)
+		start := int(sl)
+		end := start
+		if el, ok := args["end_line"].(float64); ok {
+			end = int(el)
+		}
+		if out, err = replaceLines(text, start, end, content); err != nil {
+			return "", fmt.Errorf("filesystem: edit: %w", err)
+		}
+		if end < start {
+			result = fmt.Sprintf("edited %s: inserted before line %d", p, start)
+		} else {
+			result = fmt.Sprintf("edited %s: replaced lines %d-%d", p, start, end)
+		}
+	} else {
+		return "", fmt.Errorf("filesystem: edit needs 'old_string' and 'new_string', or 'start_line' and 'content'")
+	}
+	if err := t.root.WriteFile(p, []byte(out), info.Mode().Perm()); err != nil {
+		return "", err
+	}
+	return result, nil
+}
+
+// readLines returns lines start..end (1-based, inclusive) of text, headed by
+// the range; end 0 is the last line.
+func readLines(text string, start, end int) (string, error) {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	n := len(lines)
+	if end <= 0 || end > n {
+		end = n
+	}
+	if start < 1 || start > end {
+		return "", fmt.Errorf("filesystem: read: line range %d-%d is outside the file's %d lines", start, end, n)
+	}
+	return fmt.Sprintf("lines %d-%d of %d:\n%s", start, end, n, strings.Join(lines[start-1:end], "")), nil
+}
+
+// replaceLines replaces lines start..end (1-based, inclusive) of text with
+// content. end == start-1 inserts content before line start.
+func replaceLines(text string, start, end int, content string) (string, error) {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	n := len(lines)
+	if start < 1 || start > n+1 || end < start-1 || end > n {
+		return "", fmt.Errorf("line range %d-%d is outside the file's %d lines", start, end, n)
+	}
+	if content != "" && !strings.HasSuffix(content, "\n") && (end < n || strings.HasSuffix(text, "\n")) {
+		content += "\n"
+	}
+	// a file without a final newline gets one before appended lines
+	if start == n+1 && n > 0 && !strings.HasSuffix(lines[n-1], "\n") {
+		lines[n-1] += "\n"
+	}
+	return strings.Join(lines[:start-1], "") + content + strings.Join(lines[end:], ""), nil
+}
+
+func (t *FilesystemTool) appendFile(p string, content []byte) (string, error) {
+	if dir := filepath.Dir(p); dir != "." {
+		if err := t.root.MkdirAll(dir, 0o755); err != nil {
+			return "", err
+		}
+	}
+	f, err := t.root.OpenFile(p, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	return "appended", nil
+}
+
+func (t *FilesystemTool) delete(p string, recursive bool) (string, error) {
+	if filepath.Clean(p) == "." {
+		return "", fmt.Errorf("filesystem: refusing to delete the workspace root")
+	}
+	var err error
+	if recursive {
+		if _, err = t.root.Lstat(p); err == nil {
+			err = t.root.RemoveAll(p)
+		}
+	} else {
+		err = t.root.Remove(p)
+	}
+	if err != nil {
+		return "", err
+	}
+	return "deleted", nil
+}
+
+func (t *FilesystemTool) move(from, to string) (string, error) {
+	if to == "" {
+		return "", fmt.Errorf("filesystem: move needs 'to'")
+	}
+	if filepath.Clean(from) == "." {
+		return "", fmt.Errorf("filesystem: refusing to move the workspace root")
+	}
+	if dir := filepath.Dir(to); dir != "." {
+		if err := t.root.MkdirAll(dir, 0o755); err != nil {
+			return "", err
+		}
+	}
+	if err := t.root.Rename(from, to); err != nil {
+		return "", err
+	}
+	return "moved", nil
+}
+
+// walk calls fn for every file below dir, skipping .git directories.
+func (t *FilesystemTool) walk(dir string, fn func(p string, d fs.DirEntry) error) error {
+	return fs.WalkDir(t.root.FS(), path.Clean(filepath.ToSlash(dir)), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		return fn(p, d)
+	})
+}
+
+func (t *FilesystemTool) glob(dir, pattern string) (string, error) {
+	if pattern == "" {
+		return "", fmt.Errorf("filesystem: glob needs 'pattern'")
+	}
+	if _, err := path.Match(strings.ReplaceAll(pattern, "**", "*"), ""); err != nil {
+		return "", fmt.Errorf("filesystem: glob: bad pattern %q", pattern)
+	}
+	base := path.Clean(filepath.ToSlash(dir))
+	var matches []string
+	err := t.walk(dir, func(p string, d fs.DirEntry) error {
+		rel := strings.TrimPrefix(strings.TrimPrefix(p, base), "/")
+		if base == "." {
+			rel = p
+		}
+		if matchGlob(pattern, rel) {
+			if len(matches) == maxGlobResults {
+				return errSearchFull
+			}
+			matches = append(matches, p)
+		}
+		return nil
+	})
+	if err != nil && err != errSearchFull {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "no matches", nil
+	}
+	out := strings.Join(matches, "\n")
+	if err == errSearchFull {
+		out += fmt.Sprintf("\n[stopped after %d matches]", maxGlobResults)
+	}
+	return out, nil
+}
+
+// matchGlob matches a slash-separated path against a pattern in which "**"
+// stands for any number of directories. A pattern without a slash matches
+// the file name in any directory.
+func matchGlob(pattern, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	return matchParts(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchParts(pat, parts []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			for i := 0; i <= len(parts); i++ {
+				if matchParts(pat[1:], parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], parts[0]); !ok {
+			return false
+		}
+		pat, parts = pat[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
+
+func (t *FilesystemTool) grep(ctx context.Context, dir, pattern, include string) (string, error) {
+	if pattern == "" {
+		return "", fmt.Errorf("filesystem: grep needs 'pattern'")
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", fmt.Errorf("filesystem: grep: %w", err)
+	}
+	var sb strings.Builder
+	matches := 0
+	search := func(p string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		info, err := t.root.Stat(p)
+		if err != nil || info.Size() > maxGrepFile {
+			return nil
+		}
+		b, err := t.root.ReadFile(p)
+		if err != nil || bytes.IndexByte(b, 0) >= 0 { // unreadable or binary
+			return nil
+		}
+		for i, line := range strings.Split(string(b), "\n") {
+			if !re.MatchString(line) {
+				continue
+			}
+			if matches == maxGrepMatches {
+				return errSearchFull
+			}
+			matches++
+			line = strings.TrimRight(line, "\r")
+			if len(line) > maxGrepLine {
+				line = line[:maxGrepLine] + "..."
+			}
+			fmt.Fprintf(&sb, "%s:%d: %s\n", p, i+1, line)
+		}
+		return nil
+	}
+
+	if info, statErr := t.root.Stat(dir); statErr == nil && !info.IsDir() {
+		err = search(filepath.ToSlash(filepath.Clean(dir)))
+	} else {
+		err = t.walk(dir, func(p string, d fs.DirEntry) error {
+			if include != "" && !matchGlob(include, p) {
+				return nil
+			}
+			return search(p)
+		})
+	}
+	if err != nil && err != errSearchFull {
+		return "", err
+	}
+	if matches == 0 {
+		return "no matches", nil
+	}
+	out := strings.TrimRight(sb.String(), "\n")
+	if err == errSearchFull {
+		out += fmt.Sprintf("\n[stopped after %d matches]", maxGrepMatches)
+	}
+	return out, nil
+}
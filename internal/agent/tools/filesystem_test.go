@@ -0,0 +1,154 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFilesystemRejectsSymlinkEscape(t *testing.T) {
+	d := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(d, "link")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	fs, err := NewFilesystemTool(d)
+	if err != nil {
+		t.Fatalf("NewFilesystemTool: %v", err)
+	}
+	defer fs.Close()
+	_, err = fs.Execute(context.Background(), map[string]interface{}{"action": "write", "path": "link/pwned.txt", "content": "x"})
+	if err == nil {
+		t.Fatalf("expected write through symlink to be rejected")
+	}
+	if _, statErr := os.Stat(filepath.Join(outside, "pwned.txt")); statErr == nil {
+		t.Fatalf("file was written outside the workspace")
+	}
+}
+
+func TestFilesystemEditAndSearch(t *testing.T) {
+	d := t.TempDir()
+	fs, err := NewFilesystemTool(d)
+	if err != nil {
+		t.Fatalf("NewFilesystemTool: %v", err)
+	}
+	defer fs.Close()
+	ctx := context.Background()
+	run := func(args map[string]interface{}) string {
+		t.Helper()
+		out, err := fs.Execute(ctx, args)
+		if err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+		return out
+	}
+	read := func(p string) string {
+		b, _ := os.ReadFile(filepath.Join(d, p))
+		return string(b)
+	}
+
+	run(map[string]interface{}{"action": "write", "path": "src/app.py", "content": "a = 1\nb = 2\nc = 3\n"})
+	run(map[string]interface{}{"action": "edit", "path": "src/app.py", "old_string": "b = 2", "new_string": "b = 20"})
+	run(map[string]interface{}{"action": "edit", "path": "src/app.py", "start_line": float64(3), "content": "c = 30"})
+	run(map[string]interface{}{"action": "edit", "path": "src/app.py", "start_line": float64(1), "end_line": float64(0), "content": "# header\n"})
+	run(map[string]interface{}{"action": "append", "path": "src/app.py", "content": "d = 4\n"})
+	if got, want := read("src/app.py"), "# header\na = 1\nb = 20\nc = 30\nd = 4\n"; got != want {
+		t.Fatalf("unexpected file after edits:\n%s\nwant:\n%s", got, want)
+	}
+	if got, want := run(map[string]interface{}{"action": "read", "path": "src/app.py", "start_line": float64(2), "end_line": float64(3)}), "lines 2-3 of 5:\na = 1\nb = 20\n"; got != want {
+		t.Fatalf("unexpected line range %q, want %q", got, want)
+	}
+	if _, err := fs.Execute(ctx, map[string]interface{}{"action": "edit", "path": "src/app.py", "old_string": " = ", "new_string": "="}); err == nil {
+		t.Fatalf("expected an ambiguous old_string to be rejected")
+	}
+
+	run(map[string]interface{}{"action": "mkdir", "path": "docs/notes"})
+	run(map[string]interface{}{"action": "move", "path": "src/app.py", "to": "lib/app.py"})
+	if got := run(map[string]interface{}{"action": "glob", "pattern": "**/*.py"}); got != "lib/app.py" {
+		t.Fatalf("unexpected glob result %q", got)
+	}
+	if got := run(map[string]interface{}{"action": "grep", "pattern": `^c = \d+`, "include": "*.py"}); got != "lib/app.py:4: c = 30" {
+		t.Fatalf("unexpected grep result %q", got)
+	}
+
+	if fs.RequiresApproval(map[string]interface{}{"action": "move", "path": "lib/app.py", "to": "x.py"}) == "" {
+		t.Fatalf("expected moving a file outside a project folder to need approval")
+	}
+	for _, action := range []string{"edit", "append"} {
+		if fs.RequiresApproval(map[string]interface{}{"action": action, "path": "lib/app.py", "content": "e = 5\n"}) == "" {
+			t.Fatalf("expected %s of a file outside a project folder to need approval", action)
+		}
+		if fs.RequiresApproval(map[string]interface{}{"action": action, "path": "lib/new.py", "content": "e = 5\n"}) != "" {
+			t.Fatalf("expected %s of a new file not to need approval", action)
+		}
+	}
+	run(map[string]interface{}{"action": "delete", "path": "lib", "recursive": true})
+	if _, err := os.Stat(filepath.Join(d, "lib")); !os.IsNotExist(err) {
+		t.Fatalf("expected lib to be deleted, got %v", err)
+	}
+	if _, err := fs.Execute(ctx, map[string]interface{}{"action": "delete", "path": ".", "recursive": true}); err == nil {
+		t.Fatalf("expected deleting the workspace root to be refused")
+	}
+}
+
+func TestReplaceLines(t *testing.T) {
+	for _, tc := range []struct {
+		text       string
+		start, end int
+		content    string
+		want       string
+	}{
+		{"a\nb\nc\n", 2, 2, "B", "a\nB\nc\n"},
+		{"a\nb\nc", 3, 3, "C", "a\nb\nC"},
+		{"a\nb\n", 3, 2, "c", "a\nb\nc\n"},
+		{"a\nb", 3, 2, "c", "a\nb\nc"},
+		{"a\nb\nc\n", 1, 2, "", "c\n"},
+	} {
+		got, err := replaceLines(tc.text, tc.start, tc.end, tc.content)
+		if err != nil || got != tc.want {
+			t.Fatalf("replaceLines(%q, %d, %d, %q) = %q, %v; want %q", tc.text, tc.start, tc.end, tc.content, got, err, tc.want)
+		}
+	}
+	if _, err := replaceLines("a\n", 3, 3, "x"); err == nil {
+		t.Fatalf("expected an out-of-range line to be rejected")
+	}
+}
+
+func TestFilesystemBase64(t *testing.T) {
+	d := t.TempDir()
+	fs, err := NewFilesystemTool(d)
+	if err != nil {
+		t.Fatalf("NewFilesystemTool: %v", err)
+	}
+	defer fs.Close()
+	ctx := context.Background()
+
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	enc := base64.StdEncoding.EncodeToString(png)
+	if _, err := fs.Execute(ctx, map[string]interface{}{"action": "write", "path": "img/a.png", "content": "data:image/png;base64," + enc, "encoding": "base64"}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(d, "img/a.png")); !bytes.Equal(b, png) {
+		t.Fatalf("unexpected file content %q", b)
+	}
+
+	out, err := fs.Execute(ctx, map[string]interface{}{"action": "read", "path": "img/a.png", "encoding": "base64"})
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if want := fmt.Sprintf("mime: image/png\nsize: %d bytes\n\n%s", len(png), enc); out != want {
+		t.Fatalf("unexpected base64 read:\n%s\nwant:\n%s", out, want)
+	}
+	out, err = fs.Execute(ctx, map[string]interface{}{"action": "read", "path": "img/a.png"})
+	if err != nil || !strings.Contains(out, "binary file (image/png, 16 bytes)") {
+		t.Fatalf("expected a text read of a binary file to describe it, got %q (%v)", out, err)
+	}
+	if _, err := fs.Execute(ctx, map[string]interface{}{"action": "write", "path": "b.bin", "content": "not base64!", "encoding": "base64"}); err == nil {
+		t.Fatalf("expected invalid base64 to be rejected")
+	}
+}
@@ -0,0 +1,92 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kr0nicas/picobot/internal/agent/memory"
+)
+
+// ForgetTool deletes memories the user wants forgotten, from the memory
+// database, MEMORY.md and the daily notes.
+type ForgetTool struct {
+	mem *memory.MemoryStore
+}
+
+func NewForgetTool(mem *memory.MemoryStore) *ForgetTool {
+	return &ForgetTool{mem: mem}
+}
+
+func (t *ForgetTool) Name() string { return "forget" }
+
+func (t *ForgetTool) Description() string {
+	return "Delete every memory (daily notes and long-term memory) containing some text, when the user asks to forget something. The user confirms the matching memories first."
+}
+
+func (t *ForgetTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "Text the memories to forget contain (case-insensitive), specific enough not to match others",
+			},
+		},
+		"required": []string{"query"},
+	}
+}
+
+// RequiresApproval implements ApprovalRequirer: the user sees the memories
+// that would be deleted.
+func (t *ForgetTool) RequiresApproval(args map[string]interface{}) string {
+	query, _ := args["query"].(string)
+	if strings.TrimSpace(query) == "" {
+		return ""
+	}
+	items, err := t.mem.Query(memory.Query{Keyword: query, Limit: 1000})
+	if err != nil || len(items) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("forget %s: %s", countMemories(len(items)), summarizeMemories(items))
+}
+
+func (t *ForgetTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	query, _ := args["query"].(string)
+	if strings.TrimSpace(query) == "" {
+		return "", fmt.Errorf("forget: query (string) is required")
+	}
+	items, err := t.mem.Forget(query)
+	if err != nil {
+		return "", err
+	}
+	if len(items) == 0 {
+		return fmt.Sprintf("No memories contain %q.", query), nil
+	}
+	return fmt.Sprintf("Forgot %s: %s", countMemories(len(items)), summarizeMemories(items)), nil
+}
+
+func countMemories(n int) string {
+	if n == 1 {
+		return "1 memory"
+	}
+	return fmt.Sprintf("%d memories", n)
+}
+
+// summarizeMemories quotes the first few items' texts.
+func summarizeMemories(items []memory.MemoryItem) string {
+	const show = 5
+	var quoted []string
+	for i, it := range items {
+		if i == show {
+			quoted = append(quoted, fmt.Sprintf("and %d more", len(items)-show))
+			break
+		}
+		text := it.Text
+		if r := []rune(text); len(r) > 80 {
+			text = string(r[:80]) + "…"
+		}
+		quoted = append(quoted, fmt.Sprintf("%q", text))
+	}
+	return strings.Join(quoted, ", ")
+}
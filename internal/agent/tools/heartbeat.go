@@ -0,0 +1,149 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/heartbeat"
+)
+
+// HeartbeatTool manages the periodic tasks the heartbeat runs, kept in the
+// workspace's heartbeat.json.
+// Args: {"action": "add", "name": "server", "prompt": "Check https://example.com/health", "every": "30m"}
+type HeartbeatTool struct {
+	store *heartbeat.TaskStore
+	loc   *time.Location
+}
+
+// NewHeartbeatTool returns a tool for the tasks in store, showing cron
+// schedules in loc.
+func NewHeartbeatTool(store *heartbeat.TaskStore, loc *time.Location) *HeartbeatTool {
+	if loc == nil {
+		loc = time.Local
+	}
+	return &HeartbeatTool{store: store, loc: loc}
+}
+
+func (t *HeartbeatTool) Name() string { return "heartbeat" }
+func (t *HeartbeatTool) Description() string {
+	return "Manage the heartbeat's periodic background tasks: prompts you run on your own every interval or on a cron schedule. Actions: list, add, update, remove, enable, disable."
+}
+
+func (t *HeartbeatTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"description": "list, add, update, remove, enable or disable",
+				"enum":        []string{"list", "add", "update", "remove", "enable", "disable"},
+			},
+			"name": map[string]interface{}{
+				"type":        "string",
+				"description": "Task name",
+			},
+			"prompt": map[string]interface{}{
+				"type":        "string",
+				"description": "What to do when the task runs; to tell a user about the result, include the channel and chat to message",
+			},
+			"every": map[string]interface{}{
+				"type":        "string",
+				"description": "Interval as a Go duration, at least 1m, e.g. '30m', '6h'",
+			},
+			"schedule": map[string]interface{}{
+				"type":        "string",
+				"description": "Cron expression instead of an interval, e.g. '0 8 * * mon-fri'",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *HeartbeatTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	action, _ := args["action"].(string)
+	name, _ := args["name"].(string)
+	prompt, _ := args["prompt"].(string)
+	every, _ := args["every"].(string)
+	schedule, _ := args["schedule"].(string)
+	if action != "list" && name == "" {
+		return "", fmt.Errorf("heartbeat %s: 'name' is required", action)
+	}
+
+	switch action {
+	case "list":
+		tasks, err := t.store.List()
+		if err != nil {
+			return "", err
+		}
+		if len(tasks) == 0 {
+			return "No heartbeat tasks.", nil
+		}
+		lines := make([]string, 0, len(tasks))
+		for _, task := range tasks {
+			lines = append(lines, t.format(task))
+		}
+		return strings.Join(lines, "\n"), nil
+
+	case "add":
+		task := heartbeat.Task{Name: name, Prompt: prompt, Every: every, Schedule: schedule}
+		if err := t.store.Add(task); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Added heartbeat task %q.", name), nil
+
+	case "update", "enable", "disable":
+		task, err := t.store.Update(name, func(task *heartbeat.Task) {
+			switch action {
+			case "enable":
+				task.Disabled, task.Failures = false, 0
+			case "disable":
+				task.Disabled = true
+			default:
+				if prompt != "" {
+					task.Prompt = prompt
+				}
+				if every != "" {
+					task.Every, task.Schedule = every, ""
+				} else if schedule != "" {
+					task.Every, task.Schedule = "", schedule
+				}
+			}
+		})
+		if err != nil {
+			return "", err
+		}
+		return t.format(task), nil
+
+	case "remove":
+		ok, err := t.store.Remove(name)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return fmt.Sprintf("No heartbeat task %q.", name), nil
+		}
+		return fmt.Sprintf("Removed heartbeat task %q.", name), nil
+
+	default:
+		return "", fmt.Errorf("heartbeat: unknown action %q", action)
+	}
+}
+
+// format renders a task on one line.
+func (t *HeartbeatTool) format(task heartbeat.Task) string {
+	when := "every " + task.Every
+	if task.Schedule != "" {
+		when = fmt.Sprintf("on %q", task.Schedule)
+	}
+	state := "next " + task.Next(t.loc).In(t.loc).Format("Mon Jan 2 15:04 MST")
+	if task.Disabled {
+		state = "disabled"
+	}
+	line := fmt.Sprintf("- %s (%s, %s): %s", task.Name, when, state, task.Prompt)
+	if task.LastError != "" {
+		line += fmt.Sprintf(" [%d failed runs, last error: %s]", task.Failures, task.LastError)
+	}
+	return line
+}
@@ -0,0 +1,33 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/heartbeat"
+)
+
+func TestHeartbeatTool(t *testing.T) {
+	ht := NewHeartbeatTool(heartbeat.NewTaskStore(heartbeat.TaskPath(t.TempDir())), time.UTC)
+	ctx := context.Background()
+
+	if _, err := ht.Execute(ctx, map[string]interface{}{"action": "add", "name": "news", "prompt": "summarize the news", "schedule": "0 7 * * *"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ht.Execute(ctx, map[string]interface{}{"action": "add", "name": "bad", "prompt": "x", "every": "5s"}); err == nil {
+		t.Fatal("expected too short intervals to be rejected")
+	}
+	out, err := ht.Execute(ctx, map[string]interface{}{"action": "update", "name": "news", "every": "2h"})
+	if err != nil || !strings.Contains(out, "news (every 2h") {
+		t.Fatalf("unexpected update result %q, %v", out, err)
+	}
+	ht.Execute(ctx, map[string]interface{}{"action": "disable", "name": "news"})
+	if out, _ := ht.Execute(ctx, map[string]interface{}{"action": "list"}); !strings.Contains(out, "disabled") {
+		t.Fatalf("expected the task to be disabled:\n%s", out)
+	}
+	if out, _ := ht.Execute(ctx, map[string]interface{}{"action": "remove", "name": "news"}); out != `Removed heartbeat task "news".` {
+		t.Fatalf("unexpected remove result %q", out)
+	}
+}
@@ -0,0 +1,251 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/netguard"
+)
+
+// Defaults of the http_request tool.
+const (
+	DefaultHTTPTimeout     = 30 * time.Second
+	DefaultHTTPMaxResponse = 100 << 10
+	maxHTTPRequestTimeoutS = 120
+	redactedValue          = "[REDACTED]"
+)
+
+var httpMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+
+// HTTPRequestTool calls HTTP APIs with any method, headers and body.
+// Args: {"url": "...", "method": "POST", "headers": {...}, "json": {...}}
+//
+// It applies the same domain policy and SSRF checks as the web tool, caps the
+// response body, and never logs the values of credential headers such as
+// Authorization.
+type HTTPRequestTool struct {
+	lookup    netguard.Lookup
+	domains   DomainPolicy
+	timeout   time.Duration
+	maxBytes  int
+	transport http.RoundTripper // nil means netguard.Transport
+}
+
+func NewHTTPRequestTool() *HTTPRequestTool {
+	return &HTTPRequestTool{lookup: netguard.DefaultLookup, timeout: DefaultHTTPTimeout, maxBytes: DefaultHTTPMaxResponse}
+}
+
+// UntrustedOutput marks responses as untrusted data.
+func (t *HTTPRequestTool) UntrustedOutput() bool { return true }
+
+// SetDomainPolicy restricts the hosts the tool may call.
+func (t *HTTPRequestTool) SetDomainPolicy(p DomainPolicy) { t.domains = p }
+
+// SetLimits sets the default timeout and the response size cap; zero values
+// keep the defaults.
+func (t *HTTPRequestTool) SetLimits(timeout time.Duration, maxBytes int) {
+	if timeout > 0 {
+		t.timeout = timeout
+	}
+	if maxBytes > 0 {
+		t.maxBytes = maxBytes
+	}
+}
+
+// RedactArgs implements ArgRedactor.
+func (t *HTTPRequestTool) RedactArgs(args map[string]interface{}) map[string]interface{} {
+	return redactHeaders(args)
+}
+
+func (t *HTTPRequestTool) Name() string { return "http_request" }
+func (t *HTTPRequestTool) Description() string {
+	return "Call an HTTP API with any method, headers and body, e.g. a REST endpoint. Returns the status, headers and body."
+}
+
+func (t *HTTPRequestTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"url": map[string]interface{}{
+				"type":        "string",
+				"description": "The URL to call (http or https)",
+			},
+			"method": map[string]interface{}{
+				"type":        "string",
+				"description": "HTTP method (default GET)",
+				"enum":        httpMethods,
+			},
+			"headers": map[string]interface{}{
+				"type":                 "object",
+				"description":          "Request headers, e.g. {\"Authorization\": \"Bearer ...\"}",
+				"additionalProperties": map[string]interface{}{"type": "string"},
+			},
+			"json": map[string]interface{}{
+				"description": "JSON request body; sets Content-Type: application/json",
+			},
+			"body": map[string]interface{}{
+				"type":        "string",
+				"description": "Raw request body, used when json is not given",
+			},
+			"timeout_s": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Timeout in seconds (max %d)", maxHTTPRequestTimeoutS),
+			},
+		},
+		"required": []string{"url"},
+	}
+}
+
+func (t *HTTPRequestTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	uStr, _ := args["url"].(string)
+	if uStr == "" {
+		return "", fmt.Errorf("http_request: 'url' argument required")
+	}
+	u, err := url.Parse(uStr)
+	if err != nil {
+		return "", fmt.Errorf("http_request: invalid url: %w", err)
+	}
+	check := func(ctx context.Context, u *url.URL) error {
+		if err := t.domains.Check(u.Hostname()); err != nil {
+			return err
+		}
+		return netguard.CheckURL(ctx, u, t.lookup)
+	}
+	if err := check(ctx, u); err != nil {
+		return "", fmt.Errorf("http_request: %w", err)
+	}
+
+	method := "GET"
+	if m, _ := args["method"].(string); m != "" {
+		method = strings.ToUpper(m)
+	}
+	if !slices.Contains(httpMethods, method) {
+		return "", fmt.Errorf("http_request: unsupported method %q", method)
+	}
+	var body io.Reader
+	contentType := ""
+	if v, ok := args["json"]; ok && v != nil {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("http_request: encode json body: %w", err)
+		}
+		body, contentType = bytes.NewReader(b), "application/json"
+	} else if s, _ := args["body"].(string); s != "" {
+		body = strings.NewReader(s)
+	}
+
+	timeout := t.timeout
+	if v, ok := args["timeout_s"].(float64); ok && v > 0 {
+		timeout = time.Duration(min(int(v), maxHTTPRequestTimeoutS)) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return "", fmt.Errorf("http_request: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if hs, ok := args["headers"].(map[string]interface{}); ok {
+		for k, v := range hs {
+			req.Header.Set(k, fmt.Sprint(v))
+		}
+	}
+	toolsLog.Info("sending request", "tool", "http_request", "method", method, "url", u.Redacted(), "headers", formatHeaders(req.Header))
+
+	client := &http.Client{
+		Transport: transportOr(t.transport),
+		CheckRedirect: func(r *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errors.New("too many redirects")
+			}
+			return check(r.Context(), r.URL)
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("http_request: %w", err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, int64(t.maxBytes)+1))
+	if err != nil {
+		return "", fmt.Errorf("http_request: read body: %w", err)
+	}
+	truncated := len(b) > t.maxBytes
+	if truncated {
+		b = b[:t.maxBytes]
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "HTTP %s\n", resp.Status)
+	for _, k := range []string{"Content-Type", "Content-Length", "Location", "Retry-After"} {
+		if v := resp.Header.Get(k); v != "" {
+			fmt.Fprintf(&sb, "%s: %s\n", k, v)
+		}
+	}
+	sb.WriteString("\n")
+	sb.Write(b)
+	if truncated {
+		fmt.Fprintf(&sb, "\n[response truncated to %d bytes]", t.maxBytes)
+	}
+	return sb.String(), nil
+}
+
+// sensitiveHeader reports whether a header likely carries a credential.
+func sensitiveHeader(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, w := range secretEnvWords {
+		if strings.Contains(upper, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatHeaders renders h for logs with credential values masked.
+func formatHeaders(h http.Header) string {
+	parts := make([]string, 0, len(h))
+	for k, v := range h {
+		val := strings.Join(v, ",")
+		if sensitiveHeader(k) {
+			val = redactedValue
+		}
+		parts = append(parts, k+"="+val)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, " ")
+}
+
+// redactHeaders returns args with the values of credential headers in its
+// "headers" argument masked. args itself is not modified.
+func redactHeaders(args map[string]interface{}) map[string]interface{} {
+	hs, ok := args["headers"].(map[string]interface{})
+	if !ok {
+		return args
+	}
+	masked := make(map[string]interface{}, len(hs))
+	for k, v := range hs {
+		if sensitiveHeader(k) {
+			v = redactedValue
+		}
+		masked[k] = v
+	}
+	out := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		out[k] = v
+	}
+	out["headers"] = masked
+	return out
+}
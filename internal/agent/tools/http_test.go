@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// toServer sends every request to srv, whatever its URL.
+type toServer struct{ srv *httptest.Server }
+
+func (s toServer) RoundTrip(r *http.Request) (*http.Response, error) {
+	u, _ := url.Parse(s.srv.URL)
+	out := r.Clone(r.Context())
+	out.URL.Scheme, out.URL.Host = u.Scheme, u.Host
+	resp, err := http.DefaultTransport.RoundTrip(out)
+	if resp != nil {
+		resp.Request = r
+	}
+	return resp, err
+}
+
+func TestHTTPRequestTool(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, r.Method+" "+r.Header.Get("Authorization")+" "+r.Header.Get("Content-Type")+" "+string(b))
+	}))
+	defer srv.Close()
+
+	h := NewHTTPRequestTool()
+	h.lookup = func(ctx context.Context, host string) ([]net.IP, error) {
+		return []net.IP{net.ParseIP("93.184.216.34")}, nil
+	}
+	h.transport = toServer{srv}
+	args := map[string]interface{}{
+		"url":     "https://api.example/items",
+		"method":  "post",
+		"headers": map[string]interface{}{"Authorization": "Bearer s3cret"},
+		"json":    map[string]interface{}{"name": "x"},
+	}
+	out, err := h.Execute(context.Background(), args)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(out, "HTTP 200 OK\nContent-Type: text/plain\n") || !strings.HasSuffix(out, `POST Bearer s3cret application/json {"name":"x"}`) {
+		t.Fatalf("unexpected response: %q", out)
+	}
+
+	shown := ShownArgs(h, args)
+	if shown["headers"].(map[string]interface{})["Authorization"] != "[REDACTED]" {
+		t.Fatalf("expected Authorization to be redacted, got %v", shown)
+	}
+	if args["headers"].(map[string]interface{})["Authorization"] != "Bearer s3cret" {
+		t.Fatalf("redaction must not modify the call's arguments")
+	}
+
+	h.SetLimits(0, 4)
+	out, _ = h.Execute(context.Background(), map[string]interface{}{"url": "https://api.example/"})
+	if !strings.HasSuffix(out, "\n\nGET \n[response truncated to 4 bytes]") {
+		t.Fatalf("expected a truncated body, got %q", out)
+	}
+	if _, err := h.Execute(context.Background(), map[string]interface{}{"url": "http://127.0.0.1/"}); err == nil {
+		t.Fatalf("expected loopback to be rejected")
+	}
+}
@@ -0,0 +1,322 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxRunningJobs is the number of background jobs a workspace may run
+	// at once.
+	maxRunningJobs = 4
+	// maxFinishedJobs finished jobs are kept for job_status and job_logs;
+	// older ones are forgotten.
+	maxFinishedJobs = 20
+	// jobLogBytes is how much of a job's most recent output is kept.
+	jobLogBytes = 256 << 10
+)
+
+// tailBuffer keeps the last max bytes written to it.
+type tailBuffer struct {
+	mu  sync.Mutex
+	buf []byte
+	max int
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	if over := len(b.buf) - b.max; over > 0 {
+		b.buf = append(b.buf[:0], b.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+// tail returns the last n lines written.
+func (b *tailBuffer) tail(n int) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	lines := strings.Split(strings.TrimRight(string(b.buf), "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Job is a command started in the background by the exec tool.
+type Job struct {
+	ID      string
+	Command string
+	Started time.Time
+
+	cancel context.CancelFunc
+	log    *tailBuffer
+
+	mu     sync.Mutex
+	ended  time.Time
+	err    error
+	killed bool
+}
+
+// Running reports whether the job's command has not exited yet.
+func (j *Job) Running() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.ended.IsZero()
+}
+
+// Status describes the job in one line.
+func (j *Job) Status() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	var state string
+	switch {
+	case j.ended.IsZero():
+		state = "running for " + time.Since(j.Started).Round(time.Second).String()
+	case j.killed:
+		state = "killed after " + j.ended.Sub(j.Started).Round(time.Second).String()
+	case j.err != nil:
+		state = fmt.Sprintf("failed (%v) after %s", j.err, j.ended.Sub(j.Started).Round(time.Second))
+	default:
+		state = "finished after " + j.ended.Sub(j.Started).Round(time.Second).String()
+	}
+	return fmt.Sprintf("%s: %s — %s", j.ID, j.Command, state)
+}
+
+// JobManager tracks the background jobs of one workspace.
+type JobManager struct {
+	mu   sync.Mutex
+	jobs map[string]*Job
+	next int
+}
+
+// NewJobManager returns an empty JobManager.
+func NewJobManager() *JobManager {
+	return &JobManager{jobs: make(map[string]*Job)}
+}
+
+// start runs a command as a new job. begin starts the command in ctx with
+// its output going to the given writer, like ExecTool.start.
+func (m *JobManager) start(command string, begin func(ctx context.Context, out *tailBuffer) (*exec.Cmd, func(), error)) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	running := 0
+	for _, j := range m.jobs {
+		if j.Running() {
+			running++
+		}
+	}
+	if running >= maxRunningJobs {
+		return nil, fmt.Errorf("exec: %d background jobs are already running; wait for one to finish or kill it", running)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := &tailBuffer{max: jobLogBytes}
+	cmd, cleanup, err := begin(ctx, out)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	m.next++
+	j := &Job{ID: fmt.Sprintf("job-%d", m.next), Command: command, Started: time.Now(), cancel: cancel, log: out}
+	m.jobs[j.ID] = j
+	m.prune()
+	go func() {
+		err := cmd.Wait()
+		cleanup()
+		cancel()
+		j.mu.Lock()
+		j.ended, j.err = time.Now(), err
+		j.mu.Unlock()
+	}()
+	return j, nil
+}
+
+// prune forgets the oldest finished jobs beyond maxFinishedJobs. The caller
+// holds m.mu.
+func (m *JobManager) prune() {
+	var finished []*Job
+	for _, j := range m.jobs {
+		if !j.Running() {
+			finished = append(finished, j)
+		}
+	}
+	if len(finished) <= maxFinishedJobs {
+		return
+	}
+	sort.Slice(finished, func(a, b int) bool { return finished[a].Started.Before(finished[b].Started) })
+	for _, j := range finished[:len(finished)-maxFinishedJobs] {
+		delete(m.jobs, j.ID)
+	}
+}
+
+// Get returns the job with the given ID.
+func (m *JobManager) Get(id string) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("job: no job %q", id)
+	}
+	return j, nil
+}
+
+// List returns all known jobs, oldest first.
+func (m *JobManager) List() []*Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]*Job, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		out = append(out, j)
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].Started.Before(out[b].Started) })
+	return out
+}
+
+// Kill stops a running job.
+func (m *JobManager) Kill(id string) error {
+	j, err := m.Get(id)
+	if err != nil {
+		return err
+	}
+	if !j.Running() {
+		return fmt.Errorf("job: %s is not running", id)
+	}
+	j.mu.Lock()
+	j.killed = true
+	j.mu.Unlock()
+	j.cancel()
+	return nil
+}
+
+// KillAll stops every running job, e.g. on shutdown.
+func (m *JobManager) KillAll() {
+	for _, j := range m.List() {
+		if j.Running() {
+			_ = m.Kill(j.ID)
+		}
+	}
+}
+
+// startJob starts argv as a background job under profile p. The profile's
+// timeout does not apply; the job runs until it exits or is killed.
+func (t *ExecTool) startJob(p ExecProfile, argv []string) (string, error) {
+	j, err := t.jobs.start(strings.Join(argv, " "), func(ctx context.Context, out *tailBuffer) (*exec.Cmd, func(), error) {
+		return t.start(ctx, p, argv, out)
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Started %s: %s. Use job_status, job_logs or job_kill with id %q.", j.ID, j.Command, j.ID), nil
+}
+
+// JobStatusTool reports on background jobs.
+type JobStatusTool struct{ jobs *JobManager }
+
+// NewJobStatusTool creates the job_status tool for jobs.
+func NewJobStatusTool(jobs *JobManager) *JobStatusTool { return &JobStatusTool{jobs: jobs} }
+
+func (t *JobStatusTool) Name() string { return "job_status" }
+func (t *JobStatusTool) Description() string {
+	return "Show whether a background job started with exec is still running; without an id, list all jobs"
+}
+
+func (t *JobStatusTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id": map[string]interface{}{"type": "string", "description": "Job ID, e.g. \"job-1\""},
+		},
+	}
+}
+
+func (t *JobStatusTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	if id, _ := args["id"].(string); id != "" {
+		j, err := t.jobs.Get(id)
+		if err != nil {
+			return "", err
+		}
+		return j.Status(), nil
+	}
+	jobs := t.jobs.List()
+	if len(jobs) == 0 {
+		return "No background jobs.", nil
+	}
+	lines := make([]string, len(jobs))
+	for i, j := range jobs {
+		lines[i] = j.Status()
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// JobLogsTool returns the recent output of a background job.
+type JobLogsTool struct{ jobs *JobManager }
+
+// NewJobLogsTool creates the job_logs tool for jobs.
+func NewJobLogsTool(jobs *JobManager) *JobLogsTool { return &JobLogsTool{jobs: jobs} }
+
+func (t *JobLogsTool) Name() string { return "job_logs" }
+func (t *JobLogsTool) Description() string {
+	return "Show the last lines of output (stdout and stderr) of a background job"
+}
+
+func (t *JobLogsTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id":    map[string]interface{}{"type": "string", "description": "Job ID, e.g. \"job-1\""},
+			"lines": map[string]interface{}{"type": "integer", "description": "Number of lines from the end (default 50, max 500)"},
+		},
+		"required": []string{"id"},
+	}
+}
+
+func (t *JobLogsTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	id, _ := args["id"].(string)
+	j, err := t.jobs.Get(id)
+	if err != nil {
+		return "", err
+	}
+	n := 50
+	if v, ok := args["lines"].(float64); ok && v > 0 {
+		n = min(int(v), 500)
+	}
+	out := j.log.tail(n)
+	if out == "" {
+		out = "(no output yet)"
+	}
+	return j.Status() + "\n" + out, nil
+}
+
+// JobKillTool stops a background job.
+type JobKillTool struct{ jobs *JobManager }
+
+// NewJobKillTool creates the job_kill tool for jobs.
+func NewJobKillTool(jobs *JobManager) *JobKillTool { return &JobKillTool{jobs: jobs} }
+
+func (t *JobKillTool) Name() string        { return "job_kill" }
+func (t *JobKillTool) Description() string { return "Stop a running background job" }
+
+func (t *JobKillTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id": map[string]interface{}{"type": "string", "description": "Job ID, e.g. \"job-1\""},
+		},
+		"required": []string{"id"},
+	}
+}
+
+func (t *JobKillTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	id, _ := args["id"].(string)
+	if err := t.jobs.Kill(id); err != nil {
+		return "", err
+	}
+	return "Killed " + id + ".", nil
+}
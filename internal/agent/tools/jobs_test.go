@@ -0,0 +1,47 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecBackgroundJobs(t *testing.T) {
+	e := NewExecToolWithWorkspace(2, t.TempDir())
+	status, logs, kill := NewJobStatusTool(e.Jobs()), NewJobLogsTool(e.Jobs()), NewJobKillTool(e.Jobs())
+	ctx := context.Background()
+
+	out, err := e.Execute(ctx, map[string]interface{}{"cmd": []interface{}{"echo", "done"}, "background": true})
+	if err != nil || !strings.Contains(out, "job-1") {
+		t.Fatalf("start job-1: %q, %v", out, err)
+	}
+	// sleep outlives the profile's 2s timeout, which does not apply to jobs
+	if _, err := e.Execute(ctx, map[string]interface{}{"cmd": []interface{}{"sleep", "3"}, "background": true}); err != nil {
+		t.Fatalf("start job-2: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		j, _ := e.Jobs().Get("job-1")
+		if !j.Running() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job-1 did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if out, _ := logs.Execute(ctx, map[string]interface{}{"id": "job-1"}); !strings.Contains(out, "finished") || !strings.HasSuffix(out, "\ndone") {
+		t.Fatalf("unexpected job-1 logs: %q", out)
+	}
+	if out, _ := status.Execute(ctx, map[string]interface{}{"id": "job-2"}); !strings.Contains(out, "running") {
+		t.Fatalf("expected job-2 to be running: %q", out)
+	}
+	if _, err := kill.Execute(ctx, map[string]interface{}{"id": "job-2"}); err != nil {
+		t.Fatalf("kill job-2: %v", err)
+	}
+	if _, err := kill.Execute(ctx, map[string]interface{}{"id": "job-9"}); err == nil {
+		t.Fatalf("expected error killing an unknown job")
+	}
+}
@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/mcp"
+)
+
+const defaultMCPTimeout = 60 * time.Second
+
+// MCPTool is a tool of an MCP server, registered as mcp_<server>_<tool>.
+type MCPTool struct {
+	client  *mcp.Client
+	remote  mcp.Tool
+	name    string
+	timeout time.Duration
+}
+
+// invalidToolChars are the characters not allowed in tool names by the
+// model APIs.
+var invalidToolChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// NewMCPTools wraps the tools of an MCP server. A timeout of zero means
+// one minute per call.
+func NewMCPTools(server string, c *mcp.Client, remote []mcp.Tool, timeout time.Duration) []*MCPTool {
+	if timeout <= 0 {
+		timeout = defaultMCPTimeout
+	}
+	out := make([]*MCPTool, 0, len(remote))
+	for _, rt := range remote {
+		name := invalidToolChars.ReplaceAllString("mcp_"+server+"_"+rt.Name, "_")
+		if len(name) > 64 {
+			name = name[:64]
+		}
+		out = append(out, &MCPTool{client: c, remote: rt, name: name, timeout: timeout})
+	}
+	return out
+}
+
+// UntrustedOutput marks results as untrusted: MCP servers commonly return
+// content from the web or other people.
+func (t *MCPTool) UntrustedOutput() bool { return true }
+
+func (t *MCPTool) Name() string { return t.name }
+func (t *MCPTool) Description() string {
+	if t.remote.Description == "" {
+		return "MCP tool " + t.remote.Name
+	}
+	return t.remote.Description
+}
+
+func (t *MCPTool) Parameters() map[string]interface{} {
+	if t.remote.InputSchema == nil {
+		return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+	}
+	return t.remote.InputSchema
+}
+
+func (t *MCPTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	res, err := t.client.CallTool(ctx, t.remote.Name, args)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", t.name, err)
+	}
+	if res.IsError {
+		return "", fmt.Errorf("%s: %s", t.name, res.Text())
+	}
+	return res.Text(), nil
+}
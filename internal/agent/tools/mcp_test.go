@@ -0,0 +1,26 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kr0nicas/picobot/internal/mcp"
+)
+
+func TestMCPToolNames(t *testing.T) {
+	ts := NewMCPTools("git hub", nil, []mcp.Tool{{Name: "search.issues"}, {Name: strings.Repeat("x", 80)}}, 0)
+	if ts[0].Name() != "mcp_git_hub_search_issues" {
+		t.Errorf("name = %q", ts[0].Name())
+	}
+	if len(ts[1].Name()) != 64 {
+		t.Errorf("long name not cut: %d", len(ts[1].Name()))
+	}
+	if p := ts[0].Parameters(); p["type"] != "object" {
+		t.Errorf("default schema = %v", p)
+	}
+
+	policy := RolePolicy{RoleUser: {"mcp_git_hub_*"}}
+	if !policy.Allows(RoleUser, ts[0].Name()) || policy.Allows(RoleUser, "mcp_other_x") {
+		t.Error("prefix grant does not match MCP tools")
+	}
+}
@@ -0,0 +1,115 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/agent/memory"
+)
+
+// ExportMemoryTool writes a backup of all memories (see
+// memory.MemoryStore.Export) to a file in the workspace.
+type ExportMemoryTool struct {
+	mem  *memory.MemoryStore
+	root *os.Root
+}
+
+func NewExportMemoryTool(mem *memory.MemoryStore, root *os.Root) *ExportMemoryTool {
+	return &ExportMemoryTool{mem: mem, root: root}
+}
+
+func (t *ExportMemoryTool) Name() string { return "export_memory" }
+
+func (t *ExportMemoryTool) Description() string {
+	return "Back up long-term memory, the daily notes and the memory database as one .tar.gz file in the workspace, to send to the user or restore elsewhere with import_memory"
+}
+
+func (t *ExportMemoryTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Where to write the backup in the workspace (default exports/memory-<date>.tar.gz)",
+			},
+		},
+	}
+}
+
+func (t *ExportMemoryTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	dest, _ := args["path"].(string)
+	if dest == "" {
+		dest = "exports/memory-" + time.Now().UTC().Format("2006-01-02") + ".tar.gz"
+	}
+	var buf bytes.Buffer
+	if err := t.mem.Export(&buf); err != nil {
+		return "", err
+	}
+	if dir := filepath.Dir(dest); dir != "." {
+		if err := t.root.MkdirAll(dir, 0o755); err != nil {
+			return "", fmt.Errorf("export_memory: %w", err)
+		}
+	}
+	if err := t.root.WriteFile(dest, buf.Bytes(), 0o644); err != nil {
+		return "", fmt.Errorf("export_memory: %w", err)
+	}
+	return fmt.Sprintf("Memory exported to %s (%d bytes)", dest, buf.Len()), nil
+}
+
+// ImportMemoryTool merges a backup made by export_memory into the memories.
+// Imported memories end up in prompts, so every import needs the user's
+// approval.
+type ImportMemoryTool struct {
+	mem  *memory.MemoryStore
+	root *os.Root
+}
+
+func NewImportMemoryTool(mem *memory.MemoryStore, root *os.Root) *ImportMemoryTool {
+	return &ImportMemoryTool{mem: mem, root: root}
+}
+
+func (t *ImportMemoryTool) Name() string { return "import_memory" }
+
+func (t *ImportMemoryTool) Description() string {
+	return "Merge a memory backup made by export_memory, from a file in the workspace, into the current memories"
+}
+
+func (t *ImportMemoryTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Workspace path of the backup",
+			},
+		},
+		"required": []string{"path"},
+	}
+}
+
+// RequiresApproval implements ApprovalRequirer.
+func (t *ImportMemoryTool) RequiresApproval(args map[string]interface{}) string {
+	p, _ := args["path"].(string)
+	return "import memories from " + p
+}
+
+func (t *ImportMemoryTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	p, _ := args["path"].(string)
+	if p == "" {
+		return "", fmt.Errorf("import_memory: path (string) is required")
+	}
+	f, err := t.root.Open(filepath.ToSlash(p))
+	if err != nil {
+		return "", fmt.Errorf("import_memory: %w", err)
+	}
+	defer f.Close()
+	res, err := t.mem.Import(f)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Imported %s; %d memory files created or extended", countMemories(res.Memories), res.Files), nil
+}
@@ -4,42 +4,60 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 
 	"github.com/kr0nicas/picobot/internal/chat"
 )
 
-// MessageTool sends messages to a channel via the chat Hub.
-// It holds a context (channel + chatID) which should be set per-incoming-message.
+// maxAttachment caps the size of a file sent with the message tool.
+const maxAttachment = 50 << 20
+
+// MessageTool sends messages to the chat a call is made for via the chat Hub.
 type MessageTool struct {
-	hub     *chat.Hub
-	channel string
-	chatID  string
+	hub  *chat.Hub
+	root *os.Root // workspace files may be attached when set
 }
 
 func NewMessageTool(b *chat.Hub) *MessageTool {
 	return &MessageTool{hub: b}
 }
 
-func (m *MessageTool) Name() string        { return "message" }
-func (m *MessageTool) Description() string { return "Send a message to the current channel/chat" }
+// NewMessageToolWithWorkspace creates a message tool that can also send
+// files from the workspace rooted at root.
+func NewMessageToolWithWorkspace(b *chat.Hub, root *os.Root) *MessageTool {
+	return &MessageTool{hub: b, root: root}
+}
 
-func (m *MessageTool) Parameters() map[string]interface{} {
-	return map[string]interface{}{
-		"type": "object",
-		"properties": map[string]interface{}{
-			"content": map[string]interface{}{
-				"type":        "string",
-				"description": "The message content to send",
-			},
-		},
-		"required": []string{"content"},
+func (m *MessageTool) Name() string { return "message" }
+func (m *MessageTool) Description() string {
+	if m.root != nil {
+		return "Send a message to the current channel/chat, optionally with workspace files (images, PDFs, archives) attached"
 	}
+	return "Send a message to the current channel/chat"
 }
 
-// SetContext sets the current channel and chat id for outgoing messages.
-func (m *MessageTool) SetContext(channel, chatID string) {
-	m.channel = channel
-	m.chatID = chatID
+func (m *MessageTool) Parameters() map[string]interface{} {
+	props := map[string]interface{}{
+		"content": map[string]interface{}{
+			"type":        "string",
+			"description": "The message content to send",
+		},
+	}
+	required := []string{"content"}
+	if m.root != nil {
+		props["files"] = map[string]interface{}{
+			"type":        "array",
+			"items":       map[string]interface{}{"type": "string"},
+			"description": "Workspace-relative paths of files to send with the message",
+		}
+		required = []string{}
+	}
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": props,
+		"required":   required,
+	}
 }
 
 // Expected args: {"content": "..."}
@@ -54,14 +72,20 @@ func (m *MessageTool) Execute(ctx context.Context, args map[string]interface{})
 			content = string(b)
 		}
 	}
-	if content == "" {
+	attachments, err := m.attachments(args["files"])
+	if err != nil {
+		return "", err
+	}
+	if content == "" && len(attachments) == 0 {
 		return "", fmt.Errorf("message tool: 'content' argument required")
 	}
 	// Publish outbound message to hub
+	channel, chatID := chatOf(ctx)
 	out := chat.Outbound{
-		Channel: m.channel,
-		ChatID:  m.chatID,
-		Content: content,
+		Channel:     channel,
+		ChatID:      chatID,
+		Content:     content,
+		Attachments: attachments,
 	}
 	select {
 	case m.hub.Out <- out:
@@ -70,3 +94,47 @@ func (m *MessageTool) Execute(ctx context.Context, args map[string]interface{})
 		return "", fmt.Errorf("outbound channel full")
 	}
 }
+
+// RequiresApprovalIn asks for approval before a message leaves as email:
+// unlike a chat reply, it lands in a mailbox and cannot be taken back.
+func (m *MessageTool) RequiresApprovalIn(channel, chatID string, args map[string]interface{}) string {
+	if channel != "email" {
+		return ""
+	}
+	return fmt.Sprintf("send an email to %s", chatID)
+}
+
+// attachments reads the files listed in the "files" argument from the
+// workspace.
+func (m *MessageTool) attachments(arg interface{}) ([]chat.Attachment, error) {
+	list, _ := arg.([]interface{})
+	if len(list) == 0 {
+		return nil, nil
+	}
+	if m.root == nil {
+		return nil, fmt.Errorf("message tool: sending files is not available")
+	}
+	var out []chat.Attachment
+	for _, item := range list {
+		p, _ := item.(string)
+		if p == "" {
+			continue
+		}
+		info, err := m.root.Stat(p)
+		if err != nil {
+			return nil, fmt.Errorf("message tool: %w", err)
+		}
+		if info.IsDir() {
+			return nil, fmt.Errorf("message tool: %s is a directory", p)
+		}
+		if info.Size() > maxAttachment {
+			return nil, fmt.Errorf("message tool: %s is larger than 50MB", p)
+		}
+		data, err := m.root.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("message tool: %w", err)
+		}
+		out = append(out, chat.Attachment{Name: filepath.Base(p), Data: data})
+	}
+	return out, nil
+}
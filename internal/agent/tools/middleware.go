@@ -0,0 +1,206 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/logging"
+)
+
+var toolsLog = logging.For("tools")
+
+// ToolMiddleware wraps the execution of tools registered in a Registry, for
+// concerns that apply to many tools (logging, rate limits, confirmations).
+// Before runs ahead of the tool and may replace its arguments or refuse the
+// call with an error, in which case the tool and the remaining middleware
+// are skipped. After sees the outcome and may replace it. Before hooks run
+// in the order middleware was added, After hooks in reverse.
+type ToolMiddleware interface {
+	Before(ctx context.Context, tool string, args map[string]interface{}) (map[string]interface{}, error)
+	After(ctx context.Context, tool string, args map[string]interface{}, result string, err error) (string, error)
+}
+
+// userAsker is implemented by middleware that may stop to ask the user, so
+// the agent doesn't run such calls side by side.
+type userAsker interface {
+	AsksUser(tool string) bool
+}
+
+// Use adds m to the middleware that wraps every tool execution.
+func (r *Registry) Use(m ToolMiddleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.middleware = append(r.middleware, m)
+}
+
+// AsksUser reports whether the middleware may ask the user before running
+// tool.
+func (r *Registry) AsksUser(tool string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, m := range r.middleware {
+		if ua, ok := m.(userAsker); ok && ua.AsksUser(tool) {
+			return true
+		}
+	}
+	return false
+}
+
+// run executes t through mws.
+func run(ctx context.Context, mws []ToolMiddleware, t Tool, args map[string]interface{}) (string, error) {
+	name := t.Name()
+	for _, m := range mws {
+		var err error
+		if args, err = m.Before(ctx, name, args); err != nil {
+			return "", err
+		}
+	}
+	res, err := t.Execute(ctx, args)
+	for i := len(mws) - 1; i >= 0; i-- {
+		res, err = mws[i].After(ctx, name, args, res, err)
+	}
+	return res, err
+}
+
+// ForTools limits m to the named tools; with no names it applies to all.
+func ForTools(names []string, m ToolMiddleware) ToolMiddleware {
+	if len(names) == 0 {
+		return m
+	}
+	return &filtered{names: names, m: m}
+}
+
+type filtered struct {
+	names []string
+	m     ToolMiddleware
+}
+
+func (f *filtered) Before(ctx context.Context, tool string, args map[string]interface{}) (map[string]interface{}, error) {
+	if !slices.Contains(f.names, tool) {
+		return args, nil
+	}
+	return f.m.Before(ctx, tool, args)
+}
+
+func (f *filtered) After(ctx context.Context, tool string, args map[string]interface{}, result string, err error) (string, error) {
+	if !slices.Contains(f.names, tool) {
+		return result, err
+	}
+	return f.m.After(ctx, tool, args, result, err)
+}
+
+func (f *filtered) AsksUser(tool string) bool {
+	ua, ok := f.m.(userAsker)
+	return ok && slices.Contains(f.names, tool) && ua.AsksUser(tool)
+}
+
+// LogMiddleware logs every call with its arguments and outcome. The values
+// of the arguments named in redact and of credential headers are masked.
+type LogMiddleware struct {
+	redact []string
+}
+
+// NewLogMiddleware creates a LogMiddleware masking the redact arguments.
+func NewLogMiddleware(redact []string) *LogMiddleware {
+	return &LogMiddleware{redact: redact}
+}
+
+func (l *LogMiddleware) Before(ctx context.Context, tool string, args map[string]interface{}) (map[string]interface{}, error) {
+	toolsLog.Info("tool called", "tool", tool, "args", l.format(args))
+	return args, nil
+}
+
+func (l *LogMiddleware) After(ctx context.Context, tool string, args map[string]interface{}, result string, err error) (string, error) {
+	if err != nil {
+		toolsLog.Warn("tool failed", "tool", tool, "err", err)
+	} else {
+		toolsLog.Info("tool returned", "tool", tool, "bytes", len(result))
+	}
+	return result, err
+}
+
+func (l *LogMiddleware) format(args map[string]interface{}) string {
+	shown := make(map[string]interface{}, len(args))
+	for k, v := range redactHeaders(args) {
+		if slices.Contains(l.redact, k) {
+			v = redactedValue
+		}
+		shown[k] = v
+	}
+	b, _ := json.Marshal(shown)
+	return string(b)
+}
+
+// RateLimitMiddleware refuses calls to a tool beyond a number per minute.
+type RateLimitMiddleware struct {
+	perMinute int
+	now       func() time.Time
+
+	mu    sync.Mutex
+	calls map[string][]time.Time // tool -> call times within the last minute
+}
+
+// NewRateLimitMiddleware allows perMinute calls per tool in any minute.
+func NewRateLimitMiddleware(perMinute int) *RateLimitMiddleware {
+	return &RateLimitMiddleware{perMinute: perMinute, now: time.Now, calls: make(map[string][]time.Time)}
+}
+
+func (rl *RateLimitMiddleware) Before(ctx context.Context, tool string, args map[string]interface{}) (map[string]interface{}, error) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	now := rl.now()
+	recent := rl.calls[tool][:0]
+	for _, t := range rl.calls[tool] {
+		if now.Sub(t) < time.Minute {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= rl.perMinute {
+		rl.calls[tool] = recent
+		wait := time.Minute - now.Sub(recent[0])
+		return nil, fmt.Errorf("rate limit: %s was called %d times in the last minute; try again in %s", tool, len(recent), wait.Round(time.Second))
+	}
+	rl.calls[tool] = append(recent, now)
+	return args, nil
+}
+
+func (rl *RateLimitMiddleware) After(ctx context.Context, tool string, args map[string]interface{}, result string, err error) (string, error) {
+	return result, err
+}
+
+// ConfirmMiddleware asks the user before each call goes ahead.
+type ConfirmMiddleware struct {
+	ask AskFunc
+}
+
+// NewConfirmMiddleware confirms calls with ask.
+func NewConfirmMiddleware(ask AskFunc) *ConfirmMiddleware {
+	return &ConfirmMiddleware{ask: ask}
+}
+
+func (c *ConfirmMiddleware) Before(ctx context.Context, tool string, args map[string]interface{}) (map[string]interface{}, error) {
+	b, _ := json.Marshal(args)
+	detail := string(b)
+	if len(detail) > 300 {
+		detail = detail[:300] + "..."
+	}
+	answer, err := c.ask(ctx, fmt.Sprintf("Allow the %s tool to run with %s?", tool, detail), []string{"Allow", "Deny"})
+	if err != nil {
+		return nil, fmt.Errorf("%s needs confirmation: %w", tool, err)
+	}
+	if !strings.EqualFold(answer, "Allow") {
+		return nil, fmt.Errorf("the user did not allow this %s call", tool)
+	}
+	return args, nil
+}
+
+func (c *ConfirmMiddleware) After(ctx context.Context, tool string, args map[string]interface{}, result string, err error) (string, error) {
+	return result, err
+}
+
+func (c *ConfirmMiddleware) AsksUser(string) bool { return true }
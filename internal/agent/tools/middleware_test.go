@@ -0,0 +1,101 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// echoTool returns its "text" argument.
+type echoTool struct{}
+
+func (echoTool) Name() string                       { return "echo" }
+func (echoTool) Description() string                { return "echo" }
+func (echoTool) Parameters() map[string]interface{} { return nil }
+func (echoTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	s, _ := args["text"].(string)
+	return s, nil
+}
+
+// tagMiddleware records hook order and decorates the text and result.
+type tagMiddleware struct {
+	tag   string
+	trace *[]string
+}
+
+func (m tagMiddleware) Before(ctx context.Context, tool string, args map[string]interface{}) (map[string]interface{}, error) {
+	*m.trace = append(*m.trace, "before "+m.tag)
+	return map[string]interface{}{"text": args["text"].(string) + m.tag}, nil
+}
+
+func (m tagMiddleware) After(ctx context.Context, tool string, args map[string]interface{}, result string, err error) (string, error) {
+	*m.trace = append(*m.trace, "after "+m.tag)
+	return "<" + result + ">", err
+}
+
+func TestMiddlewareWrapsExecution(t *testing.T) {
+	r := NewRegistry()
+	r.Register(echoTool{})
+	var trace []string
+	r.Use(tagMiddleware{"a", &trace})
+	r.Use(tagMiddleware{"b", &trace})
+	r.Use(ForTools([]string{"other"}, tagMiddleware{"c", &trace}))
+
+	res, err := r.Execute(context.Background(), "echo", map[string]interface{}{"text": "x"})
+	if err != nil || res != "<<xab>>" {
+		t.Fatalf("unexpected result %q, %v", res, err)
+	}
+	if got := strings.Join(trace, ","); got != "before a,before b,after b,after a" {
+		t.Fatalf("unexpected hook order: %s", got)
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	r := NewRegistry()
+	r.Register(echoTool{})
+	rl := NewRateLimitMiddleware(2)
+	now := time.Now()
+	rl.now = func() time.Time { return now }
+	r.Use(rl)
+
+	for i := 0; i < 2; i++ {
+		if _, err := r.Execute(context.Background(), "echo", nil); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+	if _, err := r.Execute(context.Background(), "echo", nil); err == nil || !strings.Contains(err.Error(), "rate limit") {
+		t.Fatalf("expected the third call to be refused, got %v", err)
+	}
+	now = now.Add(time.Minute)
+	if _, err := r.Execute(context.Background(), "echo", nil); err != nil {
+		t.Fatalf("expected calls to be allowed a minute later: %v", err)
+	}
+}
+
+func TestConfirmMiddleware(t *testing.T) {
+	r := NewRegistry()
+	r.Register(echoTool{})
+	answer := "Deny"
+	r.Use(ForTools([]string{"echo"}, NewConfirmMiddleware(func(ctx context.Context, q string, options []string) (string, error) {
+		if answer == "" {
+			return "", errors.New("nobody to ask")
+		}
+		return answer, nil
+	})))
+	if !r.AsksUser("echo") || r.AsksUser("exec") {
+		t.Fatal("AsksUser should report the confirmed tools only")
+	}
+	if _, err := r.Execute(context.Background(), "echo", map[string]interface{}{"text": "x"}); err == nil {
+		t.Fatal("expected a denied call to fail")
+	}
+	answer = "Allow"
+	if res, err := r.Execute(context.Background(), "echo", map[string]interface{}{"text": "x"}); err != nil || res != "x" {
+		t.Fatalf("expected an allowed call to run, got %q, %v", res, err)
+	}
+	answer = ""
+	if _, err := r.Execute(context.Background(), "echo", nil); err == nil || !strings.Contains(err.Error(), "nobody to ask") {
+		t.Fatalf("expected the ask error, got %v", err)
+	}
+}
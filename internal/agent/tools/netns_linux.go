@@ -0,0 +1,21 @@
+//go:build linux
+
+package tools
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// isolateNetwork makes cmd run in fresh user and network namespaces, so it
+// only sees a loopback interface that is down. Unprivileged user namespaces
+// must be enabled; otherwise starting the command fails.
+func isolateNetwork(cmd *exec.Cmd) error {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags:  syscall.CLONE_NEWUSER | syscall.CLONE_NEWNET,
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}},
+	}
+	return nil
+}
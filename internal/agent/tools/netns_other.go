@@ -0,0 +1,14 @@
+//go:build !linux
+
+package tools
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// isolateNetwork is only implemented on Linux; elsewhere commands that must
+// run without network access are refused rather than run unrestricted.
+func isolateNetwork(cmd *exec.Cmd) error {
+	return errors.New("exec: network isolation is only supported on Linux")
+}
@@ -0,0 +1,58 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+)
+
+// PlanFunc marks a step of the current chat's approved plan and returns
+// the plan as it stands.
+type PlanFunc func(ctx context.Context, step int, status, note string) (string, error)
+
+// UpdatePlanTool lets the model track its progress through a plan the user
+// approved in planning mode.
+// Args: {"step": 2, "status": "done", "note": "installed 3 packages"}
+type UpdatePlanTool struct {
+	update PlanFunc
+}
+
+func NewUpdatePlanTool(update PlanFunc) *UpdatePlanTool {
+	return &UpdatePlanTool{update: update}
+}
+
+func (t *UpdatePlanTool) Name() string { return "update_plan" }
+func (t *UpdatePlanTool) Description() string {
+	return "Mark a step of the approved plan done or skipped once you have finished it, and see the steps that are left"
+}
+
+func (t *UpdatePlanTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"step": map[string]interface{}{
+				"type":        "integer",
+				"description": "The step's number in the plan",
+			},
+			"status": map[string]interface{}{
+				"type":        "string",
+				"description": "done or skipped",
+				"enum":        []string{"done", "skipped"},
+			},
+			"note": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional outcome of the step, or why it was skipped",
+			},
+		},
+		"required": []string{"step", "status"},
+	}
+}
+
+func (t *UpdatePlanTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	step, _ := args["step"].(float64)
+	status, _ := args["status"].(string)
+	note, _ := args["note"].(string)
+	if status != "done" && status != "skipped" {
+		return "", fmt.Errorf("update_plan: 'status' must be done or skipped")
+	}
+	return t.update(ctx, int(step), status, note)
+}
@@ -0,0 +1,219 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/workspace"
+)
+
+// Limits of plugin calls.
+const (
+	pluginDescribeTimeout = 10 * time.Second
+	defaultPluginTimeout  = 60 * time.Second
+	maxPluginTimeout      = 10 * time.Minute
+	maxPluginOutput       = 1 << 20
+)
+
+// PluginDir is the name of the directory scanned for plugins, next to the
+// config file and outside the workspace.
+const PluginDir = "plugins"
+
+// PluginTool is an executable in the plugins directory that describes itself and runs as a tool. Each call starts the executable,
+// writes one JSON-RPC request line to its stdin and reads one response line
+// from its stdout:
+//
+//	-> {"jsonrpc":"2.0","id":1,"method":"describe"}
+//	<- {"jsonrpc":"2.0","id":1,"result":{"name":"weather","description":"...","parameters":{...}}}
+//	-> {"jsonrpc":"2.0","id":1,"method":"execute","params":{"arguments":{...},"channel":"telegram","chat_id":"42"}}
+//	<- {"jsonrpc":"2.0","id":1,"result":{"output":"..."}}
+//
+// Plugins are registered as plugin_<name>.
+type PluginTool struct {
+	path        string
+	workspace   string
+	passEnv     []string
+	name        string
+	description string
+	parameters  map[string]interface{}
+	timeout     time.Duration
+	untrusted   bool
+}
+
+// pluginDescription is the result of the describe method.
+type pluginDescription struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters"`
+	TimeoutS    int                    `json:"timeout_s"`
+	Untrusted   bool                   `json:"untrusted"`
+}
+
+// LoadPlugins describes every executable in dir and returns a tool per
+// plugin; plugins run in the workspace ws. dir must lie outside the workspace, which
+// the agent can write to. Plugins that fail to describe themselves are
+// reported in errs and skipped. Environment variables that look like
+// credentials are hidden from plugins unless named in passEnv.
+func LoadPlugins(ctx context.Context, dir, ws string, passEnv []string) (plugins []*PluginTool, errs []error) {
+	if _, err := workspace.Resolve(ws, dir); err == nil {
+		return nil, []error{fmt.Errorf("plugins directory %s is inside the workspace, where the agent can write; move it out", dir)}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, []error{err}
+	}
+	seen := map[string]string{}
+	for _, e := range entries {
+		p := filepath.Join(dir, e.Name())
+		if !isPluginExecutable(p, e) {
+			continue
+		}
+		t := &PluginTool{path: p, workspace: ws, passEnv: passEnv}
+		if err := t.describe(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("plugin %s: %w", e.Name(), err))
+			continue
+		}
+		if other, ok := seen[t.name]; ok {
+			errs = append(errs, fmt.Errorf("plugin %s: name %s is already used by %s", e.Name(), t.name, other))
+			continue
+		}
+		seen[t.name] = e.Name()
+		plugins = append(plugins, t)
+	}
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].name < plugins[j].name })
+	return plugins, errs
+}
+
+// isPluginExecutable reports whether a directory entry is a runnable
+// plugin: a regular file with an execute bit, or on Windows an .exe, .bat
+// or .cmd file.
+func isPluginExecutable(p string, e os.DirEntry) bool {
+	if strings.HasPrefix(e.Name(), ".") {
+		return false
+	}
+	info, err := os.Stat(p)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		switch strings.ToLower(filepath.Ext(p)) {
+		case ".exe", ".bat", ".cmd":
+			return true
+		}
+		return false
+	}
+	return info.Mode().Perm()&0o111 != 0
+}
+
+func (t *PluginTool) describe(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, pluginDescribeTimeout)
+	defer cancel()
+	var d pluginDescription
+	if err := t.call(ctx, "describe", nil, &d); err != nil {
+		return err
+	}
+	name := invalidToolChars.ReplaceAllString(strings.TrimSpace(d.Name), "_")
+	if name == "" {
+		return fmt.Errorf("describe returned no name")
+	}
+	t.name = "plugin_" + name
+	if len(t.name) > 64 {
+		t.name = t.name[:64]
+	}
+	t.description = d.Description
+	t.parameters = d.Parameters
+	if t.parameters == nil {
+		t.parameters = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+	}
+	t.timeout = defaultPluginTimeout
+	if d.TimeoutS > 0 {
+		t.timeout = min(time.Duration(d.TimeoutS)*time.Second, maxPluginTimeout)
+	}
+	t.untrusted = d.Untrusted
+	return nil
+}
+
+// call runs the plugin for one request.
+func (t *PluginTool) call(ctx context.Context, method string, params, out interface{}) error {
+	req := map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": method}
+	if params != nil {
+		req["params"] = params
+	}
+	in, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, t.path)
+	cmd.Dir = t.workspace
+	cmd.Env = scrubEnv(os.Environ(), t.passEnv)
+	cmd.Stdin = bytes.NewReader(append(in, '\n'))
+	stdout, stderr := &cappedBuffer{max: maxPluginOutput}, &cappedBuffer{max: 4096}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	runErr := cmd.Run()
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("stopped: %w", err)
+	}
+
+	// the first line that parses as a response is the answer; plugins may
+	// have printed other things before it
+	for _, line := range strings.Split(stdout.buf.String(), "\n") {
+		var resp struct {
+			Result json.RawMessage `json:"result"`
+			Error  *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal([]byte(line), &resp) != nil || (resp.Result == nil && resp.Error == nil) {
+			continue
+		}
+		if resp.Error != nil {
+			return errors.New(resp.Error.Message)
+		}
+		if err := json.Unmarshal(resp.Result, out); err != nil {
+			return fmt.Errorf("invalid %s result: %w", method, err)
+		}
+		return nil
+	}
+	msg := strings.TrimSpace(stderr.String())
+	if runErr != nil {
+		return fmt.Errorf("%v: %s", runErr, msg)
+	}
+	return fmt.Errorf("no response on stdout: %s", msg)
+}
+
+// UntrustedOutput reports whether the plugin declared its output untrusted.
+func (t *PluginTool) UntrustedOutput() bool { return t.untrusted }
+
+func (t *PluginTool) Name() string                       { return t.name }
+func (t *PluginTool) Description() string                { return t.description }
+func (t *PluginTool) Parameters() map[string]interface{} { return t.parameters }
+
+func (t *PluginTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	var res struct {
+		Output string `json:"output"`
+	}
+	channel, chatID := chatOf(ctx)
+	err := t.call(ctx, "execute", map[string]interface{}{"arguments": args, "channel": channel, "chat_id": chatID}, &res)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", t.name, err)
+	}
+	return res.Output, nil
+}
@@ -0,0 +1,62 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestLoadPlugins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script plugins")
+	}
+	ws := t.TempDir()
+	dir := filepath.Join(t.TempDir(), PluginDir)
+	os.MkdirAll(dir, 0o755)
+	write := func(name, script string, mode os.FileMode) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(script), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("greet.sh", `#!/bin/sh
+read req
+case "$req" in
+*'"describe"'*) echo 'starting'; echo '{"jsonrpc":"2.0","id":1,"result":{"name":"greet","description":"Say hello","parameters":{"type":"object","properties":{"who":{"type":"string"}}}}}' ;;
+*'"who":"bob"'*'"chat_id":"42"'*) echo '{"jsonrpc":"2.0","id":1,"result":{"output":"hello bob"}}' ;;
+*) echo '{"jsonrpc":"2.0","id":1,"error":{"code":1,"message":"unexpected request"}}' ;;
+esac
+`, 0o755)
+	write("notes.txt", "not a plugin", 0o644)
+	write("broken.sh", "#!/bin/sh\necho oops >&2\nexit 3\n", 0o755)
+
+	plugins, errs := LoadPlugins(context.Background(), dir, ws, nil)
+	if len(plugins) != 1 || plugins[0].Name() != "plugin_greet" || plugins[0].Description() != "Say hello" {
+		t.Fatalf("plugins = %+v", plugins)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "broken.sh") || !strings.Contains(errs[0].Error(), "oops") {
+		t.Fatalf("errs = %v", errs)
+	}
+
+	p := plugins[0]
+	ctx := WithChat(context.Background(), "telegram", "42")
+	out, err := p.Execute(ctx, map[string]interface{}{"who": "bob"})
+	if err != nil || out != "hello bob" {
+		t.Fatalf("Execute = %q, %v", out, err)
+	}
+	if _, err := p.Execute(ctx, map[string]interface{}{"who": "eve"}); err == nil || !strings.Contains(err.Error(), "unexpected request") {
+		t.Fatalf("expected the plugin's error, got %v", err)
+	}
+}
+
+func TestLoadPluginsRefusesWorkspace(t *testing.T) {
+	ws := t.TempDir()
+	dir := filepath.Join(ws, PluginDir)
+	os.MkdirAll(dir, 0o755)
+	plugins, errs := LoadPlugins(context.Background(), dir, ws, nil)
+	if len(plugins) != 0 || len(errs) != 1 || !strings.Contains(errs[0].Error(), "inside the workspace") {
+		t.Fatalf("plugins = %v, errs = %v", plugins, errs)
+	}
+}
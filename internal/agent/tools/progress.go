@@ -0,0 +1,67 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ProgressFunc records where the task of the current chat stands; the agent
+// passes it on to the user while the task runs long.
+type ProgressFunc func(ctx context.Context, status string)
+
+// ReportProgressTool lets the model say how a long task is going, e.g.
+// "Step 3/7: installing dependencies". The agent shows the latest report
+// in its periodic progress updates.
+// Args: {"status": "installing dependencies", "step": 3, "total": 7}
+type ReportProgressTool struct {
+	report ProgressFunc
+}
+
+func NewReportProgressTool(report ProgressFunc) *ReportProgressTool {
+	return &ReportProgressTool{report: report}
+}
+
+func (t *ReportProgressTool) Name() string { return "report_progress" }
+func (t *ReportProgressTool) Description() string {
+	return "During a long task, tell the user which step you are on; shown in the progress updates of tasks that run more than a few steps"
+}
+
+func (t *ReportProgressTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"status": map[string]interface{}{
+				"type":        "string",
+				"description": "What you are doing now, e.g. \"installing dependencies\"",
+			},
+			"step": map[string]interface{}{
+				"type":        "integer",
+				"description": "The current step's number",
+			},
+			"total": map[string]interface{}{
+				"type":        "integer",
+				"description": "How many steps you expect in all",
+			},
+		},
+		"required": []string{"status"},
+	}
+}
+
+func (t *ReportProgressTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	status, _ := args["status"].(string)
+	status = strings.TrimSpace(status)
+	if status == "" {
+		return "", fmt.Errorf("report_progress: 'status' argument required")
+	}
+	step, _ := args["step"].(float64)
+	total, _ := args["total"].(float64)
+	switch {
+	case step > 0 && total > 0:
+		status = fmt.Sprintf("Step %d/%d: %s", int(step), int(total), status)
+	case step > 0:
+		status = fmt.Sprintf("Step %d: %s", int(step), status)
+	}
+	t.report(ctx, status)
+	return "Noted; carry on with the task.", nil
+}
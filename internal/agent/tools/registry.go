@@ -3,6 +3,9 @@ package tools
 import (
 	"context"
 	"errors"
+	"fmt"
+	"maps"
+	"sort"
 	"sync"
 
 	"github.com/kr0nicas/picobot/internal/providers"
@@ -18,15 +21,57 @@ type Tool interface {
 	Execute(ctx context.Context, args map[string]interface{}) (string, error)
 }
 
+// UntrustedOutput is implemented by tools whose results come from outside
+// sources (web pages, APIs). The agent treats such output as data: it is
+// delimited and scrubbed of instruction-like text before the model sees it.
+type UntrustedOutput interface {
+	UntrustedOutput() bool
+}
+
+// ApprovalRequirer is implemented by tools for which some calls are
+// destructive (overwrites, deletes, risky commands, sending mail). When
+// RequiresApproval returns a non-empty description, the agent asks the user
+// to approve that action before executing it.
+type ApprovalRequirer interface {
+	RequiresApproval(args map[string]interface{}) string
+}
+
+// ChatApprovalRequirer is implemented by tools for which whether a call
+// needs approval depends on the chat it is made for, such as messages that
+// leave as email.
+type ChatApprovalRequirer interface {
+	RequiresApprovalIn(channel, chatID string, args map[string]interface{}) string
+}
+
+// ArgRedactor is implemented by tools whose arguments may carry secrets
+// (API tokens in headers). RedactArgs returns a copy of args that is safe to
+// log, trace or show to the user.
+type ArgRedactor interface {
+	RedactArgs(args map[string]interface{}) map[string]interface{}
+}
+
+// ShownArgs returns args as they may be logged for tool t.
+func ShownArgs(t Tool, args map[string]interface{}) map[string]interface{} {
+	if r, ok := t.(ArgRedactor); ok {
+		return r.RedactArgs(args)
+	}
+	return args
+}
+
 // Registry holds registered tools.
 type Registry struct {
-	mu    sync.RWMutex
-	tools map[string]Tool
+	mu       sync.RWMutex
+	tools    map[string]Tool
+	disabled map[string]bool // tools switched off at runtime; hidden from the model
+	roles    RolePolicy
+	scopes   ScopePolicy
+
+	middleware []ToolMiddleware // see Use
 }
 
 // NewRegistry constructs a new tool registry.
 func NewRegistry() *Registry {
-	return &Registry{tools: make(map[string]Tool)}
+	return &Registry{tools: make(map[string]Tool), disabled: make(map[string]bool), roles: DefaultRolePolicy()}
 }
 
 // Register adds a tool to the registry.
@@ -43,12 +88,121 @@ func (r *Registry) Get(name string) Tool {
 	return r.tools[name]
 }
 
+// SetEnabled enables or disables a registered tool. Disabled tools are not
+// offered to the model and cannot be executed.
+func (r *Registry) SetEnabled(name string, enabled bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.tools[name]; !ok {
+		return fmt.Errorf("tool %q not found", name)
+	}
+	if enabled {
+		delete(r.disabled, name)
+	} else {
+		r.disabled[name] = true
+	}
+	return nil
+}
+
+// Enabled reports whether a registered tool is currently enabled.
+func (r *Registry) Enabled(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.tools[name]
+	return ok && !r.disabled[name]
+}
+
+// Names returns the names of all registered tools (enabled or not), sorted.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.tools))
+	for name := range r.tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetRolePolicy replaces the per-role tool grants used by DefinitionsFor and
+// ExecuteAs.
+func (r *Registry) SetRolePolicy(p RolePolicy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.roles = p
+}
+
+// SetScopePolicy replaces the per-channel tool filters used by
+// DefinitionsFor and ExecuteAs.
+func (r *Registry) SetScopePolicy(p ScopePolicy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.scopes = p
+}
+
 // Definitions returns the list of tool definitions to expose to the model.
 func (r *Registry) Definitions() []providers.ToolDefinition {
+	return r.definitions(func(string) bool { return true })
+}
+
+// DefinitionsFor returns the definitions of the tools role may use in the
+// given scopes, so the model is never offered a tool the current user cannot
+// trigger. Tools granted only some actions are offered with just those.
+func (r *Registry) DefinitionsFor(role Role, scopes ...string) []providers.ToolDefinition {
+	r.mu.RLock()
+	roles, sp := r.roles, r.scopes
+	r.mu.RUnlock()
+	defs := r.definitions(func(name string) bool { return roles.Allows(role, name) && sp.Allows(scopes, name) })
+	out := defs[:0]
+	for _, d := range defs {
+		allowed := func(action string) bool {
+			return roles.AllowsCall(role, d.Name, action) && sp.AllowsCall(scopes, d.Name, action)
+		}
+		if nd, ok := restrictActions(d, allowed); ok {
+			out = append(out, nd)
+		}
+	}
+	return out
+}
+
+// restrictActions narrows the "action" enum of d to the actions allowed.
+// It reports false if no call of d is allowed.
+func restrictActions(d providers.ToolDefinition, allowed func(action string) bool) (providers.ToolDefinition, bool) {
+	props, _ := d.Parameters["properties"].(map[string]interface{})
+	action, _ := props["action"].(map[string]interface{})
+	enum, _ := action["enum"].([]string)
+	if len(enum) == 0 {
+		return d, allowed("")
+	}
+	var keep []string
+	for _, a := range enum {
+		if allowed(a) {
+			keep = append(keep, a)
+		}
+	}
+	if len(keep) == 0 {
+		return d, false
+	}
+	if len(keep) < len(enum) {
+		// copy the maps on the way down rather than change the tool's schema
+		narrowed := maps.Clone(action)
+		narrowed["enum"] = keep
+		p := maps.Clone(props)
+		p["action"] = narrowed
+		d.Parameters = maps.Clone(d.Parameters)
+		d.Parameters["properties"] = p
+	}
+	return d, true
+}
+
+func (r *Registry) definitions(include func(name string) bool) []providers.ToolDefinition {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	defs := make([]providers.ToolDefinition, 0, len(r.tools))
 	for _, t := range r.tools {
+		if r.disabled[t.Name()] || !include(t.Name()) {
+			continue
+		}
 		defs = append(defs, providers.ToolDefinition{
 			Name:        t.Name(),
 			Description: t.Description(),
@@ -65,9 +219,36 @@ func (r *Registry) Execute(ctx context.Context, name string, args map[string]int
 	}
 	r.mu.RLock()
 	t, ok := r.tools[name]
+	off := r.disabled[name]
+	mws := r.middleware
 	r.mu.RUnlock()
 	if !ok {
 		return "", errors.New("tool not found")
 	}
-	return t.Execute(ctx, args)
+	if off {
+		return "", fmt.Errorf("tool %q is disabled", name)
+	}
+	return run(ctx, mws, t, args)
+}
+
+// ExecuteAs is Execute for a caller with the given role in the given scopes;
+// tools or actions the role is not granted or the scopes disable are
+// refused.
+func (r *Registry) ExecuteAs(ctx context.Context, role Role, name string, args map[string]interface{}, scopes ...string) (string, error) {
+	action, _ := args["action"].(string)
+	r.mu.RLock()
+	allowed := r.roles.AllowsCall(role, name, action)
+	inScope := r.scopes.AllowsCall(scopes, name, action)
+	r.mu.RUnlock()
+	what := fmt.Sprintf("tool %q", name)
+	if action != "" {
+		what = fmt.Sprintf("%s action %q", what, action)
+	}
+	if !allowed {
+		return "", fmt.Errorf("%s is not permitted for role %q", what, role)
+	}
+	if !inScope {
+		return "", fmt.Errorf("%s is disabled in this chat", what)
+	}
+	return r.Execute(withRole(ctx, role), name, args)
 }
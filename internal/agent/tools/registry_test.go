@@ -2,6 +2,10 @@ package tools
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -11,9 +15,7 @@ import (
 func TestMessageToolPublishesOutbound(t *testing.T) {
 	b := chat.NewHub(10)
 	mt := NewMessageTool(b)
-	mt.SetContext("cli", "test-chat")
-
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	ctx, cancel := context.WithTimeout(WithChat(context.Background(), "cli", "test-chat"), 1*time.Second)
 	defer cancel()
 	res, err := mt.Execute(ctx, map[string]interface{}{"content": "hello world"})
 	if err != nil {
@@ -32,3 +34,162 @@ func TestMessageToolPublishesOutbound(t *testing.T) {
 		t.Fatalf("no outbound message published")
 	}
 }
+
+func TestMessageToolAttachesWorkspaceFiles(t *testing.T) {
+	ws := t.TempDir()
+	os.WriteFile(filepath.Join(ws, "chart.png"), []byte("PNG"), 0o644)
+	root, err := os.OpenRoot(ws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer root.Close()
+	b := chat.NewHub(10)
+	mt := NewMessageToolWithWorkspace(b, root)
+	ctx := WithChat(context.Background(), "telegram", "1")
+
+	args := map[string]interface{}{"content": "here you go", "files": []interface{}{"chart.png"}}
+	if _, err := mt.Execute(ctx, args); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := <-b.Out
+	if len(out.Attachments) != 1 || out.Attachments[0].FileName() != "chart.png" || string(out.Attachments[0].Data) != "PNG" {
+		t.Fatalf("unexpected attachments: %+v", out.Attachments)
+	}
+
+	// files outside the workspace cannot be sent
+	args = map[string]interface{}{"files": []interface{}{"../secret.txt"}}
+	if _, err := mt.Execute(ctx, args); err == nil {
+		t.Fatal("expected error for path outside the workspace")
+	}
+}
+
+func TestRegistrySetEnabled(t *testing.T) {
+	r := NewRegistry()
+	r.Register(NewMessageTool(chat.NewHub(1)))
+
+	if err := r.SetEnabled("message", false); err != nil {
+		t.Fatalf("SetEnabled: %v", err)
+	}
+	if r.Enabled("message") {
+		t.Fatalf("expected message tool to be disabled")
+	}
+	if len(r.Definitions()) != 0 {
+		t.Fatalf("disabled tool should not be offered to the model")
+	}
+	if _, err := r.Execute(context.Background(), "message", map[string]interface{}{"content": "x"}); err == nil {
+		t.Fatalf("expected error executing disabled tool")
+	}
+
+	if err := r.SetEnabled("message", true); err != nil {
+		t.Fatalf("SetEnabled: %v", err)
+	}
+	if len(r.Definitions()) != 1 {
+		t.Fatalf("expected re-enabled tool to be offered again")
+	}
+	if err := r.SetEnabled("nope", false); err == nil {
+		t.Fatalf("expected error for unknown tool")
+	}
+}
+
+func TestRegistryRolePolicy(t *testing.T) {
+	r := NewRegistry()
+	r.Register(NewMessageTool(chat.NewHub(1)))
+	r.Register(NewExecTool(1))
+
+	if n := len(r.DefinitionsFor(RoleReadOnly)); n != 1 {
+		t.Fatalf("expected readonly to see only the message tool, got %d tools", n)
+	}
+	if n := len(r.DefinitionsFor(RoleOwner)); n != 2 {
+		t.Fatalf("expected owner to see all tools, got %d", n)
+	}
+	if _, err := r.ExecuteAs(context.Background(), RoleUser, "exec", map[string]interface{}{"cmd": "echo hi"}); err == nil {
+		t.Fatalf("expected exec to be refused for role user")
+	}
+	r.SetRolePolicy(RolePolicy{RoleUser: {"exec"}})
+	if _, err := r.ExecuteAs(context.Background(), RoleUser, "exec", map[string]interface{}{"cmd": "echo hi"}); err != nil {
+		t.Fatalf("expected custom policy to grant exec: %v", err)
+	}
+}
+
+func TestRegistryScopePolicy(t *testing.T) {
+	r := NewRegistry()
+	r.Register(NewMessageTool(chat.NewHub(1)))
+	r.Register(NewExecTool(1))
+	r.SetScopePolicy(ScopePolicy{
+		"telegram:group": {Deny: []string{"exec"}},
+		"http":           {Allow: []string{"message"}},
+	})
+
+	if n := len(r.DefinitionsFor(RoleOwner, "telegram", "telegram:group", "telegram:-5")); n != 1 {
+		t.Fatalf("expected exec to be hidden in group chats, got %d tools", n)
+	}
+	if n := len(r.DefinitionsFor(RoleOwner, "telegram", "telegram:5")); n != 2 {
+		t.Fatalf("expected all tools in private chats, got %d", n)
+	}
+	if _, err := r.ExecuteAs(context.Background(), RoleOwner, "exec", map[string]interface{}{"cmd": "echo hi"}, "http"); err == nil {
+		t.Fatalf("expected exec to be refused outside the http allowlist")
+	}
+	if _, err := r.ExecuteAs(context.Background(), RoleOwner, "exec", map[string]interface{}{"cmd": "echo hi"}); err != nil {
+		t.Fatalf("expected exec without scopes to run: %v", err)
+	}
+}
+
+func TestRegistryActionGrants(t *testing.T) {
+	r := NewRegistry()
+	fs, err := NewFilesystemTool(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Close()
+	r.Register(fs)
+
+	defs := r.DefinitionsFor(RoleReadOnly)
+	if len(defs) != 1 {
+		t.Fatalf("expected readonly to be offered filesystem, got %d tools", len(defs))
+	}
+	enum := defs[0].Parameters["properties"].(map[string]interface{})["action"].(map[string]interface{})["enum"]
+	if got := fmt.Sprint(enum); got != "[read list glob grep]" {
+		t.Fatalf("expected only the read-only actions, got %s", got)
+	}
+	if full := fs.Parameters()["properties"].(map[string]interface{})["action"].(map[string]interface{})["enum"]; len(full.([]string)) != 10 {
+		t.Fatalf("the tool's own schema must not change, got %v", full)
+	}
+	if _, err := r.ExecuteAs(context.Background(), RoleReadOnly, "filesystem", map[string]interface{}{"action": "write", "path": "a.txt", "content": "x"}); err == nil {
+		t.Fatal("expected write to be refused for readonly")
+	}
+	if _, err := r.ExecuteAs(context.Background(), RoleReadOnly, "filesystem", map[string]interface{}{"action": "list", "path": "."}); err != nil {
+		t.Fatalf("expected list to be allowed: %v", err)
+	}
+
+	// scopes can take single actions away
+	r.SetScopePolicy(ScopePolicy{"telegram:group": {Deny: []string{"filesystem:write"}}})
+	if _, err := r.ExecuteAs(context.Background(), RoleOwner, "filesystem", map[string]interface{}{"action": "write", "path": "a.txt", "content": "x"}, "telegram:group"); err == nil {
+		t.Fatal("expected write to be refused in group chats")
+	}
+	if defs := r.DefinitionsFor(RoleOwner, "telegram:group"); len(defs) != 1 {
+		t.Fatalf("expected filesystem to stay available, got %d tools", len(defs))
+	}
+}
+
+func TestDocumentation(t *testing.T) {
+	r := NewRegistry()
+	r.Register(NewScratchpadTool())
+	r.Register(NewExecTool(1))
+	r.SetRolePolicy(RolePolicy{RoleReadOnly: {"scratchpad"}})
+
+	doc := r.DocumentationFor(RoleReadOnly)
+	if strings.Contains(doc, "### exec") || !strings.Contains(doc, "### scratchpad") {
+		t.Fatalf("documentation lists the wrong tools:\n%s", doc)
+	}
+	want := "- action (string, required): set, get, append, list or delete [one of: set, get, append, list, delete]"
+	if !strings.Contains(doc, want) {
+		t.Fatalf("missing %q in:\n%s", want, doc)
+	}
+	// required parameters come first
+	if strings.Index(doc, "- action") > strings.Index(doc, "- key") {
+		t.Fatalf("required parameter not first:\n%s", doc)
+	}
+	if Documentation(nil) != "" {
+		t.Fatal("expected no documentation without tools")
+	}
+}
@@ -0,0 +1,209 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/cron"
+)
+
+// RemindTool sets one-shot reminders for the current chat, at a relative
+// or absolute time. Reminders are kept by the cron scheduler, so they
+// survive restarts, but are listed and cancelled apart from cron jobs.
+// Args: {"action": "set", "message": "call mom", "in": "20 minutes"}
+type RemindTool struct {
+	scheduler *cron.Scheduler
+	now       func() time.Time
+}
+
+func NewRemindTool(scheduler *cron.Scheduler) *RemindTool {
+	return &RemindTool{scheduler: scheduler, now: time.Now}
+}
+
+func (t *RemindTool) Name() string { return "remind" }
+func (t *RemindTool) Description() string {
+	return "Remind the user of something once, after a delay ('in': '20 minutes', '2h', '3 days') or at a time ('at': '2026-03-02 09:30', '18:00', 'tomorrow 08:00'). Actions: set, list, cancel. Use cron for repeating jobs."
+}
+
+func (t *RemindTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"description": "set (default), list (this chat's reminders) or cancel",
+				"enum":        []string{"set", "list", "cancel"},
+			},
+			"message": map[string]interface{}{
+				"type":        "string",
+				"description": "What to remind the user of",
+			},
+			"in": map[string]interface{}{
+				"type":        "string",
+				"description": "Delay from now, e.g. '20 minutes', '1h30m', '2 days'",
+			},
+			"at": map[string]interface{}{
+				"type":        "string",
+				"description": "Time in the user's time zone: 'YYYY-MM-DD HH:MM', 'HH:MM' (next occurrence), 'tomorrow HH:MM', or RFC 3339",
+			},
+			"id": map[string]interface{}{
+				"type":        "string",
+				"description": "Reminder ID, for cancel",
+			},
+		},
+	}
+}
+
+func (t *RemindTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	action, _ := args["action"].(string)
+	channel, chatID := chatOf(ctx)
+	switch action {
+	case "", "set":
+		message, _ := args["message"].(string)
+		if strings.TrimSpace(message) == "" {
+			return "", fmt.Errorf("remind: 'message' is required")
+		}
+		in, _ := args["in"].(string)
+		at, _ := args["at"].(string)
+		when, err := t.resolve(in, at)
+		if err != nil {
+			return "", err
+		}
+		id := t.scheduler.AddReminder(message, when, channel, chatID, string(roleOf(ctx)))
+		return fmt.Sprintf("Reminder %s set for %s (in %s).", id, t.format(when), when.Sub(t.now()).Round(time.Minute)), nil
+
+	case "list":
+		var sb strings.Builder
+		for _, j := range t.reminders(channel, chatID) {
+			fmt.Fprintf(&sb, "- %s: %q at %s\n", j.ID, j.Message, t.format(j.FireAt))
+		}
+		if sb.Len() == 0 {
+			return "No pending reminders.", nil
+		}
+		return fmt.Sprintf("Now: %s\n%s", t.format(t.now()), sb.String()), nil
+
+	case "cancel":
+		id, _ := args["id"].(string)
+		if id == "" {
+			return "", fmt.Errorf("remind: cancel needs 'id'")
+		}
+		for _, j := range t.reminders(channel, chatID) {
+			if j.ID == id {
+				t.scheduler.Cancel(id)
+				return fmt.Sprintf("Cancelled reminder %s.", id), nil
+			}
+		}
+		return fmt.Sprintf("No reminder %s in this chat.", id), nil
+
+	default:
+		return "", fmt.Errorf("remind: unknown action %q (use set, list or cancel)", action)
+	}
+}
+
+// reminders returns the pending reminders of a chat.
+func (t *RemindTool) reminders(channel, chatID string) []cron.Job {
+	var out []cron.Job
+	for _, j := range t.scheduler.List() {
+		if j.Reminder && j.Channel == channel && j.ChatID == chatID {
+			out = append(out, j)
+		}
+	}
+	return out
+}
+
+// resolve returns the time a reminder is due from exactly one of in and at.
+func (t *RemindTool) resolve(in, at string) (time.Time, error) {
+	now := t.now()
+	switch {
+	case in != "" && at != "":
+		return time.Time{}, fmt.Errorf("remind: give either 'in' or 'at', not both")
+	case in != "":
+		d, err := parseDelay(in)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(d), nil
+	case at != "":
+		when, err := parseAt(at, now.In(t.scheduler.Location()))
+		if err != nil {
+			return time.Time{}, err
+		}
+		if !when.After(now) {
+			return time.Time{}, fmt.Errorf("remind: %s is in the past (now is %s)", t.format(when), t.format(now))
+		}
+		return when, nil
+	}
+	return time.Time{}, fmt.Errorf("remind: 'in' or 'at' is required")
+}
+
+func (t *RemindTool) format(tm time.Time) string {
+	return tm.In(t.scheduler.Location()).Format("Mon Jan 2 15:04 MST")
+}
+
+var delayPartRE = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*([a-z]+)`)
+
+// delayUnits maps the unit words accepted in delays to their length.
+var delayUnits = map[string]time.Duration{
+	"s": time.Second, "sec": time.Second, "secs": time.Second, "second": time.Second, "seconds": time.Second,
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour, "week": 7 * 24 * time.Hour, "weeks": 7 * 24 * time.Hour,
+}
+
+// parseDelay parses a Go duration or a delay in words such as
+// "2 hours and 15 minutes" or "3d".
+func parseDelay(s string) (time.Duration, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return d, nil
+	}
+	rest := strings.NewReplacer(",", " ", " and ", " ").Replace(s)
+	var total time.Duration
+	for _, m := range delayPartRE.FindAllStringSubmatch(rest, -1) {
+		unit, ok := delayUnits[m[2]]
+		n, err := strconv.ParseFloat(m[1], 64)
+		if !ok || err != nil {
+			return 0, fmt.Errorf("remind: invalid delay %q", s)
+		}
+		total += time.Duration(n * float64(unit))
+		rest = strings.Replace(rest, m[0], "", 1)
+	}
+	if total <= 0 || strings.TrimSpace(rest) != "" {
+		return 0, fmt.Errorf("remind: invalid delay %q (e.g. '20 minutes', '1h30m', '2 days')", s)
+	}
+	return total, nil
+}
+
+// parseAt parses an absolute time, in now's location unless it has a zone.
+// A bare clock time means its next occurrence.
+func parseAt(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if tm, err := time.Parse(time.RFC3339, s); err == nil {
+		return tm, nil
+	}
+	for _, layout := range calendarLayouts {
+		if tm, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return tm, nil
+		}
+	}
+	day := now
+	clock := s
+	if rest, ok := strings.CutPrefix(strings.ToLower(s), "tomorrow"); ok {
+		day = now.AddDate(0, 0, 1)
+		clock = strings.TrimSpace(strings.TrimPrefix(rest, " at"))
+	}
+	c, err := time.Parse("15:04", clock)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("remind: invalid time %q (use 'YYYY-MM-DD HH:MM', 'HH:MM' or 'tomorrow HH:MM')", s)
+	}
+	tm := time.Date(day.Year(), day.Month(), day.Day(), c.Hour(), c.Minute(), 0, 0, now.Location())
+	if clock == s && !tm.After(now) {
+		tm = tm.AddDate(0, 0, 1)
+	}
+	return tm, nil
+}
@@ -0,0 +1,84 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/cron"
+)
+
+func TestParseDelay(t *testing.T) {
+	cases := map[string]time.Duration{
+		"20 minutes":             20 * time.Minute,
+		"1h30m":                  90 * time.Minute,
+		"2 hours and 15 minutes": 135 * time.Minute,
+		"3d":                     72 * time.Hour,
+		"1.5 hours":              90 * time.Minute,
+		"1 week":                 7 * 24 * time.Hour,
+	}
+	for in, want := range cases {
+		if got, err := parseDelay(in); err != nil || got != want {
+			t.Errorf("parseDelay(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"soon", "20 parsecs", "-5m", "5 minutes later"} {
+		if _, err := parseDelay(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestParseAt(t *testing.T) {
+	now := time.Date(2026, 3, 2, 18, 30, 0, 0, time.UTC)
+	cases := map[string]string{
+		"2026-03-05 09:15":          "2026-03-05 09:15",
+		"20:00":                     "2026-03-02 20:00",
+		"08:00":                     "2026-03-03 08:00", // already passed today
+		"tomorrow 07:45":            "2026-03-03 07:45",
+		"2026-03-04T10:00:00+01:00": "2026-03-04 09:00",
+	}
+	for in, want := range cases {
+		got, err := parseAt(in, now)
+		if err != nil || got.UTC().Format("2006-01-02 15:04") != want {
+			t.Errorf("parseAt(%q) = %v, %v; want %s", in, got, err, want)
+		}
+	}
+}
+
+func TestRemindTool(t *testing.T) {
+	s := cron.NewScheduler(nil)
+	s.SetLocation(time.UTC)
+	rt := NewRemindTool(s)
+	now := time.Date(2026, 3, 2, 18, 30, 0, 0, time.UTC)
+	rt.now = func() time.Time { return now }
+	ctx := WithChat(context.Background(), "telegram", "1")
+
+	out, err := rt.Execute(ctx, map[string]interface{}{"message": "call mom", "in": "20 minutes"})
+	if err != nil || !strings.Contains(out, "Mon Mar 2 18:50 UTC") {
+		t.Fatalf("unexpected result %q, %v", out, err)
+	}
+	if _, err := rt.Execute(ctx, map[string]interface{}{"message": "x", "at": "2026-03-01 10:00"}); err == nil {
+		t.Fatal("expected a time in the past to be rejected")
+	}
+	s.AddCron("digest", "summarize feeds", "@daily", "", "telegram", "1", "owner")
+
+	out, _ = rt.Execute(ctx, map[string]interface{}{"action": "list"})
+	if !strings.Contains(out, `"call mom"`) || strings.Contains(out, "digest") {
+		t.Fatalf("expected only the reminder to be listed:\n%s", out)
+	}
+	ct := NewCronTool(s)
+	if out, _ := ct.Execute(ctx, map[string]interface{}{"action": "list"}); strings.Contains(out, "call mom") {
+		t.Fatalf("cron listed the reminder:\n%s", out)
+	}
+
+	ctx = WithChat(context.Background(), "telegram", "2")
+	if out, _ := rt.Execute(ctx, map[string]interface{}{"action": "cancel", "id": "job-1"}); !strings.Contains(out, "No reminder") {
+		t.Fatalf("another chat cancelled the reminder: %q", out)
+	}
+	ctx = WithChat(context.Background(), "telegram", "1")
+	if out, _ := rt.Execute(ctx, map[string]interface{}{"action": "cancel", "id": "job-1"}); out != "Cancelled reminder job-1." {
+		t.Fatalf("unexpected cancel result %q", out)
+	}
+}
@@ -0,0 +1,78 @@
+//go:build linux
+
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// rlimitEnv passes the limits and the program to run from limitCommand to
+// the helper process, as "<cpu seconds> <address space bytes> <path>".
+const rlimitEnv = "PICOBOT_EXEC_RLIMITS"
+
+func init() {
+	if spec, ok := os.LookupEnv(rlimitEnv); ok {
+		execLimited(spec)
+	}
+}
+
+// limitCommand makes cmd start as a copy of the running binary, which sets
+// the CPU time and address space limits of p on itself and then execs the
+// command (see execLimited). The limits are in place before its first
+// instruction runs, and its children inherit them. cmd.Env must already be
+// set.
+func limitCommand(cmd *exec.Cmd, p ExecProfile) {
+	if (p.CPUSeconds <= 0 && p.MemoryMB <= 0) || cmd.Err != nil {
+		return
+	}
+	self, err := os.Executable()
+	if err != nil {
+		cmd.Err = fmt.Errorf("exec: cannot apply resource limits: %w", err)
+		return
+	}
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	spec := fmt.Sprintf("%d %d %s", max(p.CPUSeconds, 0), max(p.MemoryMB, 0)<<20, cmd.Path)
+	cmd.Env = append(env[:len(env):len(env)], rlimitEnv+"="+spec)
+	cmd.Path = self
+}
+
+// execLimited runs in the helper process started by limitCommand: it sets
+// the limits in spec on itself and replaces itself with the program, keeping
+// the arguments it was started with. It does not return.
+func execLimited(spec string) {
+	fail := func(err error) {
+		fmt.Fprintf(os.Stderr, "picobot: %v\n", err)
+		os.Exit(127)
+	}
+	fields := strings.SplitN(spec, " ", 3)
+	if len(fields) != 3 {
+		fail(fmt.Errorf("malformed %s", rlimitEnv))
+	}
+	cpu, err1 := strconv.ParseUint(fields[0], 10, 64)
+	mem, err2 := strconv.ParseUint(fields[1], 10, 64)
+	if err := errors.Join(err1, err2); err != nil {
+		fail(fmt.Errorf("malformed %s: %w", rlimitEnv, err))
+	}
+	if cpu > 0 {
+		if err := unix.Setrlimit(unix.RLIMIT_CPU, &unix.Rlimit{Cur: cpu, Max: cpu}); err != nil {
+			fail(fmt.Errorf("set CPU time limit: %w", err))
+		}
+	}
+	if mem > 0 {
+		if err := unix.Setrlimit(unix.RLIMIT_AS, &unix.Rlimit{Cur: mem, Max: mem}); err != nil {
+			fail(fmt.Errorf("set address space limit: %w", err))
+		}
+	}
+	os.Unsetenv(rlimitEnv)
+	fail(unix.Exec(fields[2], os.Args, os.Environ()))
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package tools
+
+import "os/exec"
+
+// limitCommand is only implemented on Linux; elsewhere the CPU and memory
+// limits of a profile are not enforced.
+func limitCommand(cmd *exec.Cmd, p ExecProfile) {}
@@ -0,0 +1,87 @@
+package tools
+
+import (
+	"context"
+	"strings"
+)
+
+// Role is the access level of the person talking to the agent.
+type Role string
+
+const (
+	RoleOwner    Role = "owner"    // full access
+	RoleUser     Role = "user"     // everything except running commands
+	RoleReadOnly Role = "readonly" // chat, fetch pages, read and search files and skills; no writes
+)
+
+type roleKey struct{}
+
+// withRole returns a copy of ctx naming the role a tool call is made with.
+func withRole(ctx context.Context, role Role) context.Context {
+	return context.WithValue(ctx, roleKey{}, role)
+}
+
+// roleOf returns the role of ctx, or "" if ctx names none. Tools that act
+// later on the caller's behalf, such as scheduled jobs, keep it.
+func roleOf(ctx context.Context) Role {
+	r, _ := ctx.Value(roleKey{}).(Role)
+	return r
+}
+
+// RolePolicy maps each role to the tools it may use. "*" grants every tool,
+// a trailing "*" every tool with that prefix (e.g. "mcp_github_*"), and
+// "tool:action" one action of a tool with an "action" argument, e.g.
+// "filesystem:read". Roles missing from the policy may use no tools.
+type RolePolicy map[Role][]string
+
+// DefaultRolePolicy returns the built-in tool grants per role.
+func DefaultRolePolicy() RolePolicy {
+	return RolePolicy{
+		RoleOwner: {"*"},
+		RoleUser: {"message", "confirm", "report_progress", "update_plan", "filesystem", "web", "search", "feeds", "scratchpad", "spawn", "subagent_status", "cron", "remind", "write_memory", "search_memory", "forget",
+			"create_skill", "list_skills", "read_skill", "delete_skill"},
+		RoleReadOnly: {"message", "confirm", "report_progress", "update_plan", "web", "search", "scratchpad", "list_skills", "read_skill",
+			"filesystem:read", "filesystem:list", "filesystem:glob", "filesystem:grep", "cron:list", "remind:list", "feeds:list"},
+	}
+}
+
+// Allows reports whether role may use the named tool, or some of its
+// actions.
+func (p RolePolicy) Allows(role Role, tool string) bool {
+	return grantsTool(p[role], tool)
+}
+
+// AllowsCall reports whether role may call tool with action, the call's
+// "action" argument ("" for tools without one).
+func (p RolePolicy) AllowsCall(role Role, tool, action string) bool {
+	return grantsCall(p[role], tool, action)
+}
+
+// grantsTool reports whether list grants tool or one of its actions.
+func grantsTool(list []string, tool string) bool {
+	for _, name := range list {
+		if matchesTool(name, tool) || strings.HasPrefix(name, tool+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// grantsCall reports whether list grants action of tool.
+func grantsCall(list []string, tool, action string) bool {
+	for _, name := range list {
+		if matchesTool(name, tool) || (action != "" && name == tool+":"+action) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesTool reports whether a grant names tool, or is a prefix pattern
+// ending in "*" that matches it.
+func matchesTool(name, tool string) bool {
+	if prefix, ok := strings.CutSuffix(name, "*"); ok {
+		return strings.HasPrefix(tool, prefix)
+	}
+	return name == tool
+}
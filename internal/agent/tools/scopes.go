@@ -0,0 +1,51 @@
+package tools
+
+// ToolFilter enables or disables tools for a scope. An empty Allow permits
+// every tool; Deny always wins. Entries may name one action of a tool, as
+// in RolePolicy ("filesystem:write").
+type ToolFilter struct {
+	Allow []string
+	Deny  []string
+}
+
+// ScopePolicy maps scopes to tool filters. A scope is a channel name
+// ("telegram"), a channel's group chats ("telegram:group") or a single chat
+// ("telegram:-1001234"). A tool must pass the filter of every scope that
+// applies to a message.
+type ScopePolicy map[string]ToolFilter
+
+// Allows reports whether tool, or some of its actions, may be used in a
+// context covered by scopes.
+func (p ScopePolicy) Allows(scopes []string, tool string) bool {
+	for _, s := range scopes {
+		f, ok := p[s]
+		if !ok {
+			continue
+		}
+		if grantsCall(f.Deny, tool, "") {
+			return false
+		}
+		if len(f.Allow) > 0 && !grantsTool(f.Allow, tool) {
+			return false
+		}
+	}
+	return true
+}
+
+// AllowsCall reports whether tool may be called with action in a context
+// covered by scopes.
+func (p ScopePolicy) AllowsCall(scopes []string, tool, action string) bool {
+	for _, s := range scopes {
+		f, ok := p[s]
+		if !ok {
+			continue
+		}
+		if grantsCall(f.Deny, tool, action) {
+			return false
+		}
+		if len(f.Allow) > 0 && !grantsCall(f.Allow, tool, action) {
+			return false
+		}
+	}
+	return true
+}
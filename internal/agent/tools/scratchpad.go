@@ -0,0 +1,132 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Limits of the scratchpad of one chat.
+const (
+	maxScratchKeys  = 100
+	maxScratchBytes = 256 << 10
+)
+
+// ScratchpadTool keeps notes for the current chat in memory, so the agent
+// can stash intermediate results between tool calls without writing them
+// to memory files. Notes are lost on restart and cleared by /reset.
+// Args: {"action": "set", "key": "candidates", "value": "..."}
+type ScratchpadTool struct {
+	mu   sync.Mutex
+	pads map[string]map[string]string // session key -> notes
+}
+
+func NewScratchpadTool() *ScratchpadTool {
+	return &ScratchpadTool{pads: make(map[string]map[string]string)}
+}
+
+// Clear drops the scratchpad of a chat.
+func (t *ScratchpadTool) Clear(channel, chatID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.pads, channel+":"+chatID)
+}
+
+func (t *ScratchpadTool) Name() string { return "scratchpad" }
+func (t *ScratchpadTool) Description() string {
+	return "Keep intermediate results for this conversation (not saved to memory, cleared by /reset). Actions: set, get, append, list, delete."
+}
+
+func (t *ScratchpadTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"description": "set, get, append, list or delete",
+				"enum":        []string{"set", "get", "append", "list", "delete"},
+			},
+			"key": map[string]interface{}{
+				"type":        "string",
+				"description": "The note's name (all actions except list)",
+			},
+			"value": map[string]interface{}{
+				"type":        "string",
+				"description": "set/append: the text to store or add",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *ScratchpadTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	action, _ := args["action"].(string)
+	key, _ := args["key"].(string)
+	key = strings.TrimSpace(key)
+	value, _ := args["value"].(string)
+	if action != "list" && key == "" {
+		return "", fmt.Errorf("scratchpad: %s needs 'key'", action)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	channel, chatID := chatOf(ctx)
+	session := channel + ":" + chatID
+	pad := t.pads[session]
+	switch action {
+	case "set", "append":
+		if _, ok := pad[key]; !ok && len(pad) >= maxScratchKeys {
+			return "", fmt.Errorf("scratchpad: full (%d keys); delete some first", maxScratchKeys)
+		}
+		if action == "append" {
+			value = pad[key] + value
+		}
+		if padSize(pad)-len(pad[key])+len(value) > maxScratchBytes {
+			return "", fmt.Errorf("scratchpad: full (%d bytes); delete some notes first", maxScratchBytes)
+		}
+		if pad == nil {
+			pad = make(map[string]string)
+			t.pads[session] = pad
+		}
+		pad[key] = value
+		return fmt.Sprintf("%s: %d bytes", key, len(value)), nil
+	case "get":
+		v, ok := pad[key]
+		if !ok {
+			return "", fmt.Errorf("scratchpad: no key %q", key)
+		}
+		return v, nil
+	case "delete":
+		if _, ok := pad[key]; !ok {
+			return "", fmt.Errorf("scratchpad: no key %q", key)
+		}
+		delete(pad, key)
+		return "deleted " + key, nil
+	case "list":
+		if len(pad) == 0 {
+			return "The scratchpad is empty.", nil
+		}
+		keys := make([]string, 0, len(pad))
+		for k := range pad {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var sb strings.Builder
+		for _, k := range keys {
+			fmt.Fprintf(&sb, "- %s (%d bytes)\n", k, len(pad[k]))
+		}
+		return strings.TrimRight(sb.String(), "\n"), nil
+	}
+	return "", fmt.Errorf("scratchpad: unknown action %q", action)
+}
+
+// padSize returns the total length of the notes in pad.
+func padSize(pad map[string]string) int {
+	n := 0
+	for _, v := range pad {
+		n += len(v)
+	}
+	return n
+}
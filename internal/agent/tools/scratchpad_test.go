@@ -0,0 +1,41 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestScratchpad(t *testing.T) {
+	sp := NewScratchpadTool()
+	ctx := WithChat(context.Background(), "telegram", "1")
+	run := func(args map[string]interface{}) (string, error) { return sp.Execute(ctx, args) }
+
+	if _, err := run(map[string]interface{}{"action": "set", "key": "plan", "value": "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run(map[string]interface{}{"action": "append", "key": "plan", "value": "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if out, _ := run(map[string]interface{}{"action": "get", "key": "plan"}); out != "ab" {
+		t.Fatalf("get = %q", out)
+	}
+	if out, _ := run(map[string]interface{}{"action": "list"}); !strings.Contains(out, "plan (2 bytes)") {
+		t.Fatalf("list = %q", out)
+	}
+
+	// another chat has its own scratchpad
+	ctx = WithChat(context.Background(), "telegram", "2")
+	if _, err := run(map[string]interface{}{"action": "get", "key": "plan"}); err == nil {
+		t.Fatal("notes leaked into another chat")
+	}
+	if _, err := run(map[string]interface{}{"action": "set", "key": "big", "value": strings.Repeat("x", maxScratchBytes+1)}); err == nil {
+		t.Fatal("expected the size limit to apply")
+	}
+
+	sp.Clear("telegram", "1")
+	ctx = WithChat(context.Background(), "telegram", "1")
+	if out, _ := run(map[string]interface{}{"action": "list"}); out != "The scratchpad is empty." {
+		t.Fatalf("after Clear: %q", out)
+	}
+}
@@ -0,0 +1,297 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/webtext"
+)
+
+// Search backends.
+const (
+	SearchDuckDuckGo = "duckduckgo"
+	SearchSearXNG    = "searxng"
+	SearchBrave      = "brave"
+)
+
+const (
+	defaultSearchResults = 5
+	maxSearchResults     = 20
+	searchTimeout        = 20 * time.Second
+	maxSearchBody        = 2 << 20
+
+	duckDuckGoURL = "https://html.duckduckgo.com/html/"
+	braveURL      = "https://api.search.brave.com/res/v1/web/search"
+)
+
+// SearchResult is one hit of a web search.
+type SearchResult struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Snippet string `json:"snippet"`
+}
+
+// SearchBackend runs web searches.
+type SearchBackend interface {
+	Name() string
+	Search(ctx context.Context, client *http.Client, query string, n int) ([]SearchResult, error)
+}
+
+// NewSearchBackend returns the named backend. SearXNG needs the URL of an
+// instance with the JSON format enabled, Brave an API key; DuckDuckGo needs
+// neither.
+func NewSearchBackend(name, baseURL, apiKey string) (SearchBackend, error) {
+	switch name {
+	case "", SearchDuckDuckGo:
+		return duckDuckGo{endpoint: duckDuckGoURL}, nil
+	case SearchSearXNG:
+		if baseURL == "" {
+			return nil, fmt.Errorf("search: the searxng backend needs the instance url")
+		}
+		return searXNG{endpoint: strings.TrimSuffix(baseURL, "/") + "/search"}, nil
+	case SearchBrave:
+		if apiKey == "" {
+			return nil, fmt.Errorf("search: the brave backend needs an api key")
+		}
+		return brave{endpoint: braveURL, apiKey: apiKey}, nil
+	}
+	return nil, fmt.Errorf("search: unknown backend %q (want duckduckgo, searxng or brave)", name)
+}
+
+// SearchTool searches the web and returns a JSON list of results.
+// Args: {"query": "...", "count": 5}
+//
+// The backend is chosen by the operator, so its endpoint (often a SearXNG
+// instance on the local network) is not subject to the SSRF checks of the
+// web tool; the result URLs are only fetched through that tool.
+type SearchTool struct {
+	backend   SearchBackend
+	transport http.RoundTripper // nil means http.DefaultTransport
+}
+
+func NewSearchTool() *SearchTool { return &SearchTool{backend: duckDuckGo{endpoint: duckDuckGoURL}} }
+
+// UntrustedOutput marks search results as untrusted data.
+func (t *SearchTool) UntrustedOutput() bool { return true }
+
+// SetBackend selects the search backend.
+func (t *SearchTool) SetBackend(b SearchBackend) { t.backend = b }
+
+func (t *SearchTool) Name() string { return "search" }
+func (t *SearchTool) Description() string {
+	return "Search the web. Returns a JSON list of {title, url, snippet}; fetch a result with the web tool to read it."
+}
+
+func (t *SearchTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "The search query",
+			},
+			"count": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Number of results (default %d, max %d)", defaultSearchResults, maxSearchResults),
+			},
+		},
+		"required": []string{"query"},
+	}
+}
+
+func (t *SearchTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	query, _ := args["query"].(string)
+	if strings.TrimSpace(query) == "" {
+		return "", fmt.Errorf("search: 'query' argument required")
+	}
+	n := defaultSearchResults
+	if v, ok := args["count"].(float64); ok && v > 0 {
+		n = min(int(v), maxSearchResults)
+	}
+	ctx, cancel := context.WithTimeout(ctx, searchTimeout)
+	defer cancel()
+	results, err := t.backend.Search(ctx, &http.Client{Transport: t.transport}, query, n)
+	if err != nil {
+		return "", fmt.Errorf("search: %s: %w", t.backend.Name(), err)
+	}
+	if len(results) > n {
+		results = results[:n]
+	}
+	if len(results) == 0 {
+		return "No results.", nil
+	}
+	b, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// getSearch sends req and returns the response body.
+func getSearch(client *http.Client, req *http.Request) ([]byte, error) {
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; picobot)")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxSearchBody))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %s: %s", resp.Status, truncateChars(strings.TrimSpace(string(b)), 200))
+	}
+	return b, nil
+}
+
+// plainText strips the markup search engines put in titles and snippets.
+func plainText(s string) string {
+	return webtext.Parse(s).TextContent()
+}
+
+type searXNG struct{ endpoint string }
+
+func (searXNG) Name() string { return SearchSearXNG }
+
+func (s searXNG) Search(ctx context.Context, client *http.Client, query string, n int) ([]SearchResult, error) {
+	q := url.Values{"q": {query}, "format": {"json"}}
+	req, err := http.NewRequestWithContext(ctx, "GET", s.endpoint+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	b, err := getSearch(client, req)
+	if err != nil {
+		return nil, err
+	}
+	var body struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(b, &body); err != nil {
+		return nil, fmt.Errorf("decode response (is the json format enabled on the instance?): %w", err)
+	}
+	var out []SearchResult
+	for _, r := range body.Results {
+		if len(out) == n {
+			break
+		}
+		out = append(out, SearchResult{Title: plainText(r.Title), URL: r.URL, Snippet: plainText(r.Content)})
+	}
+	return out, nil
+}
+
+type brave struct{ endpoint, apiKey string }
+
+func (brave) Name() string { return SearchBrave }
+
+func (s brave) Search(ctx context.Context, client *http.Client, query string, n int) ([]SearchResult, error) {
+	q := url.Values{"q": {query}, "count": {fmt.Sprint(n)}}
+	req, err := http.NewRequestWithContext(ctx, "GET", s.endpoint+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Subscription-Token", s.apiKey)
+	b, err := getSearch(client, req)
+	if err != nil {
+		return nil, err
+	}
+	var body struct {
+		Web struct {
+			Results []struct {
+				Title       string `json:"title"`
+				URL         string `json:"url"`
+				Description string `json:"description"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	if err := json.Unmarshal(b, &body); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	var out []SearchResult
+	for _, r := range body.Web.Results {
+		out = append(out, SearchResult{Title: plainText(r.Title), URL: r.URL, Snippet: plainText(r.Description)})
+	}
+	return out, nil
+}
+
+// duckDuckGo scrapes the HTML version of DuckDuckGo, which needs no API
+// key. Its markup may change without notice.
+type duckDuckGo struct{ endpoint string }
+
+func (duckDuckGo) Name() string { return SearchDuckDuckGo }
+
+var (
+	ddgResult  = mustCompile(".result")
+	ddgTitle   = mustCompile("a.result__a")
+	ddgSnippet = mustCompile(".result__snippet")
+)
+
+func mustCompile(sel string) *webtext.Selector {
+	s, err := webtext.Compile(sel)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func (s duckDuckGo) Search(ctx context.Context, client *http.Client, query string, n int) ([]SearchResult, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", s.endpoint, strings.NewReader(url.Values{"q": {query}}.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	b, err := getSearch(client, req)
+	if err != nil {
+		return nil, err
+	}
+	var out []SearchResult
+	for _, r := range ddgResult.Select(webtext.Parse(string(b))) {
+		if len(out) == n {
+			break
+		}
+		if strings.Contains(r.Attr("class"), "result--ad") {
+			continue
+		}
+		links := ddgTitle.Select(r)
+		if len(links) == 0 {
+			continue
+		}
+		link := ddgResultURL(links[0].Attr("href"))
+		if link == "" {
+			continue
+		}
+		res := SearchResult{Title: links[0].TextContent(), URL: link}
+		if sn := ddgSnippet.Select(r); len(sn) > 0 {
+			res.Snippet = sn[0].TextContent()
+		}
+		out = append(out, res)
+	}
+	return out, nil
+}
+
+// ddgResultURL returns the target of a result link, which DuckDuckGo may
+// wrap in a redirect like //duckduckgo.com/l/?uddg=<url>.
+func ddgResultURL(href string) string {
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if target := u.Query().Get("uddg"); target != "" {
+		return target
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	return href
+}
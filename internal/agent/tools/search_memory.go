@@ -0,0 +1,126 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/agent/memory"
+)
+
+// SearchMemoryTool looks up memories by words, tag and time range in the
+// memory database, including notes older than those in the prompt.
+type SearchMemoryTool struct {
+	mem *memory.MemoryStore
+}
+
+func NewSearchMemoryTool(mem *memory.MemoryStore) *SearchMemoryTool {
+	return &SearchMemoryTool{mem: mem}
+}
+
+func (t *SearchMemoryTool) Name() string { return "search_memory" }
+
+func (t *SearchMemoryTool) Description() string {
+	return "Search daily notes and long-term memory by keyword, tag and date range, newest first"
+}
+
+func (t *SearchMemoryTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "Words the memory contains, in any order; a word also matches longer words it starts",
+			},
+			"tag": map[string]interface{}{
+				"type":        "string",
+				"description": "Tag given with write_memory",
+			},
+			"since": map[string]interface{}{
+				"type":        "string",
+				"description": "Earliest date, YYYY-MM-DD or RFC 3339 (UTC)",
+			},
+			"until": map[string]interface{}{
+				"type":        "string",
+				"description": "Latest date, YYYY-MM-DD (inclusive) or RFC 3339 (UTC)",
+			},
+			"kind": map[string]interface{}{
+				"type":        "string",
+				"description": "Only 'short' (daily notes) or 'long' (long-term memory)",
+				"enum":        []string{"short", "long"},
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum results (default 20)",
+			},
+		},
+	}
+}
+
+func (t *SearchMemoryTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	q := memory.Query{Limit: 20}
+	q.Text, _ = args["query"].(string)
+	q.Tag, _ = args["tag"].(string)
+	switch kind, _ := args["kind"].(string); kind {
+	case "short", "long":
+		q.Kind = kind
+	case "today": // write_memory's name for daily notes
+		q.Kind = "short"
+	case "":
+	default:
+		return "", fmt.Errorf("search_memory: unknown kind '%s'", kind)
+	}
+	if n, ok := args["limit"].(float64); ok && n > 0 {
+		q.Limit = int(n)
+	}
+	var err error
+	if s, _ := args["since"].(string); s != "" {
+		if q.Since, err = parseMemoryDate(s, false); err != nil {
+			return "", fmt.Errorf("search_memory: %w", err)
+		}
+	}
+	if s, _ := args["until"].(string); s != "" {
+		if q.Until, err = parseMemoryDate(s, true); err != nil {
+			return "", fmt.Errorf("search_memory: %w", err)
+		}
+	}
+	items, err := t.mem.Query(q)
+	if err != nil {
+		return "", err
+	}
+	if len(items) == 0 {
+		return "No matching memories.", nil
+	}
+	var sb strings.Builder
+	for _, it := range items {
+		fmt.Fprintf(&sb, "- [%s] %s", it.Timestamp.Format("2006-01-02 15:04"), it.Text)
+		for _, tag := range it.Tags {
+			sb.WriteString(" #" + tag)
+		}
+		if it.Kind == "long" {
+			sb.WriteString(" (long-term)")
+		}
+		if !it.Expires.IsZero() {
+			sb.WriteString(" (until " + it.Expires.Format("2006-01-02 15:04") + " UTC)")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String(), nil
+}
+
+// parseMemoryDate parses a date or time given to the memory tools. A bare
+// date used as the end of a range covers the whole day.
+func parseMemoryDate(s string, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q (want YYYY-MM-DD or RFC 3339)", s)
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
@@ -0,0 +1,78 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSearchBackends(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/searx/search":
+			if r.URL.Query().Get("format") != "json" {
+				http.Error(w, "format", http.StatusForbidden)
+				return
+			}
+			io.WriteString(w, `{"results":[{"title":"Go","url":"https://go.dev/","content":"The <b>Go</b> language"}]}`)
+		case "/brave":
+			if r.Header.Get("X-Subscription-Token") != "k" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			io.WriteString(w, `{"web":{"results":[{"title":"Go","url":"https://go.dev/","description":"The <strong>Go</strong> language"}]}}`)
+		case "/ddg":
+			r.ParseForm()
+			if r.PostForm.Get("q") != "golang" {
+				http.Error(w, "query", http.StatusBadRequest)
+				return
+			}
+			io.WriteString(w, `<div class="result results_links result--ad"><a class="result__a" href="https://ads.example/">Ad</a></div>
+<div class="result results_links"><h2><a class="result__a" href="//duckduckgo.com/l/?uddg=https%3A%2F%2Fgo.dev%2F&amp;rut=x">Go</a></h2>
+<a class="result__snippet" href="#">The <b>Go</b> language</a></div>`)
+		}
+	}))
+	defer srv.Close()
+
+	want := SearchResult{Title: "Go", URL: "https://go.dev/", Snippet: "The Go language"}
+	for _, b := range []SearchBackend{searXNG{endpoint: srv.URL + "/searx/search"}, brave{endpoint: srv.URL + "/brave", apiKey: "k"}, duckDuckGo{endpoint: srv.URL + "/ddg"}} {
+		st := NewSearchTool()
+		st.SetBackend(b)
+		out, err := st.Execute(context.Background(), map[string]interface{}{"query": "golang"})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", b.Name(), err)
+		}
+		var got []SearchResult
+		if err := json.Unmarshal([]byte(out), &got); err != nil {
+			t.Fatalf("%s: output is not JSON: %q", b.Name(), out)
+		}
+		if len(got) != 1 || got[0] != want {
+			t.Fatalf("%s: unexpected results %+v", b.Name(), got)
+		}
+	}
+
+	st := NewSearchTool()
+	st.SetBackend(brave{endpoint: srv.URL + "/brave", apiKey: "wrong"})
+	if _, err := st.Execute(context.Background(), map[string]interface{}{"query": "golang"}); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("expected the HTTP error to be reported, got %v", err)
+	}
+}
+
+func TestNewSearchBackend(t *testing.T) {
+	if _, err := NewSearchBackend(SearchSearXNG, "", ""); err == nil {
+		t.Fatalf("expected searxng without url to fail")
+	}
+	if _, err := NewSearchBackend(SearchBrave, "", ""); err == nil {
+		t.Fatalf("expected brave without api key to fail")
+	}
+	if _, err := NewSearchBackend("bing", "", ""); err == nil {
+		t.Fatalf("expected unknown backend to fail")
+	}
+	if b, err := NewSearchBackend("", "", ""); err != nil || b.Name() != SearchDuckDuckGo {
+		t.Fatalf("expected duckduckgo by default, got %v, %v", b, err)
+	}
+}
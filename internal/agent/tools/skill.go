@@ -4,20 +4,36 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
+	"path"
+	"path/filepath"
+	"slices"
 	"strings"
+
+	"github.com/kr0nicas/picobot/internal/agent/skills"
+	"github.com/kr0nicas/picobot/internal/workspace"
 )
 
 // SkillMetadata holds metadata parsed from SKILL.md frontmatter.
 type SkillMetadata struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Triggers    []string `json:"triggers,omitempty"`
+	AlwaysLoad  bool     `json:"always_load,omitempty"`
+	// Scripts, Dependencies and Examples make up the manifest of skills
+	// that bundle helper scripts, see skills.Skill.
+	Scripts      []string `json:"scripts,omitempty"`
+	Dependencies []string `json:"dependencies,omitempty"`
+	Examples     []string `json:"examples,omitempty"`
 }
 
 // SkillManager provides tools for managing skills in the workspace.
 // All file operations are sandboxed via os.Root (Go 1.24+).
 type SkillManager struct {
-	root *os.Root // rooted at the workspace directory
+	root     *os.Root            // rooted at the workspace directory
+	strict   bool                // refuse skill paths through symlinks
+	onChange func(change string) // called after a skill is added or deleted
 }
 
 // NewSkillManager creates a new skill manager backed by an os.Root.
@@ -25,6 +41,34 @@ func NewSkillManager(root *os.Root) *SkillManager {
 	return &SkillManager{root: root}
 }
 
+// SetStrictPaths makes the skill tools refuse paths through symlinks (see
+// workspace.Jail).
+func (sm *SkillManager) SetStrictPaths(strict bool) { sm.strict = strict }
+
+// SetOnChange sets a function called after a skill is created, imported or
+// deleted, such as the invalidation of a skills cache. It gets a summary of
+// the change, e.g. `create skill "weather"`.
+func (sm *SkillManager) SetOnChange(fn func(change string)) { sm.onChange = fn }
+
+func (sm *SkillManager) changed(change string) {
+	if sm.onChange != nil {
+		sm.onChange(change)
+	}
+}
+
+// skillDir returns the folder of the named skill, which must be a single
+// path component inside skills/.
+func (sm *SkillManager) skillDir(name string) (string, error) {
+	dir, err := workspace.SafeJoin("skills", strings.TrimSpace(name))
+	if err != nil || filepath.Dir(dir) != "skills" {
+		return "", fmt.Errorf("invalid skill name %q", name)
+	}
+	if _, err := (workspace.Jail{Root: sm.root.Name(), Strict: sm.strict}).Resolve(dir); err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(dir), nil
+}
+
 // ListSkills returns a list of all skills in the skills directory.
 func (sm *SkillManager) ListSkills() ([]SkillMetadata, error) {
 	f, err := sm.root.Open("skills")
@@ -61,7 +105,11 @@ func (sm *SkillManager) ListSkills() ([]SkillMetadata, error) {
 
 // GetSkill reads a skill's content by name.
 func (sm *SkillManager) GetSkill(name string) (string, error) {
-	content, err := sm.root.ReadFile("skills/" + name + "/SKILL.md")
+	dir, err := sm.skillDir(name)
+	if err != nil {
+		return "", err
+	}
+	content, err := sm.root.ReadFile(dir + "/SKILL.md")
 	if err != nil {
 		return "", err
 	}
@@ -69,28 +117,83 @@ func (sm *SkillManager) GetSkill(name string) (string, error) {
 }
 
 // CreateSkill creates a new skill with the given name and content.
-// Path traversal is prevented by os.Root at the kernel level.
+// The name must stay inside skills/; os.Root also refuses symlink escapes at
+// the kernel level.
 func (sm *SkillManager) CreateSkill(name, description, content string) error {
-	if name == "" {
+	return sm.CreateSkillWith(SkillMetadata{Name: name, Description: description}, content, nil)
+}
+
+// CreateSkillWith is CreateSkill with the rest of meta written to the
+// frontmatter as well, and with helper scripts: file name -> source, saved
+// as executables in the skill's scripts/ folder and listed in its manifest.
+func (sm *SkillManager) CreateSkillWith(meta SkillMetadata, content string, scripts map[string]string) error {
+	if meta.Name == "" {
 		return fmt.Errorf("skill name is required")
 	}
-	name = strings.TrimSpace(name)
+	name := strings.TrimSpace(meta.Name)
 
-	skillDir := "skills/" + name
+	skillDir, err := sm.skillDir(name)
+	if err != nil {
+		return err
+	}
 	if err := sm.root.MkdirAll(skillDir, 0o755); err != nil {
 		return err
 	}
+	for _, file := range slices.Sorted(maps.Keys(scripts)) {
+		p, err := workspace.SafeJoin("scripts", file)
+		if err != nil || filepath.Dir(p) != "scripts" {
+			return fmt.Errorf("invalid script name %q", file)
+		}
+		p = filepath.ToSlash(p)
+		if err := sm.root.MkdirAll(skillDir+"/scripts", 0o755); err != nil {
+			return err
+		}
+		if err := sm.root.WriteFile(skillDir+"/"+p, []byte(scripts[file]), 0o755); err != nil {
+			return err
+		}
+		if !slices.Contains(meta.Scripts, p) {
+			meta.Scripts = append(meta.Scripts, p)
+		}
+	}
 
 	// Create SKILL.md with frontmatter
-	frontmatter := fmt.Sprintf("---\nname: %s\ndescription: %s\n---\n\n", name, description)
-	fullContent := frontmatter + content
+	var fm strings.Builder
+	fmt.Fprintf(&fm, "---\nname: %s\ndescription: %s\n", name, meta.Description)
+	writeList := func(key string, items []string) {
+		if len(items) > 0 {
+			fmt.Fprintf(&fm, "%s:\n", key)
+			for _, item := range items {
+				fmt.Fprintf(&fm, "  - %s\n", item)
+			}
+		}
+	}
+	writeList("triggers", meta.Triggers)
+	if meta.AlwaysLoad {
+		fm.WriteString("always_load: true\n")
+	}
+	writeList("scripts", meta.Scripts)
+	writeList("dependencies", meta.Dependencies)
+	writeList("examples", meta.Examples)
+	fullContent := fm.String() + "---\n\n" + content
 
-	return sm.root.WriteFile(skillDir+"/SKILL.md", []byte(fullContent), 0o644)
+	if err := sm.root.WriteFile(skillDir+"/SKILL.md", []byte(fullContent), 0o644); err != nil {
+		return err
+	}
+	sm.changed(fmt.Sprintf("create skill %q", name))
+	return nil
 }
 
 // DeleteSkill removes a skill directory.
 func (sm *SkillManager) DeleteSkill(name string) error {
-	return sm.root.RemoveAll("skills/" + name)
+	dir, err := sm.skillDir(name)
+	if err != nil {
+		return err
+	}
+	if err := sm.root.RemoveAll(dir); err != nil {
+		return err
+	}
+	sm.changed(fmt.Sprintf("delete skill %q", path.Base(dir)))
+	return nil
 }
 
 // parseSkillMetadata extracts metadata from SKILL.md frontmatter.
@@ -99,42 +202,19 @@ func (sm *SkillManager) parseSkillMetadata(skillPath string) (SkillMetadata, err
 	if err != nil {
 		return SkillMetadata{}, err
 	}
-
-	// parse YAML frontmatter (simple parser for name and description)
-	lines := strings.Split(string(content), "\n")
-	if len(lines) < 3 || lines[0] != "---" {
-		return SkillMetadata{}, fmt.Errorf("invalid frontmatter")
-	}
-
-	meta := SkillMetadata{}
-	inFrontmatter := true
-	for i := 1; i < len(lines); i++ {
-		line := lines[i]
-		if line == "---" {
-			inFrontmatter = false
-			break
-		}
-		if !inFrontmatter {
-			break
-		}
-		parts := strings.SplitN(line, ":", 2)
-		if len(parts) != 2 {
-			continue
-		}
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-		switch key {
-		case "name":
-			meta.Name = value
-		case "description":
-			meta.Description = value
-		}
-	}
-
-	if meta.Name == "" {
-		return SkillMetadata{}, fmt.Errorf("missing name in frontmatter")
+	skill, err := skills.Parse(string(content))
+	if err != nil {
+		return SkillMetadata{}, err
 	}
-	return meta, nil
+	return SkillMetadata{
+		Name:         skill.Name,
+		Description:  skill.Description,
+		Triggers:     skill.Triggers,
+		AlwaysLoad:   skill.AlwaysLoad,
+		Scripts:      skill.Scripts,
+		Dependencies: skill.Dependencies,
+		Examples:     skill.Examples,
+	}, nil
 }
 
 // ============================================================================
@@ -146,6 +226,9 @@ type CreateSkillTool struct {
 	manager *SkillManager
 }
 
+// SetStrictPaths applies the workspace's strict symlink mode to skills.
+func (t *CreateSkillTool) SetStrictPaths(strict bool) { t.manager.SetStrictPaths(strict) }
+
 func NewCreateSkillTool(manager *SkillManager) *CreateSkillTool {
 	return &CreateSkillTool{manager: manager}
 }
@@ -153,7 +236,7 @@ func NewCreateSkillTool(manager *SkillManager) *CreateSkillTool {
 func (t *CreateSkillTool) Name() string { return "create_skill" }
 
 func (t *CreateSkillTool) Description() string {
-	return "Create a new skill in the skills directory with markdown content"
+	return "Create a new skill in the skills directory with markdown content. Its instructions are loaded into prompts whose message matches one of its triggers"
 }
 
 func (t *CreateSkillTool) Parameters() map[string]interface{} {
@@ -172,6 +255,30 @@ func (t *CreateSkillTool) Parameters() map[string]interface{} {
 				"type":        "string",
 				"description": "The markdown content for the skill (instructions, examples, etc.)",
 			},
+			"triggers": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Words or phrases that make the skill relevant to a message, e.g. ['weather', 'forecast']. Defaults to the skill name.",
+			},
+			"always_load": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Include the skill's instructions in every prompt, not just when a trigger matches",
+			},
+			"scripts": map[string]interface{}{
+				"type":                 "object",
+				"additionalProperties": map[string]interface{}{"type": "string"},
+				"description":          "Helper scripts to bundle, file name -> source, e.g. {\"fetch.py\": \"...\"}. Saved in the skill's scripts/ folder and run with exec {\"skill\": name, \"cmd\": [\"python3\", \"scripts/fetch.py\"]}",
+			},
+			"dependencies": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Python packages the scripts need, installed with uv into the skill's own environment on first use",
+			},
+			"examples": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Example invocations of the scripts",
+			},
 		},
 		"required": []string{"name", "description", "content"},
 	}
@@ -191,12 +298,42 @@ func (t *CreateSkillTool) Execute(ctx context.Context, args map[string]interface
 		return "", fmt.Errorf("content (string) is required")
 	}
 
-	if err := t.manager.CreateSkill(name, description, content); err != nil {
+	meta := SkillMetadata{
+		Name:         name,
+		Description:  description,
+		Triggers:     stringList(args["triggers"]),
+		Dependencies: stringList(args["dependencies"]),
+		Examples:     stringList(args["examples"]),
+	}
+	meta.AlwaysLoad, _ = args["always_load"].(bool)
+	scripts := map[string]string{}
+	if m, ok := args["scripts"].(map[string]interface{}); ok {
+		for file, v := range m {
+			src, ok := v.(string)
+			if !ok {
+				return "", fmt.Errorf("scripts: the source of %q must be a string", file)
+			}
+			scripts[file] = src
+		}
+	}
+	if err := t.manager.CreateSkillWith(meta, content, scripts); err != nil {
 		return "", err
 	}
 	return fmt.Sprintf("Skill '%s' created successfully", name), nil
 }
 
+// stringList returns the non-empty strings of a JSON array argument.
+func stringList(v interface{}) []string {
+	list, _ := v.([]interface{})
+	var out []string
+	for _, item := range list {
+		if s, ok := item.(string); ok && strings.TrimSpace(s) != "" {
+			out = append(out, strings.TrimSpace(s))
+		}
+	}
+	return out
+}
+
 // ListSkillsTool lists all available skills.
 type ListSkillsTool struct {
 	manager *SkillManager
@@ -239,6 +376,9 @@ type ReadSkillTool struct {
 	manager *SkillManager
 }
 
+// SetStrictPaths applies the workspace's strict symlink mode to skills.
+func (t *ReadSkillTool) SetStrictPaths(strict bool) { t.manager.SetStrictPaths(strict) }
+
 func NewReadSkillTool(manager *SkillManager) *ReadSkillTool {
 	return &ReadSkillTool{manager: manager}
 }
@@ -275,6 +415,9 @@ type DeleteSkillTool struct {
 	manager *SkillManager
 }
 
+// SetStrictPaths applies the workspace's strict symlink mode to skills.
+func (t *DeleteSkillTool) SetStrictPaths(strict bool) { t.manager.SetStrictPaths(strict) }
+
 func NewDeleteSkillTool(manager *SkillManager) *DeleteSkillTool {
 	return &DeleteSkillTool{manager: manager}
 }
@@ -0,0 +1,227 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/agent/skills"
+	"github.com/kr0nicas/picobot/internal/netguard"
+	"github.com/kr0nicas/picobot/internal/workspace"
+)
+
+// ExportSkill writes the named skill as a gzipped tarball to dest, a path in
+// the workspace.
+func (sm *SkillManager) ExportSkill(name, dest string) error {
+	if _, err := sm.skillDir(name); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := skills.Export(filepath.Join(sm.root.Name(), "skills"), strings.TrimSpace(name), &buf); err != nil {
+		return err
+	}
+	if dir := filepath.Dir(dest); dir != "." {
+		if err := sm.root.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	return sm.root.WriteFile(dest, buf.Bytes(), 0o644)
+}
+
+// ImportSkill unpacks a skill tarball (see skills.Import) into skills/.
+func (sm *SkillManager) ImportSkill(r io.Reader, replace bool) (skills.Skill, error) {
+	if _, err := (workspace.Jail{Root: sm.root.Name(), Strict: sm.strict}).Resolve("skills"); err != nil {
+		return skills.Skill{}, err
+	}
+	skill, err := skills.Import(filepath.Join(sm.root.Name(), "skills"), r, replace)
+	if err != nil {
+		return skills.Skill{}, err
+	}
+	sm.changed(fmt.Sprintf("import skill %q", skill.Name))
+	return skill, nil
+}
+
+// ExportSkillTool packs a skill into a tarball in the workspace, to share
+// it with another instance.
+type ExportSkillTool struct {
+	manager *SkillManager
+}
+
+// SetStrictPaths applies the workspace's strict symlink mode to skills.
+func (t *ExportSkillTool) SetStrictPaths(strict bool) { t.manager.SetStrictPaths(strict) }
+
+func NewExportSkillTool(manager *SkillManager) *ExportSkillTool {
+	return &ExportSkillTool{manager: manager}
+}
+
+func (t *ExportSkillTool) Name() string { return "export_skill" }
+
+func (t *ExportSkillTool) Description() string {
+	return "Export a skill with its scripts as a .tar.gz file in the workspace, to send to the user or import elsewhere with import_skill"
+}
+
+func (t *ExportSkillTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"name": map[string]interface{}{
+				"type":        "string",
+				"description": "The name of the skill to export",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Where to write the archive in the workspace (default exports/<name>.tar.gz)",
+			},
+		},
+		"required": []string{"name"},
+	}
+}
+
+func (t *ExportSkillTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	name, ok := args["name"].(string)
+	if !ok || name == "" {
+		return "", fmt.Errorf("name (string) is required")
+	}
+	dest, _ := args["path"].(string)
+	if dest == "" {
+		dest = "exports/" + strings.TrimSpace(name) + ".tar.gz"
+	}
+	if err := t.manager.ExportSkill(name, dest); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Skill '%s' exported to %s", name, dest), nil
+}
+
+// ImportSkillTool installs a skill from a tarball made by export_skill, at
+// a URL or in the workspace. Imported skills become part of prompts, so
+// every import needs the user's approval.
+type ImportSkillTool struct {
+	manager   *SkillManager
+	domains   DomainPolicy
+	lookup    netguard.Lookup
+	transport http.RoundTripper // nil means netguard.Transport
+}
+
+// SetStrictPaths applies the workspace's strict symlink mode to skills.
+func (t *ImportSkillTool) SetStrictPaths(strict bool) { t.manager.SetStrictPaths(strict) }
+
+// SetDomainPolicy restricts the hosts skills may be downloaded from.
+func (t *ImportSkillTool) SetDomainPolicy(p DomainPolicy) { t.domains = p }
+
+func NewImportSkillTool(manager *SkillManager) *ImportSkillTool {
+	return &ImportSkillTool{manager: manager, lookup: netguard.DefaultLookup}
+}
+
+func (t *ImportSkillTool) Name() string { return "import_skill" }
+
+func (t *ImportSkillTool) Description() string {
+	return "Install a skill from a .tar.gz made by export_skill, downloaded from an http(s) URL or read from a file in the workspace"
+}
+
+func (t *ImportSkillTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"source": map[string]interface{}{
+				"type":        "string",
+				"description": "URL or workspace path of the archive",
+			},
+			"replace": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Replace an installed skill of the same name",
+			},
+		},
+		"required": []string{"source"},
+	}
+}
+
+// RequiresApproval implements ApprovalRequirer: a skill's instructions
+// steer the agent, so the user confirms where they come from.
+func (t *ImportSkillTool) RequiresApproval(args map[string]interface{}) string {
+	source, _ := args["source"].(string)
+	return "import a skill from " + source
+}
+
+// skillDownloadTimeout bounds the download of a skill archive.
+const skillDownloadTimeout = time.Minute
+
+func (t *ImportSkillTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	source, _ := args["source"].(string)
+	if source == "" {
+		return "", fmt.Errorf("source (string) is required")
+	}
+	replace, _ := args["replace"].(bool)
+
+	var r io.Reader
+	if u, err := url.Parse(source); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		data, err := t.download(ctx, u)
+		if err != nil {
+			return "", err
+		}
+		r = bytes.NewReader(data)
+	} else {
+		f, err := t.manager.root.Open(filepath.ToSlash(source))
+		if err != nil {
+			return "", fmt.Errorf("import_skill: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+	skill, err := t.manager.ImportSkill(r, replace)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Skill '%s' imported: %s", skill.Name, skill.Description), nil
+}
+
+// download fetches an archive with the SSRF checks and domain policy of
+// the network tools.
+func (t *ImportSkillTool) download(ctx context.Context, u *url.URL) ([]byte, error) {
+	check := func(ctx context.Context, u *url.URL) error {
+		if err := t.domains.Check(u.Hostname()); err != nil {
+			return err
+		}
+		return netguard.CheckURL(ctx, u, t.lookup)
+	}
+	if err := check(ctx, u); err != nil {
+		return nil, fmt.Errorf("import_skill: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, skillDownloadTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{
+		Transport: transportOr(t.transport),
+		CheckRedirect: func(r *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errors.New("too many redirects")
+			}
+			return check(r.Context(), r.URL)
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("import_skill: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("import_skill: %s: HTTP %s", u.Redacted(), resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, skills.MaxArchiveBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("import_skill: %w", err)
+	}
+	if len(data) > skills.MaxArchiveBytes {
+		return nil, fmt.Errorf("import_skill: archive larger than %d MB", skills.MaxArchiveBytes>>20)
+	}
+	return data, nil
+}
@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"os"
+	"runtime"
 	"testing"
 )
 
@@ -192,3 +193,114 @@ func containsAt(s, substr string) bool {
 	}
 	return false
 }
+
+func TestSkillManager_RejectsNamesOutsideSkills(t *testing.T) {
+	root := openTestRoot(t)
+	mgr := NewSkillManager(root)
+	if err := root.MkdirAll("memory", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"../memory", "", ".", "a/b"} {
+		if err := mgr.DeleteSkill(name); err == nil {
+			t.Errorf("DeleteSkill(%q) should fail", name)
+		}
+		if err := mgr.CreateSkill(name, "d", "c"); err == nil {
+			t.Errorf("CreateSkill(%q) should fail", name)
+		}
+	}
+	if _, err := root.Stat("memory"); err != nil {
+		t.Fatalf("memory folder was removed: %v", err)
+	}
+}
+
+func TestCreateSkillToolTriggers(t *testing.T) {
+	root := openTestRoot(t)
+	mgr := NewSkillManager(root)
+	changed := 0
+	mgr.SetOnChange(func(string) { changed++ })
+	tool := NewCreateSkillTool(mgr)
+	_, err := tool.Execute(context.Background(), map[string]interface{}{
+		"name": "weather", "description": "Forecasts", "content": "Use wttr.in",
+		"triggers": []interface{}{"forecast", "rain"}, "always_load": true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed != 1 {
+		t.Fatalf("expected one change notification, got %d", changed)
+	}
+	list, err := mgr.ListSkills()
+	if err != nil || len(list) != 1 {
+		t.Fatalf("ListSkills: %v %v", list, err)
+	}
+	if m := list[0]; len(m.Triggers) != 2 || m.Triggers[1] != "rain" || !m.AlwaysLoad {
+		t.Fatalf("unexpected metadata: %+v", m)
+	}
+}
+
+func TestCreateSkillWithScripts(t *testing.T) {
+	root := openTestRoot(t)
+	mgr := NewSkillManager(root)
+	meta := SkillMetadata{Name: "feeds", Description: "Read feeds", Dependencies: []string{"feedparser"}, Examples: []string{`exec {"skill": "feeds", "cmd": ["python3", "scripts/read.py", "a,b"]}`}}
+	if err := mgr.CreateSkillWith(meta, "Use the script.", map[string]string{"read.py": "print(1)\n"}); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := root.Stat("skills/feeds/scripts/read.py")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && fi.Mode().Perm()&0o100 == 0 {
+		t.Errorf("script is not executable: %v", fi.Mode())
+	}
+	list, err := mgr.ListSkills()
+	if err != nil || len(list) != 1 {
+		t.Fatalf("ListSkills: %v %v", list, err)
+	}
+	got := list[0]
+	if len(got.Scripts) != 1 || got.Scripts[0] != "scripts/read.py" || got.Dependencies[0] != "feedparser" || got.Examples[0] != meta.Examples[0] {
+		t.Fatalf("unexpected manifest: %+v", got)
+	}
+
+	if err := mgr.CreateSkillWith(SkillMetadata{Name: "bad", Description: "d"}, "c", map[string]string{"../x.py": ""}); err == nil {
+		t.Fatal("script names must stay in scripts/")
+	}
+}
+
+func TestExportImportSkillTools(t *testing.T) {
+	root := openTestRoot(t)
+	mgr := NewSkillManager(root)
+	var changes []string
+	mgr.SetOnChange(func(change string) { changes = append(changes, change) })
+	if err := mgr.CreateSkill("notes", "Take notes", "Write them down."); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewExportSkillTool(mgr).Execute(context.Background(), map[string]interface{}{"name": "notes"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.DeleteSkill("notes"); err != nil {
+		t.Fatal(err)
+	}
+
+	imp := NewImportSkillTool(mgr)
+	args := map[string]interface{}{"source": "exports/notes.tar.gz"}
+	if imp.RequiresApproval(args) == "" {
+		t.Error("imports must need approval")
+	}
+	out, err := imp.Execute(context.Background(), args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsString(out, "notes") {
+		t.Errorf("unexpected result: %s", out)
+	}
+	if _, err := mgr.GetSkill("notes"); err != nil {
+		t.Fatalf("imported skill missing: %v", err)
+	}
+	if len(changes) != 3 || changes[2] != `import skill "notes"` {
+		t.Errorf("unexpected changes: %q", changes)
+	}
+
+	if _, err := imp.Execute(context.Background(), map[string]interface{}{"source": "../outside.tar.gz"}); err == nil {
+		t.Error("sources outside the workspace must be refused")
+	}
+}
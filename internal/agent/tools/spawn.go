@@ -3,41 +3,195 @@ package tools
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 )
 
-// SpawnTool creates a background subagent; stubbed for v0.
-// Args: {"agent": "name", "task": "..."}
+// SpawnRequest describes a subagent to start for a chat.
+type SpawnRequest struct {
+	Channel       string
+	ChatID        string
+	Name          string
+	Task          string
+	Tools         []string // empty means the tools the chat may use
+	MaxIterations int      // 0 means the default
+}
+
+// SubagentInfo is the state of a subagent.
+type SubagentInfo struct {
+	ID         string
+	Name       string
+	Task       string
+	Status     string // running, done, failed or stopped
+	Started    time.Time
+	Finished   time.Time
+	Iterations int
+	LastTool   string
+	Result     string
+}
 
-type SpawnTool struct{}
+// Subagents runs background subagents.
+type Subagents interface {
+	// Spawn starts a subagent; ctx is the spawning tool call's context.
+	Spawn(ctx context.Context, req SpawnRequest) (SubagentInfo, error)
+	// List returns the subagents of a chat, newest first.
+	List(channel, chatID string) []SubagentInfo
+	// Stop cancels a running subagent of a chat.
+	Stop(channel, chatID, id string) error
+}
+
+// SpawnTool starts a background subagent that works on a task with its own
+// tool loop and reports back to the chat when it is done.
+// Args: {"task": "...", "tools": ["web", "search"], "max_iterations": 10}
+type SpawnTool struct {
+	agents Subagents
+}
 
-func NewSpawnTool() *SpawnTool { return &SpawnTool{} }
+func NewSpawnTool(agents Subagents) *SpawnTool { return &SpawnTool{agents: agents} }
 
-func (t *SpawnTool) Name() string        { return "spawn" }
-func (t *SpawnTool) Description() string { return "Spawn a background subagent (stub)" }
+func (t *SpawnTool) Name() string { return "spawn" }
+func (t *SpawnTool) Description() string {
+	return "Start a background subagent for a self-contained task (research, long file work). It runs on its own with the tools you give it and reports its result to this chat when done; check on it with subagent_status."
+}
 
 func (t *SpawnTool) Parameters() map[string]interface{} {
 	return map[string]interface{}{
 		"type": "object",
 		"properties": map[string]interface{}{
-			"agent": map[string]interface{}{
+			"task": map[string]interface{}{
 				"type":        "string",
-				"description": "The name of the agent to spawn",
+				"description": "What the subagent should do and report, with all the context it needs; it cannot see this conversation",
 			},
-			"task": map[string]interface{}{
+			"agent": map[string]interface{}{
 				"type":        "string",
-				"description": "The task description for the spawned agent",
+				"description": "A short name for the subagent",
+			},
+			"tools": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Tools the subagent may use (default: the tools available in this chat)",
+			},
+			"max_iterations": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum number of model calls (default 20)",
 			},
 		},
-		"required": []string{},
+		"required": []string{"task"},
 	}
 }
 
 func (t *SpawnTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
-	agentName, _ := args["agent"].(string)
 	task, _ := args["task"].(string)
-	if agentName == "" && task == "" {
-		return "", fmt.Errorf("spawn: 'agent' or 'task' required")
+	if strings.TrimSpace(task) == "" {
+		return "", fmt.Errorf("spawn: 'task' required")
+	}
+	if t.agents == nil {
+		return "", fmt.Errorf("spawn: subagents are not available")
+	}
+	name, _ := args["agent"].(string)
+	channel, chatID := chatOf(ctx)
+	req := SpawnRequest{Channel: channel, ChatID: chatID, Name: name, Task: task}
+	if list, ok := args["tools"].([]interface{}); ok {
+		for _, v := range list {
+			if s, ok := v.(string); ok {
+				req.Tools = append(req.Tools, s)
+			}
+		}
+	}
+	if n, ok := args["max_iterations"].(float64); ok {
+		req.MaxIterations = int(n)
+	}
+	info, err := t.agents.Spawn(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Started subagent %s. It will report to this chat when done.", info.ID), nil
+}
+
+// SubagentStatusTool lists the chat's subagents and stops running ones.
+// Args: {"id": "sa1", "stop": true}
+type SubagentStatusTool struct {
+	agents Subagents
+}
+
+func NewSubagentStatusTool(agents Subagents) *SubagentStatusTool {
+	return &SubagentStatusTool{agents: agents}
+}
+
+func (t *SubagentStatusTool) Name() string { return "subagent_status" }
+func (t *SubagentStatusTool) Description() string {
+	return "List the subagents started in this chat with their progress, show one subagent's result, or stop a running one."
+}
+
+func (t *SubagentStatusTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id": map[string]interface{}{
+				"type":        "string",
+				"description": "A subagent ID, to show its result or stop it",
+			},
+			"stop": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Stop the subagent given by id",
+			},
+		},
+	}
+}
+
+func (t *SubagentStatusTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	if t.agents == nil {
+		return "", fmt.Errorf("subagent_status: subagents are not available")
+	}
+	id, _ := args["id"].(string)
+	channel, chatID := chatOf(ctx)
+	if stop, _ := args["stop"].(bool); stop {
+		if id == "" {
+			return "", fmt.Errorf("subagent_status: stop needs 'id'")
+		}
+		if err := t.agents.Stop(channel, chatID, id); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Stopped subagent %s.", id), nil
+	}
+	list := t.agents.List(channel, chatID)
+	if id != "" {
+		for _, s := range list {
+			if s.ID == id {
+				out := formatSubagent(s)
+				if s.Result != "" {
+					out += "\n\n" + s.Result
+				}
+				return out, nil
+			}
+		}
+		return "", fmt.Errorf("subagent_status: no subagent %q in this chat", id)
+	}
+	if len(list) == 0 {
+		return "No subagents in this chat.", nil
+	}
+	lines := make([]string, 0, len(list))
+	for _, s := range list {
+		lines = append(lines, formatSubagent(s))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func formatSubagent(s SubagentInfo) string {
+	label := s.ID
+	if s.Name != "" {
+		label += " (" + s.Name + ")"
+	}
+	line := fmt.Sprintf("- %s: %s, %d steps", label, s.Status, s.Iterations)
+	if s.Status == "running" {
+		line += fmt.Sprintf(", running for %s", time.Since(s.Started).Round(time.Second))
+		if s.LastTool != "" {
+			line += ", last tool " + s.LastTool
+		}
+	}
+	task := s.Task
+	if len(task) > 80 {
+		task = task[:80] + "..."
 	}
-	// For v0 we simply return an acknowledgement
-	return fmt.Sprintf("spawned: agent=%s task=%s", agentName, task), nil
+	return line + " — " + task
 }
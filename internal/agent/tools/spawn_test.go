@@ -0,0 +1,68 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeSubagents struct {
+	spawned []SpawnRequest
+	infos   []SubagentInfo
+	stopped string
+}
+
+func (f *fakeSubagents) Spawn(ctx context.Context, req SpawnRequest) (SubagentInfo, error) {
+	f.spawned = append(f.spawned, req)
+	return SubagentInfo{ID: fmt.Sprintf("sa%d", len(f.spawned))}, nil
+}
+
+func (f *fakeSubagents) List(channel, chatID string) []SubagentInfo { return f.infos }
+
+func (f *fakeSubagents) Stop(channel, chatID, id string) error {
+	f.stopped = id
+	return nil
+}
+
+func TestSpawnTool(t *testing.T) {
+	f := &fakeSubagents{}
+	st := NewSpawnTool(f)
+	ctx := WithChat(context.Background(), "telegram", "42")
+
+	if _, err := st.Execute(ctx, map[string]interface{}{}); err == nil {
+		t.Fatal("expected error without task")
+	}
+	args := map[string]interface{}{"task": "find flights", "tools": []interface{}{"web", "search"}, "max_iterations": float64(5)}
+	out, err := st.Execute(ctx, args)
+	if err != nil || !strings.Contains(out, "sa1") {
+		t.Fatalf("unexpected result %q, %v", out, err)
+	}
+	req := f.spawned[0]
+	if req.Channel != "telegram" || req.ChatID != "42" || strings.Join(req.Tools, ",") != "web,search" || req.MaxIterations != 5 {
+		t.Fatalf("unexpected request: %+v", req)
+	}
+}
+
+func TestSubagentStatusTool(t *testing.T) {
+	f := &fakeSubagents{infos: []SubagentInfo{
+		{ID: "sa2", Task: "summarize the feeds", Status: "running", Started: time.Now(), Iterations: 3, LastTool: "feeds"},
+		{ID: "sa1", Name: "flights", Task: "find flights", Status: "done", Iterations: 4, Result: "LH 123 at 9:40"},
+	}}
+	st := NewSubagentStatusTool(f)
+
+	out, err := st.Execute(context.Background(), map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "sa2: running, 3 steps") || !strings.Contains(out, "last tool feeds") || !strings.Contains(out, "sa1 (flights): done") {
+		t.Fatalf("unexpected list:\n%s", out)
+	}
+	if out, _ := st.Execute(context.Background(), map[string]interface{}{"id": "sa1"}); !strings.Contains(out, "LH 123") {
+		t.Fatalf("expected the result, got %q", out)
+	}
+	if _, err := st.Execute(context.Background(), map[string]interface{}{"id": "sa2", "stop": true}); err != nil || f.stopped != "sa2" {
+		t.Fatalf("expected sa2 to be stopped, got %q, %v", f.stopped, err)
+	}
+}
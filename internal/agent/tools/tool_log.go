@@ -0,0 +1,84 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/audit"
+)
+
+// maxToolLogLines bounds the calls one tool_log answer lists.
+const maxToolLogLines = 200
+
+// ToolLogTool looks up past tool calls in the tool call audit log, to answer
+// questions like "what did you run yesterday?". By default only owners may
+// use it.
+type ToolLogTool struct {
+	dir string
+}
+
+func NewToolLogTool(dir string) *ToolLogTool {
+	return &ToolLogTool{dir: dir}
+}
+
+func (t *ToolLogTool) Name() string { return "tool_log" }
+
+func (t *ToolLogTool) Description() string {
+	return "List the tool calls made in a period (commands run, files written, pages fetched...) from the audit log, with their chat, arguments, outcome and duration. Use it when the user asks what you did."
+}
+
+func (t *ToolLogTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"period": map[string]interface{}{
+				"type":        "string",
+				"description": "today (default), yesterday, a date like 2026-10-16, or a duration back from now like 3h or 7d",
+			},
+			"tool": map[string]interface{}{
+				"type":        "string",
+				"description": "Only calls of this tool, e.g. exec",
+			},
+			"chat": map[string]interface{}{
+				"type":        "string",
+				"description": "Only calls made for this channel (telegram) or chat (telegram:123)",
+			},
+			"outcome": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{audit.OutcomeOK, audit.OutcomeError, audit.OutcomeDenied, audit.OutcomeBlocked},
+				"description": "Only calls with this outcome",
+			},
+		},
+	}
+}
+
+func (t *ToolLogTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	period, _ := args["period"].(string)
+	from, to, err := audit.ParsePeriod(period, time.Now())
+	if err != nil {
+		return "", fmt.Errorf("tool_log: %w", err)
+	}
+	q := audit.ToolQuery{From: from, To: to}
+	q.Tool, _ = args["tool"].(string)
+	q.Chat, _ = args["chat"].(string)
+	q.Outcome, _ = args["outcome"].(string)
+	calls, err := audit.ReadToolCalls(t.dir, q)
+	if err != nil {
+		return "", fmt.Errorf("tool_log: %w", err)
+	}
+	if len(calls) == 0 {
+		return "No tool calls logged in that period.", nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d tool calls:\n", len(calls))
+	if len(calls) > maxToolLogLines {
+		fmt.Fprintf(&b, "(the first %d are left out)\n", len(calls)-maxToolLogLines)
+		calls = calls[len(calls)-maxToolLogLines:]
+	}
+	for _, c := range calls {
+		b.WriteString(c.String() + "\n")
+	}
+	return b.String(), nil
+}
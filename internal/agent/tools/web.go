@@ -2,18 +2,61 @@ package tools
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
 	"strings"
+	"unicode/utf8"
+
+	"github.com/kr0nicas/picobot/internal/netguard"
+	"github.com/kr0nicas/picobot/internal/webtext"
 )
 
 // WebTool supports fetch operations.
-// Args: {"url": "https://..."}
+// Args: {"url": "https://...", "max_chars": 20000, "selector": "article"}
+//
+// HTML pages are reduced to their main content (or the elements matching
+// selector) and converted to markdown; other content types are returned as
+// text. Output is cut at max_chars.
+//
+// Only http(s) URLs whose host resolves exclusively to public addresses and
+// is permitted by the domain policy are fetched; the checks are repeated for
+// every redirect, and again on the address of every connection.
+
+type WebTool struct {
+	lookup    netguard.Lookup
+	domains   DomainPolicy
+	transport http.RoundTripper // nil means netguard.Transport
+}
+
+func NewWebTool() *WebTool { return &WebTool{lookup: netguard.DefaultLookup} }
+
+// UntrustedOutput marks fetched pages as untrusted data.
+func (t *WebTool) UntrustedOutput() bool { return true }
+
+// SetDomainPolicy restricts the hosts the tool may fetch from.
+func (t *WebTool) SetDomainPolicy(p DomainPolicy) { t.domains = p }
+
+// checkURL applies the domain policy and SSRF checks to u.
+func (t *WebTool) checkURL(ctx context.Context, u *url.URL) error {
+	if err := t.domains.Check(u.Hostname()); err != nil {
+		return err
+	}
+	return netguard.CheckURL(ctx, u, t.lookup)
+}
 
-type WebTool struct{}
+// maxRedirects bounds redirect chains followed by the web tool.
+const maxRedirects = 10
 
-func NewWebTool() *WebTool { return &WebTool{} }
+const (
+	// DefaultWebMaxChars is the default output size of the web tool.
+	DefaultWebMaxChars = 20000
+	// maxWebBody is the most the web tool downloads of a page.
+	maxWebBody = 5 << 20
+)
 
 func (t *WebTool) Name() string        { return "web" }
 func (t *WebTool) Description() string { return "Fetch web content from a URL" }
@@ -26,6 +69,18 @@ func (t *WebTool) Parameters() map[string]interface{} {
 				"type":        "string",
 				"description": "The URL to fetch (must be http or https)",
 			},
+			"max_chars": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Maximum characters to return (default %d)", DefaultWebMaxChars),
+			},
+			"selector": map[string]interface{}{
+				"type":        "string",
+				"description": "CSS selector of the part of an HTML page to return, e.g. \"article\", \"#content\", \"table.prices\". Default: the page's main content.",
+			},
+			"raw": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Return the HTML as is instead of markdown",
+			},
 		},
 		"required": []string{"url"},
 	}
@@ -37,27 +92,106 @@ func (t *WebTool) Execute(ctx context.Context, args map[string]interface{}) (str
 		return "", fmt.Errorf("web: 'url' argument required")
 	}
 
-	// Simple SSRF protection: reject localhost and common private IP ranges
-	lower := strings.ToLower(uStr)
-	if strings.Contains(lower, "localhost") || strings.Contains(lower, "127.0.0.1") || strings.Contains(lower, "::1") ||
-		strings.Contains(lower, "10.") || strings.Contains(lower, "192.168.") || strings.Contains(lower, "172.16.") ||
-		strings.Contains(lower, "169.254.") { // Link-local (AWS metadata, etc.)
-		return "", fmt.Errorf("web: access to local or private network addresses is disallowed")
+	u, err := url.Parse(uStr)
+	if err != nil {
+		return "", fmt.Errorf("web: invalid url: %w", err)
+	}
+	if err := t.checkURL(ctx, u); err != nil {
+		return "", fmt.Errorf("web: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", uStr, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
 	if err != nil {
 		return "", err
 	}
-	// ... continue with request ...
-	resp, err := http.DefaultClient.Do(req)
+	client := &http.Client{
+		Transport: transportOr(t.transport),
+		CheckRedirect: func(r *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errors.New("too many redirects")
+			}
+			return t.checkURL(r.Context(), r.URL)
+		},
+	}
+	resp, err := client.Do(req)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("web: %w", err)
 	}
 	defer resp.Body.Close()
-	b, err := ioutil.ReadAll(resp.Body)
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxWebBody))
 	if err != nil {
 		return "", err
 	}
-	return string(b), nil
+
+	maxChars := DefaultWebMaxChars
+	if v, ok := args["max_chars"].(float64); ok && v > 0 {
+		maxChars = int(v)
+	}
+	out := string(b)
+	raw, _ := args["raw"].(bool)
+	selector, _ := args["selector"].(string)
+	if !raw && isHTML(resp.Header.Get("Content-Type"), b) {
+		if out, err = pageMarkdown(out, resp.Request.URL, selector); err != nil {
+			return "", fmt.Errorf("web: %w", err)
+		}
+	}
+	return truncateChars(out, maxChars), nil
+}
+
+// isHTML reports whether a response is an HTML page.
+func isHTML(contentType string, body []byte) bool {
+	if mt, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mt == "text/html" || mt == "application/xhtml+xml"
+	}
+	return strings.Contains(http.DetectContentType(body), "text/html")
+}
+
+// pageMarkdown converts an HTML page to markdown: its main content, or the
+// elements matching selector when one is given.
+func pageMarkdown(page string, u *url.URL, selector string) (string, error) {
+	doc := webtext.Parse(page)
+	title := webtext.Title(doc)
+	webtext.Clean(doc)
+	var md string
+	if selector != "" {
+		sel, err := webtext.Compile(selector)
+		if err != nil {
+			return "", err
+		}
+		nodes := sel.Select(doc)
+		if len(nodes) == 0 {
+			return "", fmt.Errorf("selector %q matched nothing on %s", selector, u)
+		}
+		parts := make([]string, 0, len(nodes))
+		for _, n := range nodes {
+			if s := webtext.Markdown(n, u); s != "" {
+				parts = append(parts, s)
+			}
+		}
+		md = strings.Join(parts, "\n\n---\n\n")
+	} else {
+		md = webtext.Markdown(webtext.Extract(doc), u)
+	}
+	header := "URL: " + u.String() + "\n"
+	if title != "" {
+		header = "Title: " + title + "\n" + header
+	}
+	return header + "\n" + md, nil
+}
+
+// truncateChars cuts s to n characters, noting how much was left out.
+func truncateChars(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:n]) + fmt.Sprintf("\n[truncated: %d more characters; use selector or a larger max_chars to see more]", len(r)-n)
+}
+
+// transportOr returns t, or netguard.Transport when t is nil.
+func transportOr(t http.RoundTripper) http.RoundTripper {
+	if t == nil {
+		return netguard.Transport
+	}
+	return t
 }
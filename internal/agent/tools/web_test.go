@@ -0,0 +1,79 @@
+package tools
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebToolRejectsLocalhost(t *testing.T) {
+	w := NewWebTool()
+	if _, err := w.Execute(context.Background(), map[string]interface{}{"url": "http://localhost:1/"}); err == nil {
+		t.Fatalf("expected localhost to be rejected")
+	}
+}
+
+func TestDomainPolicy(t *testing.T) {
+	p := DomainPolicy{Allow: []string{"*.example.com", "golang.org"}, Deny: []string{"evil.example.com"}}
+	cases := map[string]bool{
+		"docs.example.com": true,
+		"a.b.example.com":  true,
+		"example.com":      false,
+		"GoLang.org.":      true,
+		"evil.example.com": false,
+		"notexample.com":   false,
+		"other.org":        false,
+	}
+	for host, ok := range cases {
+		if err := p.Check(host); (err == nil) != ok {
+			t.Errorf("Check(%s) = %v, want ok=%v", host, err, ok)
+		}
+	}
+	if err := (DomainPolicy{Deny: []string{"*"}}).Check("anything.net"); err == nil {
+		t.Errorf("expected * in deny list to block everything")
+	}
+}
+
+func TestWebToolAppliesDomainPolicy(t *testing.T) {
+	w := NewWebTool()
+	w.SetDomainPolicy(DomainPolicy{Allow: []string{"example.com"}})
+	_, err := w.Execute(context.Background(), map[string]interface{}{"url": "https://example.org/"})
+	if err == nil || !strings.Contains(err.Error(), "allowlist") {
+		t.Fatalf("expected allowlist rejection, got %v", err)
+	}
+}
+
+func TestWebToolMarkdown(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, `<html><head><title>Prices</title><script>track()</script></head><body>
+<nav><a href="/">Home</a></nav><h1>Prices</h1><table class="prices"><tr><th>Plan</th><th>USD</th></tr><tr><td>Pro</td><td>10</td></tr></table>
+<p>All prices include taxes, fees, and a free trial of thirty days for new accounts.</p></body></html>`)
+	}))
+	defer srv.Close()
+	w := NewWebTool()
+	w.lookup = func(ctx context.Context, host string) ([]net.IP, error) {
+		return []net.IP{net.ParseIP("93.184.216.34")}, nil
+	}
+	w.transport = toServer{srv}
+
+	out, err := w.Execute(context.Background(), map[string]interface{}{"url": "https://shop.example/pricing", "selector": "table.prices"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "Title: Prices\nURL: https://shop.example/pricing\n\n| Plan | USD |\n| --- | --- |\n| Pro | 10 |"
+	if out != want {
+		t.Fatalf("unexpected output:\n%q\nwant\n%q", out, want)
+	}
+	out, _ = w.Execute(context.Background(), map[string]interface{}{"url": "https://shop.example/pricing", "max_chars": float64(20)})
+	if !strings.HasPrefix(out, "Title: Prices\nURL: h\n[truncated:") || strings.Contains(out, "track") {
+		t.Fatalf("unexpected truncated output: %q", out)
+	}
+	if _, err := w.Execute(context.Background(), map[string]interface{}{"url": "https://shop.example/", "selector": "#missing"}); err == nil {
+		t.Fatalf("expected an error when the selector matches nothing")
+	}
+}
@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/kr0nicas/picobot/internal/agent/memory"
 )
@@ -16,6 +17,10 @@ func NewWriteMemoryTool(mem *memory.MemoryStore) *WriteMemoryTool {
 	return &WriteMemoryTool{mem: mem}
 }
 
+// SetStrictPaths applies the workspace's strict symlink mode to memory
+// files.
+func (w *WriteMemoryTool) SetStrictPaths(strict bool) { w.mem.SetStrictPaths(strict) }
+
 func (w *WriteMemoryTool) Name() string { return "write_memory" }
 func (w *WriteMemoryTool) Description() string {
 	return "Write or append to memory (today's note or long-term MEMORY.md)"
@@ -39,13 +44,22 @@ func (w *WriteMemoryTool) Parameters() map[string]interface{} {
 				"description": "If true, append to existing content; if false, overwrite",
 				"default":     true,
 			},
+			"until": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional date (YYYY-MM-DD, through the end of that day) or RFC 3339 time after which the memory is forgotten, e.g. for 'remember until Friday'",
+			},
+			"tags": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Optional labels to find the memory by later with search_memory",
+			},
 		},
 		"required": []string{"target", "content"},
 	}
 }
 
 // Expected args:
-// {"target": "today"|"long", "content": "...", "append": true|false }
+// {"target": "today"|"long", "content": "...", "append": true|false, "tags": ["..."], "until": "2026-03-06" }
 func (w *WriteMemoryTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
 	targetI, ok := args["target"]
 	if !ok {
@@ -70,20 +84,31 @@ func (w *WriteMemoryTool) Execute(ctx context.Context, args map[string]interface
 		}
 	}
 
+	channel, _ := chatOf(ctx)
+	item := memory.MemoryItem{Text: content, Channel: channel, Tags: stringList(args["tags"])}
+	if until, _ := args["until"].(string); until != "" {
+		t, err := parseMemoryDate(until, true)
+		if err != nil {
+			return "", fmt.Errorf("write_memory: until: %w", err)
+		}
+		if !t.After(time.Now()) {
+			return "", fmt.Errorf("write_memory: until %s is in the past", until)
+		}
+		if target == "long" && !appendFlag {
+			return "", fmt.Errorf("write_memory: until needs append, it can't expire the whole long-term memory")
+		}
+		item.Expires = t.UTC()
+	}
+
 	switch target {
 	case "today":
-		if err := w.mem.AppendToday(content); err != nil {
+		if err := w.mem.AppendNote(item); err != nil {
 			return "", err
 		}
 		return "appended to today", nil
 	case "long":
 		if appendFlag {
-			prev, err := w.mem.ReadLongTerm()
-			if err != nil {
-				return "", err
-			}
-			new := prev + "\n" + content
-			if err := w.mem.WriteLongTerm(new); err != nil {
+			if err := w.mem.AppendLong(item); err != nil {
 				return "", err
 			}
 			return "appended to long-term memory", nil
@@ -6,13 +6,17 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/kr0nicas/picobot/internal/agent/memory"
 )
 
 func TestWriteMemoryTool_TodayAndLong(t *testing.T) {
 	tmp := t.TempDir()
-	mem := memory.NewMemoryStoreWithWorkspace(tmp, 10)
+	mem, err := memory.NewMemoryStoreWithWorkspace(tmp, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
 	w := NewWriteMemoryTool(mem)
 
 	// append to today
@@ -45,3 +49,108 @@ func TestWriteMemoryTool_TodayAndLong(t *testing.T) {
 		t.Fatalf("expected LT1 to be gone after overwrite, got %q", lt2)
 	}
 }
+
+func TestSearchMemoryFindsTaggedNotes(t *testing.T) {
+	mem, err := memory.NewMemoryStoreWithWorkspace(t.TempDir(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mem.Close()
+	w := NewWriteMemoryTool(mem)
+	ctx := WithChat(context.Background(), "telegram", "42")
+	args := map[string]interface{}{"target": "today", "content": "passport expires in May", "tags": []interface{}{"travel"}}
+	if _, err := w.Execute(ctx, args); err != nil {
+		t.Fatal(err)
+	}
+	w.Execute(ctx, map[string]interface{}{"target": "today", "content": "buy milk"})
+
+	s := NewSearchMemoryTool(mem)
+	out, err := s.Execute(context.Background(), map[string]interface{}{"tag": "travel", "since": time.Now().UTC().Format("2006-01-02")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "passport expires in May #travel") || strings.Contains(out, "milk") {
+		t.Fatalf("unexpected search result: %s", out)
+	}
+	if items, _ := mem.Query(memory.Query{Tag: "travel"}); len(items) != 1 || items[0].Channel != "telegram" {
+		t.Fatalf("channel not recorded: %+v", items)
+	}
+	out, _ = s.Execute(context.Background(), map[string]interface{}{"query": "may passport", "kind": "short"})
+	if !strings.Contains(out, "passport expires in May") {
+		t.Fatalf("unexpected word search result: %s", out)
+	}
+	if out, _ = s.Execute(context.Background(), map[string]interface{}{"query": "passport", "kind": "long"}); !strings.Contains(out, "No matching") {
+		t.Fatalf("kind filter ignored: %s", out)
+	}
+	if _, err := s.Execute(context.Background(), map[string]interface{}{"since": "last week"}); err == nil {
+		t.Error("expected an error for an invalid date")
+	}
+}
+
+func TestForgetToolAsksFirst(t *testing.T) {
+	mem, err := memory.NewMemoryStoreWithWorkspace(t.TempDir(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mem.Close()
+	w := NewWriteMemoryTool(mem)
+	w.Execute(context.Background(), map[string]interface{}{"target": "long", "content": "address: Rua A 1"})
+	if _, err := w.Execute(context.Background(), map[string]interface{}{"target": "today", "content": "x", "until": "2001-01-01"}); err == nil {
+		t.Error("expected an error for an expiry in the past")
+	}
+
+	f := NewForgetTool(mem)
+	if desc := f.RequiresApproval(map[string]interface{}{"query": "nothing like it"}); desc != "" {
+		t.Errorf("no approval needed without matches, got %q", desc)
+	}
+	args := map[string]interface{}{"query": "rua a"}
+	if desc := f.RequiresApproval(args); !strings.Contains(desc, `forget 1 memory: "address: Rua A 1"`) {
+		t.Errorf("unexpected approval: %q", desc)
+	}
+	if _, err := f.Execute(context.Background(), args); err != nil {
+		t.Fatal(err)
+	}
+	if lt, _ := mem.ReadLongTerm(); strings.Contains(lt, "Rua A") {
+		t.Fatalf("memory not forgotten: %q", lt)
+	}
+}
+
+func TestExportImportMemoryTools(t *testing.T) {
+	ws := t.TempDir()
+	root, err := os.OpenRoot(ws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer root.Close()
+	mem, err := memory.NewMemoryStoreWithWorkspace(ws, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mem.Close()
+	mem.AppendLong(memory.MemoryItem{Text: "- birthday on 3 May"})
+
+	out, err := NewExportMemoryTool(mem, root).Execute(context.Background(), map[string]interface{}{"path": "backups/mem.tar.gz"})
+	if err != nil || !strings.Contains(out, "backups/mem.tar.gz") {
+		t.Fatalf("export: %q %v", out, err)
+	}
+
+	other, err := memory.NewMemoryStoreWithWorkspace(t.TempDir(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	imp := NewImportMemoryTool(other, root)
+	args := map[string]interface{}{"path": "backups/mem.tar.gz"}
+	if imp.RequiresApproval(args) == "" {
+		t.Fatal("import must ask for approval")
+	}
+	if out, err = imp.Execute(context.Background(), args); err != nil || !strings.Contains(out, "Imported 1 memory") {
+		t.Fatalf("import: %q %v", out, err)
+	}
+	if lt, _ := other.ReadLongTerm(); !strings.Contains(lt, "birthday on 3 May") {
+		t.Fatalf("MEMORY.md not imported: %q", lt)
+	}
+	if _, err := imp.Execute(context.Background(), map[string]interface{}{"path": "../outside.tar.gz"}); err == nil {
+		t.Fatal("expected paths outside the workspace to be refused")
+	}
+}
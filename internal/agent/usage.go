@@ -0,0 +1,51 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/providers"
+	"github.com/kr0nicas/picobot/internal/usage"
+)
+
+// SetUsageLedger attaches the ledger that records the tokens of every LLM
+// request.
+func (a *AgentLoop) SetUsageLedger(l *usage.Ledger) {
+	a.usage = l
+}
+
+// recordUsage adds a request's token counts to the ledger.
+func (a *AgentLoop) recordUsage(model, task string, u providers.Usage) {
+	if err := a.usage.Record(model, task, u.PromptTokens, u.CompletionTokens); err != nil {
+		agentLog.Error("recording usage failed", "err", err)
+	}
+}
+
+// usageCommand reports today's and this month's tokens and cost per model.
+func (a *AgentLoop) usageCommand() string {
+	if a.usage == nil {
+		return "Usage accounting is not configured."
+	}
+	now := time.Now()
+	var b strings.Builder
+	writeTotals(&b, "Today", a.usage.Day(now))
+	b.WriteString("\n")
+	writeTotals(&b, "This month", a.usage.Month(now))
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func writeTotals(b *strings.Builder, title string, totals []usage.Totals) {
+	fmt.Fprintf(b, "%s:\n", title)
+	if len(totals) == 0 {
+		b.WriteString("  no requests\n")
+		return
+	}
+	var cost float64
+	for _, t := range totals {
+		fmt.Fprintf(b, "  %s: %d requests, %d in / %d out tokens, $%.4f\n",
+			t.Model, t.Requests, t.PromptTokens, t.CompletionTokens, t.Cost)
+		cost += t.Cost
+	}
+	fmt.Fprintf(b, "  total: $%.4f\n", cost)
+}
@@ -0,0 +1,45 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/providers"
+	"github.com/kr0nicas/picobot/internal/usage"
+)
+
+// usageProvider reports fixed token counts for every request.
+type usageProvider struct{}
+
+func (usageProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string) (providers.LLMResponse, error) {
+	return providers.LLMResponse{Content: "ok", Usage: providers.Usage{PromptTokens: 1000, CompletionTokens: 500}}, nil
+}
+
+func (usageProvider) GetDefaultModel() string { return "default" }
+
+func TestUsageIsRecordedAndReported(t *testing.T) {
+	ws := t.TempDir()
+	ag := newTestLoop(t, chat.NewHub(10), usageProvider{}, "gpt-test", 3, ws, nil)
+	if got := ag.usageCommand(); !strings.Contains(got, "not configured") {
+		t.Fatalf("expected not configured reply, got %q", got)
+	}
+	l, err := usage.Open(filepath.Join(ws, "usage.jsonl"), map[string]usage.Price{"gpt-test": {Input: 2, Output: 10}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	ag.SetUsageLedger(l)
+
+	ag.processMessage(context.Background(), chat.Inbound{Channel: "telegram", SenderID: "1", ChatID: "1", Content: "hi"})
+	reply, ok := ag.handleCommand(chat.Inbound{Channel: "telegram", SenderID: "1", ChatID: "1", Content: "/usage"})
+	if !ok {
+		t.Fatal("/usage was not handled")
+	}
+	// 1000 * $2/M + 500 * $10/M = $0.007
+	if !strings.Contains(reply, "gpt-test: 1 requests, 1000 in / 500 out tokens, $0.0070") {
+		t.Fatalf("unexpected /usage reply:\n%s", reply)
+	}
+}
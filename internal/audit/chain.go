@@ -0,0 +1,162 @@
+// Package audit records privileged agent operations in a tamper-evident,
+// hash-chained log. Each entry stores the hash of its predecessor, so
+// editing, inserting or removing an entry breaks every hash after it.
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Entry kinds.
+const (
+	KindExec       = "exec"
+	KindFile       = "file"
+	KindConfig     = "config"
+	KindApproval   = "approval"
+	KindModeration = "moderation"
+)
+
+// Entry is one audit record.
+type Entry struct {
+	Seq    int64                  `json:"seq"`
+	Time   time.Time              `json:"time"`
+	Kind   string                 `json:"kind"`
+	Actor  string                 `json:"actor"` // "channel:senderID" or "system"
+	Action string                 `json:"action"`
+	Detail map[string]interface{} `json:"detail,omitempty"`
+	Prev   string                 `json:"prev"`
+	Hash   string                 `json:"hash"`
+}
+
+// genesis is the Prev value of the first entry.
+const genesis = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// computeHash hashes the entry's content (everything except Hash).
+func computeHash(e Entry) (string, error) {
+	e.Hash = ""
+	b, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Log appends entries to a hash-chained JSONL file.
+// A nil *Log is valid and records nothing.
+type Log struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+	seq  int64
+	last string
+}
+
+// Open opens (or creates) the audit log at path and continues its chain.
+func Open(path string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("audit: create dir: %w", err)
+	}
+	l := &Log{path: path, last: genesis}
+	if rf, err := os.Open(path); err == nil {
+		sc := bufio.NewScanner(rf)
+		sc.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+		for sc.Scan() {
+			var e Entry
+			if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+				continue // a corrupt line is reported by Verify, not here
+			}
+			l.seq, l.last = e.Seq, e.Hash
+		}
+		rf.Close()
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("audit: open log: %w", err)
+	}
+	l.f = f
+	return l, nil
+}
+
+// Path returns the log file path.
+func (l *Log) Path() string {
+	if l == nil {
+		return ""
+	}
+	return l.path
+}
+
+// Record appends an entry to the chain.
+func (l *Log) Record(kind, actor, action string, detail map[string]interface{}) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e := Entry{Seq: l.seq + 1, Time: time.Now().UTC(), Kind: kind, Actor: actor, Action: action, Detail: detail, Prev: l.last}
+	h, err := computeHash(e)
+	if err != nil {
+		return fmt.Errorf("audit: hash entry: %w", err)
+	}
+	e.Hash = h
+	b, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("audit: encode entry: %w", err)
+	}
+	if _, err := l.f.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("audit: write entry: %w", err)
+	}
+	l.seq, l.last = e.Seq, e.Hash
+	return nil
+}
+
+// Close closes the log file.
+func (l *Log) Close() error {
+	if l == nil || l.f == nil {
+		return nil
+	}
+	return l.f.Close()
+}
+
+// Verify checks the whole chain at path and returns the number of valid
+// entries. The error identifies the first entry that fails verification.
+func Verify(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("audit: %w", err)
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	prev, n := genesis, 0
+	for line := 1; sc.Scan(); line++ {
+		var e Entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return n, fmt.Errorf("audit: line %d: malformed entry: %w", line, err)
+		}
+		if e.Seq != int64(n+1) {
+			return n, fmt.Errorf("audit: line %d: expected seq %d, found %d (entry removed or inserted)", line, n+1, e.Seq)
+		}
+		if e.Prev != prev {
+			return n, fmt.Errorf("audit: line %d (seq %d): chain broken, previous hash does not match", line, e.Seq)
+		}
+		h, err := computeHash(e)
+		if err != nil {
+			return n, err
+		}
+		if h != e.Hash {
+			return n, fmt.Errorf("audit: line %d (seq %d): entry was modified", line, e.Seq)
+		}
+		prev = e.Hash
+		n++
+	}
+	return n, sc.Err()
+}
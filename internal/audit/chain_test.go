@@ -0,0 +1,42 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestChainVerifyAndTamper(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "chain.jsonl")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	l.Record(KindExec, "telegram:1", "run ls", map[string]interface{}{"ok": true})
+	l.Record(KindFile, "telegram:1", "write notes.txt", nil)
+	l.Close()
+
+	// reopening continues the chain
+	l, _ = Open(path)
+	l.Record(KindConfig, "cli:user", "model=gpt", nil)
+	l.Close()
+
+	n, err := Verify(path)
+	if err != nil || n != 3 {
+		t.Fatalf("Verify = %d, %v; want 3, nil", n, err)
+	}
+
+	data, _ := os.ReadFile(path)
+	tampered := strings.Replace(string(data), "write notes.txt", "write other.txt", 1)
+	os.WriteFile(path, []byte(tampered), 0o600)
+	if n, err := Verify(path); err == nil || n != 1 {
+		t.Fatalf("expected tampering at entry 2 to be detected, got %d, %v", n, err)
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	os.WriteFile(path, []byte(lines[0]+lines[2]), 0o600)
+	if _, err := Verify(path); err == nil {
+		t.Fatalf("expected removed entry to be detected")
+	}
+}
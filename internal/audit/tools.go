@@ -0,0 +1,216 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Outcomes of a tool call.
+const (
+	OutcomeOK      = "ok"
+	OutcomeError   = "error"
+	OutcomeDenied  = "denied"  // the user did not approve it
+	OutcomeBlocked = "blocked" // stopped by the content filter
+)
+
+// ToolCall is one entry of the tool call log.
+type ToolCall struct {
+	Time        time.Time       `json:"time"`
+	Channel     string          `json:"channel,omitempty"`
+	ChatID      string          `json:"chatID,omitempty"`
+	Sender      string          `json:"sender,omitempty"`
+	Tool        string          `json:"tool"`
+	Args        json.RawMessage `json:"args,omitempty"` // redacted
+	ResultBytes int             `json:"resultBytes"`
+	DurationMS  int64           `json:"durationMs"`
+	Outcome     string          `json:"outcome"`
+	Error       string          `json:"error,omitempty"`
+}
+
+// String renders c as one line for people to read.
+func (c ToolCall) String() string {
+	var b strings.Builder
+	b.WriteString(c.Time.Local().Format("2006-01-02 15:04:05"))
+	if c.Channel != "" {
+		fmt.Fprintf(&b, " %s:%s", c.Channel, c.ChatID)
+	}
+	fmt.Fprintf(&b, " %s %s", c.Tool, c.Args)
+	fmt.Fprintf(&b, " -> %s, %d bytes, %s", c.Outcome, c.ResultBytes, time.Duration(c.DurationMS)*time.Millisecond)
+	if c.Error != "" {
+		fmt.Fprintf(&b, ": %s", c.Error)
+	}
+	return b.String()
+}
+
+// ToolLog appends tool calls to one JSONL file per day (tools-2006-01-02.jsonl)
+// in a directory. Files are only ever appended to. A nil *ToolLog is valid
+// and records nothing.
+type ToolLog struct {
+	mu  sync.Mutex
+	dir string
+	day string
+	f   *os.File
+}
+
+// OpenToolLog opens the tool call log in dir, creating dir if needed.
+func OpenToolLog(dir string) (*ToolLog, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("audit: create dir: %w", err)
+	}
+	return &ToolLog{dir: dir}, nil
+}
+
+// Dir returns the directory of the log files.
+func (l *ToolLog) Dir() string {
+	if l == nil {
+		return ""
+	}
+	return l.dir
+}
+
+// toolLogFile returns the file of the calls made on day (in local time).
+func toolLogFile(dir string, day time.Time) string {
+	return filepath.Join(dir, "tools-"+day.Format("2006-01-02")+".jsonl")
+}
+
+// Record appends c, stamped with the current time if it has none.
+func (l *ToolLog) Record(c ToolCall) error {
+	if l == nil {
+		return nil
+	}
+	if c.Time.IsZero() {
+		c.Time = time.Now()
+	}
+	c.Time = c.Time.UTC()
+	b, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("audit: encode tool call: %w", err)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	day := c.Time.Local().Format("2006-01-02")
+	if l.f == nil || day != l.day {
+		if l.f != nil {
+			l.f.Close()
+		}
+		f, err := os.OpenFile(toolLogFile(l.dir, c.Time.Local()), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			l.f = nil
+			return fmt.Errorf("audit: open tool log: %w", err)
+		}
+		l.f, l.day = f, day
+	}
+	if _, err := l.f.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("audit: write tool call: %w", err)
+	}
+	return nil
+}
+
+// Close closes the current log file.
+func (l *ToolLog) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
+
+// ToolQuery selects logged tool calls. Zero fields match everything.
+type ToolQuery struct {
+	From, To time.Time // calls made at or after From and before To
+	Tool     string
+	Chat     string // "channel" or "channel:chatID"
+	Outcome  string
+}
+
+func (q ToolQuery) matches(c ToolCall) bool {
+	switch {
+	case !q.From.IsZero() && c.Time.Before(q.From), !q.To.IsZero() && !c.Time.Before(q.To):
+		return false
+	case q.Tool != "" && c.Tool != q.Tool, q.Outcome != "" && c.Outcome != q.Outcome:
+		return false
+	case q.Chat != "" && q.Chat != c.Channel && q.Chat != c.Channel+":"+c.ChatID:
+		return false
+	}
+	return true
+}
+
+// ReadToolCalls returns the calls logged in dir that match q, oldest first.
+// Without q.From, only today's calls are read.
+func ReadToolCalls(dir string, q ToolQuery) ([]ToolCall, error) {
+	from, to := q.From, q.To
+	if from.IsZero() {
+		from = startOfDay(time.Now())
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+	var out []ToolCall
+	for day := startOfDay(from.Local()); day.Before(to); day = day.AddDate(0, 0, 1) {
+		f, err := os.Open(toolLogFile(dir, day))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return out, fmt.Errorf("audit: %w", err)
+		}
+		sc := bufio.NewScanner(f)
+		sc.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+		for sc.Scan() {
+			var c ToolCall
+			if json.Unmarshal(sc.Bytes(), &c) == nil && q.matches(c) {
+				out = append(out, c)
+			}
+		}
+		err = sc.Err()
+		f.Close()
+		if err != nil {
+			return out, fmt.Errorf("audit: %w", err)
+		}
+	}
+	return out, nil
+}
+
+// ParsePeriod turns "today", "yesterday", a date ("2026-10-16") or a
+// duration back from now ("3h", "7d") into the period it covers.
+func ParsePeriod(s string, now time.Time) (from, to time.Time, err error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	today := startOfDay(now)
+	switch s {
+	case "", "today":
+		return today, now, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), today, nil
+	}
+	if day, err := time.ParseInLocation("2006-01-02", s, now.Location()); err == nil {
+		return day, day.AddDate(0, 0, 1), nil
+	}
+	if n, ok := strings.CutSuffix(s, "d"); ok {
+		if days, err := strconv.Atoi(n); err == nil && days > 0 {
+			return now.AddDate(0, 0, -days), now, nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return now.Add(-d), now, nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("audit: unknown period %q; use today, yesterday, a date like 2026-10-16 or a duration like 3h or 7d", s)
+}
+
+// startOfDay returns midnight of t's day in its location.
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,43 @@
+package audit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestToolLogQuery(t *testing.T) {
+	dir := t.TempDir()
+	l, err := OpenToolLog(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	yesterday := now.AddDate(0, 0, -1)
+	l.Record(ToolCall{Time: yesterday, Channel: "telegram", ChatID: "1", Tool: "exec", Args: []byte(`{"cmd":"ls"}`), Outcome: OutcomeOK})
+	l.Record(ToolCall{Time: yesterday, Channel: "discord", ChatID: "2", Tool: "exec", Outcome: OutcomeDenied})
+	l.Record(ToolCall{Channel: "telegram", ChatID: "1", Tool: "web", Outcome: OutcomeOK})
+	l.Close()
+
+	from, to, err := ParsePeriod("yesterday", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls, err := ReadToolCalls(dir, ToolQuery{From: from, To: to})
+	if err != nil || len(calls) != 2 {
+		t.Fatalf("yesterday: got %d calls, %v", len(calls), err)
+	}
+	calls, _ = ReadToolCalls(dir, ToolQuery{From: from, To: to, Chat: "telegram"})
+	if len(calls) != 1 || string(calls[0].Args) != `{"cmd":"ls"}` {
+		t.Fatalf("unexpected telegram calls: %+v", calls)
+	}
+	if calls, _ := ReadToolCalls(dir, ToolQuery{}); len(calls) != 1 || calls[0].Tool != "web" {
+		t.Fatalf("today: unexpected calls %+v", calls)
+	}
+	from, _, _ = ParsePeriod("2d", now)
+	if calls, _ := ReadToolCalls(dir, ToolQuery{From: from, Outcome: OutcomeDenied}); len(calls) != 1 || calls[0].Channel != "discord" {
+		t.Fatalf("denied: unexpected calls %+v", calls)
+	}
+	if _, _, err := ParsePeriod("last tuesday", now); err == nil {
+		t.Fatal("an unknown period was accepted")
+	}
+}
@@ -0,0 +1,142 @@
+package calendar
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CalDAV is a calendar collection on a CalDAV server.
+type CalDAV struct {
+	url      *url.URL // the collection, with a trailing slash
+	username string
+	password string
+	loc      *time.Location // for floating times
+	client   *http.Client
+}
+
+// NewCalDAV returns the calendar collection at collectionURL, e.g.
+// https://cloud.example/remote.php/dav/calendars/me/personal/. Floating
+// times are read in loc.
+func NewCalDAV(collectionURL, username, password string, loc *time.Location) (*CalDAV, error) {
+	u, err := url.Parse(collectionURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("calendar: invalid caldav url %q", collectionURL)
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	return &CalDAV{url: u, username: username, password: password, loc: loc, client: httpClient}, nil
+}
+
+type multistatus struct {
+	Responses []struct {
+		Href     string `xml:"href"`
+		Propstat []struct {
+			Data string `xml:"prop>calendar-data"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+func (c *CalDAV) do(ctx context.Context, method, target string, body string, header map[string]string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("calendar: %w", err)
+	}
+	return resp, nil
+}
+
+func statusError(resp *http.Response) error {
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("calendar: %s %s: HTTP %s: %s", resp.Request.Method, resp.Request.URL.Redacted(), resp.Status, strings.TrimSpace(string(b)))
+}
+
+func (c *CalDAV) List(ctx context.Context, from, to time.Time) ([]Event, error) {
+	rng := fmt.Sprintf(`start="%s" end="%s"`, from.UTC().Format("20060102T150405Z"), to.UTC().Format("20060102T150405Z"))
+	body := `<?xml version="1.0" encoding="utf-8"?>
+<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><c:calendar-data><c:expand ` + rng + `/></c:calendar-data></d:prop>
+  <c:filter><c:comp-filter name="VCALENDAR"><c:comp-filter name="VEVENT"><c:time-range ` + rng + `/></c:comp-filter></c:comp-filter></c:filter>
+</c:calendar-query>`
+	resp, err := c.do(ctx, "REPORT", c.url.String(), body, map[string]string{"Depth": "1", "Content-Type": "application/xml; charset=utf-8"})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, statusError(resp)
+	}
+	var ms multistatus
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 10<<20)).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("calendar: parse caldav response: %w", err)
+	}
+	var events []Event
+	for _, r := range ms.Responses {
+		for _, ps := range r.Propstat {
+			for _, ev := range parseICS(ps.Data, c.loc) {
+				if ev.End.After(from) && ev.Start.Before(to) {
+					ev.ID = r.Href
+					events = append(events, ev)
+				}
+			}
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Start.Before(events[j].Start) })
+	return events, nil
+}
+
+func (c *CalDAV) Create(ctx context.Context, ev Event) (Event, error) {
+	now := time.Now()
+	ev.UID = newUID(now)
+	target := c.url.ResolveReference(&url.URL{Path: url.PathEscape(ev.UID) + ".ics"})
+	resp, err := c.do(ctx, "PUT", target.String(), formatICS(ev, now), map[string]string{
+		"Content-Type":  "text/calendar; charset=utf-8",
+		"If-None-Match": "*",
+	})
+	if err != nil {
+		return Event{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return Event{}, statusError(resp)
+	}
+	ev.ID = target.Path
+	return ev, nil
+}
+
+func (c *CalDAV) Delete(ctx context.Context, id string) error {
+	ref, err := url.Parse(id)
+	if err != nil {
+		return fmt.Errorf("calendar: invalid event id %q", id)
+	}
+	target := c.url.ResolveReference(ref)
+	// only resources of this collection may be deleted
+	if target.Host != c.url.Host || !strings.HasPrefix(target.Path, c.url.Path) || target.Path == c.url.Path {
+		return fmt.Errorf("calendar: event %q is not in the calendar", id)
+	}
+	resp, err := c.do(ctx, "DELETE", target.String(), "", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return statusError(resp)
+	}
+	return nil
+}
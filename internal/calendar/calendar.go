@@ -0,0 +1,44 @@
+// Package calendar reads and changes the user's calendar through CalDAV
+// (Nextcloud, iCloud, Fastmail, Radicale, ...) or the Google Calendar API.
+package calendar
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// Event is a calendar entry.
+type Event struct {
+	// ID identifies the event for Delete: the resource path on CalDAV, the
+	// event ID on Google.
+	ID          string
+	UID         string
+	Title       string
+	Start       time.Time
+	End         time.Time // exclusive; the day after the last day for all-day events
+	AllDay      bool
+	Location    string
+	Description string
+}
+
+// Backend is a calendar service.
+type Backend interface {
+	// List returns the events overlapping [from, to), ordered by start.
+	List(ctx context.Context, from, to time.Time) ([]Event, error)
+	// Create adds ev and returns it with its ID set.
+	Create(ctx context.Context, ev Event) (Event, error)
+	Delete(ctx context.Context, id string) error
+}
+
+const requestTimeout = 30 * time.Second
+
+var httpClient = &http.Client{Timeout: requestTimeout}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,188 @@
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestICSRoundTrip(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 30, 0, 0, time.UTC)
+	ev := Event{UID: "u1", Title: "Dentist, Dr. A; room 2", Start: start, End: start.Add(time.Hour),
+		Location: "Main St", Description: "bring card\n" + strings.Repeat("x", 100)}
+	data := formatICS(ev, start)
+	for _, line := range strings.Split(data, "\r\n") {
+		if len(line) > 75 {
+			t.Fatalf("line not folded: %q", line)
+		}
+	}
+	got := parseICS(data, time.UTC)
+	if len(got) != 1 {
+		t.Fatalf("got %d events", len(got))
+	}
+	g := got[0]
+	if g.UID != "u1" || g.Title != ev.Title || g.Description != ev.Description || !g.Start.Equal(start) || !g.End.Equal(ev.End) {
+		t.Fatalf("round trip: %+v", g)
+	}
+}
+
+func TestParseICS(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no tzdata")
+	}
+	data := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:a\nSUMMARY:Holiday\nDTSTART;VALUE=DATE:20260401\nEND:VEVENT\n" +
+		"BEGIN:VEVENT\nUID:b\nSUMMARY:Call\nDTSTART;TZID=Europe/Berlin:20260401T100000\nDTEND;TZID=Europe/Berlin:20260401T103000\n" +
+		"BEGIN:VALARM\nDESCRIPTION:reminder\nEND:VALARM\nEND:VEVENT\nEND:VCALENDAR\n"
+	got := parseICS(data, time.UTC)
+	if len(got) != 2 {
+		t.Fatalf("got %d events", len(got))
+	}
+	if !got[0].AllDay || !got[0].End.Equal(got[0].Start.AddDate(0, 0, 1)) {
+		t.Errorf("all-day event: %+v", got[0])
+	}
+	if want := time.Date(2026, 4, 1, 10, 0, 0, 0, berlin); !got[1].Start.Equal(want) || got[1].Description != "" {
+		t.Errorf("timed event: %+v", got[1])
+	}
+}
+
+func TestCalDAV(t *testing.T) {
+	var put string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u, p, _ := r.BasicAuth(); u != "me" || p != "pw" {
+			http.Error(w, "auth", http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == "REPORT" && r.URL.Path == "/cal/":
+			body, _ := io.ReadAll(r.Body)
+			if r.Header.Get("Depth") != "1" || !strings.Contains(string(body), `<c:time-range start="20260301T000000Z"`) {
+				http.Error(w, "bad query", http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusMultiStatus)
+			io.WriteString(w, `<?xml version="1.0"?><d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+<d:response><d:href>/cal/a.ics</d:href><d:propstat><d:prop><c:calendar-data>BEGIN:VCALENDAR
+BEGIN:VEVENT
+UID:a
+SUMMARY:Standup
+DTSTART:20260302T090000Z
+DTEND:20260302T091500Z
+END:VEVENT
+END:VCALENDAR
+</c:calendar-data></d:prop></d:propstat></d:response></d:multistatus>`)
+		case r.Method == "PUT" && strings.HasPrefix(r.URL.Path, "/cal/"):
+			b, _ := io.ReadAll(r.Body)
+			put = string(b)
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == "DELETE" && r.URL.Path == "/cal/a.ics":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c, err := NewCalDAV(srv.URL+"/cal", "me", "pw", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	events, err := c.List(ctx, from, from.AddDate(0, 0, 7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].ID != "/cal/a.ics" || events[0].Title != "Standup" {
+		t.Fatalf("List = %+v", events)
+	}
+
+	ev, err := c.Create(ctx, Event{Title: "Lunch", Start: from, End: from.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(ev.ID, "/cal/") || !strings.Contains(put, "SUMMARY:Lunch") {
+		t.Fatalf("Create = %+v, body %q", ev, put)
+	}
+
+	if err := c.Delete(ctx, "/cal/a.ics"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete(ctx, "/other/a.ics"); err == nil {
+		t.Fatal("deleting outside the collection should fail")
+	}
+}
+
+func TestGoogle(t *testing.T) {
+	tokens := 0
+	var created map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			r.ParseForm()
+			if r.PostForm.Get("refresh_token") != "rt" {
+				http.Error(w, "bad token", http.StatusBadRequest)
+				return
+			}
+			tokens++
+			io.WriteString(w, `{"access_token":"at","expires_in":3600}`)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer at" {
+			http.Error(w, "auth", http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/calendars/primary/events":
+			if r.URL.Query().Get("singleEvents") != "true" {
+				http.Error(w, "query", http.StatusBadRequest)
+				return
+			}
+			io.WriteString(w, `{"items":[
+{"id":"e1","summary":"Standup","start":{"dateTime":"2026-03-02T09:00:00Z"},"end":{"dateTime":"2026-03-02T09:15:00Z"}},
+{"id":"e2","status":"cancelled","summary":"Gone","start":{"date":"2026-03-03"},"end":{"date":"2026-03-04"}},
+{"id":"e3","summary":"Trip","start":{"date":"2026-03-05"},"end":{"date":"2026-03-07"}}]}`)
+		case r.Method == "POST" && r.URL.Path == "/calendars/primary/events":
+			json.NewDecoder(r.Body).Decode(&created)
+			io.WriteString(w, `{"id":"new","summary":"Lunch","start":{"dateTime":"2026-03-02T12:00:00Z"},"end":{"dateTime":"2026-03-02T13:00:00Z"}}`)
+		case r.Method == "DELETE" && r.URL.Path == "/calendars/primary/events/e1":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	g, err := NewGoogle("", "id", "secret", "rt", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.tokenURL = srv.URL + "/token"
+	g.apiURL = srv.URL
+	ctx := context.Background()
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	events, err := g.List(ctx, from, from.AddDate(0, 0, 7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].ID != "e1" || !events[1].AllDay || events[1].End.Sub(events[1].Start) != 48*time.Hour {
+		t.Fatalf("List = %+v", events)
+	}
+	ev, err := g.Create(ctx, Event{Title: "Lunch", Start: from, End: from.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.ID != "new" || created["summary"] != "Lunch" {
+		t.Fatalf("Create = %+v, sent %v", ev, created)
+	}
+	if err := g.Delete(ctx, "e1"); err != nil {
+		t.Fatal(err)
+	}
+	if tokens != 1 {
+		t.Errorf("token refreshed %d times, want 1", tokens)
+	}
+}
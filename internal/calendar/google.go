@@ -0,0 +1,215 @@
+package calendar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	googleTokenURL = "https://oauth2.googleapis.com/token"
+	googleAPIURL   = "https://www.googleapis.com/calendar/v3"
+)
+
+// Google is a Google calendar, accessed with an OAuth refresh token.
+type Google struct {
+	calendarID   string
+	clientID     string
+	clientSecret string
+	refreshToken string
+	loc          *time.Location // for all-day events
+	tokenURL     string
+	apiURL       string
+	client       *http.Client
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// NewGoogle returns the Google calendar calendarID ("primary" if empty).
+// The refresh token must have been granted the calendar.events scope to
+// the OAuth client.
+func NewGoogle(calendarID, clientID, clientSecret, refreshToken string, loc *time.Location) (*Google, error) {
+	if clientID == "" || clientSecret == "" || refreshToken == "" {
+		return nil, fmt.Errorf("calendar: google needs clientId, clientSecret and refreshToken")
+	}
+	if calendarID == "" {
+		calendarID = "primary"
+	}
+	return &Google{
+		calendarID:   calendarID,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		refreshToken: refreshToken,
+		loc:          loc,
+		tokenURL:     googleTokenURL,
+		apiURL:       googleAPIURL,
+		client:       httpClient,
+	}, nil
+}
+
+// accessToken returns a valid access token, refreshing it when it is about
+// to expire.
+func (g *Google) accessToken(ctx context.Context) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.token != "" && time.Until(g.expiry) > time.Minute {
+		return g.token, nil
+	}
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {g.clientID},
+		"client_secret": {g.clientSecret},
+		"refresh_token": {g.refreshToken},
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", g.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("calendar: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("calendar: refresh google token: HTTP %s: %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil || tok.AccessToken == "" {
+		return "", fmt.Errorf("calendar: invalid google token response")
+	}
+	g.token = tok.AccessToken
+	g.expiry = time.Now().Add(time.Duration(tok.ExpiresIn) * time.Second)
+	return g.token, nil
+}
+
+func (g *Google) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	token, err := g.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	target := g.apiURL + "/calendars/" + url.PathEscape(g.calendarID) + "/events" + path
+	if query != nil {
+		target += "?" + query.Encode()
+	}
+	var rd io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		rd = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, rd)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("calendar: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return statusError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 10<<20)).Decode(out); err != nil {
+		return fmt.Errorf("calendar: parse google response: %w", err)
+	}
+	return nil
+}
+
+type googleTime struct {
+	DateTime string `json:"dateTime,omitempty"`
+	Date     string `json:"date,omitempty"`
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+type googleEvent struct {
+	ID          string     `json:"id,omitempty"`
+	ICalUID     string     `json:"iCalUID,omitempty"`
+	Status      string     `json:"status,omitempty"`
+	Summary     string     `json:"summary"`
+	Location    string     `json:"location,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Start       googleTime `json:"start"`
+	End         googleTime `json:"end"`
+}
+
+func (g *Google) toEvent(ge googleEvent) Event {
+	ev := Event{ID: ge.ID, UID: ge.ICalUID, Title: ge.Summary, Location: ge.Location, Description: ge.Description}
+	if ge.Start.Date != "" {
+		ev.AllDay = true
+		ev.Start, _ = time.ParseInLocation("2006-01-02", ge.Start.Date, g.loc)
+		ev.End, _ = time.ParseInLocation("2006-01-02", ge.End.Date, g.loc)
+	} else {
+		ev.Start, _ = time.Parse(time.RFC3339, ge.Start.DateTime)
+		ev.End, _ = time.Parse(time.RFC3339, ge.End.DateTime)
+	}
+	return ev
+}
+
+func (g *Google) List(ctx context.Context, from, to time.Time) ([]Event, error) {
+	q := url.Values{
+		"timeMin":      {from.Format(time.RFC3339)},
+		"timeMax":      {to.Format(time.RFC3339)},
+		"singleEvents": {"true"},
+		"orderBy":      {"startTime"},
+		"maxResults":   {"250"},
+	}
+	var res struct {
+		Items []googleEvent `json:"items"`
+	}
+	if err := g.do(ctx, "GET", "", q, nil, &res); err != nil {
+		return nil, err
+	}
+	var events []Event
+	for _, ge := range res.Items {
+		if ge.Status != "cancelled" {
+			events = append(events, g.toEvent(ge))
+		}
+	}
+	return events, nil
+}
+
+func (g *Google) Create(ctx context.Context, ev Event) (Event, error) {
+	ge := googleEvent{Summary: ev.Title, Location: ev.Location, Description: ev.Description}
+	if ev.AllDay {
+		ge.Start.Date = ev.Start.Format("2006-01-02")
+		ge.End.Date = ev.End.Format("2006-01-02")
+	} else {
+		ge.Start.DateTime = ev.Start.Format(time.RFC3339)
+		ge.End.DateTime = ev.End.Format(time.RFC3339)
+	}
+	var created googleEvent
+	if err := g.do(ctx, "POST", "", nil, ge, &created); err != nil {
+		return Event{}, err
+	}
+	return g.toEvent(created), nil
+}
+
+func (g *Google) Delete(ctx context.Context, id string) error {
+	if id == "" || strings.ContainsAny(id, "/?#") {
+		return fmt.Errorf("calendar: invalid event id %q", id)
+	}
+	return g.do(ctx, "DELETE", "/"+url.PathEscape(id), nil, nil, nil)
+}
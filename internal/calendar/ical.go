@@ -0,0 +1,164 @@
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// parseICS returns the events of an iCalendar document. Recurrence rules
+// are not expanded; a recurring event is returned once, at its first start.
+func parseICS(data string, loc *time.Location) []Event {
+	var events []Event
+	var ev *Event
+	depth := 0 // nesting inside the current VEVENT (e.g. VALARM)
+	for _, line := range unfold(data) {
+		name, params, value := splitProp(line)
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			ev = &Event{}
+			depth = 0
+		case ev == nil:
+		case name == "BEGIN":
+			depth++
+		case name == "END" && depth > 0:
+			depth--
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			if ev.End.IsZero() {
+				ev.End = ev.Start
+				if ev.AllDay {
+					ev.End = ev.Start.AddDate(0, 0, 1)
+				}
+			}
+			events = append(events, *ev)
+			ev = nil
+		case depth > 0:
+		case name == "UID":
+			ev.UID = value
+		case name == "SUMMARY":
+			ev.Title = unescapeText(value)
+		case name == "LOCATION":
+			ev.Location = unescapeText(value)
+		case name == "DESCRIPTION":
+			ev.Description = unescapeText(value)
+		case name == "DTSTART":
+			ev.Start, ev.AllDay = parseICSTime(value, params, loc)
+		case name == "DTEND":
+			ev.End, _ = parseICSTime(value, params, loc)
+		}
+	}
+	return events
+}
+
+// unfold joins folded content lines (continuations start with a space or
+// tab).
+func unfold(data string) []string {
+	var lines []string
+	for _, l := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		if (strings.HasPrefix(l, " ") || strings.HasPrefix(l, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += l[1:]
+			continue
+		}
+		if l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+// splitProp splits a content line into its upper-cased name, its parameters
+// and its value.
+func splitProp(line string) (name string, params map[string]string, value string) {
+	head, value, _ := strings.Cut(line, ":")
+	parts := strings.Split(head, ";")
+	params = make(map[string]string, len(parts)-1)
+	for _, p := range parts[1:] {
+		if k, v, ok := strings.Cut(p, "="); ok {
+			params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), params, value
+}
+
+func parseICSTime(value string, params map[string]string, loc *time.Location) (time.Time, bool) {
+	if params["VALUE"] == "DATE" || len(value) == 8 {
+		t, err := time.ParseInLocation("20060102", value, loc)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return t, true
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, _ := time.Parse("20060102T150405Z", value)
+		return t, false
+	}
+	if tz := params["TZID"]; tz != "" {
+		if l, err := time.LoadLocation(tz); err == nil {
+			loc = l
+		}
+	}
+	t, _ := time.ParseInLocation("20060102T150405", value, loc)
+	return t, false
+}
+
+// formatICS renders ev as an iCalendar document.
+func formatICS(ev Event, now time.Time) string {
+	var sb strings.Builder
+	line := func(s string) { sb.WriteString(fold(s) + "\r\n") }
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//picobot//calendar//EN")
+	line("BEGIN:VEVENT")
+	line("UID:" + ev.UID)
+	line("DTSTAMP:" + now.UTC().Format("20060102T150405Z"))
+	if ev.AllDay {
+		line("DTSTART;VALUE=DATE:" + ev.Start.Format("20060102"))
+		line("DTEND;VALUE=DATE:" + ev.End.Format("20060102"))
+	} else {
+		line("DTSTART:" + ev.Start.UTC().Format("20060102T150405Z"))
+		line("DTEND:" + ev.End.UTC().Format("20060102T150405Z"))
+	}
+	line("SUMMARY:" + escapeText(ev.Title))
+	if ev.Location != "" {
+		line("LOCATION:" + escapeText(ev.Location))
+	}
+	if ev.Description != "" {
+		line("DESCRIPTION:" + escapeText(ev.Description))
+	}
+	line("END:VEVENT")
+	line("END:VCALENDAR")
+	return sb.String()
+}
+
+// fold splits lines longer than 75 bytes, without cutting UTF-8 sequences.
+func fold(s string) string {
+	if len(s) <= 75 {
+		return s
+	}
+	var sb strings.Builder
+	width := 75
+	for len(s) > width {
+		cut := width
+		for cut > 0 && s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		sb.WriteString(s[:cut] + "\r\n ")
+		s = s[cut:]
+		width = 74 // continuation lines start with a space
+	}
+	sb.WriteString(s)
+	return sb.String()
+}
+
+var (
+	textEscaper   = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+	textUnescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+)
+
+func escapeText(s string) string   { return textEscaper.Replace(s) }
+func unescapeText(s string) string { return textUnescaper.Replace(s) }
+
+// newUID returns a unique event ID.
+func newUID(now time.Time) string {
+	return fmt.Sprintf("%d-%s@picobot", now.UnixNano(), randomHex(6))
+}
@@ -0,0 +1,350 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/logging"
+	"github.com/kr0nicas/picobot/internal/telemetry"
+	"github.com/kr0nicas/picobot/internal/websocket"
+)
+
+var discordLog = logging.For("channels").With("channel", "discord")
+
+const (
+	discordAPIBase    = "https://discord.com/api/v10"
+	discordGatewayURL = "wss://gateway.discord.gg/?v=10&encoding=json"
+	discordMaxLen     = 2000
+	// GUILD_MESSAGES | DIRECT_MESSAGES | MESSAGE_CONTENT
+	discordIntents = 1<<9 | 1<<12 | 1<<15
+)
+
+// StartDiscord connects to the Discord gateway with a bot token.
+// allowFrom is a list of Discord user IDs permitted to talk to the bot;
+// messages from anyone else are dropped (an empty list drops everything).
+func StartDiscord(ctx context.Context, hub *chat.Hub, token string, allowFrom []string) error {
+	if token == "" {
+		return fmt.Errorf("discord token not provided")
+	}
+	return StartDiscordWithBase(ctx, hub, token, discordAPIBase, discordGatewayURL, allowFrom)
+}
+
+// StartDiscordWithBase is StartDiscord against the given REST base URL and
+// gateway URL (used by tests).
+func StartDiscordWithBase(ctx context.Context, hub *chat.Hub, token, apiBase, gatewayURL string, allowFrom []string) error {
+	if apiBase == "" || gatewayURL == "" {
+		return fmt.Errorf("discord: base and gateway URLs are required")
+	}
+	allowed := make(map[string]struct{}, len(allowFrom))
+	for _, id := range allowFrom {
+		allowed[id] = struct{}{}
+	}
+	d := &discord{hub: hub, token: token, apiBase: strings.TrimRight(apiBase, "/"), gatewayURL: gatewayURL,
+		allowed: allowed, client: &http.Client{Timeout: 15 * time.Second}}
+
+	// inbound gateway goroutine: reconnects with a backoff until ctx is done
+	go func() {
+		discordLog.Info("connecting to gateway", "allow_from", allowFrom)
+		backoff := time.Second
+		for {
+			start := time.Now()
+			err := d.runGateway(ctx)
+			if ctx.Err() != nil {
+				discordLog.Info("stopping gateway connection")
+				return
+			}
+			if time.Since(start) > time.Minute {
+				backoff = time.Second
+			}
+			discordLog.Warn("gateway disconnected, reconnecting", "err", err, "backoff", backoff)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			if backoff < time.Minute {
+				backoff *= 2
+			}
+		}
+	}()
+
+	// outbound sender goroutine
+	outbox := hub.Subscribe("discord")
+	go func() {
+		discordLog.Info("starting outbound sender")
+		for {
+			select {
+			case <-ctx.Done():
+				discordLog.Info("stopping outbound sender")
+				return
+			case out, ok := <-outbox:
+				if !ok {
+					return
+				}
+				discordLog.Debug("sending message", "chat_id", out.ChatID)
+				_, span := telemetry.StartRemote(out.Metadata, "send", "channel", "discord", "chat_id", out.ChatID)
+				for _, chunk := range splitMessage(out.Content, discordMaxLen) {
+					if err := d.send(ctx, out.ChatID, chunk); err != nil {
+						discordLog.Error("sending message failed", "chat_id", out.ChatID, "err", err)
+						span.RecordError(err)
+						break
+					}
+				}
+				span.End()
+			}
+		}
+	}()
+	return nil
+}
+
+type discord struct {
+	hub        *chat.Hub
+	token      string
+	apiBase    string
+	gatewayURL string
+	allowed    map[string]struct{}
+	client     *http.Client
+	botID      string   // set from the READY event
+	dmChannels sync.Map // user ID -> DM channel ID
+}
+
+// gatewayPayload is the envelope of every gateway message.
+type gatewayPayload struct {
+	Op int             `json:"op"`
+	D  json.RawMessage `json:"d,omitempty"`
+	S  *int64          `json:"s,omitempty"`
+	T  string          `json:"t,omitempty"`
+}
+
+type discordMessage struct {
+	ChannelID string `json:"channel_id"`
+	GuildID   string `json:"guild_id"`
+	Content   string `json:"content"`
+	Author    struct {
+		ID  string `json:"id"`
+		Bot bool   `json:"bot"`
+	} `json:"author"`
+	Mentions []struct {
+		ID string `json:"id"`
+	} `json:"mentions"`
+}
+
+// runGateway runs one gateway session: identify, heartbeat and dispatch
+// MESSAGE_CREATE events to the hub until the connection fails.
+func (d *discord) runGateway(ctx context.Context) error {
+	dialCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	conn, err := websocket.Dial(dialCtx, d.gatewayURL, nil)
+	cancel()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	var hello gatewayPayload
+	if err := conn.ReadJSON(&hello); err != nil {
+		return err
+	}
+	if hello.Op != 10 {
+		return fmt.Errorf("discord: expected hello, got op %d", hello.Op)
+	}
+	var hd struct {
+		HeartbeatInterval int `json:"heartbeat_interval"`
+	}
+	json.Unmarshal(hello.D, &hd)
+	if hd.HeartbeatInterval <= 0 {
+		hd.HeartbeatInterval = 41250
+	}
+
+	identify := map[string]interface{}{
+		"op": 2,
+		"d": map[string]interface{}{
+			"token":      d.token,
+			"intents":    discordIntents,
+			"properties": map[string]string{"os": "linux", "browser": "picobot", "device": "picobot"},
+		},
+	}
+	if err := conn.WriteJSON(identify); err != nil {
+		return err
+	}
+
+	var seq atomic.Int64 // last sequence number, -1 before the first dispatch
+	seq.Store(-1)
+	heartbeat := func() error {
+		var last interface{}
+		if s := seq.Load(); s >= 0 {
+			last = s
+		}
+		return conn.WriteJSON(map[string]interface{}{"op": 1, "d": last})
+	}
+	go func() {
+		t := time.NewTicker(time.Duration(hd.HeartbeatInterval) * time.Millisecond)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+				if err := heartbeat(); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		var p gatewayPayload
+		if err := conn.ReadJSON(&p); err != nil {
+			return err
+		}
+		if p.S != nil {
+			seq.Store(*p.S)
+		}
+		switch p.Op {
+		case 0: // dispatch
+			d.dispatch(p.T, p.D)
+		case 1: // heartbeat request
+			if err := heartbeat(); err != nil {
+				return err
+			}
+		case 7: // reconnect
+			return fmt.Errorf("discord: server requested reconnect")
+		case 9: // invalid session
+			return fmt.Errorf("discord: invalid session")
+		}
+	}
+}
+
+// dispatch handles gateway events.
+func (d *discord) dispatch(event string, data json.RawMessage) {
+	switch event {
+	case "READY":
+		var ready struct {
+			User struct {
+				ID string `json:"id"`
+			} `json:"user"`
+		}
+		json.Unmarshal(data, &ready)
+		d.botID = ready.User.ID
+		discordLog.Info("connected", "bot", d.botID)
+	case "MESSAGE_CREATE":
+		var m discordMessage
+		if err := json.Unmarshal(data, &m); err != nil || m.Author.Bot {
+			return
+		}
+		group := m.GuildID != ""
+		if group && !d.mentioned(m) {
+			return // in servers, only react when mentioned
+		}
+		if _, ok := d.allowed[m.Author.ID]; !ok {
+			discordLog.Warn("dropping message from unauthorized user", "sender", m.Author.ID)
+			return
+		}
+		content := m.Content
+		if d.botID != "" {
+			content = strings.NewReplacer("<@"+d.botID+">", "", "<@!"+d.botID+">", "").Replace(content)
+		}
+		discordLog.Info("received message", "sender", m.Author.ID, "chat_id", m.ChannelID)
+		d.hub.In <- chat.Inbound{
+			Channel:   "discord",
+			SenderID:  m.Author.ID,
+			ChatID:    m.ChannelID,
+			Content:   strings.TrimSpace(content),
+			Timestamp: time.Now(),
+			Metadata:  map[string]interface{}{"group": group},
+		}
+	}
+}
+
+// mentioned reports whether the bot is mentioned in m.
+func (d *discord) mentioned(m discordMessage) bool {
+	for _, u := range m.Mentions {
+		if u.ID == d.botID {
+			return true
+		}
+	}
+	return false
+}
+
+// send posts one message to a Discord channel. Admin notifications are
+// addressed to user IDs rather than channels; when the channel is unknown the
+// ID is treated as a user and a DM channel is opened for it.
+func (d *discord) send(ctx context.Context, channelID, content string) error {
+	if dm, ok := d.dmChannels.Load(channelID); ok {
+		channelID = dm.(string)
+	}
+	payload := map[string]string{"content": content}
+	status, err := d.post(ctx, "/channels/"+channelID+"/messages", payload, nil)
+	if status != http.StatusNotFound {
+		return err
+	}
+	var dm struct {
+		ID string `json:"id"`
+	}
+	if _, err := d.post(ctx, "/users/@me/channels", map[string]string{"recipient_id": channelID}, &dm); err != nil || dm.ID == "" {
+		return fmt.Errorf("discord: unknown channel %s", channelID)
+	}
+	d.dmChannels.Store(channelID, dm.ID)
+	_, err = d.post(ctx, "/channels/"+dm.ID+"/messages", payload, nil)
+	return err
+}
+
+// post sends a JSON request to the REST API, retrying once when rate
+// limited, and decodes the response into out if non-nil.
+func (d *discord) post(ctx context.Context, path string, payload, out interface{}) (int, error) {
+	body, _ := json.Marshal(payload)
+	for attempt := 0; attempt < 2; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.apiBase+path, bytes.NewReader(body))
+		if err != nil {
+			return 0, err
+		}
+		req.Header.Set("Authorization", "Bot "+d.token)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := d.client.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		respBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusTooManyRequests && attempt == 0 {
+			wait := time.Second
+			if s, err := strconv.ParseFloat(resp.Header.Get("Retry-After"), 64); err == nil {
+				wait = time.Duration(s * float64(time.Second))
+			}
+			select {
+			case <-ctx.Done():
+				return 0, ctx.Err()
+			case <-time.After(wait):
+			}
+			continue
+		}
+		if resp.StatusCode/100 != 2 {
+			return resp.StatusCode, fmt.Errorf("discord: POST %s: %s body=%s", path, resp.Status, string(respBody))
+		}
+		if out != nil {
+			if err := json.Unmarshal(respBody, out); err != nil {
+				return resp.StatusCode, fmt.Errorf("discord: POST %s: %w", path, err)
+			}
+		}
+		return resp.StatusCode, nil
+	}
+	return http.StatusTooManyRequests, fmt.Errorf("discord: POST %s: rate limited", path)
+}
@@ -0,0 +1,88 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/websocket"
+)
+
+func TestStartDiscordWithBase(t *testing.T) {
+	sent := make(chan string, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gateway" {
+			c, err := websocket.Accept(w, r)
+			if err != nil {
+				return
+			}
+			defer c.Close()
+			c.WriteJSON(map[string]interface{}{"op": 10, "d": map[string]int{"heartbeat_interval": 60000}})
+			var id struct {
+				Op int `json:"op"`
+				D  struct {
+					Token string `json:"token"`
+				} `json:"d"`
+			}
+			if err := c.ReadJSON(&id); err != nil || id.Op != 2 || id.D.Token != "tok" {
+				t.Errorf("unexpected identify: %+v (%v)", id, err)
+				return
+			}
+			c.WriteJSON(map[string]interface{}{"op": 0, "s": 1, "t": "READY", "d": map[string]interface{}{"user": map[string]string{"id": "900"}}})
+			// a guild message without a mention is ignored, an unauthorized DM is dropped
+			c.WriteJSON(map[string]interface{}{"op": 0, "s": 2, "t": "MESSAGE_CREATE", "d": map[string]interface{}{"channel_id": "7", "guild_id": "1", "content": "chatter", "author": map[string]string{"id": "123"}}})
+			c.WriteJSON(map[string]interface{}{"op": 0, "s": 3, "t": "MESSAGE_CREATE", "d": map[string]interface{}{"channel_id": "8", "content": "spam", "author": map[string]string{"id": "666"}}})
+			c.WriteJSON(map[string]interface{}{"op": 0, "s": 4, "t": "MESSAGE_CREATE", "d": map[string]interface{}{
+				"channel_id": "7", "guild_id": "1", "content": "<@900> hello", "author": map[string]string{"id": "123"},
+				"mentions": []map[string]string{{"id": "900"}}}})
+			for {
+				if _, _, err := c.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}
+		if strings.HasPrefix(r.URL.Path, "/channels/") && r.Header.Get("Authorization") == "Bot tok" {
+			var body struct{ Content string }
+			json.NewDecoder(r.Body).Decode(&body)
+			sent <- strings.TrimPrefix(r.URL.Path, "/channels/") + ":" + body.Content
+			w.Write([]byte(`{}`))
+			return
+		}
+		w.WriteHeader(404)
+	}))
+	defer srv.Close()
+
+	b := chat.NewHub(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gw := "ws" + strings.TrimPrefix(srv.URL, "http") + "/gateway"
+	if err := StartDiscordWithBase(ctx, b, "tok", srv.URL, gw, []string{"123"}); err != nil {
+		t.Fatalf("StartDiscordWithBase: %v", err)
+	}
+
+	select {
+	case msg := <-b.In:
+		if msg.Channel != "discord" || msg.ChatID != "7" || msg.Content != "hello" || msg.Metadata["group"] != true {
+			t.Fatalf("unexpected inbound: %+v", msg)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("timeout waiting for inbound message")
+	}
+
+	b.Out <- chat.Outbound{Channel: "discord", ChatID: "7", Content: strings.Repeat("a", 2500)}
+	for _, want := range []int{2000, 500} {
+		select {
+		case got := <-sent:
+			if !strings.HasPrefix(got, "7/messages:") || len(got)-len("7/messages:") != want {
+				t.Fatalf("unexpected chunk (%d bytes): %.40q", len(got), got)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatal("timeout waiting for message to be posted")
+		}
+	}
+}
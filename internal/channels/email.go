@@ -0,0 +1,433 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/logging"
+	"github.com/kr0nicas/picobot/internal/telemetry"
+)
+
+var emailLog = logging.For("channels").With("channel", "email")
+
+// EmailOptions configures the email channel.
+type EmailOptions struct {
+	IMAPAddr     string   // host:port of the IMAP server (TLS, usually port 993)
+	SMTPAddr     string   // host:port of the SMTP server (465 = TLS, otherwise STARTTLS)
+	Username     string   // login for both servers
+	Password     string   // password or app password
+	From         string   // sender address for replies; defaults to Username
+	Folder       string   // mailbox to watch (default "INBOX")
+	AllowFrom    []string // sender addresses permitted to talk to the bot (empty = nobody)
+	PollInterval time.Duration
+	// AuthServID is the authserv-id of the receiving server's
+	// Authentication-Results header, e.g. "mx.google.com". Required unless
+	// AllowUnauthenticated is set.
+	AuthServID string
+	// AllowUnauthenticated accepts mail whose sender did not pass DMARC or
+	// DKIM, trusting the From header alone.
+	AllowUnauthenticated bool
+}
+
+// StartEmail polls an IMAP mailbox for unseen messages from allowed
+// senders and routes them to the hub; replies are sent via SMTP in the same
+// thread. The ChatID of a conversation is the sender's address.
+func StartEmail(ctx context.Context, hub *chat.Hub, opts EmailOptions) error {
+	if opts.IMAPAddr == "" || opts.SMTPAddr == "" || opts.Username == "" || opts.Password == "" {
+		return fmt.Errorf("email: imapAddr, smtpAddr, username and password are required")
+	}
+	if opts.AuthServID == "" && !opts.AllowUnauthenticated {
+		return fmt.Errorf("email: authServId is required to check who sent a message")
+	}
+	if opts.From == "" {
+		opts.From = opts.Username
+	}
+	if opts.Folder == "" {
+		opts.Folder = "INBOX"
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Minute
+	}
+	allowed := make(map[string]struct{}, len(opts.AllowFrom))
+	for _, a := range opts.AllowFrom {
+		allowed[strings.ToLower(a)] = struct{}{}
+	}
+	e := &email{hub: hub, opts: opts, allowed: allowed, threads: make(map[string]emailThread)}
+
+	// inbound polling goroutine
+	go func() {
+		emailLog.Info("polling mailbox", "imap", opts.IMAPAddr, "folder", opts.Folder, "every", opts.PollInterval, "allow_from", opts.AllowFrom)
+		t := time.NewTicker(opts.PollInterval)
+		defer t.Stop()
+		for {
+			if err := e.poll(ctx); err != nil && ctx.Err() == nil {
+				emailLog.Warn("poll failed", "err", err)
+			}
+			select {
+			case <-ctx.Done():
+				emailLog.Info("stopping inbound polling")
+				return
+			case <-t.C:
+			}
+		}
+	}()
+
+	// outbound sender goroutine
+	outbox := hub.Subscribe("email")
+	go func() {
+		emailLog.Info("starting outbound sender")
+		for {
+			select {
+			case <-ctx.Done():
+				emailLog.Info("stopping outbound sender")
+				return
+			case out, ok := <-outbox:
+				if !ok {
+					return
+				}
+				emailLog.Debug("sending reply", "chat_id", out.ChatID)
+				_, span := telemetry.StartRemote(out.Metadata, "send", "channel", "email", "chat_id", out.ChatID)
+				if err := e.send(out); err != nil {
+					emailLog.Error("sending reply failed", "chat_id", out.ChatID, "err", err)
+					span.RecordError(err)
+				}
+				span.End()
+			}
+		}
+	}()
+	return nil
+}
+
+type email struct {
+	hub     *chat.Hub
+	opts    EmailOptions
+	allowed map[string]struct{}
+
+	mu      sync.Mutex
+	threads map[string]emailThread // last message per sender, for threading replies
+}
+
+// emailThread is what a reply needs to stay in the sender's thread.
+type emailThread struct {
+	subject    string
+	messageID  string
+	references string
+}
+
+// poll connects to the IMAP server and handles unseen messages once.
+func (e *email) poll(ctx context.Context) error {
+	d := &net.Dialer{Timeout: 30 * time.Second}
+	host, _, _ := net.SplitHostPort(e.opts.IMAPAddr)
+	conn, err := tls.DialWithDialer(d, "tcp", e.opts.IMAPAddr, &tls.Config{ServerName: host})
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Minute))
+	c, err := newIMAPConn(conn)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.logout()
+	if err := c.login(e.opts.Username, e.opts.Password); err != nil {
+		return err
+	}
+	return e.receive(ctx, c)
+}
+
+// receive routes the unseen messages of the folder to the hub. Each one is
+// marked seen before it is handled, so dropped messages are not fetched again.
+func (e *email) receive(ctx context.Context, c *imapConn) error {
+	uids, err := c.unseen(e.opts.Folder)
+	if err != nil {
+		return err
+	}
+	for _, uid := range uids {
+		if ctx.Err() != nil {
+			return nil
+		}
+		raw, err := c.fetch(uid)
+		if err != nil {
+			return err
+		}
+		if err := c.markSeen(uid); err != nil {
+			return err
+		}
+		e.handle(raw)
+	}
+	return nil
+}
+
+// handle parses one message and routes it to the hub if the sender is allowed.
+func (e *email) handle(raw []byte) {
+	m, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		emailLog.Warn("unreadable message", "err", err)
+		return
+	}
+	from, err := mail.ParseAddress(m.Header.Get("From"))
+	if err != nil {
+		emailLog.Warn("bad From header", "from", m.Header.Get("From"))
+		return
+	}
+	sender := strings.ToLower(from.Address)
+	if _, ok := e.allowed[sender]; !ok {
+		emailLog.Warn("dropping message from unauthorized sender", "sender", sender)
+		return
+	}
+	_, domain, _ := strings.Cut(sender, "@")
+	if !e.opts.AllowUnauthenticated && !senderAuthenticated(m.Header, domain, e.opts.AuthServID) {
+		emailLog.Warn("dropping message that failed sender authentication", "sender", sender)
+		return
+	}
+	dec := new(mime.WordDecoder)
+	subject, err := dec.DecodeHeader(m.Header.Get("Subject"))
+	if err != nil {
+		subject = m.Header.Get("Subject")
+	}
+	body, err := textBody(m.Header.Get("Content-Type"), m.Header.Get("Content-Transfer-Encoding"), m.Body)
+	if err != nil {
+		emailLog.Warn("reading body failed", "sender", sender, "err", err)
+		return
+	}
+
+	msgID := m.Header.Get("Message-Id")
+	refs := strings.TrimSpace(m.Header.Get("References") + " " + msgID)
+	e.mu.Lock()
+	e.threads[sender] = emailThread{subject: subject, messageID: msgID, references: refs}
+	e.mu.Unlock()
+
+	emailLog.Info("received message", "sender", sender)
+	e.hub.In <- chat.Inbound{
+		Channel:   "email",
+		SenderID:  sender,
+		ChatID:    sender,
+		Content:   "Subject: " + subject + "\n\n" + stripQuotedReply(body),
+		Timestamp: time.Now(),
+	}
+}
+
+var authComment = regexp.MustCompile(`\([^()]*\)`)
+
+// senderAuthenticated reports whether the topmost Authentication-Results
+// header from authServ, the receiving server, shows that the message passed
+// DMARC for domain, or DKIM signed by domain or a parent of it. SPF is not
+// enough: it checks the envelope sender, not the From header. The From
+// header alone can be forged by anyone.
+func senderAuthenticated(h mail.Header, domain, authServ string) bool {
+	if authServ == "" {
+		return false
+	}
+	for _, v := range h["Authentication-Results"] {
+		parts := strings.Split(authComment.ReplaceAllString(v, ""), ";")
+		id := strings.Fields(parts[0])
+		if len(id) == 0 || !strings.EqualFold(id[0], authServ) {
+			continue
+		}
+		for _, res := range parts[1:] {
+			f := strings.Fields(strings.ToLower(res))
+			if len(f) == 0 {
+				continue
+			}
+			method, result, _ := strings.Cut(f[0], "=")
+			if result != "pass" {
+				continue
+			}
+			props := make(map[string]string)
+			for _, p := range f[1:] {
+				k, v, _ := strings.Cut(p, "=")
+				props[k] = strings.Trim(v, `"`)
+			}
+			switch method {
+			case "dmarc":
+				if props["header.from"] == domain {
+					return true
+				}
+			case "dkim":
+				d := props["header.d"]
+				if d == "" {
+					_, d, _ = strings.Cut(props["header.i"], "@")
+				}
+				if d != "" && (d == domain || strings.HasSuffix(domain, "."+d)) {
+					return true
+				}
+			}
+		}
+		return false // only the receiving server's results count
+	}
+	return false
+}
+
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// textBody extracts the text of a message body, preferring text/plain
+// parts of multipart messages. HTML-only messages are reduced to text.
+func textBody(contentType, encoding string, r io.Reader) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "text/plain"
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(r, params["boundary"])
+		var fallback string
+		for {
+			p, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", err
+			}
+			text, err := textBody(p.Header.Get("Content-Type"), p.Header.Get("Content-Transfer-Encoding"), p)
+			if err != nil || text == "" {
+				continue
+			}
+			pt, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+			if pt == "text/plain" || pt == "" || strings.HasPrefix(pt, "multipart/") {
+				return text, nil
+			}
+			if fallback == "" {
+				fallback = text
+			}
+		}
+		return fallback, nil
+	}
+	if !strings.HasPrefix(mediaType, "text/") {
+		return "", nil // attachments are ignored
+	}
+	switch strings.ToLower(encoding) {
+	case "quoted-printable":
+		r = quotedprintable.NewReader(r)
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, r)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	text := string(data)
+	if mediaType == "text/html" {
+		text = htmlTag.ReplaceAllString(text, "")
+		text = strings.NewReplacer("&nbsp;", " ", "&lt;", "<", "&gt;", ">", "&quot;", `"`, "&amp;", "&").Replace(text)
+	}
+	return strings.TrimSpace(text), nil
+}
+
+// stripQuotedReply drops the quoted previous message that mail clients
+// append to replies ("On ... wrote:" followed by "> " lines).
+func stripQuotedReply(body string) string {
+	lines := strings.Split(body, "\n")
+	end := len(lines)
+	for end > 0 {
+		l := strings.TrimSpace(lines[end-1])
+		if l == "" || strings.HasPrefix(l, ">") {
+			end--
+			continue
+		}
+		if strings.HasPrefix(l, "On ") && strings.HasSuffix(l, "wrote:") {
+			end--
+		}
+		break
+	}
+	if end == 0 {
+		return strings.TrimSpace(body)
+	}
+	return strings.TrimSpace(strings.Join(lines[:end], "\n"))
+}
+
+// send delivers out as a reply in the sender's thread.
+func (e *email) send(out chat.Outbound) error {
+	msg := e.compose(out)
+	host, port, _ := net.SplitHostPort(e.opts.SMTPAddr)
+	auth := smtp.PlainAuth("", e.opts.Username, e.opts.Password, host)
+	if port != "465" {
+		// SendMail upgrades with STARTTLS when the server offers it
+		return smtp.SendMail(e.opts.SMTPAddr, auth, e.opts.From, []string{out.ChatID}, msg)
+	}
+	conn, err := tls.Dial("tcp", e.opts.SMTPAddr, &tls.Config{ServerName: host})
+	if err != nil {
+		return err
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if err := c.Auth(auth); err != nil {
+		return err
+	}
+	if err := c.Mail(e.opts.From); err != nil {
+		return err
+	}
+	if err := c.Rcpt(out.ChatID); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// compose renders out as an RFC 5322 message. Replies reference the last
+// message received from the recipient so clients show them in its thread.
+func (e *email) compose(out chat.Outbound) []byte {
+	e.mu.Lock()
+	th := e.threads[out.ChatID]
+	e.mu.Unlock()
+	subject := "Message from picobot"
+	if th.subject != "" {
+		subject = th.subject
+		if !strings.HasPrefix(strings.ToLower(subject), "re:") {
+			subject = "Re: " + subject
+		}
+	}
+	domain := "picobot.local"
+	if _, d, ok := strings.Cut(e.opts.From, "@"); ok {
+		domain = d
+	}
+	id := make([]byte, 12)
+	rand.Read(id)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", e.opts.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", out.ChatID)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", hex.EncodeToString(id), domain)
+	if th.messageID != "" {
+		fmt.Fprintf(&buf, "In-Reply-To: %s\r\n", th.messageID)
+		fmt.Fprintf(&buf, "References: %s\r\n", th.references)
+	}
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	qp := quotedprintable.NewWriter(&buf)
+	qp.Write([]byte(strings.ReplaceAll(out.Content, "\n", "\r\n")))
+	qp.Close()
+	return buf.Bytes()
+}
@@ -0,0 +1,129 @@
+package channels
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+)
+
+// fakeIMAP answers the commands of one receive pass over conn.
+func fakeIMAP(t *testing.T, conn net.Conn, messages map[string]string, seen chan<- string) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	fmt.Fprint(conn, "* OK IMAP ready\r\n")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		tag, cmd, _ := strings.Cut(strings.TrimSpace(line), " ")
+		switch {
+		case strings.HasPrefix(cmd, "SELECT"):
+			fmt.Fprint(conn, "* 3 EXISTS\r\n")
+		case cmd == "UID SEARCH UNSEEN":
+			fmt.Fprint(conn, "* SEARCH 7 8 9\r\n")
+		case strings.HasPrefix(cmd, "UID FETCH"):
+			uid := strings.Fields(cmd)[2]
+			raw := messages[uid]
+			fmt.Fprintf(conn, "* 1 FETCH (UID %s BODY[] {%d}\r\n%s)\r\n", uid, len(raw), raw)
+		case strings.HasPrefix(cmd, "UID STORE"):
+			seen <- strings.Fields(cmd)[2]
+		case cmd == "LOGOUT":
+			fmt.Fprintf(conn, "* BYE\r\n%s OK\r\n", tag)
+			return
+		}
+		fmt.Fprintf(conn, "%s OK done\r\n", tag)
+	}
+}
+
+func TestEmailReceive(t *testing.T) {
+	messages := map[string]string{
+		"7": "Authentication-Results: mx.example.net; dkim=pass header.d=example.com; dmarc=pass (p=REJECT) header.from=example.com\r\n" +
+			"From: Alice <Alice@example.com>\r\nSubject: =?utf-8?q?Weekly_r=C3=A9port?=\r\nMessage-ID: <m1@example.com>\r\n" +
+			"Content-Type: multipart/alternative; boundary=b\r\n\r\n" +
+			"--b\r\nContent-Type: text/html\r\n\r\n<p>html</p>\r\n" +
+			"--b\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\nPlease summarize=\r\n it.\r\n\r\nOn Mon, Bob wrote:\r\n> old text\r\n" +
+			"--b--\r\n",
+		"8": "From: mallory@example.com\r\nSubject: hi\r\n\r\nlet me in\r\n",
+		"9": "Authentication-Results: mx.example.net; spf=fail smtp.mailfrom=evil.test; dmarc=fail header.from=example.com\r\n" +
+			"From: alice@example.com\r\nSubject: hi\r\n\r\nforged\r\n",
+	}
+	client, server := net.Pipe()
+	seen := make(chan string, 3)
+	go fakeIMAP(t, server, messages, seen)
+
+	hub := chat.NewHub(10)
+	e := &email{hub: hub, opts: EmailOptions{Folder: "INBOX", AuthServID: "mx.example.net"}, allowed: map[string]struct{}{"alice@example.com": {}}, threads: make(map[string]emailThread)}
+	c, err := newIMAPConn(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.receive(context.Background(), c); err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+	c.logout()
+
+	if got := []string{<-seen, <-seen, <-seen}; got[0] != "7" || got[1] != "8" || got[2] != "9" {
+		t.Fatalf("expected all messages marked seen, got %v", got)
+	}
+	msg := <-hub.In
+	if msg.Channel != "email" || msg.ChatID != "alice@example.com" {
+		t.Fatalf("unexpected inbound: %+v", msg)
+	}
+	if want := "Subject: Weekly réport\n\nPlease summarize it."; msg.Content != want {
+		t.Fatalf("content = %q, want %q", msg.Content, want)
+	}
+	select {
+	case m := <-hub.In:
+		t.Fatalf("message from unauthorized or forged sender was routed: %+v", m)
+	default:
+	}
+
+	reply := string(e.compose(chat.Outbound{ChatID: "alice@example.com", Content: "Done."}))
+	for _, want := range []string{"To: alice@example.com\r\n", "Subject: =?utf-8?q?Re:_Weekly_r=C3=A9port?=\r\n",
+		"In-Reply-To: <m1@example.com>\r\n", "References: <m1@example.com>\r\n", "\r\n\r\nDone."} {
+		if !strings.Contains(reply, want) {
+			t.Errorf("reply missing %q:\n%s", want, reply)
+		}
+	}
+}
+
+func TestSenderAuthenticated(t *testing.T) {
+	cases := []struct {
+		headers  []string
+		authServ string
+		want     bool
+	}{
+		{[]string{"mx.google.com; dmarc=pass (p=NONE) header.from=example.com"}, "mx.google.com", true},
+		{[]string{"mx.google.com; dmarc=pass (p=NONE) header.from=example.com"}, "", false},
+		{[]string{"mx.google.com; dmarc=pass header.from=sub.example.com"}, "mx.google.com", false},
+		{[]string{"mx.google.com; dkim=pass header.i=@mail.example.com header.s=s1"}, "mx.google.com", false},
+		{[]string{"mx.google.com; dkim=pass header.d=example.com header.s=s1"}, "mx.google.com", true},
+		{[]string{"mx.google.com; dkim=pass header.d=evil.test header.s=s1"}, "mx.google.com", false},
+		{[]string{"mx.google.com; spf=pass (google.com: domain of a@example.com designates 1.2.3.4) smtp.mailfrom=a@example.com"}, "mx.google.com", false},
+		{[]string{"mx.google.com; spf=pass smtp.mailfrom=evil.test; dkim=fail header.d=example.com"}, "mx.google.com", false},
+		{[]string{"forged.test; dmarc=pass header.from=example.com", "mx.google.com; spf=none"}, "mx.google.com", false},
+		{[]string{"mx.google.com; spf=none", "mx.google.com; dmarc=pass header.from=example.com"}, "mx.google.com", false},
+		{[]string{"other.test; spf=none", "mx.google.com; dmarc=pass header.from=example.com"}, "mx.google.com", true},
+		{nil, "mx.google.com", false},
+	}
+	for _, c := range cases {
+		h := mail.Header{"Authentication-Results": c.headers}
+		if got := senderAuthenticated(h, "example.com", c.authServ); got != c.want {
+			t.Errorf("senderAuthenticated(%q, %q) = %v, want %v", c.headers, c.authServ, got, c.want)
+		}
+	}
+}
+
+func TestStartEmailRequiresAuthServID(t *testing.T) {
+	opts := EmailOptions{IMAPAddr: "imap.example.com:993", SMTPAddr: "smtp.example.com:587", Username: "bot@example.com", Password: "pw"}
+	if err := StartEmail(context.Background(), chat.NewHub(1), opts); err == nil || !strings.Contains(err.Error(), "authServId") {
+		t.Fatalf("expected StartEmail to refuse to start without authServId, got %v", err)
+	}
+}
@@ -0,0 +1,244 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/logging"
+	"github.com/kr0nicas/picobot/internal/netguard"
+	"github.com/kr0nicas/picobot/internal/telemetry"
+)
+
+var httpLog = logging.For("channels").With("channel", "http")
+
+// HTTPOptions configures the HTTP channel.
+type HTTPOptions struct {
+	Listen  string        // address of the HTTP server (default ":8088")
+	Token   string        // required bearer token
+	Timeout time.Duration // how long a synchronous request waits for the reply (default 2m)
+}
+
+// httpRequest is the body of POST /v1/messages.
+type httpRequest struct {
+	ChatID      string `json:"chat_id"`
+	SenderID    string `json:"sender_id"`
+	Content     string `json:"content"`
+	CallbackURL string `json:"callback_url,omitempty"`
+}
+
+// httpReply is returned synchronously or posted to the callback URL.
+type httpReply struct {
+	ChatID  string `json:"chat_id"`
+	Content string `json:"content"`
+}
+
+// StartHTTP serves POST /v1/messages so scripts and automation tools can
+// talk to the agent without a chat platform. Requests carry
+// {chat_id, sender_id, content} and a bearer token. Without callback_url the
+// reply is returned in the response; with it the request is accepted (202)
+// and every message the agent sends to that chat is POSTed to the URL.
+func StartHTTP(ctx context.Context, hub *chat.Hub, opts HTTPOptions) error {
+	if opts.Token == "" {
+		return errors.New("http channel: token is required")
+	}
+	if opts.Listen == "" {
+		opts.Listen = ":8088"
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 2 * time.Minute
+	}
+	h := newHTTPChannel(hub, opts)
+
+	mux := http.NewServeMux()
+	mux.Handle("/v1/messages", h)
+	srv := &http.Server{Addr: opts.Listen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		httpLog.Info("listening", "listen", opts.Listen)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			httpLog.Error("server failed", "err", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		httpLog.Info("stopping server")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	// outbound router: replies go to a waiting request or a callback URL
+	outbox := hub.Subscribe("http")
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case out, ok := <-outbox:
+				if !ok {
+					return
+				}
+				_, span := telemetry.StartRemote(out.Metadata, "send", "channel", "http", "chat_id", out.ChatID)
+				h.deliver(ctx, out)
+				span.End()
+			}
+		}
+	}()
+	return nil
+}
+
+type httpChannel struct {
+	hub    *chat.Hub
+	opts   HTTPOptions
+	client *http.Client
+	lookup netguard.Lookup
+
+	mu        sync.Mutex
+	waiters   map[string]chan chat.Outbound // chat ID -> synchronous request waiting for its reply
+	callbacks map[string]string             // chat ID -> callback URL of the latest async request
+}
+
+func newHTTPChannel(hub *chat.Hub, opts HTTPOptions) *httpChannel {
+	// callbacks go only to public addresses, so a client can't make the
+	// gateway reach into its own network
+	client := &http.Client{Timeout: 15 * time.Second, Transport: netguard.Transport}
+	return &httpChannel{hub: hub, opts: opts, client: client, lookup: netguard.DefaultLookup,
+		waiters: make(map[string]chan chat.Outbound), callbacks: make(map[string]string)}
+}
+
+func (h *httpChannel) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.opts.Token)) != 1 {
+		httpLog.Warn("rejecting request: bad token", "remote", r.RemoteAddr)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req httpRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if req.ChatID == "" {
+		req.ChatID = req.SenderID
+	}
+	if req.ChatID == "" || strings.TrimSpace(req.Content) == "" {
+		http.Error(w, "chat_id or sender_id, and content are required", http.StatusBadRequest)
+		return
+	}
+	if req.SenderID == "" {
+		req.SenderID = req.ChatID
+	}
+	in := chat.Inbound{Channel: "http", SenderID: req.SenderID, ChatID: req.ChatID, Content: req.Content, Timestamp: time.Now()}
+
+	if req.CallbackURL != "" {
+		u, err := url.Parse(req.CallbackURL)
+		if err == nil {
+			err = netguard.CheckURL(r.Context(), u, h.lookup)
+		}
+		if err != nil {
+			http.Error(w, "callback_url must be a public http(s) URL", http.StatusBadRequest)
+			return
+		}
+		h.mu.Lock()
+		h.callbacks[req.ChatID] = req.CallbackURL
+		h.mu.Unlock()
+		if !h.submit(w, in) {
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	reply := make(chan chat.Outbound, 1)
+	h.mu.Lock()
+	if _, busy := h.waiters[req.ChatID]; busy {
+		h.mu.Unlock()
+		http.Error(w, "a request for this chat is already waiting for a reply", http.StatusConflict)
+		return
+	}
+	h.waiters[req.ChatID] = reply
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		if h.waiters[req.ChatID] == reply {
+			delete(h.waiters, req.ChatID)
+		}
+		h.mu.Unlock()
+	}()
+
+	if !h.submit(w, in) {
+		return
+	}
+	timer := time.NewTimer(h.opts.Timeout)
+	defer timer.Stop()
+	select {
+	case out := <-reply:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(httpReply{ChatID: out.ChatID, Content: out.Content})
+	case <-timer.C:
+		http.Error(w, "timed out waiting for the agent's reply", http.StatusGatewayTimeout)
+	case <-r.Context().Done():
+	}
+}
+
+// submit queues in for the agent. If the hub is full, it answers 503 right
+// away and reports false.
+func (h *httpChannel) submit(w http.ResponseWriter, in chat.Inbound) bool {
+	select {
+	case h.hub.In <- in:
+		return true
+	default:
+		httpLog.Warn("dropping request: the agent is busy", "chat_id", in.ChatID)
+		http.Error(w, "the agent is busy, try again later", http.StatusServiceUnavailable)
+		return false
+	}
+}
+
+// deliver hands an outbound message to the synchronous request waiting for
+// it, or posts it to the chat's callback URL.
+func (h *httpChannel) deliver(ctx context.Context, out chat.Outbound) {
+	h.mu.Lock()
+	reply, waiting := h.waiters[out.ChatID]
+	if waiting {
+		delete(h.waiters, out.ChatID) // later messages go to the callback, if any
+	}
+	callback := h.callbacks[out.ChatID]
+	h.mu.Unlock()
+	if waiting {
+		reply <- out
+		return
+	}
+	if callback == "" {
+		httpLog.Warn("no pending request or callback; dropping message", "chat_id", out.ChatID)
+		return
+	}
+	body, _ := json.Marshal(httpReply{ChatID: out.ChatID, Content: out.Content})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callback, bytes.NewReader(body))
+	if err != nil {
+		httpLog.Error("callback failed", "chat_id", out.ChatID, "err", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := h.client.Do(req)
+	if err != nil {
+		httpLog.Error("callback failed", "chat_id", out.ChatID, "err", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		httpLog.Error("callback failed", "chat_id", out.ChatID, "status", resp.Status)
+	}
+}
@@ -0,0 +1,116 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+)
+
+// toServer sends every request to srv, whatever host it names.
+type toServer struct{ srv *httptest.Server }
+
+func (s toServer) RoundTrip(r *http.Request) (*http.Response, error) {
+	u, _ := url.Parse(s.srv.URL)
+	out := r.Clone(r.Context())
+	out.URL.Scheme, out.URL.Host = u.Scheme, u.Host
+	return http.DefaultTransport.RoundTrip(out)
+}
+
+func TestHTTPChannel(t *testing.T) {
+	hub := chat.NewHub(10)
+	h := newHTTPChannel(hub, HTTPOptions{Token: "tok", Timeout: 2 * time.Second})
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	// echo agent: answers every inbound message
+	go func() {
+		for in := range hub.In {
+			h.deliver(context.Background(), chat.Outbound{Channel: "http", ChatID: in.ChatID, Content: "echo: " + in.Content})
+		}
+	}()
+	defer close(hub.In)
+
+	post := func(token, body string) (*http.Response, string) {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("post: %v", err)
+		}
+		data, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp, string(data)
+	}
+
+	if resp, _ := post("wrong", `{"sender_id":"ha","content":"hi"}`); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for bad token, got %d", resp.StatusCode)
+	}
+	if resp, _ := post("tok", `{"chat_id":"c1"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 without content, got %d", resp.StatusCode)
+	}
+
+	resp, body := post("tok", `{"sender_id":"ha","content":"lights on"}`)
+	var reply httpReply
+	if resp.StatusCode != http.StatusOK || json.Unmarshal([]byte(body), &reply) != nil {
+		t.Fatalf("unexpected sync response %d %s", resp.StatusCode, body)
+	}
+	if reply.ChatID != "ha" || reply.Content != "echo: lights on" {
+		t.Fatalf("unexpected reply %+v", reply)
+	}
+
+	got := make(chan httpReply, 1)
+	cb := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rep httpReply
+		json.NewDecoder(r.Body).Decode(&rep)
+		got <- rep
+	}))
+	defer cb.Close()
+	if resp, _ := post("tok", `{"chat_id":"job","content":"report","callback_url":"`+cb.URL+`"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a callback to a local address, got %d", resp.StatusCode)
+	}
+	h.lookup = func(ctx context.Context, host string) ([]net.IP, error) {
+		return []net.IP{net.ParseIP("93.184.216.34")}, nil
+	}
+	h.client.Transport = toServer{cb}
+	if resp, _ := post("tok", `{"chat_id":"job","content":"report","callback_url":"https://hooks.example/picobot"}`); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202 for callback request, got %d", resp.StatusCode)
+	}
+	select {
+	case rep := <-got:
+		if rep.ChatID != "job" || rep.Content != "echo: report" {
+			t.Fatalf("unexpected callback %+v", rep)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for callback")
+	}
+}
+
+func TestHTTPChannelFullHub(t *testing.T) {
+	hub := chat.NewHub(0) // nobody reads it
+	h := newHTTPChannel(hub, HTTPOptions{Token: "tok", Timeout: time.Second})
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"sender_id":"ha","content":"hi"}`))
+	req.Header.Set("Authorization", "Bearer tok")
+	rec := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		h.ServeHTTP(rec, req)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the request blocked on the full hub")
+	}
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 when the hub is full, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,140 @@
+package channels
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// imapConn is a minimal IMAP4rev1 client: enough to log in, find unseen
+// messages, fetch them and mark them seen.
+type imapConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int
+}
+
+// imapLine is one untagged response line; Literal holds the data of a
+// trailing {n} literal, e.g. a fetched message.
+type imapLine struct {
+	Text    string
+	Literal []byte
+}
+
+var imapLiteral = regexp.MustCompile(`\{(\d+)\}$`)
+
+// newIMAPConn wraps an established connection and reads the greeting.
+func newIMAPConn(conn net.Conn) (*imapConn, error) {
+	c := &imapConn{conn: conn, r: bufio.NewReader(conn)}
+	line, err := c.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "* OK") && !strings.HasPrefix(line, "* PREAUTH") {
+		return nil, fmt.Errorf("imap: unexpected greeting %q", line)
+	}
+	return c, nil
+}
+
+func (c *imapConn) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// cmd sends one command and returns its untagged responses, or an error
+// when the server does not answer OK.
+func (c *imapConn) cmd(command string) ([]imapLine, error) {
+	c.tag++
+	tag := "a" + strconv.Itoa(c.tag)
+	if _, err := fmt.Fprintf(c.conn, "%s %s\r\n", tag, command); err != nil {
+		return nil, err
+	}
+	var lines []imapLine
+	for {
+		text, err := c.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if rest, ok := strings.CutPrefix(text, tag+" "); ok {
+			if !strings.HasPrefix(rest, "OK") {
+				verb, _, _ := strings.Cut(command, " ")
+				return nil, fmt.Errorf("imap: %s: %s", verb, rest)
+			}
+			return lines, nil
+		}
+		line := imapLine{Text: text}
+		if m := imapLiteral.FindStringSubmatch(text); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			line.Literal = make([]byte, n)
+			if _, err := io.ReadFull(c.r, line.Literal); err != nil {
+				return nil, err
+			}
+			// the rest of the response follows the literal, e.g. ")"
+			if _, err := c.readLine(); err != nil {
+				return nil, err
+			}
+		}
+		lines = append(lines, line)
+	}
+}
+
+// login authenticates with LOGIN.
+func (c *imapConn) login(user, pass string) error {
+	_, err := c.cmd("LOGIN " + imapQuote(user) + " " + imapQuote(pass))
+	return err
+}
+
+// unseen selects folder and returns the UIDs of unseen messages.
+func (c *imapConn) unseen(folder string) ([]string, error) {
+	if _, err := c.cmd("SELECT " + imapQuote(folder)); err != nil {
+		return nil, err
+	}
+	lines, err := c.cmd("UID SEARCH UNSEEN")
+	if err != nil {
+		return nil, err
+	}
+	var uids []string
+	for _, l := range lines {
+		if rest, ok := strings.CutPrefix(l.Text, "* SEARCH"); ok {
+			uids = append(uids, strings.Fields(rest)...)
+		}
+	}
+	return uids, nil
+}
+
+// fetch returns the raw message with the given UID without marking it seen.
+func (c *imapConn) fetch(uid string) ([]byte, error) {
+	lines, err := c.cmd("UID FETCH " + uid + " (BODY.PEEK[])")
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range lines {
+		if l.Literal != nil {
+			return l.Literal, nil
+		}
+	}
+	return nil, fmt.Errorf("imap: message %s not found", uid)
+}
+
+// markSeen sets the \Seen flag on a message.
+func (c *imapConn) markSeen(uid string) error {
+	_, err := c.cmd("UID STORE " + uid + ` +FLAGS.SILENT (\Seen)`)
+	return err
+}
+
+func (c *imapConn) logout() {
+	c.cmd("LOGOUT")
+	c.conn.Close()
+}
+
+// imapQuote encodes s as an IMAP quoted string.
+func imapQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
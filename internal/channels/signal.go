@@ -0,0 +1,250 @@
+package channels
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/logging"
+	"github.com/kr0nicas/picobot/internal/telemetry"
+)
+
+var signalLog = logging.For("channels").With("channel", "signal")
+
+// SignalOptions configures the Signal adapter.
+type SignalOptions struct {
+	// Addr is the signal-cli JSON-RPC endpoint: a UNIX socket path
+	// (signal-cli daemon --socket) or host:port (signal-cli daemon --tcp).
+	Addr string
+	// Account is the bot's phone number, needed when the daemon serves
+	// several accounts.
+	Account string
+	// AllowFrom lists the phone numbers (E.164, e.g. +15551234567) permitted
+	// to talk to the bot; an empty list drops everything.
+	AllowFrom []string
+}
+
+// StartSignal connects to a signal-cli daemon over JSON-RPC. Direct messages
+// use the sender's number as ChatID; group messages use "group:<id>" and
+// replies go to the group.
+func StartSignal(ctx context.Context, hub *chat.Hub, opts SignalOptions) error {
+	if opts.Addr == "" {
+		return fmt.Errorf("signal: signal-cli address is required")
+	}
+	s := &signal{hub: hub, opts: opts, allowed: toSet(opts.AllowFrom)}
+
+	// inbound goroutine: reconnects with a backoff until ctx is done
+	go func() {
+		signalLog.Info("connecting to signal-cli", "addr", opts.Addr, "allow_from", opts.AllowFrom)
+		backoff := time.Second
+		for {
+			start := time.Now()
+			err := s.run(ctx)
+			if ctx.Err() != nil {
+				signalLog.Info("stopping")
+				return
+			}
+			if time.Since(start) > time.Minute {
+				backoff = time.Second
+			}
+			signalLog.Warn("disconnected, reconnecting", "err", err, "backoff", backoff)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			if backoff < time.Minute {
+				backoff *= 2
+			}
+		}
+	}()
+
+	// outbound sender goroutine
+	outbox := hub.Subscribe("signal")
+	go func() {
+		signalLog.Info("starting outbound sender")
+		for {
+			select {
+			case <-ctx.Done():
+				signalLog.Info("stopping outbound sender")
+				return
+			case out, ok := <-outbox:
+				if !ok {
+					return
+				}
+				signalLog.Debug("sending message", "chat_id", out.ChatID)
+				_, span := telemetry.StartRemote(out.Metadata, "send", "channel", "signal", "chat_id", out.ChatID)
+				if err := s.send(out); err != nil {
+					signalLog.Error("sending message failed", "chat_id", out.ChatID, "err", err)
+					span.RecordError(err)
+				}
+				span.End()
+			}
+		}
+	}()
+	return nil
+}
+
+type signal struct {
+	hub     *chat.Hub
+	opts    SignalOptions
+	allowed map[string]struct{}
+	nextID  atomic.Int64
+
+	mu   sync.Mutex
+	conn net.Conn // current daemon connection, nil while disconnected
+}
+
+// signalEnvelope is the subset of a received message we use.
+type signalEnvelope struct {
+	Source       string `json:"source"`
+	SourceNumber string `json:"sourceNumber"`
+	DataMessage  *struct {
+		Message   string `json:"message"`
+		GroupInfo *struct {
+			GroupID string `json:"groupId"`
+		} `json:"groupInfo"`
+	} `json:"dataMessage"`
+}
+
+// run opens one connection to the daemon and handles notifications until it
+// fails.
+func (s *signal) run(ctx context.Context) error {
+	network := "tcp"
+	if strings.HasPrefix(s.opts.Addr, "/") {
+		network = "unix"
+	}
+	d := net.Dialer{Timeout: 10 * time.Second}
+	conn, err := d.DialContext(ctx, network, s.opts.Addr)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.conn = conn
+	s.mu.Unlock()
+	done := make(chan struct{})
+	defer func() {
+		close(done)
+		s.mu.Lock()
+		s.conn = nil
+		s.mu.Unlock()
+		conn.Close()
+	}()
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	sc := bufio.NewScanner(conn)
+	sc.Buffer(make([]byte, 64*1024), 4<<20)
+	for sc.Scan() {
+		var msg struct {
+			Method string `json:"method"`
+			Params struct {
+				Envelope signalEnvelope `json:"envelope"`
+			} `json:"params"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(sc.Bytes(), &msg); err != nil {
+			continue
+		}
+		switch {
+		case msg.Error != nil:
+			signalLog.Error("signal-cli error", "err", msg.Error.Message)
+		case msg.Method == "receive":
+			s.handle(msg.Params.Envelope)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("signal: connection closed")
+}
+
+// handle routes a text message from an allowed sender to the hub.
+func (s *signal) handle(env signalEnvelope) {
+	if env.DataMessage == nil || env.DataMessage.Message == "" {
+		return // receipts, typing indicators, reactions, ...
+	}
+	sender := env.SourceNumber
+	if sender == "" {
+		sender = env.Source
+	}
+	if _, ok := s.allowed[sender]; !ok {
+		signalLog.Warn("dropping message from unauthorized sender", "sender", sender)
+		return
+	}
+	chatID, group := sender, false
+	if g := env.DataMessage.GroupInfo; g != nil && g.GroupID != "" {
+		chatID, group = "group:"+g.GroupID, true
+	}
+	signalLog.Info("received message", "sender", sender, "chat_id", chatID)
+	s.hub.In <- chat.Inbound{
+		Channel:   "signal",
+		SenderID:  sender,
+		ChatID:    chatID,
+		Content:   env.DataMessage.Message,
+		Timestamp: time.Now(),
+		Metadata:  map[string]interface{}{"group": group},
+	}
+}
+
+// send issues a JSON-RPC "send" request for out. The result arrives on the
+// read loop; failures reported there are logged.
+func (s *signal) send(out chat.Outbound) error {
+	params := map[string]interface{}{"message": out.Content}
+	if s.opts.Account != "" {
+		params["account"] = s.opts.Account
+	}
+	if id, ok := strings.CutPrefix(out.ChatID, "group:"); ok {
+		params["groupId"] = id
+	} else {
+		params["recipient"] = []string{out.ChatID}
+	}
+	var files []string
+	for _, a := range out.Attachments {
+		files = append(files, signalAttachment(a))
+	}
+	if len(files) > 0 {
+		params["attachments"] = files
+	}
+	req, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "method": "send", "params": params, "id": s.nextID.Add(1)})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return fmt.Errorf("signal: not connected to signal-cli")
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	_, err := s.conn.Write(append(req, '\n'))
+	return err
+}
+
+// signalAttachment returns a file path, or a data URI for in-memory files,
+// as accepted by signal-cli.
+func signalAttachment(a chat.Attachment) string {
+	if a.Path != "" {
+		return a.Path
+	}
+	typ := mime.TypeByExtension(filepath.Ext(a.FileName()))
+	if typ == "" {
+		typ = "application/octet-stream"
+	}
+	typ, _, _ = strings.Cut(typ, ";")
+	return "data:" + typ + ";filename=" + a.FileName() + ";base64," + base64.StdEncoding.EncodeToString(a.Data)
+}
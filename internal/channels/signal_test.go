@@ -0,0 +1,70 @@
+package channels
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+)
+
+func TestSignalJSONRPC(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	hub := chat.NewHub(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := StartSignal(ctx, hub, SignalOptions{Addr: ln.Addr().String(), Account: "+100", AllowFrom: []string{"+111"}}); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	notify := func(source, text, group string) {
+		gi := ""
+		if group != "" {
+			gi = fmt.Sprintf(`,"groupInfo":{"groupId":%q}`, group)
+		}
+		fmt.Fprintf(conn, `{"jsonrpc":"2.0","method":"receive","params":{"envelope":{"sourceNumber":%q,"dataMessage":{"message":%q%s}}}}`+"\n", source, text, gi)
+	}
+	notify("+999", "let me in", "")
+	notify("+111", "hello", "")
+	notify("+111", "hi all", "G1==")
+
+	for _, want := range []chat.Inbound{{SenderID: "+111", ChatID: "+111", Content: "hello"}, {SenderID: "+111", ChatID: "group:G1==", Content: "hi all"}} {
+		select {
+		case in := <-hub.In:
+			if in.Channel != "signal" || in.SenderID != want.SenderID || in.ChatID != want.ChatID || in.Content != want.Content {
+				t.Fatalf("unexpected inbound %+v, want %+v", in, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout waiting for inbound")
+		}
+	}
+
+	hub.Out <- chat.Outbound{Channel: "signal", ChatID: "group:G1==", Content: "hey"}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		t.Fatalf("reading send request: %v", err)
+	}
+	var req struct {
+		Method string                 `json:"method"`
+		Params map[string]interface{} `json:"params"`
+	}
+	json.Unmarshal(line, &req)
+	if req.Method != "send" || req.Params["groupId"] != "G1==" || req.Params["message"] != "hey" || req.Params["account"] != "+100" {
+		t.Fatalf("unexpected send request: %s", line)
+	}
+}
@@ -0,0 +1,269 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/logging"
+	"github.com/kr0nicas/picobot/internal/telemetry"
+	"github.com/kr0nicas/picobot/internal/websocket"
+)
+
+var slackLog = logging.For("channels").With("channel", "slack")
+
+const (
+	slackAPIBase = "https://slack.com/api"
+	slackMaxLen  = 4000
+)
+
+// slackMention matches user mentions such as "<@U012ABC>".
+var slackMention = regexp.MustCompile(`<@[A-Z0-9]+>`)
+
+// SlackOptions configures the Slack adapter.
+type SlackOptions struct {
+	BotToken   string   // xoxb-... used for chat.postMessage
+	AppToken   string   // xapp-... with connections:write, used for Socket Mode
+	AllowFrom  []string // Slack user IDs permitted to talk to the bot (empty = nobody)
+	AllowTeams []string // workspace (team) IDs accepted; empty = any
+	APIBase    string   // default https://slack.com/api (overridden in tests)
+}
+
+// StartSlack connects to Slack via Socket Mode. Direct messages and
+// mentions are routed to the hub; replies to mentions are posted in the
+// thread of the mentioning message. Threads are separate chats: their ChatID
+// is "<channel>:<thread ts>".
+func StartSlack(ctx context.Context, hub *chat.Hub, opts SlackOptions) error {
+	if opts.BotToken == "" || opts.AppToken == "" {
+		return fmt.Errorf("slack bot token and app token are required")
+	}
+	if opts.APIBase == "" {
+		opts.APIBase = slackAPIBase
+	}
+	s := &slack{hub: hub, opts: opts, client: &http.Client{Timeout: 15 * time.Second},
+		allowed: toSet(opts.AllowFrom), teams: toSet(opts.AllowTeams)}
+	s.opts.APIBase = strings.TrimRight(opts.APIBase, "/")
+
+	// inbound Socket Mode goroutine: reconnects with a backoff until ctx is done
+	go func() {
+		slackLog.Info("connecting via Socket Mode", "allow_from", opts.AllowFrom)
+		backoff := time.Second
+		for {
+			start := time.Now()
+			err := s.runSocket(ctx)
+			if ctx.Err() != nil {
+				slackLog.Info("stopping Socket Mode connection")
+				return
+			}
+			if time.Since(start) > time.Minute {
+				backoff = time.Second
+			}
+			slackLog.Warn("socket disconnected, reconnecting", "err", err, "backoff", backoff)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			if backoff < time.Minute {
+				backoff *= 2
+			}
+		}
+	}()
+
+	// outbound sender goroutine
+	outbox := hub.Subscribe("slack")
+	go func() {
+		slackLog.Info("starting outbound sender")
+		for {
+			select {
+			case <-ctx.Done():
+				slackLog.Info("stopping outbound sender")
+				return
+			case out, ok := <-outbox:
+				if !ok {
+					return
+				}
+				slackLog.Debug("sending message", "chat_id", out.ChatID)
+				_, span := telemetry.StartRemote(out.Metadata, "send", "channel", "slack", "chat_id", out.ChatID)
+				channel, thread, _ := strings.Cut(out.ChatID, ":")
+				for _, chunk := range splitMessage(out.Content, slackMaxLen) {
+					msg := map[string]string{"channel": channel, "text": chunk}
+					if thread != "" {
+						msg["thread_ts"] = thread
+					}
+					if err := s.call(ctx, s.opts.BotToken, "chat.postMessage", msg, nil); err != nil {
+						slackLog.Error("sending message failed", "chat_id", out.ChatID, "err", err)
+						span.RecordError(err)
+						break
+					}
+				}
+				span.End()
+			}
+		}
+	}()
+	return nil
+}
+
+type slack struct {
+	hub     *chat.Hub
+	opts    SlackOptions
+	client  *http.Client
+	allowed map[string]struct{}
+	teams   map[string]struct{}
+}
+
+func toSet(ids []string) map[string]struct{} {
+	m := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		m[id] = struct{}{}
+	}
+	return m
+}
+
+// slackEvent is the subset of message and app_mention events we use.
+type slackEvent struct {
+	Type        string `json:"type"`
+	Subtype     string `json:"subtype"`
+	User        string `json:"user"`
+	BotID       string `json:"bot_id"`
+	Text        string `json:"text"`
+	Channel     string `json:"channel"`
+	ChannelType string `json:"channel_type"`
+	TS          string `json:"ts"`
+	ThreadTS    string `json:"thread_ts"`
+}
+
+// runSocket opens one Socket Mode connection and handles envelopes until it
+// fails or Slack asks us to reconnect.
+func (s *slack) runSocket(ctx context.Context) error {
+	var open struct {
+		URL string `json:"url"`
+	}
+	if err := s.call(ctx, s.opts.AppToken, "apps.connections.open", nil, &open); err != nil {
+		return err
+	}
+	dialCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	conn, err := websocket.Dial(dialCtx, open.URL, nil)
+	cancel()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	for {
+		var env struct {
+			Type       string `json:"type"`
+			EnvelopeID string `json:"envelope_id"`
+			Payload    struct {
+				TeamID string     `json:"team_id"`
+				Event  slackEvent `json:"event"`
+			} `json:"payload"`
+		}
+		if err := conn.ReadJSON(&env); err != nil {
+			return err
+		}
+		if env.EnvelopeID != "" {
+			if err := conn.WriteJSON(map[string]string{"envelope_id": env.EnvelopeID}); err != nil {
+				return err
+			}
+		}
+		switch env.Type {
+		case "disconnect":
+			return fmt.Errorf("slack: server requested reconnect")
+		case "events_api":
+			s.handleEvent(env.Payload.TeamID, env.Payload.Event)
+		}
+	}
+}
+
+// handleEvent routes DMs and mentions to the hub.
+func (s *slack) handleEvent(team string, ev slackEvent) {
+	if ev.BotID != "" || ev.Subtype != "" || ev.User == "" {
+		return // our own messages, edits, joins, ...
+	}
+	direct := ev.Type == "message" && ev.ChannelType == "im"
+	if !direct && ev.Type != "app_mention" {
+		return
+	}
+	if len(s.teams) > 0 {
+		if _, ok := s.teams[team]; !ok {
+			slackLog.Warn("dropping message from other workspace", "team", team)
+			return
+		}
+	}
+	if _, ok := s.allowed[ev.User]; !ok {
+		slackLog.Warn("dropping message from unauthorized user", "sender", ev.User)
+		return
+	}
+	chatID := ev.Channel
+	if !direct {
+		thread := ev.ThreadTS
+		if thread == "" {
+			thread = ev.TS
+		}
+		chatID += ":" + thread
+	}
+	slackLog.Info("received message", "sender", ev.User, "chat_id", chatID)
+	s.hub.In <- chat.Inbound{
+		Channel:   "slack",
+		SenderID:  ev.User,
+		ChatID:    chatID,
+		Content:   strings.TrimSpace(slackMention.ReplaceAllString(ev.Text, "")),
+		Timestamp: time.Now(),
+		Metadata:  map[string]interface{}{"group": !direct},
+	}
+}
+
+// call invokes a Web API method with a bearer token and decodes the
+// response into out if non-nil. Slack reports errors with "ok": false.
+func (s *slack) call(ctx context.Context, token, method string, payload, out interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		b, _ := json.Marshal(payload)
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.opts.APIBase+"/"+method, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	var status struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(b, &status); err != nil {
+		return fmt.Errorf("slack: %s: %s: %w", method, resp.Status, err)
+	}
+	if !status.OK {
+		return fmt.Errorf("slack: %s: %s", method, status.Error)
+	}
+	if out != nil {
+		return json.Unmarshal(b, out)
+	}
+	return nil
+}
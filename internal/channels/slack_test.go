@@ -0,0 +1,86 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/websocket"
+)
+
+func TestStartSlack(t *testing.T) {
+	posted := make(chan map[string]string, 4)
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/apps.connections.open":
+			if r.Header.Get("Authorization") != "Bearer xapp-1" {
+				w.Write([]byte(`{"ok":false,"error":"invalid_auth"}`))
+				return
+			}
+			w.Write([]byte(`{"ok":true,"url":"ws` + strings.TrimPrefix(srv.URL, "http") + `/socket"}`))
+		case "/socket":
+			c, err := websocket.Accept(w, r)
+			if err != nil {
+				return
+			}
+			defer c.Close()
+			c.WriteJSON(map[string]string{"type": "hello"})
+			send := func(id string, ev map[string]string) {
+				c.WriteJSON(map[string]interface{}{"type": "events_api", "envelope_id": id,
+					"payload": map[string]interface{}{"team_id": "T1", "event": ev}})
+				var ack map[string]string
+				if err := c.ReadJSON(&ack); err != nil || ack["envelope_id"] != id {
+					t.Errorf("expected ack for %s, got %v (%v)", id, ack, err)
+				}
+			}
+			send("e1", map[string]string{"type": "message", "channel_type": "im", "user": "U9", "text": "spam", "channel": "D9", "ts": "1.0"})
+			send("e2", map[string]string{"type": "app_mention", "user": "U1", "text": "<@UBOT> hello", "channel": "C1", "ts": "2.0"})
+			for {
+				if _, _, err := c.ReadMessage(); err != nil {
+					return
+				}
+			}
+		case "/chat.postMessage":
+			var m map[string]string
+			json.NewDecoder(r.Body).Decode(&m)
+			posted <- m
+			w.Write([]byte(`{"ok":true}`))
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer srv.Close()
+
+	b := chat.NewHub(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opts := SlackOptions{BotToken: "xoxb-1", AppToken: "xapp-1", AllowFrom: []string{"U1"}, AllowTeams: []string{"T1"}, APIBase: srv.URL}
+	if err := StartSlack(ctx, b, opts); err != nil {
+		t.Fatalf("StartSlack: %v", err)
+	}
+
+	select {
+	case msg := <-b.In:
+		if msg.Channel != "slack" || msg.SenderID != "U1" || msg.ChatID != "C1:2.0" || msg.Content != "hello" {
+			t.Fatalf("unexpected inbound: %+v", msg)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("timeout waiting for inbound message")
+	}
+
+	b.Out <- chat.Outbound{Channel: "slack", ChatID: "C1:2.0", Content: "hi there"}
+	select {
+	case m := <-posted:
+		if m["channel"] != "C1" || m["thread_ts"] != "2.0" || m["text"] != "hi there" {
+			t.Fatalf("unexpected postMessage: %v", m)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("timeout waiting for chat.postMessage")
+	}
+}
@@ -5,15 +5,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/logging"
+	"github.com/kr0nicas/picobot/internal/telemetry"
 )
 
+var telegramLog = logging.For("channels").With("channel", "telegram")
+
 // StartTelegram is a convenience wrapper that uses the real polling implementation
 // with the standard Telegram base URL.
 // allowFrom is a list of Telegram user IDs permitted to interact with the bot.
@@ -32,23 +36,22 @@ func StartTelegramWithBase(ctx context.Context, hub *chat.Hub, token, base strin
 	if base == "" {
 		return fmt.Errorf("base URL is required")
 	}
-
-	// Build a fast lookup set for allowed user IDs.
-	allowed := make(map[string]struct{}, len(allowFrom))
-	for _, id := range allowFrom {
-		allowed[id] = struct{}{}
-	}
-
+	t := newTelegram(hub, base, allowFrom)
 	client := &http.Client{Timeout: 45 * time.Second}
+	go t.setCommands()
 
 	// inbound polling goroutine
 	go func() {
-		log.Printf("telegram: starting inbound polling (allowFrom: %v)", allowFrom)
+		telegramLog.Info("starting inbound polling", "allow_from", allowFrom)
+		// getUpdates fails while a webhook is set, e.g. after switching modes
+		if resp, err := client.PostForm(base+"/deleteWebhook", nil); err == nil {
+			resp.Body.Close()
+		}
 		offset := int64(0)
 		for {
 			select {
 			case <-ctx.Done():
-				log.Println("telegram: stopping inbound polling")
+				telegramLog.Info("stopping inbound polling")
 				return
 			default:
 			}
@@ -59,30 +62,18 @@ func StartTelegramWithBase(ctx context.Context, hub *chat.Hub, token, base strin
 			u := base + "/getUpdates"
 			resp, err := client.PostForm(u, values)
 			if err != nil {
-				log.Printf("telegram getUpdates error: %v", err)
+				telegramLog.Warn("getUpdates failed", "err", err)
 				time.Sleep(5 * time.Second) // Wait bit longer on error
 				continue
 			}
 			body, _ := io.ReadAll(resp.Body)
 			resp.Body.Close()
 			var gu struct {
-				Ok     bool `json:"ok"`
-				Result []struct {
-					UpdateID int64 `json:"update_id"`
-					Message  *struct {
-						MessageID int64 `json:"message_id"`
-						From      *struct {
-							ID int64 `json:"id"`
-						} `json:"from"`
-						Chat struct {
-							ID int64 `json:"id"`
-						} `json:"chat"`
-						Text string `json:"text"`
-					} `json:"message"`
-				} `json:"result"`
+				Ok     bool       `json:"ok"`
+				Result []tgUpdate `json:"result"`
 			}
 			if err := json.Unmarshal(body, &gu); err != nil {
-				log.Printf("telegram: invalid getUpdates response (len=%d): %v", len(body), err)
+				telegramLog.Warn("invalid getUpdates response", "bytes", len(body), "err", err)
 				time.Sleep(2 * time.Second)
 				continue
 			}
@@ -90,73 +81,254 @@ func StartTelegramWithBase(ctx context.Context, hub *chat.Hub, token, base strin
 				if upd.UpdateID >= offset {
 					offset = upd.UpdateID + 1
 				}
-				if upd.Message == nil {
-					continue
-				}
-				m := upd.Message
-				fromID := ""
-				if m.From != nil {
-					fromID = strconv.FormatInt(m.From.ID, 10)
-				}
-				// Enforce allowFrom: if the list is empty, we drop all messages for security
-				if len(allowed) == 0 {
-					log.Printf("telegram: dropping message from user %s: no authorized users configured in allowFrom", fromID)
-					continue
-				}
-				if _, ok := allowed[fromID]; !ok {
-					log.Printf("telegram: dropping message from unauthorized user %s", fromID)
-					continue
-				}
-				chatID := strconv.FormatInt(m.Chat.ID, 10)
-				log.Printf("telegram: received message from %s, routing to hub", fromID)
-				hub.In <- chat.Inbound{
-					Channel:   "telegram",
-					SenderID:  fromID,
-					ChatID:    chatID,
-					Content:   m.Text,
-					Timestamp: time.Now(),
-				}
+				t.handleUpdate(upd)
 			}
 		}
 	}()
 
-	// outbound sender goroutine
+	t.startSender(ctx)
+	return nil
+}
+
+// telegram holds the state shared by the polling and webhook receivers.
+type telegram struct {
+	hub     *chat.Hub
+	base    string
+	allowed map[string]struct{}
+	client  *http.Client
+	drafts  map[string]*tgDraft // chat ID -> reply being streamed
+}
+
+func newTelegram(hub *chat.Hub, base string, allowFrom []string) *telegram {
+	// Build a fast lookup set for allowed user IDs.
+	allowed := make(map[string]struct{}, len(allowFrom))
+	for _, id := range allowFrom {
+		allowed[id] = struct{}{}
+	}
+	return &telegram{hub: hub, base: base, allowed: allowed, client: &http.Client{Timeout: 15 * time.Second}, drafts: make(map[string]*tgDraft)}
+}
+
+// telegramCommands are listed in the client's command menu. The agent
+// answers them directly without calling the model (see agent.handleCommand).
+var telegramCommands = []struct {
+	Command     string `json:"command"`
+	Description string `json:"description"`
+}{
+	{"reset", "Forget this chat's conversation history"},
+	{"status", "Show uptime and the active model"},
+	{"memory", "Show today's notes"},
+	{"usage", "Show today's and this month's token usage and cost"},
+	{"model", "Show the active model (admins: /model <name> switches it)"},
+	{"plan", "Plan a request and approve the plan before work starts"},
+	{"stop", "Stop the task the agent is working on"},
+	{"resume", "Continue a task interrupted by a restart"},
+	{"summarize", "Summarize what an interrupted task did"},
+}
+
+// setCommands registers telegramCommands with setMyCommands.
+func (t *telegram) setCommands() {
+	data, _ := json.Marshal(telegramCommands)
+	resp, err := t.client.PostForm(t.base+"/setMyCommands", url.Values{"commands": {string(data)}})
+	if err != nil {
+		telegramLog.Warn("setMyCommands failed", "err", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		telegramLog.Warn("setMyCommands failed", "status", resp.Status)
+	}
+}
+
+// tgUpdate is the subset of a Telegram Update we handle.
+type tgUpdate struct {
+	UpdateID int64 `json:"update_id"`
+	Message  *struct {
+		MessageID int64 `json:"message_id"`
+		From      *struct {
+			ID int64 `json:"id"`
+		} `json:"from"`
+		Chat struct {
+			ID   int64  `json:"id"`
+			Type string `json:"type"`
+		} `json:"chat"`
+		Text     string        `json:"text"`
+		Caption  string        `json:"caption"`
+		Photo    []tgPhotoSize `json:"photo"`
+		Document *tgDocument   `json:"document"`
+	} `json:"message"`
+	CallbackQuery *struct {
+		ID   string `json:"id"`
+		From struct {
+			ID int64 `json:"id"`
+		} `json:"from"`
+		Message *struct {
+			MessageID int64 `json:"message_id"`
+			Chat      struct {
+				ID   int64  `json:"id"`
+				Type string `json:"type"`
+			} `json:"chat"`
+		} `json:"message"`
+		Data string `json:"data"`
+	} `json:"callback_query"`
+}
+
+// handleUpdate routes a message or button press from an allowed user to the hub.
+func (t *telegram) handleUpdate(upd tgUpdate) {
+	if cq := upd.CallbackQuery; cq != nil {
+		// acknowledge the button press so the client stops its spinner
+		if resp, err := t.client.PostForm(t.base+"/answerCallbackQuery", url.Values{"callback_query_id": {cq.ID}}); err == nil {
+			resp.Body.Close()
+		}
+		fromID := strconv.FormatInt(cq.From.ID, 10)
+		if _, ok := t.allowed[fromID]; !ok || cq.Message == nil {
+			telegramLog.Warn("dropping button press from unauthorized user", "sender", fromID)
+			return
+		}
+		chatID := strconv.FormatInt(cq.Message.Chat.ID, 10)
+		messageID := strconv.FormatInt(cq.Message.MessageID, 10)
+		// remove the keyboard so the choice cannot be made twice
+		if resp, err := t.client.PostForm(t.base+"/editMessageReplyMarkup", url.Values{"chat_id": {chatID}, "message_id": {messageID}}); err == nil {
+			resp.Body.Close()
+		}
+		t.hub.In <- chat.Inbound{
+			Channel:   "telegram",
+			SenderID:  fromID,
+			ChatID:    chatID,
+			Content:   cq.Data,
+			Timestamp: time.Now(),
+			Metadata:  map[string]interface{}{"group": isGroupChat(cq.Message.Chat.Type)},
+			Button:    &chat.ButtonPress{Data: cq.Data, MessageID: messageID},
+		}
+		return
+	}
+	if upd.Message == nil {
+		return
+	}
+	m := upd.Message
+	fromID := ""
+	if m.From != nil {
+		fromID = strconv.FormatInt(m.From.ID, 10)
+	}
+	// Enforce allowFrom: if the list is empty, we drop all messages for security
+	if len(t.allowed) == 0 {
+		telegramLog.Warn("dropping message: no authorized users configured in allowFrom", "sender", fromID)
+		return
+	}
+	if _, ok := t.allowed[fromID]; !ok {
+		telegramLog.Warn("dropping message from unauthorized user", "sender", fromID)
+		return
+	}
+	chatID := strconv.FormatInt(m.Chat.ID, 10)
+	content := m.Text
+	if content == "" {
+		content = m.Caption
+	}
+	media, notes := t.attachments(m.MessageID, m.Photo, m.Document)
+	if len(notes) > 0 {
+		content = strings.TrimSpace(content + "\n" + strings.Join(notes, "\n"))
+	}
+	if content == "" && len(media) == 0 {
+		return // stickers, joins, ...
+	}
+	telegramLog.Info("received message", "sender", fromID, "chat_id", chatID)
+	t.hub.In <- chat.Inbound{
+		Channel:   "telegram",
+		SenderID:  fromID,
+		ChatID:    chatID,
+		Content:   content,
+		Timestamp: time.Now(),
+		Media:     media,
+		Metadata:  map[string]interface{}{"group": isGroupChat(m.Chat.Type)},
+	}
+}
+
+// startSender starts the outbound sender goroutine.
+func (t *telegram) startSender(ctx context.Context) {
+	outbox := t.hub.Subscribe("telegram")
+	events := t.hub.SubscribeEvents("telegram")
 	go func() {
-		log.Println("telegram: starting outbound sender")
-		client := &http.Client{Timeout: 15 * time.Second}
+		telegramLog.Info("starting outbound sender")
 		for {
 			select {
 			case <-ctx.Done():
-				log.Println("telegram: stopping outbound sender")
+				telegramLog.Info("stopping outbound sender")
 				return
-			case out := <-hub.Out:
-				if out.Channel != "telegram" {
-					continue
+			case out, ok := <-outbox:
+				if !ok {
+					return
 				}
-				log.Printf("telegram: sending message to chat %s", out.ChatID)
-				u := base + "/sendMessage"
-				chunks := splitMessage(out.Content, 4096)
-				for _, chunk := range chunks {
-					v := url.Values{}
-					v.Set("chat_id", out.ChatID)
-					v.Set("text", chunk)
-					resp, err := client.PostForm(u, v)
-					if err != nil {
-						log.Printf("telegram sendMessage error: %v", err)
-						break
-					}
-					respBody, _ := io.ReadAll(resp.Body)
-					resp.Body.Close()
-					if resp.StatusCode != 200 {
-						log.Printf("telegram sendMessage non-200: %s body=%s", resp.Status, string(respBody))
-						break
-					}
+				telegramLog.Debug("sending message", "chat_id", out.ChatID)
+				_, span := telemetry.StartRemote(out.Metadata, "send", "channel", "telegram", "chat_id", out.ChatID)
+				t.send(out)
+				span.End()
+			case ev := <-events:
+				if ev.Type == chat.EventToken {
+					t.streamToken(ev.ChatID, ev.Content)
 				}
 			}
 		}
 	}()
+}
 
-	return nil
+// send delivers one outbound message: the text in chunks of at most 4096
+// characters, then each attachment. Markdown in the text is rendered as
+// Telegram HTML; chunks Telegram refuses to parse are resent as plain text.
+// A reply that was streamed replaces its draft message instead.
+func (t *telegram) send(out chat.Outbound) {
+	chunks := splitMessage(out.Content, 4096)
+	if out.Content == "" || t.finishDraft(out) {
+		chunks = nil
+	}
+	for i, chunk := range chunks {
+		v := url.Values{}
+		v.Set("chat_id", out.ChatID)
+		v.Set("text", markdownToTelegramHTML(chunk))
+		v.Set("parse_mode", "HTML")
+		if i == len(chunks)-1 && len(out.Buttons) > 0 {
+			v.Set("reply_markup", inlineKeyboard(out.Buttons))
+		}
+		status, body, err := t.sendMessage(v)
+		if err == nil && status == http.StatusBadRequest && strings.Contains(body, "can't parse entities") {
+			telegramLog.Warn("formatting rejected, sending as plain text", "response", body)
+			v.Del("parse_mode")
+			v.Set("text", chunk)
+			status, body, err = t.sendMessage(v)
+		}
+		if err != nil {
+			telegramLog.Error("sendMessage failed", "chat_id", out.ChatID, "err", err)
+			return
+		}
+		if status != http.StatusOK {
+			telegramLog.Error("sendMessage failed", "chat_id", out.ChatID, "status", status, "response", body)
+			return
+		}
+	}
+	for _, a := range out.Attachments {
+		if err := t.sendFile(out.ChatID, a); err != nil {
+			telegramLog.Error("sending attachment failed", "chat_id", out.ChatID, "file", a.FileName(), "err", err)
+		}
+	}
+}
+
+// sendMessage calls sendMessage with v and returns the status and body.
+func (t *telegram) sendMessage(v url.Values) (int, string, error) {
+	return t.post("sendMessage", v)
+}
+
+// inlineKeyboard encodes buttons as a Telegram InlineKeyboardMarkup.
+func inlineKeyboard(rows [][]chat.Button) string {
+	type button struct {
+		Text         string `json:"text"`
+		CallbackData string `json:"callback_data"`
+	}
+	kb := make([][]button, len(rows))
+	for i, row := range rows {
+		for _, b := range row {
+			kb[i] = append(kb[i], button{Text: b.Text, CallbackData: b.Data})
+		}
+	}
+	data, _ := json.Marshal(map[string]interface{}{"inline_keyboard": kb})
+	return string(data)
 }
 
 // splitMessage splits text into chunks of at most maxLen characters,
@@ -190,3 +362,8 @@ func lastIndexByte(s string, c byte) int {
 	}
 	return -1
 }
+
+// isGroupChat reports whether a Telegram chat type is a group conversation.
+func isGroupChat(chatType string) bool {
+	return chatType == "group" || chatType == "supergroup"
+}
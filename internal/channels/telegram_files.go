@@ -0,0 +1,177 @@
+package channels
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+)
+
+// tgMaxDownload is the largest file the Bot API lets bots download.
+const tgMaxDownload = 20 << 20
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// tgPhotoSize is one resolution of a photo.
+type tgPhotoSize struct {
+	FileID   string `json:"file_id"`
+	FileSize int64  `json:"file_size"`
+}
+
+// tgDocument is a file sent as a document.
+type tgDocument struct {
+	FileID   string `json:"file_id"`
+	FileName string `json:"file_name"`
+	FileSize int64  `json:"file_size"`
+}
+
+// attachments downloads the photo (largest size) or document of a message
+// and returns the local paths plus notes about files that were skipped.
+func (t *telegram) attachments(messageID int64, photo []tgPhotoSize, doc *tgDocument) (paths, notes []string) {
+	type file struct {
+		id, name string
+		size     int64
+	}
+	var files []file
+	if len(photo) > 0 {
+		p := photo[len(photo)-1]
+		files = append(files, file{p.FileID, fmt.Sprintf("photo_%d.jpg", messageID), p.FileSize})
+	}
+	if doc != nil {
+		name := doc.FileName
+		if name == "" {
+			name = fmt.Sprintf("document_%d", messageID)
+		}
+		files = append(files, file{doc.FileID, name, doc.FileSize})
+	}
+	for _, f := range files {
+		if f.size > tgMaxDownload {
+			notes = append(notes, fmt.Sprintf("[The user attached %s, but it is larger than 20MB and could not be downloaded.]", f.name))
+			continue
+		}
+		path, err := t.download(f.id, f.name)
+		if err != nil {
+			notes = append(notes, fmt.Sprintf("[The user attached %s, but downloading it failed.]", f.name))
+			telegramLog.Warn("downloading attachment failed", "file", f.name, "err", err)
+			continue
+		}
+		paths = append(paths, path)
+	}
+	return paths, notes
+}
+
+// download fetches a file by ID into a new temporary directory. The
+// agent moves it into the workspace inbox before processing the message.
+func (t *telegram) download(fileID, name string) (string, error) {
+	resp, err := t.client.PostForm(t.base+"/getFile", url.Values{"file_id": {fileID}})
+	if err != nil {
+		return "", err
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	var res struct {
+		OK     bool `json:"ok"`
+		Result struct {
+			FilePath string `json:"file_path"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil || !res.OK || res.Result.FilePath == "" {
+		return "", fmt.Errorf("telegram: getFile failed: %s", resp.Status)
+	}
+
+	fresp, err := t.client.Get(tgFileBase(t.base) + "/" + res.Result.FilePath)
+	if err != nil {
+		return "", err
+	}
+	defer fresp.Body.Close()
+	if fresp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("telegram: file download: %s", fresp.Status)
+	}
+	dir, err := os.MkdirTemp("", "picobot-telegram-")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, unsafeFileChars.ReplaceAllString(filepath.Base(name), "_"))
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	n, err := io.Copy(f, io.LimitReader(fresp.Body, tgMaxDownload+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && n > tgMaxDownload {
+		err = fmt.Errorf("telegram: file larger than 20MB")
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return path, nil
+}
+
+// tgFileBase maps a Bot API base URL (https://api.telegram.org/bot<TOKEN>)
+// to its file download base (https://api.telegram.org/file/bot<TOKEN>).
+func tgFileBase(base string) string {
+	if i := strings.LastIndex(base, "/bot"); i >= 0 {
+		return base[:i] + "/file" + base[i:]
+	}
+	return base + "/file"
+}
+
+// Upload limits of the Bot API.
+const (
+	tgMaxPhoto  = 10 << 20
+	tgMaxUpload = 50 << 20
+)
+
+// sendFile uploads an attachment with sendPhoto for common image formats
+// and sendDocument for everything else, so charts show inline and other
+// files keep their name.
+func (t *telegram) sendFile(chatID string, a chat.Attachment) error {
+	data, err := a.Bytes()
+	if err != nil {
+		return err
+	}
+	if len(data) > tgMaxUpload {
+		return fmt.Errorf("telegram: %s is larger than 50MB", a.FileName())
+	}
+	method, field := "sendDocument", "document"
+	switch strings.ToLower(filepath.Ext(a.FileName())) {
+	case ".jpg", ".jpeg", ".png", ".webp":
+		if len(data) <= tgMaxPhoto {
+			method, field = "sendPhoto", "photo"
+		}
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("chat_id", chatID)
+	fw, err := mw.CreateFormFile(field, a.FileName())
+	if err != nil {
+		return err
+	}
+	fw.Write(data)
+	if err := mw.Close(); err != nil {
+		return err
+	}
+	resp, err := t.client.Post(t.base+"/"+method, mw.FormDataContentType(), &body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("telegram: %s: %s body=%s", method, resp.Status, string(respBody))
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package channels
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// Telegram renders a small HTML subset (parse_mode=HTML). The model writes
+// markdown, so outbound text is converted before sending: emphasis, inline
+// code, fenced code blocks, links, headings (as bold lines), bullet lists and
+// quotes. Everything else is escaped and shown as written. If Telegram still
+// rejects the result, the sender falls back to the plain text.
+
+var (
+	mdHeading = regexp.MustCompile(`^#{1,6}\s+(.*)$`)
+	mdBullet  = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	mdFence   = regexp.MustCompile("^\\s*```\\s*([\\w+#.-]*)\\s*$")
+)
+
+// markdownToTelegramHTML converts markdown to Telegram-flavoured HTML.
+func markdownToTelegramHTML(md string) string {
+	lines := strings.Split(md, "\n")
+	var out []string
+	var quote []string
+	flushQuote := func() {
+		if len(quote) > 0 {
+			out = append(out, "<blockquote>"+strings.Join(quote, "\n")+"</blockquote>")
+			quote = nil
+		}
+	}
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if m := mdFence.FindStringSubmatch(line); m != nil {
+			// find the closing fence; an unterminated fence is plain text
+			end := -1
+			for j := i + 1; j < len(lines); j++ {
+				if strings.TrimSpace(lines[j]) == "```" {
+					end = j
+					break
+				}
+			}
+			if end >= 0 {
+				flushQuote()
+				code := html.EscapeString(strings.Join(lines[i+1:end], "\n"))
+				if m[1] != "" {
+					out = append(out, `<pre><code class="language-`+html.EscapeString(m[1])+`">`+code+"</code></pre>")
+				} else {
+					out = append(out, "<pre>"+code+"</pre>")
+				}
+				i = end
+				continue
+			}
+		}
+		if rest, ok := strings.CutPrefix(line, ">"); ok {
+			quote = append(quote, inlineHTML(strings.TrimPrefix(rest, " ")))
+			continue
+		}
+		flushQuote()
+		switch {
+		case mdHeading.MatchString(line):
+			out = append(out, "<b>"+inlineHTML(mdHeading.FindStringSubmatch(line)[1])+"</b>")
+		case mdBullet.MatchString(line) && !isRule(line):
+			m := mdBullet.FindStringSubmatch(line)
+			out = append(out, m[1]+"• "+inlineHTML(m[2]))
+		default:
+			out = append(out, inlineHTML(line))
+		}
+	}
+	flushQuote()
+	return strings.Join(out, "\n")
+}
+
+// isRule reports whether line is a horizontal rule such as "---" or "* * *".
+func isRule(line string) bool {
+	s := strings.ReplaceAll(strings.TrimSpace(line), " ", "")
+	return len(s) >= 3 && (strings.Trim(s, "-") == "" || strings.Trim(s, "*") == "")
+}
+
+// inlineHTML converts inline markdown in s. Delimiters without a matching
+// closing delimiter are kept as literal text.
+func inlineHTML(s string) string {
+	var sb strings.Builder
+	text := 0 // start of pending literal text
+	flush := func(i int) {
+		sb.WriteString(html.EscapeString(s[text:i]))
+	}
+	for i := 0; i < len(s); {
+		var tag, inner string
+		var n int // bytes consumed
+		switch {
+		case s[i] == '`':
+			if end := strings.IndexByte(s[i+1:], '`'); end > 0 {
+				flush(i)
+				sb.WriteString("<code>" + html.EscapeString(s[i+1:i+1+end]) + "</code>")
+				i += end + 2
+				text = i
+				continue
+			}
+		case strings.HasPrefix(s[i:], "**"), strings.HasPrefix(s[i:], "__"):
+			tag, inner, n = emphasis(s, i, s[i:i+2], "b")
+		case strings.HasPrefix(s[i:], "~~"):
+			tag, inner, n = emphasis(s, i, "~~", "s")
+		case s[i] == '*', s[i] == '_':
+			tag, inner, n = emphasis(s, i, s[i:i+1], "i")
+		case s[i] == '[':
+			if label, url, ln := mdLink(s[i:]); ln > 0 {
+				flush(i)
+				sb.WriteString(`<a href="` + html.EscapeString(url) + `">` + inlineHTML(label) + "</a>")
+				i += ln
+				text = i
+				continue
+			}
+		}
+		if n > 0 {
+			flush(i)
+			sb.WriteString("<" + tag + ">" + inlineHTML(inner) + "</" + tag + ">")
+			i += n
+			text = i
+			continue
+		}
+		i++
+	}
+	flush(len(s))
+	return sb.String()
+}
+
+// emphasis matches delim-wrapped text starting at s[i]. The content must not
+// start or end with a space, and "_" only counts at word boundaries so
+// snake_case names stay intact. It returns the tag, the inner text and the
+// length of the match, or n == 0.
+func emphasis(s string, i int, delim, tag string) (string, string, int) {
+	start := i + len(delim)
+	if start >= len(s) || s[start] == ' ' {
+		return "", "", 0
+	}
+	if delim[0] == '_' && i > 0 && isWordByte(s[i-1]) {
+		return "", "", 0
+	}
+	for j := start + 1; j+len(delim) <= len(s); j++ {
+		if s[j:j+len(delim)] != delim || s[j-1] == ' ' {
+			continue
+		}
+		end := j + len(delim)
+		if delim[0] == '_' && end < len(s) && isWordByte(s[end]) {
+			continue
+		}
+		if len(delim) == 1 && end < len(s) && s[end] == delim[0] {
+			continue // part of a longer delimiter
+		}
+		return tag, s[start:j], end - i
+	}
+	return "", "", 0
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// mdLink parses "[label](url)" at the start of s. Only http(s), mailto and
+// tg links are converted.
+func mdLink(s string) (label, url string, n int) {
+	mid := strings.Index(s, "](")
+	if mid < 0 {
+		return "", "", 0
+	}
+	end := strings.IndexByte(s[mid+2:], ')')
+	if end < 0 {
+		return "", "", 0
+	}
+	label, url = s[1:mid], strings.TrimSpace(s[mid+2:mid+2+end])
+	if label == "" || strings.ContainsAny(label, "[]") {
+		return "", "", 0
+	}
+	lower := strings.ToLower(url)
+	if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") &&
+		!strings.HasPrefix(lower, "mailto:") && !strings.HasPrefix(lower, "tg://") {
+		return "", "", 0
+	}
+	return label, url, mid + 2 + end + 1
+}
@@ -0,0 +1,28 @@
+package channels
+
+import "testing"
+
+func TestMarkdownToTelegramHTML(t *testing.T) {
+	cases := []struct{ in, want string }{
+		{"plain <text> & more", "plain &lt;text&gt; &amp; more"},
+		{"**bold** and *italic* and ~~gone~~", "<b>bold</b> and <i>italic</i> and <s>gone</s>"},
+		{"__bold__ _it_ snake_case_name", "<b>bold</b> <i>it</i> snake_case_name"},
+		{"2 * 3 * 4", "2 * 3 * 4"},
+		{"use `a<b` here", "use <code>a&lt;b</code> here"},
+		{"**unclosed", "**unclosed"},
+		{"[docs](https://example.com/?a=1&b=2)", `<a href="https://example.com/?a=1&amp;b=2">docs</a>`},
+		{"[bad](javascript:alert(1))", "[bad](javascript:alert(1))"},
+		{"## Title", "<b>Title</b>"},
+		{"- one\n* two", "• one\n• two"},
+		{"---", "---"},
+		{"> quoted\n> *more*\nafter", "<blockquote>quoted\n<i>more</i></blockquote>\nafter"},
+		{"```go\nif a < b {}\n```", `<pre><code class="language-go">if a &lt; b {}</code></pre>`},
+		{"```\n**raw**\n```", "<pre>**raw**</pre>"},
+		{"```\nunterminated", "```\nunterminated"},
+	}
+	for _, c := range cases {
+		if got := markdownToTelegramHTML(c.in); got != c.want {
+			t.Errorf("markdownToTelegramHTML(%q)\n got %q\nwant %q", c.in, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package channels
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+)
+
+// streamEditInterval throttles draft edits; Telegram rate-limits edits of
+// the same message to roughly one per second.
+const streamEditInterval = time.Second
+
+// tgDraft is a reply being streamed into a message that is edited as more
+// text arrives. Drafts are only touched by the sender goroutine.
+type tgDraft struct {
+	messageID string
+	text      string
+	lastEdit  time.Time
+}
+
+// streamToken adds a fragment of a reply being generated to chatID's draft,
+// creating the draft message on the first visible text.
+func (t *telegram) streamToken(chatID, delta string) {
+	d := t.drafts[chatID]
+	if d == nil {
+		d = &tgDraft{}
+		t.drafts[chatID] = d
+	}
+	d.text += delta
+	text := strings.TrimSpace(d.text)
+	if text == "" || len(text) > 4000 {
+		return // nothing to show yet, or too long for one message: wait for the reply
+	}
+	if d.messageID == "" {
+		status, body, err := t.post("sendMessage", url.Values{"chat_id": {chatID}, "text": {text}})
+		if err != nil || status != http.StatusOK {
+			telegramLog.Warn("starting streamed reply failed", "status", status, "err", err)
+			return
+		}
+		var res struct {
+			Result struct {
+				MessageID int64 `json:"message_id"`
+			} `json:"result"`
+		}
+		json.Unmarshal([]byte(body), &res)
+		d.messageID = strconv.FormatInt(res.Result.MessageID, 10)
+		d.lastEdit = time.Now()
+		return
+	}
+	if time.Since(d.lastEdit) < streamEditInterval {
+		return
+	}
+	d.lastEdit = time.Now()
+	t.post("editMessageText", url.Values{"chat_id": {chatID}, "message_id": {d.messageID}, "text": {text}})
+}
+
+// finishDraft replaces chatID's streamed draft, if any, with the final reply
+// out. It reports whether out's text was delivered that way; otherwise the
+// draft is deleted and the caller sends out as usual.
+func (t *telegram) finishDraft(out chat.Outbound) bool {
+	d := t.drafts[out.ChatID]
+	delete(t.drafts, out.ChatID)
+	if d == nil || d.messageID == "" {
+		return false
+	}
+	if out.Content != "" && len(out.Content) <= 4096 {
+		v := url.Values{"chat_id": {out.ChatID}, "message_id": {d.messageID}}
+		v.Set("text", markdownToTelegramHTML(out.Content))
+		v.Set("parse_mode", "HTML")
+		if len(out.Buttons) > 0 {
+			v.Set("reply_markup", inlineKeyboard(out.Buttons))
+		}
+		status, body, err := t.post("editMessageText", v)
+		if err == nil && status == http.StatusBadRequest && strings.Contains(body, "can't parse entities") {
+			v.Del("parse_mode")
+			v.Set("text", out.Content)
+			status, body, err = t.post("editMessageText", v)
+		}
+		if err == nil && (status == http.StatusOK || strings.Contains(body, "message is not modified")) {
+			return true
+		}
+		telegramLog.Warn("finishing streamed reply failed", "status", status, "err", err, "response", body)
+	}
+	t.post("deleteMessage", url.Values{"chat_id": {out.ChatID}, "message_id": {d.messageID}})
+	return false
+}
+
+// post calls a Bot API method with form values and returns the status and body.
+func (t *telegram) post(method string, v url.Values) (int, string, error) {
+	resp, err := t.client.PostForm(t.base+"/"+method, v)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body), nil
+}
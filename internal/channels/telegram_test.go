@@ -2,10 +2,15 @@ package channels
 
 import (
 	"context"
+	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -16,6 +21,7 @@ func TestStartTelegramWithBase(t *testing.T) {
 	token := "testtoken"
 	// channel to capture sendMessage posts
 	sent := make(chan url.Values, 4)
+	commands := make(chan string, 1)
 
 	// simple stateful handler: first getUpdates returns one update, subsequent return empty
 	first := true
@@ -31,6 +37,12 @@ func TestStartTelegramWithBase(t *testing.T) {
 			w.Write([]byte(`{"ok":true,"result":[]}`))
 			return
 		}
+		if strings.HasSuffix(path, "/setMyCommands") {
+			r.ParseForm()
+			commands <- r.PostForm.Get("commands")
+			w.Write([]byte(`{"ok":true,"result":true}`))
+			return
+		}
 		if strings.HasSuffix(path, "/sendMessage") {
 			r.ParseForm()
 			sent <- r.PostForm
@@ -77,8 +89,233 @@ func TestStartTelegramWithBase(t *testing.T) {
 		t.Fatal("timeout waiting for sendMessage to be posted")
 	}
 
+	select {
+	case cmds := <-commands:
+		if !strings.Contains(cmds, `"command":"reset"`) {
+			t.Fatalf("unexpected commands: %s", cmds)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timeout waiting for setMyCommands")
+	}
+
 	// cancel and allow goroutines to stop
 	cancel()
 	// give a small grace period
 	time.Sleep(50 * time.Millisecond)
 }
+
+func TestTelegramWebhookHandler(t *testing.T) {
+	b := chat.NewHub(10)
+	tg := newTelegram(b, "http://unused", []string{"123"})
+	h := httptest.NewServer(tg.webhookHandler("s3cret"))
+	defer h.Close()
+
+	update := `{"update_id":5,"message":{"message_id":1,"from":{"id":123},"chat":{"id":-77,"type":"supergroup"},"text":"hi"}}`
+	post := func(secret string) int {
+		req, _ := http.NewRequest(http.MethodPost, h.URL, strings.NewReader(update))
+		req.Header.Set("X-Telegram-Bot-Api-Secret-Token", secret)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("post: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := post("wrong"); code != http.StatusForbidden {
+		t.Fatalf("expected 403 for bad secret, got %d", code)
+	}
+	if code := post("s3cret"); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	select {
+	case msg := <-b.In:
+		if msg.ChatID != "-77" || msg.Content != "hi" || msg.Metadata["group"] != true {
+			t.Fatalf("unexpected inbound: %+v", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for inbound message")
+	}
+}
+
+func TestTelegramCallbackQuery(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mu.Lock()
+		calls = append(calls, r.URL.Path+"?"+r.Form.Encode())
+		mu.Unlock()
+		w.Write([]byte(`{"ok":true,"result":true}`))
+	}))
+	defer api.Close()
+
+	b := chat.NewHub(10)
+	tg := newTelegram(b, api.URL, []string{"123"})
+	var upd tgUpdate
+	raw := `{"update_id":6,"callback_query":{"id":"cb1","from":{"id":123},"message":{"message_id":42,"chat":{"id":55,"type":"private"}},"data":"answer:1:0"}}`
+	if err := json.Unmarshal([]byte(raw), &upd); err != nil {
+		t.Fatal(err)
+	}
+	tg.handleUpdate(upd)
+
+	msg := <-b.In
+	if msg.ChatID != "55" || msg.SenderID != "123" || msg.Content != "answer:1:0" {
+		t.Fatalf("unexpected inbound: %+v", msg)
+	}
+	if msg.Button == nil || msg.Button.Data != "answer:1:0" || msg.Button.MessageID != "42" {
+		t.Fatalf("expected button press, got %+v", msg.Button)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"/answerCallbackQuery?callback_query_id=cb1", "/editMessageReplyMarkup?chat_id=55&message_id=42"}
+	if strings.Join(calls, " ") != strings.Join(want, " ") {
+		t.Fatalf("unexpected API calls: %v", calls)
+	}
+}
+
+func TestTelegramPhotoIntake(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/getFile":
+			r.ParseForm()
+			if r.Form.Get("file_id") != "big" {
+				t.Errorf("expected largest photo size to be fetched, got %q", r.Form.Get("file_id"))
+			}
+			w.Write([]byte(`{"ok":true,"result":{"file_path":"photos/file_1.jpg"}}`))
+		case "/file/photos/file_1.jpg":
+			w.Write([]byte("JPEGDATA"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer api.Close()
+
+	b := chat.NewHub(10)
+	tg := newTelegram(b, api.URL, []string{"123"})
+	var upd tgUpdate
+	raw := `{"update_id":7,"message":{"message_id":9,"from":{"id":123},"chat":{"id":55,"type":"private"},"caption":"what is this?",
+		"photo":[{"file_id":"small","file_size":10},{"file_id":"big","file_size":100}]}}`
+	if err := json.Unmarshal([]byte(raw), &upd); err != nil {
+		t.Fatal(err)
+	}
+	tg.handleUpdate(upd)
+
+	msg := <-b.In
+	if msg.Content != "what is this?" || len(msg.Media) != 1 {
+		t.Fatalf("unexpected inbound: %+v", msg)
+	}
+	defer os.RemoveAll(filepath.Dir(msg.Media[0]))
+	if filepath.Base(msg.Media[0]) != "photo_9.jpg" {
+		t.Fatalf("unexpected file name %q", msg.Media[0])
+	}
+	if data, _ := os.ReadFile(msg.Media[0]); string(data) != "JPEGDATA" {
+		t.Fatalf("unexpected file content %q", data)
+	}
+}
+
+func TestTelegramSendAttachments(t *testing.T) {
+	type upload struct{ method, field, name, data string }
+	var mu sync.Mutex
+	var uploads []upload
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err == nil {
+			mu.Lock()
+			for field, files := range r.MultipartForm.File {
+				f, _ := files[0].Open()
+				data, _ := io.ReadAll(f)
+				f.Close()
+				uploads = append(uploads, upload{r.URL.Path, field, files[0].Filename, string(data)})
+			}
+			mu.Unlock()
+		}
+		w.Write([]byte(`{"ok":true,"result":{}}`))
+	}))
+	defer api.Close()
+
+	tg := newTelegram(chat.NewHub(1), api.URL, nil)
+	report := filepath.Join(t.TempDir(), "report.pdf")
+	os.WriteFile(report, []byte("PDF"), 0o644)
+	tg.send(chat.Outbound{ChatID: "5", Content: "results", Attachments: []chat.Attachment{
+		{Name: "chart.png", Data: []byte("PNG")},
+		{Path: report},
+	}})
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []upload{{"/sendPhoto", "photo", "chart.png", "PNG"}, {"/sendDocument", "document", "report.pdf", "PDF"}}
+	if len(uploads) != len(want) {
+		t.Fatalf("expected %d uploads, got %+v", len(want), uploads)
+	}
+	for i := range want {
+		if uploads[i] != want[i] {
+			t.Fatalf("upload %d: expected %+v, got %+v", i, want[i], uploads[i])
+		}
+	}
+}
+
+func TestTelegramFormattingFallback(t *testing.T) {
+	var mu sync.Mutex
+	var sent []url.Values
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mu.Lock()
+		sent = append(sent, r.Form)
+		mu.Unlock()
+		if r.Form.Get("parse_mode") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"ok":false,"description":"Bad Request: can't parse entities"}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true,"result":{}}`))
+	}))
+	defer api.Close()
+
+	tg := newTelegram(chat.NewHub(1), api.URL, nil)
+	tg.send(chat.Outbound{ChatID: "5", Content: "**hi**"})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sent) != 2 {
+		t.Fatalf("expected formatted attempt and plain retry, got %d requests", len(sent))
+	}
+	if sent[0].Get("text") != "<b>hi</b>" || sent[0].Get("parse_mode") != "HTML" {
+		t.Fatalf("unexpected first attempt: %v", sent[0])
+	}
+	if sent[1].Get("text") != "**hi**" || sent[1].Get("parse_mode") != "" {
+		t.Fatalf("unexpected plain retry: %v", sent[1])
+	}
+}
+
+func TestTelegramStreamingEditsDraft(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mu.Lock()
+		calls = append(calls, r.URL.Path+" "+r.Form.Get("message_id")+" "+r.Form.Get("text"))
+		mu.Unlock()
+		w.Write([]byte(`{"ok":true,"result":{"message_id":42}}`))
+	}))
+	defer api.Close()
+
+	tg := newTelegram(chat.NewHub(1), api.URL, nil)
+	tg.streamToken("5", "Hel")
+	tg.streamToken("5", "lo") // within the edit interval: not shown yet
+	tg.drafts["5"].lastEdit = time.Time{}
+	tg.streamToken("5", " wor")
+	tg.send(chat.Outbound{ChatID: "5", Content: "Hello **world**"})
+	tg.send(chat.Outbound{ChatID: "5", Content: "next"})
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{
+		"/sendMessage  Hel",
+		"/editMessageText 42 Hello wor",
+		"/editMessageText 42 Hello <b>world</b>",
+		"/sendMessage  next",
+	}
+	if strings.Join(calls, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected calls:\n%s", strings.Join(calls, "\n"))
+	}
+}
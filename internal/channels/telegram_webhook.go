@@ -0,0 +1,133 @@
+package channels
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+)
+
+// TelegramWebhookOptions configures webhook mode.
+type TelegramWebhookOptions struct {
+	// URL is the public HTTPS address Telegram posts updates to, e.g.
+	// https://bot.example.com/telegram. Its path is also served locally.
+	URL string
+	// Listen is the local address of the HTTP server (default ":8443").
+	Listen string
+	// SecretToken is sent by Telegram in X-Telegram-Bot-Api-Secret-Token;
+	// requests without it are rejected.
+	SecretToken string
+	// CertFile and KeyFile serve HTTPS directly. Leave empty behind a
+	// TLS-terminating reverse proxy.
+	CertFile string
+	KeyFile  string
+}
+
+// StartTelegramWebhook receives updates through a webhook instead of long
+// polling. It registers opts.URL with setWebhook and serves it on opts.Listen.
+func StartTelegramWebhook(ctx context.Context, hub *chat.Hub, token string, opts TelegramWebhookOptions, allowFrom []string) error {
+	if token == "" {
+		return fmt.Errorf("telegram token not provided")
+	}
+	return StartTelegramWebhookWithBase(ctx, hub, "https://api.telegram.org/bot"+token, opts, allowFrom)
+}
+
+// StartTelegramWebhookWithBase is StartTelegramWebhook against the given Bot
+// API base URL.
+func StartTelegramWebhookWithBase(ctx context.Context, hub *chat.Hub, base string, opts TelegramWebhookOptions, allowFrom []string) error {
+	if opts.URL == "" {
+		return errors.New("telegram: webhook URL is required")
+	}
+	if opts.SecretToken == "" {
+		return errors.New("telegram: webhook secret token is required")
+	}
+	u, err := url.Parse(opts.URL)
+	if err != nil || u.Scheme != "https" {
+		return fmt.Errorf("telegram: webhook URL must be https, got %q", opts.URL)
+	}
+	if opts.Listen == "" {
+		opts.Listen = ":8443"
+	}
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+
+	t := newTelegram(hub, base, allowFrom)
+	v := url.Values{}
+	v.Set("url", opts.URL)
+	v.Set("secret_token", opts.SecretToken)
+	v.Set("allowed_updates", `["message","callback_query"]`)
+	resp, err := t.client.PostForm(base+"/setWebhook", v)
+	if err != nil {
+		return fmt.Errorf("telegram: setWebhook: %w", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	var res struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	_ = json.Unmarshal(body, &res)
+	if !res.OK {
+		return fmt.Errorf("telegram: setWebhook failed: %s %s", resp.Status, res.Description)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(path, t.webhookHandler(opts.SecretToken))
+	srv := &http.Server{Addr: opts.Listen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		telegramLog.Info("receiving updates via webhook", "listen", opts.Listen+path, "allow_from", allowFrom)
+		var err error
+		if opts.CertFile != "" {
+			err = srv.ListenAndServeTLS(opts.CertFile, opts.KeyFile)
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			telegramLog.Error("webhook server failed", "err", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		telegramLog.Info("stopping webhook server")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	go t.setCommands()
+	t.startSender(ctx)
+	return nil
+}
+
+// webhookHandler accepts update POSTs carrying the expected secret token.
+func (t *telegram) webhookHandler(secret string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		got := r.Header.Get("X-Telegram-Bot-Api-Secret-Token")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(secret)) != 1 {
+			telegramLog.Warn("rejecting webhook request: bad secret token", "remote", r.RemoteAddr)
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		var upd tgUpdate
+		if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&upd); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		// Reply first: Telegram retries updates that are not acknowledged quickly.
+		w.WriteHeader(http.StatusOK)
+		go t.handleUpdate(upd)
+	})
+}
@@ -0,0 +1,212 @@
+package channels
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/logging"
+	"github.com/kr0nicas/picobot/internal/telemetry"
+	"github.com/kr0nicas/picobot/internal/websocket"
+)
+
+var wsLog = logging.For("channels").With("channel", "websocket")
+
+// WebSocketOptions configures the WebSocket channel.
+type WebSocketOptions struct {
+	Listen string // address of the HTTP server (default ":8089")
+	Path   string // endpoint path (default "/v1/ws")
+	Token  string // required; clients send it in their hello
+}
+
+// wsEvent is the JSON envelope of every WebSocket message, in both
+// directions. Clients send "hello", "message" and "button"; the server sends
+// "ready", "message", "token", "tool_call", "tool_result" and "error".
+type wsEvent struct {
+	Type    string          `json:"type"`
+	Token   string          `json:"token,omitempty"`   // hello
+	Session string          `json:"session,omitempty"` // hello (optional, to resume) and ready
+	Content string          `json:"content,omitempty"`
+	Data    string          `json:"data,omitempty"` // button: the pressed button's data
+	Tool    string          `json:"tool,omitempty"`
+	Buttons [][]chat.Button `json:"buttons,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// wsSessionID limits client-chosen session IDs to safe characters.
+var wsSessionID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// StartWebSocket serves a WebSocket endpoint for custom frontends. A client
+// opens a session with {"type":"hello","token":...,"session":...} and gets
+// {"type":"ready","session":...}; the session ID is the chat ID, so a client
+// that reconnects with the same ID continues the conversation. Replies,
+// streamed text and tool activity are pushed to every connection of the
+// session as they happen.
+func StartWebSocket(ctx context.Context, hub *chat.Hub, opts WebSocketOptions) error {
+	if opts.Token == "" {
+		return errors.New("websocket channel: token is required")
+	}
+	if opts.Listen == "" {
+		opts.Listen = ":8089"
+	}
+	if opts.Path == "" {
+		opts.Path = "/v1/ws"
+	}
+	s := newWSChannel(hub, opts.Token)
+
+	mux := http.NewServeMux()
+	mux.Handle(opts.Path, s)
+	srv := &http.Server{Addr: opts.Listen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		wsLog.Info("listening", "listen", opts.Listen+opts.Path)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			wsLog.Error("server failed", "err", err)
+		}
+	}()
+
+	outbox := hub.Subscribe("websocket")
+	events := hub.SubscribeEvents("websocket")
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				wsLog.Info("stopping server")
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				srv.Shutdown(shutdownCtx)
+				cancel()
+				s.closeAll()
+				return
+			case out, ok := <-outbox:
+				if !ok {
+					return
+				}
+				_, span := telemetry.StartRemote(out.Metadata, "send", "channel", "websocket", "chat_id", out.ChatID)
+				s.broadcast(out.ChatID, wsEvent{Type: "message", Session: out.ChatID, Content: out.Content, Buttons: out.Buttons})
+				span.End()
+			case ev := <-events:
+				s.broadcast(ev.ChatID, wsEvent{Type: ev.Type, Session: ev.ChatID, Tool: ev.Tool, Content: ev.Content})
+			}
+		}
+	}()
+	return nil
+}
+
+type wsChannel struct {
+	hub   *chat.Hub
+	token string
+
+	mu    sync.Mutex
+	conns map[string]map[*websocket.Conn]struct{} // session -> connections
+}
+
+func newWSChannel(hub *chat.Hub, token string) *wsChannel {
+	return &wsChannel{hub: hub, token: token, conns: make(map[string]map[*websocket.Conn]struct{})}
+}
+
+func (s *wsChannel) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Accept(w, r)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// handshake: the first message must be a valid hello
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	var hello wsEvent
+	if err := conn.ReadJSON(&hello); err != nil || hello.Type != "hello" {
+		conn.WriteJSON(wsEvent{Type: "error", Error: "expected hello"})
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(hello.Token), []byte(s.token)) != 1 {
+		wsLog.Warn("rejecting connection: bad token", "remote", r.RemoteAddr)
+		conn.WriteJSON(wsEvent{Type: "error", Error: "unauthorized"})
+		return
+	}
+	session := hello.Session
+	if session == "" {
+		b := make([]byte, 8)
+		rand.Read(b)
+		session = hex.EncodeToString(b)
+	} else if !wsSessionID.MatchString(session) {
+		conn.WriteJSON(wsEvent{Type: "error", Error: "invalid session id"})
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	s.mu.Lock()
+	if s.conns[session] == nil {
+		s.conns[session] = make(map[*websocket.Conn]struct{})
+	}
+	s.conns[session][conn] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns[session], conn)
+		if len(s.conns[session]) == 0 {
+			delete(s.conns, session)
+		}
+		s.mu.Unlock()
+	}()
+	if err := conn.WriteJSON(wsEvent{Type: "ready", Session: session}); err != nil {
+		return
+	}
+	wsLog.Info("session connected", "chat_id", session, "remote", r.RemoteAddr)
+
+	for {
+		var ev wsEvent
+		if err := conn.ReadJSON(&ev); err != nil {
+			return
+		}
+		in := chat.Inbound{Channel: "websocket", SenderID: session, ChatID: session, Timestamp: time.Now()}
+		switch ev.Type {
+		case "message":
+			if ev.Content == "" {
+				continue
+			}
+			in.Content = ev.Content
+		case "button":
+			in.Content = ev.Data
+			in.Button = &chat.ButtonPress{Data: ev.Data}
+		default:
+			conn.WriteJSON(wsEvent{Type: "error", Error: "unknown event type " + ev.Type})
+			continue
+		}
+		s.hub.In <- in
+	}
+}
+
+// broadcast sends ev to every connection of session.
+func (s *wsChannel) broadcast(session string, ev wsEvent) {
+	s.mu.Lock()
+	conns := make([]*websocket.Conn, 0, len(s.conns[session]))
+	for c := range s.conns[session] {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+	if len(conns) == 0 && ev.Type == "message" {
+		wsLog.Warn("session is not connected; dropping message", "chat_id", session)
+	}
+	for _, c := range conns {
+		if err := c.WriteJSON(ev); err != nil {
+			c.Close() // the reader notices and unregisters it
+		}
+	}
+}
+
+// closeAll disconnects every client.
+func (s *wsChannel) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conns := range s.conns {
+		for c := range conns {
+			c.Close()
+		}
+	}
+}
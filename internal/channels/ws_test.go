@@ -0,0 +1,70 @@
+package channels
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/websocket"
+)
+
+func TestWebSocketChannel(t *testing.T) {
+	hub := chat.NewHub(10)
+	s := newWSChannel(hub, "tok")
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	dial := func() *websocket.Conn {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		c, err := websocket.Dial(ctx, url, nil)
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		return c
+	}
+
+	bad := dial()
+	bad.WriteJSON(wsEvent{Type: "hello", Token: "wrong"})
+	var ev wsEvent
+	if err := bad.ReadJSON(&ev); err != nil || ev.Type != "error" {
+		t.Fatalf("expected error for bad token, got %+v (%v)", ev, err)
+	}
+	bad.Close()
+
+	c := dial()
+	defer c.Close()
+	c.WriteJSON(wsEvent{Type: "hello", Token: "tok", Session: "ui-1"})
+	if err := c.ReadJSON(&ev); err != nil || ev.Type != "ready" || ev.Session != "ui-1" {
+		t.Fatalf("expected ready, got %+v (%v)", ev, err)
+	}
+
+	c.WriteJSON(wsEvent{Type: "message", Content: "hello"})
+	select {
+	case in := <-hub.In:
+		if in.Channel != "websocket" || in.ChatID != "ui-1" || in.Content != "hello" {
+			t.Fatalf("unexpected inbound: %+v", in)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for inbound")
+	}
+	c.WriteJSON(wsEvent{Type: "button", Data: "answer:1:0"})
+	if in := <-hub.In; in.Button == nil || in.Button.Data != "answer:1:0" {
+		t.Fatalf("expected button press, got %+v", in)
+	}
+
+	s.broadcast("ui-1", wsEvent{Type: chat.EventToolCall, Session: "ui-1", Tool: "web", Content: `{"url":"https://example.com"}`})
+	s.broadcast("ui-1", wsEvent{Type: "message", Session: "ui-1", Content: "done", Buttons: [][]chat.Button{{{Text: "OK", Data: "ok"}}}})
+	for _, want := range []string{chat.EventToolCall, "message"} {
+		if err := c.ReadJSON(&ev); err != nil || ev.Type != want {
+			t.Fatalf("expected %s event, got %+v (%v)", want, ev, err)
+		}
+	}
+	if ev.Content != "done" || len(ev.Buttons) != 1 || ev.Buttons[0][0].Data != "ok" {
+		t.Fatalf("unexpected message event %+v", ev)
+	}
+}
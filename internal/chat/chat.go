@@ -1,6 +1,15 @@
 package chat
 
-import "time"
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/logging"
+)
+
+var hubLog = logging.For("hub")
 
 // Inbound represents an incoming message to the agent.
 type Inbound struct {
@@ -9,8 +18,42 @@ type Inbound struct {
 	ChatID    string
 	Content   string
 	Timestamp time.Time
-	Media     []string
-	Metadata  map[string]interface{}
+	// Media holds local paths of files the user attached. Channels download
+	// them to a temporary location; the agent moves them into the workspace
+	// inbox/ folder and tells the model where they are.
+	Media    []string
+	Metadata map[string]interface{}
+	// Button is set when the message was produced by pressing a button on
+	// an earlier Outbound message; Content then holds the button's Data.
+	Button *ButtonPress
+	// Scheduled is set on messages of a cron job or reminder that fired.
+	// Only the scheduler sets it, never a channel, so clients cannot forge it.
+	Scheduled *ScheduledJob
+	// Internal is set on results the agent hands itself through the hub,
+	// such as a subagent's report. They are not from the user: they don't
+	// answer questions, count against the flood limits or enter the history
+	// as user messages. Only the agent sets it, never a channel.
+	Internal *InternalResult
+}
+
+// InternalResult describes the tool call an internal message is recorded as
+// the result of.
+type InternalResult struct {
+	CallID string
+	Tool   string
+	Args   map[string]interface{}
+}
+
+// ScheduledJob describes the job a scheduled message comes from.
+type ScheduledJob struct {
+	ID   string
+	Role string // role of the sender who scheduled it
+}
+
+// ButtonPress describes a pressed reply button.
+type ButtonPress struct {
+	Data      string // Data of the pressed Button
+	MessageID string // channel message the button was attached to, if known
 }
 
 // Outbound represents a message produced by the agent.
@@ -19,21 +62,202 @@ type Outbound struct {
 	ChatID   string
 	Content  string
 	ReplyTo  string
-	Media    []string
 	Metadata map[string]interface{}
+	// Attachments are files sent after Content. Channels without file
+	// support ignore them.
+	Attachments []Attachment
+	// Buttons are optional reply choices shown under the message (rows of
+	// buttons). Channels without button support ignore them, so Content
+	// must always explain how to answer in text.
+	Buttons [][]Button
+}
+
+// Button is a reply choice. Pressing it sends Data back as an Inbound
+// message from the same chat, with Inbound.Button set.
+type Button struct {
+	Text string `json:"text"`
+	Data string `json:"data"`
+}
+
+// Attachment is a file sent with an Outbound message, either a local file
+// (Path) or an in-memory payload (Data).
+type Attachment struct {
+	Name string // file name shown to the user; defaults to the base of Path
+	Path string
+	Data []byte // used when Path is empty
+}
+
+// FileName returns the name to show for the attachment.
+func (a Attachment) FileName() string {
+	if a.Name != "" {
+		return a.Name
+	}
+	if a.Path != "" {
+		return filepath.Base(a.Path)
+	}
+	return "file"
+}
+
+// Bytes returns the attachment's contents.
+func (a Attachment) Bytes() ([]byte, error) {
+	if a.Path != "" {
+		return os.ReadFile(a.Path)
+	}
+	return a.Data, nil
+}
+
+// Event types.
+const (
+	EventToolCall   = "tool_call"   // Content holds the arguments as JSON
+	EventToolResult = "tool_result" // Content holds the result text
+	EventToken      = "token"       // Content holds a fragment of the reply being generated
+)
+
+// Event is live progress of a reply — tool calls and streamed text — for
+// channels that can show it as it happens. The final reply is still sent
+// as an Outbound message.
+type Event struct {
+	Channel string
+	ChatID  string
+	Type    string
+	Tool    string // tool name, for tool events
+	Content string
 }
 
-// Hub provides simple buffered channels for inbound/outbound messages.
+// OutboundFilter rewrites the text of outbound messages and events before
+// they reach a channel adapter, e.g. to redact secrets.
+type OutboundFilter interface {
+	FilterOutbound(channel, chatID, text string) string
+}
+
+// OutboundFilterFunc adapts a function to OutboundFilter.
+type OutboundFilterFunc func(channel, chatID, text string) string
+
+// FilterOutbound calls f.
+func (f OutboundFilterFunc) FilterOutbound(channel, chatID, text string) string {
+	return f(channel, chatID, text)
+}
+
+// Hub provides buffered channels for inbound/outbound messages. Any number
+// of channel adapters can run at once: all of them send to In, and each
+// calls Subscribe with its name to receive the Outbound messages addressed
+// to it. Out is read only by the hub's router once anyone has subscribed.
 type Hub struct {
 	In  chan Inbound
 	Out chan Outbound
+
+	buffer  int
+	mu      sync.Mutex
+	subs    map[string]chan Outbound
+	events  map[string]chan Event
+	filters []OutboundFilter
+	route   sync.Once
 }
 
 // NewHub constructs a new Hub with the given buffer size.
 func NewHub(buffer int) *Hub {
 	return &Hub{
-		In:  make(chan Inbound, buffer),
-		Out: make(chan Outbound, buffer),
+		In:     make(chan Inbound, buffer),
+		Out:    make(chan Outbound, buffer),
+		buffer: buffer,
+		subs:   make(map[string]chan Outbound),
+		events: make(map[string]chan Event),
+	}
+}
+
+// AddFilter adds f to the filters that outbound messages and events pass
+// through, in the order they were added, on their way to the adapters.
+func (h *Hub) AddFilter(f OutboundFilter) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.filters = append(h.filters, f)
+}
+
+// filter runs text addressed to a chat through the filters.
+func (h *Hub) filter(channel, chatID, text string) string {
+	h.mu.Lock()
+	filters := h.filters
+	h.mu.Unlock()
+	for _, f := range filters {
+		text = f.FilterOutbound(channel, chatID, text)
+	}
+	return text
+}
+
+// SubscribeEvents returns the events emitted for channel.
+func (h *Hub) SubscribeEvents(channel string) <-chan Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ch, ok := h.events[channel]
+	if !ok {
+		ch = make(chan Event, 256)
+		h.events[channel] = ch
+	}
+	return ch
+}
+
+// Emit publishes ev to the subscriber of its channel, if any. Events are
+// best effort: they are dropped when nobody listens or the subscriber is
+// behind.
+func (h *Hub) Emit(ev Event) {
+	h.mu.Lock()
+	ch, ok := h.events[ev.Channel]
+	h.mu.Unlock()
+	if !ok {
+		return
+	}
+	ev.Content = h.filter(ev.Channel, ev.ChatID, ev.Content)
+	select {
+	case ch <- ev:
+	default:
+	}
+}
+
+// Subscribe returns the outbound messages addressed to channel. The first
+// call starts routing: from then on Out must only be read by the hub.
+// Each channel name should have one subscriber; later calls for the same
+// name share its queue. The returned channel is closed when the hub is closed.
+func (h *Hub) Subscribe(channel string) <-chan Outbound {
+	h.mu.Lock()
+	ch, ok := h.subs[channel]
+	if !ok {
+		ch = make(chan Outbound, max(h.buffer, 16))
+		h.subs[channel] = ch
+	} else {
+		hubLog.Warn("channel subscribed twice; its messages are split between subscribers", "channel", channel)
+	}
+	h.mu.Unlock()
+	h.route.Do(func() { go h.routeOutbound() })
+	return ch
+}
+
+// routeOutbound delivers messages from Out to the subscriber of their channel.
+// A subscriber that falls a full buffer behind loses messages rather than
+// stalling the other channels.
+func (h *Hub) routeOutbound() {
+	unrouted := make(map[string]bool)
+	for out := range h.Out {
+		h.mu.Lock()
+		ch, ok := h.subs[out.Channel]
+		h.mu.Unlock()
+		if !ok {
+			if !unrouted[out.Channel] {
+				unrouted[out.Channel] = true
+				hubLog.Warn("no adapter for channel, dropping its outbound messages", "channel", out.Channel)
+			}
+			continue
+		}
+		out.Content = h.filter(out.Channel, out.ChatID, out.Content)
+		select {
+		case ch <- out:
+		default:
+			hubLog.Warn("adapter is not keeping up, dropping message", "channel", out.Channel, "chat_id", out.ChatID)
+		}
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, ch := range h.subs {
+		close(ch)
 	}
 }
 
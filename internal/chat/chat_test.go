@@ -0,0 +1,89 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHubRoutesByChannel(t *testing.T) {
+	h := NewHub(4)
+	tg := h.Subscribe("telegram")
+	dc := h.Subscribe("discord")
+
+	h.Out <- Outbound{Channel: "nobody", ChatID: "x", Content: "dropped"}
+	h.Out <- Outbound{Channel: "discord", ChatID: "d1", Content: "to discord"}
+	h.Out <- Outbound{Channel: "telegram", ChatID: "t1", Content: "to telegram"}
+
+	for name, ch := range map[string]<-chan Outbound{"telegram": tg, "discord": dc} {
+		select {
+		case out := <-ch:
+			if out.Channel != name {
+				t.Fatalf("%s subscriber got a message for %s", name, out.Channel)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for %s message", name)
+		}
+	}
+}
+
+func TestHubSlowSubscriberDoesNotBlockOthers(t *testing.T) {
+	h := NewHub(1)
+	h.Subscribe("stuck") // never read
+	fast := h.Subscribe("fast")
+
+	go func() {
+		for i := 0; i < 50; i++ {
+			h.Out <- Outbound{Channel: "stuck", ChatID: "s"}
+		}
+		h.Out <- Outbound{Channel: "fast", ChatID: "f", Content: "hello"}
+	}()
+	select {
+	case out := <-fast:
+		if out.Content != "hello" {
+			t.Fatalf("unexpected message %+v", out)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("a stuck subscriber blocked routing to another channel")
+	}
+}
+
+func TestHubCloseClosesSubscribers(t *testing.T) {
+	h := NewHub(1)
+	ch := h.Subscribe("telegram")
+	h.Close()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Fatal("expected closed subscriber channel")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("subscriber channel not closed")
+	}
+}
+
+func TestHubFiltersOutbound(t *testing.T) {
+	h := NewHub(4)
+	h.AddFilter(OutboundFilterFunc(func(channel, chatID, text string) string {
+		return strings.ReplaceAll(text, "hunter2", "***")
+	}))
+	h.AddFilter(OutboundFilterFunc(func(channel, chatID, text string) string {
+		return channel + ":" + chatID + " " + text
+	}))
+	tg := h.Subscribe("telegram")
+	events := h.SubscribeEvents("telegram")
+
+	h.Emit(Event{Channel: "telegram", ChatID: "1", Type: EventToolResult, Content: "password hunter2"})
+	h.Out <- Outbound{Channel: "telegram", ChatID: "1", Content: "it is hunter2"}
+	if ev := <-events; ev.Content != "telegram:1 password ***" {
+		t.Fatalf("event not filtered: %q", ev.Content)
+	}
+	select {
+	case out := <-tg:
+		if out.Content != "telegram:1 it is ***" {
+			t.Fatalf("message not filtered: %q", out.Content)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for message")
+	}
+}
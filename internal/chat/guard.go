@@ -0,0 +1,109 @@
+package chat
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// GuardConfig sets the inbound abuse limits. Zero values use the defaults.
+type GuardConfig struct {
+	MaxMessageLen   int           // longer messages are truncated (default 8000 bytes)
+	MaxPerMinute    int           // messages per sender per minute before muting (default 20)
+	DuplicateWindow time.Duration // identical text messages within this window are dropped (default 10s)
+	MuteFor         time.Duration // how long a flooding sender is ignored (default 10m)
+}
+
+// Verdict is the outcome of checking an inbound message.
+type Verdict int
+
+const (
+	Accept        Verdict = iota // process the message
+	DropDuplicate                // same content as the sender's previous message, just now
+	DropMuted                    // sender is muted
+	MuteStarted                  // sender just exceeded the rate limit and is now muted
+)
+
+type senderState struct {
+	recent     []time.Time
+	lastText   string
+	lastAt     time.Time
+	mutedUntil time.Time
+}
+
+// Guard protects the agent from floods and abusive senders: it truncates
+// oversized messages, collapses duplicates and temporarily mutes senders
+// who exceed the per-minute rate. It is safe for concurrent use.
+type Guard struct {
+	cfg     GuardConfig
+	mu      sync.Mutex
+	senders map[string]*senderState
+	now     func() time.Time
+}
+
+// NewGuard creates a Guard, filling in defaults for unset limits.
+func NewGuard(cfg GuardConfig) *Guard {
+	if cfg.MaxMessageLen <= 0 {
+		cfg.MaxMessageLen = 8000
+	}
+	if cfg.MaxPerMinute <= 0 {
+		cfg.MaxPerMinute = 20
+	}
+	if cfg.DuplicateWindow <= 0 {
+		cfg.DuplicateWindow = 10 * time.Second
+	}
+	if cfg.MuteFor <= 0 {
+		cfg.MuteFor = 10 * time.Minute
+	}
+	return &Guard{cfg: cfg, senders: make(map[string]*senderState), now: time.Now}
+}
+
+// MuteFor returns the configured mute duration.
+func (g *Guard) MuteFor() time.Duration { return g.cfg.MuteFor }
+
+// Check applies the limits to msg, truncating its content in place if it is
+// too long. Internal channels (cli, heartbeat), cron and internal results are
+// never limited.
+func (g *Guard) Check(msg *Inbound) Verdict {
+	if msg.Channel == "cli" || msg.Channel == "heartbeat" || msg.Scheduled != nil || msg.Internal != nil {
+		return Accept
+	}
+	if len(msg.Content) > g.cfg.MaxMessageLen {
+		msg.Content = msg.Content[:g.cfg.MaxMessageLen] + fmt.Sprintf("\n[message truncated to %d bytes]", g.cfg.MaxMessageLen)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := g.now()
+	key := msg.Channel + ":" + msg.SenderID
+	s, ok := g.senders[key]
+	if !ok {
+		s = &senderState{}
+		g.senders[key] = s
+	}
+	if now.Before(s.mutedUntil) {
+		return DropMuted
+	}
+
+	// sliding one-minute window
+	cutoff := now.Add(-time.Minute)
+	kept := s.recent[:0]
+	for _, t := range s.recent {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	s.recent = append(kept, now)
+	if len(s.recent) > g.cfg.MaxPerMinute {
+		s.mutedUntil = now.Add(g.cfg.MuteFor)
+		s.recent = nil
+		return MuteStarted
+	}
+
+	if len(msg.Media) == 0 && msg.Content == s.lastText && now.Sub(s.lastAt) < g.cfg.DuplicateWindow {
+		s.lastAt = now
+		return DropDuplicate
+	}
+	s.lastText, s.lastAt = msg.Content, now
+	return Accept
+}
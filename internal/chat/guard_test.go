@@ -0,0 +1,44 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGuardLimits(t *testing.T) {
+	g := NewGuard(GuardConfig{MaxMessageLen: 10, MaxPerMinute: 3, MuteFor: time.Minute})
+	clock := time.Unix(0, 0)
+	g.now = func() time.Time { return clock }
+	msg := func(text string) *Inbound {
+		return &Inbound{Channel: "telegram", SenderID: "1", ChatID: "1", Content: text}
+	}
+
+	long := msg("0123456789abcdef")
+	if v := g.Check(long); v != Accept || !strings.HasPrefix(long.Content, "0123456789\n[message truncated") {
+		t.Fatalf("expected truncation, got %v %q", v, long.Content)
+	}
+	clock = clock.Add(time.Second)
+	if v := g.Check(msg("0123456789abcdef")); v != DropDuplicate {
+		t.Fatalf("expected duplicate to be dropped, got %v", v)
+	}
+	clock = clock.Add(time.Second)
+	g.Check(msg("b"))
+	clock = clock.Add(time.Second)
+	if v := g.Check(msg("c")); v != MuteStarted {
+		t.Fatalf("expected mute after exceeding rate, got %v", v)
+	}
+	if v := g.Check(msg("d")); v != DropMuted {
+		t.Fatalf("expected muted sender to be dropped, got %v", v)
+	}
+	if v := g.Check(&Inbound{Channel: "cli", SenderID: "1", Content: "d"}); v != Accept {
+		t.Fatalf("cli must never be limited")
+	}
+	if v := g.Check(&Inbound{Channel: "telegram", SenderID: "1", ChatID: "1", Content: "d", Internal: &InternalResult{}}); v != Accept {
+		t.Fatalf("internal results must never be limited, got %v", v)
+	}
+	clock = clock.Add(2 * time.Minute)
+	if v := g.Check(msg("e")); v != Accept {
+		t.Fatalf("expected mute to expire, got %v", v)
+	}
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/zalando/go-keyring"
+)
+
+// Config values like "keyring:<name>" are read from the OS keyring (macOS
+// Keychain, Windows Credential Manager or the Secret Service on Linux), from
+// the entry <name> of the service "picobot".
+const (
+	keyringPrefix  = "keyring:"
+	keyringService = "picobot"
+)
+
+// IsKeyringRef reports whether v refers to a value in the OS keyring.
+func IsKeyringRef(v string) bool {
+	return strings.HasPrefix(v, keyringPrefix)
+}
+
+// KeyringRef returns the config value referring to the keyring entry name.
+func KeyringRef(name string) string {
+	return keyringPrefix + name
+}
+
+// KeyringGet returns the value of the keyring entry name.
+func KeyringGet(name string) (string, error) {
+	v, err := keyring.Get(keyringService, name)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", fmt.Errorf("no entry %q in the OS keyring (store it with \"picobot secrets keyring-set %s\")", name, name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("read %q from the OS keyring: %w", name, err)
+	}
+	return v, nil
+}
+
+// KeyringSet stores value in the keyring entry name.
+func KeyringSet(name, value string) error {
+	if err := keyring.Set(keyringService, name, value); err != nil {
+		return fmt.Errorf("config: store %q in the OS keyring: %w", name, err)
+	}
+	return nil
+}
+
+// StoreSecretsInKeyring moves every plaintext credential field in c to the
+// OS keyring, under its key path, and replaces it with a reference. It
+// returns how many fields were moved.
+func StoreSecretsInKeyring(c *Config) (int, error) {
+	n := 0
+	for _, sf := range secretFields(c) {
+		f := sf.get()
+		if f == "" || IsEncrypted(f) || IsKeyringRef(f) {
+			continue
+		}
+		if err := KeyringSet(sf.path, f); err != nil {
+			return n, err
+		}
+		sf.set(KeyringRef(sf.path))
+		n++
+	}
+	return n, nil
+}
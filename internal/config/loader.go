@@ -2,51 +2,106 @@ package config
 
 import (
 	"encoding/json"
-	"log"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kr0nicas/picobot/internal/logging"
 )
 
-// LoadConfig loads config from ~/.picobot/config.json (or PICOBOT_HOME) if present,
-// then overrides sensitive fields with environment variables if set.
-func LoadConfig() (Config, error) {
-	var path string
-	if ph := os.Getenv("PICOBOT_HOME"); ph != "" {
-		path = filepath.Join(ph, "config.json")
-	} else {
+// configNames are the config files looked for in the picobot home, in order.
+var configNames = []string{"config.json", "config.yaml", "config.yml", "config.toml"}
+
+// ConfigPath returns the config file in PICOBOT_HOME or ~/.picobot: the first
+// of config.json, config.yaml, config.yml and config.toml that exists, else
+// config.json.
+func ConfigPath() string {
+	dir := os.Getenv("PICOBOT_HOME")
+	if dir == "" {
 		home, err := os.UserHomeDir()
 		if err != nil {
 			home = "."
 		}
-		path = filepath.Join(home, ".picobot", "config.json")
+		dir = filepath.Join(home, ".picobot")
+	}
+	for _, name := range configNames {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return filepath.Join(dir, name)
+		}
 	}
+	return filepath.Join(dir, configNames[0])
+}
 
+// LoadConfig loads the config file of ConfigPath if present, then overrides
+// sensitive fields with environment variables if set.
+func LoadConfig() (Config, error) {
+	return LoadConfigFile(ConfigPath())
+}
+
+// LoadConfigFile loads the config at path with the active profile applied,
+// see LoadProfile.
+func LoadConfigFile(path string) (Config, error) {
+	return LoadProfile(path, ActiveProfile())
+}
+
+// LoadProfile loads the config at path, JSON, YAML or TOML by its
+// extension, if present, applies the named profile unless name is empty,
+// then overrides sensitive fields with environment variables if set. If the
+// config has problems (unknown keys, missing or out of range values), it is
+// returned with a *ValidationError listing them.
+func LoadProfile(path, name string) (Config, error) {
 	var cfg Config
-	f, err := os.Open(path)
+	var problems []Problem
+	data, err := os.ReadFile(path)
+	if err != nil && name != "" {
+		return Config{}, fmt.Errorf("config: no profile %q: %w", name, err)
+	}
 	if err == nil {
-		dec := json.NewDecoder(f)
-		if err := dec.Decode(&cfg); err != nil {
-			f.Close()
+		raw, err := decodeFile(path, data)
+		if err != nil {
 			return Config{}, err
 		}
-		f.Close()
+		problems = append(unknownKeys(raw, reflect.TypeOf(cfg), ""), profileProblems(raw)...)
+		if name != "" {
+			if raw, err = applyProfile(raw, name); err != nil {
+				return Config{}, fmt.Errorf("config: %s: %w", path, err)
+			}
+		}
+		b, err := json.Marshal(raw)
+		if err == nil {
+			err = json.Unmarshal(b, &cfg)
+		}
+		if err != nil {
+			return Config{}, fmt.Errorf("config: %s: %w", path, err)
+		}
 	}
-
-	// Environment variable overrides for security and docker flexibility (Supports GIO_ and PICOBOT_ prefixes)
-	// LLM API Key
-	llmKey := strings.TrimSpace(os.Getenv("GIO_LLM_API_KEY"))
-	if llmKey == "" {
-		llmKey = strings.TrimSpace(os.Getenv("PICOBOT_LLM_API_KEY"))
+	if err := resolveSecrets(&cfg); err != nil {
+		return Config{}, err
 	}
-	if llmKey == "" {
-		llmKey = strings.TrimSpace(os.Getenv("OPENAI_API_KEY"))
+
+	// Environment variable overrides for security and docker flexibility (Supports GIO_ and PICOBOT_ prefixes).
+	// Credentials can also be given as files, Docker secrets style, with a _FILE suffix.
+	var envErr error
+	secret := func(keys ...string) string {
+		v, err := envSecret(keys...)
+		if err != nil && envErr == nil {
+			envErr = err
+		}
+		return v
 	}
+	// LLM API Key
+	llmKey := secret("GIO_LLM_API_KEY", "PICOBOT_LLM_API_KEY", "OPENAI_API_KEY")
 
 	if llmKey != "" {
 		if strings.HasSuffix(llmKey, "...") {
-			log.Printf("WARNING: LLM API Key seems to be truncated (ends with '...')")
+			logging.For("config").Warn("LLM API key seems to be truncated (ends with '...')")
 		}
 		if cfg.Providers.OpenAI == nil {
 			cfg.Providers.OpenAI = &ProviderConfig{}
@@ -55,13 +110,7 @@ func LoadConfig() (Config, error) {
 	}
 
 	// Anthropic API Key
-	anthropicKey := strings.TrimSpace(os.Getenv("GIO_ANTHROPIC_API_KEY"))
-	if anthropicKey == "" {
-		anthropicKey = strings.TrimSpace(os.Getenv("PICOBOT_ANTHROPIC_API_KEY"))
-	}
-	if anthropicKey == "" {
-		anthropicKey = strings.TrimSpace(os.Getenv("ANTHROPIC_API_KEY"))
-	}
+	anthropicKey := secret("GIO_ANTHROPIC_API_KEY", "PICOBOT_ANTHROPIC_API_KEY", "ANTHROPIC_API_KEY")
 	if anthropicKey != "" {
 		if cfg.Providers.Anthropic == nil {
 			cfg.Providers.Anthropic = &ProviderConfig{}
@@ -69,6 +118,20 @@ func LoadConfig() (Config, error) {
 		cfg.Providers.Anthropic.APIKey = anthropicKey
 	}
 
+	// Groq and Mistral API Keys
+	if key := secret("GROQ_API_KEY"); key != "" {
+		if cfg.Providers.Groq == nil {
+			cfg.Providers.Groq = &ProviderConfig{}
+		}
+		cfg.Providers.Groq.APIKey = key
+	}
+	if key := secret("MISTRAL_API_KEY"); key != "" {
+		if cfg.Providers.Mistral == nil {
+			cfg.Providers.Mistral = &ProviderConfig{}
+		}
+		cfg.Providers.Mistral.APIKey = key
+	}
+
 	// LLM API Base (for Google Gemini or local Ollama)
 	llmBase := strings.TrimSpace(os.Getenv("GIO_LLM_API_BASE"))
 	if llmBase == "" {
@@ -109,18 +172,56 @@ func LoadConfig() (Config, error) {
 	}
 
 	// Telegram
-	token := strings.TrimSpace(os.Getenv("GIO_TELEGRAM_TOKEN"))
-	if token == "" {
-		token = strings.TrimSpace(os.Getenv("PICOBOT_TELEGRAM_TOKEN"))
-	}
-	if token == "" {
-		token = strings.TrimSpace(os.Getenv("PICOBOT_GATEWAY_TELEGRAM_TOKEN"))
-	}
+	token := secret("GIO_TELEGRAM_TOKEN", "PICOBOT_TELEGRAM_TOKEN", "PICOBOT_GATEWAY_TELEGRAM_TOKEN")
 	if token != "" {
 		cfg.Channels.Telegram.Token = token
 		cfg.Channels.Telegram.Enabled = true // Auto-enable if token is provided via ENV
 	}
 
+	// Discord
+	discordToken := secret("GIO_DISCORD_TOKEN", "PICOBOT_DISCORD_TOKEN")
+	if discordToken != "" {
+		cfg.Channels.Discord.Token = discordToken
+		cfg.Channels.Discord.Enabled = true
+	}
+
+	// Slack
+	slackBot := secret("PICOBOT_SLACK_BOT_TOKEN")
+	slackApp := secret("PICOBOT_SLACK_APP_TOKEN")
+	if slackBot != "" {
+		cfg.Channels.Slack.BotToken = slackBot
+	}
+	if slackApp != "" {
+		cfg.Channels.Slack.AppToken = slackApp
+	}
+	if slackBot != "" && slackApp != "" {
+		cfg.Channels.Slack.Enabled = true
+	}
+
+	// Email
+	if pw := secret("PICOBOT_EMAIL_PASSWORD"); pw != "" {
+		cfg.Channels.Email.Password = pw
+	}
+
+	// HTTP channel
+	if tok := secret("PICOBOT_HTTP_TOKEN"); tok != "" {
+		cfg.Channels.HTTP.Token = tok
+	}
+
+	// WebSocket channel
+	if tok := secret("PICOBOT_WEBSOCKET_TOKEN"); tok != "" {
+		cfg.Channels.WebSocket.Token = tok
+	}
+
+	// Search
+	if key := secret("PICOBOT_SEARCH_API_KEY"); key != "" {
+		cfg.Tools.Search.APIKey = key
+	}
+
+	if envErr != nil {
+		return Config{}, envErr
+	}
+
 	// Allowed Users
 	allowed := strings.TrimSpace(os.Getenv("GIO_TELEGRAM_ALLOWED_USERS"))
 	if allowed == "" {
@@ -144,6 +245,15 @@ func LoadConfig() (Config, error) {
 		cfg.Agents.Defaults.RequestTimeoutS = v
 	}
 
+	// Debug tracing
+	if v := strings.TrimSpace(os.Getenv("GIO_DEBUG")); v != "" {
+		cfg.Agents.Defaults.Debug, _ = strconv.ParseBool(v)
+	} else if v := strings.TrimSpace(os.Getenv("PICOBOT_DEBUG")); v != "" {
+		cfg.Agents.Defaults.Debug, _ = strconv.ParseBool(v)
+	}
+
+	problems = append(problems, Validate(cfg)...)
+
 	// Apply sensible defaults for fields not overridable by env vars
 	if cfg.Agents.Defaults.MaxTokens <= 0 {
 		cfg.Agents.Defaults.MaxTokens = 8192
@@ -157,13 +267,118 @@ func LoadConfig() (Config, error) {
 	if cfg.Agents.Defaults.RequestTimeoutS <= 0 {
 		cfg.Agents.Defaults.RequestTimeoutS = 90
 	}
-	if cfg.Agents.Defaults.Temperature <= 0 {
-		cfg.Agents.Defaults.Temperature = 0.7
+	if cfg.Agents.Defaults.Temperature == nil {
+		t := 0.7
+		cfg.Agents.Defaults.Temperature = &t
+	}
+	cfg.Agents.Defaults.Workspace = ExpandHome(cfg.Agents.Defaults.Workspace)
+	for name, na := range cfg.Agents.Named {
+		na.Workspace = ExpandHome(na.Workspace)
+		cfg.Agents.Named[name] = na
 	}
 
+	if len(problems) > 0 {
+		return cfg, &ValidationError{File: path, Problems: problems}
+	}
 	return cfg, nil
 }
 
+// decodeFile parses a config file into generic maps, which keeps the key
+// names of every format the same as in JSON.
+func decodeFile(path string, data []byte) (map[string]any, error) {
+	var raw map[string]any
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		err = json.Unmarshal(data, &raw)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config: %s: %w", path, err)
+	}
+	if raw == nil {
+		raw = make(map[string]any)
+	}
+	return normalize(raw).(map[string]any), nil
+}
+
+// normalize converts the maps and lists YAML and TOML decode to
+// map[string]any and []any, as JSON has them.
+func normalize(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			v[k] = normalize(e)
+		}
+		return v
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = normalize(e)
+		}
+		return m
+	case []any:
+		for i, e := range v {
+			v[i] = normalize(e)
+		}
+		return v
+	case []map[string]any:
+		l := make([]any, len(v))
+		for i, e := range v {
+			l[i] = normalize(e)
+		}
+		return l
+	case time.Time:
+		// unquoted TOML times such as consolidateAt = 03:30
+		if v.Year() == 0 {
+			return v.Format("15:04")
+		}
+		return v.Format(time.RFC3339)
+	}
+	return v
+}
+
+// ExpandHome expands a leading "~" to the user's home directory and converts
+// the path to the platform's separators, so configs written as
+// "~/.picobot/workspace" work on Windows as well as Unix.
+func ExpandHome(p string) string {
+	if p == "" {
+		return p
+	}
+	if p == "~" || strings.HasPrefix(p, "~/") || strings.HasPrefix(p, `~\`) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return filepath.FromSlash(p)
+		}
+		p = filepath.Join(home, filepath.FromSlash(p[1:]))
+	}
+	return filepath.Clean(filepath.FromSlash(p))
+}
+
+// envSecret returns the first of keys set in the environment, either
+// directly or as the path of a file holding the value in KEY_FILE (as with
+// Docker secrets).
+func envSecret(keys ...string) (string, error) {
+	for _, k := range keys {
+		if v := strings.TrimSpace(os.Getenv(k)); v != "" {
+			return v, nil
+		}
+		if p := strings.TrimSpace(os.Getenv(k + "_FILE")); p != "" {
+			b, err := os.ReadFile(p)
+			if err != nil {
+				return "", fmt.Errorf("config: read %s_FILE: %w", k, err)
+			}
+			if v := strings.TrimSpace(string(b)); v != "" {
+				return v, nil
+			}
+		}
+	}
+	return "", nil
+}
+
 // envInt returns the first non-empty env var parsed as int, or 0.
 func envInt(keys ...string) int {
 	for _, k := range keys {
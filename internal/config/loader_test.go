@@ -0,0 +1,46 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExpandHome(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	cases := map[string]string{
+		"~":                    home,
+		"~/.picobot/workspace": filepath.Join(home, ".picobot", "workspace"),
+		"relative/dir":         filepath.Join("relative", "dir"),
+		"":                     "",
+	}
+	for in, want := range cases {
+		if got := ExpandHome(in); got != want {
+			t.Errorf("ExpandHome(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestLoadConfigTemperature(t *testing.T) {
+	home := clearEnv(t)
+	path := filepath.Join(home, "config.json")
+	for data, want := range map[string]float64{
+		`{"agents": {"defaults": {"temperature": 0}}}`:   0,
+		`{"agents": {"defaults": {"temperature": 1.2}}}`: 1.2,
+		`{"agents": {"defaults": {}}}`:                   0.7,
+	} {
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := LoadConfig()
+		if err != nil {
+			t.Fatalf("LoadConfig(%s): %v", data, err)
+		}
+		if got := cfg.Agents.Defaults.Temperature; got == nil || *got != want {
+			t.Errorf("LoadConfig(%s): temperature = %v, want %v", data, got, want)
+		}
+	}
+}
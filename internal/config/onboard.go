@@ -1,7 +1,10 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
@@ -12,12 +15,13 @@ import (
 
 // DefaultConfig returns a minimal default Config with sensible defaults.
 func DefaultConfig() Config {
+	temperature := 0.7
 	return Config{
 		Agents: AgentsConfig{Defaults: AgentDefaults{
 			Workspace:          "~/.picobot/workspace",
 			Model:              "stub-model",
 			MaxTokens:          8192,
-			Temperature:        0.7,
+			Temperature:        &temperature,
 			MaxToolIterations:  100,
 			HeartbeatIntervalS: 300,
 			RequestTimeoutS:    60,
@@ -41,6 +45,13 @@ func SaveConfig(cfg Config, path string) error {
 	return os.WriteFile(path, b, 0o640)
 }
 
+// TenantsDir returns the directory holding the per-chat workspaces of
+// multi-tenant mode for workspace. It lies next to the workspace rather than
+// in it, so chats served from the workspace cannot reach the tenants' files.
+func TenantsDir(workspace string) string {
+	return filepath.Clean(workspace) + "-tenants"
+}
+
 // InitializeWorkspace creates the workspace dir and bootstrap files.
 func InitializeWorkspace(basePath string) error {
 	if err := os.MkdirAll(basePath, 0o755); err != nil {
@@ -184,15 +195,32 @@ This document describes the tools available to Gio.
 ## File Operations
 
 ### filesystem
-Read, write, and list files in the workspace.
-- action: "read", "write", "list"
+Read, write, list, edit and search files in the workspace.
+- action: "read", "write", "list", "edit", "append", "delete", "move", "mkdir", "glob", "grep"
 - path: file or directory path (relative to workspace)
-- content: (for "write" action) the content to write
+- content: (for "write"/"append", or "edit" with start_line) the content
+- old_string / new_string / replace_all: (for "edit") exact text to replace
+- start_line / end_line: (for "edit") line range replaced by content; (for "read") the lines to return, e.g. of a long saved tool result
+- to: (for "move") destination path
+- recursive: (for "delete") remove a directory with its contents
+- pattern: (for "glob") e.g. "**/*.py"; (for "grep") a regular expression
+- include: (for "grep") only search files matching this glob
+- encoding: "base64" to read or write binary files (images, zips, sqlite databases); reads then report the MIME type and size
+
+To change part of a file use "edit" instead of rewriting it with "write".
 
 Examples:
 - Read: {"action": "read", "path": "data.csv"}
 - Write: {"action": "write", "path": "data.csv", "content": "Name\nBen\nKen\n"}
 - List: {"action": "list", "path": "."}
+- Edit: {"action": "edit", "path": "app.py", "old_string": "DEBUG = True", "new_string": "DEBUG = False"}
+- Edit lines: {"action": "edit", "path": "notes.md", "start_line": 3, "end_line": 4, "content": "new line 3\n"}
+- Append: {"action": "append", "path": "log.txt", "content": "done\n"}
+- Move: {"action": "move", "path": "draft.md", "to": "project-blog/post.md"}
+- Glob: {"action": "glob", "pattern": "**/*.py"}
+- Grep: {"action": "grep", "pattern": "TODO", "include": "*.py"}
+- Read binary: {"action": "read", "path": "chart.png", "encoding": "base64"}
+- Read lines: {"action": "read", "path": "build.log", "start_line": 200, "end_line": 260}
 
 ## Shell Execution
 
@@ -201,6 +229,7 @@ Execute commands safely (array form only).
 - cmd: array of strings ["program", "arg1", "arg2", ...]
 - String form is NOT allowed — always use arrays.
 - Timeout: 60 seconds.
+- skill: optional skill name; script paths like scripts/fetch.py resolve in that skill's folder, and python runs with the skill's dependencies installed
 
 **Security rules you MUST follow:**
 - Blocked programs: rm, sudo, dd, mkfs, shutdown, reboot, bash, sh, zsh, nc, netcat, nmap
@@ -228,6 +257,28 @@ Fetch and extract content from a URL.
 - url: the URL to fetch
 - Useful for checking websites, APIs, documentation
 
+### search
+Search the web when you don't know the URL.
+- query: what to search for
+- count: number of results (default 5)
+- Returns title, url and snippet per result; read a result with web
+
+### feeds
+Watch RSS/Atom feeds.
+- action: "subscribe", "unsubscribe", "list" or "fetch_new"
+- url: the feed URL (subscribe)
+- name: short name of the subscription
+- After subscribing, new posts are announced in the current chat automatically; no heartbeat task is needed
+
+## Calendar
+
+### calendar
+Read and change the user's calendar (only available when configured).
+- action: "list_events", "create_event" or "delete_event"
+- from, to: range for list_events (default the next 7 days)
+- title, start, end, location, description: the event (create_event); times like 2026-03-02T09:30 are local, a date makes an all-day event
+- id: the event to delete, as shown by list_events
+
 ## Messaging
 
 ### message
@@ -241,6 +292,46 @@ Persist information to memory files.
 - target: "today" (daily notes) or "long" (long-term memory)
 - content: what to remember
 - append: true to add, false to replace
+- tags: optional labels to find the memory by later
+- until: optional date (YYYY-MM-DD) after which the memory is forgotten, for things that only matter for a while
+
+### search_memory
+Search daily notes (including older days) and long-term memory.
+Use it to look things up that aren't in your prompt, e.g. before saying you don't know something about the user.
+- query: words the memory contains, in any order
+- tag: a tag given with write_memory
+- since, until: optional dates (YYYY-MM-DD)
+- kind: optional, "short" (daily notes) or "long" to search only one of them
+- limit: optional maximum results (default 20)
+
+### forget
+Delete memories the user asks you to forget, from the notes and long-term memory. The user confirms the matches first.
+- query: text the memories contain
+
+### export_memory
+Back up long-term memory, the daily notes and the memory database as one .tar.gz in the workspace.
+- path: optional destination (default exports/memory-<date>.tar.gz)
+
+### import_memory
+Merge a backup made by export_memory into the memories; what is already there is kept. Asks the user for approval.
+- path: workspace path of the backup
+
+### report_progress
+During a long task, say which step you are on. The latest report is shown to the user in the progress updates of tasks that take more than a few steps.
+- status: what you are doing now, e.g. "installing dependencies"
+- step, total: optional step number and expected number of steps
+
+### update_plan
+Only offered while you work through a plan the user approved. Call it after each step.
+- step: the step's number
+- status: "done" or "skipped"
+- note: optional outcome, or why the step was skipped
+
+### scratchpad
+Keep intermediate results while working on a task, without writing them to memory.
+- action: "set", "get", "append", "list" or "delete"
+- key: the note's name; value: the text (set, append)
+- Notes belong to the current conversation and are lost on restart or /reset
 
 ## Skill Management
 
@@ -249,6 +340,11 @@ Create a new skill in the skills/ directory.
 - name: skill name (used as folder name)
 - description: brief description
 - content: the skill's markdown content
+- triggers: optional words or phrases that load the skill's instructions into the prompt when a message contains them (default: the skill name)
+- always_load: optional, true to include the skill in every prompt
+- scripts: optional helper scripts, file name -> source, saved in the skill's scripts/ folder
+- dependencies: optional Python packages the scripts need, installed with uv on first use
+- examples: optional example invocations of the scripts
 
 ### list_skills
 List all available skills. No arguments needed.
@@ -261,13 +357,56 @@ Read a specific skill's content.
 Delete a skill from skills/.
 - name: the skill name to delete
 
+### export_skill
+Pack a skill and its scripts into a .tar.gz in the workspace.
+- name: the skill to export
+- path: optional destination (default exports/<name>.tar.gz)
+
+### import_skill
+Install a skill from a .tar.gz made by export_skill. Asks the user for approval.
+- source: http(s) URL or workspace path of the archive
+- replace: optional, true to overwrite an installed skill of the same name
+
 ## Background Tasks
 
+### heartbeat
+Manage your own periodic background tasks (heartbeat.json).
+- action: list, add, update, remove, enable or disable
+- name, prompt: the task and what to do when it runs
+- every: interval such as "30m" (at least 1m), or
+- schedule: cron expression such as "0 8 * * mon-fri"
+
 ### spawn
-Spawn a background subagent process.
+Start a background subagent for a self-contained task. It works on its own
+with the tools you give it and reports its result to the chat when done.
+- task: what to do and report, with all the context it needs
+- tools: optional list of tools it may use (default: those of the chat)
+- max_iterations: optional budget of model calls (default 20)
+
+### subagent_status
+List this chat's subagents and their progress, show a result (id), or stop
+a running one (id, stop: true).
 
 ### cron
-Schedule or manage cron jobs.
+Schedule jobs for this chat; they are saved and survive restarts.
+- action: add, list, remove, pause or resume
+- name: a short name (remove/pause/resume also take the job ID)
+- message: the reminder or task to carry out when the job fires
+- schedule: cron expression for recurring jobs, e.g. "0 8 * * mon-fri"
+  (weekdays 8:00), with an optional timezone such as "Europe/Madrid"
+- delay / recurring / interval: Go durations ("20m", "1h") for simple timers
+
+### remind
+Remind the user of something once; reminders survive restarts.
+- action: set (default), list or cancel (with id)
+- message: what to remind them of
+- in: a delay such as "20 minutes", "1h30m" or "2 days"
+- at: "YYYY-MM-DD HH:MM", "HH:MM" (next occurrence) or "tomorrow HH:MM"
+
+### tool_log
+List the tool calls made in a period, from the audit log (owners only).
+- period: today (default), yesterday, a date (YYYY-MM-DD) or a duration like "3h" or "7d"
+- tool, chat ("telegram" or "telegram:123"), outcome (ok, error, denied, blocked): optional filters
 `,
 
 		"NEW_POWER.md": `# NEW_POWER — Guía de uv para Gio
@@ -351,11 +490,14 @@ Los siguientes paquetes ya están disponibles sin instalar nada:
 
 		"HEARTBEAT.md": `# Heartbeat
 
-This file is checked periodically (every 60 seconds). Add tasks here that should run on a schedule.
+Periodic tasks live in heartbeat.json, each with its own interval or cron
+schedule. Ask the agent to manage them ("check my server every 30 minutes"),
+which uses the heartbeat tool, or edit heartbeat.json directly.
 
 ## Periodic Tasks
 
-<!-- Add tasks below. The agent will process them on each heartbeat check. -->
+<!-- List items below are imported into heartbeat.json once, as tasks that
+run on every heartbeat check, if that file doesn't exist yet. -->
 <!-- Example:
 - Check server status at https://example.com/health
 - Summarize unread messages
@@ -394,11 +536,41 @@ This file is checked periodically (every 60 seconds). Add tasks here that should
 	return nil
 }
 
-// extractEmbeddedSkills walks the embedded skills FS and writes each file
-// to the target directory, skipping files that already exist.
+// embeddedManifest, in the skills folder, records the hash of each embedded
+// skill file as it was last written, to tell untouched files from edited ones.
+const embeddedManifest = ".embedded.json"
+
+// SkillsUpdate reports what UpdateEmbeddedSkills did, as paths relative to
+// the skills folder.
+type SkillsUpdate struct {
+	Added   []string // new files
+	Updated []string // untouched files replaced by a newer version
+	Kept    []string // edited files left as they are although a newer version ships
+}
+
+// extractEmbeddedSkills installs and upgrades the embedded skills in
+// targetDir; see UpdateEmbeddedSkills.
 func extractEmbeddedSkills(targetDir string) error {
-	return fs.WalkDir(embeds.Skills, "skills", func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
+	_, err := UpdateEmbeddedSkills(targetDir)
+	return err
+}
+
+// UpdateEmbeddedSkills writes the skills embedded in the binary to targetDir.
+// Missing files are added, and files that still match the version written
+// last time are replaced by the current one. Files the user edited, and
+// files that existed before the manifest did, are left alone, as are files
+// the user deleted. Files in a skill's scripts/ folder are made executable.
+func UpdateEmbeddedSkills(targetDir string) (SkillsUpdate, error) {
+	var report SkillsUpdate
+	manifestPath := filepath.Join(targetDir, embeddedManifest)
+	written := map[string]string{}
+	if data, err := os.ReadFile(manifestPath); err == nil {
+		if err := json.Unmarshal(data, &written); err != nil {
+			return report, fmt.Errorf("config: %s: %w", manifestPath, err)
+		}
+	}
+	err := fs.WalkDir(embeds.Skills, "skills", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
 			return err
 		}
 		// Strip the leading "skills/" prefix to get the relative path
@@ -406,23 +578,60 @@ func extractEmbeddedSkills(targetDir string) error {
 		if err != nil {
 			return err
 		}
-		if rel == "." {
-			return nil
+		key := filepath.ToSlash(rel)
+		data, err := embeds.Skills.ReadFile(path)
+		if err != nil {
+			return err
 		}
+		want := contentHash(data)
+		last, known := written[key]
 		dest := filepath.Join(targetDir, rel)
-		if d.IsDir() {
-			return os.MkdirAll(dest, 0o755)
-		}
-		// Skip if file already exists (don't overwrite user changes)
-		if _, err := os.Stat(dest); err == nil {
+		switch disk, err := os.ReadFile(dest); {
+		case errors.Is(err, fs.ErrNotExist):
+			if known {
+				return nil // deleted by the user
+			}
+			report.Added = append(report.Added, key)
+		case err != nil:
+			return err
+		case contentHash(disk) == want:
+			written[key] = want
+			return nil
+		case known && contentHash(disk) == last:
+			report.Updated = append(report.Updated, key)
+		default:
+			if last != want {
+				report.Kept = append(report.Kept, key)
+			}
 			return nil
 		}
-		data, err := embeds.Skills.ReadFile(path)
-		if err != nil {
+		if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
 			return err
 		}
-		return os.WriteFile(dest, data, 0o644)
+		mode := os.FileMode(0o644)
+		if filepath.Base(filepath.Dir(rel)) == "scripts" {
+			mode = 0o755
+		}
+		if err := os.WriteFile(dest, data, mode); err != nil {
+			return err
+		}
+		written[key] = want
+		return nil
 	})
+	if err != nil {
+		return report, err
+	}
+	data, err := json.MarshalIndent(written, "", "  ")
+	if err != nil {
+		return report, err
+	}
+	return report, os.WriteFile(manifestPath, data, 0o644)
+}
+
+// contentHash returns the hex SHA-256 of data.
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
 }
 
 // ResolveDefaultPaths returns absolute paths for the config and workspace based on home directory
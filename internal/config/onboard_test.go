@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
 )
 
@@ -38,6 +39,12 @@ func TestInitializeWorkspaceCreatesFiles(t *testing.T) {
 			t.Fatalf("expected skill %s SKILL.md to be non-empty", skill)
 		}
 	}
+	if runtime.GOOS != "windows" {
+		fi, err := os.Stat(filepath.Join(d, "skills", "example", "scripts", "hello.py"))
+		if err != nil || fi.Mode().Perm()&0o100 == 0 {
+			t.Fatalf("expected the example skill's script to be executable: %v %v", fi, err)
+		}
+	}
 }
 
 func TestSaveAndLoadConfig(t *testing.T) {
@@ -68,3 +75,53 @@ func TestSaveAndLoadConfig(t *testing.T) {
 		t.Fatalf("expected default OpenAI API base, got %q", parsed.Providers.OpenAI.APIBase)
 	}
 }
+
+func TestUpdateEmbeddedSkills(t *testing.T) {
+	d := t.TempDir()
+	if err := InitializeWorkspace(d); err != nil {
+		t.Fatal(err)
+	}
+	skills := filepath.Join(d, "skills")
+	manifest := map[string]string{}
+	data, err := os.ReadFile(filepath.Join(skills, embeddedManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatal(err)
+	}
+
+	// weather still holds an older shipped version, example an older one
+	// the user edited, and cron was deleted by the user
+	old := []byte("old weather\n")
+	os.WriteFile(filepath.Join(skills, "weather", "SKILL.md"), old, 0o644)
+	manifest["weather/SKILL.md"] = contentHash(old)
+	os.WriteFile(filepath.Join(skills, "example", "SKILL.md"), []byte("my notes\n"), 0o644)
+	manifest["example/SKILL.md"] = contentHash([]byte("older example\n"))
+	os.Remove(filepath.Join(skills, "cron", "SKILL.md"))
+	data, _ = json.Marshal(manifest)
+	os.WriteFile(filepath.Join(skills, embeddedManifest), data, 0o644)
+
+	u, err := UpdateEmbeddedSkills(skills)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(u.Updated) != 1 || u.Updated[0] != "weather/SKILL.md" {
+		t.Errorf("Updated = %v", u.Updated)
+	}
+	if len(u.Kept) != 1 || u.Kept[0] != "example/SKILL.md" {
+		t.Errorf("Kept = %v", u.Kept)
+	}
+	if len(u.Added) != 0 {
+		t.Errorf("Added = %v", u.Added)
+	}
+	if b, _ := os.ReadFile(filepath.Join(skills, "weather", "SKILL.md")); string(b) == string(old) {
+		t.Error("untouched skill was not upgraded")
+	}
+	if b, _ := os.ReadFile(filepath.Join(skills, "example", "SKILL.md")); string(b) != "my notes\n" {
+		t.Error("edited skill was overwritten")
+	}
+	if _, err := os.Stat(filepath.Join(skills, "cron", "SKILL.md")); err == nil {
+		t.Error("deleted skill was restored")
+	}
+}
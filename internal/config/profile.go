@@ -0,0 +1,123 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Profile is a named overlay of the config, in the same layout (e.g.
+// {"agents": {"defaults": {"model": "..."}}}). Objects are merged key by
+// key; other values, lists included, replace those of the base config.
+type Profile map[string]any
+
+// profileFlag is the profile chosen with SetProfile.
+var profileFlag string
+
+// SetProfile selects the profile LoadConfig applies, overriding
+// PICOBOT_PROFILE. Empty goes back to PICOBOT_PROFILE.
+func SetProfile(name string) {
+	profileFlag = name
+}
+
+// ActiveProfile returns the profile LoadConfig applies: the one of
+// SetProfile, else PICOBOT_PROFILE; empty for the base config.
+func ActiveProfile() string {
+	if profileFlag != "" {
+		return profileFlag
+	}
+	return strings.TrimSpace(os.Getenv("PICOBOT_PROFILE"))
+}
+
+// ProfileNames returns the names of the profiles of c, sorted.
+func (c Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// profileProblems reports the unknown keys of every profile in raw, and
+// profiles nested in one.
+func profileProblems(raw map[string]any) []Problem {
+	profiles, _ := raw["profiles"].(map[string]any)
+	var out []Problem
+	for name, p := range profiles {
+		pm, ok := p.(map[string]any)
+		if !ok {
+			continue // a type error, reported when decoding
+		}
+		path := "profiles." + name
+		if _, nested := pm["profiles"]; nested {
+			out = append(out, Problem{path + ".profiles", "profiles can't be nested"})
+		}
+		out = append(out, unknownKeys(pm, reflect.TypeOf(Config{}), path)...)
+	}
+	slices.SortStableFunc(out, func(a, b Problem) int { return strings.Compare(a.Path, b.Path) })
+	return out
+}
+
+// applyProfile merges the profile name of raw into raw. Unless the profile
+// sets its own workspace, it gets <agents.defaults.workspace>/profiles/<name>
+// so its memory and sessions stay apart from the base config's.
+func applyProfile(raw map[string]any, name string) (map[string]any, error) {
+	profiles, _ := raw["profiles"].(map[string]any)
+	p, ok := profiles[name].(map[string]any)
+	if !ok {
+		names := make([]string, 0, len(profiles))
+		for n := range profiles {
+			names = append(names, n)
+		}
+		slices.Sort(names)
+		if len(names) == 0 {
+			return nil, fmt.Errorf("no profile %q: the config has no profiles", name)
+		}
+		return nil, fmt.Errorf("no profile %q (profiles: %s)", name, strings.Join(names, ", "))
+	}
+	out := mergeMaps(raw, p)
+	if lookup(p, "agents", "defaults", "workspace") == nil {
+		ws, _ := lookup(out, "agents", "defaults", "workspace").(string)
+		if ws == "" {
+			ws = "~/.picobot/workspace"
+		}
+		out = mergeMaps(out, map[string]any{"agents": map[string]any{"defaults": map[string]any{
+			"workspace": filepath.Join(ws, "profiles", name)}}})
+	}
+	return out, nil
+}
+
+// mergeMaps returns base with over merged into it, without changing either.
+func mergeMaps(base, over map[string]any) map[string]any {
+	out := make(map[string]any, len(base)+len(over))
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range over {
+		if bm, ok := out[k].(map[string]any); ok {
+			if om, ok := v.(map[string]any); ok {
+				out[k] = mergeMaps(bm, om)
+				continue
+			}
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// lookup returns the value at the key path in m, or nil.
+func lookup(m map[string]any, keys ...string) any {
+	var v any = m
+	for _, k := range keys {
+		mm, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = mm[k]
+	}
+	return v
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const profilesYAML = `agents:
+  defaults:
+    workspace: /srv/picobot
+    model: gpt-4o
+providers:
+  openai:
+    apiKey: sk-prod
+    apiBase: https://api.openai.com/v1
+channels:
+  telegram:
+    enabled: true
+    token: "123:prod"
+profiles:
+  dev:
+    agents:
+      defaults:
+        model: llama3
+    providers:
+      openai:
+        apiBase: http://localhost:11434/v1
+    channels:
+      telegram:
+        enabled: false
+  work:
+    agents:
+      defaults:
+        workspace: /srv/work
+`
+
+func TestLoadProfile(t *testing.T) {
+	home := clearEnv(t)
+	t.Setenv("PICOBOT_PROFILE", "")
+	path := filepath.Join(home, "config.yaml")
+	if err := os.WriteFile(path, []byte(profilesYAML), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	base, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if base.Agents.Defaults.Model != "gpt-4o" || !base.Channels.Telegram.Enabled || len(base.ProfileNames()) != 2 {
+		t.Fatalf("base config changed by its profiles: %+v", base)
+	}
+
+	dev, err := LoadProfile(path, "dev")
+	if err != nil {
+		t.Fatalf("LoadProfile: %v", err)
+	}
+	if dev.Agents.Defaults.Model != "llama3" || dev.Channels.Telegram.Enabled || dev.Channels.Telegram.Token != "123:prod" {
+		t.Fatalf("dev profile not applied: %+v %+v", dev.Agents.Defaults, dev.Channels.Telegram)
+	}
+	if p := dev.Providers.OpenAI; p.APIBase != "http://localhost:11434/v1" || p.APIKey != "sk-prod" {
+		t.Fatalf("provider settings should be merged: %+v", p)
+	}
+	if want := filepath.Join("/srv/picobot", "profiles", "dev"); dev.Agents.Defaults.Workspace != filepath.Clean(want) {
+		t.Fatalf("dev workspace = %s, want %s", dev.Agents.Defaults.Workspace, want)
+	}
+
+	t.Setenv("PICOBOT_PROFILE", "work")
+	work, err := LoadConfig()
+	if err != nil || work.Agents.Defaults.Workspace != filepath.Clean("/srv/work") || work.Agents.Defaults.Model != "gpt-4o" {
+		t.Fatalf("PICOBOT_PROFILE=work not applied: %+v, %v", work.Agents.Defaults, err)
+	}
+	SetProfile("dev")
+	t.Cleanup(func() { SetProfile("") })
+	if cfg, _ := LoadConfig(); cfg.Agents.Defaults.Model != "llama3" {
+		t.Fatalf("SetProfile should win over PICOBOT_PROFILE, got model %s", cfg.Agents.Defaults.Model)
+	}
+
+	if _, err := LoadProfile(path, "home"); err == nil || !strings.Contains(err.Error(), "profiles: dev, work") {
+		t.Fatalf("expected an error listing the profiles, got %v", err)
+	}
+}
+
+func TestProfileProblems(t *testing.T) {
+	home := clearEnv(t)
+	t.Setenv("PICOBOT_PROFILE", "")
+	path := filepath.Join(home, "config.json")
+	data := `{"profiles": {"dev": {"agents": {"defaults": {"modle": "llama3", "maxTokens": -1}}, "profiles": {}}}}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := LoadProfile(path, "dev")
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected a ValidationError, got %v", err)
+	}
+	var got []string
+	for _, p := range verr.Problems {
+		got = append(got, p.Path)
+	}
+	want := "profiles.dev.agents.defaults.modle profiles.dev.profiles agents.defaults.maxTokens"
+	if strings.Join(got, " ") != want {
+		t.Fatalf("problems %v, want %s", got, want)
+	}
+}
@@ -2,41 +2,504 @@ package config
 
 // Config holds picobot configuration (minimal for v0).
 type Config struct {
-	Agents    AgentsConfig    `json:"agents"`
-	Channels  ChannelsConfig  `json:"channels"`
-	Providers ProvidersConfig `json:"providers"`
+	Agents     AgentsConfig     `json:"agents"`
+	Channels   ChannelsConfig   `json:"channels"`
+	Providers  ProvidersConfig  `json:"providers"`
+	Tools      ToolsConfig      `json:"tools,omitempty"`
+	Access     AccessConfig     `json:"access,omitempty"`
+	Moderation ModerationConfig `json:"moderation,omitempty"`
+	Usage      UsageConfig      `json:"usage,omitempty"`
+	Skills     SkillsConfig     `json:"skills,omitempty"`
+	Memory     MemoryConfig     `json:"memory,omitempty"`
+	Redaction  RedactionConfig  `json:"redaction,omitempty"`
+	Logging    LoggingConfig    `json:"logging,omitempty"`
+	Telemetry  TelemetryConfig  `json:"telemetry,omitempty"`
+	// Profiles are named overlays of this config ("home", "work", "dev"),
+	// applied with --profile or PICOBOT_PROFILE.
+	Profiles map[string]Profile `json:"profiles,omitempty"`
 }
 
 type AgentsConfig struct {
 	Defaults AgentDefaults `json:"defaults"`
+	// Named agents each have their own workspace (and so SOUL.md, AGENTS.md,
+	// memory and skills), model and tools; the rest of Defaults applies to
+	// them too. Chats not routed to one are served by the default agent.
+	Named map[string]NamedAgentConfig `json:"named,omitempty"`
+	// Routes sends a channel ("discord"), its group chats ("telegram:group")
+	// or one chat ("telegram:-1001234") to a named agent, or to "default";
+	// the most specific route wins.
+	Routes map[string]string `json:"routes,omitempty"`
+	// Router has the model pick the agent for each message from a chat
+	// without a route, by the agents' descriptions.
+	Router bool `json:"router,omitempty"`
+}
+
+// NamedAgentConfig configures one named agent.
+type NamedAgentConfig struct {
+	Description string           `json:"description,omitempty"` // what the agent is for, shown to the router
+	Workspace   string           `json:"workspace,omitempty"`   // default <defaults.workspace>/agents/<name>
+	Model       string           `json:"model,omitempty"`       // default the chat model
+	Tools       ToolFilterConfig `json:"tools,omitempty"`       // tools it may use; empty allows all
 }
 
 type AgentDefaults struct {
-	Workspace          string  `json:"workspace"`
-	Model              string  `json:"model"`
-	MaxTokens          int     `json:"maxTokens"`
-	Temperature        float64 `json:"temperature"`
-	MaxToolIterations  int     `json:"maxToolIterations"`
-	HeartbeatIntervalS int     `json:"heartbeatIntervalS"`
-	RequestTimeoutS    int     `json:"requestTimeoutS"`
+	Workspace          string   `json:"workspace"`
+	Model              string   `json:"model"`
+	MaxTokens          int      `json:"maxTokens"`
+	Temperature        *float64 `json:"temperature,omitempty"` // default 0.7; 0 is honored
+	MaxToolIterations  int      `json:"maxToolIterations"`
+	HeartbeatIntervalS int      `json:"heartbeatIntervalS"`
+	// HeartbeatQuietHours ("23:00-08:00", in Timezone) is when the heartbeat
+	// sends nothing; it also holds off for HeartbeatIdleS seconds after a
+	// user message (default 120, negative to turn off).
+	HeartbeatQuietHours string `json:"heartbeatQuietHours,omitempty"`
+	HeartbeatIdleS      int    `json:"heartbeatIdleS,omitempty"`
+	RequestTimeoutS     int    `json:"requestTimeoutS"`
+	Debug               bool   `json:"debug,omitempty"`        // verbose tracing of prompts, tool args and provider payloads
+	DebugLogFile        string `json:"debugLogFile,omitempty"` // defaults to <workspace>/logs/debug.log
+	MultiTenant         bool   `json:"multiTenant,omitempty"`  // separate workspace, memory and sessions per chat
+	ExecProfile         string `json:"execProfile,omitempty"`  // exec security profile: strict, standard (default), trusted or a custom one
+	// StrictSymlinks makes tools refuse any workspace path that goes through
+	// a symlink, instead of following links that stay inside the workspace.
+	StrictSymlinks bool `json:"strictSymlinks,omitempty"`
+	// InjectionClassifier runs an extra LLM pass over fetched content and
+	// quarantines anything that tries to override the agent's instructions.
+	InjectionClassifier bool `json:"injectionClassifier,omitempty"`
+	// Destructive tool calls (overwrites/deletes outside project folders,
+	// risky commands) wait for the user's approval in chat.
+	DisableApprovals bool `json:"disableApprovals,omitempty"`
+	ApprovalTimeoutS int  `json:"approvalTimeoutS,omitempty"` // default 300; unanswered requests are denied
+	// Stream replies as they are generated: Telegram edits a draft message,
+	// WebSocket clients get token events and the CLI prints as it goes.
+	Stream bool `json:"stream,omitempty"`
+	// ModelRoutes picks models per task; empty routes use the main model.
+	ModelRoutes ModelRoutes `json:"modelRoutes,omitempty"`
+	// Further sampling settings sent to the provider; zero values are left
+	// to the API's defaults.
+	TopP             float64  `json:"topP,omitempty"`
+	Stop             []string `json:"stop,omitempty"`
+	PresencePenalty  float64  `json:"presencePenalty,omitempty"`
+	FrequencyPenalty float64  `json:"frequencyPenalty,omitempty"`
+	Seed             *int     `json:"seed,omitempty"`
+	// ThinkingBudget enables Anthropic extended thinking with this many
+	// tokens; ReasoningEffort ("low", "medium", "high") is sent to OpenAI
+	// reasoning models. The reasoning is logged, never shown to users.
+	ThinkingBudget  int    `json:"thinkingBudget,omitempty"`
+	ReasoningEffort string `json:"reasoningEffort,omitempty"`
+	// DisableVision stops sending attached images to the model, for models
+	// that only take text.
+	DisableVision bool `json:"disableVision,omitempty"`
+	// StaticToolDocs puts the workspace's TOOLS.md in the prompt instead of
+	// the tool reference generated from the registered tools.
+	StaticToolDocs bool `json:"staticToolDocs,omitempty"`
+	// RecordTraces saves every provider request and response to
+	// <workspace>/traces/, for replay with "picobot agent --replay".
+	RecordTraces bool `json:"recordTraces,omitempty"`
+	// ContextWindow is the model's context size in tokens, for models the
+	// built-in table doesn't know; prompts are trimmed to fit it.
+	ContextWindow int `json:"contextWindow,omitempty"`
+	// Tool calls of one reply run concurrently, MaxParallelTools at a time
+	// (default 4, 1 = sequentially); each may run for ToolTimeoutS
+	// (default 300).
+	MaxParallelTools int `json:"maxParallelTools,omitempty"`
+	ToolTimeoutS     int `json:"toolTimeoutS,omitempty"`
+	// Tool results longer than ToolResultMaxChars (default 16000, negative
+	// for no limit) are cut to their beginning and end, or condensed by the
+	// summarize model with SummarizeToolResults; the full output is saved
+	// under artifacts/tool-results/ in the workspace.
+	ToolResultMaxChars   int  `json:"toolResultMaxChars,omitempty"`
+	SummarizeToolResults bool `json:"summarizeToolResults,omitempty"`
+	// A task gets progress updates once it has taken ProgressAfterSteps
+	// rounds of tool calls (default 5) or ProgressAfterS seconds (default
+	// 60), at most every ProgressEveryS (default 60); negative thresholds
+	// are off.
+	ProgressAfterSteps int `json:"progressAfterSteps,omitempty"`
+	ProgressAfterS     int `json:"progressAfterS,omitempty"`
+	ProgressEveryS     int `json:"progressEveryS,omitempty"`
+	// Planning has the agent propose a numbered plan for requests that need
+	// several steps and wait for the user's approval before working on it.
+	Planning bool `json:"planning,omitempty"`
+	// A task that uses TaskMaxTokens tokens, TaskMaxCost USD (priced by
+	// usage.prices) or TaskTimeoutS seconds is paused and its sender asked
+	// whether to continue; zero is unlimited.
+	TaskMaxTokens int     `json:"taskMaxTokens,omitempty"`
+	TaskMaxCost   float64 `json:"taskMaxCost,omitempty"`
+	TaskTimeoutS  int     `json:"taskTimeoutS,omitempty"`
+	// MaxConcurrentChats is how many chats are worked on at once (default
+	// 4); the messages of one chat are handled in order.
+	MaxConcurrentChats int `json:"maxConcurrentChats,omitempty"`
+	// Timezone of cron schedules that don't name one, e.g. "Europe/Berlin";
+	// default local.
+	Timezone string `json:"timezone,omitempty"`
+	// Prompt picks and orders the sections of the system prompt.
+	Prompt PromptConfig `json:"prompt,omitempty"`
+}
+
+// PromptConfig controls which sections make up the system prompt and in
+// what order. Sections are workspace Markdown files by name ("SOUL.md") and
+// channel, memoryTool, skills, memory and memories.
+type PromptConfig struct {
+	Sections     []string `json:"sections,omitempty"`     // default SOUL.md, AGENTS.md, USER.md, TOOLS.md, channel, memoryTool, skills, memory, memories
+	Omit         []string `json:"omit,omitempty"`         // sections left out
+	MaxFileChars int      `json:"maxFileChars,omitempty"` // longer workspace files are cut; 0 = no limit
+	// Channels overrides the fields it sets per channel, e.g. a short prompt
+	// for SMS.
+	Channels map[string]PromptConfig `json:"channels,omitempty"`
+}
+
+// ModelRoutes maps tasks to models, so cheap models can handle background
+// work while a stronger one answers users.
+type ModelRoutes struct {
+	Chat      string `json:"chat,omitempty"`      // replies to users; overrides model
+	Ranking   string `json:"ranking,omitempty"`   // memory ranking
+	Heartbeat string `json:"heartbeat,omitempty"` // heartbeat tasks
+	Summarize string `json:"summarize,omitempty"` // summarizing history and notes
+	Subagent  string `json:"subagent,omitempty"`  // spawned subagents
+	Reflect   string `json:"reflect,omitempty"`   // memory reflection, see MemoryConfig.ReflectEvery
+	Route     string `json:"route,omitempty"`     // picking the named agent of a message, see AgentsConfig.Router
 }
 
 type ChannelsConfig struct {
-	Telegram TelegramConfig `json:"telegram"`
+	Telegram  TelegramConfig  `json:"telegram"`
+	Discord   DiscordConfig   `json:"discord,omitempty"`
+	Slack     SlackConfig     `json:"slack,omitempty"`
+	Email     EmailConfig     `json:"email,omitempty"`
+	HTTP      HTTPConfig      `json:"http,omitempty"`
+	WebSocket WebSocketConfig `json:"websocket,omitempty"`
+	Signal    SignalConfig    `json:"signal,omitempty"`
+	Inbound   InboundConfig   `json:"inbound,omitempty"`
+}
+
+type SlackConfig struct {
+	Enabled    bool     `json:"enabled"`
+	BotToken   string   `json:"botToken"`             // xoxb-...
+	AppToken   string   `json:"appToken"`             // xapp-... with connections:write (Socket Mode)
+	AllowFrom  []string `json:"allowFrom"`            // Slack user IDs
+	AllowTeams []string `json:"allowTeams,omitempty"` // workspace IDs; empty = any
+	Admins     []string `json:"admins,omitempty"`     // Slack user IDs allowed to run /admin and /debug
+}
+
+type EmailConfig struct {
+	Enabled       bool     `json:"enabled"`
+	IMAPAddr      string   `json:"imapAddr"` // host:port, TLS (usually :993)
+	SMTPAddr      string   `json:"smtpAddr"` // host:port; :465 = TLS, otherwise STARTTLS
+	Username      string   `json:"username"`
+	Password      string   `json:"password"`
+	From          string   `json:"from,omitempty"`   // default: username
+	Folder        string   `json:"folder,omitempty"` // default: INBOX
+	AllowFrom     []string `json:"allowFrom"`        // sender addresses
+	PollIntervalS int      `json:"pollIntervalS,omitempty"`
+	Admins        []string `json:"admins,omitempty"` // addresses allowed to run /admin and /debug
+	// AuthServID is the authserv-id of the Authentication-Results header
+	// the mail server adds, e.g. "mx.google.com"; required unless
+	// AllowUnauthenticated is set.
+	AuthServID string `json:"authServId,omitempty"`
+	// AllowUnauthenticated accepts senders that fail DMARC and DKIM.
+	AllowUnauthenticated bool `json:"allowUnauthenticated,omitempty"`
+}
+
+type HTTPConfig struct {
+	Enabled  bool   `json:"enabled"`
+	Listen   string `json:"listen,omitempty"` // default :8088
+	Token    string `json:"token"`            // bearer token required on every request
+	TimeoutS int    `json:"timeoutS,omitempty"`
+}
+
+type WebSocketConfig struct {
+	Enabled bool   `json:"enabled"`
+	Listen  string `json:"listen,omitempty"` // default :8089
+	Path    string `json:"path,omitempty"`   // default /v1/ws
+	Token   string `json:"token"`            // clients send it in their hello
+}
+
+type SignalConfig struct {
+	Enabled   bool     `json:"enabled"`
+	Addr      string   `json:"addr"`              // signal-cli JSON-RPC socket path or host:port
+	Account   string   `json:"account,omitempty"` // bot number, for daemons serving several accounts
+	AllowFrom []string `json:"allowFrom"`         // phone numbers in E.164 form
+	Admins    []string `json:"admins,omitempty"`  // numbers allowed to run /admin and /debug
+}
+
+type DiscordConfig struct {
+	Enabled   bool     `json:"enabled"`
+	Token     string   `json:"token"`
+	AllowFrom []string `json:"allowFrom"`
+	Admins    []string `json:"admins,omitempty"` // Discord user IDs allowed to run /admin and /debug
+}
+
+// InboundConfig limits what channel users can send. Zero values use the
+// defaults; internal channels (cli, heartbeat, cron) are never limited.
+type InboundConfig struct {
+	Disabled         bool `json:"disabled,omitempty"`         // turn flood protection off
+	MaxMessageLength int  `json:"maxMessageLength,omitempty"` // bytes; longer messages are truncated (default 8000)
+	MaxPerMinute     int  `json:"maxPerMinute,omitempty"`     // per sender before a temporary mute (default 20)
+	DuplicateWindowS int  `json:"duplicateWindowS,omitempty"` // identical repeats within this window are dropped (default 10)
+	MuteMinutes      int  `json:"muteMinutes,omitempty"`      // mute duration for flooding senders (default 10)
 }
 
 type TelegramConfig struct {
 	Enabled   bool     `json:"enabled"`
 	Token     string   `json:"token"`
 	AllowFrom []string `json:"allowFrom"`
+	Admins    []string `json:"admins,omitempty"` // Telegram user IDs allowed to run /admin and /debug
+	// Webhook switches from long polling to webhook mode when URL is set.
+	Webhook TelegramWebhookConfig `json:"webhook,omitempty"`
+}
+
+type TelegramWebhookConfig struct {
+	URL         string `json:"url,omitempty"`         // public https URL Telegram posts to
+	Listen      string `json:"listen,omitempty"`      // local listen address; default :8443
+	SecretToken string `json:"secretToken,omitempty"` // required; checked on every request
+	CertFile    string `json:"certFile,omitempty"`    // serve HTTPS directly (omit behind a reverse proxy)
+	KeyFile     string `json:"keyFile,omitempty"`
 }
 
 type ProvidersConfig struct {
 	OpenAI    *ProviderConfig `json:"openai,omitempty"`
 	Anthropic *ProviderConfig `json:"anthropic,omitempty"`
+	Groq      *ProviderConfig `json:"groq,omitempty"`    // used for "groq/..." models
+	Mistral   *ProviderConfig `json:"mistral,omitempty"` // used for "mistral-..." and other Mistral models
 }
 
 type ProviderConfig struct {
-	APIKey  string `json:"apiKey"`
-	APIBase string `json:"apiBase"`
+	APIKey  string       `json:"apiKey"`
+	APIBase string       `json:"apiBase"`
+	Retry   *RetryConfig `json:"retry,omitempty"`
+}
+
+// RetryConfig tunes how failed requests (network errors, 429, 5xx) to a
+// provider are retried. Unset fields keep the defaults.
+type RetryConfig struct {
+	MaxRetries      *int  `json:"maxRetries,omitempty"`      // default 3; 0 disables retries
+	BaseDelayMs     int   `json:"baseDelayMs,omitempty"`     // default 1000; doubles per attempt, x5 after a 429
+	MaxDelayMs      int   `json:"maxDelayMs,omitempty"`      // default 60000
+	Jitter          bool  `json:"jitter,omitempty"`          // randomize delays between 50% and 100%
+	HonorRetryAfter *bool `json:"honorRetryAfter,omitempty"` // default true
+}
+
+// ModerationConfig enables a content check on replies, messages and files the
+// agent produces before users see them.
+type ModerationConfig struct {
+	Mode     string              `json:"mode,omitempty"`     // "block" (default) or "flag"; empty with no rules or provider = off
+	Provider string              `json:"provider,omitempty"` // "openai" to call the /moderations API of providers.openai
+	Model    string              `json:"model,omitempty"`    // moderation model; default omni-moderation-latest
+	Rules    map[string][]string `json:"rules,omitempty"`    // category -> words or regular expressions
+}
+
+// UsageConfig prices models for the token usage ledger.
+type UsageConfig struct {
+	Prices map[string]ModelPrice `json:"prices,omitempty"` // model name -> price; unpriced models cost $0
+}
+
+// MemoryConfig controls the nightly consolidation of daily notes into
+// MEMORY.md.
+type MemoryConfig struct {
+	ConsolidateAfterDays int    `json:"consolidateAfterDays,omitempty"` // notes older than this are consolidated; 0 means 7, negative disables
+	ConsolidateAt        string `json:"consolidateAt,omitempty"`        // "HH:MM" in agents.defaults.timezone; default "03:30"
+	// ReflectEvery has the reflect route's model review every Nth exchange
+	// of a chat and store the facts worth remembering; 0 (default) is off.
+	ReflectEvery int `json:"reflectEvery,omitempty"`
+	// Ranking of earlier notes for the prompt, with the ranking route's model.
+	Ranker         string `json:"ranker,omitempty"`         // "hybrid" (default), "llm" or "simple"
+	TopK           int    `json:"topK,omitempty"`           // notes put in the prompt; default 5
+	RankCandidates int    `json:"rankCandidates,omitempty"` // keyword matches the hybrid ranker sends; default 12
+	RankTimeoutS   int    `json:"rankTimeoutS,omitempty"`   // per ranking request; default 15, negative means none
+}
+
+// SkillsConfig shares the workspace's skills through a git repository.
+type SkillsConfig struct {
+	GitRemote string `json:"gitRemote,omitempty"` // pulled on startup, pushed on every skill change
+	GitBranch string `json:"gitBranch,omitempty"` // default "main"
+}
+
+// ModelPrice is a model's price in USD per million tokens.
+type ModelPrice struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// AccessConfig assigns roles (owner, user, readonly or custom) to channel
+// identities and controls which tools each role may use.
+type AccessConfig struct {
+	DefaultRole string              `json:"defaultRole,omitempty"` // role for unlisted identities; default owner
+	Users       map[string]string   `json:"users,omitempty"`       // "telegram:<userID>" -> role
+	Roles       map[string][]string `json:"roles,omitempty"`       // role -> tool names ("*" = all); overrides built-in grants
+}
+
+type ToolsConfig struct {
+	Exec        ExecConfig        `json:"exec,omitempty"`
+	Web         WebConfig         `json:"web,omitempty"`
+	HTTPRequest HTTPRequestConfig `json:"httpRequest,omitempty"`
+	Search      SearchConfig      `json:"search,omitempty"`
+	Calendar    CalendarConfig    `json:"calendar,omitempty"`
+	// MCP names the Model Context Protocol servers whose tools are added,
+	// as mcp_<name>_<tool>.
+	MCP map[string]MCPServerConfig `json:"mcp,omitempty"`
+	// Channels enables or disables tools per scope: a channel ("telegram"),
+	// its group chats ("telegram:group") or one chat ("telegram:-1001234").
+	Channels map[string]ToolFilterConfig `json:"channels,omitempty"`
+	// Approvals marks more tool calls as needing the user's approval.
+	Approvals []ApprovalRuleConfig `json:"approvals,omitempty"`
+	// Middleware wraps tool calls, in order.
+	Middleware []ToolMiddlewareConfig `json:"middleware,omitempty"`
+}
+
+// ApprovalRuleConfig matches calls of Tool whose arguments all match the
+// regular expressions in Args (empty = every call).
+type ApprovalRuleConfig struct {
+	Tool string            `json:"tool"`
+	Args map[string]string `json:"args,omitempty"`
+}
+
+// ToolMiddlewareConfig adds a middleware of Type "log", "rateLimit" or
+// "confirm" to the tools listed in Tools (empty = all).
+type ToolMiddlewareConfig struct {
+	Type       string   `json:"type"`
+	Tools      []string `json:"tools,omitempty"`
+	PerMinute  int      `json:"perMinute,omitempty"`  // rateLimit: calls allowed per tool per minute
+	RedactArgs []string `json:"redactArgs,omitempty"` // log: arguments whose values are masked
+}
+
+// ToolFilterConfig lists the tools allowed (empty = all) and denied in a scope.
+type ToolFilterConfig struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+// WebConfig restricts the hosts network tools (web fetch and future download
+// or browser tools) may contact. Patterns: "example.com", "*.example.com", "*".
+type WebConfig struct {
+	AllowDomains []string `json:"allowDomains,omitempty"` // empty = any public host
+	DenyDomains  []string `json:"denyDomains,omitempty"`  // takes precedence over allowDomains
+}
+
+// HTTPRequestConfig configures the http_request tool.
+type HTTPRequestConfig struct {
+	TimeoutS         int `json:"timeoutS,omitempty"`         // default 30
+	MaxResponseBytes int `json:"maxResponseBytes,omitempty"` // default 102400
+}
+
+// SearchConfig selects the backend of the search tool.
+type SearchConfig struct {
+	Backend string `json:"backend,omitempty"` // "duckduckgo" (default), "searxng" or "brave"
+	URL     string `json:"url,omitempty"`     // searxng: instance URL
+	APIKey  string `json:"apiKey,omitempty"`  // brave: API key
+}
+
+// CalendarConfig connects the calendar tool to a CalDAV server or Google
+// Calendar. The tool is only available when a provider is set.
+type CalendarConfig struct {
+	Provider string `json:"provider,omitempty"` // "caldav" or "google"
+	// CalDAV: the calendar collection URL and its credentials.
+	URL      string `json:"url,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// Google: an OAuth client and a refresh token with the calendar.events scope.
+	CalendarID   string `json:"calendarId,omitempty"` // default "primary"
+	ClientID     string `json:"clientId,omitempty"`
+	ClientSecret string `json:"clientSecret,omitempty"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+	// Timezone of times given without a zone, e.g. "Europe/Berlin"; default local.
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// MCPServerConfig starts an MCP server (command) or connects to one over
+// HTTP with server-sent events (url).
+type MCPServerConfig struct {
+	Command  string            `json:"command,omitempty"`
+	Args     []string          `json:"args,omitempty"`
+	Env      map[string]string `json:"env,omitempty"`
+	Dir      string            `json:"dir,omitempty"`
+	URL      string            `json:"url,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	TimeoutS int               `json:"timeoutS,omitempty"` // per tool call; default 60
+}
+
+// ExecConfig selects exec security profiles per channel and defines custom ones.
+type ExecConfig struct {
+	Channels map[string]string            `json:"channels,omitempty"` // channel name -> profile name
+	Profiles map[string]ExecProfileConfig `json:"profiles,omitempty"` // custom profiles or overrides of built-in ones
+	// MaxConcurrent caps the commands running at once; 0 means no limit.
+	MaxConcurrent int `json:"maxConcurrent,omitempty"`
+	// PassEnv names environment variables passed to commands although they
+	// look like credentials.
+	PassEnv []string `json:"passEnv,omitempty"`
+}
+
+// ExecProfileConfig customizes an exec profile. Unset fields inherit from Base
+// (default: the built-in profile of the same name, else "standard").
+type ExecProfileConfig struct {
+	Base              string               `json:"base,omitempty"`
+	Policy            string               `json:"policy,omitempty"` // "allowlist" (only allow runs) or "denylist"
+	Allow             []string             `json:"allow,omitempty"`
+	Deny              []string             `json:"deny,omitempty"`
+	AllowShellMeta    *bool                `json:"allowShellMeta,omitempty"`
+	AllowInterpreters *bool                `json:"allowInterpreters,omitempty"`
+	TimeoutS          int                  `json:"timeoutS,omitempty"`
+	Sandbox           string               `json:"sandbox,omitempty"`      // "workspace", "none" or "container"
+	Network           *bool                `json:"network,omitempty"`      // false runs commands without network access (Linux)
+	NetworkAllow      []string             `json:"networkAllow,omitempty"` // programs that keep network access when network is false
+	MaxOutputBytes    int                  `json:"maxOutputBytes,omitempty"`
+	CPUSeconds        int                  `json:"cpuSeconds,omitempty"` // Linux only
+	MemoryMB          int                  `json:"memoryMB,omitempty"`   // Linux only
+	Container         *ExecContainerConfig `json:"container,omitempty"`
+	// Args validates the arguments per program name or glob.
+	Args map[string]ExecArgsConfig `json:"args,omitempty"`
+}
+
+// ExecArgsConfig restricts the arguments of one program. Patterns are
+// regular expressions; allow patterns must match a whole argument.
+type ExecArgsConfig struct {
+	Subcommands []string `json:"subcommands,omitempty"` // allowed first arguments
+	Allow       []string `json:"allow,omitempty"`       // every other argument must match one
+	Deny        []string `json:"deny,omitempty"`        // no argument may match
+}
+
+// ExecContainerConfig configures the container sandbox of an exec profile.
+type ExecContainerConfig struct {
+	Runtime  string  `json:"runtime,omitempty"` // "docker" (default) or "podman"
+	Image    string  `json:"image"`
+	CPUs     float64 `json:"cpus,omitempty"`
+	MemoryMB int     `json:"memoryMB,omitempty"`
+}
+
+// LoggingConfig configures the process log.
+type LoggingConfig struct {
+	Level      string            `json:"level,omitempty"`      // debug, info (default), warn or error
+	Levels     map[string]string `json:"levels,omitempty"`     // per subsystem, e.g. "channels": "debug"
+	Format     string            `json:"format,omitempty"`     // text (default) or json
+	File       string            `json:"file,omitempty"`       // default stderr
+	MaxSizeMB  int               `json:"maxSizeMB,omitempty"`  // the file is rotated at this size; default 10
+	MaxBackups int               `json:"maxBackups,omitempty"` // rotated files kept; default 5
+}
+
+// TelemetryConfig configures tracing of requests.
+type TelemetryConfig struct {
+	Enabled     bool              `json:"enabled,omitempty"`
+	Endpoint    string            `json:"endpoint,omitempty"` // OTLP/HTTP collector; default $OTEL_EXPORTER_OTLP_ENDPOINT, else spans are logged
+	Headers     map[string]string `json:"headers,omitempty"`  // sent with every export, e.g. an API key
+	ServiceName string            `json:"serviceName,omitempty"`
+}
+
+// RedactionConfig adds rules to the redaction of configured secrets and
+// common API key formats from replies, tool output and logs.
+type RedactionConfig struct {
+	Rules []RedactionRule `json:"rules,omitempty"`
+}
+
+// RedactionRule replaces the matches of a regular expression.
+type RedactionRule struct {
+	Pattern  string   `json:"pattern"`            // RE2 syntax, e.g. "(?i)acct-[0-9]{8}"
+	Replace  string   `json:"replace,omitempty"`  // default "[REDACTED]"; $1 refers to a group
+	Channels []string `json:"channels,omitempty"` // only messages to these channels; default everywhere, logs included
+}
+
+// Secrets returns every credential configured in c (API keys, bot tokens,
+// MCP server environments and headers), so they can be redacted from logs and messages.
+func (c Config) Secrets() []string {
+	var out []string
+	for _, f := range secretFields(&c) {
+		out = append(out, f.get())
+	}
+	return out
 }
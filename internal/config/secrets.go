@@ -0,0 +1,244 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Encrypted config values look like "enc:v1:<base64(salt|nonce|ciphertext)>".
+// The key is derived from the master passphrase with PBKDF2-SHA256 and the
+// value sealed with AES-256-GCM.
+const (
+	encPrefix     = "enc:v1:"
+	encSaltLen    = 16
+	encIterations = 200_000
+)
+
+// ErrNoMasterKey is returned when the config holds encrypted values but no
+// master passphrase is available.
+var ErrNoMasterKey = errors.New("config: encrypted secrets found but PICOBOT_MASTER_KEY (or PICOBOT_MASTER_KEY_FILE) is not set")
+
+// MasterKey returns the passphrase used to encrypt config secrets, read from
+// PICOBOT_MASTER_KEY or the file named by PICOBOT_MASTER_KEY_FILE.
+func MasterKey() (string, error) {
+	if k := os.Getenv("PICOBOT_MASTER_KEY"); k != "" {
+		return k, nil
+	}
+	if p := os.Getenv("PICOBOT_MASTER_KEY_FILE"); p != "" {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return "", fmt.Errorf("config: read master key file: %w", err)
+		}
+		if k := strings.TrimSpace(string(b)); k != "" {
+			return k, nil
+		}
+	}
+	return "", ErrNoMasterKey
+}
+
+// IsEncrypted reports whether v is an encrypted config value.
+func IsEncrypted(v string) bool {
+	return strings.HasPrefix(v, encPrefix)
+}
+
+func deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	return pbkdf2.Key(sha256.New, passphrase, salt, encIterations, 32)
+}
+
+// EncryptSecret seals plaintext with passphrase and returns the value to
+// store in config.json.
+func EncryptSecret(plaintext, passphrase string) (string, error) {
+	salt := make([]byte, encSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	out := append(salt, nonce...)
+	out = gcm.Seal(out, nonce, []byte(plaintext), nil)
+	return encPrefix + base64.StdEncoding.EncodeToString(out), nil
+}
+
+// DecryptSecret opens a value produced by EncryptSecret.
+func DecryptSecret(value, passphrase string) (string, error) {
+	if !IsEncrypted(value) {
+		return "", errors.New("config: value is not encrypted")
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encPrefix))
+	if err != nil {
+		return "", fmt.Errorf("config: malformed encrypted value: %w", err)
+	}
+	key, err := deriveKey(passphrase, raw[:min(len(raw), encSaltLen)])
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	if len(raw) < encSaltLen+gcm.NonceSize() {
+		return "", errors.New("config: malformed encrypted value: too short")
+	}
+	nonce := raw[encSaltLen : encSaltLen+gcm.NonceSize()]
+	plain, err := gcm.Open(nil, nonce, raw[encSaltLen+gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("config: cannot decrypt secret (wrong master key?)")
+	}
+	return string(plain), nil
+}
+
+// secretField is a credential field of a Config: a string field or an
+// entry of a map of secrets (MCP environments, HTTP headers).
+type secretField struct {
+	path  string // key path, e.g. "providers.openai.apiKey"
+	value *string
+	m     map[string]string
+	key   string
+}
+
+func (f secretField) get() string {
+	if f.value != nil {
+		return *f.value
+	}
+	return f.m[f.key]
+}
+
+func (f secretField) set(v string) {
+	if f.value != nil {
+		*f.value = v
+	} else {
+		f.m[f.key] = v
+	}
+}
+
+// mapSecrets returns the entries of m as credential fields under prefix,
+// sorted by key.
+func mapSecrets(prefix string, m map[string]string) []secretField {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]secretField, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, secretField{path: prefix + "." + k, m: m, key: k})
+	}
+	return out
+}
+
+// secretFields returns every credential field in c.
+func secretFields(c *Config) []secretField {
+	var out []secretField
+	for name, p := range map[string]*ProviderConfig{"openai": c.Providers.OpenAI, "anthropic": c.Providers.Anthropic,
+		"groq": c.Providers.Groq, "mistral": c.Providers.Mistral} {
+		if p != nil {
+			out = append(out, secretField{path: "providers." + name + ".apiKey", value: &p.APIKey})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].path < out[j].path })
+	ch := &c.Channels
+	out = append(out,
+		secretField{path: "channels.telegram.token", value: &ch.Telegram.Token},
+		secretField{path: "channels.telegram.webhook.secretToken", value: &ch.Telegram.Webhook.SecretToken},
+		secretField{path: "channels.discord.token", value: &ch.Discord.Token},
+		secretField{path: "channels.slack.botToken", value: &ch.Slack.BotToken},
+		secretField{path: "channels.slack.appToken", value: &ch.Slack.AppToken},
+		secretField{path: "channels.email.password", value: &ch.Email.Password},
+		secretField{path: "channels.http.token", value: &ch.HTTP.Token},
+		secretField{path: "channels.websocket.token", value: &ch.WebSocket.Token},
+		secretField{path: "tools.search.apiKey", value: &c.Tools.Search.APIKey},
+		secretField{path: "tools.calendar.password", value: &c.Tools.Calendar.Password},
+		secretField{path: "tools.calendar.clientSecret", value: &c.Tools.Calendar.ClientSecret},
+		secretField{path: "tools.calendar.refreshToken", value: &c.Tools.Calendar.RefreshToken})
+	names := make([]string, 0, len(c.Tools.MCP))
+	for name := range c.Tools.MCP {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		m := c.Tools.MCP[name]
+		out = append(out, mapSecrets("tools.mcp."+name+".env", m.Env)...)
+		out = append(out, mapSecrets("tools.mcp."+name+".headers", m.Headers)...)
+	}
+	return append(out, mapSecrets("telemetry.headers", c.Telemetry.Headers)...)
+}
+
+// resolveSecrets replaces encrypted credential fields in c with their
+// plaintext and keyring references with the stored values. The master key
+// is only required if something is encrypted.
+func resolveSecrets(c *Config) error {
+	var passphrase string
+	for _, sf := range secretFields(c) {
+		f := sf.get()
+		if IsKeyringRef(f) {
+			v, err := KeyringGet(strings.TrimPrefix(f, keyringPrefix))
+			if err != nil {
+				return fmt.Errorf("config: %s: %w", sf.path, err)
+			}
+			sf.set(v)
+			continue
+		}
+		if !IsEncrypted(f) {
+			continue
+		}
+		if passphrase == "" {
+			k, err := MasterKey()
+			if err != nil {
+				return err
+			}
+			passphrase = k
+		}
+		plain, err := DecryptSecret(f, passphrase)
+		if err != nil {
+			return err
+		}
+		sf.set(plain)
+	}
+	return nil
+}
+
+// EncryptSecrets encrypts every plaintext credential field in c in place and
+// returns how many fields were encrypted.
+func EncryptSecrets(c *Config, passphrase string) (int, error) {
+	n := 0
+	for _, sf := range secretFields(c) {
+		f := sf.get()
+		if f == "" || IsEncrypted(f) || IsKeyringRef(f) {
+			continue
+		}
+		enc, err := EncryptSecret(f, passphrase)
+		if err != nil {
+			return n, err
+		}
+		sf.set(enc)
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+)
+
+func TestEncryptedSecretsRoundTrip(t *testing.T) {
+	enc, err := EncryptSecret("sk-test-1234567890", "hunter2")
+	if err != nil {
+		t.Fatalf("EncryptSecret: %v", err)
+	}
+	if !IsEncrypted(enc) {
+		t.Fatalf("expected encrypted prefix, got %q", enc)
+	}
+	if got, err := DecryptSecret(enc, "hunter2"); err != nil || got != "sk-test-1234567890" {
+		t.Fatalf("DecryptSecret = %q, %v", got, err)
+	}
+	if _, err := DecryptSecret(enc, "wrong"); err == nil {
+		t.Fatalf("expected error with wrong passphrase")
+	}
+}
+
+func TestLoadConfigDecryptsSecrets(t *testing.T) {
+	for _, k := range []string{"GIO_LLM_API_KEY", "PICOBOT_LLM_API_KEY", "OPENAI_API_KEY", "GIO_TELEGRAM_TOKEN", "PICOBOT_TELEGRAM_TOKEN", "PICOBOT_GATEWAY_TELEGRAM_TOKEN", "PICOBOT_MASTER_KEY_FILE"} {
+		t.Setenv(k, "")
+	}
+	home := t.TempDir()
+	t.Setenv("PICOBOT_HOME", home)
+	t.Setenv("PICOBOT_MASTER_KEY", "")
+
+	cfg := DefaultConfig()
+	cfg.Providers.OpenAI.APIKey = "sk-plain-abcdef123"
+	cfg.Channels.Telegram.Token = "123:telegram-token"
+	if n, err := EncryptSecrets(&cfg, "master"); err != nil || n != 2 {
+		t.Fatalf("EncryptSecrets = %d, %v", n, err)
+	}
+	if err := SaveConfig(cfg, filepath.Join(home, "config.json")); err != nil {
+		t.Fatalf("SaveConfig: %v", err)
+	}
+
+	if _, err := LoadConfig(); !errors.Is(err, ErrNoMasterKey) {
+		t.Fatalf("expected ErrNoMasterKey, got %v", err)
+	}
+	t.Setenv("PICOBOT_MASTER_KEY", "master")
+	loaded, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if loaded.Providers.OpenAI.APIKey != "sk-plain-abcdef123" || loaded.Channels.Telegram.Token != "123:telegram-token" {
+		t.Fatalf("secrets not decrypted: %+v %+v", loaded.Providers.OpenAI, loaded.Channels.Telegram)
+	}
+}
+
+func TestMCPSecrets(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Tools.MCP = map[string]MCPServerConfig{
+		"github": {Command: "github-mcp", Env: map[string]string{"GITHUB_TOKEN": "ghp_mcp-token-123"}},
+		"docs":   {URL: "https://mcp.example.com/sse", Headers: map[string]string{"Authorization": "Bearer docs-token-456"}},
+	}
+	secrets := strings.Join(cfg.Secrets(), " ")
+	if !strings.Contains(secrets, "ghp_mcp-token-123") || !strings.Contains(secrets, "Bearer docs-token-456") {
+		t.Fatalf("Secrets() misses MCP credentials: %q", secrets)
+	}
+	if n, err := EncryptSecrets(&cfg, "master"); err != nil || n != 3 {
+		t.Fatalf("EncryptSecrets = %d, %v", n, err)
+	}
+	if !IsEncrypted(cfg.Tools.MCP["github"].Env["GITHUB_TOKEN"]) || !IsEncrypted(cfg.Tools.MCP["docs"].Headers["Authorization"]) {
+		t.Fatalf("MCP credentials not encrypted: %+v", cfg.Tools.MCP)
+	}
+	t.Setenv("PICOBOT_MASTER_KEY", "master")
+	if err := resolveSecrets(&cfg); err != nil {
+		t.Fatalf("resolveSecrets: %v", err)
+	}
+	if cfg.Tools.MCP["github"].Env["GITHUB_TOKEN"] != "ghp_mcp-token-123" {
+		t.Fatalf("MCP env not decrypted: %+v", cfg.Tools.MCP["github"])
+	}
+}
+
+func TestLoadConfigReadsSecretFiles(t *testing.T) {
+	home := clearEnv(t)
+	keyFile := filepath.Join(home, "llm_key")
+	os.WriteFile(keyFile, []byte("sk-from-file\n"), 0o600)
+	t.Setenv("PICOBOT_LLM_API_KEY_FILE", keyFile)
+	t.Setenv("PICOBOT_TELEGRAM_TOKEN", "123:from-env")
+	t.Setenv("PICOBOT_TELEGRAM_TOKEN_FILE", filepath.Join(home, "ignored"))
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.Providers.OpenAI == nil || cfg.Providers.OpenAI.APIKey != "sk-from-file" {
+		t.Fatalf("API key not read from file: %+v", cfg.Providers.OpenAI)
+	}
+	if cfg.Channels.Telegram.Token != "123:from-env" {
+		t.Fatalf("the variable itself should win over its _FILE, got %q", cfg.Channels.Telegram.Token)
+	}
+
+	t.Setenv("PICOBOT_LLM_API_KEY_FILE", filepath.Join(home, "missing"))
+	if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "PICOBOT_LLM_API_KEY_FILE") {
+		t.Fatalf("expected an error naming the unreadable file variable, got %v", err)
+	}
+}
+
+func TestKeyringSecrets(t *testing.T) {
+	keyring.MockInit()
+	home := clearEnv(t)
+
+	cfg := DefaultConfig()
+	cfg.Channels.Telegram.Token = "123:telegram-token"
+	if n, err := StoreSecretsInKeyring(&cfg); err != nil || n != 2 {
+		t.Fatalf("StoreSecretsInKeyring = %d, %v", n, err)
+	}
+	if cfg.Providers.OpenAI.APIKey != "keyring:providers.openai.apiKey" || !IsKeyringRef(cfg.Channels.Telegram.Token) {
+		t.Fatalf("credentials not replaced by references: %+v %+v", cfg.Providers.OpenAI, cfg.Channels.Telegram)
+	}
+	if err := SaveConfig(cfg, filepath.Join(home, "config.json")); err != nil {
+		t.Fatalf("SaveConfig: %v", err)
+	}
+	loaded, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if loaded.Providers.OpenAI.APIKey != "sk-or-v1-REPLACE_ME" || loaded.Channels.Telegram.Token != "123:telegram-token" {
+		t.Fatalf("keyring references not resolved: %+v %+v", loaded.Providers.OpenAI, loaded.Channels.Telegram)
+	}
+
+	cfg.Channels.Discord.Token = KeyringRef("discord")
+	SaveConfig(cfg, filepath.Join(home, "config.json"))
+	if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "keyring-set discord") {
+		t.Fatalf("expected an error for a missing keyring entry, got %v", err)
+	}
+}
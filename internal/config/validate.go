@@ -0,0 +1,409 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/logging"
+)
+
+// Problem is something wrong with one setting of a config.
+type Problem struct {
+	Path    string // key path, e.g. "agents.defaults.maxTokens"
+	Message string
+}
+
+func (p Problem) String() string { return p.Path + ": " + p.Message }
+
+// ValidationError lists the problems found in a config file.
+type ValidationError struct {
+	File     string
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "config: %d problem(s) in %s:", len(e.Problems), e.File)
+	for _, p := range e.Problems {
+		b.WriteString("\n  " + p.String())
+	}
+	return b.String()
+}
+
+// unknownKeys reports the keys of raw that no field of t (a Config type)
+// has, suggesting the closest known key. Like encoding/json, keys match
+// fields case-insensitively.
+func unknownKeys(raw any, t reflect.Type, path string) []Problem {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var out []Problem
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := raw.(map[string]any)
+		if !ok {
+			return nil // a type error, reported when decoding
+		}
+		fields := make(map[string]reflect.Type)
+		var names []string
+		for i := 0; i < t.NumField(); i++ {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			fields[strings.ToLower(name)] = t.Field(i).Type
+			names = append(names, name)
+		}
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			ft, ok := fields[strings.ToLower(k)]
+			if !ok {
+				msg := "unknown key"
+				if s := closest(k, names); s != "" {
+					msg += fmt.Sprintf(" (did you mean %q?)", s)
+				}
+				out = append(out, Problem{joinPath(path, k), msg})
+				continue
+			}
+			out = append(out, unknownKeys(m[k], ft, joinPath(path, k))...)
+		}
+	case reflect.Map:
+		if m, ok := raw.(map[string]any); ok {
+			for k, v := range m {
+				out = append(out, unknownKeys(v, t.Elem(), joinPath(path, k))...)
+			}
+		}
+	case reflect.Slice:
+		if l, ok := raw.([]any); ok {
+			for i, v := range l {
+				out = append(out, unknownKeys(v, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// closest returns the name most similar to key, if one is close enough to
+// be a likely typo.
+func closest(key string, names []string) string {
+	best, bestDist := "", len(key)/3+2
+	for _, n := range names {
+		if d := editDistance(strings.ToLower(key), strings.ToLower(n)); d < bestDist {
+			best, bestDist = n, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance of a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// Validate checks c for missing required settings and out of range
+// values. Zero values mean "use the default" and are not reported.
+func Validate(c Config) []Problem {
+	v := &validator{}
+	d := c.Agents.Defaults
+	p := "agents.defaults."
+	v.nonNegative(p+"maxTokens", d.MaxTokens)
+	v.nonNegative(p+"maxToolIterations", d.MaxToolIterations)
+	v.nonNegative(p+"heartbeatIntervalS", d.HeartbeatIntervalS)
+	v.nonNegative(p+"requestTimeoutS", d.RequestTimeoutS)
+	v.nonNegative(p+"approvalTimeoutS", d.ApprovalTimeoutS)
+	v.nonNegative(p+"thinkingBudget", d.ThinkingBudget)
+	v.nonNegative(p+"contextWindow", d.ContextWindow)
+	v.nonNegative(p+"maxParallelTools", d.MaxParallelTools)
+	v.nonNegative(p+"toolTimeoutS", d.ToolTimeoutS)
+	v.nonNegative(p+"taskMaxTokens", d.TaskMaxTokens)
+	v.nonNegative(p+"taskTimeoutS", d.TaskTimeoutS)
+	v.nonNegative(p+"maxConcurrentChats", d.MaxConcurrentChats)
+	v.nonNegative(p+"prompt.maxFileChars", d.Prompt.MaxFileChars)
+	if d.Temperature != nil {
+		v.between(p+"temperature", *d.Temperature, 0, 2)
+	}
+	v.between(p+"topP", d.TopP, 0, 1)
+	v.between(p+"presencePenalty", d.PresencePenalty, -2, 2)
+	v.between(p+"frequencyPenalty", d.FrequencyPenalty, -2, 2)
+	v.between(p+"taskMaxCost", d.TaskMaxCost, 0, 1e9)
+	v.oneOf(p+"reasoningEffort", d.ReasoningEffort, "low", "medium", "high")
+	if d.Timezone != "" {
+		if _, err := time.LoadLocation(d.Timezone); err != nil {
+			v.addf(p+"timezone", "unknown time zone %q (want e.g. \"Europe/Berlin\")", d.Timezone)
+		}
+	}
+	if q := d.HeartbeatQuietHours; q != "" {
+		from, to, ok := strings.Cut(strings.ReplaceAll(q, " ", ""), "-")
+		if !ok || !isClock(from) || !isClock(to) || from == to {
+			v.addf(p+"heartbeatQuietHours", "invalid period %q (want e.g. \"23:00-08:00\")", q)
+		}
+	}
+	execProfiles := []string{"strict", "standard", "trusted"}
+	for name := range c.Tools.Exec.Profiles {
+		execProfiles = append(execProfiles, name)
+	}
+	sort.Strings(execProfiles[3:])
+	v.oneOf(p+"execProfile", d.ExecProfile, execProfiles...)
+	for route, agent := range c.Agents.Routes {
+		if _, ok := c.Agents.Named[agent]; !ok && agent != "default" {
+			v.addf("agents.routes."+route, "no agent %q in agents.named (or use \"default\")", agent)
+		}
+	}
+
+	ch := c.Channels
+	if ch.Telegram.Enabled {
+		v.required("channels.telegram.token", ch.Telegram.Token, "the bot token from @BotFather, or set PICOBOT_TELEGRAM_TOKEN")
+	}
+	if wh := ch.Telegram.Webhook; wh.URL != "" {
+		if !strings.HasPrefix(wh.URL, "https://") {
+			v.addf("channels.telegram.webhook.url", "must be an https:// URL, got %q", wh.URL)
+		}
+		v.required("channels.telegram.webhook.secretToken", wh.SecretToken, "a random string Telegram sends with every update")
+	}
+	if ch.Discord.Enabled {
+		v.required("channels.discord.token", ch.Discord.Token, "the bot token, or set PICOBOT_DISCORD_TOKEN")
+	}
+	if ch.Slack.Enabled {
+		v.required("channels.slack.botToken", ch.Slack.BotToken, "the xoxb- token, or set PICOBOT_SLACK_BOT_TOKEN")
+		v.required("channels.slack.appToken", ch.Slack.AppToken, "the xapp- token, or set PICOBOT_SLACK_APP_TOKEN")
+	}
+	if ch.Email.Enabled {
+		v.required("channels.email.imapAddr", ch.Email.IMAPAddr, "host:port of the IMAP server")
+		v.required("channels.email.smtpAddr", ch.Email.SMTPAddr, "host:port of the SMTP server")
+		v.required("channels.email.username", ch.Email.Username, "the mailbox login")
+		v.required("channels.email.password", ch.Email.Password, "the mailbox password, or set PICOBOT_EMAIL_PASSWORD")
+	}
+	v.nonNegative("channels.email.pollIntervalS", ch.Email.PollIntervalS)
+	if ch.HTTP.Enabled {
+		v.required("channels.http.token", ch.HTTP.Token, "the bearer token clients send, or set PICOBOT_HTTP_TOKEN")
+	}
+	v.nonNegative("channels.http.timeoutS", ch.HTTP.TimeoutS)
+	if ch.WebSocket.Enabled {
+		v.required("channels.websocket.token", ch.WebSocket.Token, "the token clients send in their hello, or set PICOBOT_WEBSOCKET_TOKEN")
+	}
+	if ch.Signal.Enabled {
+		v.required("channels.signal.addr", ch.Signal.Addr, "the signal-cli JSON-RPC socket path or host:port")
+	}
+	v.nonNegative("channels.inbound.maxMessageLength", ch.Inbound.MaxMessageLength)
+	v.nonNegative("channels.inbound.maxPerMinute", ch.Inbound.MaxPerMinute)
+	v.nonNegative("channels.inbound.duplicateWindowS", ch.Inbound.DuplicateWindowS)
+	v.nonNegative("channels.inbound.muteMinutes", ch.Inbound.MuteMinutes)
+
+	for name, pc := range map[string]*ProviderConfig{"openai": c.Providers.OpenAI, "anthropic": c.Providers.Anthropic,
+		"groq": c.Providers.Groq, "mistral": c.Providers.Mistral} {
+		if pc == nil {
+			continue
+		}
+		p := "providers." + name + "."
+		v.httpURL(p+"apiBase", pc.APIBase)
+		if r := pc.Retry; r != nil {
+			if r.MaxRetries != nil {
+				v.nonNegative(p+"retry.maxRetries", *r.MaxRetries)
+			}
+			v.nonNegative(p+"retry.baseDelayMs", r.BaseDelayMs)
+			v.nonNegative(p+"retry.maxDelayMs", r.MaxDelayMs)
+		}
+	}
+
+	t := c.Tools
+	v.nonNegative("tools.httpRequest.timeoutS", t.HTTPRequest.TimeoutS)
+	v.nonNegative("tools.httpRequest.maxResponseBytes", t.HTTPRequest.MaxResponseBytes)
+	v.oneOf("tools.search.backend", t.Search.Backend, "duckduckgo", "searxng", "brave")
+	switch t.Search.Backend {
+	case "searxng":
+		v.required("tools.search.url", t.Search.URL, "the URL of the SearXNG instance")
+		v.httpURL("tools.search.url", t.Search.URL)
+	case "brave":
+		v.required("tools.search.apiKey", t.Search.APIKey, "the Brave Search API key, or set PICOBOT_SEARCH_API_KEY")
+	}
+	v.oneOf("tools.calendar.provider", t.Calendar.Provider, "caldav", "google")
+	switch t.Calendar.Provider {
+	case "caldav":
+		v.required("tools.calendar.url", t.Calendar.URL, "the calendar collection URL")
+	case "google":
+		v.required("tools.calendar.clientId", t.Calendar.ClientID, "the OAuth client ID")
+		v.required("tools.calendar.clientSecret", t.Calendar.ClientSecret, "the OAuth client secret")
+		v.required("tools.calendar.refreshToken", t.Calendar.RefreshToken, "a refresh token with the calendar.events scope")
+	}
+	for name, m := range t.MCP {
+		p := "tools.mcp." + name + "."
+		switch {
+		case m.Command == "" && m.URL == "":
+			v.addf(p+"command", "required: the command starting the server, or set url")
+		case m.Command != "" && m.URL != "":
+			v.addf(p+"url", "set either command or url, not both")
+		}
+		v.httpURL(p+"url", m.URL)
+		v.nonNegative(p+"timeoutS", m.TimeoutS)
+	}
+	v.nonNegative("tools.exec.maxConcurrent", t.Exec.MaxConcurrent)
+	for ch, profile := range t.Exec.Channels {
+		v.oneOf("tools.exec.channels."+ch, profile, execProfiles...)
+	}
+	for name, ep := range t.Exec.Profiles {
+		p := "tools.exec.profiles." + name + "."
+		v.oneOf(p+"base", ep.Base, execProfiles...)
+		v.oneOf(p+"policy", ep.Policy, "allowlist", "denylist")
+		v.oneOf(p+"sandbox", ep.Sandbox, "workspace", "none", "container")
+		if ep.Sandbox == "container" && (ep.Container == nil || ep.Container.Image == "") {
+			v.addf(p+"container.image", "required: the image commands run in when sandbox is \"container\"")
+		}
+		v.nonNegative(p+"timeoutS", ep.TimeoutS)
+		v.nonNegative(p+"maxOutputBytes", ep.MaxOutputBytes)
+		v.nonNegative(p+"cpuSeconds", ep.CPUSeconds)
+		v.nonNegative(p+"memoryMB", ep.MemoryMB)
+	}
+	for i, r := range t.Approvals {
+		p := fmt.Sprintf("tools.approvals[%d].", i)
+		v.required(p+"tool", r.Tool, "the name of the tool the rule applies to")
+		for arg, re := range r.Args {
+			v.regexp(p+"args."+arg, re)
+		}
+	}
+	for i, m := range t.Middleware {
+		p := fmt.Sprintf("tools.middleware[%d].", i)
+		v.required(p+"type", m.Type, "log, rateLimit or confirm")
+		v.oneOf(p+"type", m.Type, "log", "rateLimit", "confirm")
+		if m.Type == "rateLimit" && m.PerMinute <= 0 {
+			v.addf(p+"perMinute", "must be positive for a rateLimit middleware, got %d", m.PerMinute)
+		}
+	}
+
+	roles := []string{"owner", "user", "readonly"}
+	for role := range c.Access.Roles {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles[3:])
+	v.oneOf("access.defaultRole", c.Access.DefaultRole, roles...)
+	for id, role := range c.Access.Users {
+		v.oneOf("access.users."+id, role, roles...)
+	}
+
+	v.oneOf("moderation.mode", c.Moderation.Mode, "block", "flag")
+	v.oneOf("moderation.provider", c.Moderation.Provider, "openai")
+	for model, price := range c.Usage.Prices {
+		v.between("usage.prices."+model+".input", price.Input, 0, 1e6)
+		v.between("usage.prices."+model+".output", price.Output, 0, 1e6)
+	}
+
+	m := c.Memory
+	v.oneOf("memory.ranker", m.Ranker, "hybrid", "llm", "simple")
+	v.nonNegative("memory.reflectEvery", m.ReflectEvery)
+	v.nonNegative("memory.topK", m.TopK)
+	v.nonNegative("memory.rankCandidates", m.RankCandidates)
+	if m.ConsolidateAt != "" && !isClock(m.ConsolidateAt) {
+		v.addf("memory.consolidateAt", "invalid time %q (want HH:MM, e.g. \"03:30\")", m.ConsolidateAt)
+	}
+
+	for i, r := range c.Redaction.Rules {
+		p := fmt.Sprintf("redaction.rules[%d].pattern", i)
+		v.required(p, r.Pattern, "a regular expression")
+		v.regexp(p, r.Pattern)
+	}
+
+	l := c.Logging
+	if _, err := logging.ParseLevel(l.Level); err != nil {
+		v.addf("logging.level", "unknown level %q (want debug, info, warn or error)", l.Level)
+	}
+	for sub, level := range l.Levels {
+		if _, err := logging.ParseLevel(level); err != nil {
+			v.addf("logging.levels."+sub, "unknown level %q (want debug, info, warn or error)", level)
+		}
+	}
+	v.oneOf("logging.format", l.Format, "text", "json")
+	v.nonNegative("logging.maxSizeMB", l.MaxSizeMB)
+	v.nonNegative("logging.maxBackups", l.MaxBackups)
+	v.httpURL("telemetry.endpoint", c.Telemetry.Endpoint)
+
+	sort.SliceStable(v.problems, func(i, j int) bool { return v.problems[i].Path < v.problems[j].Path })
+	return v.problems
+}
+
+// validator collects the problems found by Validate.
+type validator struct {
+	problems []Problem
+}
+
+func (v *validator) addf(path, format string, args ...any) {
+	v.problems = append(v.problems, Problem{path, fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) nonNegative(path string, n int) {
+	if n < 0 {
+		v.addf(path, "must not be negative, got %d (0 uses the default)", n)
+	}
+}
+
+func (v *validator) between(path string, f, lo, hi float64) {
+	if f < lo || f > hi {
+		v.addf(path, "must be between %g and %g, got %g", lo, hi, f)
+	}
+}
+
+// oneOf reports a value that is set but not one of allowed.
+func (v *validator) oneOf(path, s string, allowed ...string) {
+	if s != "" && !slices.Contains(allowed, s) {
+		v.addf(path, "unknown value %q (want %s)", s, strings.Join(allowed, ", "))
+	}
+}
+
+func (v *validator) required(path, s, what string) {
+	if strings.TrimSpace(s) == "" {
+		v.addf(path, "required: %s", what)
+	}
+}
+
+func (v *validator) httpURL(path, s string) {
+	if s == "" {
+		return
+	}
+	if u, err := url.Parse(s); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.addf(path, "must be an http:// or https:// URL, got %q", s)
+	}
+}
+
+func (v *validator) regexp(path, re string) {
+	if _, err := regexp.Compile(re); err != nil {
+		v.addf(path, "invalid regular expression: %v", err)
+	}
+}
+
+// isClock reports whether s is a time of day as HH:MM.
+func isClock(s string) bool {
+	_, err := time.Parse("15:04", s)
+	return err == nil
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// clearEnv unsets the environment overrides of LoadConfig and points
+// PICOBOT_HOME to a temp dir, which it returns.
+func clearEnv(t *testing.T) string {
+	for _, k := range []string{"GIO_LLM_API_KEY", "PICOBOT_LLM_API_KEY", "OPENAI_API_KEY", "GIO_LLM_MODEL", "PICOBOT_LLM_MODEL", "PICOBOT_MODEL",
+		"GIO_TELEGRAM_TOKEN", "PICOBOT_TELEGRAM_TOKEN", "PICOBOT_GATEWAY_TELEGRAM_TOKEN", "GIO_TELEGRAM_ALLOWED_USERS", "PICOBOT_TELEGRAM_ALLOWED_USERS", "TELEGRAM_ALLOW_FROM"} {
+		t.Setenv(k, "")
+	}
+	home := t.TempDir()
+	t.Setenv("PICOBOT_HOME", home)
+	return home
+}
+
+func TestLoadConfigFormats(t *testing.T) {
+	files := map[string]string{
+		"config.json": `{"agents": {"defaults": {"model": "gpt-4o", "maxTokens": 4000}},
+  "channels": {"telegram": {"enabled": true, "token": "123:abc", "allowFrom": ["42"]}},
+  "tools": {"mcp": {"fs": {"command": "mcp-fs", "args": ["/data"]}}},
+  "memory": {"consolidateAt": "03:30"}}`,
+		"config.yaml": `agents:
+  defaults:
+    model: gpt-4o
+    maxTokens: 4000
+channels:
+  telegram:
+    enabled: true
+    token: "123:abc"
+    allowFrom: ["42"]
+tools:
+  mcp:
+    fs:
+      command: mcp-fs
+      args: [/data]
+memory:
+  consolidateAt: "03:30"
+`,
+		"config.toml": `[agents.defaults]
+model = "gpt-4o"
+maxTokens = 4000
+
+[channels.telegram]
+enabled = true
+token = "123:abc"
+allowFrom = ["42"]
+
+[tools.mcp.fs]
+command = "mcp-fs"
+args = ["/data"]
+
+[memory]
+consolidateAt = 03:30
+`,
+	}
+	for name, data := range files {
+		home := clearEnv(t)
+		if err := os.WriteFile(filepath.Join(home, name), []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if got := ConfigPath(); got != filepath.Join(home, name) {
+			t.Fatalf("ConfigPath = %s, want %s", got, name)
+		}
+		cfg, err := LoadConfig()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		d := cfg.Agents.Defaults
+		if d.Model != "gpt-4o" || d.MaxTokens != 4000 || d.MaxToolIterations != 100 {
+			t.Errorf("%s: agent defaults %+v", name, d)
+		}
+		if tg := cfg.Channels.Telegram; !tg.Enabled || tg.Token != "123:abc" || len(tg.AllowFrom) != 1 || tg.AllowFrom[0] != "42" {
+			t.Errorf("%s: telegram %+v", name, tg)
+		}
+		if m := cfg.Tools.MCP["fs"]; m.Command != "mcp-fs" || len(m.Args) != 1 {
+			t.Errorf("%s: mcp %+v", name, m)
+		}
+		if cfg.Memory.ConsolidateAt != "03:30" {
+			t.Errorf("%s: consolidateAt %q", name, cfg.Memory.ConsolidateAt)
+		}
+	}
+}
+
+func TestLoadConfigReportsProblems(t *testing.T) {
+	home := clearEnv(t)
+	data := `agents:
+  defaults:
+    maxTokns: 4000
+    temperature: 3
+    requestTimeoutS: -1
+  routes:
+    discord: coder
+channels:
+  telegram:
+    enabled: true
+tools:
+  mcp:
+    fs:
+      args: [/data]
+logging:
+  level: loud
+`
+	if err := os.WriteFile(filepath.Join(home, "config.yaml"), []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig()
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected a ValidationError, got %v", err)
+	}
+	if cfg.Agents.Defaults.Temperature == nil || *cfg.Agents.Defaults.Temperature != 3 {
+		t.Fatalf("the config should still be returned, got %+v", cfg.Agents.Defaults)
+	}
+	want := map[string]string{
+		"agents.defaults.maxTokns":        `did you mean "maxTokens"?`,
+		"agents.defaults.temperature":     "between 0 and 2",
+		"agents.defaults.requestTimeoutS": "must not be negative",
+		"agents.routes.discord":           `no agent "coder"`,
+		"channels.telegram.token":         "required",
+		"tools.mcp.fs.command":            "required",
+		"logging.level":                   `unknown level "loud"`,
+	}
+	for _, p := range verr.Problems {
+		if msg, ok := want[p.Path]; !ok || !strings.Contains(p.Message, msg) {
+			t.Errorf("unexpected problem %s", p)
+		}
+		delete(want, p.Path)
+	}
+	for path := range want {
+		t.Errorf("no problem reported for %s", path)
+	}
+}
+
+func TestDefaultConfigIsValid(t *testing.T) {
+	if problems := Validate(DefaultConfig()); len(problems) > 0 {
+		t.Fatalf("default config has problems: %v", problems)
+	}
+	home := clearEnv(t)
+	if err := SaveConfig(DefaultConfig(), filepath.Join(home, "config.json")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(); err != nil {
+		t.Fatalf("saved default config does not load cleanly: %v", err)
+	}
+}
@@ -1,35 +1,59 @@
 package cron
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/kr0nicas/picobot/internal/logging"
 )
 
+var cronLog = logging.For("cron")
+
 // Job represents a scheduled task.
 type Job struct {
-	ID        string
-	Name      string
-	Message   string
-	FireAt    time.Time
-	Channel   string // originating channel (e.g., "telegram")
-	ChatID    string // originating chat ID
-	Recurring bool   // if true, re-schedule after firing
-	Interval  time.Duration
-	fired     bool
+	ID        string        `json:"id"`
+	Name      string        `json:"name"`
+	Message   string        `json:"message"`
+	FireAt    time.Time     `json:"fireAt"`
+	Channel   string        `json:"channel"`             // originating channel (e.g., "telegram")
+	ChatID    string        `json:"chatId"`              // originating chat ID
+	Recurring bool          `json:"recurring,omitempty"` // if true, re-schedule after firing
+	Interval  time.Duration `json:"interval,omitempty"`
+	// Schedule is the cron expression of jobs that fire on a calendar
+	// schedule, evaluated in Timezone (default: the scheduler's location).
+	Schedule string `json:"schedule,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+	Paused   bool   `json:"paused,omitempty"`
+	// Reminder marks one-shot reminders set with the remind tool.
+	Reminder bool `json:"reminder,omitempty"`
+	// Role is the role of the sender who scheduled the job; it runs with it.
+	Role  string `json:"role,omitempty"`
+	fired bool
+	sched *Schedule
+	loc   *time.Location
 }
 
 // FireCallback is called when a job fires. The scheduler passes the job details.
 type FireCallback func(job Job)
 
-// Scheduler manages in-memory scheduled jobs and fires them when due.
+// Scheduler manages scheduled jobs and fires them when due. Jobs are kept
+// in memory and, after Load, saved to a file on every change.
 type Scheduler struct {
 	mu       sync.Mutex
 	jobs     map[string]*Job
 	callback FireCallback
 	nextID   int
 	running  bool
+	path     string         // where jobs are saved; empty keeps them in memory
+	loc      *time.Location // default location of cron schedules
 }
 
 // NewScheduler creates a new scheduler with the given fire callback.
@@ -37,15 +61,118 @@ func NewScheduler(callback FireCallback) *Scheduler {
 	return &Scheduler{
 		jobs:     make(map[string]*Job),
 		callback: callback,
+		loc:      time.Local,
+	}
+}
+
+// StorePath returns the jobs file of a workspace.
+func StorePath(workspace string) string { return filepath.Join(workspace, "cron.json") }
+
+// SetLocation sets the time zone of cron schedules that don't name one.
+func (s *Scheduler) SetLocation(loc *time.Location) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loc = loc
+	now := time.Now()
+	for _, j := range s.jobs {
+		if j.sched != nil && j.Timezone == "" {
+			j.loc = loc
+			if j.FireAt.After(now) {
+				j.FireAt = j.sched.Next(now.In(loc))
+			}
+		}
 	}
 }
 
-// Add schedules a new job. Returns the job ID.
-func (s *Scheduler) Add(name, message string, delay time.Duration, channel, chatID string) string {
+// Location returns the time zone of cron schedules that don't name one.
+func (s *Scheduler) Location() *time.Location {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	return s.loc
+}
+
+// Load reads the jobs saved at path and saves every later change there.
+// A missing file means no jobs. Jobs that came due while the scheduler
+// wasn't running fire once on the next tick.
+func (s *Scheduler) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	var jobs []*Job
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &jobs); err != nil {
+			return fmt.Errorf("cron: %s: %w", path, err)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.path = path
+	for _, j := range jobs {
+		if j.Schedule != "" {
+			if err := s.prepare(j); err != nil {
+				cronLog.Warn("skipping saved job", "job", j.Name, "id", j.ID, "err", err)
+				continue
+			}
+		}
+		s.jobs[j.ID] = j
+		if n, err := strconv.Atoi(strings.TrimPrefix(j.ID, "job-")); err == nil && n > s.nextID {
+			s.nextID = n
+		}
+	}
+	return nil
+}
+
+// prepare parses the schedule and time zone of a cron job.
+func (s *Scheduler) prepare(j *Job) error {
+	sched, err := ParseSchedule(j.Schedule)
+	if err != nil {
+		return err
+	}
+	loc := s.loc
+	if j.Timezone != "" {
+		if loc, err = time.LoadLocation(j.Timezone); err != nil {
+			return fmt.Errorf("cron: unknown time zone %q", j.Timezone)
+		}
+	}
+	j.sched, j.loc = sched, loc
+	return nil
+}
+
+// save writes the jobs to the store file, if any. s.mu must be held.
+func (s *Scheduler) save() {
+	if s.path == "" {
+		return
+	}
+	jobs := make([]*Job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, j)
+	}
+	sort.Slice(jobs, func(i, k int) bool { return jobs[i].ID < jobs[k].ID })
+	b, err := json.MarshalIndent(jobs, "", "  ")
+	if err == nil {
+		tmp := s.path + ".tmp"
+		if err = os.WriteFile(tmp, b, 0644); err == nil {
+			err = os.Rename(tmp, s.path)
+		}
+	}
+	if err != nil {
+		cronLog.Error("saving jobs failed", "path", s.path, "err", err)
+	}
+}
+
+// newID returns the next job ID. s.mu must be held.
+func (s *Scheduler) newID() string {
 	s.nextID++
-	id := fmt.Sprintf("job-%d", s.nextID)
+	return fmt.Sprintf("job-%d", s.nextID)
+}
+
+// Add schedules a new job for the chat, which runs with role when it fires.
+// Returns the job ID.
+func (s *Scheduler) Add(name, message string, delay time.Duration, channel, chatID, role string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.newID()
 	s.jobs[id] = &Job{
 		ID:      id,
 		Name:    name,
@@ -53,17 +180,39 @@ func (s *Scheduler) Add(name, message string, delay time.Duration, channel, chat
 		FireAt:  time.Now().Add(delay),
 		Channel: channel,
 		ChatID:  chatID,
+		Role:    role,
 	}
-	log.Printf("cron: scheduled job %q (%s) to fire in %v", name, id, delay)
+	s.save()
+	cronLog.Info("scheduled job", "job", name, "id", id, "in", delay)
+	return id
+}
+
+// AddReminder schedules a one-shot reminder at the given time. Returns the
+// job ID.
+func (s *Scheduler) AddReminder(message string, at time.Time, channel, chatID, role string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.newID()
+	s.jobs[id] = &Job{
+		ID:       id,
+		Name:     "reminder",
+		Message:  message,
+		FireAt:   at,
+		Channel:  channel,
+		ChatID:   chatID,
+		Reminder: true,
+		Role:     role,
+	}
+	s.save()
+	cronLog.Info("scheduled reminder", "id", id, "at", at)
 	return id
 }
 
 // AddRecurring schedules a recurring job. Returns the job ID.
-func (s *Scheduler) AddRecurring(name, message string, interval time.Duration, channel, chatID string) string {
+func (s *Scheduler) AddRecurring(name, message string, interval time.Duration, channel, chatID, role string) string {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.nextID++
-	id := fmt.Sprintf("job-%d", s.nextID)
+	id := s.newID()
 	s.jobs[id] = &Job{
 		ID:        id,
 		Name:      name,
@@ -73,18 +222,73 @@ func (s *Scheduler) AddRecurring(name, message string, interval time.Duration, c
 		ChatID:    chatID,
 		Recurring: true,
 		Interval:  interval,
+		Role:      role,
 	}
-	log.Printf("cron: scheduled recurring job %q (%s) every %v", name, id, interval)
+	s.save()
+	cronLog.Info("scheduled recurring job", "job", name, "id", id, "every", interval)
 	return id
 }
 
+// AddCron schedules a job that fires whenever the cron expression matches,
+// in time zone tz (an IANA name; empty uses the scheduler's location).
+func (s *Scheduler) AddCron(name, message, expr, tz, channel, chatID, role string) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j := &Job{
+		Name:      name,
+		Message:   message,
+		Channel:   channel,
+		ChatID:    chatID,
+		Recurring: true,
+		Schedule:  expr,
+		Timezone:  tz,
+		Role:      role,
+	}
+	if err := s.prepare(j); err != nil {
+		return Job{}, err
+	}
+	j.FireAt = j.sched.Next(time.Now().In(j.loc))
+	if j.FireAt.IsZero() {
+		return Job{}, fmt.Errorf("cron: %q never fires", expr)
+	}
+	j.ID = s.newID()
+	s.jobs[j.ID] = j
+	s.save()
+	cronLog.Info("scheduled job", "job", name, "id", j.ID, "expr", expr, "next", j.FireAt)
+	return *j, nil
+}
+
+// SetPaused pauses or resumes a job. Returns true if found. A resumed
+// job's next firing is computed from now, so missed ones are skipped.
+func (s *Scheduler) SetPaused(id string, paused bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	if !ok {
+		return false
+	}
+	if j.Paused && !paused {
+		now := time.Now()
+		switch {
+		case j.sched != nil:
+			j.FireAt = j.sched.Next(now.In(j.loc))
+		case j.Recurring && j.FireAt.Before(now):
+			j.FireAt = now.Add(j.Interval)
+		}
+	}
+	j.Paused = paused
+	s.save()
+	return true
+}
+
 // Cancel removes a job by ID. Returns true if found.
 func (s *Scheduler) Cancel(id string) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if _, ok := s.jobs[id]; ok {
 		delete(s.jobs, id)
-		log.Printf("cron: cancelled job %s", id)
+		s.save()
+		cronLog.Info("cancelled job", "id", id)
 		return true
 	}
 	return false
@@ -97,14 +301,15 @@ func (s *Scheduler) CancelByName(name string) bool {
 	for id, j := range s.jobs {
 		if j.Name == name {
 			delete(s.jobs, id)
-			log.Printf("cron: cancelled job %q (%s)", name, id)
+			s.save()
+			cronLog.Info("cancelled job", "job", name, "id", id)
 			return true
 		}
 	}
 	return false
 }
 
-// List returns all pending jobs.
+// List returns all pending jobs, the next to fire first.
 func (s *Scheduler) List() []Job {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -112,6 +317,7 @@ func (s *Scheduler) List() []Job {
 	for _, j := range s.jobs {
 		result = append(result, *j)
 	}
+	sort.Slice(result, func(i, k int) bool { return result[i].FireAt.Before(result[k].FireAt) })
 	return result
 }
 
@@ -121,12 +327,12 @@ func (s *Scheduler) Start(done <-chan struct{}) {
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
 
-	log.Println("cron: scheduler started")
+	cronLog.Info("scheduler started")
 	for {
 		select {
 		case <-done:
 			s.running = false
-			log.Println("cron: scheduler stopped")
+			cronLog.Info("scheduler stopped")
 			return
 		case now := <-ticker.C:
 			s.tick(now)
@@ -138,28 +344,37 @@ func (s *Scheduler) Start(done <-chan struct{}) {
 func (s *Scheduler) tick(now time.Time) {
 	s.mu.Lock()
 	// collect jobs to fire
-	var toFire []*Job
+	var toFire []Job
 	for _, j := range s.jobs {
-		if !j.fired && now.After(j.FireAt) {
-			toFire = append(toFire, j)
+		if !j.fired && !j.Paused && now.After(j.FireAt) {
+			toFire = append(toFire, *j)
 		}
 	}
 	// handle fired jobs while still holding lock
-	for _, j := range toFire {
-		if j.Recurring {
+	for _, f := range toFire {
+		j := s.jobs[f.ID]
+		switch {
+		case j.sched != nil:
+			if j.FireAt = j.sched.Next(now.In(j.loc)); j.FireAt.IsZero() {
+				delete(s.jobs, j.ID)
+			}
+		case j.Recurring:
 			j.FireAt = now.Add(j.Interval)
-		} else {
+		default:
 			j.fired = true
 			delete(s.jobs, j.ID)
 		}
 	}
+	if len(toFire) > 0 {
+		s.save()
+	}
 	s.mu.Unlock()
 
 	// fire callbacks outside lock
 	for _, j := range toFire {
-		log.Printf("cron: firing job %q (%s): %s", j.Name, j.ID, j.Message)
+		cronLog.Info("firing job", "job", j.Name, "id", j.ID, "message", j.Message)
 		if s.callback != nil {
-			s.callback(*j)
+			s.callback(j)
 		}
 	}
 }
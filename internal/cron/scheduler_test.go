@@ -19,7 +19,7 @@ func TestSchedulerFiresJob(t *testing.T) {
 	done := make(chan struct{})
 	go s.Start(done)
 
-	s.Add("test-reminder", "buy cheesecake", 100*time.Millisecond, "telegram", "123")
+	s.Add("test-reminder", "buy cheesecake", 100*time.Millisecond, "telegram", "123", "owner")
 
 	time.Sleep(2 * time.Second)
 	close(done)
@@ -42,8 +42,8 @@ func TestSchedulerFiresJob(t *testing.T) {
 
 func TestSchedulerList(t *testing.T) {
 	s := NewScheduler(nil)
-	s.Add("job-a", "do A", 5*time.Minute, "telegram", "1")
-	s.Add("job-b", "do B", 10*time.Minute, "telegram", "2")
+	s.Add("job-a", "do A", 5*time.Minute, "telegram", "1", "owner")
+	s.Add("job-b", "do B", 10*time.Minute, "telegram", "2", "owner")
 
 	jobs := s.List()
 	if len(jobs) != 2 {
@@ -53,7 +53,7 @@ func TestSchedulerList(t *testing.T) {
 
 func TestSchedulerCancel(t *testing.T) {
 	s := NewScheduler(nil)
-	s.Add("cancel-me", "msg", 5*time.Minute, "telegram", "1")
+	s.Add("cancel-me", "msg", 5*time.Minute, "telegram", "1", "owner")
 
 	if !s.CancelByName("cancel-me") {
 		t.Error("expected CancelByName to return true")
@@ -76,7 +76,7 @@ func TestSchedulerDoesNotFireCancelled(t *testing.T) {
 	done := make(chan struct{})
 	go s.Start(done)
 
-	s.Add("will-cancel", "nope", 100*time.Millisecond, "telegram", "1")
+	s.Add("will-cancel", "nope", 100*time.Millisecond, "telegram", "1", "owner")
 	s.CancelByName("will-cancel")
 
 	time.Sleep(300 * time.Millisecond)
@@ -88,3 +88,92 @@ func TestSchedulerDoesNotFireCancelled(t *testing.T) {
 		t.Errorf("expected 0 fired jobs after cancel, got %d", len(fired))
 	}
 }
+
+func TestScheduleNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no tzdata")
+	}
+	from := time.Date(2026, 3, 27, 9, 0, 0, 0, berlin) // a Friday
+	cases := []struct{ expr, want string }{
+		{"30 8 * * mon-fri", "2026-03-30 08:30"},
+		{"*/15 9-17 * * *", "2026-03-27 09:15"},
+		{"0 0 1 * *", "2026-04-01 00:00"},
+		{"@weekly", "2026-03-29 00:00"},
+		{"0 12 13 * fri", "2026-03-27 12:00"}, // day of month or weekday
+		{"30 2 * * *", "2026-03-28 02:30"},
+		{"0 9 29 feb *", "2028-02-29 09:00"},
+	}
+	for _, c := range cases {
+		s, err := ParseSchedule(c.expr)
+		if err != nil {
+			t.Fatalf("%s: %v", c.expr, err)
+		}
+		if got := s.Next(from).Format("2006-01-02 15:04"); got != c.want {
+			t.Errorf("%s: next = %s, want %s", c.expr, got, c.want)
+		}
+	}
+	for _, bad := range []string{"* * * *", "60 * * * *", "* * * * mon-sun2", "5-1 * * * *", "*/0 * * * *"} {
+		if _, err := ParseSchedule(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestSchedulerPersistsJobs(t *testing.T) {
+	path := StorePath(t.TempDir())
+	s := NewScheduler(nil)
+	if err := s.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	job, err := s.AddCron("standup", "post the standup notes", "0 9 * * mon-fri", "Europe/Berlin", "telegram", "1", "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Add("once", "call mom", time.Hour, "telegram", "1", "owner")
+	s.SetPaused(job.ID, true)
+
+	restarted := NewScheduler(nil)
+	if err := restarted.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	jobs := restarted.List()
+	if len(jobs) != 2 {
+		t.Fatalf("expected 2 saved jobs, got %+v", jobs)
+	}
+	var saved Job
+	for _, j := range jobs {
+		if j.ID == job.ID {
+			saved = j
+		}
+	}
+	if saved.Schedule != "0 9 * * mon-fri" || saved.Timezone != "Europe/Berlin" || !saved.Paused || !saved.FireAt.Equal(job.FireAt) {
+		t.Fatalf("job not restored: %+v", saved)
+	}
+	if id := restarted.Add("later", "x", time.Hour, "telegram", "1", "owner"); id != "job-3" {
+		t.Fatalf("expected IDs to continue after the saved ones, got %s", id)
+	}
+}
+
+func TestSchedulerCronJobReschedules(t *testing.T) {
+	var fired []Job
+	s := NewScheduler(func(job Job) { fired = append(fired, job) })
+	job, err := s.AddCron("hourly", "check the mail", "@hourly", "UTC", "telegram", "1", "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.tick(job.FireAt.Add(time.Second))
+	if len(fired) != 1 {
+		t.Fatalf("expected the job to fire, got %d", len(fired))
+	}
+	next := s.List()[0]
+	if want := job.FireAt.Add(time.Hour); !next.FireAt.Equal(want) {
+		t.Fatalf("next run at %v, want %v", next.FireAt, want)
+	}
+
+	s.SetPaused(job.ID, true)
+	s.tick(next.FireAt.Add(time.Second))
+	if len(fired) != 1 {
+		t.Fatal("a paused job must not fire")
+	}
+}
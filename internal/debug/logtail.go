@@ -0,0 +1,52 @@
+package debug
+
+import (
+	"strings"
+	"sync"
+)
+
+// LogTail is an io.Writer that keeps the last N lines written to it, so the
+// process log can be inspected remotely (e.g. via /admin logs) without a file.
+type LogTail struct {
+	mu      sync.Mutex
+	lines   []string
+	max     int
+	partial string
+}
+
+// NewLogTail creates a LogTail that retains up to max lines.
+func NewLogTail(max int) *LogTail {
+	if max <= 0 {
+		max = 200
+	}
+	return &LogTail{max: max}
+}
+
+// Write implements io.Writer.
+func (l *LogTail) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	text := l.partial + string(p)
+	parts := strings.Split(text, "\n")
+	// the last element is an unterminated line (or "" after a trailing newline)
+	l.partial = parts[len(parts)-1]
+	for _, line := range parts[:len(parts)-1] {
+		l.lines = append(l.lines, line)
+	}
+	if len(l.lines) > l.max {
+		l.lines = l.lines[len(l.lines)-l.max:]
+	}
+	return len(p), nil
+}
+
+// Tail returns up to n of the most recent complete lines, oldest first.
+func (l *LogTail) Tail(n int) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if n <= 0 || n > len(l.lines) {
+		n = len(l.lines)
+	}
+	out := make([]string, n)
+	copy(out, l.lines[len(l.lines)-n:])
+	return out
+}
@@ -0,0 +1,137 @@
+package debug
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+)
+
+// Tracer writes verbose diagnostic traces (full prompts, tool arguments,
+// provider payloads) to a dedicated log file. Tracing can be switched on and
+// off at runtime without restarting the process.
+// A nil *Tracer is valid and never traces.
+type Tracer struct {
+	mu      sync.Mutex
+	enabled atomic.Bool
+	path    string
+	f       *os.File
+	logger  *log.Logger
+	filter  func(string) string
+}
+
+// NewTracer creates a tracer writing to path. If enabled is true the log file
+// is opened immediately; otherwise it is opened on the first SetEnabled(true).
+func NewTracer(path string, enabled bool) (*Tracer, error) {
+	t := &Tracer{path: path}
+	if enabled {
+		if err := t.SetEnabled(true); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// Path returns the trace log file path.
+func (t *Tracer) Path() string {
+	if t == nil {
+		return ""
+	}
+	return t.path
+}
+
+// Enabled reports whether tracing is currently on.
+func (t *Tracer) Enabled() bool {
+	return t != nil && t.enabled.Load()
+}
+
+// SetEnabled turns tracing on or off. The log file is opened lazily the first
+// time tracing is enabled and kept open until Close.
+func (t *Tracer) SetEnabled(on bool) error {
+	if t == nil {
+		return fmt.Errorf("debug: tracer not configured")
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if on && t.f == nil {
+		if err := os.MkdirAll(filepath.Dir(t.path), 0o755); err != nil {
+			return fmt.Errorf("debug: create log dir: %w", err)
+		}
+		f, err := os.OpenFile(t.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return fmt.Errorf("debug: open log file: %w", err)
+		}
+		t.f = f
+		t.logger = log.New(f, "", log.LstdFlags|log.Lmicroseconds)
+	}
+	t.enabled.Store(on)
+	if t.logger != nil {
+		if on {
+			t.logger.Println("tracing enabled")
+		} else {
+			t.logger.Println("tracing disabled")
+		}
+	}
+	return nil
+}
+
+// SetFilter installs a function applied to every trace line before it is
+// written, e.g. to redact secrets.
+func (t *Tracer) SetFilter(f func(string) string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.filter = f
+}
+
+// Tracef writes a formatted trace line if tracing is enabled.
+func (t *Tracer) Tracef(format string, args ...interface{}) {
+	if !t.Enabled() {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.logger == nil {
+		return
+	}
+	line := fmt.Sprintf(format, args...)
+	if t.filter != nil {
+		line = t.filter(line)
+	}
+	t.logger.Print(line)
+}
+
+// TraceJSON writes label followed by v encoded as indented JSON if tracing is enabled.
+func (t *Tracer) TraceJSON(label string, v interface{}) {
+	if !t.Enabled() {
+		return
+	}
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Tracef("%s: (unencodable: %v)", label, err)
+		return
+	}
+	t.Tracef("%s:\n%s", label, b)
+}
+
+// Close closes the underlying log file.
+func (t *Tracer) Close() error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.enabled.Store(false)
+	if t.f == nil {
+		return nil
+	}
+	err := t.f.Close()
+	t.f = nil
+	t.logger = nil
+	return err
+}
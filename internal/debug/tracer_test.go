@@ -0,0 +1,55 @@
+package debug
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTracerToggle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "debug.log")
+	tr, err := NewTracer(path, false)
+	if err != nil {
+		t.Fatalf("NewTracer: %v", err)
+	}
+	defer tr.Close()
+
+	tr.Tracef("hidden %d", 1)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected no log file while disabled, stat err=%v", err)
+	}
+
+	if err := tr.SetEnabled(true); err != nil {
+		t.Fatalf("SetEnabled: %v", err)
+	}
+	tr.Tracef("visible %d", 2)
+	tr.TraceJSON("payload", map[string]string{"k": "v"})
+	if err := tr.SetEnabled(false); err != nil {
+		t.Fatalf("SetEnabled: %v", err)
+	}
+	tr.Tracef("hidden %d", 3)
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	s := string(b)
+	if !strings.Contains(s, "visible 2") || !strings.Contains(s, `"k": "v"`) {
+		t.Fatalf("expected traces in log, got %q", s)
+	}
+	if strings.Contains(s, "hidden") {
+		t.Fatalf("expected disabled traces to be dropped, got %q", s)
+	}
+}
+
+func TestNilTracerIsNoop(t *testing.T) {
+	var tr *Tracer
+	if tr.Enabled() {
+		t.Fatalf("nil tracer should be disabled")
+	}
+	tr.Tracef("nothing")
+	if err := tr.Close(); err != nil {
+		t.Fatalf("Close on nil tracer: %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+// Package feeds parses RSS and Atom feeds and keeps track of subscriptions
+// and the items already seen, so only new posts are reported.
+package feeds
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Feed is a parsed RSS or Atom feed.
+type Feed struct {
+	Title string
+	Items []Item
+}
+
+// Item is one post of a feed.
+type Item struct {
+	ID        string // guid or id, else the link
+	Title     string
+	Link      string
+	Published time.Time // zero if the feed doesn't say
+}
+
+type rssDoc struct {
+	Channel struct {
+		Title string    `xml:"title"`
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	Items []rssItem `xml:"item"` // RSS 1.0 keeps items outside the channel
+}
+
+type rssItem struct {
+	GUID    string `xml:"guid"`
+	Title   string `xml:"title"`
+	Link    string `xml:"link"`
+	PubDate string `xml:"pubDate"`
+	Date    string `xml:"http://purl.org/dc/elements/1.1/ date"`
+	About   string `xml:"about,attr"`
+}
+
+type atomDoc struct {
+	Title   string `xml:"title"`
+	Entries []struct {
+		ID    string `xml:"id"`
+		Title string `xml:"title"`
+		Links []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+		Published string `xml:"published"`
+		Updated   string `xml:"updated"`
+	} `xml:"entry"`
+}
+
+// Parse parses an RSS (0.9x, 1.0, 2.0) or Atom feed.
+func Parse(data []byte) (*Feed, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	dec.CharsetReader = func(charset string, r io.Reader) (io.Reader, error) { return r, nil }
+	var root string
+	for root == "" {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("feeds: not a feed: %w", err)
+		}
+		if se, ok := tok.(xml.StartElement); ok {
+			root = strings.ToLower(se.Name.Local)
+			switch root {
+			case "rss", "rdf":
+				var doc rssDoc
+				if err := dec.DecodeElement(&doc, &se); err != nil {
+					return nil, fmt.Errorf("feeds: parse rss: %w", err)
+				}
+				return doc.feed(), nil
+			case "feed":
+				var doc atomDoc
+				if err := dec.DecodeElement(&doc, &se); err != nil {
+					return nil, fmt.Errorf("feeds: parse atom: %w", err)
+				}
+				return doc.feed(), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("feeds: not a feed: root element <%s>", root)
+}
+
+func (d *rssDoc) feed() *Feed {
+	f := &Feed{Title: strings.TrimSpace(d.Channel.Title)}
+	for _, it := range append(d.Channel.Items, d.Items...) {
+		item := Item{
+			ID:        firstNonEmpty(it.GUID, it.About, it.Link, it.Title),
+			Title:     strings.TrimSpace(it.Title),
+			Link:      strings.TrimSpace(it.Link),
+			Published: parseTime(firstNonEmpty(it.PubDate, it.Date)),
+		}
+		if item.ID != "" {
+			f.Items = append(f.Items, item)
+		}
+	}
+	return f
+}
+
+func (d *atomDoc) feed() *Feed {
+	f := &Feed{Title: strings.TrimSpace(d.Title)}
+	for _, e := range d.Entries {
+		item := Item{Title: strings.TrimSpace(e.Title), Published: parseTime(firstNonEmpty(e.Published, e.Updated))}
+		for _, l := range e.Links {
+			if l.Rel == "" || l.Rel == "alternate" {
+				item.Link = strings.TrimSpace(l.Href)
+				break
+			}
+		}
+		if item.ID = firstNonEmpty(e.ID, item.Link, item.Title); item.ID != "" {
+			f.Items = append(f.Items, item)
+		}
+	}
+	return f
+}
+
+var timeLayouts = []string{time.RFC1123Z, time.RFC1123, time.RFC3339, "Mon, 2 Jan 2006 15:04:05 -0700", "Mon, 2 Jan 2006 15:04:05 MST", "2006-01-02"}
+
+func parseTime(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
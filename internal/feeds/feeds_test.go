@@ -0,0 +1,75 @@
+package feeds
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+const rss = `<?xml version="1.0" encoding="ISO-8859-1"?>
+<rss version="2.0"><channel><title>Blog</title>
+<item><title>Second</title><link>https://blog.example/2</link><guid>p2</guid><pubDate>Tue, 02 Jan 2024 10:00:00 +0000</pubDate></item>
+<item><title>First</title><link>https://blog.example/1</link><guid>p1</guid></item>
+</channel></rss>`
+
+const atom = `<feed xmlns="http://www.w3.org/2005/Atom"><title>News</title>
+<entry><id>urn:1</id><title>Hello</title><link rel="alternate" href="https://news.example/1"/><updated>2024-01-02T10:00:00Z</updated></entry>
+</feed>`
+
+func TestParse(t *testing.T) {
+	f, err := Parse([]byte(rss))
+	if err != nil {
+		t.Fatalf("parse rss: %v", err)
+	}
+	if f.Title != "Blog" || len(f.Items) != 2 || f.Items[0].ID != "p2" || f.Items[0].Link != "https://blog.example/2" || f.Items[0].Published.IsZero() {
+		t.Fatalf("unexpected rss feed: %+v", f)
+	}
+	f, err = Parse([]byte(atom))
+	if err != nil {
+		t.Fatalf("parse atom: %v", err)
+	}
+	if f.Title != "News" || len(f.Items) != 1 || f.Items[0].ID != "urn:1" || f.Items[0].Link != "https://news.example/1" || f.Items[0].Published.IsZero() {
+		t.Fatalf("unexpected atom feed: %+v", f)
+	}
+	if _, err := Parse([]byte("<html><body>hi</body></html>")); err == nil {
+		t.Fatalf("expected an HTML page to be rejected")
+	}
+}
+
+func TestStoreFetchNew(t *testing.T) {
+	s := NewStore(filepath.Join(t.TempDir(), "feeds.json"))
+	f, _ := Parse([]byte(rss))
+	if err := s.Subscribe(Subscription{Name: "blog", URL: "https://blog.example/feed"}, f.Items); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	if err := s.Subscribe(Subscription{Name: "Blog", URL: "https://other.example/feed"}, nil); err == nil {
+		t.Fatalf("expected a duplicate name to be rejected")
+	}
+
+	body := rss
+	fetch := func(ctx context.Context, url string) ([]byte, error) { return []byte(body), nil }
+	updates, err := s.FetchNew(context.Background(), fetch, nil)
+	if err != nil || len(updates) != 1 || len(updates[0].Items) != 0 {
+		t.Fatalf("expected no new items right after subscribing, got %+v (%v)", updates, err)
+	}
+
+	body = `<rss><channel><item><title>Third</title><guid>p3</guid></item>` + rss[len(`<?xml version="1.0" encoding="ISO-8859-1"?>
+<rss version="2.0"><channel><title>Blog</title>`):]
+	updates, err = s.FetchNew(context.Background(), fetch, nil)
+	if err != nil || len(updates) != 1 || len(updates[0].Items) != 1 || updates[0].Items[0].ID != "p3" {
+		t.Fatalf("expected the new item, got %+v (%v)", updates, err)
+	}
+	if updates[0].Subscription.LastChecked.IsZero() {
+		t.Fatalf("expected LastChecked to be recorded")
+	}
+	if updates, _ = s.FetchNew(context.Background(), fetch, nil); len(updates[0].Items) != 0 {
+		t.Fatalf("expected items to be reported once, got %+v", updates[0].Items)
+	}
+
+	if ok, err := s.Unsubscribe("https://blog.example/feed"); !ok || err != nil {
+		t.Fatalf("unsubscribe: %v, %v", ok, err)
+	}
+	if subs, _ := s.List(); len(subs) != 0 {
+		t.Fatalf("expected no subscriptions, got %+v", subs)
+	}
+}
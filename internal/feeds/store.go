@@ -0,0 +1,232 @@
+package feeds
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxSeen bounds the item IDs remembered per feed.
+const maxSeen = 1000
+
+// fileMu serializes changes to state files; the feeds tool of a workspace
+// and the heartbeat may update the same file at once.
+var fileMu sync.Mutex
+
+// Subscription is a watched feed.
+type Subscription struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// Channel and ChatID are where new posts are announced.
+	Channel     string    `json:"channel,omitempty"`
+	ChatID      string    `json:"chatId,omitempty"`
+	Added       time.Time `json:"added"`
+	LastChecked time.Time `json:"lastChecked,omitempty"`
+	LastError   string    `json:"lastError,omitempty"`
+	Seen        []string  `json:"seen,omitempty"`
+}
+
+// Fetcher downloads a feed.
+type Fetcher func(ctx context.Context, url string) ([]byte, error)
+
+// Update lists the new items of a subscription, or why it couldn't be
+// checked.
+type Update struct {
+	Subscription Subscription
+	Items        []Item
+	Err          error
+}
+
+// Store keeps subscriptions in a JSON file.
+type Store struct {
+	path string
+}
+
+// StorePath returns the state file of a workspace.
+func StorePath(workspace string) string { return filepath.Join(workspace, "feeds.json") }
+
+// NewStore returns the store kept at path. The file is created on the
+// first subscription.
+func NewStore(path string) *Store { return &Store{path: path} }
+
+func (s *Store) load() ([]Subscription, error) {
+	b, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var subs []Subscription
+	if err := json.Unmarshal(b, &subs); err != nil {
+		return nil, fmt.Errorf("feeds: %s: %w", s.path, err)
+	}
+	return subs, nil
+}
+
+func (s *Store) save(subs []Subscription) error {
+	b, err := json.MarshalIndent(subs, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// List returns the subscriptions.
+func (s *Store) List() ([]Subscription, error) {
+	fileMu.Lock()
+	defer fileMu.Unlock()
+	return s.load()
+}
+
+// Subscribe adds sub. The items currently in the feed are marked as seen,
+// so only posts published afterwards are reported.
+func (s *Store) Subscribe(sub Subscription, current []Item) error {
+	fileMu.Lock()
+	defer fileMu.Unlock()
+	subs, err := s.load()
+	if err != nil {
+		return err
+	}
+	for _, o := range subs {
+		if strings.EqualFold(o.Name, sub.Name) || o.URL == sub.URL {
+			return fmt.Errorf("feeds: already subscribed to %s as %q", o.URL, o.Name)
+		}
+	}
+	sub.Seen = nil
+	for _, it := range current {
+		sub.Seen = append(sub.Seen, it.ID)
+	}
+	sub.Seen = trimSeen(sub.Seen)
+	if sub.Added.IsZero() {
+		sub.Added = time.Now()
+	}
+	return s.save(append(subs, sub))
+}
+
+// Unsubscribe removes the subscription with the given name or URL and
+// reports whether there was one.
+func (s *Store) Unsubscribe(key string) (bool, error) {
+	fileMu.Lock()
+	defer fileMu.Unlock()
+	subs, err := s.load()
+	if err != nil {
+		return false, err
+	}
+	for i, o := range subs {
+		if strings.EqualFold(o.Name, key) || o.URL == key {
+			return true, s.save(append(subs[:i], subs[i+1:]...))
+		}
+	}
+	return false, nil
+}
+
+// FetchNew fetches the subscriptions selected by want (nil = all) and
+// returns their unseen items, which are marked as seen. Feeds that cannot be
+// fetched are reported in their Update's Err.
+func (s *Store) FetchNew(ctx context.Context, fetch Fetcher, want func(Subscription) bool) ([]Update, error) {
+	subs, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	// fetch without holding the lock, then merge the results
+	var updates []Update
+	for _, sub := range subs {
+		if want != nil && !want(sub) {
+			continue
+		}
+		u := Update{Subscription: sub}
+		data, err := fetch(ctx, sub.URL)
+		if err == nil {
+			var f *Feed
+			if f, err = Parse(data); err == nil {
+				u.Items = f.Items
+			}
+		}
+		u.Err = err
+		updates = append(updates, u)
+	}
+	if len(updates) == 0 {
+		return nil, nil
+	}
+
+	fileMu.Lock()
+	defer fileMu.Unlock()
+	subs, err = s.load()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i := range updates {
+		u := &updates[i]
+		idx := -1
+		for j := range subs {
+			if subs[j].Name == u.Subscription.Name {
+				idx = j
+			}
+		}
+		if idx < 0 { // unsubscribed meanwhile
+			u.Items = nil
+			continue
+		}
+		sub := &subs[idx]
+		sub.LastChecked = now
+		sub.LastError = ""
+		if u.Err != nil {
+			sub.LastError = u.Err.Error()
+		}
+		seen := make(map[string]bool, len(sub.Seen))
+		for _, id := range sub.Seen {
+			seen[id] = true
+		}
+		var fresh []Item
+		for _, it := range u.Items {
+			if !seen[it.ID] {
+				seen[it.ID] = true
+				fresh = append(fresh, it)
+				sub.Seen = append(sub.Seen, it.ID)
+			}
+		}
+		sub.Seen = trimSeen(sub.Seen)
+		u.Items = fresh
+		u.Subscription = *sub
+	}
+	if err := s.save(subs); err != nil {
+		return nil, err
+	}
+	return updates, nil
+}
+
+func trimSeen(ids []string) []string {
+	if len(ids) > maxSeen {
+		ids = ids[len(ids)-maxSeen:]
+	}
+	return ids
+}
+
+// Format renders the new items of u as a short message.
+func Format(u Update) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "New posts in %s:", u.Subscription.Name)
+	for _, it := range u.Items {
+		title := it.Title
+		if title == "" {
+			title = "(untitled)"
+		}
+		sb.WriteString("\n- " + title)
+		if it.Link != "" {
+			sb.WriteString(" " + it.Link)
+		}
+	}
+	return sb.String()
+}
@@ -2,52 +2,242 @@ package heartbeat
 
 import (
 	"context"
-	"log"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/kr0nicas/picobot/internal/chat"
+	"github.com/kr0nicas/picobot/internal/config"
+	"github.com/kr0nicas/picobot/internal/feeds"
+	"github.com/kr0nicas/picobot/internal/logging"
 )
 
-// StartHeartbeat starts a periodic check that reads HEARTBEAT.md and pushes
-// its content into the agent's inbound chat hub for processing.
-func StartHeartbeat(ctx context.Context, workspace string, interval time.Duration, hub *chat.Hub) {
+var heartbeatLog = logging.For("heartbeat")
+
+// FeedInterval is how often each subscribed feed is polled.
+const FeedInterval = 15 * time.Minute
+
+// TaskKey is the Inbound metadata key naming the task a heartbeat message
+// runs, so the agent can record the outcome with TaskStore.Record.
+const TaskKey = "heartbeatTask"
+
+// Heartbeat is a handle to a running heartbeat that can be paused at runtime.
+type Heartbeat struct {
+	paused atomic.Bool
+
+	mu      sync.Mutex
+	quiet   *QuietHours
+	busy    func() bool // reports an ongoing user conversation
+	heldFor string      // why the last check was skipped, for logging changes
+}
+
+// SetQuietHours sets the daily period in which the heartbeat runs no tasks
+// and announces no feed posts; nil turns quiet hours off. Work that comes
+// due meanwhile runs once they end.
+func (h *Heartbeat) SetQuietHours(q *QuietHours) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.quiet = q
+}
+
+// SetBusy makes the heartbeat hold off while busy reports that a user
+// conversation is in progress, so its work doesn't interleave with it.
+func (h *Heartbeat) SetBusy(busy func() bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.busy = busy
+}
+
+// holding reports whether the check at now is skipped, logging when that
+// changes.
+func (h *Heartbeat) holding(now time.Time) bool {
+	reason := h.holdOff(now)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if reason != h.heldFor {
+		if reason != "" {
+			heartbeatLog.Info("holding off", "reason", reason)
+		} else {
+			heartbeatLog.Info("resuming checks")
+		}
+		h.heldFor = reason
+	}
+	return reason != ""
+}
+
+// holdOff reports why the heartbeat should skip the check at now, or "".
+func (h *Heartbeat) holdOff(now time.Time) string {
+	h.mu.Lock()
+	q, busy := h.quiet, h.busy
+	h.mu.Unlock()
+	switch {
+	case q != nil && q.Contains(now):
+		return "quiet hours " + q.String()
+	case busy != nil && busy():
+		return "a conversation is in progress"
+	}
+	return ""
+}
+
+// QuietHours is a daily period, such as 23:00-08:00, in a time zone.
+type QuietHours struct {
+	from, to int // minutes after midnight
+	loc      *time.Location
+}
+
+// ParseQuietHours parses "HH:MM-HH:MM" in loc. The period may span
+// midnight.
+func ParseQuietHours(s string, loc *time.Location) (*QuietHours, error) {
+	a, b, ok := strings.Cut(strings.ReplaceAll(s, " ", ""), "-")
+	from, err1 := time.Parse("15:04", a)
+	to, err2 := time.Parse("15:04", b)
+	if !ok || err1 != nil || err2 != nil || a == b {
+		return nil, fmt.Errorf("heartbeat: invalid quiet hours %q (want e.g. 23:00-08:00)", s)
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+	return &QuietHours{from: from.Hour()*60 + from.Minute(), to: to.Hour()*60 + to.Minute(), loc: loc}, nil
+}
+
+// Contains reports whether t falls within the quiet hours.
+func (q *QuietHours) Contains(t time.Time) bool {
+	t = t.In(q.loc)
+	m := t.Hour()*60 + t.Minute()
+	if q.from < q.to {
+		return m >= q.from && m < q.to
+	}
+	return m >= q.from || m < q.to
+}
+
+func (q *QuietHours) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", q.from/60, q.from%60, q.to/60, q.to%60)
+}
+
+// Pause suspends heartbeat checks until Resume is called.
+func (h *Heartbeat) Pause() { h.paused.Store(true) }
+
+// Resume re-enables heartbeat checks.
+func (h *Heartbeat) Resume() { h.paused.Store(false) }
+
+// Paused reports whether the heartbeat is currently paused.
+func (h *Heartbeat) Paused() bool { return h.paused.Load() }
+
+// StartHeartbeat starts a periodic check of the tasks in the workspace's
+// heartbeat.json: each task that is due is pushed into the agent's inbound
+// chat hub for processing, with cron schedules evaluated in loc. With a
+// non-nil fetch it also polls the feeds subscribed to in the workspace and
+// its tenants, and announces new posts in the chats that subscribed.
+//
+// A workspace without heartbeat.json gets the list items of its
+// HEARTBEAT.md imported as tasks run every interval.
+func StartHeartbeat(ctx context.Context, workspace string, interval time.Duration, hub *chat.Hub, fetch feeds.Fetcher, loc *time.Location) *Heartbeat {
+	h := &Heartbeat{}
+	store := NewTaskStore(TaskPath(workspace))
+	if n, err := importHeartbeatMD(workspace, store, interval); err != nil {
+		heartbeatLog.Error("importing HEARTBEAT.md failed", "err", err)
+	} else if n > 0 {
+		heartbeatLog.Info("imported tasks from HEARTBEAT.md", "tasks", n, "path", store.path)
+	}
 	go func() {
 		ticker := time.NewTicker(interval)
 		defer ticker.Stop()
-		log.Printf("heartbeat: started (every %v)", interval)
+		heartbeatLog.Info("started", "every", interval)
 		for {
 			select {
 			case <-ctx.Done():
-				log.Println("heartbeat: stopping")
+				heartbeatLog.Info("stopping")
 				return
 			case <-ticker.C:
-				path := filepath.Join(workspace, "HEARTBEAT.md")
-				data, err := os.ReadFile(path)
-				if err != nil {
-					// file doesn't exist or can't be read — skip silently
+				if h.Paused() || h.holding(time.Now()) {
 					continue
 				}
-				content := strings.TrimSpace(string(data))
-				if content == "" {
-					continue
-				}
-
-				// Non-blocking send: skip if hub is busy processing previous message
-				log.Println("heartbeat: sending tasks to agent")
-				select {
-				case hub.In <- chat.Inbound{
-					Channel:  "heartbeat",
-					ChatID:   "system",
-					SenderID: "heartbeat",
-					Content:  "[HEARTBEAT CHECK] Review and execute any pending tasks from HEARTBEAT.md:\n\n" + content,
-				}:
-				default:
-					log.Println("heartbeat: hub busy, skipping heartbeat")
+				if fetch != nil {
+					checkFeeds(ctx, workspace, hub, fetch)
 				}
+				runTasks(store, hub, loc)
 			}
 		}
 	}()
+	return h
+}
+
+// runTasks sends the tasks that are due to the agent and records that
+// they ran. Tasks that don't fit into a busy hub are retried next tick.
+func runTasks(store *TaskStore, hub *chat.Hub, loc *time.Location) {
+	tasks, err := store.List()
+	if err != nil {
+		heartbeatLog.Error("listing tasks failed", "err", err)
+		return
+	}
+	now := time.Now()
+	var sent []string
+	for _, t := range tasks {
+		if !t.Due(now, loc) {
+			continue
+		}
+		// Non-blocking send: skip if hub is busy processing previous message
+		select {
+		case hub.In <- chat.Inbound{
+			Channel:  "heartbeat",
+			ChatID:   "system",
+			SenderID: "heartbeat",
+			Content:  fmt.Sprintf("[HEARTBEAT TASK %q] %s", t.Name, t.Prompt),
+			Metadata: map[string]interface{}{TaskKey: t.Name},
+		}:
+			heartbeatLog.Info("running task", "task", t.Name)
+			sent = append(sent, t.Name)
+		default:
+			heartbeatLog.Info("hub busy, task waits for the next check", "task", t.Name)
+		}
+	}
+	if len(sent) > 0 {
+		if err := store.markRun(sent, now); err != nil {
+			heartbeatLog.Error("recording task runs failed", "err", err)
+		}
+	}
+}
+
+// checkFeeds announces the new posts of every feed that is due.
+func checkFeeds(ctx context.Context, workspace string, hub *chat.Hub, fetch feeds.Fetcher) {
+	paths := []string{feeds.StorePath(workspace)}
+	if tenants, err := filepath.Glob(feeds.StorePath(filepath.Join(config.TenantsDir(workspace), "*"))); err == nil {
+		paths = append(paths, tenants...)
+	}
+	due := func(s feeds.Subscription) bool {
+		// subscriptions made from the heartbeat or CLI have nowhere to go
+		if s.Channel == "" || s.Channel == "heartbeat" || s.Channel == "cli" {
+			return false
+		}
+		return time.Since(s.LastChecked) >= FeedInterval
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		updates, err := feeds.NewStore(path).FetchNew(ctx, fetch, due)
+		if err != nil {
+			heartbeatLog.Error("loading feeds failed", "path", path, "err", err)
+			continue
+		}
+		for _, u := range updates {
+			if u.Err != nil {
+				heartbeatLog.Warn("checking feed failed", "feed", u.Subscription.Name, "err", u.Err)
+				continue
+			}
+			if len(u.Items) == 0 {
+				continue
+			}
+			heartbeatLog.Info("new posts in feed", "feed", u.Subscription.Name, "posts", len(u.Items))
+			select {
+			case hub.Out <- chat.Outbound{Channel: u.Subscription.Channel, ChatID: u.Subscription.ChatID, Content: feeds.Format(u)}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
 }
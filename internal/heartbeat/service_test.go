@@ -0,0 +1,59 @@
+package heartbeat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuietHours(t *testing.T) {
+	q, err := ParseQuietHours("23:00-08:00", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(h, m int) time.Time { return time.Date(2026, 3, 2, h, m, 0, 0, time.UTC) }
+	for _, c := range []struct {
+		t     time.Time
+		quiet bool
+	}{
+		{at(22, 59), false}, {at(23, 0), true}, {at(3, 0), true}, {at(7, 59), true}, {at(8, 0), false}, {at(12, 0), false},
+	} {
+		if q.Contains(c.t) != c.quiet {
+			t.Errorf("Contains(%s) = %v", c.t.Format("15:04"), !c.quiet)
+		}
+	}
+	if q.String() != "23:00-08:00" {
+		t.Fatalf("String() = %q", q.String())
+	}
+
+	day, _ := ParseQuietHours("12:00 - 13:30", time.UTC)
+	if !day.Contains(at(13, 0)) || day.Contains(at(14, 0)) {
+		t.Fatal("daytime quiet hours wrong")
+	}
+	for _, bad := range []string{"", "23:00", "25:00-08:00", "08:00-08:00"} {
+		if _, err := ParseQuietHours(bad, time.UTC); err == nil {
+			t.Errorf("ParseQuietHours(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestHoldOff(t *testing.T) {
+	h := &Heartbeat{}
+	noon := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	if h.holding(noon) {
+		t.Fatal("held off without quiet hours or a conversation")
+	}
+	q, _ := ParseQuietHours("11:00-13:00", time.UTC)
+	h.SetQuietHours(q)
+	if !h.holding(noon) || h.holding(noon.Add(2*time.Hour)) {
+		t.Fatal("quiet hours not honored")
+	}
+	busy := true
+	h.SetBusy(func() bool { return busy })
+	if !h.holding(noon.Add(2 * time.Hour)) {
+		t.Fatal("ran during a conversation")
+	}
+	busy = false
+	if h.holding(noon.Add(2 * time.Hour)) {
+		t.Fatal("still holding off after the conversation")
+	}
+}
@@ -0,0 +1,255 @@
+package heartbeat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/cron"
+)
+
+// MaxFailures is how many runs of a task may fail in a row before it is
+// disabled.
+const MaxFailures = 5
+
+// taskMu serializes changes to task files; the heartbeat, the agent
+// recording results and the heartbeat tool may update them at once.
+var taskMu sync.Mutex
+
+// Task is a periodic job of the heartbeat: a prompt run by the agent every
+// interval or on a cron schedule.
+type Task struct {
+	Name   string `json:"name"`
+	Prompt string `json:"prompt"`
+	// Every is a Go duration such as "1h"; Schedule a cron expression such
+	// as "0 8 * * *". One of them is set.
+	Every     string    `json:"every,omitempty"`
+	Schedule  string    `json:"schedule,omitempty"`
+	Disabled  bool      `json:"disabled,omitempty"`
+	Added     time.Time `json:"added"`
+	LastRun   time.Time `json:"lastRun,omitempty"`
+	LastError string    `json:"lastError,omitempty"`
+	Failures  int       `json:"failures,omitempty"` // consecutive failed runs
+}
+
+// Validate checks the task's name and timing.
+func (t Task) Validate() error {
+	if strings.TrimSpace(t.Name) == "" || strings.TrimSpace(t.Prompt) == "" {
+		return errors.New("heartbeat: a task needs a name and a prompt")
+	}
+	if (t.Every == "") == (t.Schedule == "") {
+		return fmt.Errorf("heartbeat: task %q needs either every or schedule", t.Name)
+	}
+	if t.Every != "" {
+		d, err := time.ParseDuration(t.Every)
+		if err != nil || d < time.Minute {
+			return fmt.Errorf("heartbeat: task %q: every must be a duration of at least 1m, got %q", t.Name, t.Every)
+		}
+		return nil
+	}
+	_, err := cron.ParseSchedule(t.Schedule)
+	return err
+}
+
+// Next returns when the task runs next, with cron schedules in loc.
+func (t Task) Next(loc *time.Location) time.Time {
+	base := t.LastRun
+	if base.IsZero() {
+		base = t.Added
+	}
+	if t.Every != "" {
+		d, _ := time.ParseDuration(t.Every)
+		return base.Add(d)
+	}
+	s, err := cron.ParseSchedule(t.Schedule)
+	if err != nil {
+		return time.Time{}
+	}
+	return s.Next(base.In(loc))
+}
+
+// Due reports whether the task should run at now.
+func (t Task) Due(now time.Time, loc *time.Location) bool {
+	if t.Disabled {
+		return false
+	}
+	next := t.Next(loc)
+	return !next.IsZero() && !next.After(now)
+}
+
+// TaskStore keeps heartbeat tasks in a JSON file.
+type TaskStore struct {
+	path string
+}
+
+// TaskPath returns the task file of a workspace.
+func TaskPath(workspace string) string { return filepath.Join(workspace, "heartbeat.json") }
+
+// NewTaskStore returns the store kept at path. The file is created when
+// the first task is added.
+func NewTaskStore(path string) *TaskStore { return &TaskStore{path: path} }
+
+func (s *TaskStore) load() ([]Task, error) {
+	b, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var tasks []Task
+	if err := json.Unmarshal(b, &tasks); err != nil {
+		return nil, fmt.Errorf("heartbeat: %s: %w", s.path, err)
+	}
+	return tasks, nil
+}
+
+func (s *TaskStore) save(tasks []Task) error {
+	b, err := json.MarshalIndent(tasks, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// List returns the tasks.
+func (s *TaskStore) List() ([]Task, error) {
+	taskMu.Lock()
+	defer taskMu.Unlock()
+	return s.load()
+}
+
+// Add adds a new task.
+func (s *TaskStore) Add(t Task) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	taskMu.Lock()
+	defer taskMu.Unlock()
+	tasks, err := s.load()
+	if err != nil {
+		return err
+	}
+	for _, o := range tasks {
+		if strings.EqualFold(o.Name, t.Name) {
+			return fmt.Errorf("heartbeat: there is already a task %q", o.Name)
+		}
+	}
+	if t.Added.IsZero() {
+		t.Added = time.Now()
+	}
+	return s.save(append(tasks, t))
+}
+
+// Update applies fn to the task with the given name and saves it if it
+// is still valid.
+func (s *TaskStore) Update(name string, fn func(*Task)) (Task, error) {
+	taskMu.Lock()
+	defer taskMu.Unlock()
+	tasks, err := s.load()
+	if err != nil {
+		return Task{}, err
+	}
+	for i := range tasks {
+		if strings.EqualFold(tasks[i].Name, name) {
+			t := tasks[i]
+			fn(&t)
+			if err := t.Validate(); err != nil {
+				return Task{}, err
+			}
+			tasks[i] = t
+			return t, s.save(tasks)
+		}
+	}
+	return Task{}, fmt.Errorf("heartbeat: no task %q", name)
+}
+
+// Remove deletes the task with the given name and reports whether there
+// was one.
+func (s *TaskStore) Remove(name string) (bool, error) {
+	taskMu.Lock()
+	defer taskMu.Unlock()
+	tasks, err := s.load()
+	if err != nil {
+		return false, err
+	}
+	for i, t := range tasks {
+		if strings.EqualFold(t.Name, name) {
+			return true, s.save(append(tasks[:i], tasks[i+1:]...))
+		}
+	}
+	return false, nil
+}
+
+// Record stores the outcome of a run of the named task. After MaxFailures
+// failures in a row the task is disabled.
+func (s *TaskStore) Record(name string, runErr error) error {
+	_, err := s.Update(name, func(t *Task) {
+		if runErr == nil {
+			t.LastError, t.Failures = "", 0
+			return
+		}
+		t.LastError = runErr.Error()
+		t.Failures++
+		if t.Failures >= MaxFailures {
+			t.Disabled = true
+		}
+	})
+	return err
+}
+
+// markRun sets the last run of the named tasks to now.
+func (s *TaskStore) markRun(names []string, now time.Time) error {
+	taskMu.Lock()
+	defer taskMu.Unlock()
+	tasks, err := s.load()
+	if err != nil {
+		return err
+	}
+	for i := range tasks {
+		for _, n := range names {
+			if tasks[i].Name == n {
+				tasks[i].LastRun = now
+			}
+		}
+	}
+	return s.save(tasks)
+}
+
+var (
+	htmlCommentRE = regexp.MustCompile(`(?s)<!--.*?-->`)
+	listItemRE    = regexp.MustCompile(`(?m)^\s*[-*]\s+(.+?)\s*$`)
+)
+
+// importHeartbeatMD turns the list items of a legacy HEARTBEAT.md into
+// tasks run every interval, when the workspace has no task file yet.
+func importHeartbeatMD(workspace string, store *TaskStore, interval time.Duration) (int, error) {
+	if _, err := os.Stat(store.path); !errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	data, err := os.ReadFile(filepath.Join(workspace, "HEARTBEAT.md"))
+	if err != nil {
+		return 0, nil
+	}
+	text := htmlCommentRE.ReplaceAllString(string(data), "")
+	every := max(interval, time.Minute).String()
+	n := 0
+	for i, m := range listItemRE.FindAllStringSubmatch(text, -1) {
+		t := Task{Name: fmt.Sprintf("task-%d", i+1), Prompt: m[1], Every: every}
+		if err := store.Add(t); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
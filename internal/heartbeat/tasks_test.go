@@ -0,0 +1,94 @@
+package heartbeat
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/chat"
+)
+
+func TestTaskDue(t *testing.T) {
+	added := time.Date(2026, 3, 2, 7, 0, 0, 0, time.UTC)
+	every := Task{Name: "a", Prompt: "p", Every: "1h", Added: added}
+	if every.Due(added.Add(59*time.Minute), time.UTC) || !every.Due(added.Add(time.Hour), time.UTC) {
+		t.Fatal("interval task due at the wrong time")
+	}
+	every.LastRun = added.Add(time.Hour)
+	if every.Due(added.Add(90*time.Minute), time.UTC) {
+		t.Fatal("task due again before its interval passed")
+	}
+
+	daily := Task{Name: "b", Prompt: "p", Schedule: "30 8 * * *", Added: added}
+	if daily.Due(added.Add(time.Hour), time.UTC) || !daily.Due(added.Add(90*time.Minute), time.UTC) {
+		t.Fatal("scheduled task due at the wrong time")
+	}
+	daily.Disabled = true
+	if daily.Due(added.Add(2*time.Hour), time.UTC) {
+		t.Fatal("disabled task must not be due")
+	}
+
+	for _, bad := range []Task{{Name: "x", Prompt: "p"}, {Name: "x", Prompt: "p", Every: "10s"}, {Name: "x", Prompt: "p", Every: "1h", Schedule: "@daily"}, {Name: "x", Prompt: "p", Schedule: "daily"}} {
+		if bad.Validate() == nil {
+			t.Errorf("expected %+v to be invalid", bad)
+		}
+	}
+}
+
+func TestTaskStoreRecordDisablesFailingTask(t *testing.T) {
+	s := NewTaskStore(TaskPath(t.TempDir()))
+	if err := s.Add(Task{Name: "check", Prompt: "check the server", Every: "5m"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(Task{Name: "CHECK", Prompt: "again", Every: "5m"}); err == nil {
+		t.Fatal("expected duplicate names to be rejected")
+	}
+	for i := 0; i < MaxFailures; i++ {
+		if err := s.Record("check", errors.New("rate limited")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tasks, _ := s.List()
+	if !tasks[0].Disabled || tasks[0].Failures != MaxFailures || tasks[0].LastError != "rate limited" {
+		t.Fatalf("expected the task to be disabled: %+v", tasks[0])
+	}
+	s.Update("check", func(t *Task) { t.Disabled, t.Failures = false, 0 })
+	s.Record("check", nil)
+	if tasks, _ := s.List(); tasks[0].LastError != "" || tasks[0].Disabled {
+		t.Fatalf("expected a clean task after a successful run: %+v", tasks[0])
+	}
+}
+
+func TestRunTasksSendsDueTasks(t *testing.T) {
+	ws := t.TempDir()
+	os.WriteFile(filepath.Join(ws, "HEARTBEAT.md"), []byte("# Heartbeat\n<!-- Example:\n- not a task\n-->\n- Check server status\n- Summarize unread messages\n"), 0o644)
+	s := NewTaskStore(TaskPath(ws))
+	if n, err := importHeartbeatMD(ws, s, time.Minute); err != nil || n != 2 {
+		t.Fatalf("expected 2 imported tasks, got %d, %v", n, err)
+	}
+	// make the first task due
+	s.Update("task-1", func(t *Task) { t.Added = time.Now().Add(-time.Hour) })
+
+	hub := chat.NewHub(10)
+	runTasks(s, hub, time.UTC)
+	select {
+	case in := <-hub.In:
+		if in.Metadata[TaskKey] != "task-1" || in.Content != `[HEARTBEAT TASK "task-1"] Check server status` {
+			t.Fatalf("unexpected message: %+v", in)
+		}
+	default:
+		t.Fatal("due task was not sent")
+	}
+	if len(hub.In) != 0 {
+		t.Fatal("only the due task should run")
+	}
+	runTasks(s, hub, time.UTC)
+	if len(hub.In) != 0 {
+		t.Fatal("a task that just ran must wait for its interval")
+	}
+	if n, _ := importHeartbeatMD(ws, s, time.Minute); n != 0 {
+		t.Fatal("HEARTBEAT.md must only be imported once")
+	}
+}
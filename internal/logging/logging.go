@@ -0,0 +1,163 @@
+// Package logging sets up picobot's structured logger (log/slog) and hands
+// out the loggers of its subsystems, which tag every record with the
+// subsystem and fields such as channel=telegram, chat_id or tool=exec.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Options configures the process log.
+type Options struct {
+	Level      string            // debug, info (default), warn or error
+	Levels     map[string]string // levels of single subsystems, e.g. "channels": "debug"
+	Format     string            // text (default) or json
+	File       string            // "" writes to the process's log output (stderr)
+	MaxSizeMB  int               // the file is rotated at this size; default 10
+	MaxBackups int               // rotated files kept; default 5
+}
+
+// setup is the handler every logger writes through and the levels it
+// filters with.
+type setup struct {
+	handler slog.Handler // accepts every level
+	level   slog.Level
+	levels  map[string]slog.Level
+}
+
+var current atomic.Pointer[setup]
+
+// Until Setup, records are written as text to the log package's output.
+func init() {
+	current.Store(&setup{handler: slog.NewTextHandler(stdOutput{}, &slog.HandlerOptions{Level: slog.LevelDebug})})
+}
+
+type stdOutput struct{}
+
+func (stdOutput) Write(p []byte) (int, error) { return log.Writer().Write(p) }
+
+var (
+	outputOnce sync.Once
+	output     io.Writer // the log package's output before Setup took it over
+)
+
+// Setup makes a logger of opts the default of slog and of the log package.
+// Everything it writes passes through filter, if set (e.g. to redact
+// secrets), and is copied to tee, if set. The returned closer closes the
+// log file; Setup may be called again to apply new options.
+func Setup(opts Options, filter func(io.Writer) io.Writer, tee io.Writer) (io.Closer, error) {
+	level, err := ParseLevel(opts.Level)
+	if err != nil {
+		return nil, err
+	}
+	levels := make(map[string]slog.Level, len(opts.Levels))
+	for name, l := range opts.Levels {
+		if levels[name], err = ParseLevel(l); err != nil {
+			return nil, fmt.Errorf("logging: subsystem %s: %w", name, err)
+		}
+	}
+
+	outputOnce.Do(func() { output = log.Writer() })
+	var w io.Writer = output
+	var closer io.Closer = nopCloser{}
+	if opts.File != "" {
+		rf, err := openRotating(opts.File, opts.MaxSizeMB, opts.MaxBackups)
+		if err != nil {
+			return nil, err
+		}
+		w, closer = rf, rf
+	}
+	if tee != nil {
+		w = io.MultiWriter(w, tee)
+	}
+	if filter != nil {
+		w = filter(w)
+	}
+
+	ho := &slog.HandlerOptions{Level: slog.LevelDebug}
+	var h slog.Handler
+	switch strings.ToLower(opts.Format) {
+	case "", "text":
+		h = slog.NewTextHandler(w, ho)
+	case "json":
+		h = slog.NewJSONHandler(w, ho)
+	default:
+		closer.Close()
+		return nil, fmt.Errorf("logging: unknown format %q; use text or json", opts.Format)
+	}
+	current.Store(&setup{handler: h, level: level, levels: levels})
+	slog.SetDefault(slog.New(&subsystemHandler{}))
+	return closer, nil
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// ParseLevel parses debug, info, warn or error; "" is info.
+func ParseLevel(s string) (slog.Level, error) {
+	var l slog.Level
+	if s == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := l.UnmarshalText([]byte(s)); err != nil {
+		return l, fmt.Errorf("logging: unknown level %q; use debug, info, warn or error", s)
+	}
+	return l, nil
+}
+
+// For returns the logger of a subsystem. It writes through the handler of
+// the latest Setup even when it was created before, so packages can keep it
+// in a variable.
+func For(subsystem string) *slog.Logger {
+	return slog.New(&subsystemHandler{subsystem: subsystem, with: []func(slog.Handler) slog.Handler{
+		func(h slog.Handler) slog.Handler {
+			return h.WithAttrs([]slog.Attr{slog.String("subsystem", subsystem)})
+		},
+	}})
+}
+
+// subsystemHandler filters records by the level of its subsystem and passes
+// them to the current handler with its attributes and groups.
+type subsystemHandler struct {
+	subsystem string
+	with      []func(slog.Handler) slog.Handler
+}
+
+func (h *subsystemHandler) Enabled(_ context.Context, l slog.Level) bool {
+	s := current.Load()
+	min, ok := s.levels[h.subsystem]
+	if !ok {
+		min = s.level
+	}
+	return l >= min
+}
+
+func (h *subsystemHandler) Handle(ctx context.Context, r slog.Record) error {
+	target := current.Load().handler
+	for _, fn := range h.with {
+		target = fn(target)
+	}
+	return target.Handle(ctx, r)
+}
+
+func (h *subsystemHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.add(func(t slog.Handler) slog.Handler { return t.WithAttrs(attrs) })
+}
+
+func (h *subsystemHandler) WithGroup(name string) slog.Handler {
+	return h.add(func(t slog.Handler) slog.Handler { return t.WithGroup(name) })
+}
+
+func (h *subsystemHandler) add(fn func(slog.Handler) slog.Handler) slog.Handler {
+	with := make([]func(slog.Handler) slog.Handler, len(h.with), len(h.with)+1)
+	copy(with, h.with)
+	return &subsystemHandler{subsystem: h.subsystem, with: append(with, fn)}
+}
@@ -0,0 +1,117 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// upper is a filter that makes everything logged upper case.
+func upper(w io.Writer) io.Writer { return upperWriter{w} }
+
+type upperWriter struct{ w io.Writer }
+
+func (u upperWriter) Write(p []byte) (int, error) {
+	if _, err := u.w.Write(bytes.ToUpper(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func TestSetupLevelsAndFields(t *testing.T) {
+	// created before Setup, as package-level loggers are
+	tg := For("channels").With("channel", "telegram")
+	ag := For("agent")
+
+	path := filepath.Join(t.TempDir(), "logs", "picobot.log")
+	var tee bytes.Buffer
+	closer, err := Setup(Options{Level: "warn", Levels: map[string]string{"channels": "debug"}, Format: "json", File: path}, nil, &tee)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Setup(Options{}, nil, nil)
+	tg.Debug("sending message", "chat_id", "42")
+	ag.Info("processing message")
+	ag.Warn("task stopped", "tool", "exec")
+	closer.Close()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tee.String() != string(b) {
+		t.Fatalf("tee got %q, file %q", tee.String(), b)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected the debug record of channels and the warning of agent, got %q", lines)
+	}
+	var rec map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec["subsystem"] != "channels" || rec["channel"] != "telegram" || rec["chat_id"] != "42" || rec["level"] != "DEBUG" {
+		t.Fatalf("unexpected record %v", rec)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec["subsystem"] != "agent" || rec["tool"] != "exec" || rec["msg"] != "task stopped" {
+		t.Fatalf("unexpected record %v", rec)
+	}
+}
+
+func TestSetupFilter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "picobot.log")
+	closer, err := Setup(Options{File: path}, upper, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Setup(Options{}, nil, nil)
+	For("cron").Info("firing job", "job", "standup")
+	closer.Close()
+	b, _ := os.ReadFile(path)
+	if !strings.Contains(string(b), `MSG="FIRING JOB" SUBSYSTEM=CRON JOB=STANDUP`) {
+		t.Fatalf("record did not pass through the filter: %q", b)
+	}
+}
+
+func TestSetupRejectsBadOptions(t *testing.T) {
+	if _, err := Setup(Options{Level: "loud"}, nil, nil); err == nil {
+		t.Fatal("expected an error for an unknown level")
+	}
+	if _, err := Setup(Options{Levels: map[string]string{"agent": "chatty"}}, nil, nil); err == nil {
+		t.Fatal("expected an error for an unknown subsystem level")
+	}
+	if _, err := Setup(Options{Format: "xml"}, nil, nil); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "picobot.log")
+	r, err := openRotating(path, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.maxSize = 10
+	for _, s := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := r.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r.Close()
+	for name, want := range map[string]string{path: "fourth\n", path + ".1": "third\n", path + ".2": "second\n"} {
+		b, err := os.ReadFile(name)
+		if err != nil || string(b) != want {
+			t.Errorf("%s: got %q (%v), want %q", filepath.Base(name), b, err, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("only 2 backups should be kept")
+	}
+}
@@ -0,0 +1,276 @@
+// Package mcp is a client for Model Context Protocol servers. It speaks
+// JSON-RPC 2.0 over a server's stdin/stdout or over HTTP with server-sent
+// events, and lists and calls the tools a server offers.
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const protocolVersion = "2024-11-05"
+
+// ErrClosed is returned for calls on a closed connection.
+var ErrClosed = errors.New("mcp: connection closed")
+
+// transport carries JSON-RPC messages to and from a server.
+type transport interface {
+	send(ctx context.Context, msg []byte) error
+	// messages delivers the server's messages; it is closed when the
+	// connection ends.
+	messages() <-chan []byte
+	close() error
+}
+
+// Tool is a tool offered by a server.
+type Tool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"inputSchema,omitempty"`
+}
+
+// Content is a part of a tool result.
+type Content struct {
+	Type     string `json:"type"` // text, image, audio or resource
+	Text     string `json:"text,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+	Data     string `json:"data,omitempty"` // base64, for images and audio
+	Resource *struct {
+		URI      string `json:"uri"`
+		MimeType string `json:"mimeType,omitempty"`
+		Text     string `json:"text,omitempty"`
+	} `json:"resource,omitempty"`
+}
+
+// Result is the result of a tool call.
+type Result struct {
+	Content []Content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
+}
+
+// Text renders the result as text; binary parts are summarized.
+func (r Result) Text() string {
+	var parts []string
+	for _, c := range r.Content {
+		switch {
+		case c.Type == "text":
+			parts = append(parts, c.Text)
+		case c.Type == "resource" && c.Resource != nil:
+			if c.Resource.Text != "" {
+				parts = append(parts, c.Resource.Text)
+			} else {
+				parts = append(parts, fmt.Sprintf("[resource %s]", c.Resource.URI))
+			}
+		default:
+			parts = append(parts, fmt.Sprintf("[%s %s, %d bytes base64]", c.Type, c.MimeType, len(c.Data)))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// rpcError is a JSON-RPC error object.
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string { return fmt.Sprintf("mcp: %s (code %d)", e.Message, e.Code) }
+
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  interface{}      `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *rpcError        `json:"error,omitempty"`
+}
+
+// Client is a connection to a server. It is safe for concurrent use.
+type Client struct {
+	t      transport
+	nextID atomic.Int64
+
+	mu      sync.Mutex
+	pending map[int64]chan message
+	done    chan struct{} // closed when the connection ends
+
+	// ServerName is the name the server reported when connecting.
+	ServerName string
+}
+
+func newClient(t transport) *Client {
+	c := &Client{t: t, pending: make(map[int64]chan message), done: make(chan struct{})}
+	go c.readLoop()
+	return c
+}
+
+// initialize performs the protocol handshake.
+func (c *Client) initialize(ctx context.Context) error {
+	var res struct {
+		ServerInfo struct {
+			Name string `json:"name"`
+		} `json:"serverInfo"`
+	}
+	err := c.call(ctx, "initialize", map[string]interface{}{
+		"protocolVersion": protocolVersion,
+		"capabilities":    map[string]interface{}{},
+		"clientInfo":      map[string]interface{}{"name": "picobot", "version": "1"},
+	}, &res)
+	if err != nil {
+		return err
+	}
+	c.ServerName = res.ServerInfo.Name
+	return c.notify(ctx, "notifications/initialized")
+}
+
+func (c *Client) readLoop() {
+	defer func() {
+		c.mu.Lock()
+		close(c.done)
+		c.mu.Unlock()
+	}()
+	for data := range c.t.messages() {
+		var msg message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue
+		}
+		switch {
+		case msg.Method != "" && msg.ID != nil:
+			c.answer(msg)
+		case msg.Method != "":
+			// notifications (progress, list changes, logs) are not used
+		case msg.ID != nil:
+			var id int64
+			if json.Unmarshal(*msg.ID, &id) != nil {
+				continue
+			}
+			c.mu.Lock()
+			ch := c.pending[id]
+			delete(c.pending, id)
+			c.mu.Unlock()
+			if ch != nil {
+				ch <- msg
+			}
+		}
+	}
+}
+
+// answer replies to a request from the server: pings are answered, other
+// requests (sampling, roots) are not supported.
+func (c *Client) answer(req message) {
+	resp := message{JSONRPC: "2.0", ID: req.ID}
+	if req.Method == "ping" {
+		resp.Result = json.RawMessage("{}")
+	} else {
+		resp.Error = &rpcError{Code: -32601, Message: "method not found"}
+	}
+	if b, err := json.Marshal(resp); err == nil {
+		_ = c.t.send(context.Background(), b)
+	}
+}
+
+// call sends a request and decodes its result into out.
+func (c *Client) call(ctx context.Context, method string, params, out interface{}) error {
+	id := c.nextID.Add(1)
+	raw := json.RawMessage(fmt.Sprint(id))
+	b, err := json.Marshal(message{JSONRPC: "2.0", ID: &raw, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	ch := make(chan message, 1)
+	c.mu.Lock()
+	select {
+	case <-c.done:
+		c.mu.Unlock()
+		return ErrClosed
+	default:
+	}
+	c.pending[id] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.t.send(ctx, b); err != nil {
+		return err
+	}
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if out == nil {
+			return nil
+		}
+		if err := json.Unmarshal(resp.Result, out); err != nil {
+			return fmt.Errorf("mcp: %s: invalid result: %w", method, err)
+		}
+		return nil
+	case <-c.done:
+		return ErrClosed
+	case <-ctx.Done():
+		// tell the server to stop working on it
+		_ = c.notify(context.Background(), "notifications/cancelled", map[string]interface{}{"requestId": id})
+		return ctx.Err()
+	}
+}
+
+// notify sends a notification, with optional params.
+func (c *Client) notify(ctx context.Context, method string, params ...interface{}) error {
+	msg := message{JSONRPC: "2.0", Method: method}
+	if len(params) > 0 {
+		msg.Params = params[0]
+	}
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return c.t.send(ctx, b)
+}
+
+// Tools lists the server's tools.
+func (c *Client) Tools(ctx context.Context) ([]Tool, error) {
+	var all []Tool
+	cursor := ""
+	for {
+		params := map[string]interface{}{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		var res struct {
+			Tools      []Tool `json:"tools"`
+			NextCursor string `json:"nextCursor"`
+		}
+		if err := c.call(ctx, "tools/list", params, &res); err != nil {
+			return nil, err
+		}
+		all = append(all, res.Tools...)
+		if res.NextCursor == "" || res.NextCursor == cursor {
+			return all, nil
+		}
+		cursor = res.NextCursor
+	}
+}
+
+// CallTool calls a tool of the server.
+func (c *Client) CallTool(ctx context.Context, name string, args map[string]interface{}) (Result, error) {
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	var res Result
+	err := c.call(ctx, "tools/call", map[string]interface{}{"name": name, "arguments": args}, &res)
+	return res, err
+}
+
+// Done is closed when the connection ends.
+func (c *Client) Done() <-chan struct{} { return c.done }
+
+// Close ends the connection and, for stdio servers, stops the server.
+func (c *Client) Close() error { return c.t.close() }
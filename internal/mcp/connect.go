@@ -0,0 +1,44 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Server describes how to reach a server: a command to run (stdio) or
+// the URL of its SSE endpoint.
+type Server struct {
+	Name    string
+	Command string
+	Args    []string
+	Env     map[string]string // added to picobot's environment
+	Dir     string
+	URL     string
+	Headers map[string]string // sent with every HTTP request, e.g. Authorization
+}
+
+// Connect starts or dials the server and performs the handshake.
+func Connect(ctx context.Context, s Server) (*Client, error) {
+	var t transport
+	var err error
+	switch {
+	case s.Command != "" && s.URL != "":
+		return nil, fmt.Errorf("mcp: server %s: set either command or url, not both", s.Name)
+	case s.Command != "":
+		t, err = startStdio(s.Name, s.Command, s.Args, s.Env, s.Dir)
+	case s.URL != "":
+		t, err = dialSSE(ctx, s.URL, s.Headers, http.DefaultClient)
+	default:
+		return nil, fmt.Errorf("mcp: server %s needs a command or a url", s.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := newClient(t)
+	if err := c.initialize(ctx); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("mcp: server %s: initialize: %w", s.Name, err)
+	}
+	return c, nil
+}
@@ -0,0 +1,175 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// handle answers one request of the test server; it returns nil for
+// notifications.
+func handle(req map[string]interface{}) map[string]interface{} {
+	id, ok := req["id"]
+	if !ok {
+		return nil
+	}
+	resp := map[string]interface{}{"jsonrpc": "2.0", "id": id}
+	params, _ := req["params"].(map[string]interface{})
+	switch req["method"] {
+	case "initialize":
+		resp["result"] = map[string]interface{}{"protocolVersion": protocolVersion, "serverInfo": map[string]interface{}{"name": "test"}, "capabilities": map[string]interface{}{}}
+	case "tools/list":
+		// two pages
+		if params["cursor"] == nil {
+			resp["result"] = map[string]interface{}{"tools": []interface{}{map[string]interface{}{"name": "echo", "inputSchema": map[string]interface{}{"type": "object"}}}, "nextCursor": "2"}
+		} else {
+			resp["result"] = map[string]interface{}{"tools": []interface{}{map[string]interface{}{"name": "fail"}}}
+		}
+	case "tools/call":
+		args, _ := params["arguments"].(map[string]interface{})
+		text := fmt.Sprint(args["text"])
+		resp["result"] = map[string]interface{}{"content": []interface{}{map[string]interface{}{"type": "text", "text": text}}, "isError": params["name"] == "fail"}
+	default:
+		resp["error"] = map[string]interface{}{"code": -32601, "message": "method not found"}
+	}
+	return resp
+}
+
+// TestMain runs the test binary as a stdio server when asked to.
+func TestMain(m *testing.M) {
+	if os.Getenv("MCP_TEST_SERVER") == "1" {
+		sc := bufio.NewScanner(os.Stdin)
+		for sc.Scan() {
+			var req map[string]interface{}
+			if json.Unmarshal(sc.Bytes(), &req) != nil {
+				continue
+			}
+			if resp := handle(req); resp != nil {
+				b, _ := json.Marshal(resp)
+				os.Stdout.Write(append(b, '\n'))
+			}
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func exercise(t *testing.T, c *Client) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if c.ServerName != "test" {
+		t.Errorf("ServerName = %q", c.ServerName)
+	}
+	tools, err := c.Tools(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tools) != 2 || tools[0].Name != "echo" || tools[1].Name != "fail" {
+		t.Fatalf("Tools = %+v", tools)
+	}
+	res, err := c.CallTool(ctx, "echo", map[string]interface{}{"text": "hi"})
+	if err != nil || res.IsError || res.Text() != "hi" {
+		t.Fatalf("CallTool = %+v, %v", res, err)
+	}
+	if res, _ := c.CallTool(ctx, "fail", nil); !res.IsError {
+		t.Fatal("expected an error result")
+	}
+	var out interface{}
+	if err := c.call(ctx, "resources/list", nil, &out); err == nil || !strings.Contains(err.Error(), "method not found") {
+		t.Fatalf("unknown method: %v", err)
+	}
+}
+
+func TestStdio(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c, err := Connect(ctx, Server{Name: "t", Command: os.Args[0], Env: map[string]string{"MCP_TEST_SERVER": "1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exercise(t, c)
+	c.Close()
+	select {
+	case <-c.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection not closed")
+	}
+	if _, err := c.Tools(ctx); err != ErrClosed {
+		t.Fatalf("call after close: %v", err)
+	}
+}
+
+func TestSSE(t *testing.T) {
+	var mu sync.Mutex
+	streams := map[string]chan []byte{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer k" {
+			http.Error(w, "auth", http.StatusUnauthorized)
+			return
+		}
+		switch r.Method {
+		case "GET":
+			ch := make(chan []byte, 16)
+			mu.Lock()
+			streams["s1"] = ch
+			mu.Unlock()
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "event: endpoint\ndata: /messages?session=s1\n\n")
+			w.(http.Flusher).Flush()
+			for {
+				select {
+				case b := <-ch:
+					fmt.Fprintf(w, "event: message\ndata: %s\n\n", b)
+					w.(http.Flusher).Flush()
+				case <-r.Context().Done():
+					return
+				}
+			}
+		case "POST":
+			mu.Lock()
+			ch := streams[r.URL.Query().Get("session")]
+			mu.Unlock()
+			var req map[string]interface{}
+			if ch == nil || json.NewDecoder(r.Body).Decode(&req) != nil {
+				http.Error(w, "bad request", http.StatusBadRequest)
+				return
+			}
+			if resp := handle(req); resp != nil {
+				b, _ := json.Marshal(resp)
+				ch <- b
+			}
+			w.WriteHeader(http.StatusAccepted)
+			io.WriteString(w, "Accepted")
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c, err := Connect(ctx, Server{Name: "t", URL: srv.URL + "/sse", Headers: map[string]string{"Authorization": "Bearer k"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	exercise(t, c)
+}
+
+func TestReadEvents(t *testing.T) {
+	var got []string
+	readEvents(strings.NewReader(": comment\nevent: a\ndata: 1\ndata: 2\n\ndata:3\n\n"), func(event, data string) {
+		got = append(got, event+"="+data)
+	})
+	if strings.Join(got, "|") != "a=1\n2|=3" {
+		t.Fatalf("events = %q", got)
+	}
+}
@@ -0,0 +1,150 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// sse talks to a server over HTTP: the server sends messages as events
+// of a long-lived GET request, and the client POSTs its messages to the
+// endpoint announced in the first event.
+type sse struct {
+	client   *http.Client
+	headers  map[string]string
+	endpoint string
+	msgs     chan []byte
+	cancel   context.CancelFunc
+	once     sync.Once
+}
+
+func dialSSE(ctx context.Context, rawURL string, headers map[string]string, client *http.Client) (*sse, error) {
+	base, err := url.Parse(rawURL)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") {
+		return nil, fmt.Errorf("mcp: invalid url %q", rawURL)
+	}
+	// the stream outlives ctx, which only bounds the connection setup
+	streamCtx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(streamCtx, "GET", base.String(), nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	type dialed struct {
+		resp *http.Response
+		err  error
+	}
+	ch := make(chan dialed, 1)
+	go func() {
+		resp, err := client.Do(req)
+		ch <- dialed{resp, err}
+	}()
+	var resp *http.Response
+	select {
+	case d := <-ch:
+		if d.err != nil {
+			cancel()
+			return nil, fmt.Errorf("mcp: %w", d.err)
+		}
+		resp = d.resp
+	case <-ctx.Done():
+		cancel()
+		return nil, ctx.Err()
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("mcp: %s: HTTP %s", base.Redacted(), resp.Status)
+	}
+
+	s := &sse{client: client, headers: headers, msgs: make(chan []byte, 16), cancel: cancel}
+	endpoint := make(chan string, 1)
+	go func() {
+		defer resp.Body.Close()
+		defer close(s.msgs)
+		readEvents(resp.Body, func(event, data string) {
+			switch event {
+			case "endpoint":
+				if u, err := base.Parse(strings.TrimSpace(data)); err == nil {
+					select {
+					case endpoint <- u.String():
+					default:
+					}
+				}
+			case "", "message":
+				s.msgs <- []byte(data)
+			}
+		})
+	}()
+	select {
+	case s.endpoint = <-endpoint:
+		return s, nil
+	case <-ctx.Done():
+		cancel()
+		return nil, fmt.Errorf("mcp: %s sent no endpoint: %w", base.Redacted(), ctx.Err())
+	}
+}
+
+// readEvents parses a server-sent event stream, calling fn per event.
+func readEvents(r io.Reader, fn func(event, data string)) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), 16<<20)
+	var event string
+	var data []string
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			if len(data) > 0 {
+				fn(event, strings.Join(data, "\n"))
+			}
+			event, data = "", nil
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+}
+
+func (s *sse) send(ctx context.Context, msg []byte) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", s.endpoint, bytes.NewReader(msg))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("mcp: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("mcp: post message: HTTP %s", resp.Status)
+	}
+	return nil
+}
+
+func (s *sse) messages() <-chan []byte { return s.msgs }
+
+func (s *sse) close() error {
+	s.once.Do(s.cancel)
+	return nil
+}
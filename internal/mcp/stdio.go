@@ -0,0 +1,116 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/logging"
+)
+
+// stdio runs a server as a child process and exchanges newline-delimited
+// messages over its stdin and stdout.
+type stdio struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	msgs  chan []byte
+
+	writeMu   sync.Mutex
+	closeOnce sync.Once
+	exited    chan struct{}
+}
+
+func startStdio(name, command string, args []string, env map[string]string, dir string) (*stdio, error) {
+	cmd := exec.Command(command, args...)
+	cmd.Dir = dir
+	cmd.Env = os.Environ()
+	for k, v := range env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	cmd.Stderr = &logWriter{logger: logging.For("mcp").With("server", name)}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("mcp: start %s: %w", command, err)
+	}
+	s := &stdio{cmd: cmd, stdin: stdin, msgs: make(chan []byte, 16), exited: make(chan struct{})}
+	go func() {
+		defer close(s.msgs)
+		r := bufio.NewReaderSize(stdout, 64<<10)
+		for {
+			line, err := r.ReadBytes('\n')
+			if line = bytes.TrimSpace(line); len(line) > 0 {
+				s.msgs <- line
+			}
+			if err != nil {
+				break
+			}
+		}
+		_ = cmd.Wait()
+		close(s.exited)
+	}()
+	return s, nil
+}
+
+func (s *stdio) send(ctx context.Context, msg []byte) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if _, err := s.stdin.Write(append(msg, '\n')); err != nil {
+		return ErrClosed
+	}
+	return nil
+}
+
+func (s *stdio) messages() <-chan []byte { return s.msgs }
+
+// close closes the server's stdin, which asks it to exit, and kills it if
+// it is still running after a grace period.
+func (s *stdio) close() error {
+	s.closeOnce.Do(func() {
+		s.stdin.Close()
+		select {
+		case <-s.exited:
+		case <-time.After(2 * time.Second):
+			_ = s.cmd.Process.Kill()
+		}
+	})
+	return nil
+}
+
+// logWriter logs a server's stderr line by line.
+type logWriter struct {
+	logger *slog.Logger
+	buf    []byte
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if line := bytes.TrimSpace(w.buf[:i]); len(line) > 0 {
+			w.logger.Info("stderr", "line", string(line))
+		}
+		w.buf = w.buf[i+1:]
+	}
+	if len(w.buf) > 4096 {
+		w.logger.Info("stderr", "line", string(w.buf))
+		w.buf = w.buf[:0]
+	}
+	return len(p), nil
+}
@@ -0,0 +1,157 @@
+// Package moderation checks generated content (replies, messages, files)
+// before it reaches users, using local rules or a provider moderation API.
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Result is the outcome of moderating a piece of text.
+type Result struct {
+	Flagged    bool
+	Categories []string // categories that matched, sorted
+}
+
+// Moderator classifies text.
+type Moderator interface {
+	Moderate(ctx context.Context, text string) (Result, error)
+}
+
+// Rules is a local Moderator matching case-insensitive regular expressions
+// grouped by category.
+type Rules struct {
+	rules map[string][]*regexp.Regexp
+}
+
+// NewRules compiles category -> patterns. Patterns are matched
+// case-insensitively; plain words match on word boundaries.
+func NewRules(categories map[string][]string) (*Rules, error) {
+	r := &Rules{rules: make(map[string][]*regexp.Regexp, len(categories))}
+	for cat, patterns := range categories {
+		for _, p := range patterns {
+			if regexp.QuoteMeta(p) == p {
+				p = `\b` + p + `\b`
+			}
+			re, err := regexp.Compile("(?i)" + p)
+			if err != nil {
+				return nil, fmt.Errorf("moderation: category %q: %w", cat, err)
+			}
+			r.rules[cat] = append(r.rules[cat], re)
+		}
+	}
+	return r, nil
+}
+
+// Moderate implements Moderator.
+func (r *Rules) Moderate(_ context.Context, text string) (Result, error) {
+	var res Result
+	for cat, patterns := range r.rules {
+		for _, re := range patterns {
+			if re.MatchString(text) {
+				res.Categories = append(res.Categories, cat)
+				break
+			}
+		}
+	}
+	sort.Strings(res.Categories)
+	res.Flagged = len(res.Categories) > 0
+	return res, nil
+}
+
+// OpenAI calls an OpenAI-compatible /moderations endpoint.
+type OpenAI struct {
+	APIKey  string
+	APIBase string
+	Model   string
+	Client  *http.Client
+}
+
+// NewOpenAI creates a moderator for the given API. Empty apiBase and model
+// default to api.openai.com and omni-moderation-latest.
+func NewOpenAI(apiKey, apiBase, model string) *OpenAI {
+	if apiBase == "" {
+		apiBase = "https://api.openai.com/v1"
+	}
+	if model == "" {
+		model = "omni-moderation-latest"
+	}
+	return &OpenAI{APIKey: apiKey, APIBase: strings.TrimRight(apiBase, "/"), Model: model, Client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// Moderate implements Moderator.
+func (o *OpenAI) Moderate(ctx context.Context, text string) (Result, error) {
+	body, _ := json.Marshal(map[string]string{"model": o.Model, "input": text})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.APIBase+"/moderations", bytes.NewReader(body))
+	if err != nil {
+		return Result{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+o.APIKey)
+	resp, err := o.Client.Do(req)
+	if err != nil {
+		return Result{}, fmt.Errorf("moderation: %w", err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return Result{}, fmt.Errorf("moderation: status %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+	var out struct {
+		Results []struct {
+			Flagged    bool            `json:"flagged"`
+			Categories map[string]bool `json:"categories"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return Result{}, fmt.Errorf("moderation: decode response: %w", err)
+	}
+	var res Result
+	for _, r := range out.Results {
+		res.Flagged = res.Flagged || r.Flagged
+		for cat, hit := range r.Categories {
+			if hit {
+				res.Categories = append(res.Categories, cat)
+			}
+		}
+	}
+	sort.Strings(res.Categories)
+	return res, nil
+}
+
+// Chain runs several moderators and merges their results. The first error
+// is returned along with whatever the others found.
+type Chain []Moderator
+
+// Moderate implements Moderator.
+func (c Chain) Moderate(ctx context.Context, text string) (Result, error) {
+	var res Result
+	var firstErr error
+	seen := make(map[string]bool)
+	for _, m := range c {
+		r, err := m.Moderate(ctx, text)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		res.Flagged = res.Flagged || r.Flagged
+		for _, cat := range r.Categories {
+			if !seen[cat] {
+				seen[cat] = true
+				res.Categories = append(res.Categories, cat)
+			}
+		}
+	}
+	sort.Strings(res.Categories)
+	return res, firstErr
+}
@@ -0,0 +1,47 @@
+package moderation
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRules(t *testing.T) {
+	r, err := NewRules(map[string][]string{
+		"profanity": {"darn"},
+		"pii":       {`\b\d{3}-\d{2}-\d{4}\b`},
+	})
+	if err != nil {
+		t.Fatalf("NewRules: %v", err)
+	}
+	res, _ := r.Moderate(context.Background(), "Darn, my SSN is 123-45-6789")
+	if !res.Flagged || len(res.Categories) != 2 || res.Categories[0] != "pii" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if res, _ := r.Moderate(context.Background(), "darned fine weather"); res.Flagged {
+		t.Fatalf("plain words should match on word boundaries: %+v", res)
+	}
+	if _, err := NewRules(map[string][]string{"bad": {"("}}); err == nil {
+		t.Fatalf("expected error for invalid pattern")
+	}
+}
+
+func TestOpenAI(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/moderations" || r.Header.Get("Authorization") != "Bearer key" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"results":[{"flagged":true,"categories":{"violence":true,"hate":false}}]}`))
+	}))
+	defer srv.Close()
+
+	res, err := NewOpenAI("key", srv.URL, "").Moderate(context.Background(), "text")
+	if err != nil {
+		t.Fatalf("Moderate: %v", err)
+	}
+	if !res.Flagged || len(res.Categories) != 1 || res.Categories[0] != "violence" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+}
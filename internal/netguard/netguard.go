@@ -0,0 +1,114 @@
+// Package netguard keeps outgoing requests made on behalf of the agent or its
+// clients away from the local machine and private networks (SSRF).
+package netguard
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// cgnatRange is the carrier-grade NAT block (RFC 6598), commonly used for
+// internal cloud networks and not covered by net.IP.IsPrivate.
+var cgnatRange = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// IsBlocked reports whether ip points at the local machine or a private,
+// link-local or otherwise non-public network.
+func IsBlocked(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() ||
+		cgnatRange.Contains(ip)
+}
+
+// Lookup resolves a hostname to its IP addresses.
+type Lookup func(ctx context.Context, host string) ([]net.IP, error)
+
+// DefaultLookup resolves host with the default resolver.
+func DefaultLookup(ctx context.Context, host string) ([]net.IP, error) {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, len(addrs))
+	for i, a := range addrs {
+		ips[i] = a.IP
+	}
+	return ips, nil
+}
+
+// CheckURL rejects URLs that are not http(s) or whose host resolves to
+// any non-public address. Every resolved address is checked, so a hostname
+// with one public and one private record is rejected.
+func CheckURL(ctx context.Context, u *url.URL, lookup Lookup) error {
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return fmt.Errorf("scheme %q is not allowed (use http or https)", u.Scheme)
+	}
+	host := u.Hostname()
+	if host == "" {
+		return fmt.Errorf("URL has no host")
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if IsBlocked(ip) {
+			return fmt.Errorf("access to %s (local or private network) is disallowed", ip)
+		}
+		return nil
+	}
+	ips, err := lookup(ctx, host)
+	if err != nil {
+		return fmt.Errorf("resolve %s: %w", host, err)
+	}
+	if len(ips) == 0 {
+		return fmt.Errorf("resolve %s: no addresses", host)
+	}
+	for _, ip := range ips {
+		if IsBlocked(ip) {
+			return fmt.Errorf("%s resolves to %s (local or private network); access is disallowed", host, ip)
+		}
+	}
+	return nil
+}
+
+// checkDialAddr is a net.Dialer Control function that refuses connections
+// to non-public addresses. It sees the address actually being connected to,
+// after DNS resolution, so a hostname that passed CheckURL but
+// resolves differently at dial time (DNS rebinding) is still blocked.
+func checkDialAddr(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("dial %s: not an IP address", address)
+	}
+	if IsBlocked(ip) {
+		return fmt.Errorf("connection to %s (local or private network) is disallowed", ip)
+	}
+	return nil
+}
+
+// Transport is an HTTP transport that only connects to public addresses. It
+// ignores proxy settings, since a proxy would connect on our behalf without
+// these checks.
+var Transport = &http.Transport{
+	DialContext: (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   checkDialAddr,
+	}).DialContext,
+	ForceAttemptHTTP2:     true,
+	MaxIdleConns:          20,
+	IdleConnTimeout:       90 * time.Second,
+	TLSHandshakeTimeout:   10 * time.Second,
+	ExpectContinueTimeout: time.Second,
+}
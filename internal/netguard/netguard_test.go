@@ -0,0 +1,63 @@
+package netguard
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCheckURL(t *testing.T) {
+	lookup := func(ctx context.Context, host string) ([]net.IP, error) {
+		switch host {
+		case "public.example":
+			return []net.IP{net.ParseIP("93.184.216.34")}, nil
+		case "rebind.example":
+			return []net.IP{net.ParseIP("93.184.216.34"), net.ParseIP("10.0.0.5")}, nil
+		case "metadata.example":
+			return []net.IP{net.ParseIP("169.254.169.254")}, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: host}
+	}
+	cases := []struct {
+		url string
+		ok  bool
+	}{
+		{"https://public.example/page", true},
+		{"http://93.184.216.34/", true},
+		{"https://rebind.example/", false},
+		{"https://metadata.example/latest", false},
+		{"http://127.0.0.1:8080/", false},
+		{"http://[::1]/", false},
+		{"http://[fd00::1]/", false},
+		{"http://100.64.1.1/", false},
+		{"http://0.0.0.0/", false},
+		{"file:///etc/passwd", false},
+		{"gopher://public.example/", false},
+	}
+	for _, c := range cases {
+		u, err := url.Parse(c.url)
+		if err != nil {
+			t.Fatalf("parse %s: %v", c.url, err)
+		}
+		err = CheckURL(context.Background(), u, lookup)
+		if (err == nil) != c.ok {
+			t.Errorf("CheckURL(%s) = %v, want ok=%v", c.url, err, c.ok)
+		}
+	}
+}
+
+func TestTransportBlocksPrivateDial(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	// the URL checks are bypassed here, as when a host is re-resolved to a
+	// private address between the check and the connection
+	client := &http.Client{Transport: Transport}
+	_, err := client.Get(srv.URL)
+	if err == nil || !strings.Contains(err.Error(), "disallowed") {
+		t.Fatalf("expected the dial to be refused, got %v", err)
+	}
+}
@@ -3,8 +3,8 @@ package providers
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -18,6 +18,10 @@ type AnthropicProvider struct {
 	APIBase   string // e.g. https://api.anthropic.com/v1
 	MaxTokens int
 	Client    *http.Client
+	Retry     RetryPolicy
+	// Params are sent with every request; WithParams overrides them per
+	// request. Penalties and seed are not supported by the API.
+	Params GenerationParams
 }
 
 func NewAnthropicProvider(apiKey, apiBase string, timeoutSecs, maxTokens int) *AnthropicProvider {
@@ -34,6 +38,7 @@ func NewAnthropicProvider(apiKey, apiBase string, timeoutSecs, maxTokens int) *A
 		APIKey:    apiKey,
 		APIBase:   strings.TrimRight(apiBase, "/"),
 		MaxTokens: maxTokens,
+		Retry:     DefaultRetryPolicy,
 		Client: &http.Client{
 			Timeout: time.Duration(timeoutSecs) * time.Second,
 		},
@@ -49,22 +54,62 @@ type anthropicRequest struct {
 	MaxTokens int                `json:"max_tokens"`
 	System    string             `json:"system,omitempty"`
 	Tools     []anthropicTool    `json:"tools,omitempty"`
+	Stream    bool               `json:"stream,omitempty"`
+
+	Temperature   *float64 `json:"temperature,omitempty"`
+	TopP          *float64 `json:"top_p,omitempty"`
+	StopSequences []string `json:"stop_sequences,omitempty"`
+
+	Thinking   *anthropicThinking   `json:"thinking,omitempty"`
+	ToolChoice *anthropicToolChoice `json:"tool_choice,omitempty"`
+}
+
+// anthropicToolChoice forces a call of the named tool.
+type anthropicToolChoice struct {
+	Type string `json:"type"` // "tool"
+	Name string `json:"name"`
+}
+
+// anthropicThinking enables extended thinking.
+type anthropicThinking struct {
+	Type         string `json:"type"` // "enabled"
+	BudgetTokens int    `json:"budget_tokens"`
 }
 
+// minThinkingBudget is the smallest budget the API accepts.
+const minThinkingBudget = 1024
+
 type anthropicMessage struct {
 	Role    string           `json:"role"`
 	Content []anthropicBlock `json:"content"`
 }
 
 type anthropicBlock struct {
-	Type      string          `json:"type"`
-	Text      string          `json:"text,omitempty"`
-	ID        string          `json:"id,omitempty"`          // for tool_use
-	Name      string          `json:"name,omitempty"`        // for tool_use
-	Input     json.RawMessage `json:"input,omitempty"`       // for tool_use
-	ToolUseID string          `json:"tool_use_id,omitempty"` // for tool_result
-	Content   string          `json:"content,omitempty"`     // for tool_result
-	IsError   bool            `json:"is_error,omitempty"`    // for tool_result
+	Type      string           `json:"type"`
+	Text      string           `json:"text,omitempty"`
+	ID        string           `json:"id,omitempty"`          // for tool_use
+	Name      string           `json:"name,omitempty"`        // for tool_use
+	Input     json.RawMessage  `json:"input,omitempty"`       // for tool_use
+	ToolUseID string           `json:"tool_use_id,omitempty"` // for tool_result
+	Content   string           `json:"content,omitempty"`     // for tool_result
+	IsError   bool             `json:"is_error,omitempty"`    // for tool_result
+	Thinking  string           `json:"thinking,omitempty"`    // for thinking
+	Signature string           `json:"signature,omitempty"`   // for thinking
+	Data      string           `json:"data,omitempty"`        // for redacted_thinking
+	Source    *anthropicSource `json:"source,omitempty"`      // for image
+}
+
+// anthropicSource is the source of an image block.
+type anthropicSource struct {
+	Type      string `json:"type"` // "base64" or "url"
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
 }
 
 type anthropicTool struct {
@@ -79,6 +124,7 @@ type anthropicResponse struct {
 	Role       string           `json:"role"`
 	Content    []anthropicBlock `json:"content"`
 	StopReason string           `json:"stop_reason"`
+	Usage      anthropicUsage   `json:"usage"`
 	Error      *struct {
 		Type    string `json:"type"`
 		Message string `json:"message"`
@@ -87,8 +133,34 @@ type anthropicResponse struct {
 
 func (p *AnthropicProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string) (LLMResponse, error) {
 	if p.APIKey == "" {
-		return LLMResponse{}, errors.New("Anthropic provider: API key is not configured")
+		return LLMResponse{}, errNoAPIKey("Anthropic")
+	}
+	resp, err := p.post(ctx, p.request(ctx, messages, tools, model))
+	if err != nil {
+		return LLMResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	var out anthropicResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return LLMResponse{}, err
+	}
+
+	if out.Error != nil {
+		return LLMResponse{}, streamError("Anthropic", out.Error.Type, out.Error.Message)
 	}
+	res := anthropicLLMResponse(out.Content)
+	res = p.structuredReply(ctx, res)
+	res.Usage = Usage{PromptTokens: out.Usage.InputTokens, CompletionTokens: out.Usage.OutputTokens}
+	if out.StopReason == "refusal" && res.Content == "" {
+		return res, errFiltered("Anthropic", "refusal")
+	}
+	return res, nil
+}
+
+// request converts messages and tools to a Messages API request with the
+// provider's params and those of ctx.
+func (p *AnthropicProvider) request(ctx context.Context, messages []Message, tools []ToolDefinition, model string) anthropicRequest {
 	if model == "" {
 		model = p.GetDefaultModel()
 	}
@@ -116,6 +188,21 @@ func (p *AnthropicProvider) Chat(ctx context.Context, messages []Message, tools
 		}
 
 		msgBlocks := []anthropicBlock{}
+		// thinking blocks must come first and be returned unchanged
+		for _, tb := range m.Thinking {
+			if tb.Data != "" {
+				msgBlocks = append(msgBlocks, anthropicBlock{Type: "redacted_thinking", Data: tb.Data})
+			} else {
+				msgBlocks = append(msgBlocks, anthropicBlock{Type: "thinking", Thinking: tb.Text, Signature: tb.Signature})
+			}
+		}
+		for _, img := range m.Images {
+			src := &anthropicSource{Type: "url", URL: img.URL}
+			if img.URL == "" {
+				src = &anthropicSource{Type: "base64", MediaType: img.MediaType, Data: base64.StdEncoding.EncodeToString(img.Data)}
+			}
+			msgBlocks = append(msgBlocks, anthropicBlock{Type: "image", Source: src})
+		}
 		if m.Content != "" {
 			msgBlocks = append(msgBlocks, anthropicBlock{Type: "text", Text: m.Content})
 		}
@@ -142,6 +229,29 @@ func (p *AnthropicProvider) Chat(ctx context.Context, messages []Message, tools
 		System:    systemPrompt,
 		MaxTokens: p.MaxTokens,
 	}
+	params := p.Params.Merge(ParamsFrom(ctx))
+	reqBody.StopSequences = params.Stop
+	if f := params.ResponseFormat; f != nil {
+		// JSON mode: the reply is the input of a forced tool call, which
+		// rules out thinking
+		reqBody.Tools = append(reqBody.Tools, anthropicTool{Name: f.name(), Description: "Give your reply.", InputSchema: f.schema()})
+		reqBody.ToolChoice = &anthropicToolChoice{Type: "tool", Name: f.name()}
+		params.ThinkingBudget = nil
+	}
+	if budget := params.ThinkingBudget; budget != nil && *budget > 0 {
+		// thinking counts against max_tokens and rules out sampling settings
+		b := max(*budget, minThinkingBudget)
+		reqBody.Thinking = &anthropicThinking{Type: "enabled", BudgetTokens: b}
+		if reqBody.MaxTokens <= b {
+			reqBody.MaxTokens += b
+		}
+	} else {
+		reqBody.Temperature = params.Temperature
+		if params.Temperature == nil {
+			// recent models accept only one of temperature and top_p
+			reqBody.TopP = params.TopP
+		}
+	}
 
 	if len(tools) > 0 {
 		for _, t := range tools {
@@ -153,9 +263,15 @@ func (p *AnthropicProvider) Chat(ctx context.Context, messages []Message, tools
 		}
 	}
 
+	return reqBody
+}
+
+// post sends a Messages API request, retrying transient failures, and
+// returns the response of a successful call.
+func (p *AnthropicProvider) post(ctx context.Context, reqBody anthropicRequest) (*http.Response, error) {
 	b, err := json.Marshal(reqBody)
 	if err != nil {
-		return LLMResponse{}, err
+		return nil, err
 	}
 
 	apiURL := fmt.Sprintf("%s/messages", p.APIBase)
@@ -170,32 +286,53 @@ func (p *AnthropicProvider) Chat(ctx context.Context, messages []Message, tools
 		return req, nil
 	}
 
-	resp, err := doWithRetry(ctx, p.Client, buildReq)
+	resp, err := doWithRetry(ctx, p.Client, p.Retry, buildReq)
 	if err != nil {
-		return LLMResponse{}, err
+		return nil, err
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
 		bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
-		return LLMResponse{}, fmt.Errorf("Anthropic API error: %s - %s", resp.Status, string(bodyBytes))
+		return nil, apiError("Anthropic", resp, string(bodyBytes))
 	}
+	return resp, nil
+}
 
-	var out anthropicResponse
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-		return LLMResponse{}, err
+// structuredReply turns the forced tool call of a JSON mode request into
+// the reply's content.
+func (p *AnthropicProvider) structuredReply(ctx context.Context, res LLMResponse) LLMResponse {
+	f := p.Params.Merge(ParamsFrom(ctx)).ResponseFormat
+	if f == nil {
+		return res
 	}
-
-	if out.Error != nil {
-		return LLMResponse{}, fmt.Errorf("Anthropic API error: %s - %s", out.Error.Type, out.Error.Message)
+	for i, tc := range res.ToolCalls {
+		if tc.Name == f.name() {
+			b, _ := json.Marshal(tc.Arguments)
+			res.Content = string(b)
+			res.ToolCalls = append(res.ToolCalls[:i:i], res.ToolCalls[i+1:]...)
+			res.HasToolCalls = len(res.ToolCalls) > 0
+			break
+		}
 	}
+	return res
+}
 
+// anthropicLLMResponse normalizes the content blocks of a reply.
+func anthropicLLMResponse(blocks []anthropicBlock) LLMResponse {
 	var finalContent strings.Builder
 	var tcs []ToolCall
+	var thinking []ThinkingBlock
+	var reasoning []string
 	hasToolCalls := false
 
-	for _, block := range out.Content {
-		if block.Type == "text" {
+	for _, block := range blocks {
+		if block.Type == "thinking" {
+			thinking = append(thinking, ThinkingBlock{Text: block.Thinking, Signature: block.Signature})
+			reasoning = append(reasoning, block.Thinking)
+		} else if block.Type == "redacted_thinking" {
+			thinking = append(thinking, ThinkingBlock{Data: block.Data})
+		} else if block.Type == "text" {
 			finalContent.WriteString(block.Text)
 		} else if block.Type == "tool_use" {
 			hasToolCalls = true
@@ -213,5 +350,7 @@ func (p *AnthropicProvider) Chat(ctx context.Context, messages []Message, tools
 		Content:      strings.TrimSpace(finalContent.String()),
 		HasToolCalls: hasToolCalls,
 		ToolCalls:    tcs,
-	}, nil
+		Reasoning:    strings.TrimSpace(strings.Join(reasoning, "\n\n")),
+		Thinking:     thinking,
+	}
 }
@@ -0,0 +1,108 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// anthropicStreamEvent is one server-sent event of a streamed message.
+type anthropicStreamEvent struct {
+	Type         string         `json:"type"`
+	Index        int            `json:"index"`
+	ContentBlock anthropicBlock `json:"content_block"`
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		Thinking    string `json:"thinking"`
+		Signature   string `json:"signature"`
+		StopReason  string `json:"stop_reason"` // message_delta
+	} `json:"delta"`
+	Message struct {
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message"` // message_start
+	Usage anthropicUsage `json:"usage"` // message_delta
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// ChatStream requests a streamed message (server-sent events) and calls
+// onDelta with each fragment of text. Tool inputs arrive as partial JSON and
+// are assembled before the response is returned.
+func (p *AnthropicProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, onDelta StreamFunc) (LLMResponse, error) {
+	if p.APIKey == "" {
+		return LLMResponse{}, errNoAPIKey("Anthropic")
+	}
+	req := p.request(ctx, messages, tools, model)
+	req.Stream = true
+	resp, err := p.post(ctx, req)
+	if err != nil {
+		return LLMResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	var blocks []anthropicBlock
+	var inputs []string // partial tool input JSON, by block index
+	var usage Usage
+	var stopReason string
+	err = readSSE(resp.Body, func(data []byte) error {
+		var ev anthropicStreamEvent
+		if err := json.Unmarshal(data, &ev); err != nil {
+			return fmt.Errorf("Anthropic API: bad stream event: %w", err)
+		}
+		switch ev.Type {
+		case "error":
+			if ev.Error != nil {
+				return streamError("Anthropic", ev.Error.Type, ev.Error.Message)
+			}
+			return errors.New("Anthropic API error in stream")
+		case "message_start":
+			usage.PromptTokens = ev.Message.Usage.InputTokens
+		case "message_delta":
+			usage.CompletionTokens = ev.Usage.OutputTokens
+			stopReason = ev.Delta.StopReason
+		case "content_block_start":
+			for len(blocks) <= ev.Index {
+				blocks = append(blocks, anthropicBlock{})
+				inputs = append(inputs, "")
+			}
+			blocks[ev.Index] = ev.ContentBlock
+		case "content_block_delta":
+			if ev.Index >= len(blocks) {
+				return nil
+			}
+			switch ev.Delta.Type {
+			case "text_delta":
+				blocks[ev.Index].Text += ev.Delta.Text
+				if onDelta != nil && ev.Delta.Text != "" {
+					onDelta(ev.Delta.Text)
+				}
+			case "input_json_delta":
+				inputs[ev.Index] += ev.Delta.PartialJSON
+			case "thinking_delta":
+				blocks[ev.Index].Thinking += ev.Delta.Thinking
+			case "signature_delta":
+				blocks[ev.Index].Signature += ev.Delta.Signature
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return LLMResponse{}, err
+	}
+	for i := range blocks {
+		if blocks[i].Type == "tool_use" && inputs[i] != "" {
+			blocks[i].Input = json.RawMessage(inputs[i])
+		}
+	}
+	res := p.structuredReply(ctx, anthropicLLMResponse(blocks))
+	res.Usage = usage
+	if stopReason == "refusal" && res.Content == "" {
+		return res, errFiltered("Anthropic", "refusal")
+	}
+	return res, nil
+}
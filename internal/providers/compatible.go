@@ -0,0 +1,49 @@
+package providers
+
+import "strings"
+
+// Groq and Mistral serve OpenAI-compatible chat completion APIs; they only
+// differ in endpoint, default model and how their models are named.
+
+// NewGroqProvider returns a provider for Groq. Models are configured as
+// "groq/<model>" and sent without the prefix.
+func NewGroqProvider(apiKey, apiBase string, timeoutSecs, maxTokens int) *OpenAIProvider {
+	if apiBase == "" {
+		apiBase = "https://api.groq.com/openai/v1"
+	}
+	p := NewOpenAIProvider(apiKey, apiBase, timeoutSecs, maxTokens)
+	p.Name = "Groq"
+	p.Model = "groq/llama-3.3-70b-versatile"
+	p.ModelPrefix = "groq/"
+	return p
+}
+
+// NewMistralProvider returns a provider for Mistral's La Plateforme. Models
+// use Mistral's own names ("mistral-large-latest"); a "mistral/" prefix is
+// accepted and stripped.
+func NewMistralProvider(apiKey, apiBase string, timeoutSecs, maxTokens int) *OpenAIProvider {
+	if apiBase == "" {
+		apiBase = "https://api.mistral.ai/v1"
+	}
+	p := NewOpenAIProvider(apiKey, apiBase, timeoutSecs, maxTokens)
+	p.Name = "Mistral"
+	p.Model = "mistral-small-latest"
+	p.ModelPrefix = "mistral/"
+	p.noStreamOptions = true
+	return p
+}
+
+// isGroqModel reports whether model is routed to Groq.
+func isGroqModel(model string) bool {
+	return strings.HasPrefix(model, "groq/")
+}
+
+// isMistralModel reports whether model is one of Mistral's model families.
+func isMistralModel(model string) bool {
+	for _, prefix := range []string{"mistral/", "mistral-", "open-mistral-", "open-mixtral-", "codestral-", "ministral-", "pixtral-", "magistral-", "devstral-"} {
+		if strings.HasPrefix(model, prefix) {
+			return true
+		}
+	}
+	return false
+}
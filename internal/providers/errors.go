@@ -0,0 +1,89 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Errors providers return, wrapped with the API's message, for failures the
+// agent handles differently. Test them with errors.Is; rate limits are
+// reported as *ErrRateLimited (errors.As).
+var (
+	ErrAuth            = errors.New("authentication failed")
+	ErrContextTooLong  = errors.New("prompt exceeds the model's context window")
+	ErrContentFiltered = errors.New("blocked by the provider's content filter")
+	ErrModelNotFound   = errors.New("model not found")
+)
+
+// ErrRateLimited is returned when the API keeps answering 429 after the
+// retries of the provider's RetryPolicy.
+type ErrRateLimited struct {
+	RetryAfter time.Duration // the server's Retry-After hint; 0 if none
+	Message    string
+}
+
+func (e *ErrRateLimited) Error() string { return e.Message }
+
+// errNoAPIKey is returned by providers without a key.
+func errNoAPIKey(label string) error {
+	return fmt.Errorf("%w: %s provider: API key is not configured", ErrAuth, label)
+}
+
+// errFiltered is returned when a reply is withheld by the provider.
+func errFiltered(label, reason string) error {
+	return fmt.Errorf("%s API stopped the reply (%s): %w", label, reason, ErrContentFiltered)
+}
+
+// apiError builds the error for a failed API call. label names the provider,
+// status is the HTTP status line (e.g. "400 Bad Request") and body the API's
+// error message.
+func apiError(label string, resp *http.Response, body string) error {
+	msg := fmt.Sprintf("%s API error: %s", label, resp.Status)
+	if body != "" {
+		msg += " - " + body
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return &ErrRateLimited{RetryAfter: retryAfterDelay(resp), Message: msg}
+	}
+	if kind := classifyError(resp.StatusCode, body); kind != nil {
+		return fmt.Errorf("%s: %w", msg, kind)
+	}
+	return errors.New(msg)
+}
+
+// classifyError maps an HTTP status (0 for errors reported inside a stream)
+// and error message to one of the typed errors, or nil.
+func classifyError(status int, body string) error {
+	b := strings.ToLower(body)
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden ||
+		strings.Contains(b, "authentication_error") || strings.Contains(b, "invalid_api_key") || strings.Contains(b, "incorrect api key"):
+		return ErrAuth
+	case strings.Contains(b, "context_length_exceeded") || strings.Contains(b, "maximum context length") ||
+		strings.Contains(b, "prompt is too long") || strings.Contains(b, "context window") || status == http.StatusRequestEntityTooLarge:
+		return ErrContextTooLong
+	case strings.Contains(b, "content_filter") || strings.Contains(b, "content_policy") || strings.Contains(b, "content management policy"):
+		return ErrContentFiltered
+	case strings.Contains(b, "model_not_found") || (strings.Contains(b, "model") && (status == http.StatusNotFound || strings.Contains(b, "does not exist"))):
+		return ErrModelNotFound
+	}
+	return nil
+}
+
+// streamError builds the error for a failure reported inside a stream.
+func streamError(label, typ, message string) error {
+	msg := fmt.Sprintf("%s API error: %s", label, message)
+	if typ != "" {
+		msg = fmt.Sprintf("%s API error: %s - %s", label, typ, message)
+	}
+	if typ == "rate_limit_error" || strings.Contains(strings.ToLower(message), "rate limit") {
+		return &ErrRateLimited{Message: msg}
+	}
+	if kind := classifyError(0, typ+" "+message); kind != nil {
+		return fmt.Errorf("%s: %w", msg, kind)
+	}
+	return errors.New(msg)
+}
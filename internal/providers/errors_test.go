@@ -0,0 +1,57 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestProviderErrorsAreTyped(t *testing.T) {
+	cases := []struct {
+		status int
+		header string
+		body   string
+		want   error
+	}{
+		{401, "", `{"error":{"message":"Incorrect API key provided"}}`, ErrAuth},
+		{400, "", `{"error":{"code":"context_length_exceeded","message":"This model's maximum context length is 128000 tokens"}}`, ErrContextTooLong},
+		{400, "", `{"type":"error","error":{"type":"invalid_request_error","message":"prompt is too long: 210000 tokens > 200000 maximum"}}`, ErrContextTooLong},
+		{404, "", `{"error":{"code":"model_not_found","message":"The model gpt-9 does not exist"}}`, ErrModelNotFound},
+		{400, "", `{"error":{"code":"content_filter","message":"filtered"}}`, ErrContentFiltered},
+	}
+	for _, c := range cases {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(c.status)
+			w.Write([]byte(c.body))
+		}))
+		p := NewOpenAIProvider("k", srv.URL, 5, 100)
+		p.Retry.MaxRetries = 0
+		_, err := p.Chat(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, "m")
+		srv.Close()
+		if !errors.Is(err, c.want) {
+			t.Errorf("%d %s: expected %v, got %v", c.status, c.body, c.want, err)
+		}
+	}
+}
+
+func TestRateLimitErrorCarriesRetryAfter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+	p := NewAnthropicProvider("k", srv.URL, 5, 100)
+	p.Retry.MaxRetries = 0
+	_, err := p.Chat(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, "claude-x")
+	var rl *ErrRateLimited
+	if !errors.As(err, &rl) || rl.RetryAfter != 30*time.Second {
+		t.Fatalf("expected a rate limit error with RetryAfter 30s, got %v", err)
+	}
+
+	if _, err := NewOpenAIProvider("", "", 5, 100).Chat(context.Background(), nil, nil, "m"); !errors.Is(err, ErrAuth) {
+		t.Fatalf("expected ErrAuth without an API key, got %v", err)
+	}
+}
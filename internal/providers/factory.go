@@ -2,26 +2,101 @@ package providers
 
 import (
 	"strings"
+	"time"
 
 	"github.com/kr0nicas/picobot/internal/config"
 )
 
 // NewProviderFromConfig creates a provider based on the configuration.
 func NewProviderFromConfig(cfg config.Config) LLMProvider {
-	model := cfg.Agents.Defaults.Model
+	return newProviderForModel(cfg, cfg.Agents.Defaults.Model)
+}
 
-	// If it's a Claude model and we have an Anthropic key, use the native provider.
-	// (Note: AnthropicProvider implementation pending in anthropic.go)
+// ParamsFromConfig returns the generation params of agents.defaults.
+func ParamsFromConfig(cfg config.Config) GenerationParams {
+	d := cfg.Agents.Defaults
+	p := GenerationParams{Stop: d.Stop, Seed: d.Seed, ReasoningEffort: d.ReasoningEffort}
+	if d.Temperature != nil {
+		p.Temperature = Float(*d.Temperature)
+	}
+	if d.TopP > 0 {
+		p.TopP = Float(d.TopP)
+	}
+	if d.PresencePenalty != 0 {
+		p.PresencePenalty = Float(d.PresencePenalty)
+	}
+	if d.FrequencyPenalty != 0 {
+		p.FrequencyPenalty = Float(d.FrequencyPenalty)
+	}
+	if d.ThinkingBudget > 0 {
+		p.ThinkingBudget = Int(d.ThinkingBudget)
+	}
+	return p
+}
+
+// RetryPolicyFromConfig returns the retry policy of a provider block,
+// filling in DefaultRetryPolicy for unset fields.
+func RetryPolicyFromConfig(pc *config.ProviderConfig) RetryPolicy {
+	rp := DefaultRetryPolicy
+	if pc == nil || pc.Retry == nil {
+		return rp
+	}
+	r := pc.Retry
+	if r.MaxRetries != nil {
+		rp.MaxRetries = max(*r.MaxRetries, 0)
+	}
+	if r.BaseDelayMs > 0 {
+		rp.BaseDelay = time.Duration(r.BaseDelayMs) * time.Millisecond
+	}
+	if r.MaxDelayMs > 0 {
+		rp.MaxDelay = time.Duration(r.MaxDelayMs) * time.Millisecond
+	}
+	rp.Jitter = r.Jitter
+	if r.HonorRetryAfter != nil {
+		rp.HonorRetryAfter = *r.HonorRetryAfter
+	}
+	return rp
+}
+
+// newProviderForModel picks the configured provider that serves model and
+// gives it the configured generation params and retry policy.
+func newProviderForModel(cfg config.Config, model string) LLMProvider {
+	p, pc := pickProvider(cfg, model)
+	switch p := p.(type) {
+	case *OpenAIProvider:
+		p.Params = ParamsFromConfig(cfg)
+		p.Retry = RetryPolicyFromConfig(pc)
+	case *AnthropicProvider:
+		p.Params = ParamsFromConfig(cfg)
+		p.Retry = RetryPolicyFromConfig(pc)
+	}
+	return p
+}
+
+// pickProvider returns the configured provider that serves model and its
+// config block.
+func pickProvider(cfg config.Config, model string) (LLMProvider, *config.ProviderConfig) {
 	maxTokens := cfg.Agents.Defaults.MaxTokens
 	timeout := cfg.Agents.Defaults.RequestTimeoutS
 
+	// Groq and Mistral are picked by model name, so they can sit next to an
+	// OpenAI-compatible default without replacing it.
+	if isGroqModel(model) && cfg.Providers.Groq != nil && cfg.Providers.Groq.APIKey != "" {
+		return NewGroqProvider(cfg.Providers.Groq.APIKey, cfg.Providers.Groq.APIBase, timeout, maxTokens), cfg.Providers.Groq
+	}
+	if isMistralModel(model) && cfg.Providers.Mistral != nil && cfg.Providers.Mistral.APIKey != "" {
+		return NewMistralProvider(cfg.Providers.Mistral.APIKey, cfg.Providers.Mistral.APIBase, timeout, maxTokens), cfg.Providers.Mistral
+	}
+
+	// If it's a Claude model and we have an Anthropic key, use the native provider.
+	// (Note: AnthropicProvider implementation pending in anthropic.go)
 	if strings.HasPrefix(model, "claude-") && cfg.Providers.Anthropic != nil && cfg.Providers.Anthropic.APIKey != "" {
 		return NewAnthropicProvider(
 			cfg.Providers.Anthropic.APIKey,
 			cfg.Providers.Anthropic.APIBase,
 			timeout,
 			maxTokens,
-		)
+		), cfg.Providers.Anthropic
 	}
 
 	// Default to OpenAI-compatible provider (works for GPT, Gemini, Grok, etc.)
@@ -31,7 +106,7 @@ func NewProviderFromConfig(cfg config.Config) LLMProvider {
 			cfg.Providers.OpenAI.APIBase,
 			timeout,
 			maxTokens,
-		)
+		), cfg.Providers.OpenAI
 	}
 
 	// Fallback to Anthropic if that's all we have and it wasn't caught by the model prefix
@@ -41,8 +116,16 @@ func NewProviderFromConfig(cfg config.Config) LLMProvider {
 			cfg.Providers.Anthropic.APIBase,
 			timeout,
 			maxTokens,
-		)
+		), cfg.Providers.Anthropic
+	}
+
+	// Otherwise use whichever of them is configured
+	if cfg.Providers.Groq != nil && cfg.Providers.Groq.APIKey != "" {
+		return NewGroqProvider(cfg.Providers.Groq.APIKey, cfg.Providers.Groq.APIBase, timeout, maxTokens), cfg.Providers.Groq
+	}
+	if cfg.Providers.Mistral != nil && cfg.Providers.Mistral.APIKey != "" {
+		return NewMistralProvider(cfg.Providers.Mistral.APIKey, cfg.Providers.Mistral.APIBase, timeout, maxTokens), cfg.Providers.Mistral
 	}
 
-	return NewStubProvider()
+	return NewStubProvider(), nil
 }
@@ -24,3 +24,48 @@ func TestNewProviderFromConfig_FallbacksToStub(t *testing.T) {
 		t.Fatalf("expected StubProvider, got %T", p)
 	}
 }
+
+func TestNewProviderFromConfig_RoutesGroqAndMistralByModel(t *testing.T) {
+	cfg := config.Config{}
+	cfg.Providers.OpenAI = &config.ProviderConfig{APIKey: "openai"}
+	cfg.Providers.Groq = &config.ProviderConfig{APIKey: "groq"}
+	cfg.Providers.Mistral = &config.ProviderConfig{APIKey: "mistral"}
+
+	for model, want := range map[string]string{
+		"groq/llama-3.3-70b-versatile": "https://api.groq.com/openai/v1",
+		"mistral-large-latest":         "https://api.mistral.ai/v1",
+		"codestral-latest":             "https://api.mistral.ai/v1",
+		"gpt-4o-mini":                  "https://api.openai.com/v1",
+	} {
+		cfg.Agents.Defaults.Model = model
+		p, ok := NewProviderFromConfig(cfg).(*OpenAIProvider)
+		if !ok || p.APIBase != want {
+			t.Fatalf("%s: expected provider at %s, got %+v", model, want, p)
+		}
+	}
+
+	// a Groq model without a Groq key falls back to the OpenAI block
+	cfg.Providers.Groq = nil
+	cfg.Agents.Defaults.Model = "groq/llama"
+	if p := NewProviderFromConfig(cfg).(*OpenAIProvider); p.APIKey != "openai" {
+		t.Fatalf("expected OpenAI fallback, got %+v", p)
+	}
+}
+
+func TestRouterPicksProviderPerModel(t *testing.T) {
+	cfg := config.Config{}
+	cfg.Agents.Defaults.Model = "claude-sonnet-4-5"
+	cfg.Providers.Anthropic = &config.ProviderConfig{APIKey: "a"}
+	cfg.Providers.Groq = &config.ProviderConfig{APIKey: "g"}
+	r := NewRouterFromConfig(cfg)
+
+	if _, ok := r.For("").(*AnthropicProvider); !ok {
+		t.Fatalf("expected the default model's provider, got %T", r.For(""))
+	}
+	if p, ok := r.For("groq/llama-3.1-8b-instant").(*OpenAIProvider); !ok || p.Name != "Groq" {
+		t.Fatalf("expected Groq for a groq/ model, got %+v", r.For("groq/llama-3.1-8b-instant"))
+	}
+	if r.For("groq/x") != r.For("groq/x") {
+		t.Fatal("expected providers to be reused per model")
+	}
+}
@@ -3,10 +3,8 @@ package providers
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"strings"
 	"time"
@@ -18,6 +16,21 @@ type OpenAIProvider struct {
 	APIBase   string // e.g. https://api.openai.com/v1 or https://openrouter.ai/api/v1
 	MaxTokens int
 	Client    *http.Client
+	Retry     RetryPolicy
+
+	// Name labels errors and logs; defaults to "OpenAI".
+	Name string
+	// Model overrides the default model. ModelPrefix is stripped from model
+	// names before they are sent, for services selected by prefix ("groq/").
+	Model       string
+	ModelPrefix string
+	// Params are sent with every request; WithParams overrides them per
+	// request.
+	Params GenerationParams
+
+	// noStreamOptions omits stream_options from streamed requests, for APIs
+	// that reject it and report usage anyway.
+	noStreamOptions bool
 }
 
 func NewOpenAIProvider(apiKey, apiBase string, timeoutSecs, maxTokens int) *OpenAIProvider {
@@ -34,13 +47,27 @@ func NewOpenAIProvider(apiKey, apiBase string, timeoutSecs, maxTokens int) *Open
 		APIKey:    apiKey,
 		APIBase:   strings.TrimRight(apiBase, "/"),
 		MaxTokens: maxTokens,
+		Retry:     DefaultRetryPolicy,
 		Client: &http.Client{
 			Timeout: time.Duration(timeoutSecs) * time.Second,
 		},
 	}
 }
 
-func (p *OpenAIProvider) GetDefaultModel() string { return "gpt-4o-mini" }
+func (p *OpenAIProvider) GetDefaultModel() string {
+	if p.Model != "" {
+		return p.Model
+	}
+	return "gpt-4o-mini"
+}
+
+// label returns the provider name used in errors and logs.
+func (p *OpenAIProvider) label() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return "OpenAI"
+}
 
 // Request/response shapes using the modern OpenAI "tools" format.
 type chatRequest struct {
@@ -48,6 +75,28 @@ type chatRequest struct {
 	Messages []messageJSON `json:"messages"`
 	Tools    []toolWrapper `json:"tools,omitempty"`
 	MaxTokens int          `json:"max_tokens,omitempty"`
+
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"top_p,omitempty"`
+	Stop             []string `json:"stop,omitempty"`
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"`
+	Seed             *int     `json:"seed,omitempty"`
+	ReasoningEffort  string   `json:"reasoning_effort,omitempty"`
+
+	ResponseFormat *responseFormatJSON `json:"response_format,omitempty"`
+}
+
+// responseFormatJSON is {"type": "json_object"} or
+// {"type": "json_schema", "json_schema": {"name": ..., "schema": ...}}.
+type responseFormatJSON struct {
+	Type       string          `json:"type"`
+	JSONSchema *jsonSchemaJSON `json:"json_schema,omitempty"`
+}
+
+type jsonSchemaJSON struct {
+	Name   string                 `json:"name"`
+	Schema map[string]interface{} `json:"schema"`
 }
 
 // toolWrapper is the OpenAI tools array element: {"type": "function", "function": {...}}
@@ -64,11 +113,22 @@ type functionDef struct {
 
 type messageJSON struct {
 	Role       string         `json:"role"`
-	Content    string         `json:"content"`
+	Content    interface{}    `json:"content"` // string, or []contentPart with images
 	ToolCallID string         `json:"tool_call_id,omitempty"`
 	ToolCalls  []toolCallJSON `json:"tool_calls,omitempty"`
 }
 
+// contentPart is an element of a multimodal message's content.
+type contentPart struct {
+	Type     string        `json:"type"` // "text" or "image_url"
+	Text     string        `json:"text,omitempty"`
+	ImageURL *imageURLJSON `json:"image_url,omitempty"`
+}
+
+type imageURLJSON struct {
+	URL string `json:"url"` // http(s) or data: URI
+}
+
 type toolCallJSON struct {
 	ID           string                `json:"id"`
 	Type         string                `json:"type"`
@@ -93,26 +153,90 @@ type messageResponseJSON struct {
 	Role      string         `json:"role"`
 	Content   string         `json:"content"`
 	ToolCalls []toolCallJSON `json:"tool_calls,omitempty"`
+	// reasoning text: "reasoning" on OpenRouter, "reasoning_content" on
+	// DeepSeek and others
+	Reasoning        string `json:"reasoning,omitempty"`
+	ReasoningContent string `json:"reasoning_content,omitempty"`
 }
 
 type chatResponse struct {
 	Choices []struct {
-		Message messageResponseJSON `json:"message"`
+		Message      messageResponseJSON `json:"message"`
+		FinishReason string              `json:"finish_reason"`
 	} `json:"choices"`
+	Usage *usageJSON `json:"usage,omitempty"`
+}
+
+type usageJSON struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+func (u *usageJSON) usage() Usage {
+	if u == nil {
+		return Usage{}
+	}
+	return Usage{PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens}
 }
 
 // Chat calls an OpenAI-compatible chat completion endpoint and returns a simplified response.
 func (p *OpenAIProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string) (LLMResponse, error) {
 	if p.APIKey == "" {
-		return LLMResponse{}, errors.New("OpenAI provider: API key is not configured")
+		return LLMResponse{}, errNoAPIKey(p.label())
+	}
+	resp, err := p.post(ctx, p.request(ctx, messages, tools, model))
+	if err != nil {
+		return LLMResponse{}, err
 	}
+	defer resp.Body.Close()
+
+	var out chatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return LLMResponse{}, err
+	}
+
+	if len(out.Choices) == 0 {
+		return LLMResponse{}, fmt.Errorf("%s API returned no choices", p.label())
+	}
+	res := toLLMResponse(out.Choices[0].Message)
+	res.Usage = out.Usage.usage()
+	if out.Choices[0].FinishReason == "content_filter" && res.Content == "" && !res.HasToolCalls {
+		return res, errFiltered(p.label(), "content_filter")
+	}
+	return res, nil
+}
+
+// request converts messages and tools to a chat completion request with the
+// provider's params and those of ctx.
+func (p *OpenAIProvider) request(ctx context.Context, messages []Message, tools []ToolDefinition, model string) chatRequest {
 	if model == "" {
 		model = p.GetDefaultModel()
 	}
-
+	model = strings.TrimPrefix(model, p.ModelPrefix)
 	reqBody := chatRequest{Model: model, MaxTokens: p.MaxTokens, Messages: make([]messageJSON, 0, len(messages))}
+	params := p.Params.Merge(ParamsFrom(ctx))
+	reqBody.Stop, reqBody.Seed = params.Stop, params.Seed
+	if isReasoningModel(model) {
+		reqBody.ReasoningEffort = params.ReasoningEffort
+	} else {
+		reqBody.Temperature, reqBody.TopP = params.Temperature, params.TopP
+		reqBody.PresencePenalty, reqBody.FrequencyPenalty = params.PresencePenalty, params.FrequencyPenalty
+	}
+	if f := params.ResponseFormat; f != nil {
+		reqBody.ResponseFormat = &responseFormatJSON{Type: "json_object"}
+		if f.Schema != nil {
+			reqBody.ResponseFormat = &responseFormatJSON{Type: "json_schema", JSONSchema: &jsonSchemaJSON{Name: f.name(), Schema: f.Schema}}
+		}
+	}
 	for _, m := range messages {
 		mj := messageJSON{Role: m.Role, Content: m.Content, ToolCallID: m.ToolCallID}
+		if len(m.Images) > 0 {
+			parts := []contentPart{{Type: "text", Text: m.Content}}
+			for _, img := range m.Images {
+				parts = append(parts, contentPart{Type: "image_url", ImageURL: &imageURLJSON{URL: img.dataURI()}})
+			}
+			mj.Content = parts
+		}
 		// Convert provider ToolCall to JSON-serializable toolCallJSON
 		for _, tc := range m.ToolCalls {
 			argsBytes, _ := json.Marshal(tc.Arguments)
@@ -153,9 +277,15 @@ func (p *OpenAIProvider) Chat(ctx context.Context, messages []Message, tools []T
 		}
 	}
 
+	return reqBody
+}
+
+// post sends a chat completion request, retrying transient failures, and
+// returns the response of a successful call.
+func (p *OpenAIProvider) post(ctx context.Context, reqBody interface{}) (*http.Response, error) {
 	b, err := json.Marshal(reqBody)
 	if err != nil {
-		return LLMResponse{}, err
+		return nil, err
 	}
 
 	apiURL := fmt.Sprintf("%s/chat/completions", p.APIBase)
@@ -169,33 +299,24 @@ func (p *OpenAIProvider) Chat(ctx context.Context, messages []Message, tools []T
 		return req, nil
 	}
 
-	resp, err := doWithRetry(ctx, p.Client, buildReq)
+	resp, err := doWithRetry(ctx, p.Client, p.Retry, buildReq)
 	if err != nil {
-		return LLMResponse{}, err
+		return nil, err
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
 		// attempt to read response body for more details (do not expose API key)
 		bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
 		body := strings.TrimSpace(string(bodyBytes))
-		log.Printf("OpenAI API non-2xx: %s body=%q", resp.Status, body)
-		if body == "" {
-			return LLMResponse{}, fmt.Errorf("OpenAI API error: %s", resp.Status)
-		}
-		return LLMResponse{}, fmt.Errorf("OpenAI API error: %s - %s", resp.Status, body)
-	}
-
-	var out chatResponse
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-		return LLMResponse{}, err
-	}
-
-	if len(out.Choices) == 0 {
-		return LLMResponse{}, errors.New("OpenAI API returned no choices")
+		providersLog.Error("API request failed", "provider", p.label(), "status", resp.Status, "response", body)
+		return nil, apiError(p.label(), resp, body)
 	}
+	return resp, nil
+}
 
-	msg := out.Choices[0].Message
+// toLLMResponse normalizes the assistant message of a completion.
+func toLLMResponse(msg messageResponseJSON) LLMResponse {
 	// If the model requested tool calls, parse them
 	if len(msg.ToolCalls) > 0 {
 		var tcs []ToolCall
@@ -219,12 +340,32 @@ func (p *OpenAIProvider) Chat(ctx context.Context, messages []Message, tools []T
 			})
 		}
 		if len(tcs) > 0 {
-			return LLMResponse{Content: strings.TrimSpace(msg.Content), HasToolCalls: true, ToolCalls: tcs}, nil
+			return LLMResponse{Content: strings.TrimSpace(msg.Content), HasToolCalls: true, ToolCalls: tcs, Reasoning: msg.reasoning()}
 		}
 	}
 
 	// No tool calls
-	return LLMResponse{Content: strings.TrimSpace(msg.Content), HasToolCalls: false}, nil
+	return LLMResponse{Content: strings.TrimSpace(msg.Content), HasToolCalls: false, Reasoning: msg.reasoning()}
+}
+
+func (m messageResponseJSON) reasoning() string {
+	if m.Reasoning != "" {
+		return strings.TrimSpace(m.Reasoning)
+	}
+	return strings.TrimSpace(m.ReasoningContent)
+}
+
+// isReasoningModel reports whether model is an OpenAI reasoning model
+// (o-series, gpt-5), which takes reasoning_effort and rejects sampling
+// settings.
+func isReasoningModel(model string) bool {
+	model = model[strings.LastIndex(model, "/")+1:] // "openai/o3-mini" on OpenRouter
+	for _, prefix := range []string{"o1", "o3", "o4", "gpt-5"} {
+		if strings.HasPrefix(model, prefix) {
+			return true
+		}
+	}
+	return false
 }
 
 func sanitizeToolName(name string) string {
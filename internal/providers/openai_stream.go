@@ -0,0 +1,120 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// chatStreamRequest is a chat completion request with streaming enabled.
+type chatStreamRequest struct {
+	chatRequest
+	Stream        bool           `json:"stream"`
+	StreamOptions *streamOptions `json:"stream_options,omitempty"`
+}
+
+// streamOptions asks for a final chunk with the request's token usage.
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// chatStreamChunk is one server-sent event of a streamed completion.
+type chatStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content          string `json:"content"`
+			Reasoning        string `json:"reasoning"`
+			ReasoningContent string `json:"reasoning_content"`
+			ToolCalls        []struct {
+				Index        int                   `json:"index"`
+				ID           string                `json:"id"`
+				Function     toolCallFunctionJSON  `json:"function"`
+				ExtraContent *toolCallExtraContent `json:"extra_content,omitempty"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *usageJSON `json:"usage,omitempty"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// ChatStream requests a streamed completion (server-sent events) and calls
+// onDelta with each fragment of text. Tool call arguments arrive in pieces
+// and are assembled before the response is returned.
+func (p *OpenAIProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, onDelta StreamFunc) (LLMResponse, error) {
+	if p.APIKey == "" {
+		return LLMResponse{}, errNoAPIKey(p.label())
+	}
+	req := chatStreamRequest{chatRequest: p.request(ctx, messages, tools, model), Stream: true}
+	if !p.noStreamOptions {
+		req.StreamOptions = &streamOptions{IncludeUsage: true}
+	}
+	resp, err := p.post(ctx, req)
+	if err != nil {
+		return LLMResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	var msg messageResponseJSON
+	var usage Usage
+	var finish string
+	err = readSSE(resp.Body, func(data []byte) error {
+		var chunk chatStreamChunk
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return fmt.Errorf("%s API: bad stream event: %w", p.label(), err)
+		}
+		if chunk.Error != nil {
+			return streamError(p.label(), "", chunk.Error.Message)
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage.usage()
+		}
+		if len(chunk.Choices) == 0 {
+			return nil
+		}
+		if r := chunk.Choices[0].FinishReason; r != "" {
+			finish = r
+		}
+		delta := chunk.Choices[0].Delta
+		msg.Reasoning += delta.Reasoning
+		msg.ReasoningContent += delta.ReasoningContent
+		if delta.Content != "" {
+			msg.Content += delta.Content
+			if onDelta != nil {
+				onDelta(delta.Content)
+			}
+		}
+		for _, tc := range delta.ToolCalls {
+			for len(msg.ToolCalls) <= tc.Index {
+				msg.ToolCalls = append(msg.ToolCalls, toolCallJSON{Type: "function"})
+			}
+			t := &msg.ToolCalls[tc.Index]
+			if tc.ID != "" {
+				t.ID = tc.ID
+			}
+			t.Function.Name += tc.Function.Name
+			t.Function.Arguments += tc.Function.Arguments
+			if tc.ExtraContent != nil {
+				t.ExtraContent = tc.ExtraContent
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return LLMResponse{}, err
+	}
+	// tools without arguments may stream none; treat that as {}
+	for i := range msg.ToolCalls {
+		if msg.ToolCalls[i].Function.Arguments == "" {
+			msg.ToolCalls[i].Function.Arguments = "{}"
+		}
+	}
+	res := toLLMResponse(msg)
+	res.Usage = usage
+	if finish == "content_filter" && res.Content == "" && !res.HasToolCalls {
+		return res, errFiltered(p.label(), "content_filter")
+	}
+	return res, nil
+}
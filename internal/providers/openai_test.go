@@ -2,8 +2,10 @@ package providers
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
@@ -57,3 +59,47 @@ func TestOpenAIFunctionCallParsing(t *testing.T) {
 		t.Fatalf("unexpected argument content: %v", resp.ToolCalls[0].Arguments)
 	}
 }
+
+func TestGroqProviderStripsModelPrefix(t *testing.T) {
+	var model string
+	h := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		model = req.Model
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"nope"}`))
+	}))
+	defer h.Close()
+
+	p := NewGroqProvider("k", h.URL, 5, 100)
+	_, err := p.Chat(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, "groq/llama-3.1-8b-instant")
+	if model != "llama-3.1-8b-instant" {
+		t.Fatalf("expected prefix to be stripped, server got %q", model)
+	}
+	if err == nil || !strings.HasPrefix(err.Error(), "Groq API error") {
+		t.Fatalf("expected a Groq API error, got %v", err)
+	}
+}
+
+func TestImagesAreTranslatedPerProvider(t *testing.T) {
+	msgs := []Message{{Role: "user", Content: "what is this?", Images: []Image{
+		{MediaType: "image/png", Data: []byte("png")},
+		{URL: "https://example.com/cat.jpg"},
+	}}}
+
+	oreq := NewOpenAIProvider("k", "", 60, 4096).request(context.Background(), msgs, nil, "gpt-4o")
+	b, _ := json.Marshal(oreq.Messages[0])
+	want := `{"role":"user","content":[{"type":"text","text":"what is this?"},{"type":"image_url","image_url":{"url":"data:image/png;base64,cG5n"}},{"type":"image_url","image_url":{"url":"https://example.com/cat.jpg"}}]}`
+	if string(b) != want {
+		t.Fatalf("unexpected OpenAI message:\n%s\nwant\n%s", b, want)
+	}
+
+	areq := NewAnthropicProvider("k", "", 60, 4096).request(context.Background(), msgs, nil, "claude-sonnet-4-5")
+	blocks := areq.Messages[0].Content
+	if len(blocks) != 3 || blocks[0].Type != "image" || blocks[0].Source.Type != "base64" || blocks[0].Source.Data != "cG5n" ||
+		blocks[1].Source.Type != "url" || blocks[2].Type != "text" {
+		t.Fatalf("unexpected Anthropic blocks: %+v", blocks)
+	}
+}
@@ -0,0 +1,77 @@
+package providers
+
+import "context"
+
+// GenerationParams are the sampling settings sent with a request. Nil and
+// empty fields are left out, so the API's defaults apply; settings an API
+// does not support are ignored.
+type GenerationParams struct {
+	Temperature      *float64
+	TopP             *float64
+	Stop             []string
+	PresencePenalty  *float64
+	FrequencyPenalty *float64
+	Seed             *int
+
+	// ThinkingBudget enables Anthropic extended thinking with this many
+	// tokens (minimum 1024); 0 disables it.
+	ThinkingBudget *int
+	// ReasoningEffort ("low", "medium" or "high") is sent to OpenAI
+	// reasoning models.
+	ReasoningEffort string
+
+	// ResponseFormat asks for a JSON reply; see StructuredAsk.
+	ResponseFormat *ResponseFormat
+}
+
+// Merge returns p with the fields set in o replacing its own.
+func (p GenerationParams) Merge(o GenerationParams) GenerationParams {
+	if o.Temperature != nil {
+		p.Temperature = o.Temperature
+	}
+	if o.TopP != nil {
+		p.TopP = o.TopP
+	}
+	if o.Stop != nil {
+		p.Stop = o.Stop
+	}
+	if o.PresencePenalty != nil {
+		p.PresencePenalty = o.PresencePenalty
+	}
+	if o.FrequencyPenalty != nil {
+		p.FrequencyPenalty = o.FrequencyPenalty
+	}
+	if o.Seed != nil {
+		p.Seed = o.Seed
+	}
+	if o.ThinkingBudget != nil {
+		p.ThinkingBudget = o.ThinkingBudget
+	}
+	if o.ReasoningEffort != "" {
+		p.ReasoningEffort = o.ReasoningEffort
+	}
+	if o.ResponseFormat != nil {
+		p.ResponseFormat = o.ResponseFormat
+	}
+	return p
+}
+
+// Float returns a pointer to v, for GenerationParams literals.
+func Float(v float64) *float64 { return &v }
+
+// Int returns a pointer to v, for GenerationParams literals.
+func Int(v int) *int { return &v }
+
+type paramsKey struct{}
+
+// WithParams returns a context whose requests use p on top of the
+// provider's configured params and of params set by outer contexts.
+func WithParams(ctx context.Context, p GenerationParams) context.Context {
+	return context.WithValue(ctx, paramsKey{}, ParamsFrom(ctx).Merge(p))
+}
+
+// ParamsFrom returns the per-request params set with WithParams.
+func ParamsFrom(ctx context.Context) GenerationParams {
+	p, _ := ctx.Value(paramsKey{}).(GenerationParams)
+	return p
+}
@@ -0,0 +1,99 @@
+package providers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kr0nicas/picobot/internal/config"
+)
+
+func TestOpenAIRequestParams(t *testing.T) {
+	seed := 7
+	p := NewOpenAIProvider("k", "", 60, 4096)
+	p.Params = GenerationParams{Temperature: Float(0.7), TopP: Float(0.9), Stop: []string{"END"}, Seed: &seed}
+	ctx := WithParams(context.Background(), GenerationParams{Temperature: Float(0)})
+
+	req := p.request(ctx, []Message{{Role: "user", Content: "hi"}}, nil, "gpt-4o")
+	if req.Temperature == nil || *req.Temperature != 0 {
+		t.Fatalf("expected the per-request temperature 0, got %v", req.Temperature)
+	}
+	if req.TopP == nil || *req.TopP != 0.9 || len(req.Stop) != 1 || req.Seed == nil || *req.Seed != 7 {
+		t.Fatalf("configured params not sent: %+v", req)
+	}
+
+	// reasoning models reject sampling settings
+	req = p.request(ctx, []Message{{Role: "user", Content: "hi"}}, nil, "openai/o3-mini")
+	if req.Temperature != nil || req.TopP != nil {
+		t.Fatalf("expected no sampling params for o3-mini, got %+v", req)
+	}
+}
+
+func TestAnthropicRequestParams(t *testing.T) {
+	p := NewAnthropicProvider("k", "", 60, 4096)
+	p.Params = GenerationParams{Temperature: Float(0.5), TopP: Float(0.9), Stop: []string{"END"}}
+	req := p.request(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, "claude-sonnet-4-5")
+	if req.Temperature == nil || *req.Temperature != 0.5 || req.TopP != nil || len(req.StopSequences) != 1 {
+		t.Fatalf("unexpected params: %+v", req)
+	}
+}
+
+func TestParamsFromConfig(t *testing.T) {
+	cfg := config.Config{}
+	cfg.Agents.Defaults.Temperature = Float(0.3)
+	cfg.Agents.Defaults.FrequencyPenalty = -0.5
+	p := ParamsFromConfig(cfg)
+	if p.Temperature == nil || *p.Temperature != 0.3 || p.FrequencyPenalty == nil || *p.FrequencyPenalty != -0.5 {
+		t.Fatalf("unexpected params: %+v", p)
+	}
+	if p.TopP != nil || p.PresencePenalty != nil || p.Seed != nil {
+		t.Fatalf("unset fields must stay nil: %+v", p)
+	}
+	cfg.Agents.Defaults.Temperature = Float(0)
+	if p := ParamsFromConfig(cfg); p.Temperature == nil || *p.Temperature != 0 {
+		t.Fatalf("temperature 0 must be sent, got %v", p.Temperature)
+	}
+}
+
+func TestAnthropicThinking(t *testing.T) {
+	p := NewAnthropicProvider("k", "", 60, 1000)
+	p.Params = GenerationParams{Temperature: Float(0.7), ThinkingBudget: Int(2000)}
+	msgs := []Message{
+		{Role: "user", Content: "hi"},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "t1", Name: "web"}}, Thinking: []ThinkingBlock{{Text: "hmm", Signature: "sig"}, {Data: "enc"}}},
+		{Role: "tool", ToolCallID: "t1", Content: "result"},
+	}
+	req := p.request(context.Background(), msgs, nil, "claude-sonnet-4-5")
+	if req.Thinking == nil || req.Thinking.BudgetTokens != 2000 || req.MaxTokens <= 2000 || req.Temperature != nil {
+		t.Fatalf("unexpected thinking request: %+v", req)
+	}
+	blocks := req.Messages[1].Content
+	if len(blocks) != 3 || blocks[0].Type != "thinking" || blocks[0].Signature != "sig" || blocks[1].Type != "redacted_thinking" || blocks[2].Type != "tool_use" {
+		t.Fatalf("thinking blocks not replayed first: %+v", blocks)
+	}
+
+	// per-request override turns it off
+	req = p.request(WithParams(context.Background(), GenerationParams{ThinkingBudget: Int(0)}), msgs, nil, "claude-sonnet-4-5")
+	if req.Thinking != nil || req.Temperature == nil {
+		t.Fatalf("expected thinking disabled, got %+v", req)
+	}
+
+	res := anthropicLLMResponse([]anthropicBlock{{Type: "thinking", Thinking: "let me see", Signature: "s"}, {Type: "text", Text: "answer"}})
+	if res.Content != "answer" || res.Reasoning != "let me see" || len(res.Thinking) != 1 {
+		t.Fatalf("unexpected response: %+v", res)
+	}
+}
+
+func TestOpenAIReasoning(t *testing.T) {
+	p := NewOpenAIProvider("k", "", 60, 4096)
+	p.Params = GenerationParams{ReasoningEffort: "high", Temperature: Float(0.7)}
+	if req := p.request(context.Background(), nil, nil, "o4-mini"); req.ReasoningEffort != "high" {
+		t.Fatalf("expected reasoning_effort for o4-mini, got %+v", req)
+	}
+	if req := p.request(context.Background(), nil, nil, "gpt-4o"); req.ReasoningEffort != "" {
+		t.Fatalf("reasoning_effort sent to gpt-4o: %+v", req)
+	}
+	res := toLLMResponse(messageResponseJSON{Content: "answer", ReasoningContent: "thinking..."})
+	if res.Content != "answer" || res.Reasoning != "thinking..." {
+		t.Fatalf("unexpected response: %+v", res)
+	}
+}
@@ -1,6 +1,13 @@
 package providers
 
-import "context"
+import (
+	"context"
+	"encoding/base64"
+
+	"github.com/kr0nicas/picobot/internal/logging"
+)
+
+var providersLog = logging.For("providers")
 
 // Message represents a chat message to/from the LLM.
 type Message struct {
@@ -8,6 +15,35 @@ type Message struct {
 	Content    string     `json:"content"`
 	ToolCallID string     `json:"tool_call_id,omitempty"` // set when Role == "tool"
 	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // set on assistant msgs with tool calls
+	// Thinking carries an assistant reply's thinking blocks back to the
+	// provider, which requires them alongside tool calls.
+	Thinking []ThinkingBlock `json:"-"`
+	// Images are sent with a user message to models that accept them.
+	Images []Image `json:"images,omitempty"`
+}
+
+// Image is a picture attached to a message, either inline Data of MediaType
+// (e.g. "image/png") or a URL the provider fetches itself.
+type Image struct {
+	MediaType string `json:"mediaType,omitempty"`
+	Data      []byte `json:"-"`
+	URL       string `json:"url,omitempty"`
+}
+
+// dataURI returns the image as a data: URI, or its URL.
+func (img Image) dataURI() string {
+	if img.URL != "" {
+		return img.URL
+	}
+	return "data:" + img.MediaType + ";base64," + base64.StdEncoding.EncodeToString(img.Data)
+}
+
+// ThinkingBlock is an opaque block of model reasoning (Anthropic extended
+// thinking). Redacted blocks carry only encrypted Data.
+type ThinkingBlock struct {
+	Text      string
+	Signature string
+	Data      string
 }
 
 // ToolDefinition is a lightweight description of a tool available to the model.
@@ -30,6 +66,18 @@ type LLMResponse struct {
 	Content      string     `json:"content"`
 	HasToolCalls bool       `json:"hasToolCalls"`
 	ToolCalls    []ToolCall `json:"toolCalls,omitempty"`
+	Usage        Usage      `json:"usage"`
+	// Reasoning is the model's thinking, when the API returns it. It is for
+	// logs and traces only and never reaches the user.
+	Reasoning string          `json:"reasoning,omitempty"`
+	Thinking  []ThinkingBlock `json:"-"` // to send back with the assistant message
+}
+
+// Usage is the number of tokens a request consumed, as reported by the API
+// (zero when it reports nothing).
+type Usage struct {
+	PromptTokens     int `json:"promptTokens"`
+	CompletionTokens int `json:"completionTokens"`
 }
 
 // LLMProvider is the interface used by the agent loop to call LLMs.
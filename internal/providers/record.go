@@ -0,0 +1,180 @@
+package providers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Recording is one request to a provider and its outcome, as stored in a
+// trace file (JSONL, one recording per line).
+type Recording struct {
+	Time     time.Time        `json:"time"`
+	Model    string           `json:"model"`
+	Messages []Message        `json:"messages"`
+	Tools    []ToolDefinition `json:"tools,omitempty"`
+	Response LLMResponse      `json:"response"`
+	Error    string           `json:"error,omitempty"`
+}
+
+// RecordingProvider passes requests to another provider and appends every
+// request/response pair to a trace file, so a conversation can be replayed
+// later with ReplayProvider.
+type RecordingProvider struct {
+	p    LLMProvider
+	path string
+
+	mu sync.Mutex
+	f  *os.File
+}
+
+// NewRecordingProvider records p's traffic to a new file in dir named after
+// the current time. The file is created on the first request.
+func NewRecordingProvider(p LLMProvider, dir string) *RecordingProvider {
+	name := time.Now().Format("20060102-150405") + ".jsonl"
+	return &RecordingProvider{p: p, path: filepath.Join(dir, name)}
+}
+
+// Path returns the trace file.
+func (r *RecordingProvider) Path() string { return r.path }
+
+func (r *RecordingProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string) (LLMResponse, error) {
+	resp, err := r.p.Chat(ctx, messages, tools, model)
+	r.record(model, messages, tools, resp, err)
+	return resp, err
+}
+
+func (r *RecordingProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, onDelta StreamFunc) (LLMResponse, error) {
+	resp, err := ChatStream(ctx, r.p, messages, tools, model, onDelta)
+	r.record(model, messages, tools, resp, err)
+	return resp, err
+}
+
+func (r *RecordingProvider) GetDefaultModel() string { return r.p.GetDefaultModel() }
+
+// record appends a recording; failures are logged, never returned, so
+// recording cannot break a conversation.
+func (r *RecordingProvider) record(model string, messages []Message, tools []ToolDefinition, resp LLMResponse, err error) {
+	rec := Recording{Time: time.Now().UTC(), Model: model, Messages: messages, Tools: tools, Response: resp}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	b, jerr := json.Marshal(rec)
+	if jerr != nil {
+		providersLog.Error("trace recording failed", "err", jerr)
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+			providersLog.Error("trace recording failed", "err", err)
+			return
+		}
+		f, err := os.OpenFile(r.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			providersLog.Error("trace recording failed", "err", err)
+			return
+		}
+		r.f = f
+	}
+	if _, err := r.f.Write(append(b, '\n')); err != nil {
+		providersLog.Error("trace recording failed", "err", err)
+	}
+}
+
+// Close closes the trace file.
+func (r *RecordingProvider) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return nil
+	}
+	return r.f.Close()
+}
+
+// ReplayProvider serves the responses of a trace file in order, without
+// calling any API, so recorded conversations can be reproduced offline.
+type ReplayProvider struct {
+	mu   sync.Mutex
+	recs []Recording
+	next int
+}
+
+// NewReplayProvider loads a trace written by RecordingProvider.
+func NewReplayProvider(path string) (*ReplayProvider, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("replay: %w", err)
+	}
+	defer f.Close()
+	var recs []Recording
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 64<<20)
+	for line := 1; sc.Scan(); line++ {
+		var rec Recording
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("replay: %s line %d: %w", path, line, err)
+		}
+		recs = append(recs, rec)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("replay: %w", err)
+	}
+	return &ReplayProvider{recs: recs}, nil
+}
+
+// Remaining returns the number of responses not served yet.
+func (r *ReplayProvider) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.recs) - r.next
+}
+
+func (r *ReplayProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string) (LLMResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.next >= len(r.recs) {
+		return LLMResponse{}, errors.New("replay: no recorded responses left")
+	}
+	rec := r.recs[r.next]
+	r.next++
+	if last, want := lastContent(messages), lastContent(rec.Messages); last != want {
+		providersLog.Warn("replayed request differs from the recording", "request", r.next, "last_message", last, "recorded", want)
+	}
+	if rec.Error != "" {
+		return rec.Response, errors.New(rec.Error)
+	}
+	return rec.Response, nil
+}
+
+// ChatStream serves the recorded reply as a single fragment.
+func (r *ReplayProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, onDelta StreamFunc) (LLMResponse, error) {
+	resp, err := r.Chat(ctx, messages, tools, model)
+	if err == nil && onDelta != nil && resp.Content != "" {
+		onDelta(resp.Content)
+	}
+	return resp, err
+}
+
+func (r *ReplayProvider) GetDefaultModel() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.recs) > 0 {
+		return r.recs[0].Model
+	}
+	return "replay"
+}
+
+func lastContent(messages []Message) string {
+	if len(messages) == 0 {
+		return ""
+	}
+	return messages[len(messages)-1].Content
+}
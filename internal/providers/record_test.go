@@ -0,0 +1,62 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+)
+
+// scriptedProvider returns a tool call, then a reply, then an error.
+type scriptedProvider struct{ n int }
+
+func (p *scriptedProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string) (LLMResponse, error) {
+	p.n++
+	switch p.n {
+	case 1:
+		return LLMResponse{HasToolCalls: true, ToolCalls: []ToolCall{{ID: "c1", Name: "web", Arguments: map[string]interface{}{"url": "https://example.com"}}}}, nil
+	case 2:
+		return LLMResponse{Content: "done", Usage: Usage{PromptTokens: 10, CompletionTokens: 2}}, nil
+	}
+	return LLMResponse{}, errors.New("boom")
+}
+
+func (p *scriptedProvider) GetDefaultModel() string { return "scripted" }
+
+func TestRecordAndReplay(t *testing.T) {
+	rec := NewRecordingProvider(&scriptedProvider{}, t.TempDir())
+	ctx := context.Background()
+	msgs := []Message{{Role: "user", Content: "fetch it"}}
+	var live []LLMResponse
+	for i := 0; i < 3; i++ {
+		resp, _ := rec.Chat(ctx, msgs, nil, "m1")
+		live = append(live, resp)
+	}
+	rec.Close()
+	if _, err := os.Stat(rec.Path()); err != nil {
+		t.Fatalf("trace not written: %v", err)
+	}
+
+	rp, err := NewReplayProvider(rec.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rp.GetDefaultModel() != "m1" || rp.Remaining() != 3 {
+		t.Fatalf("unexpected replay state: model %s, %d left", rp.GetDefaultModel(), rp.Remaining())
+	}
+	resp, _ := rp.Chat(ctx, msgs, nil, "")
+	if !resp.HasToolCalls || resp.ToolCalls[0].Arguments["url"] != "https://example.com" {
+		t.Fatalf("tool call not replayed: %+v", resp)
+	}
+	var streamed string
+	resp, _ = rp.ChatStream(ctx, msgs, nil, "", func(d string) { streamed += d })
+	if resp.Content != live[1].Content || streamed != "done" || resp.Usage != live[1].Usage {
+		t.Fatalf("reply not replayed: %+v (streamed %q)", resp, streamed)
+	}
+	if _, err := rp.Chat(ctx, msgs, nil, ""); err == nil || err.Error() != "boom" {
+		t.Fatalf("expected the recorded error, got %v", err)
+	}
+	if _, err := rp.Chat(ctx, msgs, nil, ""); err == nil {
+		t.Fatal("expected an error once the trace is exhausted")
+	}
+}
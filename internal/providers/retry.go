@@ -2,20 +2,35 @@ package providers
 
 import (
 	"context"
-	"log"
 	"math"
+	"math/rand/v2"
 	"net/http"
 	"strconv"
 	"time"
-)
 
-const (
-	maxRetries    = 3
-	baseDelay     = 1 * time.Second
-	maxDelay      = 60 * time.Second
-	rateLimitBase = 5 * time.Second // longer base delay for 429
+	"github.com/kr0nicas/picobot/internal/telemetry"
 )
 
+// RetryPolicy controls how requests that fail with a network error, 429 or
+// 5xx are retried.
+type RetryPolicy struct {
+	MaxRetries int           // 0 disables retries
+	BaseDelay  time.Duration // first delay; doubles on every attempt (x5 for 429)
+	MaxDelay   time.Duration // cap on a single delay
+	// Jitter randomizes each delay between half and all of its value, so
+	// clients that failed together don't retry together.
+	Jitter bool
+	// HonorRetryAfter waits as long as the server's Retry-After header asks,
+	// up to MaxDelay.
+	HonorRetryAfter bool
+}
+
+// DefaultRetryPolicy is used by providers unless configured otherwise.
+var DefaultRetryPolicy = RetryPolicy{MaxRetries: 3, BaseDelay: time.Second, MaxDelay: 60 * time.Second, HonorRetryAfter: true}
+
+// rateLimitFactor lengthens the base delay after a 429.
+const rateLimitFactor = 5
+
 // retryableStatusCode returns true for HTTP status codes that warrant a retry.
 func retryableStatusCode(code int) bool {
 	switch code {
@@ -29,11 +44,19 @@ func retryableStatusCode(code int) bool {
 	return false
 }
 
-// backoffDelay returns the delay for the given attempt using exponential backoff.
-func backoffDelay(attempt int) time.Duration {
-	delay := time.Duration(float64(baseDelay) * math.Pow(2, float64(attempt)))
-	if delay > maxDelay {
-		delay = maxDelay
+// backoffDelay returns the delay before retry number attempt (from 0) after
+// a response with status (0 for network errors).
+func (rp RetryPolicy) backoffDelay(attempt, status int) time.Duration {
+	base := rp.BaseDelay
+	if status == http.StatusTooManyRequests {
+		base *= rateLimitFactor
+	}
+	delay := time.Duration(float64(base) * math.Pow(2, float64(attempt)))
+	if delay > rp.MaxDelay || delay <= 0 {
+		delay = rp.MaxDelay
+	}
+	if rp.Jitter && delay > 1 {
+		delay = delay/2 + time.Duration(rand.Int64N(int64(delay/2)))
 	}
 	return delay
 }
@@ -59,26 +82,26 @@ func retryAfterDelay(resp *http.Response) time.Duration {
 	return 0
 }
 
-// doWithRetry executes an HTTP request with retries for transient errors.
-// It respects the Retry-After header for 429 responses.
-func doWithRetry(ctx context.Context, client *http.Client, buildReq func() (*http.Request, error)) (*http.Response, error) {
+// doWithRetry executes an HTTP request, retrying transient errors as rp
+// specifies.
+func doWithRetry(ctx context.Context, client *http.Client, rp RetryPolicy, buildReq func() (*http.Request, error)) (*http.Response, error) {
 	var resp *http.Response
 	var err error
 
-	for attempt := 0; attempt <= maxRetries; attempt++ {
+	for attempt := 0; attempt <= rp.MaxRetries; attempt++ {
 		if attempt > 0 {
-			delay := backoffDelay(attempt - 1)
-			// For 429, use longer base delay or Retry-After header
-			if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
-				delay = time.Duration(float64(rateLimitBase) * math.Pow(2, float64(attempt-1)))
-				if ra := retryAfterDelay(resp); ra > 0 && ra <= 60*time.Second {
+			status := 0
+			if resp != nil {
+				status = resp.StatusCode
+			}
+			delay := rp.backoffDelay(attempt-1, status)
+			if resp != nil && rp.HonorRetryAfter {
+				if ra := retryAfterDelay(resp); ra > 0 && ra <= rp.MaxDelay {
 					delay = ra
 				}
-				if delay > maxDelay {
-					delay = maxDelay
-				}
 			}
-			log.Printf("provider: retrying request (attempt %d/%d, waiting %v)", attempt, maxRetries, delay)
+			providersLog.Warn("retrying request", "attempt", attempt, "max", rp.MaxRetries, "wait", delay)
+			telemetry.FromContext(ctx).SetAttributes("retry", attempt, "retry_wait_ms", delay.Milliseconds())
 			select {
 			case <-ctx.Done():
 				return nil, ctx.Err()
@@ -95,10 +118,11 @@ func doWithRetry(ctx context.Context, client *http.Client, buildReq func() (*htt
 		resp, err = client.Do(req)
 		if err != nil {
 			// Network errors are retryable
+			resp = nil
 			continue
 		}
 
-		if !retryableStatusCode(resp.StatusCode) {
+		if !retryableStatusCode(resp.StatusCode) || attempt == rp.MaxRetries {
 			return resp, nil
 		}
 
@@ -106,9 +130,6 @@ func doWithRetry(ctx context.Context, client *http.Client, buildReq func() (*htt
 		resp.Body.Close()
 	}
 
-	// Return the last response or error
-	if err != nil {
-		return nil, err
-	}
-	return resp, nil
+	// Only network errors get here
+	return nil, err
 }
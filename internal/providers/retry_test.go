@@ -0,0 +1,68 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/config"
+)
+
+func TestDoWithRetryFollowsPolicy(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	get := func() (*http.Request, error) { return http.NewRequest("GET", srv.URL, nil) }
+	rp := RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond, Jitter: true}
+
+	resp, err := doWithRetry(context.Background(), srv.Client(), rp, get)
+	if err != nil || resp.StatusCode != http.StatusOK || calls.Load() != 3 {
+		t.Fatalf("expected success on the third call, got %v %v after %d calls", resp, err, calls.Load())
+	}
+	resp.Body.Close()
+
+	// without retries the failure comes back at once, with its body readable
+	calls.Store(0)
+	rp.MaxRetries = 0
+	resp, err = doWithRetry(context.Background(), srv.Client(), rp, get)
+	if err != nil || resp.StatusCode != http.StatusServiceUnavailable || calls.Load() != 1 {
+		t.Fatalf("expected one 503, got %v %v after %d calls", resp, err, calls.Load())
+	}
+	resp.Body.Close()
+}
+
+func TestBackoffDelay(t *testing.T) {
+	rp := RetryPolicy{BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+	if d := rp.backoffDelay(2, 0); d != 4*time.Second {
+		t.Fatalf("expected 4s, got %v", d)
+	}
+	if d := rp.backoffDelay(1, http.StatusTooManyRequests); d != 10*time.Second {
+		t.Fatalf("expected the 429 delay capped at 10s, got %v", d)
+	}
+	rp.Jitter = true
+	for i := 0; i < 20; i++ {
+		if d := rp.backoffDelay(2, 0); d < 2*time.Second || d > 4*time.Second {
+			t.Fatalf("jittered delay out of range: %v", d)
+		}
+	}
+}
+
+func TestRetryPolicyFromConfig(t *testing.T) {
+	zero, off := 0, false
+	rp := RetryPolicyFromConfig(&config.ProviderConfig{Retry: &config.RetryConfig{MaxRetries: &zero, BaseDelayMs: 200, HonorRetryAfter: &off}})
+	if rp.MaxRetries != 0 || rp.BaseDelay != 200*time.Millisecond || rp.MaxDelay != DefaultRetryPolicy.MaxDelay || rp.HonorRetryAfter {
+		t.Fatalf("unexpected policy: %+v", rp)
+	}
+	if RetryPolicyFromConfig(nil) != DefaultRetryPolicy {
+		t.Fatal("expected the default policy without config")
+	}
+}
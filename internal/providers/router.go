@@ -0,0 +1,51 @@
+package providers
+
+import (
+	"context"
+	"sync"
+
+	"github.com/kr0nicas/picobot/internal/config"
+)
+
+// Router is an LLMProvider that sends each request to the configured
+// provider for the request's model, so tasks routed to different models
+// (e.g. "claude-..." for chat, "groq/..." for ranking) reach the right
+// service.
+type Router struct {
+	cfg config.Config
+	def LLMProvider
+
+	mu      sync.Mutex
+	byModel map[string]LLMProvider
+}
+
+// NewRouterFromConfig creates a Router; requests without a model go to the
+// provider of agents.defaults.model.
+func NewRouterFromConfig(cfg config.Config) *Router {
+	return &Router{cfg: cfg, def: NewProviderFromConfig(cfg), byModel: make(map[string]LLMProvider)}
+}
+
+// For returns the provider that serves model.
+func (r *Router) For(model string) LLMProvider {
+	if model == "" || model == r.cfg.Agents.Defaults.Model {
+		return r.def
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.byModel[model]
+	if !ok {
+		p = newProviderForModel(r.cfg, model)
+		r.byModel[model] = p
+	}
+	return p
+}
+
+func (r *Router) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string) (LLMResponse, error) {
+	return r.For(model).Chat(ctx, messages, tools, model)
+}
+
+func (r *Router) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, onDelta StreamFunc) (LLMResponse, error) {
+	return ChatStream(ctx, r.For(model), messages, tools, model, onDelta)
+}
+
+func (r *Router) GetDefaultModel() string { return r.def.GetDefaultModel() }
@@ -0,0 +1,64 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+)
+
+// StreamFunc receives fragments of the reply text as they are generated.
+type StreamFunc func(delta string)
+
+// StreamingProvider is implemented by providers that can stream replies.
+// Providers that only implement Chat keep working; callers fall back to it.
+type StreamingProvider interface {
+	LLMProvider
+	// ChatStream is Chat with onDelta called for every fragment of text as
+	// it arrives. The returned response is complete, as from Chat.
+	ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, onDelta StreamFunc) (LLMResponse, error)
+}
+
+// ChatStream streams from p when it supports it and otherwise calls Chat,
+// in which case onDelta is not called.
+func ChatStream(ctx context.Context, p LLMProvider, messages []Message, tools []ToolDefinition, model string, onDelta StreamFunc) (LLMResponse, error) {
+	if sp, ok := p.(StreamingProvider); ok && onDelta != nil {
+		return sp.ChatStream(ctx, messages, tools, model, onDelta)
+	}
+	return p.Chat(ctx, messages, tools, model)
+}
+
+// readSSE calls fn with the data of each server-sent event in r until the
+// stream ends, fn fails, or an OpenAI-style "[DONE]" marker arrives.
+func readSSE(r io.Reader, fn func(data []byte) error) error {
+	br := bufio.NewReaderSize(r, 64*1024)
+	var data []byte
+	for {
+		line, err := br.ReadBytes('\n')
+		line = bytes.TrimRight(line, "\r\n")
+		switch {
+		case len(line) == 0 && len(data) > 0: // end of event
+			if bytes.Equal(data, []byte("[DONE]")) {
+				return nil
+			}
+			if ferr := fn(data); ferr != nil {
+				return ferr
+			}
+			data = data[:0]
+		case bytes.HasPrefix(line, []byte("data:")):
+			if len(data) > 0 {
+				data = append(data, '\n')
+			}
+			data = append(data, bytes.TrimPrefix(bytes.TrimPrefix(line, []byte("data:")), []byte(" "))...)
+		}
+		if err == io.EOF {
+			if len(data) > 0 && !bytes.Equal(data, []byte("[DONE]")) {
+				return fn(data)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOpenAIChatStream(t *testing.T) {
+	h := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["stream"] != true || req["stream_options"] == nil {
+			t.Errorf("expected stream=true and stream_options in request, got %v", req)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, ev := range []string{
+			`{"choices":[{"delta":{"role":"assistant","content":"Hel"}}]}`,
+			`{"choices":[{"delta":{"content":"lo"}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"message","arguments":"{\"con"}}]}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"tent\":\"hi\"}"}}]}}]}`,
+			`{"choices":[],"usage":{"prompt_tokens":12,"completion_tokens":5}}`,
+			`[DONE]`,
+		} {
+			w.Write([]byte("data: " + ev + "\n\n"))
+		}
+	}))
+	defer h.Close()
+
+	p := NewOpenAIProvider("test-key", h.URL, 5, 100)
+	var deltas []string
+	resp, err := p.ChatStream(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, "m", func(d string) { deltas = append(deltas, d) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(deltas, "|") != "Hel|lo" || resp.Content != "Hello" {
+		t.Fatalf("unexpected stream: deltas=%q content=%q", deltas, resp.Content)
+	}
+	if !resp.HasToolCalls || resp.ToolCalls[0].ID != "call_1" || resp.ToolCalls[0].Arguments["content"] != "hi" {
+		t.Fatalf("unexpected tool calls: %+v", resp.ToolCalls)
+	}
+	if resp.Usage != (Usage{PromptTokens: 12, CompletionTokens: 5}) {
+		t.Fatalf("unexpected usage: %+v", resp.Usage)
+	}
+}
+
+func TestAnthropicChatStream(t *testing.T) {
+	h := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, ev := range []string{
+			`{"type":"message_start","message":{"id":"m1","usage":{"input_tokens":30,"output_tokens":1}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Let me "}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"check."}}`,
+			`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"tu_1","name":"web","input":{}}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"url\":"}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"https://x\"}"}}`,
+			`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":42}}`,
+			`{"type":"message_stop"}`,
+		} {
+			w.Write([]byte("event: x\ndata: " + ev + "\n\n"))
+		}
+	}))
+	defer h.Close()
+
+	p := NewAnthropicProvider("test-key", h.URL, 5, 100)
+	var got strings.Builder
+	resp, err := p.ChatStream(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, "m", func(d string) { got.WriteString(d) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != "Let me check." || resp.Content != "Let me check." {
+		t.Fatalf("unexpected stream %q / %q", got.String(), resp.Content)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Name != "web" || resp.ToolCalls[0].Arguments["url"] != "https://x" {
+		t.Fatalf("unexpected tool calls: %+v", resp.ToolCalls)
+	}
+	if resp.Usage != (Usage{PromptTokens: 30, CompletionTokens: 42}) {
+		t.Fatalf("unexpected usage: %+v", resp.Usage)
+	}
+}
+
+func TestChatStreamFallsBackToChat(t *testing.T) {
+	called := false
+	resp, err := ChatStream(context.Background(), NewStubProvider(), []Message{{Role: "user", Content: "hi"}}, nil, "", func(string) { called = true })
+	if err != nil || resp.Content == "" || called {
+		t.Fatalf("unexpected fallback result: %+v, %v, called=%v", resp, err, called)
+	}
+}
@@ -0,0 +1,185 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ResponseFormat asks for a reply that is a JSON object. With a Schema,
+// OpenAI gets it as a json_schema response format and Anthropic is made to
+// call a tool taking it as input; without one any object will do. Schemas
+// use the JSON Schema subset of tool parameters and must describe an
+// object.
+type ResponseFormat struct {
+	Name   string // names the schema for the API; default "response"
+	Schema map[string]interface{}
+}
+
+func (f *ResponseFormat) name() string {
+	if f.Name == "" {
+		return "response"
+	}
+	return f.Name
+}
+
+func (f *ResponseFormat) schema() map[string]interface{} {
+	if f.Schema == nil {
+		return map[string]interface{}{"type": "object"}
+	}
+	return f.Schema
+}
+
+// ErrInvalidStructuredReply is returned by StructuredAsk when the model
+// keeps replying with JSON that doesn't match the schema.
+var ErrInvalidStructuredReply = errors.New("no valid structured reply")
+
+// StructuredAsk sends messages to p in JSON mode, checks the reply against
+// f.Schema and decodes it into out. Invalid replies are handed back to the
+// model with what is wrong, up to retries times. It returns the last
+// response, so callers can still salvage an invalid one.
+func StructuredAsk(ctx context.Context, p LLMProvider, messages []Message, model string, f ResponseFormat, retries int, out interface{}) (LLMResponse, error) {
+	ctx = WithParams(ctx, GenerationParams{ResponseFormat: &f})
+	instr := "Reply with only a JSON object"
+	if f.Schema != nil {
+		s, _ := json.Marshal(f.Schema)
+		instr += " matching this JSON schema: " + string(s)
+	}
+	msgs := append(messages[:len(messages):len(messages)], Message{Role: "user", Content: instr + "."})
+	var resp LLMResponse
+	var err error
+	for attempt := 0; ; attempt++ {
+		resp, err = p.Chat(ctx, msgs, nil, model)
+		if err != nil {
+			return resp, err
+		}
+		raw := structuredContent(resp)
+		if err = decodeStructured(raw, f.schema(), out); err == nil {
+			return resp, nil
+		}
+		if attempt >= retries {
+			return resp, fmt.Errorf("%w after %d attempts: %v", ErrInvalidStructuredReply, attempt+1, err)
+		}
+		msgs = append(msgs,
+			Message{Role: "assistant", Content: raw},
+			Message{Role: "user", Content: fmt.Sprintf("That reply is invalid: %v. %s.", err, instr)})
+	}
+}
+
+// structuredContent returns the JSON of a reply: its text without code
+// fences or surrounding prose, or the arguments of a tool call for models
+// that answer with one.
+func structuredContent(resp LLMResponse) string {
+	s := strings.TrimSpace(resp.Content)
+	if s == "" && len(resp.ToolCalls) > 0 {
+		b, _ := json.Marshal(resp.ToolCalls[0].Arguments)
+		return string(b)
+	}
+	if i, j := strings.Index(s, "{"), strings.LastIndex(s, "}"); i >= 0 && j > i {
+		s = s[i : j+1]
+	}
+	return s
+}
+
+func decodeStructured(raw string, schema map[string]interface{}, out interface{}) error {
+	var v interface{}
+	if err := json.Unmarshal([]byte(raw), &v); err != nil {
+		return fmt.Errorf("not JSON: %v", err)
+	}
+	if err := ValidateJSON(schema, v); err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal([]byte(raw), out)
+}
+
+// ValidateJSON checks v, as decoded by encoding/json into an interface{},
+// against schema. It understands type, enum, properties, required and
+// items, which is what tool and response schemas use.
+func ValidateJSON(schema map[string]interface{}, v interface{}) error {
+	// round-trip the schema so slices written in Go ([]string) look like
+	// decoded JSON
+	b, err := json.Marshal(schema)
+	if err != nil {
+		return fmt.Errorf("bad schema: %v", err)
+	}
+	var norm map[string]interface{}
+	json.Unmarshal(b, &norm)
+	return validateJSON(norm, v, "$")
+}
+
+func validateJSON(schema map[string]interface{}, v interface{}, path string) error {
+	if t, ok := schema["type"].(string); ok && !hasJSONType(v, t) {
+		return fmt.Errorf("%s: expected %s", path, t)
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if fmt.Sprint(e) == fmt.Sprint(v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: %v is not one of %v", path, v, enum)
+		}
+	}
+	switch x := v.(type) {
+	case map[string]interface{}:
+		required, _ := schema["required"].([]interface{})
+		for _, r := range required {
+			name, _ := r.(string)
+			if _, ok := x[name]; !ok {
+				return fmt.Errorf("%s: missing required property %q", path, name)
+			}
+		}
+		props, _ := schema["properties"].(map[string]interface{})
+		for name, val := range x {
+			if sub, ok := props[name].(map[string]interface{}); ok {
+				if err := validateJSON(sub, val, path+"."+name); err != nil {
+					return err
+				}
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, val := range x {
+				if err := validateJSON(items, val, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func hasJSONType(v interface{}, t string) bool {
+	switch t {
+	case "object":
+		_, ok := v.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := v.([]interface{})
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f)
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "null":
+		return v == nil
+	}
+	return true
+}
@@ -0,0 +1,85 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// replyQueue returns its replies in order.
+type replyQueue struct {
+	replies []string
+	seen    [][]Message
+	params  []GenerationParams
+}
+
+func (s *replyQueue) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string) (LLMResponse, error) {
+	s.seen = append(s.seen, messages)
+	s.params = append(s.params, ParamsFrom(ctx))
+	r := s.replies[0]
+	s.replies = s.replies[1:]
+	return LLMResponse{Content: r}, nil
+}
+
+func (s *replyQueue) GetDefaultModel() string { return "m" }
+
+var colorFormat = ResponseFormat{Name: "color", Schema: map[string]interface{}{
+	"type":     "object",
+	"required": []string{"color"},
+	"properties": map[string]interface{}{
+		"color": map[string]interface{}{"type": "string", "enum": []string{"red", "blue"}},
+		"score": map[string]interface{}{"type": "integer"},
+	},
+}}
+
+func TestStructuredAskRetriesInvalidReplies(t *testing.T) {
+	p := &replyQueue{replies: []string{"sure!", `{"color": "green"}`, "```json\n{\"color\": \"red\", \"score\": 3}\n```"}}
+	var out struct {
+		Color string `json:"color"`
+		Score int    `json:"score"`
+	}
+	if _, err := StructuredAsk(context.Background(), p, []Message{{Role: "user", Content: "pick"}}, "m", colorFormat, 2, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Color != "red" || out.Score != 3 {
+		t.Fatalf("unexpected result: %+v", out)
+	}
+	if len(p.seen) != 3 || p.params[0].ResponseFormat == nil || p.params[0].ResponseFormat.Name != "color" {
+		t.Fatalf("expected 3 JSON mode requests, got %d", len(p.seen))
+	}
+	last := p.seen[2][len(p.seen[2])-1].Content
+	if !strings.Contains(last, "green is not one of") {
+		t.Fatalf("expected the validation error to be sent back, got %q", last)
+	}
+
+	p = &replyQueue{replies: []string{`{"score": 1.5}`}}
+	if _, err := StructuredAsk(context.Background(), p, nil, "m", colorFormat, 0, nil); !errors.Is(err, ErrInvalidStructuredReply) {
+		t.Fatalf("expected ErrInvalidStructuredReply, got %v", err)
+	}
+}
+
+func TestResponseFormatPerProvider(t *testing.T) {
+	ctx := WithParams(context.Background(), GenerationParams{ResponseFormat: &colorFormat})
+	msgs := []Message{{Role: "user", Content: "pick"}}
+
+	req := NewOpenAIProvider("k", "", 60, 4096).request(ctx, msgs, nil, "gpt-4o")
+	if rf := req.ResponseFormat; rf == nil || rf.Type != "json_schema" || rf.JSONSchema.Name != "color" {
+		t.Fatalf("unexpected OpenAI response_format: %+v", rf)
+	}
+	req = NewOpenAIProvider("k", "", 60, 4096).request(WithParams(context.Background(), GenerationParams{ResponseFormat: &ResponseFormat{}}), msgs, nil, "gpt-4o")
+	if rf := req.ResponseFormat; rf == nil || rf.Type != "json_object" || rf.JSONSchema != nil {
+		t.Fatalf("unexpected OpenAI response_format: %+v", rf)
+	}
+
+	ap := NewAnthropicProvider("k", "", 60, 4096)
+	ap.Params = GenerationParams{ThinkingBudget: Int(2000)}
+	areq := ap.request(ctx, msgs, nil, "claude-sonnet-4-5")
+	if areq.ToolChoice == nil || areq.ToolChoice.Name != "color" || len(areq.Tools) != 1 || areq.Thinking != nil {
+		t.Fatalf("expected a forced tool call without thinking, got %+v", areq)
+	}
+	res := ap.structuredReply(ctx, anthropicLLMResponse([]anthropicBlock{{Type: "tool_use", ID: "t", Name: "color", Input: []byte(`{"color":"blue"}`)}}))
+	if res.HasToolCalls || res.Content != `{"color":"blue"}` {
+		t.Fatalf("expected the tool input as content, got %+v", res)
+	}
+}
@@ -0,0 +1,95 @@
+package providers
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+)
+
+// EstimateTokens approximates the number of tokens in s without a
+// tokenizer: about four characters per token for ASCII text, and one token
+// per character for other scripts, which BPE vocabularies split finely.
+// It errs on the high side.
+func EstimateTokens(s string) int {
+	ascii, other := 0, 0
+	for i := 0; i < len(s); {
+		if s[i] < utf8.RuneSelf {
+			ascii++
+			i++
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		other++
+		i += size
+	}
+	return (ascii+3)/4 + other
+}
+
+// messageOverhead is the per-message cost of roles and separators.
+const messageOverhead = 4
+
+// EstimateMessageTokens approximates the prompt size of messages.
+func EstimateMessageTokens(messages []Message) int {
+	n := 0
+	for _, m := range messages {
+		n += messageOverhead + EstimateTokens(m.Content)
+		for _, tc := range m.ToolCalls {
+			args, _ := json.Marshal(tc.Arguments)
+			n += EstimateTokens(tc.Name) + EstimateTokens(string(args))
+		}
+		n += 1000 * len(m.Images) // a typical image, after provider downscaling
+	}
+	return n
+}
+
+// EstimateToolTokens approximates the prompt size of tool definitions.
+func EstimateToolTokens(tools []ToolDefinition) int {
+	if len(tools) == 0 {
+		return 0
+	}
+	b, _ := json.Marshal(tools)
+	return EstimateTokens(string(b))
+}
+
+// contextWindows lists context sizes by model name prefix; longer prefixes
+// come first so "gpt-4o" is not matched as "gpt-4".
+var contextWindows = []struct {
+	prefix string
+	tokens int
+}{
+	{"gpt-4.1", 1_047_576},
+	{"gpt-4o", 128_000},
+	{"gpt-4-turbo", 128_000},
+	{"gpt-4", 8_192},
+	{"gpt-5", 400_000},
+	{"gpt-3.5", 16_385},
+	{"o1", 200_000},
+	{"o3", 200_000},
+	{"o4", 200_000},
+	{"claude", 200_000},
+	{"gemini", 1_048_576},
+	{"grok", 131_072},
+	{"llama-3", 128_000},
+	{"llama3", 128_000},
+	{"codestral", 256_000},
+	{"devstral", 128_000},
+	{"mistral-", 128_000},
+	{"magistral", 40_000},
+	{"deepseek", 64_000},
+	{"qwen", 32_768},
+}
+
+// DefaultContextWindow is assumed for models ContextWindow doesn't know.
+const DefaultContextWindow = 32_768
+
+// ContextWindow returns the context size in tokens of model, looked up by
+// name without any "vendor/" prefix (OpenRouter, Groq).
+func ContextWindow(model string) int {
+	name := strings.ToLower(model[strings.LastIndex(model, "/")+1:])
+	for _, w := range contextWindows {
+		if strings.HasPrefix(name, w.prefix) {
+			return w.tokens
+		}
+	}
+	return DefaultContextWindow
+}
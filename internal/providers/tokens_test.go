@@ -0,0 +1,31 @@
+package providers
+
+import "testing"
+
+func TestContextWindow(t *testing.T) {
+	cases := map[string]int{
+		"gpt-4o-mini":                 128_000,
+		"gpt-4":                       8_192,
+		"gpt-4.1":                     1_047_576,
+		"anthropic/claude-sonnet-4-5": 200_000,
+		"google/gemini-2.5-pro":       1_048_576,
+		"some-local-model":            DefaultContextWindow,
+	}
+	for model, want := range cases {
+		if got := ContextWindow(model); got != want {
+			t.Errorf("ContextWindow(%q) = %d, want %d", model, got, want)
+		}
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	if got := EstimateTokens("hello world!"); got != 3 {
+		t.Errorf("ascii: got %d, want 3", got)
+	}
+	if got := EstimateTokens("日本語"); got != 3 {
+		t.Errorf("cjk: got %d, want 3", got)
+	}
+	if got := EstimateTokens(""); got != 0 {
+		t.Errorf("empty: got %d, want 0", got)
+	}
+}
@@ -0,0 +1,237 @@
+// Package redact scrubs secrets from text before it leaves the process:
+// tool results, provider prompts and responses, logs and channel messages.
+package redact
+
+import (
+	"io"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Placeholder replaces every redacted value.
+const Placeholder = "[REDACTED]"
+
+// minSecretLen avoids redacting short values (e.g. "test") that would mangle
+// ordinary text.
+const minSecretLen = 8
+
+// credentialPatterns match common credential formats even when they are not
+// configured secrets. Patterns with a capture group keep the first group
+// (e.g. the "api_key=" prefix) and redact the rest.
+var credentialPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+	regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}`),          // OpenAI / Anthropic / OpenRouter
+	regexp.MustCompile(`\b\d{8,10}:[A-Za-z0-9_-]{35}\b`),   // Telegram bot token
+	regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`),   // GitHub
+	regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{60,}\b`), // GitHub fine-grained
+	regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`),   // Slack
+	regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),    // AWS access key ID
+	regexp.MustCompile(`(?i)(\baws_?secret_?access_?key["']?\s*[:=]\s*["']?)[A-Za-z0-9/+=]{40}`),
+	regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`),                // Google API key
+	regexp.MustCompile(`(?i)(\bbearer\s+)[A-Za-z0-9._~+/=-]{16,}`), // Authorization headers
+	regexp.MustCompile(`(?i)(\b(?:api[_-]?key|secret|password|passwd|access[_-]?token|auth[_-]?token)["']?\s*[:=]\s*["']?)[^\s"',;]{8,}`),
+}
+
+// Rule is a user-defined redaction: matches of Pattern are replaced with
+// Replace, in which $1 and the like refer to Pattern's groups.
+type Rule struct {
+	Pattern  *regexp.Regexp
+	Replace  string   // default Placeholder
+	Channels []string // only on these channels' messages; default everywhere
+}
+
+// Redactor replaces known secrets and credential-like strings with
+// Placeholder. A nil *Redactor is valid and returns text unchanged.
+type Redactor struct {
+	mu      sync.RWMutex
+	secrets []string // sorted longest first so overlapping secrets redact fully
+	rules   []Rule
+}
+
+// New creates a Redactor that knows the given secrets.
+func New(secrets ...string) *Redactor {
+	r := &Redactor{}
+	r.AddSecrets(secrets...)
+	return r
+}
+
+// AddSecrets registers additional literal secrets. Empty and very short
+// values are ignored.
+func (r *Redactor) AddSecrets(secrets ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range secrets {
+		s = strings.TrimSpace(s)
+		if len(s) < minSecretLen {
+			continue
+		}
+		r.secrets = append(r.secrets, s)
+	}
+	sort.Slice(r.secrets, func(i, j int) bool { return len(r.secrets[i]) > len(r.secrets[j]) })
+}
+
+// AddRules registers user-defined redactions, applied after the built-in
+// ones.
+func (r *Redactor) AddRules(rules ...Rule) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rule := range rules {
+		if rule.Replace == "" {
+			rule.Replace = Placeholder
+		}
+		r.rules = append(r.rules, rule)
+	}
+}
+
+// Redact returns s with secrets, credential patterns and the matches of
+// rules for every channel replaced.
+func (r *Redactor) Redact(s string) string {
+	return r.redact("", s)
+}
+
+// FilterOutbound redacts text sent to channel, including the matches of the
+// rules for that channel, so a Redactor can be added to a chat.Hub.
+func (r *Redactor) FilterOutbound(channel, chatID, text string) string {
+	return r.redact(channel, text)
+}
+
+// redact applies everything that is in force on channel ("" for text that
+// goes nowhere in particular, such as logs).
+func (r *Redactor) redact(channel, s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, Placeholder)
+	}
+	for _, re := range credentialPatterns {
+		if re.NumSubexp() > 0 {
+			s = re.ReplaceAllString(s, "${1}"+Placeholder)
+		} else {
+			s = re.ReplaceAllString(s, Placeholder)
+		}
+	}
+	for _, rule := range r.rules {
+		if len(rule.Channels) == 0 || (channel != "" && slices.Contains(rule.Channels, channel)) {
+			s = rule.Pattern.ReplaceAllString(s, rule.Replace)
+		}
+	}
+	return s
+}
+
+// Writer wraps w so everything written through it is redacted. It is meant
+// for line-oriented output such as the standard logger, which writes each
+// entry in a single call.
+func (r *Redactor) Writer(w io.Writer) io.Writer {
+	return &writer{r: r, w: w}
+}
+
+type writer struct {
+	r *Redactor
+	w io.Writer
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.w, w.r.Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// streamHold is how much text a Stream holds back at least, so a credential
+// pattern that is still arriving is matched whole. Configured secrets longer
+// than this raise it to their length.
+const streamHold = 128
+
+// Stream redacts text that arrives in pieces, such as a streamed reply, so a
+// secret split across two pieces is still caught. It holds back the tail that
+// could be the start of a secret and passes the rest to emit. A Stream is not
+// safe for concurrent use.
+type Stream struct {
+	r    *Redactor
+	emit func(string)
+	buf  string
+}
+
+// Stream returns a Stream that passes redacted text to emit. Call Flush when
+// the text is complete.
+func (r *Redactor) Stream(emit func(string)) *Stream {
+	return &Stream{r: r, emit: emit}
+}
+
+// Write adds delta to the text and emits what can no longer be part of a
+// secret.
+func (s *Stream) Write(delta string) {
+	if s.r == nil {
+		s.emit(delta)
+		return
+	}
+	s.buf += delta
+	split := s.r.safeSplit(s.buf)
+	if split <= 0 {
+		return
+	}
+	s.emit(s.r.Redact(s.buf[:split]))
+	s.buf = s.buf[split:]
+}
+
+// Flush emits the text held back.
+func (s *Stream) Flush() {
+	if s.buf != "" {
+		s.emit(s.r.Redact(s.buf))
+		s.buf = ""
+	}
+}
+
+// safeSplit returns how much of s can be redacted on its own: everything but
+// the last streamHold bytes (or the length of the longest secret), moved back
+// to the start of any match that would be cut and of a private key block that
+// hasn't ended yet.
+func (r *Redactor) safeSplit(s string) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	hold := streamHold
+	if len(r.secrets) > 0 && len(r.secrets[0]) > hold {
+		hold = len(r.secrets[0])
+	}
+	split := len(s) - hold
+	if split <= 0 {
+		return 0
+	}
+	if i := strings.LastIndex(s, "-----BEGIN "); i >= 0 && i < split && !strings.Contains(s[i:], "-----END ") {
+		split = i
+	}
+	var spans [][]int
+	for _, secret := range r.secrets {
+		for off := 0; ; {
+			i := strings.Index(s[off:], secret)
+			if i < 0 {
+				break
+			}
+			spans = append(spans, []int{off + i, off + i + len(secret)})
+			off += i + 1
+		}
+	}
+	for _, re := range credentialPatterns {
+		spans = append(spans, re.FindAllStringIndex(s, -1)...)
+	}
+	for _, rule := range r.rules {
+		if len(rule.Channels) == 0 {
+			spans = append(spans, rule.Pattern.FindAllStringIndex(s, -1)...)
+		}
+	}
+	for moved := true; moved; {
+		moved = false
+		for _, sp := range spans {
+			if sp[0] < split && sp[1] > split {
+				split, moved = sp[0], true
+			}
+		}
+	}
+	return split
+}
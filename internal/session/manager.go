@@ -1,10 +1,14 @@
 package session
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 )
 
 // MaxHistorySize is the maximum number of messages kept in a session.
@@ -13,13 +17,39 @@ import (
 // Important information should be persisted via write_memory, not session history.
 const MaxHistorySize = 50
 
-// Session holds a short chat history.
+// Message is one message of a conversation: a user message, an assistant
+// reply or tool call, or the result of a tool call.
+type Message struct {
+	Role       string     `json:"role"` // "user", "assistant" or "tool"
+	Content    string     `json:"content"`
+	ToolCalls  []ToolCall `json:"toolCalls,omitempty"`  // calls made by an assistant message
+	ToolCallID string     `json:"toolCallID,omitempty"` // the call a tool message answers
+	Time       time.Time  `json:"time,omitempty"`
+}
+
+// ToolCall is a tool call of an assistant message.
+type ToolCall struct {
+	ID               string                 `json:"id"`
+	Name             string                 `json:"name"`
+	Arguments        map[string]interface{} `json:"arguments,omitempty"`
+	ThoughtSignature string                 `json:"thoughtSignature,omitempty"` // Gemini
+}
+
+// Session holds the recent history of one chat.
 type Session struct {
 	Key     string
-	History []string
+	History []Message
 }
 
-// SessionManager stores sessions in memory and persists to disk under workspace.
+// Key returns the session key of a chat, e.g. "telegram:123".
+func Key(channel, chatID string) string {
+	return channel + ":" + chatID
+}
+
+// SessionManager keeps sessions in memory and persists each one as a JSONL
+// file (one message per line) under <workspace>/sessions/. Sessions are
+// loaded from disk the first time a chat is seen, so history survives
+// restarts.
 type SessionManager struct {
 	mu        sync.RWMutex
 	sessions  map[string]*Session
@@ -30,70 +60,126 @@ func NewSessionManager(workspace string) *SessionManager {
 	return &SessionManager{sessions: make(map[string]*Session), workspace: workspace}
 }
 
-func (sm *SessionManager) GetOrCreate(key string) *Session {
+// GetOrCreate returns the session of a chat, restoring it from disk if it
+// is not in memory yet.
+func (sm *SessionManager) GetOrCreate(channel, chatID string) *Session {
+	key := Key(channel, chatID)
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 	if s, ok := sm.sessions[key]; ok {
 		return s
 	}
-	s := &Session{Key: key, History: make([]string, 0)}
+	s := &Session{Key: key, History: sm.load(key)}
 	sm.sessions[key] = s
 	return s
 }
 
+// Save trims the session and writes it to disk, replacing the previous file.
 func (sm *SessionManager) Save(s *Session) error {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 	// Trim history to the most recent messages
 	s.trim()
-	path := filepath.Join(sm.workspace, "sessions")
-	os.MkdirAll(path, 0755)
-	fpath := filepath.Join(path, s.Key+".json")
-	b, err := json.MarshalIndent(s, "", "  ")
-	if err != nil {
+	dir := filepath.Join(sm.workspace, "sessions")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, m := range s.History {
+		if err := enc.Encode(m); err != nil {
+			return err
+		}
+	}
+	// write to a temp file and rename so a crash never leaves a torn file
+	fpath := filepath.Join(dir, sessionFileName(s.Key))
+	tmp := fpath + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
 		return err
 	}
-	return os.WriteFile(fpath, b, 0644)
+	return os.Rename(tmp, fpath)
 }
 
-func (sm *SessionManager) LoadAll() error {
-	sm.mu.Lock()
-	defer sm.mu.Unlock()
-	path := filepath.Join(sm.workspace, "sessions")
-	_ = os.MkdirAll(path, 0755)
-	entries, err := os.ReadDir(path)
+// load reads a session's history from disk. Unreadable lines are skipped;
+// a missing file yields an empty history.
+func (sm *SessionManager) load(key string) []Message {
+	history := make([]Message, 0)
+	f, err := os.Open(filepath.Join(sm.workspace, "sessions", sessionFileName(key)))
 	if err != nil {
-		return err
+		return sm.loadLegacy(key)
 	}
-	for _, e := range entries {
-		if e.IsDir() {
-			continue
-		}
-		b, err := os.ReadFile(filepath.Join(path, e.Name()))
-		if err != nil {
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 16<<20)
+	for sc.Scan() {
+		var m Message
+		if err := json.Unmarshal(sc.Bytes(), &m); err != nil || m.Role == "" {
 			continue
 		}
-		var s Session
-		if err := json.Unmarshal(b, &s); err != nil {
-			continue
+		history = append(history, m)
+	}
+	return history
+}
+
+// loadLegacy reads the single-JSON session files written by older versions,
+// whose history entries were "role: content" strings. The file is rewritten
+// as JSONL on the next Save.
+func (sm *SessionManager) loadLegacy(key string) []Message {
+	history := make([]Message, 0)
+	name := strings.TrimSuffix(sessionFileName(key), ".jsonl") + ".json"
+	b, err := os.ReadFile(filepath.Join(sm.workspace, "sessions", name))
+	if err != nil {
+		return history
+	}
+	var old struct{ History []string }
+	if json.Unmarshal(b, &old) != nil {
+		return history
+	}
+	for _, h := range old.History {
+		role, content, ok := strings.Cut(h, ": ")
+		if !ok || (role != "user" && role != "assistant") {
+			role, content = "user", h
 		}
-		sm.sessions[s.Key] = &s
+		history = append(history, Message{Role: role, Content: content})
 	}
-	return nil
+	return history
+}
+
+// sessionFileName maps a session key such as "telegram:123" to a file name
+// that is valid on every platform (":" is reserved on Windows).
+func sessionFileName(key string) string {
+	return strings.NewReplacer(":", "_", "/", "_", `\`, "_").Replace(key) + ".jsonl"
 }
 
 func (s *Session) AddMessage(role, content string) {
-	s.History = append(s.History, role+": "+content)
+	s.Add(Message{Role: role, Content: content})
+}
+
+// Add appends m, timestamped now if it has no time.
+func (s *Session) Add(m Message) {
+	if m.Time.IsZero() {
+		m.Time = time.Now()
+	}
+	s.History = append(s.History, m)
+}
+
+// Clear drops the session history.
+func (s *Session) Clear() {
+	s.History = make([]Message, 0)
 }
 
 // GetHistory returns the session history.
-func (s *Session) GetHistory() []string {
+func (s *Session) GetHistory() []Message {
 	return s.History
 }
 
-// trim keeps only the last MaxHistorySize messages, discarding the oldest.
+// trim keeps only the last MaxHistorySize messages, discarding the oldest,
+// and then drops tool calls and replies left without their user message.
 func (s *Session) trim() {
 	if len(s.History) > MaxHistorySize {
 		s.History = s.History[len(s.History)-MaxHistorySize:]
+		for len(s.History) > 0 && s.History[0].Role != "user" {
+			s.History = s.History[1:]
+		}
 	}
 }
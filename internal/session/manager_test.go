@@ -0,0 +1,94 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSessionPersistsAcrossRestart(t *testing.T) {
+	ws := t.TempDir()
+	sm := NewSessionManager(ws)
+	s := sm.GetOrCreate("telegram", "123")
+	s.AddMessage("user", "hi\nthere")
+	s.AddMessage("assistant", "hello")
+	if err := sm.Save(s); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(ws, "sessions", "telegram_123.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "\n"); n != 2 {
+		t.Fatalf("expected 2 JSONL lines, got %d: %s", n, data)
+	}
+
+	// a new manager, as after a restart, restores the history
+	got := NewSessionManager(ws).GetOrCreate("telegram", "123").GetHistory()
+	if len(got) != 2 || got[0].Role != "user" || got[0].Content != "hi\nthere" || got[1].Role != "assistant" {
+		t.Fatalf("unexpected restored history: %+v", got)
+	}
+	if other := NewSessionManager(ws).GetOrCreate("discord", "123").GetHistory(); len(other) != 0 {
+		t.Fatalf("sessions of different channels must be separate, got %+v", other)
+	}
+}
+
+func TestSessionKeepsToolCalls(t *testing.T) {
+	ws := t.TempDir()
+	sm := NewSessionManager(ws)
+	s := sm.GetOrCreate("telegram", "1")
+	s.AddMessage("user", "list files")
+	s.Add(Message{Role: "assistant", ToolCalls: []ToolCall{{ID: "c1", Name: "filesystem", Arguments: map[string]interface{}{"action": "list"}}}})
+	s.Add(Message{Role: "tool", Content: "a.txt", ToolCallID: "c1"})
+	s.AddMessage("assistant", "There is a.txt.")
+	for i := 0; i < MaxHistorySize-3; i++ {
+		s.AddMessage("user", "m")
+	}
+	if err := sm.Save(s); err != nil {
+		t.Fatal(err)
+	}
+	got := NewSessionManager(ws).GetOrCreate("telegram", "1").GetHistory()
+	// trimming the first message leaves no tool call without its request
+	if len(got) != MaxHistorySize-3 || got[0].Role != "user" || got[0].Content != "m" {
+		t.Fatalf("unexpected trimmed history: %d messages, first %+v", len(got), got[0])
+	}
+
+	s = sm.GetOrCreate("telegram", "2")
+	s.AddMessage("user", "list files")
+	s.Add(Message{Role: "assistant", ToolCalls: []ToolCall{{ID: "c1", Name: "filesystem", Arguments: map[string]interface{}{"action": "list"}}}})
+	s.Add(Message{Role: "tool", Content: "a.txt", ToolCallID: "c1"})
+	if err := sm.Save(s); err != nil {
+		t.Fatal(err)
+	}
+	got = NewSessionManager(ws).GetOrCreate("telegram", "2").GetHistory()
+	if len(got) != 3 || got[1].ToolCalls[0].Name != "filesystem" || got[1].ToolCalls[0].Arguments["action"] != "list" || got[2].ToolCallID != "c1" {
+		t.Fatalf("tool calls not restored: %+v", got)
+	}
+}
+
+func TestSessionTrimmedOnSave(t *testing.T) {
+	sm := NewSessionManager(t.TempDir())
+	s := sm.GetOrCreate("http", "x")
+	for i := 0; i < MaxHistorySize+10; i++ {
+		s.AddMessage("user", "m")
+	}
+	if err := sm.Save(s); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(s.GetHistory()); n != MaxHistorySize {
+		t.Fatalf("expected %d messages after trim, got %d", MaxHistorySize, n)
+	}
+}
+
+func TestSessionLoadsLegacyJSON(t *testing.T) {
+	ws := t.TempDir()
+	os.MkdirAll(filepath.Join(ws, "sessions"), 0755)
+	legacy := `{"Key":"telegram:1","History":["user: hi","assistant: hello: world"]}`
+	os.WriteFile(filepath.Join(ws, "sessions", "telegram_1.json"), []byte(legacy), 0644)
+
+	got := NewSessionManager(ws).GetOrCreate("telegram", "1").GetHistory()
+	if len(got) != 2 || got[0].Content != "hi" || got[1].Role != "assistant" || got[1].Content != "hello: world" {
+		t.Fatalf("unexpected legacy history: %+v", got)
+	}
+}
@@ -0,0 +1,252 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kr0nicas/picobot/internal/logging"
+)
+
+var telemetryLog = logging.For("telemetry")
+
+// logExporter writes each finished span to the process log.
+type logExporter struct{}
+
+func (logExporter) export(s *Span) {
+	kv := []any{"span", s.Name, "trace_id", s.TraceID.String(), "duration_ms", s.Duration().Milliseconds()}
+	s.mu.Lock()
+	for _, a := range s.attrs {
+		kv = append(kv, a.key, a.value)
+	}
+	if s.errMsg != "" {
+		kv = append(kv, "err", s.errMsg)
+	}
+	s.mu.Unlock()
+	telemetryLog.Info("span ended", kv...)
+}
+
+func (logExporter) shutdown(context.Context) error { return nil }
+
+// Batching of the OTLP exporter.
+const (
+	exportInterval = 5 * time.Second
+	exportBatch    = 256  // spans that trigger an export before the interval
+	maxQueued      = 4096 // spans beyond this are dropped while the collector is unreachable
+)
+
+// otlpExporter sends spans in batches to an OTLP/HTTP collector, JSON
+// encoded.
+type otlpExporter struct {
+	url     string
+	headers map[string]string
+	service string
+	client  *http.Client
+
+	mu      sync.Mutex
+	queue   []*Span
+	dropped int
+	kick    chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+func newOTLPExporter(opts Options) (*otlpExporter, error) {
+	u := strings.TrimRight(opts.Endpoint, "/")
+	if !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+		return nil, fmt.Errorf("telemetry: endpoint %q must start with http:// or https://", opts.Endpoint)
+	}
+	if !strings.HasSuffix(u, "/v1/traces") {
+		u += "/v1/traces"
+	}
+	e := &otlpExporter{url: u, headers: opts.Headers, service: opts.ServiceName, client: &http.Client{Timeout: 10 * time.Second},
+		kick: make(chan struct{}, 1), stop: make(chan struct{}), done: make(chan struct{})}
+	go e.run()
+	return e, nil
+}
+
+func (e *otlpExporter) export(s *Span) {
+	e.mu.Lock()
+	if len(e.queue) >= maxQueued {
+		e.dropped++
+	} else {
+		e.queue = append(e.queue, s)
+	}
+	full := len(e.queue) >= exportBatch
+	e.mu.Unlock()
+	if full {
+		select {
+		case e.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (e *otlpExporter) run() {
+	defer close(e.done)
+	tick := time.NewTicker(exportInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+		case <-e.kick:
+		case <-e.stop:
+			return
+		}
+		if err := e.flush(context.Background()); err != nil {
+			telemetryLog.Warn("exporting spans failed", "endpoint", e.url, "err", err)
+		}
+	}
+}
+
+// flush sends the queued spans. Spans of a failed export are put back for
+// the next attempt.
+func (e *otlpExporter) flush(ctx context.Context) error {
+	e.mu.Lock()
+	batch, dropped := e.queue, e.dropped
+	e.queue, e.dropped = nil, 0
+	e.mu.Unlock()
+	if dropped > 0 {
+		telemetryLog.Warn("dropped spans while the collector was unreachable", "spans", dropped)
+	}
+	if len(batch) == 0 {
+		return nil
+	}
+	if err := e.send(ctx, batch); err != nil {
+		e.mu.Lock()
+		e.queue = append(batch, e.queue...)
+		if n := len(e.queue) - maxQueued; n > 0 {
+			e.queue, e.dropped = e.queue[n:], e.dropped+n
+		}
+		e.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (e *otlpExporter) send(ctx context.Context, spans []*Span) error {
+	body, err := json.Marshal(encodeOTLP(e.service, spans))
+	if err != nil {
+		return fmt.Errorf("telemetry: encode spans: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("telemetry: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("telemetry: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("telemetry: collector answered %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// shutdown stops the background exports and sends what is left.
+func (e *otlpExporter) shutdown(ctx context.Context) error {
+	close(e.stop)
+	<-e.done
+	return e.flush(ctx)
+}
+
+// The OTLP/JSON encoding of spans (opentelemetry-proto, trace/v1).
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttr `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID      string      `json:"traceId"`
+		SpanID       string      `json:"spanId"`
+		ParentSpanID string      `json:"parentSpanId,omitempty"`
+		Name         string      `json:"name"`
+		Kind         int         `json:"kind"`
+		Start        string      `json:"startTimeUnixNano"`
+		End          string      `json:"endTimeUnixNano"`
+		Attributes   []otlpAttr  `json:"attributes,omitempty"`
+		Status       *otlpStatus `json:"status,omitempty"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"` // 2 = error
+		Message string `json:"message,omitempty"`
+	}
+	otlpAttr struct {
+		Key   string         `json:"key"`
+		Value map[string]any `json:"value"`
+	}
+)
+
+// spanKindInternal is SPAN_KIND_INTERNAL.
+const spanKindInternal = 1
+
+func encodeOTLP(service string, spans []*Span) otlpRequest {
+	out := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		s.mu.Lock()
+		sp := otlpSpan{TraceID: s.TraceID.String(), SpanID: s.ID.String(), Name: s.Name, Kind: spanKindInternal,
+			Start: strconv.FormatInt(s.Start.UnixNano(), 10), End: strconv.FormatInt(s.end.UnixNano(), 10)}
+		if s.Parent != (SpanID{}) {
+			sp.ParentSpanID = s.Parent.String()
+		}
+		for _, a := range s.attrs {
+			sp.Attributes = append(sp.Attributes, otlpAttribute(a.key, a.value))
+		}
+		if s.errMsg != "" {
+			sp.Status = &otlpStatus{Code: 2, Message: s.errMsg}
+		}
+		s.mu.Unlock()
+		out = append(out, sp)
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: []otlpAttr{otlpAttribute("service.name", service)}},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "picobot"}, Spans: out}},
+	}}}
+}
+
+func otlpAttribute(key string, v any) otlpAttr {
+	var val map[string]any
+	switch v := v.(type) {
+	case string:
+		val = map[string]any{"stringValue": v}
+	case bool:
+		val = map[string]any{"boolValue": v}
+	case int:
+		val = map[string]any{"intValue": strconv.Itoa(v)}
+	case int64:
+		val = map[string]any{"intValue": strconv.FormatInt(v, 10)}
+	case float64:
+		val = map[string]any{"doubleValue": v}
+	case time.Duration:
+		val = map[string]any{"intValue": strconv.FormatInt(v.Milliseconds(), 10)}
+	default:
+		val = map[string]any{"stringValue": fmt.Sprint(v)}
+	}
+	return otlpAttr{Key: key, Value: val}
+}
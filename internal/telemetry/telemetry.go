@@ -0,0 +1,243 @@
+// Package telemetry records OpenTelemetry-compatible trace spans of a
+// request's lifecycle (inbound message, context build, provider calls, tool
+// runs, outbound send) and exports them to an OTLP/HTTP collector or to the
+// process log. Until Setup enables it, spans are nil and cost nothing.
+package telemetry
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Options configures tracing.
+type Options struct {
+	// Endpoint is the OTLP/HTTP collector, e.g. http://localhost:4318;
+	// spans are sent to <Endpoint>/v1/traces. Empty logs each finished
+	// span instead.
+	Endpoint    string
+	Headers     map[string]string // sent with every export, e.g. an API key
+	ServiceName string            // default "picobot"
+}
+
+// exporter receives the spans that ended.
+type exporter interface {
+	export(s *Span)
+	shutdown(ctx context.Context) error
+}
+
+var active atomic.Pointer[exporter]
+
+// Setup enables tracing. The returned function flushes the spans not yet
+// exported and disables tracing again.
+func Setup(opts Options) (func(context.Context) error, error) {
+	if opts.ServiceName == "" {
+		opts.ServiceName = "picobot"
+	}
+	var e exporter
+	if opts.Endpoint == "" {
+		e = logExporter{}
+	} else {
+		oe, err := newOTLPExporter(opts)
+		if err != nil {
+			return nil, err
+		}
+		e = oe
+	}
+	active.Store(&e)
+	return func(ctx context.Context) error {
+		active.CompareAndSwap(&e, nil)
+		return e.shutdown(ctx)
+	}, nil
+}
+
+// Enabled reports whether spans are recorded.
+func Enabled() bool { return active.Load() != nil }
+
+// TraceID and SpanID identify spans as in W3C Trace Context.
+type (
+	TraceID [16]byte
+	SpanID  [8]byte
+)
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+func (s SpanID) String() string  { return hex.EncodeToString(s[:]) }
+
+// Span is a timed operation of a trace. A nil *Span is valid and records
+// nothing, so callers need not check whether tracing is on.
+type Span struct {
+	Name    string
+	TraceID TraceID
+	ID      SpanID
+	Parent  SpanID // zero for the root span
+	Start   time.Time
+
+	mu     sync.Mutex
+	end    time.Time
+	attrs  []attr
+	errMsg string
+}
+
+type attr struct {
+	key   string
+	value any
+}
+
+type ctxKey struct{}
+
+// FromContext returns the span ctx carries, or nil.
+func FromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(ctxKey{}).(*Span)
+	return s
+}
+
+// Start begins a span, a child of the one in ctx if any, with attributes
+// given as key-value pairs ("tool", "exec"). The returned context carries
+// the span for the operations it contains.
+func Start(ctx context.Context, name string, kv ...any) (context.Context, *Span) {
+	if !Enabled() {
+		return ctx, nil
+	}
+	s := newSpan(name, kv)
+	if parent := FromContext(ctx); parent != nil {
+		s.TraceID, s.Parent = parent.TraceID, parent.ID
+	} else {
+		rand.Read(s.TraceID[:])
+	}
+	return context.WithValue(ctx, ctxKey{}, s), s
+}
+
+func newSpan(name string, kv []any) *Span {
+	s := &Span{Name: name, Start: time.Now()}
+	rand.Read(s.ID[:])
+	s.SetAttributes(kv...)
+	return s
+}
+
+// SetAttributes adds key-value pairs to s, replacing earlier values of
+// the same keys.
+func (s *Span) SetAttributes(kv ...any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+next:
+	for i := 0; i+1 < len(kv); i += 2 {
+		key, ok := kv[i].(string)
+		if !ok {
+			key = fmt.Sprint(kv[i])
+		}
+		for j := range s.attrs {
+			if s.attrs[j].key == key {
+				s.attrs[j].value = kv[i+1]
+				continue next
+			}
+		}
+		s.attrs = append(s.attrs, attr{key, kv[i+1]})
+	}
+}
+
+// SetStart moves the start of s back, e.g. to when a message arrived
+// rather than when its processing began.
+func (s *Span) SetStart(t time.Time) {
+	if s == nil || t.IsZero() || t.After(s.Start) {
+		return
+	}
+	s.Start = t
+}
+
+// RecordError marks s as failed with err. A nil err is ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.errMsg = err.Error()
+	s.mu.Unlock()
+}
+
+// End finishes s and hands it to the exporter. Only the first call counts.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if !s.end.IsZero() {
+		s.mu.Unlock()
+		return
+	}
+	s.end = time.Now()
+	s.mu.Unlock()
+	if e := active.Load(); e != nil {
+		(*e).export(s)
+	}
+}
+
+// Duration returns how long s took, or has taken so far.
+func (s *Span) Duration() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.end.IsZero() {
+		return time.Since(s.Start)
+	}
+	return s.end.Sub(s.Start)
+}
+
+// MetadataKey is the key of the W3C traceparent in the metadata of chat
+// messages, so channel adapters can continue the trace of a reply.
+const MetadataKey = "traceparent"
+
+// Inject stores the traceparent of the span in ctx in md, allocating md if
+// needed, and returns md.
+func Inject(ctx context.Context, md map[string]interface{}) map[string]interface{} {
+	s := FromContext(ctx)
+	if s == nil {
+		return md
+	}
+	if md == nil {
+		md = make(map[string]interface{})
+	}
+	md[MetadataKey] = "00-" + s.TraceID.String() + "-" + s.ID.String() + "-01"
+	return md
+}
+
+// StartRemote begins a span that continues the trace whose traceparent md
+// holds, e.g. the send of a reply by a channel adapter. Without one, it
+// starts a new trace.
+func StartRemote(md map[string]interface{}, name string, kv ...any) (context.Context, *Span) {
+	ctx := context.Background()
+	if !Enabled() {
+		return ctx, nil
+	}
+	tp, _ := md[MetadataKey].(string)
+	traceID, parent, err := parseTraceparent(tp)
+	if err != nil {
+		return Start(ctx, name, kv...)
+	}
+	s := newSpan(name, kv)
+	s.TraceID, s.Parent = traceID, parent
+	return context.WithValue(ctx, ctxKey{}, s), s
+}
+
+func parseTraceparent(tp string) (TraceID, SpanID, error) {
+	var t TraceID
+	var s SpanID
+	parts := strings.Split(tp, "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return t, s, errors.New("telemetry: bad traceparent")
+	}
+	if _, err := hex.Decode(t[:], []byte(parts[1])); err != nil {
+		return t, s, err
+	}
+	if _, err := hex.Decode(s[:], []byte(parts[2])); err != nil {
+		return t, s, err
+	}
+	return t, s, nil
+}
@@ -0,0 +1,104 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// collector is an OTLP/HTTP endpoint that keeps the spans it receives.
+type collector struct {
+	mu     sync.Mutex
+	header http.Header
+	spans  []otlpSpan
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req otlpRequest
+	if r.URL.Path != "/v1/traces" || json.NewDecoder(r.Body).Decode(&req) != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.header = r.Header
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			c.spans = append(c.spans, ss.Spans...)
+		}
+	}
+}
+
+func TestSpansAreExported(t *testing.T) {
+	col := &collector{}
+	srv := httptest.NewServer(col)
+	defer srv.Close()
+	shutdown, err := Setup(Options{Endpoint: srv.URL, Headers: map[string]string{"X-Api-Key": "k"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, root := Start(context.Background(), "message", "channel", "telegram")
+	_, tool := Start(ctx, "tool exec", "tool", "exec")
+	tool.SetAttributes("outcome", "ok", "outcome", "error")
+	tool.RecordError(errors.New("exit status 1"))
+	tool.End()
+	md := Inject(ctx, nil)
+	root.End()
+	_, send := StartRemote(md, "send", "chat_id", "42")
+	send.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if Enabled() {
+		t.Fatal("tracing should stop after shutdown")
+	}
+
+	if col.header.Get("X-Api-Key") != "k" {
+		t.Fatalf("headers not sent: %v", col.header)
+	}
+	if len(col.spans) != 3 {
+		t.Fatalf("expected 3 spans, got %+v", col.spans)
+	}
+	byName := make(map[string]otlpSpan)
+	for _, s := range col.spans {
+		byName[s.Name] = s
+	}
+	r, te, se := byName["message"], byName["tool exec"], byName["send"]
+	if r.ParentSpanID != "" || te.ParentSpanID != r.SpanID || se.ParentSpanID != r.SpanID {
+		t.Fatalf("spans not linked to the root: %+v", col.spans)
+	}
+	if te.TraceID != r.TraceID || se.TraceID != r.TraceID || len(r.TraceID) != 32 {
+		t.Fatalf("spans not in one trace: %+v", col.spans)
+	}
+	if te.Status == nil || te.Status.Code != 2 || te.Status.Message != "exit status 1" {
+		t.Fatalf("error not recorded: %+v", te.Status)
+	}
+	if len(te.Attributes) != 2 || te.Attributes[1].Value["stringValue"] != "error" {
+		t.Fatalf("attributes should be set once with the latest value: %+v", te.Attributes)
+	}
+}
+
+func TestSpansAreNilWhenDisabled(t *testing.T) {
+	ctx, s := Start(context.Background(), "message")
+	if s != nil || FromContext(ctx) != nil {
+		t.Fatal("expected no span while tracing is off")
+	}
+	// a nil span must be usable
+	s.SetAttributes("k", "v")
+	s.RecordError(errors.New("x"))
+	s.End()
+	if md := Inject(ctx, nil); md != nil {
+		t.Fatalf("nothing should be injected, got %v", md)
+	}
+}
+
+func TestSetupRejectsBadEndpoint(t *testing.T) {
+	if _, err := Setup(Options{Endpoint: "localhost:4318"}); err == nil {
+		t.Fatal("expected an error for an endpoint without scheme")
+	}
+}
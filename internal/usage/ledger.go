@@ -0,0 +1,168 @@
+// Package usage records the tokens spent on every LLM request in a JSONL
+// ledger and keeps daily and monthly totals per model, with costs computed
+// from configured prices.
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Price is the cost of a model in USD per million tokens.
+type Price struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// Cost returns the cost in USD of a request.
+func (p Price) Cost(promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens)*p.Input + float64(completionTokens)*p.Output) / 1e6
+}
+
+// Entry is one ledger record.
+type Entry struct {
+	Time             time.Time `json:"time"`
+	Model            string    `json:"model"`
+	Task             string    `json:"task,omitempty"` // e.g. "chat", "ranking"
+	PromptTokens     int       `json:"promptTokens"`
+	CompletionTokens int       `json:"completionTokens"`
+	Cost             float64   `json:"cost,omitempty"` // USD; 0 when the model has no price
+}
+
+// Totals aggregates entries.
+type Totals struct {
+	Model            string
+	Requests         int
+	PromptTokens     int
+	CompletionTokens int
+	Cost             float64
+}
+
+func (t *Totals) add(e Entry) {
+	t.Requests++
+	t.PromptTokens += e.PromptTokens
+	t.CompletionTokens += e.CompletionTokens
+	t.Cost += e.Cost
+}
+
+// Ledger appends usage entries to a JSONL file and keeps running totals.
+// A nil *Ledger is valid and records nothing.
+type Ledger struct {
+	mu     sync.Mutex
+	f      *os.File
+	prices map[string]Price
+	totals map[string]map[string]*Totals // period ("2006-01-02" or "2006-01") -> model -> totals
+}
+
+// Open opens (or creates) the ledger at path and loads its totals. Models
+// are priced by exact name from prices.
+func Open(path string, prices map[string]Price) (*Ledger, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("usage: create dir: %w", err)
+	}
+	l := &Ledger{prices: prices, totals: make(map[string]map[string]*Totals)}
+	if rf, err := os.Open(path); err == nil {
+		sc := bufio.NewScanner(rf)
+		for sc.Scan() {
+			var e Entry
+			if json.Unmarshal(sc.Bytes(), &e) == nil {
+				l.add(e)
+			}
+		}
+		rf.Close()
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("usage: open ledger: %w", err)
+	}
+	l.f = f
+	return l, nil
+}
+
+// add counts e in its day and month. The caller holds l.mu.
+func (l *Ledger) add(e Entry) {
+	t := e.Time.Local()
+	for _, period := range []string{t.Format("2006-01-02"), t.Format("2006-01")} {
+		byModel := l.totals[period]
+		if byModel == nil {
+			byModel = make(map[string]*Totals)
+			l.totals[period] = byModel
+		}
+		tot := byModel[e.Model]
+		if tot == nil {
+			tot = &Totals{Model: e.Model}
+			byModel[e.Model] = tot
+		}
+		tot.add(e)
+	}
+}
+
+// Cost returns the cost in USD of a request to model, or 0 if the model has
+// no price.
+func (l *Ledger) Cost(model string, promptTokens, completionTokens int) float64 {
+	if l == nil {
+		return 0
+	}
+	return l.prices[model].Cost(promptTokens, completionTokens)
+}
+
+// Record appends a request's usage. Requests for which the provider
+// reported no tokens are skipped.
+func (l *Ledger) Record(model, task string, promptTokens, completionTokens int) error {
+	if l == nil || promptTokens+completionTokens == 0 {
+		return nil
+	}
+	e := Entry{Time: time.Now().UTC(), Model: model, Task: task, PromptTokens: promptTokens, CompletionTokens: completionTokens}
+	if p, ok := l.prices[model]; ok {
+		e.Cost = p.Cost(promptTokens, completionTokens)
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("usage: encode entry: %w", err)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.add(e)
+	if _, err := l.f.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("usage: write entry: %w", err)
+	}
+	return nil
+}
+
+// Day returns the totals per model for the day of t, sorted by model.
+func (l *Ledger) Day(t time.Time) []Totals {
+	return l.period(t.Local().Format("2006-01-02"))
+}
+
+// Month returns the totals per model for the month of t, sorted by model.
+func (l *Ledger) Month(t time.Time) []Totals {
+	return l.period(t.Local().Format("2006-01"))
+}
+
+func (l *Ledger) period(key string) []Totals {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make([]Totals, 0, len(l.totals[key]))
+	for _, t := range l.totals[key] {
+		out = append(out, *t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Model < out[j].Model })
+	return out
+}
+
+// Close closes the ledger file.
+func (l *Ledger) Close() error {
+	if l == nil || l.f == nil {
+		return nil
+	}
+	return l.f.Close()
+}
@@ -0,0 +1,43 @@
+package usage
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLedgerTotalsSurviveReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.jsonl")
+	prices := map[string]Price{"big": {Input: 3, Output: 15}}
+	l, err := Open(path, prices)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Record("big", "chat", 1000, 200)
+	l.Record("big", "chat", 1000, 0)
+	l.Record("small", "ranking", 500, 10)
+	l.Record("small", "ranking", 0, 0) // nothing reported: skipped
+	l.Close()
+
+	l, err = Open(path, prices)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	for _, totals := range [][]Totals{l.Day(time.Now()), l.Month(time.Now())} {
+		if len(totals) != 2 || totals[0].Model != "big" || totals[1].Model != "small" {
+			t.Fatalf("unexpected totals: %+v", totals)
+		}
+		big := totals[0]
+		if big.Requests != 2 || big.PromptTokens != 2000 || big.CompletionTokens != 200 || math.Abs(big.Cost-0.009) > 1e-9 {
+			t.Fatalf("unexpected totals for big: %+v", big)
+		}
+		if totals[1].Cost != 0 || totals[1].Requests != 1 {
+			t.Fatalf("unpriced model should count tokens only: %+v", totals[1])
+		}
+	}
+	if got := l.Day(time.Now().AddDate(0, 0, -40)); len(got) != 0 {
+		t.Fatalf("expected no usage on another day, got %+v", got)
+	}
+}
@@ -0,0 +1,58 @@
+package websocket
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDialAccept(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := Accept(w, r)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		for {
+			op, data, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+			c.WriteMessage(op, append([]byte("echo:"), data...))
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, err := Dial(ctx, "ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer c.Close()
+
+	big := strings.Repeat("x", 70000) // exercises the 64-bit length form
+	for _, msg := range []string{"hi", strings.Repeat("y", 300), big} {
+		if err := c.WriteMessage(TextMessage, []byte(msg)); err != nil {
+			t.Fatalf("WriteMessage: %v", err)
+		}
+		op, data, err := c.ReadMessage()
+		if err != nil {
+			t.Fatalf("ReadMessage: %v", err)
+		}
+		if op != TextMessage || string(data) != "echo:"+msg {
+			t.Fatalf("unexpected echo (op %d, %d bytes)", op, len(data))
+		}
+	}
+
+	if err := c.WriteJSON(map[string]int{"A": 1}); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	_, data, _ := c.ReadMessage()
+	if string(data) != `echo:{"A":1}` {
+		t.Fatalf("unexpected JSON echo %q", data)
+	}
+}
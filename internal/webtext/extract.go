@@ -0,0 +1,137 @@
+package webtext
+
+import (
+	"regexp"
+	"strings"
+)
+
+// junkElements never hold page content.
+var junkElements = set("script", "style", "noscript", "template", "svg", "canvas", "iframe", "object", "embed",
+	"form", "button", "input", "select", "textarea", "link", "meta")
+
+// chromeElements frame the content of most pages.
+var chromeElements = set("nav", "header", "footer", "aside", "menu", "dialog")
+
+// boilerplate matches the class or id of navigation, ads and other page
+// furniture.
+var boilerplate = regexp.MustCompile(`(?i)(^|[-_ ])(nav|navbar|menu|breadcrumbs?|sidebar|footer|masthead|cookie|consent|banner|share|sharing|social|advert|ads?|promo|sponsor|related|recommended|newsletter|subscribe|popup|modal|comments?)($|[-_ ])`)
+
+// Clean removes scripts, styles, forms and similar elements that never
+// carry readable content.
+func Clean(doc *Node) {
+	doc.walk(func(n *Node) bool {
+		if junkElements[n.Tag] || n.Attr("hidden") != "" || n.Attr("aria-hidden") == "true" {
+			n.remove()
+			return false
+		}
+		return true
+	})
+}
+
+// Title returns the document title.
+func Title(doc *Node) string {
+	if t := doc.Find("title"); t != nil {
+		return t.TextContent()
+	}
+	if h := doc.Find("h1"); h != nil {
+		return h.TextContent()
+	}
+	return ""
+}
+
+// Extract returns the element holding the main content of a cleaned
+// document, in the manner of readability: navigation, headers, footers and
+// elements named like boilerplate are dropped, then the element whose
+// paragraphs hold the most text wins. An <article> or <main> with enough text
+// is taken as is.
+func Extract(doc *Node) *Node {
+	body := doc.Find("body")
+	if body == nil {
+		body = doc
+	}
+	for _, tag := range []string{"article", "main"} {
+		if n := body.Find(tag); n != nil && len(n.TextContent()) >= 200 {
+			stripChrome(n)
+			return n
+		}
+	}
+	if n := firstMatch(body, func(n *Node) bool { return n.Attr("role") == "main" }); n != nil && len(n.TextContent()) >= 200 {
+		stripChrome(n)
+		return n
+	}
+	stripChrome(body)
+
+	scores := make(map[*Node]float64)
+	body.walk(func(n *Node) bool {
+		if n.Tag != "p" && n.Tag != "pre" && n.Tag != "blockquote" && n.Tag != "td" {
+			return true
+		}
+		text := n.TextContent()
+		if len(text) < 25 {
+			return false
+		}
+		score := 1 + float64(strings.Count(text, ",")) + min(float64(len(text))/100, 3)
+		if p := n.Parent; p != nil {
+			scores[p] += score
+			if g := p.Parent; g != nil {
+				scores[g] += score / 2
+			}
+		}
+		return false
+	})
+	var best *Node
+	bestScore := 0.0
+	for n, s := range scores {
+		s *= 1 - linkDensity(n)
+		if s > bestScore {
+			best, bestScore = n, s
+		}
+	}
+	if best == nil {
+		return body
+	}
+	return best
+}
+
+// stripChrome removes the navigation and boilerplate inside n.
+func stripChrome(n *Node) {
+	n.walk(func(c *Node) bool {
+		if c == n || c.IsText() {
+			return true
+		}
+		if chromeElements[c.Tag] || c.Attr("role") == "navigation" || c.Attr("role") == "complementary" ||
+			boilerplate.MatchString(c.Attr("class")) || boilerplate.MatchString(c.Attr("id")) {
+			c.remove()
+			return false
+		}
+		return true
+	})
+}
+
+// linkDensity is the share of n's text inside links.
+func linkDensity(n *Node) float64 {
+	total := len(n.TextContent())
+	if total == 0 {
+		return 0
+	}
+	links := 0
+	n.walk(func(c *Node) bool {
+		if c.Tag == "a" {
+			links += len(c.TextContent())
+			return false
+		}
+		return true
+	})
+	return float64(links) / float64(total)
+}
+
+func firstMatch(n *Node, fn func(*Node) bool) *Node {
+	var found *Node
+	n.walk(func(c *Node) bool {
+		if found == nil && !c.IsText() && fn(c) {
+			found = c
+		}
+		return found == nil
+	})
+	return found
+}
@@ -0,0 +1,312 @@
+package webtext
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// blockElements start a new block in the markdown output.
+var blockElements = set("address", "article", "aside", "blockquote", "body", "dd", "details", "dialog", "div", "dl", "dt",
+	"fieldset", "figcaption", "figure", "footer", "h1", "h2", "h3", "h4", "h5", "h6", "header", "hr", "li", "main",
+	"nav", "ol", "p", "pre", "section", "summary", "table", "ul", "#document", "html", "center")
+
+// Markdown renders n as markdown. Relative links and image sources are
+// resolved against base, which may be nil.
+func Markdown(n *Node, base *url.URL) string {
+	r := renderer{base: base}
+	out := strings.Join(r.block(n), "\n\n")
+	return strings.TrimSpace(blankLines.ReplaceAllString(out, "\n\n"))
+}
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+type renderer struct {
+	base *url.URL
+}
+
+// blocks renders the children of n as a list of markdown blocks.
+func (r *renderer) blocks(n *Node) []string {
+	var out []string
+	var inline strings.Builder
+	flush := func() {
+		if s := tidyInline(inline.String()); s != "" {
+			out = append(out, s)
+		}
+		inline.Reset()
+	}
+	for _, c := range n.Children {
+		if c.IsText() || !blockElements[c.Tag] {
+			inline.WriteString(r.inline(c))
+			continue
+		}
+		flush()
+		out = append(out, r.block(c)...)
+	}
+	flush()
+	return out
+}
+
+// block renders a block-level element.
+func (r *renderer) block(n *Node) []string {
+	switch n.Tag {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		if s := tidyInline(r.inlineChildren(n)); s != "" {
+			level := int(n.Tag[1] - '0')
+			return []string{strings.Repeat("#", level) + " " + strings.ReplaceAll(s, "\n", " ")}
+		}
+		return nil
+	case "hr":
+		return []string{"---"}
+	case "pre":
+		code := strings.Trim(rawText(n), "\n")
+		if code == "" {
+			return nil
+		}
+		fence := "```"
+		for strings.Contains(code, fence) {
+			fence += "`"
+		}
+		return []string{fence + codeLanguage(n) + "\n" + code + "\n" + fence}
+	case "blockquote":
+		inner := strings.Join(r.blocks(n), "\n\n")
+		if inner == "" {
+			return nil
+		}
+		return []string{prefixLines(inner, "> ")}
+	case "ul", "ol":
+		if s := r.list(n); s != "" {
+			return []string{s}
+		}
+		return nil
+	case "table":
+		if s := r.table(n); s != "" {
+			return []string{s}
+		}
+		return nil
+	case "dt":
+		if s := tidyInline(r.inlineChildren(n)); s != "" {
+			return []string{"**" + s + "**"}
+		}
+		return nil
+	}
+	return r.blocks(n)
+}
+
+func (r *renderer) list(n *Node) string {
+	var items []string
+	i, _ := strconv.Atoi(n.Attr("start"))
+	if i == 0 {
+		i = 1
+	}
+	for _, c := range n.Children {
+		if c.Tag != "li" {
+			continue
+		}
+		marker := "- "
+		if n.Tag == "ol" {
+			marker = strconv.Itoa(i) + ". "
+			i++
+		}
+		body := strings.Join(r.blocks(c), "\n")
+		if body == "" {
+			continue
+		}
+		indent := strings.Repeat(" ", len(marker))
+		lines := strings.Split(body, "\n")
+		for j := 1; j < len(lines); j++ {
+			if lines[j] != "" {
+				lines[j] = indent + lines[j]
+			}
+		}
+		items = append(items, marker+strings.Join(lines, "\n"))
+	}
+	return strings.Join(items, "\n")
+}
+
+func (r *renderer) table(n *Node) string {
+	var rows [][]string
+	n.walk(func(c *Node) bool {
+		if c != n && c.Tag == "table" {
+			return false // nested tables are flattened into their cell
+		}
+		if c.Tag != "tr" {
+			return true
+		}
+		var row []string
+		for _, cell := range c.Children {
+			if cell.Tag == "td" || cell.Tag == "th" {
+				text := strings.Join(r.blocks(cell), " ")
+				row = append(row, strings.ReplaceAll(strings.ReplaceAll(text, "\n", " "), "|", `\|`))
+			}
+		}
+		if len(row) > 0 {
+			rows = append(rows, row)
+		}
+		return false
+	})
+	if len(rows) == 0 {
+		return ""
+	}
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	var sb strings.Builder
+	for i, row := range rows {
+		for len(row) < width {
+			row = append(row, "")
+		}
+		sb.WriteString("| " + strings.Join(row, " | ") + " |\n")
+		if i == 0 {
+			sb.WriteString("|" + strings.Repeat(" --- |", width) + "\n")
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func (r *renderer) inlineChildren(n *Node) string {
+	var sb strings.Builder
+	for _, c := range n.Children {
+		sb.WriteString(r.inline(c))
+	}
+	return sb.String()
+}
+
+// inline renders n as inline markdown. Block elements met here (e.g. a
+// <div> inside a link) are rendered as their inline content.
+func (r *renderer) inline(n *Node) string {
+	if n.IsText() {
+		return collapseSpace(n.Text)
+	}
+	switch n.Tag {
+	case "br":
+		return "\n"
+	case "img":
+		alt := strings.TrimSpace(n.Attr("alt"))
+		src := r.resolve(n.Attr("src"))
+		if alt == "" || src == "" {
+			return alt
+		}
+		return "![" + alt + "](" + src + ")"
+	case "a":
+		text := strings.TrimSpace(r.inlineChildren(n))
+		href := n.Attr("href")
+		if text == "" || href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+			return r.inlineChildren(n)
+		}
+		return "[" + text + "](" + r.resolve(href) + ")"
+	case "code", "kbd", "samp", "tt":
+		code := strings.TrimSpace(n.TextContent())
+		if code == "" {
+			return ""
+		}
+		return "`" + code + "`"
+	case "strong", "b":
+		return wrap(r.inlineChildren(n), "**")
+	case "em", "i":
+		return wrap(r.inlineChildren(n), "*")
+	case "del", "s", "strike":
+		return wrap(r.inlineChildren(n), "~~")
+	}
+	s := r.inlineChildren(n)
+	if blockElements[n.Tag] {
+		s = " " + s + " "
+	}
+	return s
+}
+
+// wrap surrounds the trimmed s with marker, keeping the spaces around it.
+func wrap(s, marker string) string {
+	t := strings.TrimSpace(s)
+	if t == "" {
+		return s
+	}
+	lead := s[:strings.Index(s, t)]
+	trail := s[len(lead)+len(t):]
+	return lead + marker + t + marker + trail
+}
+
+func (r *renderer) resolve(ref string) string {
+	ref = strings.TrimSpace(ref)
+	if r.base == nil || ref == "" {
+		return ref
+	}
+	u, err := r.base.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
+
+// rawText returns the text of n without collapsing whitespace.
+func rawText(n *Node) string {
+	var sb strings.Builder
+	n.walk(func(c *Node) bool {
+		if c.IsText() {
+			sb.WriteString(c.Text)
+		} else if c.Tag == "br" {
+			sb.WriteByte('\n')
+		}
+		return true
+	})
+	return sb.String()
+}
+
+// codeLanguage returns the language of a <pre> from a "language-x" class on
+// it or its <code>.
+func codeLanguage(pre *Node) string {
+	for _, n := range []*Node{pre, pre.Find("code")} {
+		if n == nil {
+			continue
+		}
+		for _, c := range strings.Fields(n.Attr("class")) {
+			if lang, ok := strings.CutPrefix(c, "language-"); ok {
+				return lang
+			}
+		}
+	}
+	return ""
+}
+
+func collapseSpace(s string) string {
+	var sb strings.Builder
+	space := false
+	for _, r := range s {
+		if r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f' || r == '\u00a0' {
+			space = true
+			continue
+		}
+		if space {
+			sb.WriteByte(' ')
+			space = false
+		}
+		sb.WriteRune(r)
+	}
+	if space {
+		sb.WriteByte(' ')
+	}
+	return sb.String()
+}
+
+// tidyInline trims each line of rendered inline content and drops empty
+// ones.
+func tidyInline(s string) string {
+	lines := strings.Split(s, "\n")
+	out := lines[:0]
+	for _, l := range lines {
+		if l = strings.TrimSpace(l); l != "" {
+			out = append(out, l)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+func prefixLines(s, prefix string) string {
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(prefix+l, " ")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,360 @@
+// Package webtext turns fetched HTML pages into compact markdown for the
+// model: a forgiving HTML parser, a subset of CSS selectors, readability
+// style main-content extraction and a markdown renderer.
+package webtext
+
+import (
+	"html"
+	"strings"
+)
+
+// Node is an element or a text node of a parsed document.
+type Node struct {
+	Tag      string // lowercased element name; "" for text nodes
+	Text     string // text nodes only, unescaped
+	Attrs    map[string]string
+	Parent   *Node
+	Children []*Node
+}
+
+// Attr returns the value of an attribute.
+func (n *Node) Attr(name string) string { return n.Attrs[name] }
+
+// IsText reports whether n is a text node.
+func (n *Node) IsText() bool { return n.Tag == "" }
+
+func (n *Node) append(c *Node) {
+	c.Parent = n
+	n.Children = append(n.Children, c)
+}
+
+// remove detaches n from its parent.
+func (n *Node) remove() {
+	p := n.Parent
+	if p == nil {
+		return
+	}
+	for i, c := range p.Children {
+		if c == n {
+			p.Children = append(p.Children[:i], p.Children[i+1:]...)
+			break
+		}
+	}
+	n.Parent = nil
+}
+
+// walk calls fn for n and its descendants in document order; returning
+// false skips a node's children.
+func (n *Node) walk(fn func(*Node) bool) {
+	if !fn(n) {
+		return
+	}
+	for _, c := range append([]*Node(nil), n.Children...) {
+		c.walk(fn)
+	}
+}
+
+// Find returns the first descendant element with the given tag.
+func (n *Node) Find(tag string) *Node {
+	var found *Node
+	n.walk(func(c *Node) bool {
+		if found == nil && c != n && c.Tag == tag {
+			found = c
+		}
+		return found == nil
+	})
+	return found
+}
+
+// TextContent returns the text of n and its descendants with whitespace
+// collapsed.
+func (n *Node) TextContent() string {
+	var sb strings.Builder
+	n.walk(func(c *Node) bool {
+		if c.IsText() {
+			sb.WriteString(c.Text)
+			sb.WriteByte(' ')
+		}
+		return true
+	})
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// voidElements never have children.
+var voidElements = set("area", "base", "br", "col", "embed", "hr", "img", "input", "link", "meta", "param", "source", "track", "wbr")
+
+// rawTextElements contain text up to their end tag, not markup.
+var rawTextElements = set("script", "style", "textarea", "title", "noscript", "xmp", "iframe", "noembed")
+
+// closesP are start tags that end an open paragraph.
+var closesP = set("address", "article", "aside", "blockquote", "div", "dl", "fieldset", "figure", "footer", "form",
+	"h1", "h2", "h3", "h4", "h5", "h6", "header", "hr", "main", "nav", "ol", "p", "pre", "section", "table", "ul")
+
+// pScope bounds the search for an open paragraph to close.
+var pScope = set("div", "section", "article", "body", "td", "th", "li", "blockquote")
+
+// implicitEnd lists, per tag, the open elements a new start tag of that
+// kind closes, and the elements that bound the search.
+var implicitEnd = map[string]struct{ closes, stops map[string]bool }{
+	"li":     {set("li"), set("ul", "ol")},
+	"dt":     {set("dt", "dd"), set("dl")},
+	"dd":     {set("dt", "dd"), set("dl")},
+	"tr":     {set("tr", "td", "th"), set("table", "thead", "tbody", "tfoot")},
+	"td":     {set("td", "th"), set("tr", "table")},
+	"th":     {set("td", "th"), set("tr", "table")},
+	"option": {set("option"), set("select", "datalist")},
+}
+
+func set(names ...string) map[string]bool {
+	m := make(map[string]bool, len(names))
+	for _, n := range names {
+		m[n] = true
+	}
+	return m
+}
+
+// Parse parses an HTML document. It never fails: malformed markup is
+// repaired the way browsers mostly do, or ignored.
+func Parse(src string) *Node {
+	doc := &Node{Tag: "#document"}
+	p := parser{src: src, stack: []*Node{doc}}
+	p.run()
+	return doc
+}
+
+type parser struct {
+	src   string
+	pos   int
+	stack []*Node
+}
+
+func (p *parser) top() *Node { return p.stack[len(p.stack)-1] }
+
+func (p *parser) run() {
+	for p.pos < len(p.src) {
+		i := strings.IndexByte(p.src[p.pos:], '<')
+		if i < 0 {
+			p.text(p.src[p.pos:])
+			return
+		}
+		if i > 0 {
+			p.text(p.src[p.pos : p.pos+i])
+			p.pos += i
+		}
+		p.tag()
+	}
+}
+
+func (p *parser) text(s string) {
+	if s == "" {
+		return
+	}
+	top := p.top()
+	if n := len(top.Children); n > 0 && top.Children[n-1].IsText() {
+		top.Children[n-1].Text += html.UnescapeString(s)
+		return
+	}
+	top.append(&Node{Text: html.UnescapeString(s)})
+}
+
+// tag handles the markup at p.pos, which starts with '<'.
+func (p *parser) tag() {
+	rest := p.src[p.pos:]
+	switch {
+	case strings.HasPrefix(rest, "<!--"):
+		end := strings.Index(rest[4:], "-->")
+		if end < 0 {
+			p.pos = len(p.src)
+		} else {
+			p.pos += 4 + end + 3
+		}
+	case strings.HasPrefix(rest, "<!") || strings.HasPrefix(rest, "<?"):
+		p.skipPast('>')
+	case strings.HasPrefix(rest, "</"):
+		name := tagName(rest[2:])
+		p.skipPast('>')
+		if name != "" {
+			p.end(name)
+		}
+	default:
+		name := tagName(rest[1:])
+		if name == "" {
+			p.text("<")
+			p.pos++
+			return
+		}
+		p.pos += 1 + len(name)
+		attrs, selfClosing := p.attrs()
+		p.start(strings.ToLower(name), attrs, selfClosing)
+	}
+}
+
+func (p *parser) skipPast(c byte) {
+	if i := strings.IndexByte(p.src[p.pos:], c); i >= 0 {
+		p.pos += i + 1
+	} else {
+		p.pos = len(p.src)
+	}
+}
+
+// tagName returns the tag name at the start of s.
+func tagName(s string) string {
+	i := 0
+	for i < len(s) && (isLetter(s[i]) || (i > 0 && (s[i] == '-' || s[i] == ':' || (s[i] >= '0' && s[i] <= '9')))) {
+		i++
+	}
+	return s[:i]
+}
+
+func isLetter(c byte) bool { return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
+
+func isSpace(c byte) bool { return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' }
+
+// attrs parses attributes up to and including the closing '>'.
+func (p *parser) attrs() (map[string]string, bool) {
+	attrs := make(map[string]string)
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case isSpace(c):
+			p.pos++
+		case c == '>':
+			p.pos++
+			return attrs, false
+		case c == '/':
+			p.pos++
+			if p.pos < len(p.src) && p.src[p.pos] == '>' {
+				p.pos++
+				return attrs, true
+			}
+		default:
+			start := p.pos
+			for p.pos < len(p.src) && !isSpace(p.src[p.pos]) && !strings.ContainsRune("=>/", rune(p.src[p.pos])) {
+				p.pos++
+			}
+			name := strings.ToLower(p.src[start:p.pos])
+			if p.pos == start { // stray character
+				p.pos++
+				continue
+			}
+			for p.pos < len(p.src) && isSpace(p.src[p.pos]) {
+				p.pos++
+			}
+			val := ""
+			if p.pos < len(p.src) && p.src[p.pos] == '=' {
+				p.pos++
+				for p.pos < len(p.src) && isSpace(p.src[p.pos]) {
+					p.pos++
+				}
+				val = p.attrValue()
+			}
+			if _, dup := attrs[name]; !dup {
+				attrs[name] = html.UnescapeString(val)
+			}
+		}
+	}
+	return attrs, false
+}
+
+func (p *parser) attrValue() string {
+	if p.pos >= len(p.src) {
+		return ""
+	}
+	if q := p.src[p.pos]; q == '"' || q == '\'' {
+		end := strings.IndexByte(p.src[p.pos+1:], q)
+		if end < 0 {
+			v := p.src[p.pos+1:]
+			p.pos = len(p.src)
+			return v
+		}
+		v := p.src[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return v
+	}
+	start := p.pos
+	for p.pos < len(p.src) && !isSpace(p.src[p.pos]) && p.src[p.pos] != '>' {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *parser) start(name string, attrs map[string]string, selfClosing bool) {
+	if closesP[name] {
+		p.closeIfOpen("p", pScope)
+	}
+	if ie, ok := implicitEnd[name]; ok {
+		for i := len(p.stack) - 1; i > 0; i-- {
+			tag := p.stack[i].Tag
+			if ie.stops[tag] {
+				break
+			}
+			if ie.closes[tag] {
+				p.stack = p.stack[:i]
+				break
+			}
+		}
+	}
+	n := &Node{Tag: name, Attrs: attrs}
+	p.top().append(n)
+	if voidElements[name] || selfClosing {
+		return
+	}
+	if rawTextElements[name] {
+		end := indexEndTag(p.src[p.pos:], name)
+		if end < 0 {
+			end = len(p.src) - p.pos
+		}
+		if raw := p.src[p.pos : p.pos+end]; raw != "" {
+			if name == "title" || name == "textarea" {
+				raw = html.UnescapeString(raw)
+			}
+			n.append(&Node{Text: raw})
+		}
+		p.pos += end
+		p.skipPast('>')
+		return
+	}
+	p.stack = append(p.stack, n)
+}
+
+// closeIfOpen closes the innermost open name element unless one of the
+// stops is more recent.
+func (p *parser) closeIfOpen(name string, stops map[string]bool) {
+	for i := len(p.stack) - 1; i > 0; i-- {
+		tag := p.stack[i].Tag
+		if tag == name {
+			p.stack = p.stack[:i]
+			return
+		}
+		if stops[tag] {
+			return
+		}
+	}
+}
+
+// end closes the innermost open element called name, and everything opened
+// after it. End tags without an open element are ignored.
+func (p *parser) end(name string) {
+	name = strings.ToLower(name)
+	for i := len(p.stack) - 1; i > 0; i-- {
+		if p.stack[i].Tag == name {
+			p.stack = p.stack[:i]
+			return
+		}
+	}
+}
+
+// indexEndTag returns the index of the first "</name" in s, ignoring case,
+// or -1.
+func indexEndTag(s, name string) int {
+	for i := 0; ; i += 2 {
+		j := strings.Index(s[i:], "</")
+		if j < 0 {
+			return -1
+		}
+		i += j
+		if i+2+len(name) <= len(s) && strings.EqualFold(s[i+2:i+2+len(name)], name) {
+			return i
+		}
+	}
+}
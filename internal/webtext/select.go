@@ -0,0 +1,253 @@
+package webtext
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Selector is a compiled CSS selector. Supported: type (div), universal (*),
+// #id, .class, [attr], [attr=value], [attr~=value], [attr^=value],
+// [attr$=value], [attr*=value], descendant (a b) and child (a > b)
+// combinators, and comma-separated groups.
+type Selector struct {
+	groups [][]step // each group lists its compounds right to left
+}
+
+// step is a compound selector and the combinator joining it to the
+// compound on its left (' ' or '>'; 0 for the leftmost one).
+type step struct {
+	compound
+	comb byte
+}
+
+type compound struct {
+	tag     string
+	id      string
+	classes []string
+	attrs   []attrCond
+}
+
+type attrCond struct {
+	name, op, val string
+}
+
+// Compile parses a selector.
+func Compile(sel string) (*Selector, error) {
+	s := &Selector{}
+	for _, group := range strings.Split(sel, ",") {
+		steps, err := parseGroup(strings.TrimSpace(group))
+		if err != nil {
+			return nil, fmt.Errorf("webtext: selector %q: %w", sel, err)
+		}
+		s.groups = append(s.groups, steps)
+	}
+	return s, nil
+}
+
+func parseGroup(g string) ([]step, error) {
+	if g == "" {
+		return nil, fmt.Errorf("empty selector")
+	}
+	var steps []step
+	comb := byte(0)
+	for i := 0; i < len(g); {
+		switch c := g[i]; {
+		case isSpace(c):
+			if comb == 0 && len(steps) > 0 {
+				comb = ' '
+			}
+			i++
+			continue
+		case c == '>':
+			if len(steps) == 0 {
+				return nil, fmt.Errorf("combinator without a left side")
+			}
+			comb = '>'
+			i++
+			continue
+		}
+		cp, n, err := parseCompound(g[i:])
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, step{compound: cp, comb: comb})
+		comb = 0
+		i += n
+	}
+	if comb == '>' {
+		return nil, fmt.Errorf("combinator without a right side")
+	}
+	// reverse so matching starts at the subject
+	for i, j := 0, len(steps)-1; i < j; i, j = i+1, j-1 {
+		steps[i], steps[j] = steps[j], steps[i]
+	}
+	return steps, nil
+}
+
+// parseCompound parses one compound selector at the start of s and returns
+// it with the number of bytes consumed.
+func parseCompound(s string) (compound, int, error) {
+	var cp compound
+	i := 0
+	ident := func() string {
+		start := i
+		for i < len(s) && (isLetter(s[i]) || (s[i] >= '0' && s[i] <= '9') || s[i] == '-' || s[i] == '_') {
+			i++
+		}
+		return s[start:i]
+	}
+	if i < len(s) && s[i] == '*' {
+		i++
+	} else {
+		cp.tag = strings.ToLower(ident())
+	}
+	for i < len(s) {
+		switch s[i] {
+		case '#':
+			i++
+			if cp.id = ident(); cp.id == "" {
+				return cp, 0, fmt.Errorf("missing id after #")
+			}
+		case '.':
+			i++
+			c := ident()
+			if c == "" {
+				return cp, 0, fmt.Errorf("missing class after .")
+			}
+			cp.classes = append(cp.classes, c)
+		case '[':
+			end := strings.IndexByte(s[i:], ']')
+			if end < 0 {
+				return cp, 0, fmt.Errorf("unclosed [")
+			}
+			cond, err := parseAttrCond(s[i+1 : i+end])
+			if err != nil {
+				return cp, 0, err
+			}
+			cp.attrs = append(cp.attrs, cond)
+			i += end + 1
+		default:
+			if i == 0 {
+				return cp, 0, fmt.Errorf("unexpected %q", s[i])
+			}
+			if !isSpace(s[i]) && s[i] != '>' {
+				return cp, 0, fmt.Errorf("unsupported syntax at %q", s[i:])
+			}
+			return cp, i, nil
+		}
+	}
+	if i == 0 {
+		return cp, 0, fmt.Errorf("empty compound")
+	}
+	return cp, i, nil
+}
+
+func parseAttrCond(s string) (attrCond, error) {
+	for _, op := range []string{"~=", "^=", "$=", "*=", "="} {
+		if name, val, ok := strings.Cut(s, op); ok {
+			val = strings.Trim(strings.TrimSpace(val), `"'`)
+			return attrCond{name: strings.ToLower(strings.TrimSpace(name)), op: op, val: val}, nil
+		}
+	}
+	name := strings.ToLower(strings.TrimSpace(s))
+	if name == "" {
+		return attrCond{}, fmt.Errorf("empty attribute selector")
+	}
+	return attrCond{name: name}, nil
+}
+
+func (c compound) matches(n *Node) bool {
+	if n.IsText() || n.Tag == "#document" {
+		return false
+	}
+	if c.tag != "" && c.tag != n.Tag {
+		return false
+	}
+	if c.id != "" && n.Attr("id") != c.id {
+		return false
+	}
+	if len(c.classes) > 0 {
+		have := strings.Fields(n.Attr("class"))
+		for _, want := range c.classes {
+			if !contains(have, want) {
+				return false
+			}
+		}
+	}
+	for _, a := range c.attrs {
+		v, ok := n.Attrs[a.name]
+		if !ok {
+			return false
+		}
+		switch a.op {
+		case "=":
+			ok = v == a.val
+		case "~=":
+			ok = contains(strings.Fields(v), a.val)
+		case "^=":
+			ok = strings.HasPrefix(v, a.val)
+		case "$=":
+			ok = strings.HasSuffix(v, a.val)
+		case "*=":
+			ok = strings.Contains(v, a.val)
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Match reports whether n matches the selector.
+func (s *Selector) Match(n *Node) bool {
+	for _, steps := range s.groups {
+		if matchSteps(steps, n) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchSteps(steps []step, n *Node) bool {
+	if !steps[0].matches(n) {
+		return false
+	}
+	if len(steps) == 1 {
+		return true
+	}
+	switch steps[0].comb {
+	case '>':
+		return n.Parent != nil && matchSteps(steps[1:], n.Parent)
+	default: // descendant
+		for a := n.Parent; a != nil; a = a.Parent {
+			if matchSteps(steps[1:], a) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Select returns the descendants of n matching the selector, in document
+// order. Matches nested inside another match are left out, so rendering
+// the result doesn't repeat content.
+func (s *Selector) Select(n *Node) []*Node {
+	var out []*Node
+	n.walk(func(c *Node) bool {
+		if c != n && s.Match(c) {
+			out = append(out, c)
+			return false
+		}
+		return true
+	})
+	return out
+}
@@ -0,0 +1,83 @@
+package webtext
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const page = `<!DOCTYPE html>
+<html><head><title>Go &amp; You</title><style>p { color: red }</style>
+<script>var x = "<p>not text</p>";</script></head>
+<body>
+<nav><a href="/">Home</a> | <a href="/blog">Blog</a></nav>
+<div class="cookie-banner">We use cookies</div>
+<div id="content">
+  <h1>Why Go</h1>
+  <p>Go is a <b>simple</b>, fast language, with <a href="/docs/spec">a short spec</a>, good tooling, and a large standard library.
+  <p>It compiles quickly, runs fast, and <em>deploys</em> as a single static binary, which makes it easy to ship.</p>
+  <ul><li>Goroutines<li>Channels<ul><li>buffered</li></ul></ul>
+  <pre><code class="language-go">func main() {
+	fmt.Println("hi")
+}</code></pre>
+  <table><tr><th>Name</th><th>Year</th></tr><tr><td>Go</td><td>2009</td></tr></table>
+</div>
+<div class="sidebar"><p>Related posts, links and more links, to other things entirely unrelated.</p></div>
+<footer>© 2024</footer>
+</body></html>`
+
+func TestExtractMarkdown(t *testing.T) {
+	doc := Parse(page)
+	Clean(doc)
+	if got := Title(doc); got != "Go & You" {
+		t.Fatalf("title = %q", got)
+	}
+	base, _ := url.Parse("https://example.com/blog/post")
+	md := Markdown(Extract(doc), base)
+	want := "# Why Go\n\n" +
+		"Go is a **simple**, fast language, with [a short spec](https://example.com/docs/spec), good tooling, and a large standard library.\n\n" +
+		"It compiles quickly, runs fast, and *deploys* as a single static binary, which makes it easy to ship.\n\n" +
+		"- Goroutines\n- Channels\n  - buffered\n\n" +
+		"```go\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n```\n\n" +
+		"| Name | Year |\n| --- | --- |\n| Go | 2009 |"
+	if md != want {
+		t.Fatalf("unexpected markdown:\n%s\n--- want:\n%s", md, want)
+	}
+	for _, junk := range []string{"cookies", "Home", "Related", "2024", "not text", "color"} {
+		if strings.Contains(md, junk) {
+			t.Fatalf("boilerplate %q left in output", junk)
+		}
+	}
+}
+
+func TestSelector(t *testing.T) {
+	doc := Parse(`<div id="a" class="x y"><p class="x">one</p><span><p>two</p></span></div><p data-k="v1">three</p>`)
+	cases := map[string]string{
+		"p":               "one|two|three",
+		"#a > p":          "one",
+		"div p":           "one|two",
+		"div.x.y span p":  "two",
+		".x":              "one two",
+		"[data-k^=v]":     "three",
+		"span p, p.x":     "one|two",
+		"*[class~=y] > *": "one|two",
+	}
+	for sel, want := range cases {
+		s, err := Compile(sel)
+		if err != nil {
+			t.Fatalf("compile %q: %v", sel, err)
+		}
+		var got []string
+		for _, n := range s.Select(doc) {
+			got = append(got, n.TextContent())
+		}
+		if strings.Join(got, "|") != want {
+			t.Errorf("%q selected %q, want %q", sel, strings.Join(got, "|"), want)
+		}
+	}
+	for _, bad := range []string{"", "a >", "p:first-child", "[x"} {
+		if _, err := Compile(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
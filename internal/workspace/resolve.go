@@ -0,0 +1,174 @@
+// Package workspace resolves paths inside the agent workspace so that
+// neither ".." components nor symlinks can be used to escape it. Tools
+// check paths with it rather than inspecting them for ".." themselves.
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrOutside is returned when a path resolves outside the workspace root.
+var ErrOutside = errors.New("path escapes the workspace")
+
+// ErrSymlink is returned in strict mode for paths that pass through a
+// symlink.
+var ErrSymlink = errors.New("path goes through a symlink")
+
+// Jail confines paths to the workspace at Root. By default symlinks are
+// followed as long as their target stays inside the workspace. With Strict
+// set, any path through an existing symlink is refused, so a link cannot lead
+// out of the workspace even if it is changed between the check and its use.
+type Jail struct {
+	Root   string
+	Strict bool
+}
+
+// Resolve is like the package-level Resolve, and in strict mode also refuses
+// paths through symlinks.
+func (j Jail) Resolve(p string) (string, error) {
+	if j.Strict {
+		if err := noSymlinks(j.Root, p); err != nil {
+			return "", err
+		}
+	}
+	return Resolve(j.Root, p)
+}
+
+// SafeJoin joins p to root, refusing absolute paths outside root and ".."
+// components that climb out of it. It only looks at the path's text; use
+// Resolve for paths that may go through symlinks. root may be relative, e.g.
+// "skills" to keep a name inside that folder.
+func SafeJoin(root, p string) (string, error) {
+	root = filepath.Clean(root)
+	if filepath.IsAbs(p) {
+		if !filepath.IsAbs(root) || !Within(root, filepath.Clean(p)) {
+			return "", fmt.Errorf("workspace: %s: %w", p, ErrOutside)
+		}
+		return filepath.Clean(p), nil
+	}
+	joined := filepath.Join(root, p)
+	rel, err := filepath.Rel(root, joined)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("workspace: %s: %w", p, ErrOutside)
+	}
+	return joined, nil
+}
+
+// noSymlinks reports an error if any existing component of p below root is
+// a symlink.
+func noSymlinks(root, p string) error {
+	joined, err := SafeJoin(root, p)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(filepath.Clean(root), joined)
+	if err != nil || rel == "." {
+		return err
+	}
+	cur := filepath.Clean(root)
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		cur = filepath.Join(cur, part)
+		fi, err := os.Lstat(cur)
+		if err != nil {
+			return nil // the rest doesn't exist yet
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("workspace: %s: %w", p, ErrSymlink)
+		}
+	}
+	return nil
+}
+
+// Resolve returns the absolute, symlink-free form of p and verifies that it
+// lies inside root. Relative paths are interpreted relative to root. The path
+// does not need to exist: symlinks are evaluated for the longest existing
+// prefix, so files about to be created are checked through the directories
+// they would be written into.
+func Resolve(root, p string) (string, error) {
+	realRoot, err := realPath(root)
+	if err != nil {
+		return "", fmt.Errorf("workspace: resolve root: %w", err)
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", fmt.Errorf("workspace: resolve root: %w", err)
+	}
+	target := p
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(absRoot, target)
+	}
+	resolved, err := realPath(filepath.Clean(target))
+	if err != nil {
+		return "", fmt.Errorf("workspace: resolve %s: %w", p, err)
+	}
+	if !Within(realRoot, resolved) {
+		return "", fmt.Errorf("workspace: %s: %w", p, ErrOutside)
+	}
+	return resolved, nil
+}
+
+// Within reports whether path is root or lies beneath it. Both must be clean,
+// absolute paths.
+func Within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || filepath.IsAbs(rel) {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// maxLinks bounds the dangling symlinks realPath follows, like the kernel's
+// limit on symlinks in a path.
+const maxLinks = 40
+
+// realPath evaluates symlinks in the longest existing prefix of p and appends
+// the remaining (not yet existing) components unchanged. A dangling symlink
+// counts as existing: a file created through it lands at its target, so the
+// target is resolved in its place.
+func realPath(p string) (string, error) {
+	p, err := filepath.Abs(p)
+	if err != nil {
+		return "", err
+	}
+	var rest []string
+	cur := p
+	links := 0
+	for {
+		r, err := filepath.EvalSymlinks(cur)
+		if err == nil {
+			return filepath.Join(append([]string{r}, rest...)...), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		if fi, lerr := os.Lstat(cur); lerr == nil && fi.Mode()&os.ModeSymlink != 0 {
+			if links++; links > maxLinks {
+				return "", fmt.Errorf("%s: too many levels of symbolic links", p)
+			}
+			target, err := os.Readlink(cur)
+			if err != nil {
+				return "", err
+			}
+			if !filepath.IsAbs(target) {
+				dir, err := filepath.EvalSymlinks(filepath.Dir(cur))
+				if err != nil {
+					return "", err
+				}
+				target = filepath.Join(dir, target)
+			}
+			cur = filepath.Join(append([]string{target}, rest...)...)
+			rest = nil
+			continue
+		}
+		parent := filepath.Dir(cur)
+		if parent == cur {
+			return "", err
+		}
+		rest = append([]string{filepath.Base(cur)}, rest...)
+		cur = parent
+	}
+}
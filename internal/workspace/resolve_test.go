@@ -0,0 +1,97 @@
+package workspace
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "notes"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(root, "notes"), filepath.Join(root, "alias")); err != nil {
+		t.Fatal(err)
+	}
+
+	ok := []string{"notes/a.md", "new/dir/file.txt", "alias/b.md", ".", filepath.Join(root, "notes")}
+	for _, p := range ok {
+		if _, err := Resolve(root, p); err != nil {
+			t.Errorf("Resolve(%q) unexpected error: %v", p, err)
+		}
+	}
+	bad := []string{"../x", "escape/secret", "escape/new/file", "notes/../../x", outside}
+	for _, p := range bad {
+		if _, err := Resolve(root, p); !errors.Is(err, ErrOutside) {
+			t.Errorf("Resolve(%q) = %v, want ErrOutside", p, err)
+		}
+	}
+}
+
+func TestResolveDanglingSymlink(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Symlink(filepath.Join(outside, "loot.txt"), filepath.Join(root, "note.md")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "missing"), filepath.Join(root, "dir")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../"+filepath.Base(outside)+"/x", filepath.Join(root, "rel")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(root, "notes", "new.md"), filepath.Join(root, "inside")); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"note.md", "dir/a.md", "rel"} {
+		if _, err := Resolve(root, p); !errors.Is(err, ErrOutside) {
+			t.Errorf("Resolve(%q) = %v, want ErrOutside", p, err)
+		}
+	}
+	if got, err := Resolve(root, "inside"); err != nil || filepath.Base(got) != "new.md" {
+		t.Errorf("expected a dangling symlink inside the workspace to resolve to its target, got %q, %v", got, err)
+	}
+}
+
+func TestSafeJoin(t *testing.T) {
+	for _, p := range []string{"a/b", "a/../b", "./c", "x..y"} {
+		if _, err := SafeJoin("skills", p); err != nil {
+			t.Errorf("SafeJoin(skills, %q) unexpected error: %v", p, err)
+		}
+	}
+	for _, p := range []string{"..", "../memory", "a/../../b", "/etc/passwd"} {
+		if _, err := SafeJoin("skills", p); !errors.Is(err, ErrOutside) {
+			t.Errorf("SafeJoin(skills, %q) = %v, want ErrOutside", p, err)
+		}
+	}
+	root := t.TempDir()
+	if got, err := SafeJoin(root, filepath.Join(root, "a")); err != nil || got != filepath.Join(root, "a") {
+		t.Errorf("expected an absolute path inside root to be accepted, got %q, %v", got, err)
+	}
+}
+
+func TestJailStrict(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "notes"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(root, "notes"), filepath.Join(root, "alias")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	if _, err := (Jail{Root: root}).Resolve("alias/a.md"); err != nil {
+		t.Fatalf("expected a symlink inside the workspace to be followed: %v", err)
+	}
+	strict := Jail{Root: root, Strict: true}
+	if _, err := strict.Resolve("alias/a.md"); !errors.Is(err, ErrSymlink) {
+		t.Fatalf("expected strict mode to refuse the symlink, got %v", err)
+	}
+	if _, err := strict.Resolve("notes/new/a.md"); err != nil {
+		t.Fatalf("expected a plain path to be accepted in strict mode: %v", err)
+	}
+}